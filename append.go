@@ -0,0 +1,327 @@
+package tiler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/conv/coor"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/las"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/writer"
+)
+
+// appendFolderPrefix is the prefix of the folders storing the subtrees rebuilt by AppendFiles
+const appendFolderPrefix = "append"
+
+// AppendFiles adds the points of the given LAS files to a tileset previously generated in the existingTileset folder,
+// without reprocessing the whole point cloud. Only the subtrees of the root tile overlapping the area covered by the
+// new points are rebuilt: their points are read back from the tiles, merged with the new ones and exported as a single
+// subtree in a new "appendN" subfolder, which replaces them in the root tileset.json. The root tile and the subtrees
+// not overlapping the new points are left untouched, hence the result can differ from the tileset obtained processing
+// all the files together. The options should match the ones the tileset was generated with. Appending to 3tz or zip
+// packages is not supported.
+func (t *GoCesiumTiler) AppendFiles(existingTileset string, inputLasFiles []string, epsgCode int, opts *TilerOptions, ctx context.Context) error {
+	if opts.packaging != PackageNone {
+		return fmt.Errorf("appending files to 3tz or zip packages is not supported")
+	}
+	appendOpts := *opts
+	appendOpts.terrainOutput = false
+	appendOpts.debugFootprints = ""
+	appendOpts.manifestPath = ""
+
+	tilesetFile := filepath.Join(existingTileset, "tileset.json")
+	data, err := os.ReadFile(tilesetFile)
+	if err != nil {
+		return err
+	}
+	tileset := writer.Tileset{}
+	if err := json.Unmarshal(data, &tileset); err != nil {
+		return fmt.Errorf("invalid tileset %s: %w", tilesetFile, err)
+	}
+
+	// READ THE NEW POINTS
+	lasFile, err := t.lasReaderProvider(inputLasFiles, epsgCode, &appendOpts)
+	if err != nil {
+		return err
+	}
+	defer closeReader(lasFile)
+	newPts, region, err := t.readAppendedPoints(lasFile, inputLasFiles, epsgCode, &appendOpts, ctx)
+	if err != nil {
+		return err
+	}
+	// the file indexes of the new points follow the ones of the files already in the tileset
+	sourceFiles := []string{}
+	if files, ok := tileset.Asset.Extras["sourceFiles"].([]interface{}); ok {
+		for _, f := range files {
+			sourceFiles = append(sourceFiles, fmt.Sprint(f))
+		}
+	}
+	for i := range newPts {
+		newPts[i].FileIndex += len(sourceFiles)
+	}
+	sourceFiles = append(sourceFiles, listFiles(lasFile, inputLasFiles)...)
+
+	// COLLECT THE POINTS OF THE OVERLAPPING SUBTREES
+	// a tileset made of the root tile alone is rebuilt as a whole
+	outFolder := ""
+	affected := []writer.Child{}
+	kept := []writer.Child{}
+	if len(tileset.Root.Children) == 0 {
+		affected = append(affected, writer.Child{Content: tileset.Root.Content})
+	} else {
+		for _, c := range tileset.Root.Children {
+			if regionsIntersect(c.BoundingVolume.Region, region) {
+				affected = append(affected, c)
+			} else {
+				kept = append(kept, c)
+			}
+		}
+		outFolder = nextAppendFolder(existingTileset)
+	}
+	pts := newPts
+	for _, c := range affected {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		tilePts, err := readTilePoints(existingTileset, c.Content.Url)
+		if err != nil {
+			return err
+		}
+		pts = append(pts, tilePts...)
+	}
+
+	// BUILD AND EXPORT THE NEW SUBTREE
+	// the points are already in EPSG:4978 and have already been processed by the mutators
+	tr := t.treeProvider(&appendOpts, nil)
+	if err := tr.Load(las.NewSliceLasReader(pts, coor.EcefSrid), t.cconv, nil, ctx); err != nil {
+		return err
+	}
+	if err := tr.Build(); err != nil {
+		return err
+	}
+	w, err := t.writerProvider(existingTileset, sourceFiles, lasFile, t.cconv, &appendOpts, nil)
+	if err != nil {
+		return err
+	}
+	if err := w.Write(tr, outFolder, ctx); err != nil {
+		return err
+	}
+	if outFolder == "" {
+		return nil
+	}
+
+	// REPLACE THE OVERLAPPING SUBTREES IN THE ROOT TILESET
+	root := tr.GetRootNode()
+	reg, err := root.GetBoundingBoxRegion(t.cconv)
+	if err != nil {
+		return err
+	}
+	child := writer.Child{
+		Content:        writer.Content{Url: outFolder + "/tileset.json"},
+		BoundingVolume: writer.BoundingVolume{Region: reg.GetAsArray()},
+		GeometricError: math.Min(root.ComputeGeometricError(), tileset.Root.GeometricError),
+		Refine:         "ADD",
+	}
+	tileset.Root.Children = append(kept, child)
+	tileset.Root.BoundingVolume.Region = unionRegion(tileset.Root.BoundingVolume.Region, child.BoundingVolume.Region)
+	if tileset.Asset.Extras == nil {
+		tileset.Asset.Extras = map[string]interface{}{}
+	}
+	if appendOpts.sourceFileAttribute {
+		tileset.Asset.Extras["sourceFiles"] = sourceFiles
+	}
+	if q, ok := lasFile.(las.QuantizationSource); ok && appendOpts.sourceQuantization {
+		source, _ := tileset.Asset.Extras["source"].([]interface{})
+		for _, s := range q.Quantization() {
+			source = append(source, s)
+		}
+		tileset.Asset.Extras["source"] = source
+	}
+	if appendOpts.tilesetProperties {
+		// the ranges of the point properties are widened with the ones of the new subtree
+		if err := mergeProperties(&tileset, filepath.Join(existingTileset, outFolder, "tileset.json")); err != nil {
+			return err
+		}
+	}
+	if appendOpts.prettyTileset {
+		data, err = json.MarshalIndent(tileset, "", "\t")
+	} else {
+		data, err = json.Marshal(tileset)
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(tilesetFile, data, 0644); err != nil {
+		return err
+	}
+	// the replaced subtrees are removed only once the root tileset no longer references them
+	for _, c := range affected {
+		if folder := path.Dir(c.Content.Url); folder != "." {
+			if err := os.RemoveAll(filepath.Join(existingTileset, filepath.FromSlash(folder))); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// mergeProperties widens the ranges of the point properties of the given tileset with the ones of the tileset stored
+// in the given file
+func mergeProperties(tileset *writer.Tileset, file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	other := writer.Tileset{}
+	if err := json.Unmarshal(data, &other); err != nil {
+		return fmt.Errorf("invalid tileset %s: %w", file, err)
+	}
+	if tileset.Properties == nil {
+		tileset.Properties = map[string]writer.Property{}
+	}
+	for name, p := range other.Properties {
+		if current, ok := tileset.Properties[name]; ok {
+			p.Minimum = math.Min(p.Minimum, current.Minimum)
+			p.Maximum = math.Max(p.Maximum, current.Maximum)
+		}
+		tileset.Properties[name] = p
+	}
+	return nil
+}
+
+// readAppendedPoints reads the points to append, applying the same transformations applied by the tree when loading
+// them, and returns them in EPSG:4978 together with the region they cover, as [west, south, east, north] in radians
+func (t *GoCesiumTiler) readAppendedPoints(lasFile las.LasReader, inputLasFiles []string, epsgCode int, opts *TilerOptions, ctx context.Context) ([]geom.Point64, [4]float64, error) {
+	region := [4]float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+	var source las.LasReader = lasFile
+	if opts.externalClassification != "" {
+		labels, err := las.NewLabelReader(lasFile, opts.externalClassification)
+		if err != nil {
+			return nil, region, err
+		}
+		defer labels.Close()
+		source = labels
+	}
+	mutators, err := newMutatorPipeline(opts, epsgCode, t.cconv, &runResources{}, lasFile)
+	if err != nil {
+		return nil, region, err
+	}
+	if err := t.addGroundMutators(mutators, inputLasFiles, epsgCode, opts); err != nil {
+		return nil, region, err
+	}
+	eConv, err := t.newElevationConverter(epsgCode, opts)
+	if err != nil {
+		return nil, region, err
+	}
+	pts := make([]geom.Point64, 0, source.NumberOfPoints())
+	for i := 0; i < source.NumberOfPoints(); i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, region, err
+		}
+		pt, err := source.GetNext()
+		if errors.Is(err, las.ErrNoMorePoints) {
+			break
+		}
+		if err != nil {
+			return nil, region, err
+		}
+		pt, ok := mutators.Mutate(pt)
+		if !ok {
+			continue
+		}
+		z, err := eConv.ConvertElevation(pt.X, pt.Y, pt.Z)
+		if err != nil {
+			return nil, region, err
+		}
+		lonLat, err := t.cconv.ToSrid(epsgCode, coor.GeographicSrid, geom.Coord{X: pt.X, Y: pt.Y, Z: z})
+		if err != nil {
+			return nil, region, err
+		}
+		region[0] = math.Min(region[0], lonLat.X*math.Pi/180)
+		region[1] = math.Min(region[1], lonLat.Y*math.Pi/180)
+		region[2] = math.Max(region[2], lonLat.X*math.Pi/180)
+		region[3] = math.Max(region[3], lonLat.Y*math.Pi/180)
+		ecef, err := t.cconv.ToWGS84Cartesian(geom.Coord{X: pt.X, Y: pt.Y, Z: z}, epsgCode)
+		if err != nil {
+			return nil, region, err
+		}
+		pt.X, pt.Y, pt.Z = ecef.X, ecef.Y, ecef.Z
+		pts = append(pts, pt)
+	}
+	if len(pts) == 0 {
+		return nil, region, fmt.Errorf("no points to append")
+	}
+	return pts, region, nil
+}
+
+// readTilePoints reads all the points of the tile with the given content, relative to the given folder, and,
+// if the content is a tileset, of all its descendants
+func readTilePoints(folder string, uri string) ([]geom.Point64, error) {
+	file := filepath.Join(folder, filepath.FromSlash(uri))
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if path.Ext(uri) != ".json" {
+		pts, err := writer.ReadPnts(data)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read %s: %w", file, err)
+		}
+		return pts, nil
+	}
+	tileset := writer.Tileset{}
+	if err := json.Unmarshal(data, &tileset); err != nil {
+		return nil, fmt.Errorf("invalid tileset %s: %w", file, err)
+	}
+	tilesetFolder := filepath.Dir(file)
+	pts, err := readTilePoints(tilesetFolder, tileset.Root.Content.Url)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range tileset.Root.Children {
+		childPts, err := readTilePoints(tilesetFolder, c.Content.Url)
+		if err != nil {
+			return nil, err
+		}
+		pts = append(pts, childPts...)
+	}
+	return pts, nil
+}
+
+// nextAppendFolder returns the first appendN folder name not yet used in the given tileset folder
+func nextAppendFolder(tilesetFolder string) string {
+	for i := 1; ; i++ {
+		name := appendFolderPrefix + strconv.Itoa(i)
+		if _, err := os.Stat(filepath.Join(tilesetFolder, name)); os.IsNotExist(err) {
+			return name
+		}
+	}
+}
+
+// regionsIntersect returns true if the given tileset region, [west, south, east, north, minimum height, maximum height],
+// overlaps the given [west, south, east, north] area
+func regionsIntersect(region []float64, area [4]float64) bool {
+	if len(region) < 4 {
+		return true
+	}
+	return region[0] <= area[2] && area[0] <= region[2] && region[1] <= area[3] && area[1] <= region[3]
+}
+
+// unionRegion returns the smallest tileset region containing both the given regions
+func unionRegion(a, b []float64) []float64 {
+	if len(a) < 6 {
+		return b
+	}
+	return []float64{
+		math.Min(a[0], b[0]), math.Min(a[1], b[1]),
+		math.Max(a[2], b[2]), math.Max(a[3], b[3]),
+		math.Min(a[4], b[4]), math.Max(a[5], b[5]),
+	}
+}
@@ -0,0 +1,64 @@
+package tiler
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/conv/coor"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/las"
+)
+
+// boundsEdgeSamples is the number of segments each edge of the extent declared in the headers is split into when
+// reprojecting it, so that the bounds account for the curvature of the edges in the geographic CRS
+const boundsEdgeSamples = 16
+
+// ComputeBounds returns the geographic extent of the given LAS files, whose coordinates are in the CRS with the given
+// EPSG code, without reading their points nor building any tree. The extent declared in the headers of the files is
+// combined and reprojected sampling its edges, so the result covers all the points if the headers are accurate, e.g.
+// to show the footprint of a dataset before tiling it. Virtual point clouds are expanded as when tiling.
+func (t *GoCesiumTiler) ComputeBounds(files []string, epsgCode int) (Region, error) {
+	if len(files) == 0 {
+		return Region{}, fmt.Errorf("no input files")
+	}
+	reader, err := t.lasReaderProvider(files, epsgCode, NewDefaultTilerOptions())
+	if err != nil {
+		return Region{}, err
+	}
+	defer closeReader(reader)
+	extent, ok := reader.(las.HorizontalExtent)
+	if !ok {
+		return Region{}, fmt.Errorf("the extent of the points is not declared by the input files")
+	}
+	minX, minY, maxX, maxY := extent.Extent()
+	z := 0.0
+	if elevation, ok := reader.(las.ElevationRange); ok {
+		z = (elevation.MinZ() + elevation.MaxZ()) / 2
+	}
+	return geographicBounds(t.cconv, epsgCode, minX, minY, maxX, maxY, z)
+}
+
+// geographicBounds returns the smallest region containing the points along the edges of the given extent, at the
+// elevation z, reprojected from the CRS with the given EPSG code
+func geographicBounds(conv coor.CoordinateConverter, epsgCode int, minX, minY, maxX, maxY, z float64) (Region, error) {
+	if minX > maxX || minY > maxY {
+		return Region{}, fmt.Errorf("invalid extent X from %v to %v and Y from %v to %v", minX, maxX, minY, maxY)
+	}
+	region := Region{West: math.Inf(1), South: math.Inf(1), East: math.Inf(-1), North: math.Inf(-1)}
+	for i := 0; i <= boundsEdgeSamples; i++ {
+		f := float64(i) / boundsEdgeSamples
+		x := minX + (maxX-minX)*f
+		y := minY + (maxY-minY)*f
+		for _, c := range []geom.Coord{{X: x, Y: minY, Z: z}, {X: x, Y: maxY, Z: z}, {X: minX, Y: y, Z: z}, {X: maxX, Y: y, Z: z}} {
+			lonLat, err := conv.ToSrid(epsgCode, coor.GeographicSrid, c)
+			if err != nil {
+				return Region{}, err
+			}
+			region.West = math.Min(region.West, lonLat.X)
+			region.South = math.Min(region.South, lonLat.Y)
+			region.East = math.Max(region.East, lonLat.X)
+			region.North = math.Max(region.North, lonLat.Y)
+		}
+	}
+	return region, nil
+}
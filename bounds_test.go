@@ -0,0 +1,55 @@
+package tiler
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/conv/coor"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/las"
+)
+
+// bulgingConverter is a coordinate converter bending the lines of constant Y, so that the northernmost point of the
+// edges is halfway along them
+type bulgingConverter struct {
+	coor.CoordinateConverter
+}
+
+func (c *bulgingConverter) ToSrid(sourceSrid int, targetSrid int, coord geom.Coord) (geom.Coord, error) {
+	return geom.Coord{X: coord.X, Y: coord.Y + coord.X*(1-coord.X), Z: coord.Z}, nil
+}
+
+func TestTilerComputeBounds(t *testing.T) {
+	tiler, err := NewGoCesiumTiler()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tiler.cconv = &bulgingConverter{}
+	var readFiles []string
+	tiler.lasReaderProvider = func(inputLasFiles []string, epsgCode int, opts *TilerOptions) (las.LasReader, error) {
+		readFiles = inputLasFiles
+		return &extentReader{extent: [4]float64{0, 10, 1, 11}}, nil
+	}
+	bounds, err := tiler.ComputeBounds([]string{"a.las", "b.las"}, 32633)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(readFiles) != 2 {
+		t.Errorf("expected the headers of %d files to be read got %d", 2, len(readFiles))
+	}
+	expected := Region{West: 0, South: 10, East: 1, North: 11.25}
+	if math.Abs(bounds.West-expected.West) > 1e-9 || math.Abs(bounds.South-expected.South) > 1e-9 ||
+		math.Abs(bounds.East-expected.East) > 1e-9 || math.Abs(bounds.North-expected.North) > 1e-9 {
+		t.Errorf("expected bounds %v got %v", expected, bounds)
+	}
+
+	tiler.lasReaderProvider = func(inputLasFiles []string, epsgCode int, opts *TilerOptions) (las.LasReader, error) {
+		return &las.MockLasReader{}, nil
+	}
+	if _, err := tiler.ComputeBounds([]string{"a.las"}, 32633); err == nil {
+		t.Errorf("expected error for a reader without extent, got none")
+	}
+	if _, err := tiler.ComputeBounds(nil, 32633); err == nil {
+		t.Errorf("expected error for no input files, got none")
+	}
+}
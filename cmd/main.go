@@ -3,16 +3,17 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	tiler "github.com/mfbonfigli/gocesiumtiler/v2"
-	"github.com/mfbonfigli/gocesiumtiler/v2/internal/utils"
 	"github.com/urfave/cli/v2"
 )
 
@@ -21,8 +22,6 @@ var tilerProvider func() (tiler.Tiler, error) = func() (tiler.Tiler, error) {
 	return tiler.NewGoCesiumTiler()
 }
 
-var version = "2.0.0-alpha"
-
 const logo = `
                            _                 _   _ _
   __ _  ___   ___ ___  ___(_)_   _ _ __ ___ | |_(_) | ___ _ __ 
@@ -42,11 +41,11 @@ func getCli(c *cliOpts) *cli.App {
 	return &cli.App{
 		Name:    "gocesiumtiler",
 		Usage:   "transforms LAS files into Cesium.JS 3D Tiles",
-		Version: version,
+		Version: tiler.Version(),
 		Commands: []*cli.Command{
 			{
 				Name:  "file",
-				Usage: "convert a LAS file into 3D tiles",
+				Usage: "convert a LAS file into 3D tiles, pass - as file to read it from the standard input",
 				Flags: getFileFlags(c),
 				Action: func(cCtx *cli.Context) error {
 					fileCommand(c, cCtx.Args().First())
@@ -62,13 +61,28 @@ func getCli(c *cliOpts) *cli.App {
 					return nil
 				},
 			},
+			{
+				Name:  "selftest",
+				Usage: "tile a synthetic point cloud in a temporary folder and validate the output to check the installation",
+				Flags: getSelftestFlags(c),
+				Action: func(cCtx *cli.Context) error {
+					selftestCommand(c)
+					return nil
+				},
+			},
 		},
 		EnableBashCompletion: true,
 	}
 }
 
 func getFileFlags(c *cliOpts) []cli.Flag {
-	return getFlags(c)
+	fileListFlag := &cli.StringFlag{
+		Name:        "file-list",
+		Value:       c.fileList,
+		Usage:       "path of a text file listing the LAS files to merge into a single cloud, one per line, instead of the file argument. Blank lines and lines starting with # are ignored",
+		Destination: &c.fileList,
+	}
+	return append(getFlags(c), fileListFlag)
 }
 
 func getFolderFlags(c *cliOpts) []cli.Flag {
@@ -80,7 +94,32 @@ func getFolderFlags(c *cliOpts) []cli.Flag {
 		Usage:       "merge the input LAS files in the folder into a single cloud. The LAS files must have the same properties (CRS etc)",
 		Destination: &c.join,
 	}
-	return append(stdFlags, joinFlag)
+	patternFlag := &cli.StringFlag{
+		Name:        "pattern",
+		Aliases:     []string{"p"},
+		Value:       c.pattern,
+		Usage:       "only process the LAS files whose name matches the given case insensitive glob pattern, e.g. tile_00*.las",
+		Destination: &c.pattern,
+	}
+	continueFlag := &cli.BoolFlag{
+		Name:        "continue-on-error",
+		Value:       c.continueOnError,
+		Usage:       "keep processing the remaining files when a file fails, reporting all the failures at the end. Ignored with the join flag",
+		Destination: &c.continueOnError,
+	}
+	groupFlag := &cli.BoolFlag{
+		Name:        "group-by-crs",
+		Value:       c.groupByCrs,
+		Usage:       "merge the input LAS files in the folder into one cloud per CRS declared in their headers, writing each tileset in an epsg_<code> subfolder and listing them in crs.json. The epsg flag is only required for the files not declaring their CRS",
+		Destination: &c.groupByCrs,
+	}
+	recursiveFlag := &cli.BoolFlag{
+		Name:        "recursive",
+		Value:       c.recursive,
+		Usage:       "process the LAS files in all the subfolders of the input folder too. Each tileset is stored under the path of its file relative to the input folder, e.g. a/scan.las in a/scan",
+		Destination: &c.recursive,
+	}
+	return append(stdFlags, joinFlag, patternFlag, continueFlag, groupFlag, recursiveFlag)
 }
 
 func getFlags(c *cliOpts) []cli.Flag {
@@ -140,31 +179,65 @@ func getFlags(c *cliOpts) []cli.Flag {
 			Usage:       "set to interpret the input points color as part of a 8bit color space",
 			Destination: &c.eightBit,
 		},
+		&cli.BoolFlag{
+			Name:        "3tz",
+			Value:       c.threeTz,
+			Usage:       "set to write each output tileset as a single 3D Tiles archive (tileset.3tz) instead of loose files",
+			Destination: &c.threeTz,
+		},
+		&cli.BoolFlag{
+			Name:        "ion-zip",
+			Value:       c.ionZip,
+			Usage:       "set to write each output tileset as a single zip archive (tileset.zip) ready to be uploaded to Cesium ion instead of loose files",
+			Destination: &c.ionZip,
+		},
+		&cli.StringFlag{
+			Name:        "tmp",
+			Value:       c.tmp,
+			Usage:       "directory where to store the copy of the standard input, defaults to the OS temp directory",
+			Destination: &c.tmp,
+		},
 	}
 }
 
 type cliOpts struct {
-	output     string
-	epsg       int
-	maxDepth   int
-	minPoints  int
-	resolution float64
-	zOffset    float64
-	geoid      bool
-	eightBit   bool
-	join       bool
+	output          string
+	epsg            int
+	maxDepth        int
+	minPoints       int
+	resolution      float64
+	zOffset         float64
+	geoid           bool
+	eightBit        bool
+	join            bool
+	threeTz         bool
+	ionZip          bool
+	pattern         string
+	tmp             string
+	fileList        string
+	continueOnError bool
+	groupByCrs      bool
+	recursive       bool
 }
 
 func defaultCliOptions() *cliOpts {
 	return &cliOpts{
-		epsg:       -1,
-		maxDepth:   10,
-		minPoints:  5000,
-		resolution: 20,
-		zOffset:    0,
-		geoid:      false,
-		eightBit:   false,
-		join:       false,
+		epsg:            -1,
+		maxDepth:        10,
+		minPoints:       5000,
+		resolution:      20,
+		zOffset:         0,
+		geoid:           false,
+		eightBit:        false,
+		join:            false,
+		threeTz:         false,
+		ionZip:          false,
+		pattern:         "",
+		tmp:             "",
+		fileList:        "",
+		continueOnError: false,
+		groupByCrs:      false,
+		recursive:       false,
 	}
 }
 
@@ -172,9 +245,12 @@ func (c *cliOpts) validate() {
 	if c.output == "" {
 		log.Fatal("output flag must be set")
 	}
-	if c.epsg <= 0 {
+	if c.epsg <= 0 && !c.groupByCrs {
 		log.Fatal("epsg code is invalid")
 	}
+	if c.join && c.groupByCrs {
+		log.Fatal("join and group-by-crs cannot be used together")
+	}
 	if c.maxDepth <= 1 || c.maxDepth > 20 {
 		log.Fatal("depth should be between 1 and 20")
 	}
@@ -184,6 +260,12 @@ func (c *cliOpts) validate() {
 	if c.resolution < 0.5 || c.resolution > 1000 {
 		log.Fatal("resolution should be between 1 and 1000 meters")
 	}
+	if c.threeTz && c.ionZip {
+		log.Fatal("3tz and ion-zip cannot be used together")
+	}
+	if _, err := filepath.Match(c.pattern, ""); err != nil {
+		log.Fatal("pattern is not a valid glob pattern")
+	}
 }
 
 func (c *cliOpts) print() {
@@ -196,12 +278,26 @@ func (c *cliOpts) print() {
 - Geoid elevation: %v,
 - 8Bit Color: %v
 - Join Clouds: %v
+- 3tz Archive: %v
+- Ion Zip Archive: %v
+- File Pattern: %s
+- Temp Directory: %s
+- Continue on Error: %v
+- Group by CRS: %v
+- Recursive: %v
 
-`, c.epsg, c.maxDepth, c.resolution, c.minPoints, c.zOffset, c.geoid, c.eightBit, c.join)
+`, c.epsg, c.maxDepth, c.resolution, c.minPoints, c.zOffset, c.geoid, c.eightBit, c.join, c.threeTz, c.ionZip, c.pattern, c.tmp, c.continueOnError, c.groupByCrs, c.recursive)
 }
 
 func (c *cliOpts) getTilerOptions() *tiler.TilerOptions {
 	c.validate()
+	packaging := tiler.PackageNone
+	if c.threeTz {
+		packaging = tiler.Package3tz
+	}
+	if c.ionZip {
+		packaging = tiler.PackageIonZip
+	}
 	return tiler.NewTilerOptions(
 		tiler.WithEightBitColors(c.eightBit),
 		tiler.WithGeoidElevation(c.geoid),
@@ -209,7 +305,12 @@ func (c *cliOpts) getTilerOptions() *tiler.TilerOptions {
 		tiler.WithGridSize(c.resolution),
 		tiler.WithMaxDepth(c.maxDepth),
 		tiler.WithMinPointsPerTile(c.minPoints),
-		tiler.WithCallback(eventListener),
+		tiler.WithPackaging(packaging),
+		tiler.WithFilePattern(c.pattern),
+		tiler.WithContinueOnError(c.continueOnError),
+		tiler.WithCrsGrouping(c.groupByCrs),
+		tiler.WithRecursive(c.recursive),
+		tiler.WithProgressWriter(os.Stdout),
 	)
 }
 
@@ -218,15 +319,60 @@ func fileCommand(opts *cliOpts, filepath string) {
 	if err != nil {
 		log.Fatal(err)
 	}
-	fmt.Printf("*** Mode: File, process LAS file at %s\n", filepath)
+	files := []string{filepath}
+	if opts.fileList != "" {
+		if filepath != "" {
+			log.Fatal("the file argument and the file-list flag cannot be used together")
+		}
+		files, err = tiler.ReadFileList(opts.fileList)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("*** Mode: File, process %d LAS files listed in %s\n", len(files), opts.fileList)
+	} else if filepath == stdinArg {
+		fmt.Printf("*** Mode: File, process LAS file read from the standard input\n")
+	} else {
+		fmt.Printf("*** Mode: File, process LAS file at %s\n", filepath)
+	}
 	opts.print()
 	tilerOpts := opts.getTilerOptions()
 	runnable := func(ctx context.Context) error {
-		return t.ProcessFiles([]string{filepath}, opts.output, opts.epsg, tilerOpts, ctx)
+		if filepath == stdinArg {
+			spooled, err := spoolStdin(os.Stdin, opts.tmp)
+			if err != nil {
+				return err
+			}
+			defer os.Remove(spooled)
+			files = []string{spooled}
+		}
+		return t.ProcessFiles(files, opts.output, opts.epsg, tilerOpts, ctx)
 	}
 	launch(runnable)
 }
 
+// stdinArg is the file argument that makes the file command read the LAS file from the standard input
+const stdinArg = "-"
+
+// spoolStdin copies the LAS file piped to the standard input to a temporary file in the given folder, or in the
+// default temp folder if empty, as the reader needs to seek the file and reads it multiple times.
+// Returns the path of the temporary file, which the caller must remove.
+func spoolStdin(stdin io.Reader, dir string) (string, error) {
+	f, err := os.CreateTemp(dir, "stdin-*.las")
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(f, stdin); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", fmt.Errorf("unable to read the standard input: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
 func folderCommand(opts *cliOpts, folderpath string) {
 	t, err := tilerProvider()
 	if err != nil {
@@ -236,18 +382,23 @@ func folderCommand(opts *cliOpts, folderpath string) {
 	opts.print()
 	tilerOpts := opts.getTilerOptions()
 	runnable := func(ctx context.Context) error {
-		if opts.join {
-			files, err := utils.FindLasFilesInFolder(folderpath)
-			if err != nil {
-				return err
-			}
-			return t.ProcessFiles(files, opts.output, opts.epsg, tilerOpts, ctx)
-		}
-		return t.ProcessFolder(folderpath, opts.output, opts.epsg, tilerOpts, ctx)
+		return processFolder(t, opts, folderpath, tilerOpts, ctx)
 	}
 	launch(runnable)
 }
 
+// processFolder converts the files of the folder, either joining them into a single tileset or into a tileset each
+func processFolder(t tiler.Tiler, opts *cliOpts, folderpath string, tilerOpts *tiler.TilerOptions, ctx context.Context) error {
+	if opts.join {
+		files, err := tiler.FindMatchingLasFiles(folderpath, opts.recursive, opts.pattern)
+		if err != nil {
+			return err
+		}
+		return t.ProcessFiles(files, opts.output, opts.epsg, tilerOpts, ctx)
+	}
+	return t.ProcessFolder(folderpath, opts.output, opts.epsg, tilerOpts, ctx)
+}
+
 func launch(function func(ctx context.Context) error) {
 	ctx, _ := signal.NotifyContext(context.Background(), os.Interrupt)
 	wg := &sync.WaitGroup{}
@@ -262,10 +413,6 @@ func launch(function func(ctx context.Context) error) {
 	wg.Wait()
 }
 
-func eventListener(e tiler.TilerEvent, filename string, elapsed int64, msg string) {
-	fmt.Printf("[%s] [%s] %s\n", time.Now().UTC().Format("2006-01-02 15:04:05.000"), filename, msg)
-}
-
 func printBanner() {
 	fmt.Println(strings.ReplaceAll(logo, "YYYY", strconv.Itoa(time.Now().Year())))
 }
@@ -1,9 +1,11 @@
 package main
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 
 	tiler "github.com/mfbonfigli/gocesiumtiler/v2"
@@ -35,6 +37,8 @@ func TestMainProcessFile(t *testing.T) {
 		"-depth", "13",
 		"-min-points-per-tile", "1200",
 		"-geoid", "-8-bit",
+		"-3tz",
+		"-tmp", "/data/tmp",
 		"myfile.las"}
 	main()
 	if mockTiler.ProcessFilesCalled != true {
@@ -55,8 +59,8 @@ func TestMainProcessFile(t *testing.T) {
 	if actual := mockTiler.GeoidElev; actual != true {
 		t.Errorf("expected tiler to be called with GeoidElev %v but got %v", true, actual)
 	}
-	if actual := mockTiler.GridSize; actual != 11.1 {
-		t.Errorf("expected tiler to be called with GridSize %v but got %v", 11.1, actual)
+	if actual := mockTiler.GridSize; actual != [3]float64{11.1, 11.1, 11.1} {
+		t.Errorf("expected tiler to be called with GridSize %v but got %v", [3]float64{11.1, 11.1, 11.1}, actual)
 	}
 	if actual := mockTiler.PtsPerTile; actual != 1200 {
 		t.Errorf("expected tiler to be called with PtsPerTile %v but got %v", 1200, actual)
@@ -67,6 +71,86 @@ func TestMainProcessFile(t *testing.T) {
 	if actual := mockTiler.ElevOffset; actual != -1 {
 		t.Errorf("expected tiler to be called with ElevOffset %v but got %v", -1, actual)
 	}
+	if actual := mockTiler.Packaging; actual != tiler.Package3tz {
+		t.Errorf("expected tiler to be called with Packaging %v but got %v", tiler.Package3tz, actual)
+	}
+}
+
+func TestMainProcessFileList(t *testing.T) {
+	tmp := t.TempDir()
+	list := filepath.Join(tmp, "files.txt")
+	if err := os.WriteFile(list, []byte("# inputs\na.las\n\nb.las\n"), 0644); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	mockTiler := &tiler.MockTiler{}
+	tilerProvider = func() (tiler.Tiler, error) {
+		return mockTiler, nil
+	}
+	os.Args = []string{"gocesiumtiler", "file",
+		"-out", ".\\abc",
+		"-epsg", "4979",
+		"-file-list", list}
+	main()
+	if mockTiler.ProcessFilesCalled != true {
+		t.Error("expected processFiles called but was not")
+	}
+	expected := []string{filepath.Join(tmp, "a.las"), filepath.Join(tmp, "b.las")}
+	if actual := mockTiler.InputFiles; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected tiler to be called with %v but got %v", expected, actual)
+	}
+}
+
+func TestMainProcessFileFromStdin(t *testing.T) {
+	tmp := t.TempDir()
+	input := filepath.Join(tmp, "input.las")
+	if err := os.WriteFile(input, []byte("LASF"), 0644); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	f, err := os.Open(input)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	defer f.Close()
+	stdin := os.Stdin
+	os.Stdin = f
+	defer func() { os.Stdin = stdin }()
+
+	mockTiler := &tiler.MockTiler{}
+	tilerProvider = func() (tiler.Tiler, error) {
+		return mockTiler, nil
+	}
+	os.Args = []string{"gocesiumtiler", "file",
+		"-out", ".\\abc",
+		"-epsg", "4979",
+		"-tmp", tmp,
+		"-"}
+	main()
+	if mockTiler.ProcessFilesCalled != true {
+		t.Fatal("expected processFiles called but was not")
+	}
+	if actual := mockTiler.InputFiles; len(actual) != 1 || filepath.Dir(actual[0]) != tmp || !strings.HasPrefix(filepath.Base(actual[0]), "stdin-") {
+		t.Errorf("expected tiler to be called with a temporary file in %s but got %v", tmp, actual)
+	} else if _, err := os.Stat(actual[0]); !os.IsNotExist(err) {
+		t.Errorf("expected the temporary file %s to be removed", actual[0])
+	}
+}
+
+func TestSpoolStdin(t *testing.T) {
+	tmp := t.TempDir()
+	spooled, err := spoolStdin(strings.NewReader("LASF content"), tmp)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	data, err := os.ReadFile(spooled)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if string(data) != "LASF content" {
+		t.Errorf("expected the content of the standard input got %q", data)
+	}
+	if _, err := spoolStdin(strings.NewReader(""), filepath.Join(tmp, "missing")); err == nil {
+		t.Errorf("expected error for a missing temp folder, got none")
+	}
 }
 
 func TestMainProcessFolder(t *testing.T) {
@@ -82,6 +166,7 @@ func TestMainProcessFolder(t *testing.T) {
 		"-depth", "13",
 		"-min-points-per-tile", "1200",
 		"-geoid", "-8-bit",
+		"-ion-zip",
 		"myfolder"}
 	main()
 	if mockTiler.ProcessFolderCalled != true {
@@ -102,8 +187,8 @@ func TestMainProcessFolder(t *testing.T) {
 	if actual := mockTiler.GeoidElev; actual != true {
 		t.Errorf("expected tiler to be called with GeoidElev %v but got %v", true, actual)
 	}
-	if actual := mockTiler.GridSize; actual != 11.1 {
-		t.Errorf("expected tiler to be called with GridSize %v but got %v", 11.1, actual)
+	if actual := mockTiler.GridSize; actual != [3]float64{11.1, 11.1, 11.1} {
+		t.Errorf("expected tiler to be called with GridSize %v but got %v", [3]float64{11.1, 11.1, 11.1}, actual)
 	}
 	if actual := mockTiler.PtsPerTile; actual != 1200 {
 		t.Errorf("expected tiler to be called with PtsPerTile %v but got %v", 1200, actual)
@@ -114,6 +199,9 @@ func TestMainProcessFolder(t *testing.T) {
 	if actual := mockTiler.ElevOffset; actual != -1 {
 		t.Errorf("expected tiler to be called with ElevOffset %v but got %v", -1, actual)
 	}
+	if actual := mockTiler.Packaging; actual != tiler.PackageIonZip {
+		t.Errorf("expected tiler to be called with Packaging %v but got %v", tiler.PackageIonZip, actual)
+	}
 }
 
 func TestMainProcessFolderJoin(t *testing.T) {
@@ -171,8 +259,8 @@ func TestMainProcessFolderJoin(t *testing.T) {
 	if actual := mockTiler.GeoidElev; actual != true {
 		t.Errorf("expected tiler to be called with GeoidElev %v but got %v", true, actual)
 	}
-	if actual := mockTiler.GridSize; actual != 11.1 {
-		t.Errorf("expected tiler to be called with GridSize %v but got %v", 11.1, actual)
+	if actual := mockTiler.GridSize; actual != [3]float64{11.1, 11.1, 11.1} {
+		t.Errorf("expected tiler to be called with GridSize %v but got %v", [3]float64{11.1, 11.1, 11.1}, actual)
 	}
 	if actual := mockTiler.PtsPerTile; actual != 1200 {
 		t.Errorf("expected tiler to be called with PtsPerTile %v but got %v", 1200, actual)
@@ -184,3 +272,95 @@ func TestMainProcessFolderJoin(t *testing.T) {
 		t.Errorf("expected tiler to be called with ElevOffset %v but got %v", -1, actual)
 	}
 }
+
+func TestMainProcessFolderJoinWithPattern(t *testing.T) {
+	tmp, err := os.MkdirTemp(os.TempDir(), "tst")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(tmp)
+	})
+
+	utils.TouchFile(filepath.Join(tmp, "tile_001.las"))
+	utils.TouchFile(filepath.Join(tmp, "tile_002.LAS"))
+	utils.TouchFile(filepath.Join(tmp, "other.las"))
+
+	mockTiler := &tiler.MockTiler{}
+	tilerProvider = func() (tiler.Tiler, error) {
+		return mockTiler, nil
+	}
+	os.Args = []string{"gocesiumtiler", "folder",
+		"-out", ".\\abc",
+		"-epsg", "4979",
+		"-join",
+		"-pattern", "tile_*",
+		tmp}
+	main()
+	if mockTiler.ProcessFilesCalled != true {
+		t.Error("expected processFiles called but was not")
+	}
+	expected := []string{
+		filepath.Join(tmp, "tile_001.las"),
+		filepath.Join(tmp, "tile_002.LAS"),
+	}
+	if actual := mockTiler.InputFiles; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected tiler to be called with %v but got %v", expected, actual)
+	}
+	if actual := mockTiler.Pattern; actual != "tile_*" {
+		t.Errorf("expected tiler to be called with Pattern %v but got %v", "tile_*", actual)
+	}
+}
+
+func TestProcessFolderJoinNoMatchingFiles(t *testing.T) {
+	tmp := t.TempDir()
+	utils.TouchFile(filepath.Join(tmp, "other.las"))
+
+	mockTiler := &tiler.MockTiler{}
+	opts := &cliOpts{join: true, pattern: "tile_*", output: "out", epsg: 4979}
+	err := processFolder(mockTiler, opts, tmp, tiler.NewDefaultTilerOptions(), context.TODO())
+	if err == nil || !strings.Contains(err.Error(), "no LAS files matching the pattern tile_*") {
+		t.Errorf("expected error for no matching files, got %v", err)
+	}
+	if mockTiler.ProcessFilesCalled {
+		t.Errorf("expected processFiles not to be called")
+	}
+}
+
+func TestMainProcessFolderContinueOnError(t *testing.T) {
+	mockTiler := &tiler.MockTiler{}
+	tilerProvider = func() (tiler.Tiler, error) {
+		return mockTiler, nil
+	}
+	os.Args = []string{"gocesiumtiler", "folder",
+		"-out", ".\\abc",
+		"-epsg", "4979",
+		"-continue-on-error",
+		t.TempDir()}
+	main()
+	if mockTiler.ProcessFolderCalled != true {
+		t.Error("expected processFolder called but was not")
+	}
+	if actual := mockTiler.ContinueOnError; actual != true {
+		t.Errorf("expected tiler to be called with ContinueOnError %v but got %v", true, actual)
+	}
+}
+
+func TestMainProcessFolderGroupByCrs(t *testing.T) {
+	mockTiler := &tiler.MockTiler{}
+	tilerProvider = func() (tiler.Tiler, error) {
+		return mockTiler, nil
+	}
+	// the epsg flag can be omitted when grouping by CRS
+	os.Args = []string{"gocesiumtiler", "folder",
+		"-out", ".\\abc",
+		"-group-by-crs",
+		t.TempDir()}
+	main()
+	if mockTiler.ProcessFolderCalled != true {
+		t.Error("expected processFolder called but was not")
+	}
+	if actual := mockTiler.CrsGrouping; actual != true {
+		t.Errorf("expected tiler to be called with CrsGrouping %v but got %v", true, actual)
+	}
+}
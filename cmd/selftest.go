@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+
+	tiler "github.com/mfbonfigli/gocesiumtiler/v2"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/las"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/writer"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	// selftestEpsg is the CRS of the synthetic cloud, UTM 33N, exercising the reprojection of the points
+	selftestEpsg = 32633
+	// selftestSide is the number of points along each side of the square synthetic cloud
+	selftestSide = 60
+)
+
+func getSelftestFlags(c *cliOpts) []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:        "tmp",
+			Value:       c.tmp,
+			Usage:       "directory where to store the synthetic cloud and the tileset, defaults to the OS temp directory",
+			Destination: &c.tmp,
+		},
+	}
+}
+
+func selftestCommand(opts *cliOpts) {
+	fmt.Printf("*** Mode: Self test, tile a synthetic cloud of %d points and validate the output\n", selftestSide*selftestSide)
+	launch(func(ctx context.Context) error {
+		if err := runSelftest(opts.tmp, ctx); err != nil {
+			return fmt.Errorf("self test FAILED: %w", err)
+		}
+		fmt.Println("*** Self test PASSED")
+		return nil
+	})
+}
+
+// runSelftest generates a synthetic LAS file in a temporary folder created in tmp, tiles it with the tiler and
+// validates the resulting tileset, checking that it stores all the input points. The temporary folder is removed
+// at the end.
+func runSelftest(tmp string, ctx context.Context) error {
+	t, err := tilerProvider()
+	if err != nil {
+		return err
+	}
+	dir, err := os.MkdirTemp(tmp, "selftest-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	input := filepath.Join(dir, "synthetic.las")
+	pts := syntheticCloud()
+	if err := las.WriteLasFile(input, pts); err != nil {
+		return fmt.Errorf("unable to write the synthetic cloud: %w", err)
+	}
+	output := filepath.Join(dir, "tileset")
+	tilerOpts := tiler.NewTilerOptions(
+		tiler.WithGridSize(2),
+		tiler.WithMaxDepth(6),
+		tiler.WithMinPointsPerTile(100),
+	)
+	if err := t.ProcessFiles([]string{input}, output, selftestEpsg, tilerOpts, ctx); err != nil {
+		return fmt.Errorf("unable to tile the synthetic cloud: %w", err)
+	}
+	n, err := writer.ValidateTileset(output)
+	if err != nil {
+		return err
+	}
+	if n != len(pts) {
+		return fmt.Errorf("expected %d points in the tileset, found %d", len(pts), n)
+	}
+	return nil
+}
+
+// syntheticCloud returns a square grid of points with a 1 meter spacing, with a wavy elevation and colors
+// varying along the grid
+func syntheticCloud() []geom.Point64 {
+	pts := make([]geom.Point64, 0, selftestSide*selftestSide)
+	for i := 0; i < selftestSide; i++ {
+		for j := 0; j < selftestSide; j++ {
+			pts = append(pts, geom.Point64{
+				X:              500000 + float64(i),
+				Y:              5000000 + float64(j),
+				Z:              100 + 5*math.Sin(float64(i)/10)*math.Cos(float64(j)/10),
+				R:              uint8(i * 255 / selftestSide),
+				G:              uint8(j * 255 / selftestSide),
+				B:              128,
+				Intensity:      uint8((i + j) % 256),
+				Classification: 2,
+			})
+		}
+	}
+	return pts
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	tiler "github.com/mfbonfigli/gocesiumtiler/v2"
+)
+
+func TestRunSelftest(t *testing.T) {
+	tilerProvider = func() (tiler.Tiler, error) {
+		return tiler.NewGoCesiumTiler()
+	}
+	tmp := t.TempDir()
+	if err := runSelftest(tmp, context.TODO()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if entries, err := os.ReadDir(tmp); err != nil || len(entries) != 0 {
+		t.Errorf("expected the temporary folder to be removed, found %v", entries)
+	}
+
+	// a tiler producing no output must fail the validation
+	tilerProvider = func() (tiler.Tiler, error) {
+		return &tiler.MockTiler{}, nil
+	}
+	if err := runSelftest(tmp, context.TODO()); err == nil {
+		t.Errorf("expected error for a missing tileset, got none")
+	}
+}
@@ -0,0 +1,109 @@
+package tiler
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/assets"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/conv/coor"
+)
+
+var (
+	geographicEpsgOnce  sync.Once
+	geographicEpsgCodes map[int]bool
+)
+
+// isGeographicEpsg returns whether the CRS with the given EPSG code has coordinates in degrees, according to the
+// proj4 definitions bundled with the tiler. known is false if the code is not in the definitions.
+func isGeographicEpsg(code int) (geographic bool, known bool) {
+	geographicEpsgOnce.Do(func() {
+		geographicEpsgCodes = map[int]bool{}
+		f, err := assets.GetAssets().Open("epsg_projections.txt")
+		if err != nil {
+			return
+		}
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			tokens := strings.Split(scanner.Text(), "\t")
+			if len(tokens) < 3 {
+				continue
+			}
+			c, err := strconv.Atoi(strings.TrimPrefix(tokens[0], "EPSG:"))
+			if err != nil {
+				continue
+			}
+			geographicEpsgCodes[c] = strings.Contains(tokens[2], "+proj=longlat") || strings.Contains(tokens[2], "+proj=latlong")
+		}
+	})
+	geographic, known = geographicEpsgCodes[code]
+	return geographic, known
+}
+
+// checkCrsExtent compares the magnitude of the X, Y extent of the points with the units of the CRS with the given
+// EPSG code, returning an error suggesting the likely correct CRS if they do not match:
+//   - for geographic CRSs the coordinates must be valid longitudes and latitudes
+//   - for projected CRSs, coordinates that are valid longitudes and latitudes spanning less than a unit in both
+//     directions are assumed to be degrees, as an extent of less than a meter is implausible for a point cloud
+//
+// Unknown EPSG codes are not checked.
+func checkCrsExtent(epsgCode int, minX, minY, maxX, maxY float64) error {
+	geographic, known := isGeographicEpsg(epsgCode)
+	if !known {
+		return nil
+	}
+	lonLat := math.Abs(minX) <= 180 && math.Abs(maxX) <= 180 && math.Abs(minY) <= 90 && math.Abs(maxY) <= 90
+	if geographic && !lonLat {
+		return fmt.Errorf("the coordinates of the points, X from %.3f to %.3f and Y from %.3f to %.3f, are not valid "+
+			"longitudes and latitudes for the geographic EPSG:%d: the points are likely in a projected CRS in meters, e.g. "+
+			"a UTM zone such as EPSG:326xx", minX, maxX, minY, maxY, epsgCode)
+	}
+	if !geographic && lonLat && maxX-minX < 1 && maxY-minY < 1 {
+		return fmt.Errorf("the coordinates of the points, X from %.6f to %.6f and Y from %.6f to %.6f, look like "+
+			"longitudes and latitudes in degrees but EPSG:%d is projected: the points are likely in EPSG:4326, or "+
+			"EPSG:4979 if the elevations are ellipsoidal", minX, maxX, minY, maxY, epsgCode)
+	}
+	return nil
+}
+
+// processWithEpsgRegistry converts the given files as processFiles does, with the coordinate converter of the tiler
+// extended with the definitions of the EPSG registry set with WithEpsgRegistry
+func (t *GoCesiumTiler) processWithEpsgRegistry(inputLasFiles []string, outputFolder string, epsgCode int, opts *TilerOptions, res *runResources, ctx context.Context) error {
+	definitions, err := res.getEpsgRegistry(opts.epsgRegistry)
+	if err != nil {
+		return err
+	}
+	extender, ok := t.cconv.(coor.Extender)
+	if !ok {
+		return fmt.Errorf("the coordinate converter does not support custom EPSG definitions")
+	}
+	conv := extender.Extend(definitions)
+	defer conv.Cleanup()
+	registryTiler := *t
+	registryTiler.cconv = conv
+	registryOpts := *opts
+	registryOpts.epsgRegistry = ""
+	return registryTiler.processFiles(inputLasFiles, outputFolder, epsgCode, &registryOpts, res, ctx)
+}
+
+// processWithDatumGridPath converts the given files as processFiles does, with the coordinate converter of the tiler
+// searching the datum grid shift files in the folder set with WithDatumGridPath
+func (t *GoCesiumTiler) processWithDatumGridPath(inputLasFiles []string, outputFolder string, epsgCode int, opts *TilerOptions, res *runResources, ctx context.Context) error {
+	if info, err := os.Stat(opts.datumGridPath); err != nil || !info.IsDir() {
+		return fmt.Errorf("the datum grid path %s is not a folder", opts.datumGridPath)
+	}
+	locator, ok := t.cconv.(coor.DatumGridLocator)
+	if !ok {
+		return fmt.Errorf("the coordinate converter does not support datum grid shift files")
+	}
+	defer locator.AddDatumGridPath(opts.datumGridPath)()
+	gridOpts := *opts
+	gridOpts.datumGridPath = ""
+	return t.processFiles(inputLasFiles, outputFolder, epsgCode, &gridOpts, res, ctx)
+}
@@ -0,0 +1,222 @@
+package tiler
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/conv/coor"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/las"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/mutator"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/tree"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/writer"
+)
+
+func TestIsGeographicEpsg(t *testing.T) {
+	cases := []struct {
+		code       int
+		geographic bool
+		known      bool
+	}{
+		{4326, true, true},
+		{4979, true, true},
+		{32633, false, true},
+		{4978, false, true},
+		{-1, false, false},
+	}
+	for _, c := range cases {
+		geographic, known := isGeographicEpsg(c.code)
+		if geographic != c.geographic || known != c.known {
+			t.Errorf("EPSG:%d: expected geographic %v and known %v got %v and %v", c.code, c.geographic, c.known, geographic, known)
+		}
+	}
+}
+
+func TestCheckCrsExtent(t *testing.T) {
+	cases := []struct {
+		epsg                   int
+		minX, minY, maxX, maxY float64
+		valid                  bool
+	}{
+		{4326, 12.1, 45.2, 12.3, 45.4, true},
+		{4326, 500000, 5000000, 501000, 5001000, false},
+		{32633, 500000, 5000000, 501000, 5001000, true},
+		{32633, 12.1, 45.2, 12.3, 45.4, false},
+		// a projected cloud close to the origin spanning more than a meter is plausible
+		{32633, 10, 20, 50, 60, true},
+		{-1, 500000, 5000000, 501000, 5001000, true},
+	}
+	for _, c := range cases {
+		err := checkCrsExtent(c.epsg, c.minX, c.minY, c.maxX, c.maxY)
+		if (err == nil) != c.valid {
+			t.Errorf("EPSG:%d extent %v %v %v %v: expected valid %v got error %v", c.epsg, c.minX, c.minY, c.maxX, c.maxY, c.valid, err)
+		}
+	}
+}
+
+// extentReader is a mock reader reporting the X, Y extent of the points
+type extentReader struct {
+	las.MockLasReader
+	extent [4]float64
+}
+
+func (r *extentReader) Extent() (float64, float64, float64, float64) {
+	return r.extent[0], r.extent[1], r.extent[2], r.extent[3]
+}
+
+func TestTilerProcessFilesCrsCheck(t *testing.T) {
+	tiler, err := NewGoCesiumTiler()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tiler.writerProvider = func(folder string, inputFiles []string, reader las.LasReader, c coor.CoordinateConverter, opts *TilerOptions, progress writer.ProgressFunc) (writer.Writer, error) {
+		return &writer.MockWriter{}, nil
+	}
+	tiler.treeProvider = func(opts *TilerOptions, mut mutator.Mutator) tree.Tree {
+		return &tree.MockNode{}
+	}
+	tiler.lasReaderProvider = func(inputLasFiles []string, epsgCode int, opts *TilerOptions) (las.LasReader, error) {
+		return &extentReader{extent: [4]float64{500000, 5000000, 501000, 5001000}}, nil
+	}
+	warnings := 0
+	callback := func(event TilerEvent, inputDesc string, elapsed int64, msg string) {
+		if event == EventCrsWarning {
+			warnings++
+		}
+	}
+	if err := tiler.ProcessFiles([]string{"abc.las"}, "out", 4326, NewTilerOptions(WithCallback(callback)), context.TODO()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if warnings != 1 {
+		t.Errorf("expected %d warning got %d", 1, warnings)
+	}
+	if err := tiler.ProcessFiles([]string{"abc.las"}, "out", 4326, NewTilerOptions(WithStrictCrs(true)), context.TODO()); err == nil {
+		t.Errorf("expected error for the CRS mismatch, got none")
+	}
+	if err := tiler.ProcessFiles([]string{"abc.las"}, "out", 32633, NewTilerOptions(WithStrictCrs(true)), context.TODO()); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+// extendingConverter is a coordinate converter recording the definitions it is extended with
+type extendingConverter struct {
+	coor.CoordinateConverter
+	definitions map[int]string
+	cleanups    int
+}
+
+func (c *extendingConverter) Extend(definitions map[int]string) coor.CoordinateConverter {
+	return &extendingConverter{definitions: definitions}
+}
+
+func (c *extendingConverter) Cleanup() {
+	c.cleanups++
+}
+
+func TestTilerProcessFilesEpsgRegistry(t *testing.T) {
+	tiler, err := NewGoCesiumTiler()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tiler.cconv = &extendingConverter{}
+	var writerConv coor.CoordinateConverter
+	tiler.writerProvider = func(folder string, inputFiles []string, reader las.LasReader, c coor.CoordinateConverter, opts *TilerOptions, progress writer.ProgressFunc) (writer.Writer, error) {
+		writerConv = c
+		return &writer.MockWriter{}, nil
+	}
+	tiler.treeProvider = func(opts *TilerOptions, m mutator.Mutator) tree.Tree {
+		return &tree.MockNode{}
+	}
+	tiler.lasReaderProvider = func(inputLasFiles []string, epsgCode int, opts *TilerOptions) (las.LasReader, error) {
+		return &las.MockLasReader{}, nil
+	}
+	registry := filepath.Join(t.TempDir(), "epsg")
+	if err := os.WriteFile(registry, []byte("<900001> +proj=longlat +datum=WGS84 +no_defs <>\n"), 0666); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := tiler.ProcessFiles([]string{"abc.las"}, t.TempDir(), 900001, NewTilerOptions(WithEpsgRegistry(registry)), context.TODO()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	extended, ok := writerConv.(*extendingConverter)
+	if !ok || extended == tiler.cconv {
+		t.Fatalf("expected the tileset to be written with the extended converter, got %v", writerConv)
+	}
+	if extended.definitions[900001] != "+proj=longlat +datum=WGS84 +no_defs" {
+		t.Errorf("unexpected definitions %v", extended.definitions)
+	}
+	if extended.cleanups != 1 {
+		t.Errorf("expected the extended converter to be cleaned up once, got %d", extended.cleanups)
+	}
+	if tiler.cconv.(*extendingConverter).cleanups != 0 {
+		t.Errorf("expected the converter of the tiler not to be cleaned up")
+	}
+
+	missing := NewTilerOptions(WithEpsgRegistry(filepath.Join(t.TempDir(), "missing")))
+	if err := tiler.ProcessFiles([]string{"abc.las"}, t.TempDir(), 900001, missing, context.TODO()); err == nil {
+		t.Errorf("expected error for a missing registry, got none")
+	}
+
+	tiler.cconv = &offsetConverter{}
+	if err := tiler.ProcessFiles([]string{"abc.las"}, t.TempDir(), 900001, NewTilerOptions(WithEpsgRegistry(registry)), context.TODO()); err == nil {
+		t.Errorf("expected error for a converter not supporting custom definitions, got none")
+	}
+}
+
+// gridLocatingConverter is a coordinate converter recording the datum grid paths currently added to it
+type gridLocatingConverter struct {
+	coor.CoordinateConverter
+	paths []string
+}
+
+func (c *gridLocatingConverter) AddDatumGridPath(dir string) func() {
+	c.paths = append(c.paths, dir)
+	return func() {
+		for i, p := range c.paths {
+			if p == dir {
+				c.paths = append(c.paths[:i], c.paths[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+func TestTilerProcessFilesDatumGridPath(t *testing.T) {
+	tiler, err := NewGoCesiumTiler()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	conv := &gridLocatingConverter{}
+	tiler.cconv = conv
+	var pathsWhileWriting []string
+	tiler.writerProvider = func(folder string, inputFiles []string, reader las.LasReader, c coor.CoordinateConverter, opts *TilerOptions, progress writer.ProgressFunc) (writer.Writer, error) {
+		pathsWhileWriting = append([]string{}, conv.paths...)
+		return &writer.MockWriter{}, nil
+	}
+	tiler.treeProvider = func(opts *TilerOptions, m mutator.Mutator) tree.Tree {
+		return &tree.MockNode{}
+	}
+	tiler.lasReaderProvider = func(inputLasFiles []string, epsgCode int, opts *TilerOptions) (las.LasReader, error) {
+		return &las.MockLasReader{}, nil
+	}
+	grids := t.TempDir()
+	if err := tiler.ProcessFiles([]string{"abc.las"}, t.TempDir(), 4267, NewTilerOptions(WithDatumGridPath(grids)), context.TODO()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(pathsWhileWriting) != 1 || pathsWhileWriting[0] != grids {
+		t.Errorf("expected the grid path to be set while tiling, got %v", pathsWhileWriting)
+	}
+	if len(conv.paths) != 0 {
+		t.Errorf("expected the grid path to be removed after tiling, got %v", conv.paths)
+	}
+
+	missing := NewTilerOptions(WithDatumGridPath(filepath.Join(t.TempDir(), "missing")))
+	if err := tiler.ProcessFiles([]string{"abc.las"}, t.TempDir(), 4267, missing, context.TODO()); err == nil {
+		t.Errorf("expected error for a missing grid path, got none")
+	}
+
+	tiler.cconv = &offsetConverter{}
+	if err := tiler.ProcessFiles([]string{"abc.las"}, t.TempDir(), 4267, NewTilerOptions(WithDatumGridPath(grids)), context.TODO()); err == nil {
+		t.Errorf("expected error for a converter not supporting datum grids, got none")
+	}
+}
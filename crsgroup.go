@@ -0,0 +1,76 @@
+package tiler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/las"
+)
+
+// crsIndexFile is the file, stored in the output folder, listing the tilesets exported by WithCrsGrouping
+const crsIndexFile = "crs.json"
+
+// CrsIndex lists the tilesets exported by WithCrsGrouping, one per CRS of the input files
+type CrsIndex struct {
+	// Groups are sorted by ascending EPSG code
+	Groups []CrsGroup `json:"groups"`
+}
+
+// CrsGroup is a tileset merging the input files in the CRS with the given EPSG code
+type CrsGroup struct {
+	Epsg int `json:"epsg"`
+	// Folder is the folder of the tileset relative to the output folder
+	Folder string `json:"folder"`
+	// Files are the paths of the input files merged in the tileset
+	Files []string `json:"files"`
+}
+
+// processCrsGroups groups the given files by the EPSG code declared in their headers, falling back to the given
+// EPSG code for the files not declaring one, exports a tileset merging the files of each group in a subfolder of the
+// output folder and writes the index of the exported tilesets.
+func (t *GoCesiumTiler) processCrsGroups(files []string, outputFolder string, epsgCode int, opts *TilerOptions, res *runResources, ctx context.Context) error {
+	groups := map[int][]string{}
+	for _, f := range files {
+		code, err := las.ReadEpsgCode(f)
+		if err != nil {
+			return &las.FileError{File: f, Err: err}
+		}
+		if code == 0 {
+			if epsgCode <= 0 {
+				return &las.FileError{File: f, Err: fmt.Errorf("the file does not declare an EPSG code and no default EPSG code is set")}
+			}
+			code = epsgCode
+		}
+		groups[code] = append(groups[code], f)
+	}
+	codes := make([]int, 0, len(groups))
+	for code := range groups {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	index := CrsIndex{Groups: []CrsGroup{}}
+	for _, code := range codes {
+		group := CrsGroup{
+			Epsg:   code,
+			Folder: "epsg_" + strconv.Itoa(code),
+			Files:  groups[code],
+		}
+		if err := t.processFiles(group.Files, filepath.Join(outputFolder, group.Folder), code, opts, res, ctx); err != nil {
+			return err
+		}
+		index.Groups = append(index.Groups, group)
+	}
+	data, err := json.MarshalIndent(index, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(outputFolder, 0777); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(outputFolder, crsIndexFile), data, 0666)
+}
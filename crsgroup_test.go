@@ -0,0 +1,70 @@
+package tiler
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/las"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/writer"
+)
+
+func TestTilerCrsGrouping(t *testing.T) {
+	tiler, err := NewGoCesiumTiler()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	in := t.TempDir()
+	pts := []geom.Point64{}
+	for i := 0; i < 10; i++ {
+		for j := 0; j < 10; j++ {
+			pts = append(pts, geom.Point64{X: 500000 + float64(i), Y: 5000000 + float64(j), Z: 100})
+		}
+	}
+	files := map[string][]func(*las.WriterOptions){
+		"a.las": {las.WithGeoKeys(32633, false)},
+		"b.las": {las.WithGeoKeys(32633, false)},
+		"c.las": nil,
+	}
+	for name, opts := range files {
+		if err := las.WriteLasFile(filepath.Join(in, name), pts, opts...); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	out := t.TempDir()
+	opts := NewTilerOptions(WithGridSize(2), WithCrsGrouping(true))
+	if err := tiler.ProcessFolder(in, out, 32632, opts, context.TODO()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(out, crsIndexFile))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	index := CrsIndex{}
+	if err := json.Unmarshal(data, &index); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := CrsIndex{Groups: []CrsGroup{
+		{Epsg: 32632, Folder: "epsg_32632", Files: []string{filepath.Join(in, "c.las")}},
+		{Epsg: 32633, Folder: "epsg_32633", Files: []string{filepath.Join(in, "a.las"), filepath.Join(in, "b.las")}},
+	}}
+	if !reflect.DeepEqual(index, expected) {
+		t.Errorf("expected index %v got %v", expected, index)
+	}
+	for _, group := range expected.Groups {
+		n, err := writer.ValidateTileset(filepath.Join(out, group.Folder))
+		if err != nil || n != len(pts)*len(group.Files) {
+			t.Errorf("expected %d points in the tileset of EPSG:%d got %d (%v)", len(pts)*len(group.Files), group.Epsg, n, err)
+		}
+	}
+
+	// c.las does not declare an EPSG code
+	if err := tiler.ProcessFolder(in, t.TempDir(), -1, opts, context.TODO()); err == nil {
+		t.Errorf("expected error without a default EPSG code, got none")
+	}
+}
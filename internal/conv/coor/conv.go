@@ -4,8 +4,53 @@ import (
 	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
 )
 
+// The CRSs in which the tiler expresses its outputs. CesiumJS expects the positions of the pnts contents, relative to
+// their RTC_CENTER, in Earth-centered, Earth-fixed cartesian coordinates, while the region bounding volumes of the
+// tileset are expressed as geographic coordinates, with ellipsoidal heights. Both refer to the WGS 84 ellipsoid.
+const (
+	// EcefSrid is EPSG:4978, the WGS 84 geocentric CRS with X, Y and Z in meters. The points are stored internally
+	// and exported in this CRS.
+	EcefSrid = 4978
+	// GeographicSrid is EPSG:4979, the WGS 84 geographic 3D CRS with longitude and latitude in degrees and the
+	// height above the ellipsoid in meters. The bounding regions are computed in this CRS and converted to radians.
+	GeographicSrid = 4979
+)
+
 type CoordinateConverter interface {
 	ToSrid(sourceSrid int, targetSrid int, coord geom.Coord) (geom.Coord, error)
+	// ToWGS84Cartesian converts the coordinate from the given srid to EcefSrid
 	ToWGS84Cartesian(coord geom.Coord, sourceSrid int) (geom.Coord, error)
 	Cleanup()
 }
+
+// Forker is implemented by the converters that are not safe for concurrent use. Fork returns an independent
+// converter, with its own projection objects, that can be used concurrently with the original one. The returned
+// converter must be cleaned up once no longer needed.
+type Forker interface {
+	Fork() CoordinateConverter
+}
+
+// Extender is implemented by the converters whose CRSs are defined by proj4 definitions. Extend returns a converter
+// supporting, in addition to the CRSs of the original one, the CRSs with the given proj4 definitions by EPSG code,
+// which override the definitions of the original converter. The returned converter must be cleaned up once no
+// longer needed.
+type Extender interface {
+	Extend(definitions map[int]string) CoordinateConverter
+}
+
+// DatumGridLocator is implemented by the converters applying the datum grid shift files, such as NTv2 and NADCON,
+// referenced by the CRS definitions. AddDatumGridPath adds a folder the grid files are searched in, until the
+// returned function is called.
+type DatumGridLocator interface {
+	AddDatumGridPath(dir string) (remove func())
+}
+
+// Fork returns a converter to use in a new goroutine, forking the given converter if it implements Forker or
+// returning it as is otherwise, together with the function releasing the returned converter
+func Fork(c CoordinateConverter) (CoordinateConverter, func()) {
+	if f, ok := c.(Forker); ok {
+		forked := f.Fork()
+		return forked, forked.Cleanup
+	}
+	return c, func() {}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math"
 
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/conv/coor"
 	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
 	"github.com/wroge/wgs84/v2"
 )
@@ -48,11 +49,11 @@ func (cc *goprojCoordinateConverter) ToSrid(sourceSrid int, targetSrid int, coor
 }
 
 func (g *goprojCoordinateConverter) ToWGS84Cartesian(coord geom.Coord, sourceSrid int) (geom.Coord, error) {
-	if sourceSrid == 4978 {
+	if sourceSrid == coor.EcefSrid {
 		return coord, nil
 	}
 
-	return g.ToSrid(sourceSrid, 4978, coord)
+	return g.ToSrid(sourceSrid, coor.EcefSrid, coord)
 }
 
 func (g *goprojCoordinateConverter) Cleanup() {}
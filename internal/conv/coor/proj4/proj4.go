@@ -9,8 +9,10 @@ import (
 	"path"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/mfbonfigli/gocesiumtiler/v2/internal/assets"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/conv/coor"
 	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
 	proj "github.com/xeonx/proj4"
 )
@@ -19,8 +21,12 @@ const toRadians = math.Pi / 180
 const toDeg = 180 / math.Pi
 
 type proj4CoordinateConverter struct {
-	epsgDatabase   map[int]*epsgProjection
+	epsgDatabase map[int]*epsgProjection
+	// projections caches the projection objects by EPSG code, they are not safe for concurrent use
+	projections    map[int]*proj.Proj
 	assetTmpFolder string
+	// assetFolder is the folder of the unpacked assets, shared with the forks and the extensions of the converter
+	assetFolder string
 }
 
 func NewProj4CoordinateConverter() (*proj4CoordinateConverter, error) {
@@ -31,7 +37,7 @@ func NewProj4CoordinateConverter() (*proj4CoordinateConverter, error) {
 	}
 
 	// Set path for retrieving projection assets data
-	proj.SetFinder([]string{tempDir})
+	searchPaths.add(&searchPaths.assets, tempDir)
 
 	// Initialization of EPSG Proj4 database
 	db, err := loadEPSGProjectionDatabase()
@@ -40,7 +46,9 @@ func NewProj4CoordinateConverter() (*proj4CoordinateConverter, error) {
 	}
 	return &proj4CoordinateConverter{
 		epsgDatabase:   db,
+		projections:    map[int]*proj.Proj{},
 		assetTmpFolder: tempDir,
+		assetFolder:    tempDir,
 	}, nil
 }
 
@@ -101,6 +109,9 @@ func loadEPSGProjectionDatabase() (map[int]*epsgProjection, error) {
 
 func parseEPSGProjectionDatabaseRecord(databaseRecord string) (int, *epsgProjection, error) {
 	tokens := strings.Split(databaseRecord, "\t")
+	if len(tokens) < 3 {
+		return 0, nil, fmt.Errorf("error while parsing the epsg projection file: invalid record %q", databaseRecord)
+	}
 	code, err := strconv.Atoi(strings.Replace(tokens[0], "EPSG:", "", -1))
 	if err != nil {
 		return 0, nil, fmt.Errorf("error while parsing the epsg projection file: %v", err)
@@ -136,28 +147,119 @@ func (cc *proj4CoordinateConverter) ToSrid(sourceSrid int, targetSrid int, coord
 	return *converted, result
 }
 
-// Converts the input coordinate from the given srid to EPSG:4326 srid
+// Converts the input coordinate from the given srid to EPSG:4978, going through the WGS 84 geographic 3D CRS
+// EPSG:4979 so that the ellipsoidal height of the point is preserved
 func (cc *proj4CoordinateConverter) ToWGS84Cartesian(coord geom.Coord, sourceSrid int) (geom.Coord, error) {
-	if sourceSrid == 4978 {
+	if sourceSrid == coor.EcefSrid {
 		return coord, nil
 	}
 
-	res, err := cc.ToSrid(sourceSrid, 4326, coord)
+	res, err := cc.ToSrid(sourceSrid, coor.GeographicSrid, coord)
 	if err != nil {
 		return coord, err
 	}
-	res2, err := cc.ToSrid(4329, 4978, res)
-	return res2, err
+	return cc.ToSrid(coor.GeographicSrid, coor.EcefSrid, res)
+}
+
+// Fork returns a converter sharing the EPSG definitions and the unpacked assets of cc but initializing its own
+// projection objects, so that it can be used concurrently with cc. Cleaning up the fork only releases its
+// projection objects.
+func (cc *proj4CoordinateConverter) Fork() coor.CoordinateConverter {
+	return &proj4CoordinateConverter{
+		epsgDatabase: cc.epsgDatabase,
+		projections:  map[int]*proj.Proj{},
+		assetFolder:  cc.assetFolder,
+	}
+}
+
+// Extend returns a converter whose EPSG definitions are the ones of cc overridden and augmented by the given
+// proj4 definitions, sharing the unpacked assets of cc. Cleaning up the returned converter only releases its
+// projection objects.
+func (cc *proj4CoordinateConverter) Extend(definitions map[int]string) coor.CoordinateConverter {
+	db := make(map[int]*epsgProjection, len(cc.epsgDatabase)+len(definitions))
+	for code, projection := range cc.epsgDatabase {
+		db[code] = projection
+	}
+	for code, definition := range definitions {
+		db[code] = &epsgProjection{
+			EpsgCode:    code,
+			Description: fmt.Sprintf("EPSG:%d", code),
+			Proj4:       definition,
+		}
+	}
+	return &proj4CoordinateConverter{
+		epsgDatabase: db,
+		projections:  map[int]*proj.Proj{},
+		assetFolder:  cc.assetFolder,
+	}
+}
+
+// AddDatumGridPath makes proj4 search the datum grid shift files, such as the NTv2 and NADCON grids referenced by the
+// +nadgrids parameter of the definitions, in the given folder before the bundled assets, until the returned function
+// is called. The search folders are global, so they apply to all the converters: the folders added concurrently are
+// all searched, in the order they were added, and a grid not found the first time it is needed is not searched again.
+func (cc *proj4CoordinateConverter) AddDatumGridPath(dir string) func() {
+	searchPaths.add(&searchPaths.grids, dir)
+	var once sync.Once
+	return func() {
+		once.Do(func() { searchPaths.remove(&searchPaths.grids, dir) })
+	}
+}
+
+// finderPaths collects the folders proj4 searches its data files in, i.e. the datum grid folders of the active runs
+// and the asset folders of the converters, since the proj4 finder is a single process wide list of folders
+type finderPaths struct {
+	sync.Mutex
+	grids  []string
+	assets []string
+}
+
+var searchPaths = &finderPaths{}
+
+// add appends the folder to the given list and updates the proj4 finder
+func (f *finderPaths) add(paths *[]string, dir string) {
+	f.Lock()
+	defer f.Unlock()
+	*paths = append(*paths, dir)
+	f.apply()
+}
+
+// remove removes one occurrence of the folder from the given list and updates the proj4 finder
+func (f *finderPaths) remove(paths *[]string, dir string) {
+	f.Lock()
+	defer f.Unlock()
+	for i, p := range *paths {
+		if p == dir {
+			*paths = append((*paths)[:i], (*paths)[i+1:]...)
+			break
+		}
+	}
+	f.apply()
+}
+
+// apply sets the proj4 finder to the grid folders followed by the asset folders, without duplicates
+func (f *finderPaths) apply() {
+	paths := []string{}
+	seen := map[string]bool{}
+	for _, p := range append(append([]string{}, f.grids...), f.assets...) {
+		if !seen[p] {
+			seen[p] = true
+			paths = append(paths, p)
+		}
+	}
+	proj.SetFinder(paths)
 }
 
 // Releases all projection objects from memory
 func (cc *proj4CoordinateConverter) Cleanup() {
-	for _, val := range cc.epsgDatabase {
-		if val.Projection != nil {
-			val.Projection.Close()
-		}
+	for code, projection := range cc.projections {
+		projection.Close()
+		delete(cc.projections, code)
+	}
+	if cc.assetTmpFolder != "" {
+		searchPaths.remove(&searchPaths.assets, cc.assetTmpFolder)
+		os.Remove(cc.assetTmpFolder)
 	}
-	os.Remove(cc.assetTmpFolder)
 }
 
 func executeConversion(coord *geom.Coord, sourceProj *proj.Proj, destinationProj *proj.Proj) (*geom.Coord, error) {
@@ -218,17 +320,19 @@ func getCoordinateFromRadiansToSridFormat(coord float64, srid *proj.Proj) float6
 	return angle
 }
 
-// Returns the projection corresponding to the given EPSG code, storing it in the relevant EpsgDatabase entry for caching
+// Returns the projection corresponding to the given EPSG code, storing it in the projections of the converter for caching
 func (cc *proj4CoordinateConverter) initProjection(code int) (*proj.Proj, error) {
+	if projection, ok := cc.projections[code]; ok {
+		return projection, nil
+	}
 	val, ok := cc.epsgDatabase[code]
 	if !ok {
 		return &proj.Proj{}, errors.New("epsg code not found")
-	} else if val.Projection == nil {
-		projection, err := proj.InitPlus(val.Proj4)
-		if err != nil {
-			return &proj.Proj{}, errors.New("unable to init projection")
-		}
-		val.Projection = projection
 	}
-	return val.Projection, nil
+	projection, err := proj.InitPlus(val.Proj4)
+	if err != nil {
+		return &proj.Proj{}, errors.New("unable to init projection")
+	}
+	cc.projections[code] = projection
+	return projection, nil
 }
@@ -1,8 +1,13 @@
 package proj4
 
 import (
+	"math"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/assets"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/conv/coor"
 	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
 	"github.com/mfbonfigli/gocesiumtiler/v2/internal/utils"
 )
@@ -84,3 +89,203 @@ func TestToWGS84Cartesian(t *testing.T) {
 	}
 	c.Cleanup()
 }
+
+func TestEcefTransformRoundTrip(t *testing.T) {
+	c, err := NewProj4CoordinateConverter()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	center, err := c.ToSrid(4326, 4978, geom.Coord{X: 11.25, Y: 46.5, Z: 250})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	transform := geom.NewTranslation(center.X, center.Y, center.Z)
+	inverse, err := transform.Inverse()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	for _, expected := range []geom.Coord{{X: 11.25, Y: 46.5, Z: 250}, {X: 11.2512, Y: 46.4987, Z: 312.5}, {X: 11.24, Y: 46.51, Z: -20}} {
+		ecef, err := c.ToSrid(4326, 4978, expected)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		// tile local coordinates back to geographic ones
+		local := inverse.Apply(ecef)
+		actual, err := c.ToSrid(4978, 4326, transform.Apply(local))
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if err := utils.CompareCoord(actual, expected, 1e-6); err != nil {
+			t.Errorf("expected coordinate %v, got %v. Err: %v", expected, actual, err)
+		}
+	}
+}
+
+func TestGeographicToEcef(t *testing.T) {
+	c, err := NewProj4CoordinateConverter()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	defer c.Cleanup()
+	cases := []struct {
+		geographic geom.Coord
+		ecef       geom.Coord
+	}{
+		// on the equator at the prime meridian, the X axis crosses the ellipsoid at the semi-major axis
+		{geographic: geom.Coord{X: 0, Y: 0, Z: 0}, ecef: geom.Coord{X: 6378137, Y: 0, Z: 0}},
+		// heights are measured along the normal to the ellipsoid
+		{geographic: geom.Coord{X: 90, Y: 0, Z: 100}, ecef: geom.Coord{X: 0, Y: 6378237, Z: 0}},
+		// the Z axis crosses the ellipsoid at the semi-minor axis at the north pole
+		{geographic: geom.Coord{X: 0, Y: 90, Z: 0}, ecef: geom.Coord{X: 0, Y: 0, Z: 6356752.314245179}},
+		{geographic: geom.Coord{X: 11.25, Y: 46.5, Z: 250}, ecef: geom.Coord{X: 4313831.742033427, Y: 858074.484285383, Z: 4603861.742238379}},
+		{geographic: geom.Coord{X: -74.0445, Y: 40.6892, Z: 93}, ecef: geom.Coord{X: 1331360.0379008688, Y: -4656651.149354034, Z: 4136374.0304966415}},
+	}
+	for _, tc := range cases {
+		actual, err := c.ToSrid(coor.GeographicSrid, coor.EcefSrid, tc.geographic)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if err := utils.CompareCoord(actual, tc.ecef, coordTolerance); err != nil {
+			t.Errorf("expected %v to map to %v, got %v. Err: %v", tc.geographic, tc.ecef, actual, err)
+		}
+		actual, err = c.ToWGS84Cartesian(tc.geographic, coor.GeographicSrid)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if err := utils.CompareCoord(actual, tc.ecef, coordTolerance); err != nil {
+			t.Errorf("expected %v to map to %v, got %v. Err: %v", tc.geographic, tc.ecef, actual, err)
+		}
+		if tc.geographic.Y == 90 {
+			// the longitude is undefined at the pole
+			continue
+		}
+		// the inverse transform, used for the bounding regions, restores the geographic coordinates
+		actual, err = c.ToSrid(coor.EcefSrid, coor.GeographicSrid, tc.ecef)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if err := utils.CompareCoord(actual, tc.geographic, 1e-6); err != nil {
+			t.Errorf("expected %v to map to %v, got %v. Err: %v", tc.ecef, tc.geographic, actual, err)
+		}
+	}
+	if actual, err := c.ToWGS84Cartesian(geom.Coord{X: 1, Y: 2, Z: 3}, coor.EcefSrid); err != nil || actual != (geom.Coord{X: 1, Y: 2, Z: 3}) {
+		t.Errorf("expected EPSG:4978 coordinates to be returned unchanged, got %v (%v)", actual, err)
+	}
+}
+
+func TestFork(t *testing.T) {
+	c, err := NewProj4CoordinateConverter()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	defer c.Cleanup()
+	pt := geom.Coord{X: 123.474003, Y: 8.099314, Z: 0}
+	expected, err := c.ToSrid(4326, 3124, pt)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	forked := c.Fork()
+	actual, err := forked.ToSrid(4326, 3124, pt)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := utils.CompareCoord(actual, expected, coordTolerance); err != nil {
+		t.Errorf("expected coordinate %v, got %v. Err: %v", expected, actual, err)
+	}
+	if f := forked.(*proj4CoordinateConverter); f.projections[4326] == c.projections[4326] {
+		t.Errorf("expected the fork not to share the projection objects")
+	}
+	// cleaning up the fork must not release the projections of the original converter
+	forked.Cleanup()
+	if _, err := c.ToSrid(4326, 3124, pt); err != nil {
+		t.Errorf("unexpected error after the fork cleanup %v", err)
+	}
+}
+
+func TestToSridNad27ToNad83(t *testing.T) {
+	c, err := NewProj4CoordinateConverter()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	defer c.Cleanup()
+	// NAD27 applies the bundled Canadian NTv1 grid, the shift is -111d0'2.952"W 50d0'0.111"N
+	actual, err := c.ToSrid(4267, 4269, geom.Coord{X: -111, Y: 50, Z: 0})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	expected := geom.Coord{X: -111 - 2.952/3600, Y: 50 + 0.111/3600, Z: 0}
+	if math.Abs(actual.X-expected.X) > 1e-6 || math.Abs(actual.Y-expected.Y) > 1e-6 {
+		t.Errorf("expected coordinate %v, got %v", expected, actual)
+	}
+}
+
+func TestAddDatumGridPath(t *testing.T) {
+	c, err := NewProj4CoordinateConverter()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	defer c.Cleanup()
+	grid, err := assets.GetAssets().ReadFile("share/ntv1_can.dat")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "custom_ntv1.dat"), grid, 0666); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	extended := c.Extend(map[int]string{
+		900001: "+proj=longlat +ellps=clrk66 +nadgrids=custom_ntv1.dat +no_defs",
+		900002: "+proj=longlat +ellps=clrk66 +nadgrids=missing_ntv1.dat +no_defs",
+	})
+	defer extended.Cleanup()
+
+	remove := extended.(coor.DatumGridLocator).AddDatumGridPath(dir)
+	defer remove()
+	actual, err := extended.ToSrid(900001, 4269, geom.Coord{X: -111, Y: 50, Z: 0})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	expected := geom.Coord{X: -111 - 2.952/3600, Y: 50 + 0.111/3600, Z: 0}
+	if math.Abs(actual.X-expected.X) > 1e-6 || math.Abs(actual.Y-expected.Y) > 1e-6 {
+		t.Errorf("expected coordinate %v, got %v", expected, actual)
+	}
+	if _, err := extended.ToSrid(900002, 4269, geom.Coord{X: -111, Y: 50, Z: 0}); err == nil {
+		t.Errorf("expected error for a missing grid, got none")
+	}
+}
+
+func TestAddDatumGridPathConcurrently(t *testing.T) {
+	c1, err := NewProj4CoordinateConverter()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	defer c1.Cleanup()
+	c2, err := NewProj4CoordinateConverter()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	defer c2.Cleanup()
+	contains := func(paths []string, dir string) bool {
+		for _, p := range paths {
+			if p == dir {
+				return true
+			}
+		}
+		return false
+	}
+	dir1, dir2 := t.TempDir(), t.TempDir()
+	remove1 := c1.AddDatumGridPath(dir1)
+	remove2 := c2.AddDatumGridPath(dir2)
+	remove1()
+	remove1()
+	if !contains(searchPaths.grids, dir2) || contains(searchPaths.grids, dir1) {
+		t.Errorf("expected only the grid path of the run in progress to be searched, got %v", searchPaths.grids)
+	}
+	if !contains(searchPaths.assets, c1.assetFolder) || !contains(searchPaths.assets, c2.assetFolder) {
+		t.Errorf("expected the asset folders of both converters to be searched, got %v", searchPaths.assets)
+	}
+	remove2()
+	if contains(searchPaths.grids, dir2) {
+		t.Errorf("expected the grid path to be removed, got %v", searchPaths.grids)
+	}
+}
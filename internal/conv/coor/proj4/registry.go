@@ -0,0 +1,91 @@
+package proj4
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// LoadEpsgRegistry reads the proj4 definitions, by EPSG code, stored in the file at the given path, to be passed to
+// Extend. The file can be a proj init file, where each definition is preceded by its code in angle brackets and
+// terminated by <>, possibly spanning multiple lines, and # starts a comment:
+//
+//	# WGS 84
+//	<4326> +proj=longlat +datum=WGS84 +no_defs <>
+//
+// or a tab separated file with the EPSG:code, the description and the definition on each line, as the definitions
+// bundled with the tiler. Entries whose code is not a number, such as <WGS84>, are ignored.
+func LoadEpsgRegistry(path string) (map[int]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	definitions, err := parseEpsgRegistry(f)
+	if err != nil {
+		return nil, fmt.Errorf("invalid EPSG registry %s: %w", path, err)
+	}
+	return definitions, nil
+}
+
+func parseEpsgRegistry(r io.Reader) (map[int]string, error) {
+	definitions := map[int]string{}
+	initEntries := strings.Builder{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "EPSG:") && strings.Contains(line, "\t") {
+			code, projection, err := parseEPSGProjectionDatabaseRecord(line)
+			if err != nil {
+				return nil, err
+			}
+			definitions[code] = strings.TrimSpace(projection.Proj4)
+			continue
+		}
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		initEntries.WriteString(line)
+		initEntries.WriteString("\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	rest := initEntries.String()
+	for {
+		open := strings.Index(rest, "<")
+		if open < 0 {
+			break
+		}
+		if strings.TrimSpace(rest[:open]) != "" {
+			return nil, fmt.Errorf("unexpected text %q outside of an entry", strings.TrimSpace(rest[:open]))
+		}
+		end := strings.Index(rest[open:], ">")
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated entry code %q", strings.TrimSpace(rest[open:]))
+		}
+		key := rest[open+1 : open+end]
+		rest = rest[open+end+1:]
+		terminator := strings.Index(rest, "<>")
+		if terminator < 0 {
+			return nil, fmt.Errorf("entry <%s> is not terminated by <>", key)
+		}
+		definition := strings.Join(strings.Fields(rest[:terminator]), " ")
+		rest = rest[terminator+2:]
+		code, err := strconv.Atoi(strings.TrimSpace(key))
+		if err != nil {
+			continue
+		}
+		if definition == "" {
+			return nil, fmt.Errorf("entry <%d> has an empty definition", code)
+		}
+		definitions[code] = definition
+	}
+	if strings.TrimSpace(rest) != "" {
+		return nil, fmt.Errorf("unexpected text %q outside of an entry", strings.TrimSpace(rest))
+	}
+	return definitions, nil
+}
@@ -0,0 +1,91 @@
+package proj4
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseEpsgRegistry(t *testing.T) {
+	registry := `# custom definitions
+# WGS 84
+<4326> +proj=longlat +datum=WGS84 +no_defs <>
+# a definition spanning multiple lines
+<900001> +proj=tmerc +lat_0=0 +lon_0=9
+  +k=0.9996 +x_0=500000 +y_0=0 +ellps=GRS80 +units=m +no_defs <>
+<WGS84> +proj=longlat +datum=WGS84 +no_defs <>
+EPSG:900002	EPSG:900002: custom	+proj=longlat +ellps=GRS80 +no_defs 
+`
+	actual, err := parseEpsgRegistry(strings.NewReader(registry))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	expected := map[int]string{
+		4326:   "+proj=longlat +datum=WGS84 +no_defs",
+		900001: "+proj=tmerc +lat_0=0 +lon_0=9 +k=0.9996 +x_0=500000 +y_0=0 +ellps=GRS80 +units=m +no_defs",
+		900002: "+proj=longlat +ellps=GRS80 +no_defs",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v got %v", expected, actual)
+	}
+
+	for _, invalid := range []string{
+		"<4326> +proj=longlat +datum=WGS84 +no_defs",
+		"<4326 +proj=longlat",
+		"+proj=longlat +datum=WGS84 +no_defs <>",
+		"<4326> <>",
+		"EPSG:4326\t+proj=longlat",
+	} {
+		if _, err := parseEpsgRegistry(strings.NewReader(invalid)); err == nil {
+			t.Errorf("expected error for registry %q, got none", invalid)
+		}
+	}
+}
+
+func TestLoadEpsgRegistry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "epsg")
+	if err := os.WriteFile(path, []byte("<900001> +proj=longlat +ellps=GRS80 +no_defs <>\n"), 0666); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	actual, err := LoadEpsgRegistry(path)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if actual[900001] != "+proj=longlat +ellps=GRS80 +no_defs" {
+		t.Errorf("unexpected definitions %v", actual)
+	}
+	if _, err := LoadEpsgRegistry(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Errorf("expected error for missing registry, got none")
+	}
+}
+
+func TestExtend(t *testing.T) {
+	c, err := NewProj4CoordinateConverter()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	defer c.Cleanup()
+	original := c.epsgDatabase[4326].Proj4
+	extended := c.Extend(map[int]string{
+		4326:   "+proj=longlat +ellps=GRS80 +no_defs",
+		900001: "+proj=longlat +datum=WGS84 +no_defs",
+	}).(*proj4CoordinateConverter)
+	defer extended.Cleanup()
+	if actual := extended.epsgDatabase[900001]; actual == nil || actual.Proj4 != "+proj=longlat +datum=WGS84 +no_defs" {
+		t.Errorf("expected the added definition, got %v", actual)
+	}
+	if actual := extended.epsgDatabase[4326].Proj4; actual != "+proj=longlat +ellps=GRS80 +no_defs" {
+		t.Errorf("expected the overridden definition, got %s", actual)
+	}
+	if actual := extended.epsgDatabase[32633]; actual != c.epsgDatabase[32633] {
+		t.Errorf("expected the bundled definitions to be kept")
+	}
+	if c.epsgDatabase[4326].Proj4 != original || c.epsgDatabase[900001] != nil {
+		t.Errorf("expected the original converter to be unchanged")
+	}
+	if extended.assetTmpFolder != "" {
+		t.Errorf("expected the extended converter not to own the assets")
+	}
+}
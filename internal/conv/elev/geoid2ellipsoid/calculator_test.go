@@ -1,6 +1,10 @@
 package geoid2ellipsoid
 
 import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/mfbonfigli/gocesiumtiler/v2/internal/utils"
@@ -122,3 +126,55 @@ func TestBufferedCalc(t *testing.T) {
 		t.Errorf("expected elevation diff to be within %f from %f but got %f (diff=%f)", tolerance, expected, actual, diff)
 	}
 }
+
+func TestGridCalculator(t *testing.T) {
+	conv, err := test.GetTestCoordinateConverter()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	// a 3x3 grid with 0.5 degrees spacing whose lower left node is at lon 14 lat 42
+	path := filepath.Join(t.TempDir(), "geoid.gtx")
+	buf := &bytes.Buffer{}
+	binary.Write(buf, binary.BigEndian, []float64{42, 14, 0.5, 0.5})
+	binary.Write(buf, binary.BigEndian, []int32{3, 3})
+	binary.Write(buf, binary.BigEndian, []float32{
+		40, 41, 42,
+		44, 45, 46,
+		48, 49, gtxNoData,
+	})
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	c, err := NewGridCalculator(path, conv)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	actual, err := c.GetEllipsoidToGeoidOffset(42.25, 14.25, 4326)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if diff, err := utils.CompareWithTolerance(actual, 42.5, 1e-6); err != nil {
+		t.Errorf("expected undulation %f got %f (diff=%f)", 42.5, actual, diff)
+	}
+	actual, err = c.GetEllipsoidToGeoidOffset(42.5, 15, 4326)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if diff, err := utils.CompareWithTolerance(actual, 46, 1e-6); err != nil {
+		t.Errorf("expected undulation %f got %f (diff=%f)", 46.0, actual, diff)
+	}
+	if _, err := c.GetEllipsoidToGeoidOffset(42.75, 14.75, 4326); err == nil {
+		t.Errorf("expected error for missing undulation, got none")
+	}
+	if _, err := c.GetEllipsoidToGeoidOffset(41, 14.25, 4326); err == nil {
+		t.Errorf("expected error outside of the grid, got none")
+	}
+
+	if err := os.WriteFile(path, buf.Bytes()[:50], 0644); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, err := NewGridCalculator(path, conv); err == nil {
+		t.Errorf("expected error for truncated grid, got none")
+	}
+}
@@ -0,0 +1,156 @@
+package geoid2ellipsoid
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/conv/coor"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/raster"
+)
+
+// gtxNoData is the value marking the missing undulations in GTX files
+const gtxNoData = -88.8888
+
+// undulationGrid returns the geoid undulation at the given geographic coordinates in degrees,
+// or false if the coordinates fall outside of the grid or on missing data
+type undulationGrid interface {
+	undulation(lon, lat float64) (float64, bool)
+}
+
+// GridCalculator computes the geoid undulation interpolating bilinearly a grid of undulations in geographic
+// coordinates, such as a local high precision geoid model, instead of the built-in EGM model
+type GridCalculator struct {
+	grid undulationGrid
+	conv coor.CoordinateConverter
+}
+
+// NewGridCalculator reads the undulation grid stored at the given path, either a GTX file or, for any other
+// extension, a single band GeoTIFF in EPSG:4326
+func NewGridCalculator(path string, coordinateConverter coor.CoordinateConverter) (*GridCalculator, error) {
+	var grid undulationGrid
+	var err error
+	if strings.EqualFold(filepath.Ext(path), ".gtx") {
+		grid, err = readGtx(path)
+	} else {
+		grid, err = readGeoTiffGrid(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &GridCalculator{
+		grid: grid,
+		conv: coordinateConverter,
+	}, nil
+}
+
+func (g *GridCalculator) GetEllipsoidToGeoidOffset(y, x float64, sourceSrid int) (float64, error) {
+	coordinateInEPSG4326, err := g.conv.ToSrid(sourceSrid, 4326, geom.Coord{X: x, Y: y, Z: 0})
+	if err != nil {
+		return 0, err
+	}
+	n, ok := g.grid.undulation(coordinateInEPSG4326.X, coordinateInEPSG4326.Y)
+	if !ok {
+		return 0, fmt.Errorf("no geoid undulation available at lon %f lat %f", coordinateInEPSG4326.X, coordinateInEPSG4326.Y)
+	}
+	return n, nil
+}
+
+// gtxGrid is a grid in the GTX format: a big endian header with the latitude and longitude of the lower left node,
+// the latitude and longitude spacing, the number of rows and columns, followed by the float32 undulations row by row
+// from south to north, each row from west to east
+type gtxGrid struct {
+	lat0, lon0, dlat, dlon float64
+	rows, cols             int
+	values                 []float32
+}
+
+func readGtx(path string) (*gtxGrid, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	header := struct {
+		Lat0, Lon0, DLat, DLon float64
+		Rows, Cols             int32
+	}{}
+	if err := binary.Read(f, binary.BigEndian, &header); err != nil {
+		return nil, fmt.Errorf("unable to read GTX header of %s: %w", path, err)
+	}
+	if header.Rows < 2 || header.Cols < 2 || header.DLat <= 0 || header.DLon <= 0 {
+		return nil, fmt.Errorf("invalid GTX grid %s: %d rows, %d columns, spacing %f %f", path, header.Rows, header.Cols, header.DLat, header.DLon)
+	}
+	g := &gtxGrid{
+		lat0:   header.Lat0,
+		lon0:   header.Lon0,
+		dlat:   header.DLat,
+		dlon:   header.DLon,
+		rows:   int(header.Rows),
+		cols:   int(header.Cols),
+		values: make([]float32, int(header.Rows)*int(header.Cols)),
+	}
+	if err := binary.Read(f, binary.BigEndian, g.values); err != nil {
+		if err == io.ErrUnexpectedEOF || err == io.EOF {
+			return nil, fmt.Errorf("GTX grid %s is truncated", path)
+		}
+		return nil, err
+	}
+	return g, nil
+}
+
+func (g *gtxGrid) undulation(lon, lat float64) (float64, bool) {
+	// grids covering the whole globe may store the longitudes in the [0, 360) range
+	if lon < g.lon0 {
+		lon += 360
+	}
+	fx, fy := (lon-g.lon0)/g.dlon, (lat-g.lat0)/g.dlat
+	if fx < 0 || fy < 0 || fx > float64(g.cols-1) || fy > float64(g.rows-1) {
+		return 0, false
+	}
+	// the last row and column are interpolated from the previous cell
+	c0, r0 := int(math.Min(math.Floor(fx), float64(g.cols-2))), int(math.Min(math.Floor(fy), float64(g.rows-2)))
+	tx, ty := fx-float64(c0), fy-float64(r0)
+	n := 0.0
+	for _, node := range []struct {
+		dc, dr int
+		w      float64
+	}{
+		{0, 0, (1 - tx) * (1 - ty)},
+		{1, 0, tx * (1 - ty)},
+		{0, 1, (1 - tx) * ty},
+		{1, 1, tx * ty},
+	} {
+		if node.w == 0 {
+			continue
+		}
+		v := float64(g.values[(r0+node.dr)*g.cols+c0+node.dc])
+		if math.Abs(v-gtxNoData) < 1e-3 {
+			return 0, false
+		}
+		n += node.w * v
+	}
+	return n, true
+}
+
+// geoTiffGrid is a grid stored in the first band of a GeoTIFF in EPSG:4326
+type geoTiffGrid struct {
+	*raster.GeoTiff
+}
+
+func readGeoTiffGrid(path string) (*geoTiffGrid, error) {
+	g, err := raster.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &geoTiffGrid{GeoTiff: g}, nil
+}
+
+func (g *geoTiffGrid) undulation(lon, lat float64) (float64, bool) {
+	return g.Bilinear(0, lon, lat)
+}
@@ -0,0 +1,180 @@
+package geom
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// KDTree is a static 3D k-d tree built over a slice of points, used to perform nearest neighbor queries.
+// The tree is immutable once built and is therefore safe for concurrent queries.
+type KDTree struct {
+	pts []Point32
+	// idx stores the indices of the points, arranged so that each subtree occupies a contiguous
+	// range whose median element is the splitting node of the subtree
+	idx []int
+}
+
+// Neighbor is a point returned by a nearest neighbor query
+type Neighbor struct {
+	// Index is the index of the neighbor in the slice the tree was built with
+	Index int
+	// DistSq is the squared distance of the neighbor from the query point
+	DistSq float64
+}
+
+// NewKDTree builds a new KDTree indexing the given points. The slice must not be modified afterwards.
+func NewKDTree(pts []Point32) *KDTree {
+	idx := make([]int, len(pts))
+	for i := range idx {
+		idx[i] = i
+	}
+	t := &KDTree{
+		pts: pts,
+		idx: idx,
+	}
+	t.build(0, len(idx), 0)
+	return t
+}
+
+// Len returns the number of points stored in the tree
+func (t *KDTree) Len() int {
+	return len(t.pts)
+}
+
+// Nearest returns up to k neighbors closest to the given coordinates, sorted by increasing distance.
+// If skip is a valid point index, that point is excluded from the results, which is useful to
+// search the neighbors of a point belonging to the tree itself.
+func (t *KDTree) Nearest(x, y, z float32, k int, skip int) []Neighbor {
+	return t.NearestActive(x, y, z, k, skip, nil)
+}
+
+// NearestActive returns the neighbors as Nearest does, considering only the points flagged in active, which is
+// indexed as the slice the tree was built with. A nil active considers all the points.
+func (t *KDTree) NearestActive(x, y, z float32, k int, skip int, active []bool) []Neighbor {
+	if k <= 0 {
+		return nil
+	}
+	h := make(neighborHeap, 0, k+1)
+	t.search(0, len(t.idx), 0, [3]float32{x, y, z}, k, skip, active, &h)
+	sort.Slice(h, func(i, j int) bool {
+		return h[i].DistSq < h[j].DistSq
+	})
+	return h
+}
+
+// Within returns the points whose distance from the given coordinates does not exceed the given radius, in no
+// particular order. If skip is a valid point index, that point is excluded from the results.
+func (t *KDTree) Within(x, y, z float32, radius float64, skip int) []Neighbor {
+	return t.WithinActive(x, y, z, radius, skip, nil)
+}
+
+// WithinActive returns the points as Within does, considering only the points flagged in active, which is indexed
+// as the slice the tree was built with. A nil active considers all the points.
+func (t *KDTree) WithinActive(x, y, z float32, radius float64, skip int, active []bool) []Neighbor {
+	if radius < 0 {
+		return nil
+	}
+	var res []Neighbor
+	t.searchWithin(0, len(t.idx), 0, [3]float32{x, y, z}, radius*radius, skip, active, &res)
+	return res
+}
+
+func (t *KDTree) build(lo, hi, axis int) {
+	if hi-lo <= 1 {
+		return
+	}
+	sub := t.idx[lo:hi]
+	sort.Slice(sub, func(i, j int) bool {
+		return coordinate(t.pts[sub[i]], axis) < coordinate(t.pts[sub[j]], axis)
+	})
+	mid := (lo + hi) / 2
+	next := (axis + 1) % 3
+	t.build(lo, mid, next)
+	t.build(mid+1, hi, next)
+}
+
+func (t *KDTree) search(lo, hi, axis int, q [3]float32, k int, skip int, active []bool, h *neighborHeap) {
+	if lo >= hi {
+		return
+	}
+	mid := (lo + hi) / 2
+	i := t.idx[mid]
+	p := t.pts[i]
+	if i != skip && (active == nil || active[i]) {
+		dx, dy, dz := float64(p.X-q[0]), float64(p.Y-q[1]), float64(p.Z-q[2])
+		d := dx*dx + dy*dy + dz*dz
+		if h.Len() < k {
+			heap.Push(h, Neighbor{Index: i, DistSq: d})
+		} else if d < (*h)[0].DistSq {
+			(*h)[0] = Neighbor{Index: i, DistSq: d}
+			heap.Fix(h, 0)
+		}
+	}
+	diff := float64(q[axis] - coordinate(p, axis))
+	next := (axis + 1) % 3
+	// visit first the side of the split the query point falls into
+	if diff < 0 {
+		t.search(lo, mid, next, q, k, skip, active, h)
+		if h.Len() < k || diff*diff < (*h)[0].DistSq {
+			t.search(mid+1, hi, next, q, k, skip, active, h)
+		}
+	} else {
+		t.search(mid+1, hi, next, q, k, skip, active, h)
+		if h.Len() < k || diff*diff < (*h)[0].DistSq {
+			t.search(lo, mid, next, q, k, skip, active, h)
+		}
+	}
+}
+
+func (t *KDTree) searchWithin(lo, hi, axis int, q [3]float32, radiusSq float64, skip int, active []bool, res *[]Neighbor) {
+	if lo >= hi {
+		return
+	}
+	mid := (lo + hi) / 2
+	i := t.idx[mid]
+	p := t.pts[i]
+	if i != skip && (active == nil || active[i]) {
+		dx, dy, dz := float64(p.X-q[0]), float64(p.Y-q[1]), float64(p.Z-q[2])
+		if d := dx*dx + dy*dy + dz*dz; d <= radiusSq {
+			*res = append(*res, Neighbor{Index: i, DistSq: d})
+		}
+	}
+	diff := float64(q[axis] - coordinate(p, axis))
+	next := (axis + 1) % 3
+	// the side of the split the query point does not fall into is visited only if it intersects the sphere
+	if diff < 0 || diff*diff <= radiusSq {
+		t.searchWithin(lo, mid, next, q, radiusSq, skip, active, res)
+	}
+	if diff >= 0 || diff*diff <= radiusSq {
+		t.searchWithin(mid+1, hi, next, q, radiusSq, skip, active, res)
+	}
+}
+
+func coordinate(p Point32, axis int) float32 {
+	switch axis {
+	case 0:
+		return p.X
+	case 1:
+		return p.Y
+	}
+	return p.Z
+}
+
+// neighborHeap is a max heap of neighbors, the farthest neighbor is at the root
+type neighborHeap []Neighbor
+
+func (h neighborHeap) Len() int           { return len(h) }
+func (h neighborHeap) Less(i, j int) bool { return h[i].DistSq > h[j].DistSq }
+func (h neighborHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *neighborHeap) Push(x any) {
+	*h = append(*h, x.(Neighbor))
+}
+
+func (h *neighborHeap) Pop() any {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
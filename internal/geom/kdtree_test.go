@@ -0,0 +1,113 @@
+package geom
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestKDTreeNearest(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	pts := make([]Point32, 500)
+	for i := range pts {
+		pts[i] = Point32{X: r.Float32() * 100, Y: r.Float32() * 100, Z: r.Float32() * 10}
+	}
+	tree := NewKDTree(pts)
+	if actual := tree.Len(); actual != len(pts) {
+		t.Errorf("expected %d points got %d", len(pts), actual)
+	}
+
+	for q := 0; q < 20; q++ {
+		// brute force the expected result
+		expected := make([]Neighbor, 0, len(pts))
+		for i, p := range pts {
+			if i == q {
+				continue
+			}
+			dx, dy, dz := float64(p.X-pts[q].X), float64(p.Y-pts[q].Y), float64(p.Z-pts[q].Z)
+			expected = append(expected, Neighbor{Index: i, DistSq: dx*dx + dy*dy + dz*dz})
+		}
+		sort.Slice(expected, func(i, j int) bool {
+			return expected[i].DistSq < expected[j].DistSq
+		})
+		actual := tree.Nearest(pts[q].X, pts[q].Y, pts[q].Z, 8, q)
+		if len(actual) != 8 {
+			t.Fatalf("expected %d neighbors got %d", 8, len(actual))
+		}
+		for i := range actual {
+			if actual[i].DistSq != expected[i].DistSq {
+				t.Errorf("query %d, neighbor %d: expected distance %v got %v", q, i, expected[i].DistSq, actual[i].DistSq)
+			}
+		}
+	}
+}
+
+func TestKDTreeNearestFewPoints(t *testing.T) {
+	pts := []Point32{{X: 0}, {X: 1}, {X: 3}}
+	tree := NewKDTree(pts)
+	actual := tree.Nearest(0, 0, 0, 5, -1)
+	if len(actual) != 3 {
+		t.Fatalf("expected %d neighbors got %d", 3, len(actual))
+	}
+	for i, expected := range []int{0, 1, 2} {
+		if actual[i].Index != expected {
+			t.Errorf("expected neighbor %d to be %d got %d", i, expected, actual[i].Index)
+		}
+	}
+	if actual := tree.Nearest(0, 0, 0, 0, -1); len(actual) != 0 {
+		t.Errorf("expected no neighbors got %d", len(actual))
+	}
+}
+
+func TestKDTreeWithin(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	pts := make([]Point32, 500)
+	for i := range pts {
+		pts[i] = Point32{X: r.Float32() * 100, Y: r.Float32() * 100, Z: r.Float32() * 10}
+	}
+	tree := NewKDTree(pts)
+	radius := 7.5
+	for q := 0; q < 20; q++ {
+		// brute force the expected result
+		expected := []int{}
+		for i, p := range pts {
+			dx, dy, dz := float64(p.X-pts[q].X), float64(p.Y-pts[q].Y), float64(p.Z-pts[q].Z)
+			if i != q && dx*dx+dy*dy+dz*dz <= radius*radius {
+				expected = append(expected, i)
+			}
+		}
+		actual := []int{}
+		for _, n := range tree.Within(pts[q].X, pts[q].Y, pts[q].Z, radius, q) {
+			actual = append(actual, n.Index)
+		}
+		sort.Ints(actual)
+		if len(actual) != len(expected) {
+			t.Fatalf("query %d: expected %d neighbors got %d", q, len(expected), len(actual))
+		}
+		for i := range actual {
+			if actual[i] != expected[i] {
+				t.Errorf("query %d: expected neighbor %d got %d", q, expected[i], actual[i])
+			}
+		}
+	}
+	if actual := tree.Within(0, 0, 0, -1, -1); len(actual) != 0 {
+		t.Errorf("expected no neighbors got %d", len(actual))
+	}
+}
+
+func TestKDTreeActive(t *testing.T) {
+	pts := []Point32{{X: 0}, {X: 1}, {X: 2}, {X: 3}}
+	tree := NewKDTree(pts)
+	active := []bool{true, false, true, true}
+	nearest := tree.NearestActive(0, 0, 0, 2, 0, active)
+	if len(nearest) != 2 || nearest[0].Index != 2 || nearest[1].Index != 3 {
+		t.Errorf("expected the inactive point to be skipped, got %v", nearest)
+	}
+	within := tree.WithinActive(0, 0, 0, 1.5, -1, active)
+	if len(within) != 1 || within[0].Index != 0 {
+		t.Errorf("expected only the active point within the radius, got %v", within)
+	}
+	if all := tree.WithinActive(0, 0, 0, 1.5, -1, nil); len(all) != 2 {
+		t.Errorf("expected a nil active to consider all the points, got %v", all)
+	}
+}
@@ -4,9 +4,20 @@ import (
 	"fmt"
 )
 
+// Classification flags of a point. The bit layout matches the one used by LAS 1.4 point formats 6-10.
+const (
+	FlagSynthetic uint8 = 1 << iota
+	FlagKeyPoint
+	FlagWithheld
+	FlagOverlap
+)
+
 // Point64 contains data of a Point Cloud Point, namely X,Y,Z coords,
-// R,G,B color components, Intensity and Classification. Coordinates are expressed
-// as double precision float64 numbers.
+// R,G,B color components, Intensity, Classification and classification Flags.
+// Coordinates are expressed as double precision float64 numbers.
+// FileIndex is the index of the file the point was read from, when reading multiple files.
+// Extra is the value of the extra bytes dimension selected when reading the file, if any.
+// PointSourceId identifies the source of the point, typically the flight line or scan it was acquired in.
 type Point64 struct {
 	X              float64
 	Y              float64
@@ -16,12 +27,23 @@ type Point64 struct {
 	B              uint8
 	Intensity      uint8
 	Classification uint8
+	Flags          uint8
+	FileIndex      int
+	Extra          float64
+	PointSourceId  uint16
+	// GpsTime is the GPS time of the point, 0 if the point format of the file does not store it
+	GpsTime float64
+}
+
+// HasFlag returns true if the point has all the given classification flags set
+func (p Point64) HasFlag(flag uint8) bool {
+	return p.Flags&flag == flag
 }
 
 // ToPointFromBaseline returns a Point from this Point64 with coordinates expressed as
 // offset from a baseline
 func (p Point64) ToPointFromBaseline(baseline Point64) Point32 {
-	return NewPoint32(
+	pt := NewPoint32(
 		float32(p.X-baseline.X),
 		float32(p.Y-baseline.Y),
 		float32(p.Z-baseline.Z),
@@ -31,11 +53,16 @@ func (p Point64) ToPointFromBaseline(baseline Point64) Point32 {
 		p.Intensity,
 		p.Classification,
 	)
+	pt.FileIndex = uint16(p.FileIndex)
+	pt.PointSourceId = p.PointSourceId
+	return pt
 }
 
 // Point32 Contains data of a Point32 Cloud Point32, namely X,Y,Z coords,
 // R,G,B color components, Intensity and Classification. X,Y,Z coordinates
-// are expressed as float32 single precision numbers
+// are expressed as float32 single precision numbers. FileIndex is the index of
+// the file the point was read from, it fits in the padding of the struct.
+// PointSourceId identifies the source of the point, typically the flight line or scan it was acquired in.
 type Point32 struct {
 	X              float32
 	Y              float32
@@ -45,6 +72,8 @@ type Point32 struct {
 	B              uint8
 	Intensity      uint8
 	Classification uint8
+	FileIndex      uint16
+	PointSourceId  uint16
 }
 
 // Builds a new Point from the given coordinates, colors, intensity and classification values
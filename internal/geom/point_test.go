@@ -12,6 +12,7 @@ func TestToPointFromBaseline(t *testing.T) {
 		B:              3,
 		Intensity:      4,
 		Classification: 5,
+		FileIndex:      3,
 	}
 	baseline := &Point64{
 		X:              5,
@@ -24,6 +25,7 @@ func TestToPointFromBaseline(t *testing.T) {
 		Classification: 2,
 	}
 	expected := NewPoint32(5, 6, 7, 1, 2, 3, 4, 5)
+	expected.FileIndex = 3
 	pt := p.ToPointFromBaseline(*baseline)
 	if pt != expected {
 		t.Errorf("unexpected point, expected %v got %v", expected, pt)
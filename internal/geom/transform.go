@@ -0,0 +1,46 @@
+package geom
+
+import "fmt"
+
+// Transform is a 4x4 affine transformation matrix stored in column-major order, as the transform property of 3D Tiles
+type Transform [16]float64
+
+// NewTranslation returns the transform translating the coordinates by the given offsets
+func NewTranslation(x, y, z float64) Transform {
+	return Transform{
+		1, 0, 0, 0,
+		0, 1, 0, 0,
+		0, 0, 1, 0,
+		x, y, z, 1,
+	}
+}
+
+// Apply returns the coordinate transformed by the matrix
+func (t Transform) Apply(c Coord) Coord {
+	return Coord{
+		X: t[0]*c.X + t[4]*c.Y + t[8]*c.Z + t[12],
+		Y: t[1]*c.X + t[5]*c.Y + t[9]*c.Z + t[13],
+		Z: t[2]*c.X + t[6]*c.Y + t[10]*c.Z + t[14],
+	}
+}
+
+// Inverse returns the inverse of the affine transform. Returns an error if the matrix is singular.
+func (t Transform) Inverse() (Transform, error) {
+	// the linear part is inverted with its adjugate, the translation is then mapped back through it
+	a, b, c := t[0], t[4], t[8]
+	d, e, f := t[1], t[5], t[9]
+	g, h, i := t[2], t[6], t[10]
+	det := a*(e*i-f*h) - b*(d*i-f*g) + c*(d*h-e*g)
+	if det == 0 {
+		return Transform{}, fmt.Errorf("the transform is not invertible")
+	}
+	inv := Transform{
+		(e*i - f*h) / det, (f*g - d*i) / det, (d*h - e*g) / det, 0,
+		(c*h - b*i) / det, (a*i - c*g) / det, (b*g - a*h) / det, 0,
+		(b*f - c*e) / det, (c*d - a*f) / det, (a*e - b*d) / det, 0,
+		0, 0, 0, 1,
+	}
+	tr := inv.Apply(Coord{X: t[12], Y: t[13], Z: t[14]})
+	inv[12], inv[13], inv[14] = -tr.X, -tr.Y, -tr.Z
+	return inv, nil
+}
@@ -0,0 +1,43 @@
+package geom
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTranslation(t *testing.T) {
+	tr := NewTranslation(10, -20, 30)
+	if actual, expected := tr.Apply(Coord{X: 1, Y: 2, Z: 3}), (Coord{X: 11, Y: -18, Z: 33}); actual != expected {
+		t.Errorf("expected %v got %v", expected, actual)
+	}
+	inv, err := tr.Inverse()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if expected := NewTranslation(-10, 20, -30); inv != expected {
+		t.Errorf("expected inverse %v got %v", expected, inv)
+	}
+}
+
+func TestTransformInverse(t *testing.T) {
+	// rotation of 90 degrees around z, scaling by 2 and translation
+	tr := Transform{
+		0, 2, 0, 0,
+		-2, 0, 0, 0,
+		0, 0, 2, 0,
+		100, 200, 300, 1,
+	}
+	inv, err := tr.Inverse()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	for _, c := range []Coord{{X: 0, Y: 0, Z: 0}, {X: 1, Y: 2, Z: 3}, {X: -4500.5, Y: 12.25, Z: 7}} {
+		back := inv.Apply(tr.Apply(c))
+		if math.Abs(back.X-c.X) > 1e-9 || math.Abs(back.Y-c.Y) > 1e-9 || math.Abs(back.Z-c.Z) > 1e-9 {
+			t.Errorf("expected %v got %v", c, back)
+		}
+	}
+	if _, err := (Transform{}).Inverse(); err == nil {
+		t.Errorf("expected error for a singular transform, got none")
+	}
+}
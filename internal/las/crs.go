@@ -0,0 +1,199 @@
+package las
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// userDefinedGeoKey is the value of the GeoTIFF keys denoting a user defined CRS, which has no EPSG code
+const userDefinedGeoKey = 32767
+
+// ReadEpsgCode returns the EPSG code of the CRS of the points declared in the GeoTIFF keys of the given LAS file,
+// the projected CRS taking precedence over the geographic one, or in the OGC WKT describing the CRS, stored in a VLR
+// or, for LAS 1.4 files, in an EVLR after the points. The WKT takes precedence if the header declares the CRS as WKT,
+// otherwise it is only used if the GeoTIFF keys declare no EPSG code. Returns 0 if the file does not declare an EPSG
+// code, e.g. because it has no CRS records or it declares a user defined CRS.
+func ReadEpsgCode(fileName string) (int, error) {
+	las := lasFile{fileName: fileName}
+	var err error
+	if las.f, err = os.Open(fileName); err != nil {
+		return 0, err
+	}
+	defer las.close()
+	if err := las.readHeader(); err != nil {
+		return 0, err
+	}
+	if err := las.readVLRs(); err != nil {
+		return 0, err
+	}
+	if err := las.readEVLRs(); err != nil {
+		return 0, err
+	}
+	return las.epsgCode(), nil
+}
+
+// epsgCode returns the EPSG code of the CRS declared in the WKT, if the header declares the CRS as WKT or the
+// GeoTIFF keys do not declare an EPSG code, or in the GeoTIFF keys otherwise
+func (las *lasFile) epsgCode() int {
+	code := 0
+	if las.Header.GlobalEncoding.CoordinateReferenceSystemMethod() != WellKnownText {
+		code = las.geokeys.epsgCode()
+	}
+	if code == 0 && las.wkt != "" {
+		if node, err := parseWkt(las.wkt); err == nil {
+			code = node.epsgCode()
+		}
+	}
+	return code
+}
+
+// epsgCode returns the EPSG code declared by the ProjectedCSTypeGeoKey or, if missing, by the GeographicTypeGeoKey,
+// or 0 if none is declared
+func (gk *geoKeys) epsgCode() int {
+	dir := gk.GeoKeyDirectory
+	if len(dir) < 4 {
+		return 0
+	}
+	codes := map[uint16]int{}
+	// each key is stored as key ID, location, count and value, the value is stored in place if the location is 0
+	for i := 1; i <= int(dir[3]) && 4*i+3 < len(dir); i++ {
+		key, location, value := dir[4*i], dir[4*i+1], dir[4*i+3]
+		if location == 0 && value != 0 && value != userDefinedGeoKey {
+			codes[key] = int(value)
+		}
+	}
+	if code, ok := codes[tProjectedCSTypeGeoKey]; ok {
+		return code
+	}
+	return codes[tGeographicTypeGeoKey]
+}
+
+// wktNode is a node of an OGC WKT, e.g. PROJCS["name",GEOGCS[...],AUTHORITY["EPSG","32633"]], with the keyword, the
+// values, unquoted, and the nested nodes
+type wktNode struct {
+	keyword  string
+	values   []string
+	children []*wktNode
+}
+
+// parseWkt parses a WKT 1 or WKT 2 string, with square or round brackets
+func parseWkt(wkt string) (*wktNode, error) {
+	p := &wktParser{s: wkt}
+	node, err := p.node()
+	if err != nil {
+		return nil, err
+	}
+	if p.skipSpaces(); p.pos != len(p.s) {
+		return nil, fmt.Errorf("unexpected %q at position %d of the WKT", p.s[p.pos], p.pos)
+	}
+	return node, nil
+}
+
+type wktParser struct {
+	s   string
+	pos int
+}
+
+func (p *wktParser) skipSpaces() {
+	for p.pos < len(p.s) && strings.ContainsRune(" \t\r\n", rune(p.s[p.pos])) {
+		p.pos++
+	}
+}
+
+// token returns the text up to the next delimiter, trimmed
+func (p *wktParser) token() string {
+	start := p.pos
+	for p.pos < len(p.s) && !strings.ContainsRune(",[]()", rune(p.s[p.pos])) {
+		p.pos++
+	}
+	return strings.TrimSpace(p.s[start:p.pos])
+}
+
+// quoted returns the quoted string starting at the current position, where "" escapes a quote
+func (p *wktParser) quoted() (string, error) {
+	var b strings.Builder
+	for p.pos++; p.pos < len(p.s); p.pos++ {
+		if p.s[p.pos] != '"' {
+			b.WriteByte(p.s[p.pos])
+			continue
+		}
+		if p.pos+1 < len(p.s) && p.s[p.pos+1] == '"' {
+			b.WriteByte('"')
+			p.pos++
+			continue
+		}
+		p.pos++
+		return b.String(), nil
+	}
+	return "", fmt.Errorf("unterminated string in the WKT")
+}
+
+func (p *wktParser) node() (*wktNode, error) {
+	p.skipSpaces()
+	n := &wktNode{keyword: strings.ToUpper(p.token())}
+	if n.keyword == "" || p.pos >= len(p.s) || (p.s[p.pos] != '[' && p.s[p.pos] != '(') {
+		return nil, fmt.Errorf("expected a WKT node at position %d", p.pos)
+	}
+	p.pos++
+	for {
+		p.skipSpaces()
+		if p.pos >= len(p.s) {
+			return nil, fmt.Errorf("unterminated WKT node %s", n.keyword)
+		}
+		if p.s[p.pos] == '"' {
+			v, err := p.quoted()
+			if err != nil {
+				return nil, err
+			}
+			n.values = append(n.values, v)
+		} else {
+			start := p.pos
+			v := p.token()
+			if p.pos < len(p.s) && (p.s[p.pos] == '[' || p.s[p.pos] == '(') {
+				p.pos = start
+				child, err := p.node()
+				if err != nil {
+					return nil, err
+				}
+				n.children = append(n.children, child)
+			} else {
+				n.values = append(n.values, v)
+			}
+		}
+		p.skipSpaces()
+		if p.pos >= len(p.s) {
+			return nil, fmt.Errorf("unterminated WKT node %s", n.keyword)
+		}
+		switch p.s[p.pos] {
+		case ',':
+			p.pos++
+		case ']', ')':
+			p.pos++
+			return n, nil
+		default:
+			return nil, fmt.Errorf("unexpected %q at position %d of the WKT", p.s[p.pos], p.pos)
+		}
+	}
+}
+
+// epsgCode returns the EPSG code of the CRS, declared by its AUTHORITY (WKT 1) or ID (WKT 2) node, or for compound
+// CRSs without a code, the code of their first, horizontal, component. Returns 0 if no EPSG code is declared.
+func (n *wktNode) epsgCode() int {
+	for _, c := range n.children {
+		if (c.keyword == "AUTHORITY" || c.keyword == "ID") && len(c.values) >= 2 && strings.EqualFold(c.values[0], "EPSG") {
+			if code, err := strconv.Atoi(c.values[1]); err == nil {
+				return code
+			}
+		}
+	}
+	if n.keyword == "COMPD_CS" || n.keyword == "COMPOUNDCRS" {
+		for _, c := range n.children {
+			if c.keyword != "AUTHORITY" && c.keyword != "ID" {
+				return c.epsgCode()
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,146 @@
+package las
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+)
+
+func TestReadEpsgCode(t *testing.T) {
+	pts := []geom.Point64{{X: 500000, Y: 5000000, Z: 100}}
+	tmp := t.TempDir()
+	cases := []struct {
+		opts     []func(*WriterOptions)
+		expected int
+	}{
+		{nil, 0},
+		{[]func(*WriterOptions){WithGeoKeys(32633, false)}, 32633},
+		{[]func(*WriterOptions){WithGeoKeys(4326, true)}, 4326},
+		{[]func(*WriterOptions){WithGeoKeys(userDefinedGeoKey, false)}, 0},
+	}
+	for i, c := range cases {
+		path := filepath.Join(tmp, "out.las")
+		if err := WriteLasFile(path, pts, c.opts...); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		actual, err := ReadEpsgCode(path)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if actual != c.expected {
+			t.Errorf("case %d: expected EPSG code %d got %d", i, c.expected, actual)
+		}
+		// the points are still readable after the VLR
+		r, err := NewFileLasReader(path, 32633, false)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if pt, err := r.GetNext(); err != nil || pt.X != 500000 {
+			t.Errorf("case %d: expected the point to be readable got %v (%v)", i, pt, err)
+		}
+	}
+
+	if actual, err := ReadEpsgCode("./testdata/las-12-pf1.las"); err != nil || actual != 0 {
+		t.Errorf("expected no EPSG code got %d (%v)", actual, err)
+	}
+	if _, err := ReadEpsgCode(filepath.Join(tmp, "missing.las")); err == nil {
+		t.Errorf("expected error for a missing file, got none")
+	}
+}
+
+// withEvlr returns the given LAS 1.4 file with the given record appended as an EVLR, declaring the CRS as WKT if wkt
+func withEvlr(t *testing.T, file string, recordID uint16, data []byte, wkt bool) string {
+	t.Helper()
+	b, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	binary.LittleEndian.PutUint64(b[235:243], uint64(len(b)))
+	binary.LittleEndian.PutUint32(b[243:247], 1)
+	if wkt {
+		binary.LittleEndian.PutUint16(b[6:8], binary.LittleEndian.Uint16(b[6:8])|16)
+	}
+	evlr := make([]byte, evlrHeaderSize+len(data))
+	copy(evlr[2:18], "LASF_Projection")
+	binary.LittleEndian.PutUint16(evlr[18:20], recordID)
+	binary.LittleEndian.PutUint64(evlr[20:28], uint64(len(data)))
+	copy(evlr[28:60], "OGC WKT")
+	copy(evlr[evlrHeaderSize:], data)
+	out := filepath.Join(t.TempDir(), filepath.Base(file))
+	if err := os.WriteFile(out, append(b, evlr...), 0666); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	return out
+}
+
+func TestReadEpsgCodeEvlr(t *testing.T) {
+	wkt := `PROJCS["WGS 84 / UTM zone 33N",GEOGCS["WGS 84",DATUM["WGS_1984",SPHEROID["WGS 84",6378137,298.257223563,` +
+		`AUTHORITY["EPSG","7030"]],AUTHORITY["EPSG","6326"]],PRIMEM["Greenwich",0,AUTHORITY["EPSG","8901"]],` +
+		`UNIT["degree",0.0174532925199433,AUTHORITY["EPSG","9122"]],AUTHORITY["EPSG","4326"]],` +
+		`PROJECTION["Transverse_Mercator"],PARAMETER["central_meridian",15],UNIT["metre",1,AUTHORITY["EPSG","9001"]],` +
+		`AXIS["Easting",EAST],AXIS["Northing",NORTH],AUTHORITY["EPSG","32633"]]` + "\x00"
+	file := withEvlr(t, "./testdata/las-14-pf2.las", wktRecordID, []byte(wkt), true)
+	if actual, err := ReadEpsgCode(file); err != nil || actual != 32633 {
+		t.Errorf("expected EPSG code %d got %d (%v)", 32633, actual, err)
+	}
+	// the points are still readable
+	r, err := NewFileLasReader(file, 32633, false)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, err := r.GetNext(); err != nil {
+		t.Errorf("expected the points to be readable got %v", err)
+	}
+
+	// GeoTIFF keys stored in an EVLR
+	vlr := geoKeysVlr(32632, false)
+	file = withEvlr(t, "./testdata/las-14-pf2.las", tGeoKeyDirectoryTag, vlr[vlrHeaderSize:], false)
+	if actual, err := ReadEpsgCode(file); err != nil || actual != 32632 {
+		t.Errorf("expected EPSG code %d got %d (%v)", 32632, actual, err)
+	}
+
+	// the EVLR exceeding the file
+	b, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := os.WriteFile(file, b[:len(b)-4], 0666); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, err := ReadEpsgCode(file); err == nil {
+		t.Errorf("expected an error for a truncated EVLR, got none")
+	}
+}
+
+func TestWktEpsgCode(t *testing.T) {
+	cases := []struct {
+		wkt      string
+		expected int
+	}{
+		{`GEOGCS["WGS 84",DATUM["WGS_1984",SPHEROID["WGS 84",6378137,298.257223563]],AUTHORITY["EPSG","4326"]]`, 4326},
+		{`PROJCRS["WGS 84 / UTM zone 33N", BASEGEOGCRS["WGS 84", ID["EPSG", 4326]], ID["EPSG", 32633]]`, 32633},
+		{`PROJCS("name with ""quotes""",GEOGCS("WGS 84"),AUTHORITY("EPSG","32633"))`, 32633},
+		{`COMPD_CS["UTM 33N + EGM96",PROJCS["UTM 33N",AUTHORITY["EPSG","32633"]],VERT_CS["EGM96",AUTHORITY["EPSG","5773"]]]`, 32633},
+		{`COMPOUNDCRS["x",PROJCRS["UTM 33N",ID["EPSG",32633]],VERTCRS["EGM96",ID["EPSG",5773]],ID["EPSG",9999]]`, 9999},
+		{`PROJCS["local",GEOGCS["WGS 84",AUTHORITY["EPSG","4326"]]]`, 0},
+		{`LOCAL_CS["local",AUTHORITY["ESRI","12345"]]`, 0},
+	}
+	for i, c := range cases {
+		node, err := parseWkt(c.wkt)
+		if err != nil {
+			t.Errorf("case %d: unexpected error %v", i, err)
+			continue
+		}
+		if actual := node.epsgCode(); actual != c.expected {
+			t.Errorf("case %d: expected EPSG code %d got %d", i, c.expected, actual)
+		}
+	}
+	for _, wkt := range []string{``, `PROJCS["x"`, `PROJCS["x]`, `PROJCS["x"] trailing`, `["x"]`} {
+		if _, err := parseWkt(wkt); err == nil {
+			t.Errorf("expected an error parsing %q", wkt)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package las
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// extraBytesDescriptorLength is the length of each descriptor stored in the Extra Bytes VLR
+const extraBytesDescriptorLength = 192
+
+// extraDimension describes a scalar dimension stored in the extra bytes of the point records
+type extraDimension struct {
+	name string
+	// dataType is the LAS extra bytes data type, 1-10 for scalar values
+	dataType byte
+	// offset is the offset of the dimension from the start of the point record
+	offset    int
+	scale     float64
+	shift     float64
+	hasNoData bool
+	noData    [8]byte
+}
+
+// extraBytesTypeSizes holds the size in bytes of the scalar extra bytes data types 1-10
+var extraBytesTypeSizes = [11]int{0, 1, 1, 2, 2, 4, 4, 8, 8, 4, 8}
+
+// findExtraDimension looks up the dimension with the given name in the Extra Bytes VLRs of the file.
+// The extra bytes of a record start after the fields of the point format, at the given offset.
+func findExtraDimension(vlrs []VLR, name string, recordOffset int) (*extraDimension, error) {
+	offset := recordOffset
+	for _, vlr := range vlrs {
+		if vlr.UserID != "LASF_Spec" || vlr.RecordID != 4 {
+			continue
+		}
+		for d := 0; d+extraBytesDescriptorLength <= len(vlr.BinaryData); d += extraBytesDescriptorLength {
+			desc := vlr.BinaryData[d : d+extraBytesDescriptorLength]
+			dataType := desc[2]
+			options := desc[3]
+			size := extraBytesSize(dataType, options)
+			dimName := strings.TrimRight(string(desc[4:36]), "\x00 ")
+			if dimName == name {
+				if dataType < 1 || dataType > 10 {
+					return nil, fmt.Errorf("extra bytes dimension %s has unsupported data type %d", name, dataType)
+				}
+				dim := &extraDimension{
+					name:      name,
+					dataType:  dataType,
+					offset:    offset,
+					scale:     1,
+					hasNoData: options&1 != 0,
+				}
+				copy(dim.noData[:], desc[40:48])
+				if options&(1<<3) != 0 {
+					dim.scale = math.Float64frombits(binary.LittleEndian.Uint64(desc[112:120]))
+				}
+				if options&(1<<4) != 0 {
+					dim.shift = math.Float64frombits(binary.LittleEndian.Uint64(desc[136:144]))
+				}
+				return dim, nil
+			}
+			offset += size
+		}
+	}
+	return nil, fmt.Errorf("extra bytes dimension %s not found", name)
+}
+
+// extraBytesSize returns the size in bytes of an extra bytes dimension. Type 0 stores opaque bytes whose number is
+// given by the options field, while the deprecated types 11-30 are arrays of 2 or 3 values of the scalar types.
+func extraBytesSize(dataType byte, options byte) int {
+	switch {
+	case dataType == 0:
+		return int(options)
+	case dataType <= 10:
+		return extraBytesTypeSizes[dataType]
+	case dataType <= 30:
+		return extraBytesTypeSizes[(dataType-1)%10+1] * int((dataType-1)/10+1)
+	}
+	return 0
+}
+
+// value decodes the dimension from the given point record, applying its scale and offset.
+// Returns NaN if the record stores the no data value.
+func (d *extraDimension) value(record []byte) float64 {
+	raw := record[d.offset : d.offset+extraBytesTypeSizes[d.dataType]]
+	var v float64
+	var noData float64
+	switch d.dataType {
+	case 1:
+		v, noData = float64(raw[0]), float64(binary.LittleEndian.Uint64(d.noData[:]))
+	case 2:
+		v, noData = float64(int8(raw[0])), float64(int64(binary.LittleEndian.Uint64(d.noData[:])))
+	case 3:
+		v, noData = float64(binary.LittleEndian.Uint16(raw)), float64(binary.LittleEndian.Uint64(d.noData[:]))
+	case 4:
+		v, noData = float64(int16(binary.LittleEndian.Uint16(raw))), float64(int64(binary.LittleEndian.Uint64(d.noData[:])))
+	case 5:
+		v, noData = float64(binary.LittleEndian.Uint32(raw)), float64(binary.LittleEndian.Uint64(d.noData[:]))
+	case 6:
+		v, noData = float64(int32(binary.LittleEndian.Uint32(raw))), float64(int64(binary.LittleEndian.Uint64(d.noData[:])))
+	case 7:
+		v, noData = float64(binary.LittleEndian.Uint64(raw)), float64(binary.LittleEndian.Uint64(d.noData[:]))
+	case 8:
+		v, noData = float64(int64(binary.LittleEndian.Uint64(raw))), float64(int64(binary.LittleEndian.Uint64(d.noData[:])))
+	case 9:
+		v, noData = float64(math.Float32frombits(binary.LittleEndian.Uint32(raw))), math.Float64frombits(binary.LittleEndian.Uint64(d.noData[:]))
+	case 10:
+		v, noData = math.Float64frombits(binary.LittleEndian.Uint64(raw)), math.Float64frombits(binary.LittleEndian.Uint64(d.noData[:]))
+	}
+	if d.hasNoData && v == noData {
+		return math.NaN()
+	}
+	return v*d.scale + d.shift
+}
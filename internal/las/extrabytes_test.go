@@ -0,0 +1,116 @@
+package las
+
+import (
+	"encoding/binary"
+	"math"
+	"os"
+	"testing"
+)
+
+// extraBytesDescriptor returns an Extra Bytes VLR descriptor for a dimension with the given properties
+func extraBytesDescriptor(name string, dataType byte, options byte, noData uint64, scale, offset float64) []byte {
+	d := make([]byte, extraBytesDescriptorLength)
+	d[2] = dataType
+	d[3] = options
+	copy(d[4:36], name)
+	binary.LittleEndian.PutUint64(d[40:48], noData)
+	binary.LittleEndian.PutUint64(d[112:120], math.Float64bits(scale))
+	binary.LittleEndian.PutUint64(d[136:144], math.Float64bits(offset))
+	return d
+}
+
+// writeTestLasWithExtraBytes writes a LAS file with an Extra Bytes VLR made of the given descriptors
+func writeTestLasWithExtraBytes(t *testing.T, format byte, recordLength int, descriptors []byte, records [][]byte) string {
+	t.Helper()
+	file := writeTestLas(t, format, recordLength, nil)
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	vlr := make([]byte, 54)
+	copy(vlr[2:18], "LASF_Spec")
+	binary.LittleEndian.PutUint16(vlr[18:20], 4)
+	binary.LittleEndian.PutUint16(vlr[20:22], uint16(len(descriptors)))
+	binary.LittleEndian.PutUint32(data[96:100], uint32(len(data)+len(vlr)+len(descriptors)))
+	binary.LittleEndian.PutUint32(data[100:104], 1)
+	binary.LittleEndian.PutUint64(data[247:255], uint64(len(records)))
+	data = append(append(data, vlr...), descriptors...)
+	for _, r := range records {
+		data = append(data, r...)
+	}
+	if err := os.WriteFile(file, data, 0644); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	return file
+}
+
+func TestReaderExtraDimension(t *testing.T) {
+	// format 6 records are 30 bytes long, followed by an unsigned short "flags" and a scaled "confidence" byte
+	descriptors := append(
+		extraBytesDescriptor("flags", 3, 0, 0, 0, 0),
+		extraBytesDescriptor("confidence", 1, 1|1<<3|1<<4, 255, 0.01, 0.5)...,
+	)
+	rec := func(confidence byte) []byte {
+		r := make([]byte, 33)
+		binary.LittleEndian.PutUint16(r[30:32], 0xFFFF)
+		r[32] = confidence
+		return r
+	}
+	file := writeTestLasWithExtraBytes(t, 6, 33, descriptors, [][]byte{rec(20), rec(255)})
+
+	r, err := NewFileLasReader(file, 32633, false, WithExtraDimension("confidence"))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	pt, err := r.GetNext()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if math.Abs(pt.Extra-0.7) > 1e-9 {
+		t.Errorf("expected extra value %v got %v", 0.7, pt.Extra)
+	}
+	pt, err = r.GetNext()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !math.IsNaN(pt.Extra) {
+		t.Errorf("expected NaN for the no data value got %v", pt.Extra)
+	}
+
+	r, err = NewFileLasReader(file, 32633, false, WithExtraDimension("flags"))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if pt, _ := r.GetNext(); pt.Extra != 0xFFFF {
+		t.Errorf("expected extra value %v got %v", 0xFFFF, pt.Extra)
+	}
+
+	if _, err := NewFileLasReader(file, 32633, false, WithExtraDimension("missing")); err == nil {
+		t.Errorf("expected error for a missing dimension, got none")
+	}
+	short := writeTestLasWithExtraBytes(t, 6, 32, descriptors, nil)
+	if _, err := NewFileLasReader(short, 32633, false, WithExtraDimension("confidence")); err == nil {
+		t.Errorf("expected error for a dimension exceeding the record length, got none")
+	}
+}
+
+func TestExtraBytesSize(t *testing.T) {
+	cases := []struct {
+		dataType byte
+		options  byte
+		expected int
+	}{
+		{0, 5, 5},
+		{1, 0, 1},
+		{4, 0, 2},
+		{9, 0, 4},
+		{10, 0, 8},
+		{13, 0, 4},  // 2 unsigned shorts
+		{30, 0, 24}, // 3 doubles
+	}
+	for _, c := range cases {
+		if actual := extraBytesSize(c.dataType, c.options); actual != c.expected {
+			t.Errorf("type %d: expected size %d got %d", c.dataType, c.expected, actual)
+		}
+	}
+}
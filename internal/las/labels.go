@@ -0,0 +1,58 @@
+package las
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+)
+
+// LabelReader wraps a reader replacing the classification of the points with the labels stored in a separate
+// file, holding one byte per point in the same order the points are read
+type LabelReader struct {
+	LasReader
+	file   *os.File
+	labels *bufio.Reader
+}
+
+// NewLabelReader returns a reader overriding the classification of the points read by the given reader with
+// the labels of the given file. Returns an error if the number of labels differs from the number of points.
+func NewLabelReader(r LasReader, path string) (*LabelReader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if stat.Size() != int64(r.NumberOfPoints()) {
+		f.Close()
+		return nil, fmt.Errorf("the labels file %s stores %d labels but the input has %d points", path, stat.Size(), r.NumberOfPoints())
+	}
+	return &LabelReader{
+		LasReader: r,
+		file:      f,
+		labels:    bufio.NewReader(f),
+	}, nil
+}
+
+func (l *LabelReader) GetNext() (geom.Point64, error) {
+	pt, err := l.LasReader.GetNext()
+	if err != nil {
+		return pt, err
+	}
+	label, err := l.labels.ReadByte()
+	if err != nil {
+		return pt, fmt.Errorf("unable to read the label of the point: %w", err)
+	}
+	pt.Classification = label
+	return pt, nil
+}
+
+// Close closes the labels file
+func (l *LabelReader) Close() error {
+	return l.file.Close()
+}
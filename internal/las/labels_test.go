@@ -0,0 +1,40 @@
+package las
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+)
+
+func TestLabelReader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "labels.bin")
+	if err := os.WriteFile(path, []byte{2, 6, 9}, 0644); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	r, err := NewLabelReader(&MockLasReader{Pts: []geom.Point64{{X: 1, Classification: 1}, {X: 2, Classification: 1}, {X: 3, Classification: 1}}}, path)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	defer r.Close()
+	for i, expected := range []uint8{2, 6, 9} {
+		pt, err := r.GetNext()
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if pt.X != float64(i+1) || pt.Classification != expected {
+			t.Errorf("expected point %d with class %d got %v", i+1, expected, pt)
+		}
+	}
+	if _, err := r.GetNext(); err == nil {
+		t.Errorf("expected error past the last point, got none")
+	}
+
+	if _, err := NewLabelReader(&MockLasReader{Pts: []geom.Point64{{}, {}}}, path); err == nil {
+		t.Errorf("expected error for mismatching label count, got none")
+	}
+	if _, err := NewLabelReader(&MockLasReader{}, filepath.Join(t.TempDir(), "missing.bin")); err == nil {
+		t.Errorf("expected error for missing file, got none")
+	}
+}
@@ -15,13 +15,23 @@ import (
 // NoData value used when indexing data outside of allowable range.
 var NoData = math.Inf(-1)
 
+const (
+	// evlrHeaderSize is the size of the header of an extended variable length record
+	evlrHeaderSize = 60
+	// wktRecordID is the record ID of the (extended) variable length record storing the CRS as OGC WKT
+	wktRecordID = 2112
+)
+
 // lasFile is a structure for manipulating LAS files.
 type lasFile struct {
 	fileName string
 	f        *os.File
 	Header   lasHeader
 	VlrData  []VLR
+	EvlrData []VLR
 	geokeys  geoKeys
+	// wkt is the OGC WKT describing the CRS of the points, if stored in a VLR or an EVLR
+	wkt string
 	sync.RWMutex
 }
 
@@ -138,14 +148,16 @@ func (las *lasFile) readHeader() error {
 		offset += 8
 	}
 	if las.Header.VersionMajor == 1 && las.Header.VersionMinor == 4 {
-		// Skip start of first Extended Variable Length and number of EVLR
-		offset += 12
+		las.Header.StartOfFirstEVLR = binary.LittleEndian.Uint64(b[offset : offset+8])
+		offset += 8
+		las.Header.NumberOfEVLRs = int(binary.LittleEndian.Uint32(b[offset : offset+4]))
+		offset += 4
 		// For Las 1.4 get the number of points from the new fields
 
-		las.Header.NumberPoints = int(binary.LittleEndian.Uint32(b[offset : offset+8]))
+		las.Header.NumberPoints = int(binary.LittleEndian.Uint64(b[offset : offset+8]))
 		offset += 8
 		for i := 0; i < 15; i++ {
-			las.Header.NumberPointsByReturn[i] = int(binary.LittleEndian.Uint32(b[offset : offset+8]))
+			las.Header.NumberPointsByReturn[i] = int(binary.LittleEndian.Uint64(b[offset : offset+8]))
 			offset += 8
 		}
 	}
@@ -189,22 +201,78 @@ func (las *lasFile) readVLRs() error {
 			vlr.BinaryData[j] = b[offset]
 			offset++
 		}
-		if vlr.RecordID == 34735 {
-			// GeoKey directory
-			las.geokeys.addKeyDirectory(vlr.BinaryData)
-		} else if vlr.RecordID == 34736 {
-			// Double GeoKey parameters
-			las.geokeys.addDoubleParams(vlr.BinaryData)
-		} else if vlr.RecordID == 34737 {
-			// ASCII GeoKey parameters
-			las.geokeys.addASCIIParams(vlr.BinaryData)
-		}
+		las.readCrsRecord(vlr)
 		las.VlrData[i] = vlr
 	}
 
 	return nil
 }
 
+// readEVLRs reads the extended variable length records of LAS 1.4 files, stored after the point records. Only the
+// data of the records describing the CRS is read, the data of the other records, e.g. the waveform packets, can be
+// very large and is left empty.
+func (las *lasFile) readEVLRs() error {
+	las.Lock()
+	defer las.Unlock()
+	las.EvlrData = make([]VLR, 0, las.Header.NumberOfEVLRs)
+	if las.Header.NumberOfEVLRs == 0 {
+		return nil
+	}
+	info, err := las.f.Stat()
+	if err != nil {
+		return err
+	}
+	offset := int64(las.Header.StartOfFirstEVLR)
+	h := make([]byte, evlrHeaderSize)
+	for i := 0; i < las.Header.NumberOfEVLRs; i++ {
+		if _, err := las.f.ReadAt(h, offset); err != nil {
+			return fmt.Errorf("unable to read the extended variable length record %d: %w", i, err)
+		}
+		offset += evlrHeaderSize
+		vlr := VLR{
+			Reserved:    int(binary.LittleEndian.Uint16(h[0:2])),
+			UserID:      strings.Trim(strings.Trim(string(h[2:18]), " "), "\x00"),
+			RecordID:    int(binary.LittleEndian.Uint16(h[18:20])),
+			Description: strings.Trim(strings.Trim(string(h[28:60]), " "), "\x00"),
+		}
+		length := binary.LittleEndian.Uint64(h[20:28])
+		if length > uint64(info.Size()-offset) {
+			return fmt.Errorf("the extended variable length record %d exceeds the file size", i)
+		}
+		vlr.RecordLengthAfterHeader = int(length)
+		if isCrsRecord(vlr.RecordID) {
+			vlr.BinaryData = make([]uint8, vlr.RecordLengthAfterHeader)
+			if _, err := las.f.ReadAt(vlr.BinaryData, offset); err != nil {
+				return fmt.Errorf("unable to read the extended variable length record %d: %w", i, err)
+			}
+			las.readCrsRecord(vlr)
+		}
+		offset += int64(length)
+		las.EvlrData = append(las.EvlrData, vlr)
+	}
+	return nil
+}
+
+// isCrsRecord returns whether the (extended) variable length record with the given ID describes the CRS
+func isCrsRecord(recordID int) bool {
+	return recordID == tGeoKeyDirectoryTag || recordID == tGeoDoubleParamsTag || recordID == tGeoASCIIParamsTag || recordID == wktRecordID
+}
+
+// readCrsRecord stores the CRS described by the given (extended) variable length record, if any
+func (las *lasFile) readCrsRecord(vlr VLR) {
+	switch vlr.RecordID {
+	case tGeoKeyDirectoryTag:
+		las.geokeys.addKeyDirectory(vlr.BinaryData)
+	case tGeoDoubleParamsTag:
+		las.geokeys.addDoubleParams(vlr.BinaryData)
+	case tGeoASCIIParamsTag:
+		las.geokeys.addASCIIParams(vlr.BinaryData)
+	case wktRecordID:
+		// OGC coordinate system WKT, null terminated
+		las.wkt = strings.TrimRight(string(vlr.BinaryData), "\x00")
+	}
+}
+
 // printGeokeys interprets the Geokeys, if there are any.
 func (las *lasFile) printGeokeys() string {
 	return las.geokeys.interpretGeokeys()
@@ -245,6 +313,8 @@ type lasHeader struct {
 	MaxZ                 float64
 	MinZ                 float64
 	WaveformDataStart    uint64
+	StartOfFirstEVLR     uint64
+	NumberOfEVLRs        int
 	projectIDUsed        bool
 }
 
@@ -0,0 +1,40 @@
+package las
+
+// OpenFileLimiter bounds the number of LAS files kept open at the same time by the readers sharing it
+type OpenFileLimiter struct {
+	slots chan struct{}
+}
+
+// NewOpenFileLimiter returns a limiter allowing at most n files to be open at the same time, or nil, meaning no
+// limit, if n is not greater than zero
+func NewOpenFileLimiter(n int) *OpenFileLimiter {
+	if n <= 0 {
+		return nil
+	}
+	return &OpenFileLimiter{slots: make(chan struct{}, n)}
+}
+
+// acquire blocks until a file can be opened. Does nothing on a nil limiter.
+func (l *OpenFileLimiter) acquire() {
+	if l == nil {
+		return
+	}
+	l.slots <- struct{}{}
+}
+
+// release frees the slot of a file that has been closed. Does nothing on a nil limiter.
+func (l *OpenFileLimiter) release() {
+	if l == nil {
+		return
+	}
+	<-l.slots
+}
+
+// WithOpenFileLimiter makes the reader keep its file open only while reading the header and the point records,
+// taking a slot of the given limiter for that time. A reader waiting for a slot blocks until another one has read
+// all its points or is closed.
+func WithOpenFileLimiter(l *OpenFileLimiter) func(*FileLasReader) {
+	return func(f *FileLasReader) {
+		f.limiter = l
+	}
+}
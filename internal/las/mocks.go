@@ -1,8 +1,6 @@
 package las
 
 import (
-	"fmt"
-
 	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
 )
 
@@ -24,5 +22,5 @@ func (m *MockLasReader) GetNext() (geom.Point64, error) {
 		m.Cur++
 		return m.Pts[m.Cur-1], nil
 	}
-	return geom.Point64{}, fmt.Errorf("point not available")
+	return geom.Point64{}, ErrNoMorePoints
 }
@@ -3,103 +3,180 @@ package las
 import (
 	"bufio"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"sync"
 
 	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
 )
 
-var recLengths = [11][4]int{
-	{20, 18, 19, 17}, // Point format 0
-	{28, 26, 27, 25}, // Point format 1
-	{26, 24, 25, 23}, // Point format 2
-	{34, 32, 33, 31}, // Point format 3
-	{57, 55, 56, 54}, // Point format 4
-	{63, 61, 62, 60}, // Point format 5
-	{30, 28, 29, 27}, // Point format 6
-	{36, 34, 35, 33}, // Point format 7
-	{38, 36, 37, 35}, // Point format 8
-	{59, 57, 58, 56}, // Point format 9
-	{67, 65, 66, 64}, // Point format 10
-}
-
-var xyzOffets = [11][3]int{
-	{0, 4, 8}, // Point format 0
-	{0, 4, 8}, // Point format 1
-	{0, 4, 8}, // Point format 2
-	{0, 4, 8}, // Point format 3
-	{0, 4, 8}, // Point format 4
-	{0, 4, 8}, // Point format 5
-	{0, 4, 8}, // Point format 6
-	{0, 4, 8}, // Point format 7
-	{0, 4, 8}, // Point format 8
-	{0, 4, 8}, // Point format 9
-	{0, 4, 8}, // Point format 10
-}
-
-var rgbOffets = [11][]int{
-	nil,          // Point format 0
-	nil,          // Point format 1
-	{20, 22, 24}, // Point format 2
-	{28, 30, 32}, // Point format 3
-	nil,          // Point format 4
-	{28, 30, 32}, // Point format 5
-	nil,          // Point format 6
-	{30, 32, 34}, // Point format 7
-	{30, 32, 34}, // Point format 8
-	nil,          // Point format 9
-	{30, 32, 34}, // Point format 10
-}
-
-// intensity offset is always 12
-
-var classificationOffets = [11]int{
-	15, // Point format 0
-	15, // Point format 1
-	15, // Point format 2
-	15, // Point format 3
-	15, // Point format 4
-	15, // Point format 5
-	16, // Point format 6
-	16, // Point format 7
-	16, // Point format 8
-	16, // Point format 9
-	16, // Point format 10
+// pointFormat describes the layout of the records of a LAS point data format
+type pointFormat struct {
+	// length is the minimum length of a record, records can be longer if they contain extra bytes
+	length int
+	// rgbOffset is the offset of the R, G, B channels or -1 if the format does not store colors
+	rgbOffset int
+	// nirOffset is the offset of the near infrared channel or -1 if the format does not store it
+	nirOffset int
+	// classificationOffset is the offset of the classification byte
+	classificationOffset int
+	// gpsTimeOffset is the offset of the GPS time or -1 if the format does not store it
+	gpsTimeOffset int
+	// extended is true for the LAS 1.4 formats (6-10), which store the classification as a full byte
+	// and the classification flags in the low 4 bits of a dedicated byte
+	extended bool
 }
 
+// X, Y, Z are always stored as 32 bit integers at offsets 0, 4, 8 and the intensity at offset 12.
+// The formats 4, 5, 9 and 10 end with the 29 bytes of the wave packet fields, which are skipped as part of the record,
+// while the waveform packets themselves are stored after the point records or in external files and are never read.
+var pointFormats = [11]pointFormat{
+	{length: 20, rgbOffset: -1, nirOffset: -1, classificationOffset: 15, gpsTimeOffset: -1},                 // Point format 0
+	{length: 28, rgbOffset: -1, nirOffset: -1, classificationOffset: 15, gpsTimeOffset: 20},                 // Point format 1
+	{length: 26, rgbOffset: 20, nirOffset: -1, classificationOffset: 15, gpsTimeOffset: -1},                 // Point format 2
+	{length: 34, rgbOffset: 28, nirOffset: -1, classificationOffset: 15, gpsTimeOffset: 20},                 // Point format 3
+	{length: 57, rgbOffset: -1, nirOffset: -1, classificationOffset: 15, gpsTimeOffset: 20},                 // Point format 4
+	{length: 63, rgbOffset: 28, nirOffset: -1, classificationOffset: 15, gpsTimeOffset: 20},                 // Point format 5
+	{length: 30, rgbOffset: -1, nirOffset: -1, classificationOffset: 16, gpsTimeOffset: 22, extended: true}, // Point format 6
+	{length: 36, rgbOffset: 30, nirOffset: -1, classificationOffset: 16, gpsTimeOffset: 22, extended: true}, // Point format 7
+	{length: 38, rgbOffset: 30, nirOffset: 36, classificationOffset: 16, gpsTimeOffset: 22, extended: true}, // Point format 8
+	{length: 59, rgbOffset: -1, nirOffset: -1, classificationOffset: 16, gpsTimeOffset: 22, extended: true}, // Point format 9
+	{length: 67, rgbOffset: 30, nirOffset: 36, classificationOffset: 16, gpsTimeOffset: 22, extended: true}, // Point format 10
+}
+
+// flagsOffset is the offset of the classification flags byte in the extended point formats
+const flagsOffset = 15
+
+// pointSourceIdOffset and extendedPointSourceIdOffset are the offsets of the point source ID
+// in the legacy (0-5) and extended (6-10) point formats
+const (
+	pointSourceIdOffset         = 18
+	extendedPointSourceIdOffset = 20
+)
+
+// ColorSource identifies a channel of the LAS point records that can be used as a color channel
+type ColorSource int
+
+const (
+	ColorRed ColorSource = iota
+	ColorGreen
+	ColorBlue
+	// ColorNIR is the near infrared channel, only stored by the point formats 8 and 10
+	ColorNIR
+	ColorIntensity
+)
+
+// Attributes is a set of the attributes of the points decoded by the readers, besides the coordinates which are
+// always decoded
+type Attributes int
+
+const (
+	// AttributeColor are the R, G and B colors, decoded from the channels set with WithColorChannelMapping
+	AttributeColor Attributes = 1 << iota
+	AttributeIntensity
+	// AttributeClassification is the classification together with the classification flags
+	AttributeClassification
+	AttributeGpsTime
+	AttributePointSourceId
+	// AllAttributes are all the attributes of the points
+	AllAttributes = AttributeColor | AttributeIntensity | AttributeClassification | AttributeGpsTime | AttributePointSourceId
+)
+
+// ErrNoMorePoints is returned by GetNext when all the points have been read, distinguishing the clean completion of
+// the reading from the actual read errors
+var ErrNoMorePoints = errors.New("no more points to read")
+
 type LasReader interface {
 	// NumberOfPoints returns the number of points stored in the LAS file
 	NumberOfPoints() int
-	// GetNext returns the next point in the las file
+	// GetNext returns the next point in the las file, or ErrNoMorePoints if all points have been read
 	GetNext() (geom.Point64, error)
 	GetSrid() int
 }
 
+// FileTracker is implemented by readers that read points from multiple files and can
+// report the file the last point returned by GetNext has been read from. The readers
+// also set the FileIndex of the points, that FileName maps back to the file name.
+type FileTracker interface {
+	CurrentFile() string
+	FileName(index int) string
+}
+
+// FileLister is implemented by readers that read points from multiple files and can list them, in the order
+// matching the FileIndex of the points
+type FileLister interface {
+	FileNames() []string
+}
+
+// ElevationRange is implemented by readers that can report the elevation range of the points
+// as declared in the headers of the files, in the input CRS
+type ElevationRange interface {
+	MinZ() float64
+	MaxZ() float64
+}
+
+// HorizontalExtent is implemented by readers that can report the X, Y extent of the points as declared in the
+// headers of the files, in the input CRS
+type HorizontalExtent interface {
+	Extent() (minX, minY, maxX, maxY float64)
+}
+
+// Quantization is the scale and offset that map the integer coordinates stored in a LAS file to the actual ones
+type Quantization struct {
+	File   string     `json:"file"`
+	Scale  [3]float64 `json:"scale"`
+	Offset [3]float64 `json:"offset"`
+}
+
+// QuantizationSource is implemented by readers that can report the scale and offset of the coordinates
+// of the files they read, as declared in the headers of the files
+type QuantizationSource interface {
+	Quantization() []Quantization
+}
+
+// ColorOverflowCounter is implemented by readers that count the points whose colors, read as 8 bit values, exceed
+// 255 and are truncated, as happens reading files with 16 bit colors in the eight bit color mode
+type ColorOverflowCounter interface {
+	// ColorOverflows returns the number of points read so far with truncated colors and a description of the first one
+	ColorOverflows() (int, string)
+}
+
+// FileError wraps an error that occurred while reading a specific LAS file
+type FileError struct {
+	File string
+	Err  error
+}
+
+func (e *FileError) Error() string {
+	return fmt.Sprintf("%s: %v", e.File, e.Err)
+}
+
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
 // CombinedFileLasReader enables reading a a list of LAS files as if they were a single one
 // the files MUST have the same properties (SRID, etc)
 type CombinedFileLasReader struct {
 	currentReader int
 	currentCount  int
 	readers       []*FileLasReader
-	numPts        int
-	srid          int
+	// transforms are the transforms applied to the points of each reader
+	transforms []Transform
+	numPts     int
+	srid       int
 }
 
-func NewCombinedFileLasReader(files []string, srid int, eightBitColor bool) (*CombinedFileLasReader, error) {
-	r := &CombinedFileLasReader{
-		srid: srid,
+// NewCombinedFileLasReader returns a reader of the given files. The options are applied to the reader of each file.
+func NewCombinedFileLasReader(files []string, srid int, eightBitColor bool, opts ...func(*FileLasReader)) (*CombinedFileLasReader, error) {
+	virtualFiles := make([]VirtualFile, len(files))
+	for i, f := range files {
+		virtualFiles[i] = VirtualFile{Path: f, Transform: identityTransform}
 	}
-	for _, f := range files {
-		fr, err := NewFileLasReader(f, srid, eightBitColor)
-		if err != nil {
-			return nil, err
-		}
-		r.numPts += fr.NumberOfPoints()
-		r.readers = append(r.readers, fr)
-	}
-	return r, nil
+	return NewVirtualLasReader(virtualFiles, srid, eightBitColor, opts...)
 }
 
 func (m *CombinedFileLasReader) NumberOfPoints() int {
@@ -112,103 +189,488 @@ func (m *CombinedFileLasReader) GetSrid() int {
 
 func (m *CombinedFileLasReader) GetNext() (geom.Point64, error) {
 	if m.currentReader >= len(m.readers) {
-		return geom.Point64{}, fmt.Errorf("no points to read")
+		return geom.Point64{}, ErrNoMorePoints
 	}
 	r := m.readers[m.currentReader]
 	if m.currentCount == r.NumberOfPoints() {
 		m.currentReader++
 		m.currentCount = 0
 		if m.currentReader >= len(m.readers) {
-			return geom.Point64{}, fmt.Errorf("no points to read")
+			return geom.Point64{}, ErrNoMorePoints
 		}
 		r = m.readers[m.currentReader]
 	}
 	m.currentCount++
-	return r.GetNext()
+	pt, err := r.GetNext()
+	if err != nil {
+		return pt, &FileError{File: r.f.fileName, Err: err}
+	}
+	pt.FileIndex = m.currentReader
+	if t := m.transforms[m.currentReader]; t != identityTransform {
+		pt.X, pt.Y, pt.Z = t.apply(pt.X, pt.Y, pt.Z)
+	}
+	return pt, nil
+}
+
+// Close closes the files of all the readers
+func (m *CombinedFileLasReader) Close() error {
+	errs := []error{}
+	for _, r := range m.readers {
+		errs = append(errs, r.Close())
+	}
+	return errors.Join(errs...)
 }
 
+// CurrentFile returns the name of the file currently being read
+func (m *CombinedFileLasReader) CurrentFile() string {
+	if m.currentReader >= len(m.readers) {
+		return ""
+	}
+	return m.readers[m.currentReader].f.fileName
+}
+
+// MinZ returns the minimum elevation declared in the headers of the files, after applying their transforms
+func (m *CombinedFileLasReader) MinZ() float64 {
+	minZ := math.Inf(1)
+	for i, r := range m.readers {
+		_, _, rMinZ, _, _, _ := transformedExtent(r, m.transforms[i])
+		minZ = math.Min(minZ, rMinZ)
+	}
+	return minZ
+}
+
+// MaxZ returns the maximum elevation declared in the headers of the files, after applying their transforms
+func (m *CombinedFileLasReader) MaxZ() float64 {
+	maxZ := math.Inf(-1)
+	for i, r := range m.readers {
+		_, _, _, _, _, rMaxZ := transformedExtent(r, m.transforms[i])
+		maxZ = math.Max(maxZ, rMaxZ)
+	}
+	return maxZ
+}
+
+// Extent returns the X, Y extent of the points declared in the headers of the files, after applying their transforms
+func (m *CombinedFileLasReader) Extent() (minX, minY, maxX, maxY float64) {
+	minX, minY, maxX, maxY = math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for i, r := range m.readers {
+		rMinX, rMinY, _, rMaxX, rMaxY, _ := transformedExtent(r, m.transforms[i])
+		minX, minY = math.Min(minX, rMinX), math.Min(minY, rMinY)
+		maxX, maxY = math.Max(maxX, rMaxX), math.Max(maxY, rMaxY)
+	}
+	return minX, minY, maxX, maxY
+}
+
+// FileName returns the name of the file with the given index, in the order the files were given
+func (m *CombinedFileLasReader) FileName(index int) string {
+	if index < 0 || index >= len(m.readers) {
+		return ""
+	}
+	return m.readers[index].f.fileName
+}
+
+// FileNames returns the names of the files, in the order the files were given
+func (m *CombinedFileLasReader) FileNames() []string {
+	names := make([]string, len(m.readers))
+	for i, r := range m.readers {
+		names[i] = r.f.fileName
+	}
+	return names
+}
+
+// ColorOverflows returns the number of points read so far, from all the files, whose 8 bit colors exceed 255 and a
+// description of the first one
+func (m *CombinedFileLasReader) ColorOverflows() (int, string) {
+	total, sample := 0, ""
+	for _, r := range m.readers {
+		n, s := r.ColorOverflows()
+		if total == 0 {
+			sample = s
+		}
+		total += n
+	}
+	return total, sample
+}
+
+// Quantization returns the scale and offset of each file, in the order the files were given
+func (m *CombinedFileLasReader) Quantization() []Quantization {
+	q := []Quantization{}
+	for _, r := range m.readers {
+		q = append(q, r.Quantization()...)
+	}
+	return q
+}
+
+// DefaultReadBufferSize is the default size, in bytes, of the buffer used to read the point records
+const DefaultReadBufferSize = 1024 * 1024
+
 // FileLasReader enables reading a single LAS file
 type FileLasReader struct {
-	f             *lasFile
-	eightBitColor bool
-	srid          int
-	r             io.Reader
-	current       int
+	f              *lasFile
+	eightBitColor  bool
+	srid           int
+	r              io.Reader
+	current        int
+	readBufferSize int
+	colorMapping   [3]ColorSource
+	extraName      string
+	extraDim       *extraDimension
+	attributes     Attributes
+	// eightBitIntensity is set if the intensities of the file fit in 8 bits, they are then used as colors unscaled
+	eightBitIntensity bool
+	// rangeStart and rangeCount delimit the point records read, rangeCount is negative to read up to the last one
+	rangeStart int
+	rangeCount int
+	// colorOverflows is the number of points read whose 8 bit colors are truncated, the first is described by
+	// colorOverflowSample
+	colorOverflows      int
+	colorOverflowSample string
+	// limiter bounds the files open at the same time, if set the file is closed once all the points are read
+	limiter *OpenFileLimiter
 	sync.Mutex
 }
 
-func NewFileLasReader(fileName string, srid int, eightBitColor bool) (*FileLasReader, error) {
-	vlrs := []VLR{}
-	las := lasFile{fileName: fileName, Header: lasHeader{}, VlrData: vlrs}
-	var err error
-	if las.f, err = os.Open(las.fileName); err != nil {
+// WithColorChannelMapping sets the channels of the point records used as red, green and blue colors of the points,
+// e.g. (ColorNIR, ColorRed, ColorGreen) produces a false color infrared representation. All sources are converted
+// to 8 bit values as the RGB channels are, except the intensities of files storing only 8 bit values, which are used
+// as they are. Sources not stored by the point format of the file produce zero values.
+func WithColorChannelMapping(r, g, b ColorSource) func(*FileLasReader) {
+	return func(f *FileLasReader) {
+		f.colorMapping = [3]ColorSource{r, g, b}
+	}
+}
+
+// WithExtraDimension sets the name of the dimension stored in the extra bytes of the point records, as described by
+// the Extra Bytes VLR, whose value is decoded in the Extra field of the points. The scale and offset of the
+// dimension are applied, while the points storing the no data value of the dimension get a NaN value.
+func WithExtraDimension(name string) func(*FileLasReader) {
+	return func(f *FileLasReader) {
+		f.extraName = name
+	}
+}
+
+// WithAttributes sets the attributes of the points decoded from the point records, leaving the others to their zero
+// value to save the time spent decoding them. The dimension set with WithExtraDimension is decoded anyway.
+func WithAttributes(attributes Attributes) func(*FileLasReader) {
+	return func(f *FileLasReader) {
+		f.attributes = attributes
+	}
+}
+
+// WithReadBufferSize sets the size in bytes of the buffer used to read the point records.
+// Larger buffers reduce the number of read syscalls, which helps on high latency storage.
+func WithReadBufferSize(size int) func(*FileLasReader) {
+	return func(f *FileLasReader) {
+		f.readBufferSize = size
+	}
+}
+
+// WithPointRange reads only the count point records starting at the given index, e.g. to split the processing of a
+// single large file among several workers. The range is clipped to the records stored in the file. A negative count
+// reads all the records from start onwards.
+func WithPointRange(start, count int) func(*FileLasReader) {
+	return func(f *FileLasReader) {
+		f.rangeStart = start
+		f.rangeCount = count
+	}
+}
+
+func NewFileLasReader(fileName string, srid int, eightBitColor bool, opts ...func(*FileLasReader)) (*FileLasReader, error) {
+	r := &FileLasReader{
+		f:              &lasFile{fileName: fileName, Header: lasHeader{}, VlrData: []VLR{}},
+		eightBitColor:  eightBitColor,
+		srid:           srid,
+		readBufferSize: DefaultReadBufferSize,
+		colorMapping:   [3]ColorSource{ColorRed, ColorGreen, ColorBlue},
+		attributes:     AllAttributes,
+		rangeCount:     -1,
+	}
+	for _, optFn := range opts {
+		optFn(r)
+	}
+	if err := r.openFile(); err != nil {
 		return nil, err
 	}
-	if err = las.readHeader(); err != nil {
+	if err := r.init(); err != nil {
+		r.Close()
 		return nil, err
 	}
+	if r.limiter != nil {
+		// the file is opened again when the points are read
+		r.Close()
+	}
+	return r, nil
+}
+
+// init reads the header and the VLRs of the file, validating them against the options of the reader
+func (f *FileLasReader) init() error {
+	las := f.f
+	if err := las.readHeader(); err != nil {
+		return err
+	}
 	if err := las.readVLRs(); err != nil {
-		return nil, err
+		return err
+	}
+	if err := validatePointFormat(las.Header); err != nil {
+		return err
+	}
+	if f.readBufferSize <= 0 {
+		return fmt.Errorf("invalid read buffer size %d", f.readBufferSize)
+	}
+	if f.rangeStart < 0 {
+		return fmt.Errorf("invalid point range start %d", f.rangeStart)
+	}
+	for _, c := range f.colorMapping {
+		if c < ColorRed || c > ColorIntensity {
+			return fmt.Errorf("invalid color source %d", c)
+		}
+	}
+	if f.colorMapping[0] == ColorIntensity || f.colorMapping[1] == ColorIntensity || f.colorMapping[2] == ColorIntensity {
+		if !f.eightBitColor {
+			eightBit, err := f.intensityFitsEightBits()
+			if err != nil {
+				return err
+			}
+			f.eightBitIntensity = eightBit
+		}
+	}
+	if f.extraName != "" {
+		dim, err := findExtraDimension(las.VlrData, f.extraName, pointFormats[las.Header.PointFormatID].length)
+		if err != nil {
+			return err
+		}
+		if end := dim.offset + extraBytesTypeSizes[dim.dataType]; end > las.Header.PointRecordLength {
+			return fmt.Errorf("extra bytes dimension %s exceeds the point record length %d", f.extraName, las.Header.PointRecordLength)
+		}
+		f.extraDim = dim
+	}
+	return nil
+}
+
+// openFile opens the file, if not open yet, waiting for a slot of the limiter
+func (f *FileLasReader) openFile() error {
+	if f.f.f != nil {
+		return nil
+	}
+	f.limiter.acquire()
+	file, err := os.Open(f.f.fileName)
+	if err != nil {
+		f.limiter.release()
+		return err
 	}
-	return &FileLasReader{
-		f:             &las,
-		eightBitColor: eightBitColor,
-		srid:          srid,
-	}, nil
+	f.f.f = file
+	return nil
+}
+
+// closeFile closes the file, if open, releasing its slot of the limiter
+func (f *FileLasReader) closeFile() error {
+	if f.f.f == nil {
+		return nil
+	}
+	err := f.f.close()
+	f.f.f = nil
+	f.limiter.release()
+	return err
+}
+
+// Close closes the file. Reading the points from the first one opens it again.
+func (f *FileLasReader) Close() error {
+	f.Lock()
+	defer f.Unlock()
+	return f.closeFile()
 }
 
+// NumberOfPoints returns the number of points read, i.e. the points in the point range if one is set
 func (f *FileLasReader) NumberOfPoints() int {
-	return f.f.Header.NumberPoints
+	n := f.f.Header.NumberPoints - f.rangeStart
+	if f.rangeCount >= 0 && f.rangeCount < n {
+		n = f.rangeCount
+	}
+	if n < 0 {
+		return 0
+	}
+	return n
 }
 
 func (f *FileLasReader) GetNext() (geom.Point64, error) {
 	data := make([]byte, f.f.Header.PointRecordLength)
 	out := geom.Point64{}
 	f.Lock()
+	// the bytes following the last record, such as internal waveform packets, are not points
+	if f.current >= f.NumberOfPoints() {
+		f.Unlock()
+		return geom.Point64{}, ErrNoMorePoints
+	}
 	if f.current == 0 {
-		f.f.f.Seek(int64(f.f.Header.OffsetToPoints), 0)
-		f.r = bufio.NewReaderSize(f.f.f, 64*1024)
+		if err := f.openFile(); err != nil {
+			f.Unlock()
+			return geom.Point64{}, err
+		}
+		f.f.f.Seek(int64(f.f.Header.OffsetToPoints)+int64(f.rangeStart)*int64(f.f.Header.PointRecordLength), 0)
+		f.r = bufio.NewReaderSize(f.f.f, f.readBufferSize)
 	}
 	f.current = f.current + 1
+	// the index of the record in the file
+	index := f.rangeStart + f.current - 1
 	if _, err := io.ReadFull(f.r, data); err != nil {
 		f.Unlock()
 		return geom.Point64{}, err
 	}
+	if f.limiter != nil && f.current == f.NumberOfPoints() {
+		// the last record has been read, the file is closed to let the other readers open theirs
+		f.closeFile()
+	}
 	f.Unlock()
 	header := f.f.Header
-	xyzOffsetValues := xyzOffets[header.PointFormatID]
-	xOffset := xyzOffsetValues[0]
-	yOffset := xyzOffsetValues[1]
-	zOffset := xyzOffsetValues[2]
-	out.X = float64(int32(binary.LittleEndian.Uint32(data[xOffset:xOffset+4])))*header.XScaleFactor + header.XOffset
-	out.Y = float64(int32(binary.LittleEndian.Uint32(data[yOffset:yOffset+4])))*header.YScaleFactor + header.YOffset
-	out.Z = float64(int32(binary.LittleEndian.Uint32(data[zOffset:zOffset+4])))*header.ZScaleFactor + header.ZOffset
-
-	rgbOffsetValues := rgbOffets[header.PointFormatID]
-	if rgbOffsetValues != nil {
-		rOffset := rgbOffsetValues[0]
-		gOffset := rgbOffsetValues[1]
-		bOffset := rgbOffsetValues[2]
-		var conversionFactor = uint16(256)
+	format := pointFormats[header.PointFormatID]
+	out.X = float64(int32(binary.LittleEndian.Uint32(data[0:4])))*header.XScaleFactor + header.XOffset
+	out.Y = float64(int32(binary.LittleEndian.Uint32(data[4:8])))*header.YScaleFactor + header.YOffset
+	out.Z = float64(int32(binary.LittleEndian.Uint32(data[8:12])))*header.ZScaleFactor + header.ZOffset
+
+	if f.extraDim != nil {
+		out.Extra = f.extraDim.value(data)
+	}
+	if f.attributes&AttributeColor != 0 {
+		var channels [5]uint16
+		if format.rgbOffset >= 0 {
+			rgb := data[format.rgbOffset : format.rgbOffset+6]
+			channels[ColorRed] = binary.LittleEndian.Uint16(rgb[0:2])
+			channels[ColorGreen] = binary.LittleEndian.Uint16(rgb[2:4])
+			channels[ColorBlue] = binary.LittleEndian.Uint16(rgb[4:6])
+		}
+		if format.nirOffset >= 0 {
+			channels[ColorNIR] = binary.LittleEndian.Uint16(data[format.nirOffset : format.nirOffset+2])
+		}
+		channels[ColorIntensity] = binary.LittleEndian.Uint16(data[12:14])
 		if f.eightBitColor {
-			conversionFactor = uint16(1)
+			f.countColorOverflow(index, channels[f.colorMapping[0]], channels[f.colorMapping[1]], channels[f.colorMapping[2]])
 		}
-
-		out.R = uint8(binary.LittleEndian.Uint16(data[rOffset:rOffset+2]) / conversionFactor)
-		out.G = uint8(binary.LittleEndian.Uint16(data[gOffset:gOffset+2]) / conversionFactor)
-		out.B = uint8(binary.LittleEndian.Uint16(data[bOffset:bOffset+2]) / conversionFactor)
+		out.R = f.color(&channels, f.colorMapping[0])
+		out.G = f.color(&channels, f.colorMapping[1])
+		out.B = f.color(&channels, f.colorMapping[2])
+	}
+	if f.attributes&AttributeIntensity != 0 {
+		out.Intensity = uint8(binary.LittleEndian.Uint16(data[12:14]))
+	}
+	if f.attributes&AttributeGpsTime != 0 && format.gpsTimeOffset >= 0 {
+		out.GpsTime = math.Float64frombits(binary.LittleEndian.Uint64(data[format.gpsTimeOffset:]))
+	}
+	if f.attributes&AttributePointSourceId != 0 {
+		if format.extended {
+			out.PointSourceId = binary.LittleEndian.Uint16(data[extendedPointSourceIdOffset:])
+		} else {
+			out.PointSourceId = binary.LittleEndian.Uint16(data[pointSourceIdOffset:])
+		}
+	}
+	if f.attributes&AttributeClassification == 0 {
+		return out, nil
+	}
+	classification := data[format.classificationOffset]
+	if format.extended {
+		// extended formats use the full byte for the classification and store the flags
+		// in the low 4 bits of a separate byte, with the same layout used by geom.Point64
+		out.Classification = classification
+		out.Flags = data[flagsOffset] & 0b00001111
+		return out, nil
 	}
-	intensityOffset := 12
-	out.Intensity = uint8(binary.LittleEndian.Uint16(data[intensityOffset : intensityOffset+2]))
-	classificationOffset := classificationOffets[header.PointFormatID]
-	classification := data[classificationOffset]
 	// the upper 3 high bits are used for metadata and not for the actual classification
 	// so wipe them out
 	out.Classification = uint8(classification & 0b00011111)
-
+	// legacy formats store synthetic, key-point and withheld in the 3 high bits of the
+	// classification byte, while overlap points are marked with the dedicated class 12
+	out.Flags = (classification >> 5) & 0b00000111
+	if out.Classification == 12 {
+		out.Flags |= geom.FlagOverlap
+	}
 	return out, nil
 }
 
+// validatePointFormat checks that the point records described by the header can be parsed
+func validatePointFormat(header lasHeader) error {
+	if header.PointFormatID&0b11000000 != 0 {
+		return fmt.Errorf("point format %d denotes a compressed (LAZ) file, which is not supported", header.PointFormatID)
+	}
+	if int(header.PointFormatID) >= len(pointFormats) {
+		return fmt.Errorf("unsupported point format %d", header.PointFormatID)
+	}
+	if expected := pointFormats[header.PointFormatID].length; header.PointRecordLength < expected {
+		return fmt.Errorf("invalid point record length %d for point format %d, expected at least %d bytes", header.PointRecordLength, header.PointFormatID, expected)
+	}
+	return nil
+}
+
 func (f *FileLasReader) GetSrid() int {
 	return f.srid
 }
+
+// MinZ returns the minimum elevation declared in the header of the file
+func (f *FileLasReader) MinZ() float64 {
+	return f.f.Header.MinZ
+}
+
+// MaxZ returns the maximum elevation declared in the header of the file
+func (f *FileLasReader) MaxZ() float64 {
+	return f.f.Header.MaxZ
+}
+
+// Extent returns the X, Y extent of the points declared in the header of the file
+func (f *FileLasReader) Extent() (minX, minY, maxX, maxY float64) {
+	h := f.f.Header
+	return h.MinX, h.MinY, h.MaxX, h.MaxY
+}
+
+// color converts the value of the channel c to an 8 bit color, scaling the 16 bit values
+func (f *FileLasReader) color(channels *[5]uint16, c ColorSource) uint8 {
+	if f.eightBitColor || (c == ColorIntensity && f.eightBitIntensity) {
+		return uint8(channels[c])
+	}
+	return uint8(channels[c] / 256)
+}
+
+// intensityFitsEightBits scans the intensities of all the records of the file, not only of the point range, so that
+// the readers of the ranges of a file agree, stopping at the first one exceeding 255
+func (f *FileLasReader) intensityFitsEightBits() (bool, error) {
+	header := f.f.Header
+	length := int64(header.PointRecordLength)
+	section := io.NewSectionReader(f.f.f, int64(header.OffsetToPoints), int64(header.NumberPoints)*length)
+	r := bufio.NewReaderSize(section, f.readBufferSize)
+	data := make([]byte, length)
+	for i := 0; i < header.NumberPoints; i++ {
+		if _, err := io.ReadFull(r, data); err != nil {
+			return false, err
+		}
+		if binary.LittleEndian.Uint16(data[12:14]) > math.MaxUint8 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// countColorOverflow counts the point if any of its 8 bit colors exceeds 255
+func (f *FileLasReader) countColorOverflow(index int, r, g, b uint16) {
+	if r <= math.MaxUint8 && g <= math.MaxUint8 && b <= math.MaxUint8 {
+		return
+	}
+	f.Lock()
+	defer f.Unlock()
+	if f.colorOverflows == 0 {
+		f.colorOverflowSample = fmt.Sprintf("point %d of %s has color %d, %d, %d", index, f.f.fileName, r, g, b)
+	}
+	f.colorOverflows++
+}
+
+// ColorOverflows returns the number of points read so far whose 8 bit colors exceed 255 and a description of the first one
+func (f *FileLasReader) ColorOverflows() (int, string) {
+	f.Lock()
+	defer f.Unlock()
+	return f.colorOverflows, f.colorOverflowSample
+}
+
+// Quantization returns the scale and offset of the coordinates declared in the header of the file
+func (f *FileLasReader) Quantization() []Quantization {
+	h := f.f.Header
+	return []Quantization{{
+		File:   f.f.fileName,
+		Scale:  [3]float64{h.XScaleFactor, h.YScaleFactor, h.ZScaleFactor},
+		Offset: [3]float64{h.XOffset, h.YOffset, h.ZOffset},
+	}}
+}
@@ -1,7 +1,10 @@
 package las
 
 import (
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"math"
 	"os"
 	"testing"
 
@@ -33,6 +36,38 @@ func TestCombinedReader(t *testing.T) {
 		t.Errorf("expected epsg %d got epsg %d", 32633, actual)
 	}
 
+	minZ, maxZ := math.Inf(1), math.Inf(-1)
+	for _, fr := range r.readers {
+		minZ, maxZ = math.Min(minZ, fr.f.Header.MinZ), math.Max(maxZ, fr.f.Header.MaxZ)
+	}
+	if r.MinZ() != minZ || r.MaxZ() != maxZ || minZ > maxZ {
+		t.Errorf("expected elevation range [%v, %v] got [%v, %v]", minZ, maxZ, r.MinZ(), r.MaxZ())
+	}
+
+	minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for _, fr := range r.readers {
+		h := fr.f.Header
+		minX, minY = math.Min(minX, h.MinX), math.Min(minY, h.MinY)
+		maxX, maxY = math.Max(maxX, h.MaxX), math.Max(maxY, h.MaxY)
+	}
+	if aMinX, aMinY, aMaxX, aMaxY := r.Extent(); aMinX != minX || aMinY != minY || aMaxX != maxX || aMaxY != maxY || minX > maxX {
+		t.Errorf("expected extent [%v, %v, %v, %v] got [%v, %v, %v, %v]", minX, minY, maxX, maxY, aMinX, aMinY, aMaxX, aMaxY)
+	}
+
+	q := r.Quantization()
+	if len(q) != len(files) {
+		t.Fatalf("expected %d quantizations got %d", len(files), len(q))
+	}
+	for i, fr := range r.readers {
+		h := fr.f.Header
+		if q[i].File != files[i] || q[i].Scale != [3]float64{h.XScaleFactor, h.YScaleFactor, h.ZScaleFactor} || q[i].Offset != [3]float64{h.XOffset, h.YOffset, h.ZOffset} {
+			t.Errorf("unexpected quantization %v for file %s", q[i], files[i])
+		}
+		if q[i].Scale[0] == 0 {
+			t.Errorf("expected non zero scale for file %s", files[i])
+		}
+	}
+
 	for i := 0; i < r.NumberOfPoints(); i++ {
 		_, err := r.GetNext()
 		if err != nil {
@@ -40,8 +75,45 @@ func TestCombinedReader(t *testing.T) {
 		}
 	}
 	_, err = r.GetNext()
-	if err == nil {
-		t.Errorf("expected error, got none")
+	if !errors.Is(err, ErrNoMorePoints) {
+		t.Errorf("expected error %v, got %v", ErrNoMorePoints, err)
+	}
+}
+
+func TestCombinedReaderCurrentFile(t *testing.T) {
+	files := []string{"./testdata/las-12-pf1.las", "./testdata/las-13-pf4.las"}
+	r, err := NewCombinedFileLasReader(files, 32633, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 0; i < r.NumberOfPoints(); i++ {
+		pt, err := r.GetNext()
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if expected, actual := files[i/10], r.CurrentFile(); actual != expected {
+			t.Errorf("expected current file %s for point %d got %s", expected, i, actual)
+		}
+		if expected, actual := files[i/10], r.FileName(pt.FileIndex); actual != expected {
+			t.Errorf("expected file %s for point %d got %s", expected, i, actual)
+		}
+	}
+	if actual := r.FileName(len(files)); actual != "" {
+		t.Errorf("expected no file name for invalid index, got %s", actual)
+	}
+}
+
+func TestCombinedReaderFileError(t *testing.T) {
+	_, err := NewCombinedFileLasReader([]string{"./testdata/las-12-pf1.las", "./testdata/missing.las"}, 32633, false)
+	var fileErr *FileError
+	if !errors.As(err, &fileErr) {
+		t.Fatalf("expected a FileError, got %v", err)
+	}
+	if fileErr.File != "./testdata/missing.las" {
+		t.Errorf("expected error for file %s got %s", "./testdata/missing.las", fileErr.File)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected the wrapped error to be preserved, got %v", err)
 	}
 }
 
@@ -92,16 +164,21 @@ func TestReader(t *testing.T) {
 			if err != nil {
 				t.Errorf("unexpected error returned: %v", err)
 			}
+			expected := expectedWcolor[i]
 			if r.f.Header.PointFormatID == 1 || r.f.Header.PointFormatID == 4 {
-				if actual != expectedWOcolor[i] {
-					t.Errorf("for file %s, expected point %v got %v", filename, expectedWOcolor[i], actual)
-				}
-			} else {
-				if actual != expectedWcolor[i] {
-					t.Errorf("for file %s, expected point %v got %v", filename, expectedWcolor[i], actual)
-				}
+				expected = expectedWOcolor[i]
+			}
+			// the legacy format test files have the synthetic bit set in the classification byte
+			if r.f.Header.PointFormatID < 6 {
+				expected.Flags = geom.FlagSynthetic
+			}
+			if actual != expected {
+				t.Errorf("for file %s, expected point %v got %v", filename, expected, actual)
 			}
 		}
+		if _, err := r.GetNext(); !errors.Is(err, ErrNoMorePoints) {
+			t.Errorf("for file %s, expected error %v after the last point, got %v", filename, ErrNoMorePoints, err)
+		}
 	}
 
 }
@@ -147,3 +224,516 @@ Waveform Data Start: 0
 	}
 
 }
+
+// testRecordLayout lists the offsets of the fields of each point format as defined by the LAS specification
+var testRecordLayout = []struct {
+	format         byte
+	length         int
+	classification int
+	flags          int // offset of the classification flags byte, -1 for legacy formats
+	rgb            int // -1 if the format has no color
+	pointSourceId  int
+	gpsTime        int // -1 if the format has no GPS time
+}{
+	{0, 20, 15, -1, -1, 18, -1},
+	{1, 28, 15, -1, -1, 18, 20},
+	{2, 26, 15, -1, 20, 18, -1},
+	{3, 34, 15, -1, 28, 18, 20},
+	{4, 57, 15, -1, -1, 18, 20},
+	{5, 63, 15, -1, 28, 18, 20},
+	{6, 30, 16, 15, -1, 20, 22},
+	{7, 36, 16, 15, 30, 20, 22},
+	{8, 38, 16, 15, 30, 20, 22},
+	{9, 59, 16, 15, -1, 20, 22},
+	{10, 67, 16, 15, 30, 20, 22},
+}
+
+// writeTestLas writes a LAS 1.4 file with the given point format and raw point records
+func writeTestLas(t *testing.T, format byte, recordLength int, records [][]byte) string {
+	t.Helper()
+	header := make([]byte, 375)
+	copy(header[0:4], "LASF")
+	header[24] = 1
+	header[25] = 4
+	binary.LittleEndian.PutUint16(header[94:96], 375)
+	binary.LittleEndian.PutUint32(header[96:100], 375)
+	header[104] = format
+	binary.LittleEndian.PutUint16(header[105:107], uint16(recordLength))
+	for i, scale := range []float64{0.01, 0.01, 0.01} {
+		binary.LittleEndian.PutUint64(header[131+i*8:139+i*8], math.Float64bits(scale))
+	}
+	for i, offset := range []float64{1000, 2000, 10} {
+		binary.LittleEndian.PutUint64(header[155+i*8:163+i*8], math.Float64bits(offset))
+	}
+	binary.LittleEndian.PutUint64(header[247:255], uint64(len(records)))
+
+	f, err := os.CreateTemp(t.TempDir(), "*.las")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(header); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	for _, r := range records {
+		if _, err := f.Write(r); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+	return f.Name()
+}
+
+func TestReaderPointFormats(t *testing.T) {
+	for _, layout := range testRecordLayout {
+		rec := make([]byte, layout.length)
+		x, y, z := int32(150), int32(-250), int32(320)
+		binary.LittleEndian.PutUint32(rec[0:4], uint32(x))
+		binary.LittleEndian.PutUint32(rec[4:8], uint32(y))
+		binary.LittleEndian.PutUint32(rec[8:12], uint32(z))
+		binary.LittleEndian.PutUint16(rec[12:14], 42)
+		binary.LittleEndian.PutUint16(rec[layout.pointSourceId:], 513)
+		expected := geom.Point64{X: 1001.5, Y: 1997.5, Z: 13.2, Intensity: 42, PointSourceId: 513}
+		if layout.flags >= 0 {
+			// extended formats: full byte classification, withheld and overlap flags set,
+			// scanner channel bits set to ensure they are ignored
+			rec[layout.classification] = 64
+			rec[layout.flags] = 0b00111100
+			expected.Classification = 64
+			expected.Flags = geom.FlagWithheld | geom.FlagOverlap
+		} else {
+			// legacy formats: classification 6 with the key-point bit set
+			rec[layout.classification] = 6 | 0b01000000
+			expected.Classification = 6
+			expected.Flags = geom.FlagKeyPoint
+		}
+		if layout.rgb >= 0 {
+			binary.LittleEndian.PutUint16(rec[layout.rgb:], 10*256)
+			binary.LittleEndian.PutUint16(rec[layout.rgb+2:], 20*256)
+			binary.LittleEndian.PutUint16(rec[layout.rgb+4:], 30*256)
+			expected.R, expected.G, expected.B = 10, 20, 30
+		}
+		if layout.gpsTime >= 0 {
+			binary.LittleEndian.PutUint64(rec[layout.gpsTime:], math.Float64bits(3.5e8))
+			expected.GpsTime = 3.5e8
+		}
+
+		file := writeTestLas(t, layout.format, layout.length, [][]byte{rec})
+		r, err := NewFileLasReader(file, 32633, false)
+		if err != nil {
+			t.Fatalf("format %d: unexpected error %v", layout.format, err)
+		}
+		if actual := r.NumberOfPoints(); actual != 1 {
+			t.Errorf("format %d: expected %d points got %d", layout.format, 1, actual)
+		}
+		actual, err := r.GetNext()
+		if err != nil {
+			t.Fatalf("format %d: unexpected error %v", layout.format, err)
+		}
+		if math.Abs(actual.X-expected.X) > 1e-9 || math.Abs(actual.Y-expected.Y) > 1e-9 || math.Abs(actual.Z-expected.Z) > 1e-9 {
+			t.Errorf("format %d: expected coordinates %v %v %v got %v %v %v", layout.format, expected.X, expected.Y, expected.Z, actual.X, actual.Y, actual.Z)
+		}
+		actual.X, actual.Y, actual.Z = expected.X, expected.Y, expected.Z
+		if actual != expected {
+			t.Errorf("format %d: expected point %v got %v", layout.format, expected, actual)
+		}
+	}
+}
+
+func TestReaderWaveformPackets(t *testing.T) {
+	// format 5 records: the format 3 fields followed by the wave packet descriptor index, the offset and size of
+	// the waveform packet, the return point location and the parametric line, all of them to be ignored
+	recs := [][]byte{make([]byte, 63), make([]byte, 63)}
+	for i, rec := range recs {
+		binary.LittleEndian.PutUint32(rec[0:4], uint32(100*(i+1)))
+		binary.LittleEndian.PutUint32(rec[4:8], uint32(200*(i+1)))
+		binary.LittleEndian.PutUint32(rec[8:12], uint32(300*(i+1)))
+		rec[15] = 2
+		binary.LittleEndian.PutUint16(rec[28:30], uint16(10*(i+1))*256)
+		rec[34] = 1
+		binary.LittleEndian.PutUint64(rec[35:43], uint64(64*i))
+		binary.LittleEndian.PutUint32(rec[43:47], 64)
+		for j := 47; j < 63; j++ {
+			rec[j] = 0xff
+		}
+	}
+	file := writeTestLas(t, 5, 63, recs)
+	// flag the waveform data as internal and store the packets after the point records
+	f, err := os.OpenFile(file, os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, err := f.WriteAt([]byte{0b00000010, 0}, 6); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, err := f.WriteAt(make([]byte, 128), 375+2*63); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	f.Close()
+
+	r, err := NewFileLasReader(file, 32633, false)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if actual := r.NumberOfPoints(); actual != 2 {
+		t.Fatalf("expected %d points got %d", 2, actual)
+	}
+	for i := 0; i < 2; i++ {
+		pt, err := r.GetNext()
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		x, y, z := 1000+float64(i+1), 2000+2*float64(i+1), 10+3*float64(i+1)
+		if math.Abs(pt.X-x) > 1e-9 || math.Abs(pt.Y-y) > 1e-9 || math.Abs(pt.Z-z) > 1e-9 {
+			t.Errorf("point %d: expected coordinates %v %v %v got %v %v %v", i, x, y, z, pt.X, pt.Y, pt.Z)
+		}
+		if pt.R != uint8(10*(i+1)) || pt.Classification != 2 {
+			t.Errorf("point %d: expected red %d and classification 2 got %d and %d", i, 10*(i+1), pt.R, pt.Classification)
+		}
+	}
+	if _, err := r.GetNext(); err == nil {
+		t.Errorf("expected error reading past the last point, got none")
+	}
+}
+
+func TestReaderExtraBytes(t *testing.T) {
+	// records longer than the format length carry extra bytes that must be skipped
+	recs := [][]byte{make([]byte, 34), make([]byte, 34)}
+	recs[0][16] = 2
+	recs[1][16] = 5
+	file := writeTestLas(t, 6, 34, recs)
+	r, err := NewFileLasReader(file, 32633, false)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	for _, expected := range []uint8{2, 5} {
+		pt, err := r.GetNext()
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if pt.Classification != expected {
+			t.Errorf("expected classification %d got %d", expected, pt.Classification)
+		}
+	}
+}
+
+func TestReaderColorChannelMapping(t *testing.T) {
+	// format 8 stores R, G, B at offset 30 followed by NIR at offset 36
+	rec := make([]byte, 38)
+	binary.LittleEndian.PutUint16(rec[12:14], 50*256)
+	binary.LittleEndian.PutUint16(rec[30:32], 10*256)
+	binary.LittleEndian.PutUint16(rec[32:34], 20*256)
+	binary.LittleEndian.PutUint16(rec[34:36], 30*256)
+	binary.LittleEndian.PutUint16(rec[36:38], 40*256)
+	file := writeTestLas(t, 8, 38, [][]byte{rec})
+	// format 3 has no NIR channel
+	rec3 := make([]byte, 34)
+	binary.LittleEndian.PutUint16(rec3[28:30], 10*256)
+	file3 := writeTestLas(t, 3, 34, [][]byte{rec3})
+
+	cases := []struct {
+		file     string
+		mapping  [3]ColorSource
+		expected [3]uint8
+	}{
+		{file, [3]ColorSource{ColorNIR, ColorRed, ColorGreen}, [3]uint8{40, 10, 20}},
+		{file, [3]ColorSource{ColorIntensity, ColorIntensity, ColorBlue}, [3]uint8{50, 50, 30}},
+		{file3, [3]ColorSource{ColorNIR, ColorRed, ColorRed}, [3]uint8{0, 10, 10}},
+	}
+	for _, c := range cases {
+		r, err := NewFileLasReader(c.file, 32633, false, WithColorChannelMapping(c.mapping[0], c.mapping[1], c.mapping[2]))
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		pt, err := r.GetNext()
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if actual := [3]uint8{pt.R, pt.G, pt.B}; actual != c.expected {
+			t.Errorf("mapping %v: expected colors %v got %v", c.mapping, c.expected, actual)
+		}
+	}
+	if _, err := NewFileLasReader(file, 32633, false, WithColorChannelMapping(ColorRed, ColorGreen, ColorSource(9))); err == nil {
+		t.Errorf("expected error for an invalid color source")
+	}
+}
+
+func TestReaderColorOverflows(t *testing.T) {
+	// format 3 stores R, G, B at offset 28
+	valid := make([]byte, 34)
+	binary.LittleEndian.PutUint16(valid[28:30], 10)
+	binary.LittleEndian.PutUint16(valid[30:32], 255)
+	overflow := make([]byte, 34)
+	binary.LittleEndian.PutUint16(overflow[32:34], 300)
+	file := writeTestLas(t, 3, 34, [][]byte{valid, overflow, overflow})
+
+	r, err := NewCombinedFileLasReader([]string{file, file}, 32633, true)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	for i := 0; i < r.NumberOfPoints(); i++ {
+		if _, err := r.GetNext(); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+	n, sample := r.ColorOverflows()
+	if n != 4 {
+		t.Errorf("expected %d overflows got %d", 4, n)
+	}
+	if expected := fmt.Sprintf("point 1 of %s has color 0, 0, 300", file); sample != expected {
+		t.Errorf("expected sample %q got %q", expected, sample)
+	}
+
+	// 16 bit colors are scaled, hence never truncated
+	r16, err := NewFileLasReader(file, 32633, false)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	for i := 0; i < r16.NumberOfPoints(); i++ {
+		if _, err := r16.GetNext(); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+	if n, _ := r16.ColorOverflows(); n != 0 {
+		t.Errorf("expected no overflows got %d", n)
+	}
+}
+
+func TestReaderAttributes(t *testing.T) {
+	// format 7 record with all the attributes set
+	rec := make([]byte, 36)
+	binary.LittleEndian.PutUint32(rec[0:4], 100)
+	binary.LittleEndian.PutUint16(rec[12:14], 42)
+	rec[15] = 0b00000100
+	rec[16] = 6
+	binary.LittleEndian.PutUint16(rec[20:22], 513)
+	binary.LittleEndian.PutUint64(rec[22:30], math.Float64bits(3.5e8))
+	binary.LittleEndian.PutUint16(rec[30:32], 10*256)
+	binary.LittleEndian.PutUint16(rec[32:34], 20*256)
+	binary.LittleEndian.PutUint16(rec[34:36], 30*256)
+	file := writeTestLas(t, 7, 36, [][]byte{rec})
+
+	all := geom.Point64{R: 10, G: 20, B: 30, Intensity: 42, Classification: 6, Flags: geom.FlagWithheld, GpsTime: 3.5e8, PointSourceId: 513}
+	cases := []struct {
+		attributes Attributes
+		expected   geom.Point64
+	}{
+		{AllAttributes, all},
+		{0, geom.Point64{}},
+		{AttributeColor, geom.Point64{R: 10, G: 20, B: 30}},
+		{AttributeIntensity | AttributeGpsTime, geom.Point64{Intensity: 42, GpsTime: 3.5e8}},
+		{AttributeClassification | AttributePointSourceId, geom.Point64{Classification: 6, Flags: geom.FlagWithheld, PointSourceId: 513}},
+	}
+	for _, c := range cases {
+		r, err := NewFileLasReader(file, 32633, false, WithAttributes(c.attributes))
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		pt, err := r.GetNext()
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		// the coordinates are always decoded
+		if pt.X != 1001 {
+			t.Errorf("attributes %b: expected X %v got %v", c.attributes, 1001, pt.X)
+		}
+		pt.X, pt.Y, pt.Z = 0, 0, 0
+		if pt != c.expected {
+			t.Errorf("attributes %b: expected point %v got %v", c.attributes, c.expected, pt)
+		}
+	}
+}
+
+func TestReaderColorChannelMappingEightBitIntensity(t *testing.T) {
+	// the 8 bit intensities are used as they are, the 16 bit colors of the same file are still scaled
+	rec := make([]byte, 34)
+	binary.LittleEndian.PutUint16(rec[12:14], 200)
+	binary.LittleEndian.PutUint16(rec[28:30], 10*256)
+	file := writeTestLas(t, 3, 34, [][]byte{rec, rec})
+	// a single intensity exceeding 255 makes all the intensities of the file 16 bit values
+	rec16 := make([]byte, 34)
+	binary.LittleEndian.PutUint16(rec16[12:14], 200)
+	rec16b := make([]byte, 34)
+	binary.LittleEndian.PutUint16(rec16b[12:14], 50*256)
+	file16 := writeTestLas(t, 3, 34, [][]byte{rec16, rec16b})
+
+	cases := []struct {
+		file     string
+		opts     []func(*FileLasReader)
+		expected [3]uint8
+	}{
+		{file, nil, [3]uint8{200, 200, 10}},
+		{file, []func(*FileLasReader){WithPointRange(1, 1)}, [3]uint8{200, 200, 10}},
+		{file16, nil, [3]uint8{0, 0, 0}},
+		{file16, []func(*FileLasReader){WithPointRange(1, 1)}, [3]uint8{50, 50, 0}},
+	}
+	for i, c := range cases {
+		opts := append([]func(*FileLasReader){WithColorChannelMapping(ColorIntensity, ColorIntensity, ColorRed)}, c.opts...)
+		r, err := NewFileLasReader(c.file, 32633, false, opts...)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		pt, err := r.GetNext()
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if actual := [3]uint8{pt.R, pt.G, pt.B}; actual != c.expected {
+			t.Errorf("case %d: expected colors %v got %v", i, c.expected, actual)
+		}
+	}
+}
+
+func TestReaderInvalidPointFormat(t *testing.T) {
+	cases := []struct {
+		format byte
+		length int
+	}{
+		{11, 30},  // unknown format
+		{7, 30},   // record too short
+		{131, 34}, // LAZ compressed format 3
+	}
+	for _, c := range cases {
+		file := writeTestLas(t, c.format, c.length, nil)
+		if _, err := NewFileLasReader(file, 32633, false); err == nil {
+			t.Errorf("expected error for format %d with record length %d, got none", c.format, c.length)
+		}
+	}
+}
+
+func TestReaderBufferSize(t *testing.T) {
+	r, err := NewFileLasReader("./testdata/las-12-pf2.las", 32633, false, WithReadBufferSize(16))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if r.readBufferSize != 16 {
+		t.Errorf("expected buffer size %d got %d", 16, r.readBufferSize)
+	}
+	ref, err := NewFileLasReader("./testdata/las-12-pf2.las", 32633, false)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if ref.readBufferSize != DefaultReadBufferSize {
+		t.Errorf("expected buffer size %d got %d", DefaultReadBufferSize, ref.readBufferSize)
+	}
+	for i := 0; i < r.NumberOfPoints(); i++ {
+		actual, err := r.GetNext()
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		expected, _ := ref.GetNext()
+		if actual != expected {
+			t.Errorf("expected point %v got %v", expected, actual)
+		}
+	}
+	for _, size := range []int{0, -1} {
+		if _, err := NewFileLasReader("./testdata/las-12-pf2.las", 32633, false, WithReadBufferSize(size)); err == nil {
+			t.Errorf("expected error for buffer size %d, got none", size)
+		}
+	}
+}
+
+func TestReaderPointRange(t *testing.T) {
+	ref, err := NewFileLasReader("./testdata/las-12-pf2.las", 32633, false)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	all := make([]geom.Point64, ref.NumberOfPoints())
+	for i := range all {
+		if all[i], err = ref.GetNext(); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+	n := len(all)
+	for _, tc := range []struct {
+		start, count, expected int
+	}{
+		{start: 3, count: 5, expected: 5},
+		{start: 0, count: -1, expected: n},
+		{start: n - 2, count: 10, expected: 2},
+		{start: n + 1, count: 10, expected: 0},
+	} {
+		r, err := NewFileLasReader("./testdata/las-12-pf2.las", 32633, false, WithPointRange(tc.start, tc.count))
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if actual := r.NumberOfPoints(); actual != tc.expected {
+			t.Errorf("range %d+%d: expected %d points got %d", tc.start, tc.count, tc.expected, actual)
+		}
+		for i := 0; i < tc.expected; i++ {
+			actual, err := r.GetNext()
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if expected := all[tc.start+i]; actual != expected {
+				t.Errorf("expected point %v got %v", expected, actual)
+			}
+		}
+		if _, err := r.GetNext(); err == nil {
+			t.Errorf("range %d+%d: expected error reading past the range, got none", tc.start, tc.count)
+		}
+	}
+	if _, err := NewFileLasReader("./testdata/las-12-pf2.las", 32633, false, WithPointRange(-1, 2)); err == nil {
+		t.Errorf("expected error for a negative start, got none")
+	}
+}
+
+func TestReaderOpenFileLimiter(t *testing.T) {
+	limiter := NewOpenFileLimiter(1)
+	files := []string{"./testdata/las-12-pf1.las", "./testdata/las-12-pf2.las"}
+	r, err := NewCombinedFileLasReader(files, 32633, false, WithOpenFileLimiter(limiter))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	for _, fr := range r.readers {
+		if fr.f.f != nil {
+			t.Errorf("expected the file %s to be closed after reading the header", fr.f.fileName)
+		}
+	}
+	for i := 0; i < r.NumberOfPoints(); i++ {
+		if _, err := r.GetNext(); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if actual := len(limiter.slots); actual > 1 {
+			t.Fatalf("point %d: expected at most %d open files got %d", i, 1, actual)
+		}
+		if i == r.readers[0].NumberOfPoints()-1 && r.readers[0].f.f != nil {
+			t.Errorf("expected the first file to be closed once read")
+		}
+	}
+	if actual := len(limiter.slots); actual != 0 {
+		t.Errorf("expected no open files once all the points are read, got %d", actual)
+	}
+
+	// closing a reader releases its slot
+	fr, err := NewFileLasReader(files[0], 32633, false, WithOpenFileLimiter(limiter))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, err := fr.GetNext(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := fr.Close(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if actual := len(limiter.slots); actual != 0 {
+		t.Errorf("expected no open files after closing the reader, got %d", actual)
+	}
+
+	if NewOpenFileLimiter(0) != nil {
+		t.Errorf("expected no limiter for a non positive number of files")
+	}
+}
+
+func TestCombinedReaderClose(t *testing.T) {
+	r, err := NewCombinedFileLasReader([]string{"./testdata/las-12-pf1.las", "./testdata/las-12-pf2.las"}, 32633, false)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	for _, fr := range r.readers {
+		if fr.f.f != nil {
+			t.Errorf("expected the file %s to be closed", fr.f.fileName)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package las
+
+import (
+	"math"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+)
+
+// SliceLasReader reads the points from an in-memory slice as if they were stored in a LAS file
+type SliceLasReader struct {
+	pts              []geom.Point64
+	cur              int
+	srid             int
+	minX, minY, minZ float64
+	maxX, maxY, maxZ float64
+}
+
+// NewSliceLasReader returns a reader of the given points, expressed in the CRS with the given EPSG code
+func NewSliceLasReader(pts []geom.Point64, srid int) *SliceLasReader {
+	r := &SliceLasReader{
+		pts:  pts,
+		srid: srid,
+		minX: math.Inf(1), minY: math.Inf(1), minZ: math.Inf(1),
+		maxX: math.Inf(-1), maxY: math.Inf(-1), maxZ: math.Inf(-1),
+	}
+	for _, pt := range pts {
+		r.minX, r.minY, r.minZ = math.Min(r.minX, pt.X), math.Min(r.minY, pt.Y), math.Min(r.minZ, pt.Z)
+		r.maxX, r.maxY, r.maxZ = math.Max(r.maxX, pt.X), math.Max(r.maxY, pt.Y), math.Max(r.maxZ, pt.Z)
+	}
+	return r
+}
+
+func (r *SliceLasReader) NumberOfPoints() int {
+	return len(r.pts)
+}
+
+func (r *SliceLasReader) GetSrid() int {
+	return r.srid
+}
+
+func (r *SliceLasReader) GetNext() (geom.Point64, error) {
+	if r.cur >= len(r.pts) {
+		return geom.Point64{}, ErrNoMorePoints
+	}
+	r.cur++
+	return r.pts[r.cur-1], nil
+}
+
+// MinZ returns the minimum elevation of the points
+func (r *SliceLasReader) MinZ() float64 {
+	return r.minZ
+}
+
+// MaxZ returns the maximum elevation of the points
+func (r *SliceLasReader) MaxZ() float64 {
+	return r.maxZ
+}
+
+// Extent returns the X, Y extent of the points
+func (r *SliceLasReader) Extent() (minX, minY, maxX, maxY float64) {
+	return r.minX, r.minY, r.maxX, r.maxY
+}
@@ -0,0 +1,37 @@
+package las
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+)
+
+func TestSliceLasReader(t *testing.T) {
+	pts := []geom.Point64{{X: 1, Y: 5, Z: 3, Intensity: 1}, {X: -2, Y: 7, Z: 10, Intensity: 2}}
+	r := NewSliceLasReader(pts, 32633)
+	if actual := r.NumberOfPoints(); actual != 2 {
+		t.Errorf("expected %d points got %d", 2, actual)
+	}
+	if actual := r.GetSrid(); actual != 32633 {
+		t.Errorf("expected epsg %d got epsg %d", 32633, actual)
+	}
+	if r.MinZ() != 3 || r.MaxZ() != 10 {
+		t.Errorf("expected elevation range [%v, %v] got [%v, %v]", 3, 10, r.MinZ(), r.MaxZ())
+	}
+	if minX, minY, maxX, maxY := r.Extent(); minX != -2 || minY != 5 || maxX != 1 || maxY != 7 {
+		t.Errorf("expected extent [%v, %v, %v, %v] got [%v, %v, %v, %v]", -2, 5, 1, 7, minX, minY, maxX, maxY)
+	}
+	for i, expected := range pts {
+		actual, err := r.GetNext()
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if actual != expected {
+			t.Errorf("point %d: expected %v got %v", i, expected, actual)
+		}
+	}
+	if _, err := r.GetNext(); !errors.Is(err, ErrNoMorePoints) {
+		t.Errorf("expected error %v after the last point, got %v", ErrNoMorePoints, err)
+	}
+}
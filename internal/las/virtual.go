@@ -0,0 +1,140 @@
+package las
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Transform is an affine transform applied to the coordinates of the points of a file, each coordinate is
+// multiplied by the scale and then the offset is added
+type Transform struct {
+	Offset [3]float64 `json:"offset"`
+	Scale  [3]float64 `json:"scale"`
+}
+
+// identityTransform leaves the coordinates unchanged
+var identityTransform = Transform{Scale: [3]float64{1, 1, 1}}
+
+func (t Transform) apply(x, y, z float64) (float64, float64, float64) {
+	return x*t.Scale[0] + t.Offset[0], y*t.Scale[1] + t.Offset[1], z*t.Scale[2] + t.Offset[2]
+}
+
+// VirtualFile is a LAS file part of a virtual cloud, with the transform applied to its points
+type VirtualFile struct {
+	Path string `json:"path"`
+	Transform
+}
+
+// virtualCloud is the content of a virtual cloud descriptor, e.g.:
+//
+//	{
+//	  "files": [
+//	    {"path": "a.las"},
+//	    {"path": "b.las", "offset": [0, 0, -32.5], "scale": [1, 1, 0.3048]}
+//	  ]
+//	}
+//
+// The offset defaults to 0 and the scale to 1 for each coordinate.
+type virtualCloud struct {
+	Files []VirtualFile `json:"files"`
+}
+
+// IsVirtualCloud returns whether the given input file is a virtual cloud descriptor, i.e. a .json file
+func IsVirtualCloud(file string) bool {
+	return strings.EqualFold(filepath.Ext(file), ".json")
+}
+
+// ReadVirtualCloud reads the LAS files listed by a virtual cloud descriptor, in order. Relative paths are resolved
+// against the folder of the descriptor.
+func ReadVirtualCloud(descriptor string) ([]VirtualFile, error) {
+	data, err := os.ReadFile(descriptor)
+	if err != nil {
+		return nil, err
+	}
+	cloud := virtualCloud{}
+	if err := json.Unmarshal(data, &cloud); err != nil {
+		return nil, fmt.Errorf("invalid virtual cloud %s: %w", descriptor, err)
+	}
+	if len(cloud.Files) == 0 {
+		return nil, fmt.Errorf("invalid virtual cloud %s: no files listed", descriptor)
+	}
+	// the scale is checked on the raw json, as a missing scale decodes as 0
+	raw := struct {
+		Files []struct {
+			Scale *[3]float64 `json:"scale"`
+		} `json:"files"`
+	}{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid virtual cloud %s: %w", descriptor, err)
+	}
+	for i := range cloud.Files {
+		f := &cloud.Files[i]
+		if f.Path == "" {
+			return nil, fmt.Errorf("invalid virtual cloud %s: file %d has no path", descriptor, i)
+		}
+		if IsVirtualCloud(f.Path) {
+			return nil, fmt.Errorf("invalid virtual cloud %s: nested descriptor %s is not supported", descriptor, f.Path)
+		}
+		if !filepath.IsAbs(f.Path) {
+			f.Path = filepath.Join(filepath.Dir(descriptor), f.Path)
+		}
+		if raw.Files[i].Scale == nil {
+			f.Scale = identityTransform.Scale
+		}
+		if f.Scale[0] == 0 || f.Scale[1] == 0 || f.Scale[2] == 0 {
+			return nil, fmt.Errorf("invalid virtual cloud %s: file %s has a zero scale", descriptor, f.Path)
+		}
+	}
+	return cloud.Files, nil
+}
+
+// ExpandVirtualClouds replaces the virtual cloud descriptors among the given input files with the files they list,
+// the other files are returned as is with an identity transform
+func ExpandVirtualClouds(inputs []string) ([]VirtualFile, error) {
+	files := []VirtualFile{}
+	for _, input := range inputs {
+		if !IsVirtualCloud(input) {
+			files = append(files, VirtualFile{Path: input, Transform: identityTransform})
+			continue
+		}
+		listed, err := ReadVirtualCloud(input)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, listed...)
+	}
+	return files, nil
+}
+
+// NewVirtualLasReader returns a reader of the given files as if they were a single one, applying to the points of
+// each file its transform. The files must be in the same CRS. The options are applied to the reader of each file.
+func NewVirtualLasReader(files []VirtualFile, srid int, eightBitColor bool, opts ...func(*FileLasReader)) (*CombinedFileLasReader, error) {
+	r := &CombinedFileLasReader{
+		srid: srid,
+	}
+	for _, f := range files {
+		fr, err := NewFileLasReader(f.Path, srid, eightBitColor, opts...)
+		if err != nil {
+			r.Close()
+			return nil, &FileError{File: f.Path, Err: err}
+		}
+		r.numPts += fr.NumberOfPoints()
+		r.readers = append(r.readers, fr)
+		r.transforms = append(r.transforms, f.Transform)
+	}
+	return r, nil
+}
+
+// transformedExtent returns the extent of the points of the given file reader, as declared in its header, after
+// applying the given transform
+func transformedExtent(r *FileLasReader, t Transform) (minX, minY, minZ, maxX, maxY, maxZ float64) {
+	hMinX, hMinY, hMaxX, hMaxY := r.Extent()
+	x0, y0, z0 := t.apply(hMinX, hMinY, r.MinZ())
+	x1, y1, z1 := t.apply(hMaxX, hMaxY, r.MaxZ())
+	// a negative scale swaps the bounds
+	return math.Min(x0, x1), math.Min(y0, y1), math.Min(z0, z1), math.Max(x0, x1), math.Max(y0, y1), math.Max(z0, z1)
+}
@@ -0,0 +1,128 @@
+package las
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadVirtualCloud(t *testing.T) {
+	dir := t.TempDir()
+	descriptor := filepath.Join(dir, "cloud.json")
+	abs := filepath.Join(dir, "abs.las")
+	content := `{"files": [
+		{"path": "a.las"},
+		{"path": "` + filepath.ToSlash(abs) + `", "offset": [1, 2, 3], "scale": [1, 1, 0.5]}
+	]}`
+	if err := os.WriteFile(descriptor, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	files, err := ReadVirtualCloud(descriptor)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	expected := []VirtualFile{
+		{Path: filepath.Join(dir, "a.las"), Transform: identityTransform},
+		{Path: filepath.Clean(abs), Transform: Transform{Offset: [3]float64{1, 2, 3}, Scale: [3]float64{1, 1, 0.5}}},
+	}
+	if len(files) != len(expected) {
+		t.Fatalf("expected %d files got %d", len(expected), len(files))
+	}
+	for i := range expected {
+		if filepath.Clean(files[i].Path) != expected[i].Path || files[i].Transform != expected[i].Transform {
+			t.Errorf("expected file %v got %v", expected[i], files[i])
+		}
+	}
+
+	for _, c := range []struct {
+		content string
+		err     string
+	}{
+		{`{"files": []}`, "no files listed"},
+		{`{"files": [{"offset": [1, 2, 3]}]}`, "has no path"},
+		{`{"files": [{"path": "other.json"}]}`, "nested descriptor"},
+		{`{"files": [{"path": "a.las", "scale": [1, 0, 1]}]}`, "zero scale"},
+		{`{"files": `, "invalid virtual cloud"},
+	} {
+		if err := os.WriteFile(descriptor, []byte(c.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ReadVirtualCloud(descriptor); err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("%s: expected error containing %q got %v", c.content, c.err, err)
+		}
+	}
+}
+
+func TestVirtualLasReader(t *testing.T) {
+	dir := t.TempDir()
+	source := "./testdata/las-12-pf2.las"
+	descriptor := filepath.Join(dir, "cloud.json")
+	absSource, err := filepath.Abs(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := `{"files": [
+		{"path": "` + filepath.ToSlash(absSource) + `"},
+		{"path": "` + filepath.ToSlash(absSource) + `", "offset": [100, -50, 10], "scale": [1, 1, -2]}
+	]}`
+	if err := os.WriteFile(descriptor, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	files, err := ExpandVirtualClouds([]string{source, descriptor})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(files) != 3 || files[0].Path != source || files[0].Transform != identityTransform {
+		t.Fatalf("unexpected expanded files %v", files)
+	}
+	r, err := NewVirtualLasReader(files, 32633, false)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	plain, err := NewFileLasReader(source, 32633, false)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	n := plain.NumberOfPoints()
+	if r.NumberOfPoints() != 3*n {
+		t.Fatalf("expected %d points got %d", 3*n, r.NumberOfPoints())
+	}
+	if names := r.FileNames(); len(names) != 3 || names[2] != absSource {
+		t.Errorf("unexpected file names %v", names)
+	}
+	for pass := 0; pass < 3; pass++ {
+		plain, err = NewFileLasReader(source, 32633, false)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		for i := 0; i < n; i++ {
+			expected, err := plain.GetNext()
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			pt, err := r.GetNext()
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if pass == 2 {
+				expected.X, expected.Y, expected.Z = expected.X+100, expected.Y-50, -2*expected.Z+10
+			}
+			if pt.X != expected.X || pt.Y != expected.Y || pt.Z != expected.Z || pt.FileIndex != pass {
+				t.Errorf("file %d point %d: expected %v got %v", pass, i, expected, pt)
+			}
+		}
+	}
+	minX, minY, maxX, maxY := plain.Extent()
+	if aMinX, aMinY, aMaxX, aMaxY := r.Extent(); aMinX != minX || aMinY != minY-50 || aMaxX != maxX+100 || aMaxY != maxY {
+		t.Errorf("unexpected extent [%v, %v, %v, %v]", aMinX, aMinY, aMaxX, aMaxY)
+	}
+	// the negative scale of the z coordinate swaps the bounds of the transformed file
+	if expected := math.Min(plain.MinZ(), -2*plain.MaxZ()+10); r.MinZ() != expected {
+		t.Errorf("expected min z %v got %v", expected, r.MinZ())
+	}
+	if expected := math.Max(plain.MaxZ(), -2*plain.MinZ()+10); r.MaxZ() != expected {
+		t.Errorf("expected max z %v got %v", expected, r.MaxZ())
+	}
+}
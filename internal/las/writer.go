@@ -0,0 +1,148 @@
+package las
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+)
+
+const (
+	// writerHeaderSize is the size of a LAS 1.2 header
+	writerHeaderSize = 227
+	// writerRecordLength is the length of a point data record format 2 record
+	writerRecordLength = 26
+	// writerScale is the scale factor of the coordinates written, i.e. their precision
+	writerScale = 0.001
+	// vlrHeaderSize is the size of the header of a variable length record
+	vlrHeaderSize = 54
+)
+
+// WriterOptions configures the LAS files written by WriteLasFile
+type WriterOptions struct {
+	epsgCode   int
+	geographic bool
+}
+
+// WithGeoKeys declares the CRS of the points in the GeoTIFF keys of the file, as the EPSG code of a projected CRS or,
+// if geographic is true, of a geographic one
+func WithGeoKeys(epsgCode int, geographic bool) func(*WriterOptions) {
+	return func(o *WriterOptions) {
+		o.epsgCode = epsgCode
+		o.geographic = geographic
+	}
+}
+
+// WriteLasFile writes the given points to a LAS 1.2 file with point data record format 2, storing the coordinates,
+// the colors, the intensity, the classification and the point source ID. Colors are stored as 16 bit values.
+// The coordinates are stored with millimetric precision, relative to the minimum of the points.
+// No CRS information is written unless WithGeoKeys is set.
+func WriteLasFile(path string, pts []geom.Point64, opts ...func(*WriterOptions)) error {
+	if len(pts) == 0 {
+		return fmt.Errorf("no points to write")
+	}
+	o := &WriterOptions{}
+	for _, optFn := range opts {
+		optFn(o)
+	}
+	var vlr []byte
+	if o.epsgCode > 0 {
+		vlr = geoKeysVlr(o.epsgCode, o.geographic)
+	}
+	min := [3]float64{math.Inf(1), math.Inf(1), math.Inf(1)}
+	max := [3]float64{math.Inf(-1), math.Inf(-1), math.Inf(-1)}
+	for _, pt := range pts {
+		for i, c := range [3]float64{pt.X, pt.Y, pt.Z} {
+			min[i] = math.Min(min[i], c)
+			max[i] = math.Max(max[i], c)
+		}
+	}
+	for i := range min {
+		if (max[i]-min[i])/writerScale > math.MaxInt32 {
+			return fmt.Errorf("the points extent is too large to be stored in a LAS file")
+		}
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+
+	header := make([]byte, writerHeaderSize)
+	copy(header[0:4], "LASF")
+	header[24] = 1
+	header[25] = 2
+	copy(header[58:90], "gocesiumtiler")
+	binary.LittleEndian.PutUint16(header[94:96], writerHeaderSize)
+	binary.LittleEndian.PutUint32(header[96:100], uint32(writerHeaderSize+len(vlr)))
+	if len(vlr) > 0 {
+		binary.LittleEndian.PutUint32(header[100:104], 1)
+	}
+	header[104] = 2
+	binary.LittleEndian.PutUint16(header[105:107], writerRecordLength)
+	binary.LittleEndian.PutUint32(header[107:111], uint32(len(pts)))
+	for i := 0; i < 3; i++ {
+		binary.LittleEndian.PutUint64(header[131+i*8:], math.Float64bits(writerScale))
+		binary.LittleEndian.PutUint64(header[155+i*8:], math.Float64bits(min[i]))
+		binary.LittleEndian.PutUint64(header[179+i*16:], math.Float64bits(max[i]))
+		binary.LittleEndian.PutUint64(header[187+i*16:], math.Float64bits(min[i]))
+	}
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	if _, err := w.Write(vlr); err != nil {
+		return err
+	}
+
+	rec := make([]byte, writerRecordLength)
+	for _, pt := range pts {
+		for i, c := range [3]float64{pt.X, pt.Y, pt.Z} {
+			binary.LittleEndian.PutUint32(rec[i*4:], uint32(int32(math.Round((c-min[i])/writerScale))))
+		}
+		binary.LittleEndian.PutUint16(rec[12:14], uint16(pt.Intensity))
+		// single return
+		rec[14] = 0b00001001
+		rec[15] = pt.Classification & 0b00011111
+		binary.LittleEndian.PutUint16(rec[18:20], pt.PointSourceId)
+		binary.LittleEndian.PutUint16(rec[20:22], uint16(pt.R)*256)
+		binary.LittleEndian.PutUint16(rec[22:24], uint16(pt.G)*256)
+		binary.LittleEndian.PutUint16(rec[24:26], uint16(pt.B)*256)
+		if _, err := w.Write(rec); err != nil {
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// geoKeysVlr returns the GeoKeyDirectory variable length record declaring the CRS with the given EPSG code
+func geoKeysVlr(epsgCode int, geographic bool) []byte {
+	// the model type is 1 for projected CRSs and 2 for geographic ones
+	modelType, crsKey := uint16(1), uint16(tProjectedCSTypeGeoKey)
+	if geographic {
+		modelType, crsKey = 2, tGeographicTypeGeoKey
+	}
+	// directory header (version, revision, minor revision, number of keys) followed by the keys sorted by ID, each
+	// stored as key ID, location (0 as the value is stored in place), count and value
+	directory := []uint16{
+		1, 1, 0, 2,
+		tGTModelTypeGeoKey, 0, 1, modelType,
+		crsKey, 0, 1, uint16(epsgCode),
+	}
+	vlr := make([]byte, vlrHeaderSize+2*len(directory))
+	copy(vlr[2:18], "LASF_Projection")
+	binary.LittleEndian.PutUint16(vlr[18:20], tGeoKeyDirectoryTag)
+	binary.LittleEndian.PutUint16(vlr[20:22], uint16(2*len(directory)))
+	copy(vlr[22:54], "GeoTiff GeoKeyDirectoryTag")
+	for i, v := range directory {
+		binary.LittleEndian.PutUint16(vlr[vlrHeaderSize+2*i:], v)
+	}
+	return vlr
+}
@@ -0,0 +1,50 @@
+package las
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+)
+
+func TestWriteLasFile(t *testing.T) {
+	pts := []geom.Point64{
+		{X: 500000.123, Y: 5000000.456, Z: 100.789, R: 10, G: 20, B: 30, Intensity: 40, Classification: 2, PointSourceId: 7},
+		{X: 500010.5, Y: 5000020.25, Z: 90, R: 255, G: 0, B: 128, Intensity: 200, Classification: 6, PointSourceId: 8},
+	}
+	path := filepath.Join(t.TempDir(), "out.las")
+	if err := WriteLasFile(path, pts); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	r, err := NewFileLasReader(path, 32633, false)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if actual := r.NumberOfPoints(); actual != len(pts) {
+		t.Fatalf("expected %d points got %d", len(pts), actual)
+	}
+	if actual := r.MinZ(); actual != 90 {
+		t.Errorf("expected min Z %v got %v", 90, actual)
+	}
+	for i, expected := range pts {
+		actual, err := r.GetNext()
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if math.Abs(actual.X-expected.X) > 1e-3 || math.Abs(actual.Y-expected.Y) > 1e-3 || math.Abs(actual.Z-expected.Z) > 1e-3 {
+			t.Errorf("point %d: expected coordinates of %v got %v", i, expected, actual)
+		}
+		if actual.R != expected.R || actual.G != expected.G || actual.B != expected.B || actual.Intensity != expected.Intensity ||
+			actual.Classification != expected.Classification || actual.PointSourceId != expected.PointSourceId {
+			t.Errorf("point %d: expected attributes of %v got %v", i, expected, actual)
+		}
+	}
+
+	if err := WriteLasFile(path, nil); err == nil {
+		t.Errorf("expected error for no points, got none")
+	}
+	if err := WriteLasFile(filepath.Join(t.TempDir(), "missing", "out.las"), pts); err == nil {
+		t.Errorf("expected error for a missing folder, got none")
+	}
+}
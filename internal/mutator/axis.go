@@ -0,0 +1,32 @@
+package mutator
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+)
+
+// AxisFlip negates the coordinates of the points along the flagged axes, mirroring them around the origin of the
+// coordinate system, e.g. to fix the handedness of data exported with a flipped Y axis
+type AxisFlip struct {
+	X, Y, Z bool
+}
+
+func NewAxisFlip(x, y, z bool) *AxisFlip {
+	return &AxisFlip{
+		X: x,
+		Y: y,
+		Z: z,
+	}
+}
+
+func (a *AxisFlip) Mutate(pt geom.Point64) (geom.Point64, bool) {
+	if a.X {
+		pt.X = -pt.X
+	}
+	if a.Y {
+		pt.Y = -pt.Y
+	}
+	if a.Z {
+		pt.Z = -pt.Z
+	}
+	return pt, true
+}
@@ -0,0 +1,125 @@
+package mutator
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+)
+
+// ClassificationRemap replaces the classification of the points using a lookup table
+// indexed by the original classification
+type ClassificationRemap struct {
+	Table [256]uint8
+}
+
+// NewClassificationRemap returns a ClassificationRemap converting the classes according to the given mapping.
+// Classes not present in the mapping are left unchanged.
+func NewClassificationRemap(mapping map[uint8]uint8) *ClassificationRemap {
+	r := &ClassificationRemap{}
+	for i := range r.Table {
+		r.Table[i] = uint8(i)
+	}
+	for from, to := range mapping {
+		r.Table[from] = to
+	}
+	return r
+}
+
+func (r *ClassificationRemap) Mutate(pt geom.Point64) (geom.Point64, bool) {
+	pt.Classification = r.Table[pt.Classification]
+	return pt, true
+}
+
+// UnmappedClassColor is the color of the classes not present in the palette of ClassificationColor
+var UnmappedClassColor = [3]uint8{128, 128, 128}
+
+// DefaultClassificationPalette is the built-in palette of the ASPRS standard classes
+var DefaultClassificationPalette = map[uint8][3]uint8{
+	1:  {200, 200, 200}, // unclassified
+	2:  {153, 102, 51},  // ground
+	3:  {179, 230, 102}, // low vegetation
+	4:  {76, 175, 80},   // medium vegetation
+	5:  {27, 94, 32},    // high vegetation
+	6:  {230, 57, 70},   // building
+	7:  {255, 0, 255},   // low point (noise)
+	9:  {33, 150, 243},  // water
+	10: {121, 85, 72},   // rail
+	11: {96, 96, 96},    // road surface
+	17: {255, 193, 7},   // bridge deck
+	18: {255, 0, 255},   // high noise
+}
+
+// ReadClassificationPalette reads a palette of classification colors from a CSV file with the classification code in
+// the first column and the color in the second, as a hex RGB triplet with an optional leading #, e.g. "6,#e63946".
+// A header on the first line, empty lines and lines starting with # are skipped.
+func ReadClassificationPalette(path string) (map[uint8][3]uint8, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	palette := map[uint8][3]uint8{}
+	for first := true; ; first = false {
+		record, err := r.Read()
+		if err == io.EOF {
+			return palette, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid classification palette %s: %w", path, err)
+		}
+		line, _ := r.FieldPos(0)
+		if len(record) != 2 {
+			return nil, fmt.Errorf("invalid classification palette %s: line %d: expected 2 fields, got %d", path, line, len(record))
+		}
+		class, err := strconv.ParseUint(strings.TrimSpace(record[0]), 10, 8)
+		if err != nil {
+			if first {
+				continue
+			}
+			return nil, fmt.Errorf("invalid classification palette %s: line %d: invalid classification %q", path, line, record[0])
+		}
+		hex := strings.TrimPrefix(strings.TrimSpace(record[1]), "#")
+		rgb, err := strconv.ParseUint(hex, 16, 32)
+		if len(hex) != 6 || err != nil {
+			return nil, fmt.Errorf("invalid classification palette %s: line %d: invalid color %q", path, line, record[1])
+		}
+		palette[uint8(class)] = [3]uint8{uint8(rgb >> 16), uint8(rgb >> 8), uint8(rgb)}
+	}
+}
+
+// ClassificationColor replaces the color of the points with the one associated to their classification
+// by a lookup table indexed by the classification
+type ClassificationColor struct {
+	Table [256][3]uint8
+}
+
+// NewClassificationColor returns a ClassificationColor coloring the points with the default palette, overridden by
+// the given one. Classes present in neither of them are colored with UnmappedClassColor.
+func NewClassificationColor(palette map[uint8][3]uint8) *ClassificationColor {
+	c := &ClassificationColor{}
+	for i := range c.Table {
+		c.Table[i] = UnmappedClassColor
+	}
+	for class, color := range DefaultClassificationPalette {
+		c.Table[class] = color
+	}
+	for class, color := range palette {
+		c.Table[class] = color
+	}
+	return c
+}
+
+func (c *ClassificationColor) Mutate(pt geom.Point64) (geom.Point64, bool) {
+	color := c.Table[pt.Classification]
+	pt.R, pt.G, pt.B = color[0], color[1], color[2]
+	return pt, true
+}
@@ -0,0 +1,108 @@
+package mutator
+
+import (
+	"math"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+)
+
+// ColorGamma applies a gamma correction to the R, G and B channels of the points, computing
+// each channel as 255 * (c/255)^(1/Gamma). Gamma values above 1 brighten the colors, values below 1 darken them.
+type ColorGamma struct {
+	Gamma float64
+	lut   [256]uint8
+}
+
+func NewColorGamma(gamma float64) *ColorGamma {
+	g := &ColorGamma{
+		Gamma: gamma,
+	}
+	// precompute the correction for each possible channel value
+	for i := range g.lut {
+		g.lut[i] = uint8(math.Round(255 * math.Pow(float64(i)/255, 1/gamma)))
+	}
+	return g
+}
+
+func (g *ColorGamma) Mutate(pt geom.Point64) (geom.Point64, bool) {
+	pt.R = g.lut[pt.R]
+	pt.G = g.lut[pt.G]
+	pt.B = g.lut[pt.B]
+	return pt, true
+}
+
+// ColorMatrix transforms the R, G and B channels of the points with a 3x3 matrix stored in row major order, computing
+// R' = M[0]*R + M[1]*G + M[2]*B and so on. The results are rounded and clamped to the 0-255 range.
+type ColorMatrix struct {
+	Matrix [9]float64
+}
+
+func NewColorMatrix(matrix [9]float64) *ColorMatrix {
+	return &ColorMatrix{
+		Matrix: matrix,
+	}
+}
+
+func (c *ColorMatrix) Mutate(pt geom.Point64) (geom.Point64, bool) {
+	r, g, b := float64(pt.R), float64(pt.G), float64(pt.B)
+	m := c.Matrix
+	pt.R = toColor(m[0]*r + m[1]*g + m[2]*b)
+	pt.G = toColor(m[3]*r + m[4]*g + m[5]*b)
+	pt.B = toColor(m[6]*r + m[7]*g + m[8]*b)
+	return pt, true
+}
+
+// DefaultColor assigns a constant color to the points without a color, i.e. whose R, G and B channels are all zero
+type DefaultColor struct {
+	Color [3]uint8
+}
+
+func NewDefaultColor(r, g, b uint8) *DefaultColor {
+	return &DefaultColor{
+		Color: [3]uint8{r, g, b},
+	}
+}
+
+func (d *DefaultColor) Mutate(pt geom.Point64) (geom.Point64, bool) {
+	if pt.R == 0 && pt.G == 0 && pt.B == 0 {
+		pt.R, pt.G, pt.B = d.Color[0], d.Color[1], d.Color[2]
+	}
+	return pt, true
+}
+
+// ColorBits reduces the precision of the R, G and B channels of the points to the given number of bits per channel,
+// e.g. 5, 6 and 5 for RGB565. Each channel is truncated to its most significant bits and expanded back to the 0-255
+// range, so that the darkest and brightest values are preserved while the intermediate ones are banded.
+type ColorBits struct {
+	Bits [3]int
+	lut  [3][256]uint8
+}
+
+// NewColorBits returns a mutator storing the colors with the given total number of bits, between 3 and 24, split
+// among the channels giving the extra bits to green first and then red, e.g. 16 bits are split as RGB565.
+func NewColorBits(bits int) *ColorBits {
+	c := &ColorBits{
+		Bits: [3]int{bits / 3, bits / 3, bits / 3},
+	}
+	if bits%3 >= 1 {
+		c.Bits[1]++
+	}
+	if bits%3 == 2 {
+		c.Bits[0]++
+	}
+	for channel, n := range c.Bits {
+		levels := float64(int(1)<<n - 1)
+		for i := range c.lut[channel] {
+			q := i >> (8 - n)
+			c.lut[channel][i] = uint8(math.Round(float64(q) * 255 / levels))
+		}
+	}
+	return c
+}
+
+func (c *ColorBits) Mutate(pt geom.Point64) (geom.Point64, bool) {
+	pt.R = c.lut[0][pt.R]
+	pt.G = c.lut[1][pt.G]
+	pt.B = c.lut[2][pt.B]
+	return pt, true
+}
@@ -0,0 +1,36 @@
+package mutator
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+)
+
+// ElevationClamp limits the Z coordinate of the points to the [Min, Max] range. Points outside
+// of the range are discarded, unless Clamp is true, in which case their Z is set to the nearest bound.
+type ElevationClamp struct {
+	Min   float64
+	Max   float64
+	Clamp bool
+}
+
+func NewElevationClamp(min, max float64, clamp bool) *ElevationClamp {
+	return &ElevationClamp{
+		Min:   min,
+		Max:   max,
+		Clamp: clamp,
+	}
+}
+
+func (e *ElevationClamp) Mutate(pt geom.Point64) (geom.Point64, bool) {
+	if pt.Z >= e.Min && pt.Z <= e.Max {
+		return pt, true
+	}
+	if !e.Clamp {
+		return pt, false
+	}
+	if pt.Z < e.Min {
+		pt.Z = e.Min
+	} else {
+		pt.Z = e.Max
+	}
+	return pt, true
+}
@@ -0,0 +1,23 @@
+package mutator
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+)
+
+// HeightExaggeration multiplies the height of the points above the Reference elevation by the given Factor
+type HeightExaggeration struct {
+	Reference float64
+	Factor    float64
+}
+
+func NewHeightExaggeration(reference, factor float64) *HeightExaggeration {
+	return &HeightExaggeration{
+		Reference: reference,
+		Factor:    factor,
+	}
+}
+
+func (h *HeightExaggeration) Mutate(pt geom.Point64) (geom.Point64, bool) {
+	pt.Z = h.Reference + (pt.Z-h.Reference)*h.Factor
+	return pt, true
+}
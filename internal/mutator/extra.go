@@ -0,0 +1,23 @@
+package mutator
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+)
+
+// ExtraRangeFilter discards the points whose Extra value is outside of the [Min, Max] range
+// or is NaN, which denotes a no data value
+type ExtraRangeFilter struct {
+	Min float64
+	Max float64
+}
+
+func NewExtraRangeFilter(min, max float64) *ExtraRangeFilter {
+	return &ExtraRangeFilter{
+		Min: min,
+		Max: max,
+	}
+}
+
+func (f *ExtraRangeFilter) Mutate(pt geom.Point64) (geom.Point64, bool) {
+	return pt, pt.Extra >= f.Min && pt.Extra <= f.Max
+}
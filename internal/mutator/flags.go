@@ -0,0 +1,35 @@
+package mutator
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+)
+
+// FlagFilter discards all points having at least one of the given classification flags set
+type FlagFilter struct {
+	Mask uint8
+}
+
+func NewFlagFilter(mask uint8) *FlagFilter {
+	return &FlagFilter{
+		Mask: mask,
+	}
+}
+
+func (f *FlagFilter) Mutate(pt geom.Point64) (geom.Point64, bool) {
+	return pt, pt.Flags&f.Mask == 0
+}
+
+// FlagSelect keeps only the points having all the given classification flags set
+type FlagSelect struct {
+	Mask uint8
+}
+
+func NewFlagSelect(mask uint8) *FlagSelect {
+	return &FlagSelect{
+		Mask: mask,
+	}
+}
+
+func (f *FlagSelect) Mutate(pt geom.Point64) (geom.Point64, bool) {
+	return pt, pt.HasFlag(f.Mask)
+}
@@ -0,0 +1,129 @@
+package mutator
+
+import (
+	"math"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+)
+
+// GroundClassification is the ASPRS classification of the ground points
+const GroundClassification = 2
+
+type groundCell struct {
+	x, y int
+}
+
+// GroundSurface is a coarse model of the ground, storing the mean elevation of the ground points falling in each cell
+// of a regular grid. It is built before the points are loaded and is read only afterwards.
+type GroundSurface struct {
+	CellSize float64
+	sum      map[groundCell]float64
+	count    map[groundCell]int
+}
+
+func NewGroundSurface(cellSize float64) *GroundSurface {
+	return &GroundSurface{
+		CellSize: cellSize,
+		sum:      map[groundCell]float64{},
+		count:    map[groundCell]int{},
+	}
+}
+
+// Add adds a ground point to the surface
+func (g *GroundSurface) Add(x, y, z float64) {
+	c := groundCell{x: int(math.Floor(x / g.CellSize)), y: int(math.Floor(y / g.CellSize))}
+	g.sum[c] += z
+	g.count[c]++
+}
+
+// Height returns the elevation of the ground at the given coordinates, interpolated bilinearly between the centers
+// of the four closest cells. Cells without ground points are ignored, returns false if all of them are empty.
+func (g *GroundSurface) Height(x, y float64) (float64, bool) {
+	fx, fy := x/g.CellSize-0.5, y/g.CellSize-0.5
+	x0, y0 := math.Floor(fx), math.Floor(fy)
+	tx, ty := fx-x0, fy-y0
+	sum, weights := 0.0, 0.0
+	for _, n := range []struct {
+		dx, dy int
+		w      float64
+	}{
+		{0, 0, (1 - tx) * (1 - ty)},
+		{1, 0, tx * (1 - ty)},
+		{0, 1, (1 - tx) * ty},
+		{1, 1, tx * ty},
+	} {
+		c := groundCell{x: int(x0) + n.dx, y: int(y0) + n.dy}
+		if count := g.count[c]; count > 0 {
+			sum += n.w * g.sum[c] / float64(count)
+			weights += n.w
+		}
+	}
+	if weights == 0 {
+		// all the cells contributing to the interpolation are empty
+		return 0, false
+	}
+	return sum / weights, true
+}
+
+// HeightAboveGround discards the points whose height above the ground surface is outside of the [Min, Max] range.
+// Ground points are always retained, while points with no ground nearby are discarded.
+type HeightAboveGround struct {
+	Ground *GroundSurface
+	Min    float64
+	Max    float64
+}
+
+func NewHeightAboveGround(ground *GroundSurface, min, max float64) *HeightAboveGround {
+	return &HeightAboveGround{
+		Ground: ground,
+		Min:    min,
+		Max:    max,
+	}
+}
+
+func (h *HeightAboveGround) Mutate(pt geom.Point64) (geom.Point64, bool) {
+	if pt.Classification == GroundClassification {
+		return pt, true
+	}
+	z, ok := h.Ground.Height(pt.X, pt.Y)
+	if !ok {
+		return pt, false
+	}
+	return pt, pt.Z-z >= h.Min && pt.Z-z <= h.Max
+}
+
+// HeightClass is a rule assigning the classification to the points whose height above the ground is in the
+// [Min, Max] range
+type HeightClass struct {
+	Min            float64
+	Max            float64
+	Classification uint8
+}
+
+// HeightReclassification assigns to the points the classification of the first rule whose range contains their height
+// above the ground surface. Points matching no rule, or with no ground nearby, keep their classification.
+type HeightReclassification struct {
+	Ground *GroundSurface
+	Rules  []HeightClass
+}
+
+func NewHeightReclassification(ground *GroundSurface, rules []HeightClass) *HeightReclassification {
+	return &HeightReclassification{
+		Ground: ground,
+		Rules:  rules,
+	}
+}
+
+func (h *HeightReclassification) Mutate(pt geom.Point64) (geom.Point64, bool) {
+	z, ok := h.Ground.Height(pt.X, pt.Y)
+	if !ok {
+		return pt, true
+	}
+	for _, r := range h.Rules {
+		if pt.Z-z >= r.Min && pt.Z-z <= r.Max {
+			pt.Classification = r.Classification
+			break
+		}
+	}
+	return pt, true
+}
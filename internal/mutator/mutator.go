@@ -0,0 +1,35 @@
+package mutator
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+)
+
+// Mutator transforms the points as they are loaded, before they are converted to the internal
+// coordinate reference system. Mutate returns the transformed point and false if the point
+// should be discarded instead. Implementations must be safe for concurrent use.
+type Mutator interface {
+	Mutate(pt geom.Point64) (geom.Point64, bool)
+}
+
+// Pipeline chains multiple mutators, applying them in order. If any of the mutators discards
+// the point, the following ones are not invoked.
+type Pipeline struct {
+	Mutators []Mutator
+}
+
+func NewPipeline(mutators ...Mutator) *Pipeline {
+	return &Pipeline{
+		Mutators: mutators,
+	}
+}
+
+func (p *Pipeline) Mutate(pt geom.Point64) (geom.Point64, bool) {
+	var ok bool
+	for _, m := range p.Mutators {
+		pt, ok = m.Mutate(pt)
+		if !ok {
+			return pt, false
+		}
+	}
+	return pt, true
+}
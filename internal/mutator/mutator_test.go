@@ -0,0 +1,505 @@
+package mutator
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+)
+
+type mockMutator struct {
+	called bool
+	keep   bool
+}
+
+func (m *mockMutator) Mutate(pt geom.Point64) (geom.Point64, bool) {
+	m.called = true
+	pt.X++
+	return pt, m.keep
+}
+
+func TestPipeline(t *testing.T) {
+	m1 := &mockMutator{keep: true}
+	m2 := &mockMutator{keep: true}
+	p := NewPipeline(m1, m2)
+	pt, ok := p.Mutate(geom.Point64{X: 1})
+	if !ok {
+		t.Errorf("expected point to be kept")
+	}
+	if pt.X != 3 {
+		t.Errorf("expected X %v got %v", 3, pt.X)
+	}
+
+	m1 = &mockMutator{keep: false}
+	m2 = &mockMutator{keep: true}
+	p = NewPipeline(m1, m2)
+	if _, ok := p.Mutate(geom.Point64{X: 1}); ok {
+		t.Errorf("expected point to be discarded")
+	}
+	if m2.called {
+		t.Errorf("expected second mutator not to be called")
+	}
+}
+
+func TestFlagFilter(t *testing.T) {
+	f := NewFlagFilter(geom.FlagWithheld | geom.FlagOverlap)
+	cases := []struct {
+		flags    uint8
+		expected bool
+	}{
+		{0, true},
+		{geom.FlagSynthetic, true},
+		{geom.FlagWithheld, false},
+		{geom.FlagOverlap, false},
+		{geom.FlagKeyPoint | geom.FlagOverlap, false},
+	}
+	for _, c := range cases {
+		if _, actual := f.Mutate(geom.Point64{Flags: c.flags}); actual != c.expected {
+			t.Errorf("for flags %b expected %v got %v", c.flags, c.expected, actual)
+		}
+	}
+}
+
+func TestFlagSelect(t *testing.T) {
+	f := NewFlagSelect(geom.FlagSynthetic)
+	cases := []struct {
+		flags    uint8
+		expected bool
+	}{
+		{0, false},
+		{geom.FlagSynthetic, true},
+		{geom.FlagSynthetic | geom.FlagWithheld, true},
+		{geom.FlagWithheld, false},
+	}
+	for _, c := range cases {
+		if _, actual := f.Mutate(geom.Point64{Flags: c.flags}); actual != c.expected {
+			t.Errorf("for flags %b expected %v got %v", c.flags, c.expected, actual)
+		}
+	}
+}
+
+func TestPointSourceFilter(t *testing.T) {
+	f := NewPointSourceFilter([]uint16{3, 7})
+	cases := []struct {
+		id       uint16
+		expected bool
+	}{
+		{3, true},
+		{7, true},
+		{0, false},
+		{4, false},
+	}
+	for _, c := range cases {
+		if _, actual := f.Mutate(geom.Point64{PointSourceId: c.id}); actual != c.expected {
+			t.Errorf("for point source %d expected %v got %v", c.id, c.expected, actual)
+		}
+	}
+}
+
+func TestTimeWindowFilter(t *testing.T) {
+	f := NewTimeWindowFilter(100, 110)
+	cases := []struct {
+		time     float64
+		expected bool
+	}{
+		{100, true},
+		{105.5, true},
+		{110, false},
+		{99.9, false},
+	}
+	for _, c := range cases {
+		if _, actual := f.Mutate(geom.Point64{GpsTime: c.time}); actual != c.expected {
+			t.Errorf("for GPS time %v expected %v got %v", c.time, c.expected, actual)
+		}
+	}
+}
+
+func TestElevationClamp(t *testing.T) {
+	cases := []struct {
+		clamp    bool
+		z        float64
+		expected float64
+		keep     bool
+	}{
+		{false, 5, 5, true},
+		{false, 0, 0, true},
+		{false, 10, 10, true},
+		{false, -1, -1, false},
+		{false, 11, 11, false},
+		{true, 5, 5, true},
+		{true, -1, 0, true},
+		{true, 11, 10, true},
+	}
+	for _, c := range cases {
+		e := NewElevationClamp(0, 10, c.clamp)
+		pt, keep := e.Mutate(geom.Point64{Z: c.z})
+		if keep != c.keep {
+			t.Errorf("for z %v and clamp %v expected keep %v got %v", c.z, c.clamp, c.keep, keep)
+		}
+		if pt.Z != c.expected {
+			t.Errorf("for z %v and clamp %v expected z %v got %v", c.z, c.clamp, c.expected, pt.Z)
+		}
+	}
+}
+
+func TestColorGamma(t *testing.T) {
+	g := NewColorGamma(1)
+	pt, keep := g.Mutate(geom.Point64{R: 0, G: 100, B: 255})
+	if !keep {
+		t.Errorf("expected point to be kept")
+	}
+	if pt.R != 0 || pt.G != 100 || pt.B != 255 {
+		t.Errorf("expected gamma 1 to be a no-op, got %v", pt)
+	}
+
+	g = NewColorGamma(2.2)
+	pt, _ = g.Mutate(geom.Point64{R: 0, G: 100, B: 255})
+	// 255 * (100/255)^(1/2.2) = 166.6
+	if pt.R != 0 || pt.G != 167 || pt.B != 255 {
+		t.Errorf("expected {0 167 255} got {%d %d %d}", pt.R, pt.G, pt.B)
+	}
+
+	g = NewColorGamma(0.5)
+	pt, _ = g.Mutate(geom.Point64{R: 0, G: 100, B: 255})
+	// 255 * (100/255)^2 = 39.2
+	if pt.R != 0 || pt.G != 39 || pt.B != 255 {
+		t.Errorf("expected {0 39 255} got {%d %d %d}", pt.R, pt.G, pt.B)
+	}
+}
+
+func TestColorMatrix(t *testing.T) {
+	m := NewColorMatrix([9]float64{1, 0, 0, 0, 1, 0, 0, 0, 1})
+	pt, keep := m.Mutate(geom.Point64{R: 10, G: 100, B: 255, Intensity: 7})
+	if !keep || pt.R != 10 || pt.G != 100 || pt.B != 255 || pt.Intensity != 7 {
+		t.Errorf("expected the identity to be a no-op, got %v (%v)", pt, keep)
+	}
+
+	// swaps red and blue, doubles green and averages the three channels in blue
+	m = NewColorMatrix([9]float64{0, 0, 1, 0, 2, 0, 1.0 / 3, 1.0 / 3, 1.0 / 3})
+	pt, _ = m.Mutate(geom.Point64{R: 10, G: 100, B: 250})
+	if pt.R != 250 || pt.G != 200 || pt.B != 120 {
+		t.Errorf("expected {250 200 120} got {%d %d %d}", pt.R, pt.G, pt.B)
+	}
+
+	// results are clamped
+	m = NewColorMatrix([9]float64{-1, 0, 0, 0, 3, 0, 0, 0, 1})
+	pt, _ = m.Mutate(geom.Point64{R: 10, G: 100, B: 250})
+	if pt.R != 0 || pt.G != 255 || pt.B != 250 {
+		t.Errorf("expected {0 255 250} got {%d %d %d}", pt.R, pt.G, pt.B)
+	}
+}
+
+func TestDefaultColor(t *testing.T) {
+	d := NewDefaultColor(128, 129, 130)
+	pt, keep := d.Mutate(geom.Point64{Intensity: 7})
+	if !keep || pt.R != 128 || pt.G != 129 || pt.B != 130 || pt.Intensity != 7 {
+		t.Errorf("expected the default color to be assigned, got %v (%v)", pt, keep)
+	}
+	pt, _ = d.Mutate(geom.Point64{B: 1})
+	if pt.R != 0 || pt.G != 0 || pt.B != 1 {
+		t.Errorf("expected the color to be left unchanged, got {%d %d %d}", pt.R, pt.G, pt.B)
+	}
+}
+
+func TestColorBits(t *testing.T) {
+	c := NewColorBits(24)
+	for _, v := range []uint8{0, 1, 127, 128, 254, 255} {
+		if pt, keep := c.Mutate(geom.Point64{R: v, G: v, B: v, Intensity: 7}); !keep || pt.R != v || pt.G != v || pt.B != v || pt.Intensity != 7 {
+			t.Errorf("expected 24 bits to be a no-op for %d, got %v (%v)", v, pt, keep)
+		}
+	}
+
+	c = NewColorBits(16)
+	if c.Bits != [3]int{5, 6, 5} {
+		t.Errorf("expected 16 bits to be split as RGB565 got %v", c.Bits)
+	}
+	pt, _ := c.Mutate(geom.Point64{R: 255, G: 255, B: 255})
+	if pt.R != 255 || pt.G != 255 || pt.B != 255 {
+		t.Errorf("expected white to be preserved got {%d %d %d}", pt.R, pt.G, pt.B)
+	}
+	// 100 >> 3 = 12 levels of 31 for red and blue, 100 >> 2 = 25 levels of 63 for green
+	pt, _ = c.Mutate(geom.Point64{R: 100, G: 100, B: 7})
+	if pt.R != 99 || pt.G != 101 || pt.B != 0 {
+		t.Errorf("expected {99 101 0} got {%d %d %d}", pt.R, pt.G, pt.B)
+	}
+
+	if c = NewColorBits(23); c.Bits != [3]int{8, 8, 7} {
+		t.Errorf("expected 23 bits to be split as 8, 8, 7 got %v", c.Bits)
+	}
+	c = NewColorBits(3)
+	pt, _ = c.Mutate(geom.Point64{R: 127, G: 128, B: 200})
+	if pt.R != 0 || pt.G != 255 || pt.B != 255 {
+		t.Errorf("expected {0 255 255} got {%d %d %d}", pt.R, pt.G, pt.B)
+	}
+}
+
+func TestRasterElevation(t *testing.T) {
+	sample := func(x, y float64) (float64, bool) {
+		if x < 0 {
+			return 0, false
+		}
+		return x + y, true
+	}
+	for _, keepOutside := range []bool{false, true} {
+		r := NewRasterElevation(sample, keepOutside)
+		pt, keep := r.Mutate(geom.Point64{X: 1, Y: 2, Z: 10})
+		if !keep || pt.Z != 3 {
+			t.Errorf("expected point to be kept with Z %v, got %v (%v)", 3, pt.Z, keep)
+		}
+		pt, keep = r.Mutate(geom.Point64{X: -1, Y: 2, Z: 10})
+		if keep != keepOutside {
+			t.Errorf("expected keep %v for point outside of the raster got %v", keepOutside, keep)
+		}
+		if pt.Z != 10 {
+			t.Errorf("expected original Z %v to be retained, got %v", 10, pt.Z)
+		}
+	}
+}
+
+func TestRasterColor(t *testing.T) {
+	sample := func(x, y float64) ([3]float64, bool) {
+		if x < 0 {
+			return [3]float64{}, false
+		}
+		return [3]float64{x, 300, -4}, true
+	}
+	r := NewRasterColor(sample)
+	pt, keep := r.Mutate(geom.Point64{X: 10.6, Y: 2, R: 1, G: 2, B: 3})
+	if !keep || pt.R != 11 || pt.G != 255 || pt.B != 0 {
+		t.Errorf("expected point to be kept with color 11, 255, 0 got %v, %v, %v (%v)", pt.R, pt.G, pt.B, keep)
+	}
+	pt, keep = r.Mutate(geom.Point64{X: -1, Y: 2, R: 1, G: 2, B: 3})
+	if !keep || pt.R != 1 || pt.G != 2 || pt.B != 3 {
+		t.Errorf("expected point outside of the raster to be kept with its color, got %v, %v, %v (%v)", pt.R, pt.G, pt.B, keep)
+	}
+}
+
+func TestClassificationRemap(t *testing.T) {
+	r := NewClassificationRemap(map[uint8]uint8{40: 2, 2: 8})
+	cases := []struct {
+		class    uint8
+		expected uint8
+	}{
+		{40, 2},
+		{2, 8},
+		{6, 6},
+		{255, 255},
+	}
+	for _, c := range cases {
+		pt, keep := r.Mutate(geom.Point64{Classification: c.class})
+		if !keep {
+			t.Errorf("expected point to be kept")
+		}
+		if pt.Classification != c.expected {
+			t.Errorf("for class %d expected %d got %d", c.class, c.expected, pt.Classification)
+		}
+	}
+}
+
+func TestClassificationColor(t *testing.T) {
+	c := NewClassificationColor(map[uint8][3]uint8{6: {1, 2, 3}, 64: {4, 5, 6}})
+	cases := []struct {
+		class    uint8
+		expected [3]uint8
+	}{
+		{6, [3]uint8{1, 2, 3}},
+		{64, [3]uint8{4, 5, 6}},
+		{2, DefaultClassificationPalette[2]},
+		{100, UnmappedClassColor},
+	}
+	for _, tc := range cases {
+		pt, keep := c.Mutate(geom.Point64{R: 255, G: 255, B: 255, Classification: tc.class})
+		if !keep {
+			t.Errorf("expected point to be kept")
+		}
+		if actual := [3]uint8{pt.R, pt.G, pt.B}; actual != tc.expected {
+			t.Errorf("for class %d expected %v got %v", tc.class, tc.expected, actual)
+		}
+		if pt.Classification != tc.class {
+			t.Errorf("expected classification to be unchanged, got %d", pt.Classification)
+		}
+	}
+}
+
+func TestReadClassificationPalette(t *testing.T) {
+	tmp := t.TempDir()
+	write := func(name, content string) string {
+		p := filepath.Join(tmp, name)
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return p
+	}
+	palette, err := ReadClassificationPalette(write("ok.csv", "class,color\n# buildings\n6,#E63946\n\n2, 996633\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := map[uint8][3]uint8{6: {230, 57, 70}, 2: {153, 102, 51}}; !reflect.DeepEqual(palette, expected) {
+		t.Errorf("expected palette %v got %v", expected, palette)
+	}
+	for content, msg := range map[string]string{
+		"6,#e63946\n7,#zz0000\n":   "line 2: invalid color",
+		"6,#e63946\n300,#000000\n": "line 2: invalid classification",
+		"6,#e63946\n\n7\n":         "line 3: expected 2 fields",
+		"6,#e6394\n":               "line 1: invalid color",
+	} {
+		_, err := ReadClassificationPalette(write("bad.csv", content))
+		if err == nil || !strings.Contains(err.Error(), msg) {
+			t.Errorf("expected error containing %q for %q, got %v", msg, content, err)
+		}
+	}
+	if _, err := ReadClassificationPalette(filepath.Join(tmp, "missing.csv")); err == nil {
+		t.Errorf("expected error for missing file, got none")
+	}
+}
+
+func TestUnitScale(t *testing.T) {
+	u := NewUnitScale(0.3048, 1)
+	pt, keep := u.Mutate(geom.Point64{X: 10, Y: 20, Z: 30, Classification: 2})
+	if !keep {
+		t.Errorf("expected point to be kept")
+	}
+	if math.Abs(pt.X-3.048) > 1e-9 || math.Abs(pt.Y-6.096) > 1e-9 || pt.Z != 30 || pt.Classification != 2 {
+		t.Errorf("unexpected point %v", pt)
+	}
+}
+
+func TestAxisFlip(t *testing.T) {
+	a := NewAxisFlip(false, true, false)
+	pt, keep := a.Mutate(geom.Point64{X: 10, Y: 20, Z: 30, Classification: 2})
+	if !keep || pt.X != 10 || pt.Y != -20 || pt.Z != 30 || pt.Classification != 2 {
+		t.Errorf("unexpected point %v (%v)", pt, keep)
+	}
+	pt, _ = NewAxisFlip(true, false, true).Mutate(geom.Point64{X: 10, Y: 20, Z: 30})
+	if pt.X != -10 || pt.Y != 20 || pt.Z != -30 {
+		t.Errorf("unexpected point %v", pt)
+	}
+}
+
+func TestHeightExaggeration(t *testing.T) {
+	h := NewHeightExaggeration(100, 3)
+	cases := []struct {
+		z        float64
+		expected float64
+	}{
+		{100, 100},
+		{110, 130},
+		{90, 70},
+	}
+	for _, c := range cases {
+		pt, keep := h.Mutate(geom.Point64{X: 1, Y: 2, Z: c.z})
+		if !keep {
+			t.Errorf("expected point to be kept")
+		}
+		if pt.Z != c.expected || pt.X != 1 || pt.Y != 2 {
+			t.Errorf("for z %v expected %v got %v", c.z, c.expected, pt)
+		}
+	}
+}
+
+func TestExtraRangeFilter(t *testing.T) {
+	f := NewExtraRangeFilter(0.5, 1)
+	cases := []struct {
+		extra    float64
+		expected bool
+	}{
+		{0.5, true},
+		{0.75, true},
+		{1, true},
+		{0.49, false},
+		{1.01, false},
+		{math.NaN(), false},
+	}
+	for _, c := range cases {
+		if _, keep := f.Mutate(geom.Point64{Extra: c.extra}); keep != c.expected {
+			t.Errorf("for extra %v expected keep %v got %v", c.extra, c.expected, keep)
+		}
+	}
+}
+
+func TestGroundSurface(t *testing.T) {
+	g := NewGroundSurface(10)
+	// a plane rising 1 unit every 10 along x, sampled at the cell centers
+	for x := 0; x < 5; x++ {
+		for y := 0; y < 5; y++ {
+			g.Add(float64(x)*10+5, float64(y)*10+5, float64(x))
+			g.Add(float64(x)*10+5, float64(y)*10+5, float64(x))
+		}
+	}
+	cases := []struct {
+		x, y     float64
+		expected float64
+		ok       bool
+	}{
+		{5, 5, 0, true},
+		{10, 20, 0.5, true},
+		{32.5, 41, 2.75, true},
+		// outside of the grid only the closest cells contribute
+		{52, 25, 4, true},
+		{100, 100, 0, false},
+	}
+	for _, c := range cases {
+		z, ok := g.Height(c.x, c.y)
+		if ok != c.ok || math.Abs(z-c.expected) > 1e-9 {
+			t.Errorf("at %v,%v expected height %v (%v) got %v (%v)", c.x, c.y, c.expected, c.ok, z, ok)
+		}
+	}
+}
+
+func TestHeightReclassification(t *testing.T) {
+	g := NewGroundSurface(10)
+	g.Add(5, 5, 100)
+	h := NewHeightReclassification(g, []HeightClass{
+		{Min: -1000, Max: 0.2, Classification: GroundClassification},
+		{Min: 0.2, Max: 2, Classification: 3},
+		{Min: 1, Max: 30, Classification: 5},
+	})
+	cases := []struct {
+		pt       geom.Point64
+		expected uint8
+	}{
+		{geom.Point64{X: 5, Y: 5, Z: 99, Classification: 1}, GroundClassification},
+		{geom.Point64{X: 5, Y: 5, Z: 100.1, Classification: 1}, GroundClassification},
+		// the first matching rule wins
+		{geom.Point64{X: 5, Y: 5, Z: 101.5, Classification: 1}, 3},
+		{geom.Point64{X: 5, Y: 5, Z: 110, Classification: 1}, 5},
+		// no matching rule
+		{geom.Point64{X: 5, Y: 5, Z: 200, Classification: 6}, 6},
+		// no ground nearby
+		{geom.Point64{X: 500, Y: 5, Z: 100, Classification: 1}, 1},
+	}
+	for _, c := range cases {
+		pt, keep := h.Mutate(c.pt)
+		if !keep || pt.Classification != c.expected {
+			t.Errorf("for point %v expected classification %d got %d (%v)", c.pt, c.expected, pt.Classification, keep)
+		}
+	}
+}
+
+func TestHeightAboveGround(t *testing.T) {
+	g := NewGroundSurface(10)
+	g.Add(5, 5, 100)
+	h := NewHeightAboveGround(g, 0.5, 3)
+	cases := []struct {
+		pt       geom.Point64
+		expected bool
+	}{
+		{geom.Point64{X: 5, Y: 5, Z: 101}, true},
+		{geom.Point64{X: 5, Y: 5, Z: 103}, true},
+		{geom.Point64{X: 5, Y: 5, Z: 100.2}, false},
+		{geom.Point64{X: 5, Y: 5, Z: 110}, false},
+		// ground points are retained regardless of their height
+		{geom.Point64{X: 5, Y: 5, Z: 100.2, Classification: GroundClassification}, true},
+		// no ground nearby
+		{geom.Point64{X: 500, Y: 5, Z: 101}, false},
+	}
+	for _, c := range cases {
+		if _, keep := h.Mutate(c.pt); keep != c.expected {
+			t.Errorf("for point %v expected keep %v got %v", c.pt, c.expected, keep)
+		}
+	}
+}
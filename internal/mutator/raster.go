@@ -0,0 +1,65 @@
+package mutator
+
+import (
+	"math"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+)
+
+// Sampler returns the value of a raster at the given coordinates, expressed in the input CRS of the points.
+// Returns false if no value is available, e.g. because the coordinates fall outside of the raster.
+type Sampler func(x, y float64) (float64, bool)
+
+// RasterElevation replaces the Z coordinate of the points with the elevation sampled at their X, Y coordinates.
+// Points where no elevation is available are discarded, unless KeepOutside is true, in which case their
+// original Z coordinate is retained.
+type RasterElevation struct {
+	Sample      Sampler
+	KeepOutside bool
+}
+
+func NewRasterElevation(sample Sampler, keepOutside bool) *RasterElevation {
+	return &RasterElevation{
+		Sample:      sample,
+		KeepOutside: keepOutside,
+	}
+}
+
+func (r *RasterElevation) Mutate(pt geom.Point64) (geom.Point64, bool) {
+	z, ok := r.Sample(pt.X, pt.Y)
+	if !ok {
+		return pt, r.KeepOutside
+	}
+	pt.Z = z
+	return pt, true
+}
+
+// ColorSampler returns the red, green and blue values of a raster at the given coordinates, expressed in the input
+// CRS of the points. Returns false if no color is available, e.g. because the coordinates fall outside of the raster.
+type ColorSampler func(x, y float64) ([3]float64, bool)
+
+// RasterColor replaces the color of the points with the one sampled at their X, Y coordinates. The sampled values
+// are expected in the 0-255 range and are clamped to it. Points where no color is available keep their color.
+type RasterColor struct {
+	Sample ColorSampler
+}
+
+func NewRasterColor(sample ColorSampler) *RasterColor {
+	return &RasterColor{
+		Sample: sample,
+	}
+}
+
+func (r *RasterColor) Mutate(pt geom.Point64) (geom.Point64, bool) {
+	c, ok := r.Sample(pt.X, pt.Y)
+	if !ok {
+		return pt, true
+	}
+	pt.R, pt.G, pt.B = toColor(c[0]), toColor(c[1]), toColor(c[2])
+	return pt, true
+}
+
+// toColor rounds the given value to the closest 8 bit color component
+func toColor(v float64) uint8 {
+	return uint8(math.Round(math.Max(0, math.Min(255, v))))
+}
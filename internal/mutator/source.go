@@ -0,0 +1,24 @@
+package mutator
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+)
+
+// PointSourceFilter discards the points whose point source ID is not among the given ones
+type PointSourceFilter struct {
+	Ids map[uint16]bool
+}
+
+func NewPointSourceFilter(ids []uint16) *PointSourceFilter {
+	f := &PointSourceFilter{
+		Ids: make(map[uint16]bool, len(ids)),
+	}
+	for _, id := range ids {
+		f.Ids[id] = true
+	}
+	return f
+}
+
+func (f *PointSourceFilter) Mutate(pt geom.Point64) (geom.Point64, bool) {
+	return pt, f.Ids[pt.PointSourceId]
+}
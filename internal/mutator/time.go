@@ -0,0 +1,22 @@
+package mutator
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+)
+
+// TimeWindowFilter discards the points whose GPS time is outside of the [Start, End) interval
+type TimeWindowFilter struct {
+	Start float64
+	End   float64
+}
+
+func NewTimeWindowFilter(start, end float64) *TimeWindowFilter {
+	return &TimeWindowFilter{
+		Start: start,
+		End:   end,
+	}
+}
+
+func (f *TimeWindowFilter) Mutate(pt geom.Point64) (geom.Point64, bool) {
+	return pt, pt.GpsTime >= f.Start && pt.GpsTime < f.End
+}
@@ -0,0 +1,26 @@
+package mutator
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+)
+
+// UnitScale converts the coordinates of the points to meters, multiplying X and Y by the Horizontal factor
+// and Z by the Vertical factor
+type UnitScale struct {
+	Horizontal float64
+	Vertical   float64
+}
+
+func NewUnitScale(horizontal, vertical float64) *UnitScale {
+	return &UnitScale{
+		Horizontal: horizontal,
+		Vertical:   vertical,
+	}
+}
+
+func (u *UnitScale) Mutate(pt geom.Point64) (geom.Point64, bool) {
+	pt.X *= u.Horizontal
+	pt.Y *= u.Horizontal
+	pt.Z *= u.Vertical
+	return pt, true
+}
@@ -0,0 +1,435 @@
+package raster
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// TIFF tags used to decode the raster
+const (
+	tagImageWidth          = 256
+	tagImageLength         = 257
+	tagBitsPerSample       = 258
+	tagCompression         = 259
+	tagStripOffsets        = 273
+	tagSamplesPerPixel     = 277
+	tagRowsPerStrip        = 278
+	tagStripByteCounts     = 279
+	tagPlanarConfiguration = 284
+	tagPredictor           = 317
+	tagTileWidth           = 322
+	tagTileLength          = 323
+	tagTileOffsets         = 324
+	tagTileByteCounts      = 325
+	tagSampleFormat        = 339
+	tagModelPixelScale     = 33550
+	tagModelTiepoint       = 33922
+	tagModelTransformation = 34264
+	tagGeoKeyDirectory     = 34735
+	tagGdalNoData          = 42113
+)
+
+// GeoTIFF keys used to determine the raster CRS
+const (
+	keyRasterType     = 1025
+	keyGeographicType = 2048
+	keyProjectedType  = 3072
+	rasterPixelIsArea = 1
+)
+
+// GeoTiff is a georeferenced raster fully loaded in memory. Only single image, uncompressed or
+// deflate compressed GeoTIFFs are supported, with strip or tile layout and any number of bands.
+type GeoTiff struct {
+	// Width and Height are the size of the raster in pixels
+	Width, Height int
+	// Bands is the number of bands (samples per pixel) of the raster
+	Bands int
+	// Epsg is the EPSG code of the raster CRS, 0 if not specified in the file
+	Epsg int
+	// NoData is the value marking missing data, NaN if not specified in the file
+	NoData float64
+	// geoTransform maps pixel coordinates to world coordinates, with the same semantics of the GDAL geotransform:
+	// x = gt[0] + col*gt[1] + row*gt[2], y = gt[3] + col*gt[4] + row*gt[5]
+	// where col and row refer to the top left corner of the pixel
+	geoTransform [6]float64
+	// inverse of the geotransform, mapping world coordinates to pixel coordinates
+	inverse [6]float64
+	// data stores the samples, band interleaved by pixel
+	data []float32
+}
+
+// Open reads the GeoTIFF file at the given path
+func Open(path string) (*GeoTiff, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	raw, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	g, err := decode(raw)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read GeoTIFF %s: %w", path, err)
+	}
+	return g, nil
+}
+
+// Value returns the value of the given band at the given pixel
+func (g *GeoTiff) Value(band, col, row int) float64 {
+	return float64(g.data[(row*g.Width+col)*g.Bands+band])
+}
+
+// ToPixel converts world coordinates to continuous pixel coordinates, where integer
+// values refer to the top left corner of the pixel
+func (g *GeoTiff) ToPixel(x, y float64) (float64, float64) {
+	inv := g.inverse
+	return inv[0] + x*inv[1] + y*inv[2], inv[3] + x*inv[4] + y*inv[5]
+}
+
+// Nearest returns the value of the given band of the pixel containing the given world coordinates.
+// Returns false if the coordinates fall outside of the raster or on a NoData pixel.
+func (g *GeoTiff) Nearest(band int, x, y float64) (float64, bool) {
+	px, py := g.ToPixel(x, y)
+	col, row := int(math.Floor(px)), int(math.Floor(py))
+	if col < 0 || row < 0 || col >= g.Width || row >= g.Height {
+		return 0, false
+	}
+	v := g.Value(band, col, row)
+	if g.isNoData(v) {
+		return 0, false
+	}
+	return v, true
+}
+
+// Bilinear returns the value of the given band at the given world coordinates, interpolating
+// bilinearly the values of the four closest pixel centers. Returns false if the coordinates fall
+// outside of the raster or if any of the pixels involved in the interpolation is NoData.
+func (g *GeoTiff) Bilinear(band int, x, y float64) (float64, bool) {
+	px, py := g.ToPixel(x, y)
+	if px < 0 || py < 0 || px > float64(g.Width) || py > float64(g.Height) {
+		return 0, false
+	}
+	// shift to pixel center coordinates and clamp at the raster edges
+	cx := math.Min(math.Max(px-0.5, 0), float64(g.Width-1))
+	cy := math.Min(math.Max(py-0.5, 0), float64(g.Height-1))
+	c0, r0 := int(math.Floor(cx)), int(math.Floor(cy))
+	c1, r1 := minInt(c0+1, g.Width-1), minInt(r0+1, g.Height-1)
+	fx, fy := cx-float64(c0), cy-float64(r0)
+
+	samples := [4]struct {
+		col, row int
+		weight   float64
+	}{
+		{c0, r0, (1 - fx) * (1 - fy)},
+		{c1, r0, fx * (1 - fy)},
+		{c0, r1, (1 - fx) * fy},
+		{c1, r1, fx * fy},
+	}
+	out := 0.0
+	for _, s := range samples {
+		if s.weight == 0 {
+			// pixels not contributing to the result are ignored even if NoData
+			continue
+		}
+		v := g.Value(band, s.col, s.row)
+		if g.isNoData(v) {
+			return 0, false
+		}
+		out += v * s.weight
+	}
+	return out, true
+}
+
+func (g *GeoTiff) isNoData(v float64) bool {
+	if math.IsNaN(v) {
+		return true
+	}
+	return !math.IsNaN(g.NoData) && float64(float32(g.NoData)) == v
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// ifdEntry is a raw entry of a TIFF image file directory
+type ifdEntry struct {
+	typ   uint16
+	count uint32
+	data  []byte
+}
+
+// typeSizes maps the TIFF field types to their size in bytes
+var typeSizes = map[uint16]int{1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8}
+
+func decode(raw []byte) (*GeoTiff, error) {
+	if len(raw) < 8 {
+		return nil, errors.New("file too short")
+	}
+	var bo binary.ByteOrder
+	switch string(raw[0:2]) {
+	case "II":
+		bo = binary.LittleEndian
+	case "MM":
+		bo = binary.BigEndian
+	default:
+		return nil, errors.New("not a TIFF file")
+	}
+	if magic := bo.Uint16(raw[2:4]); magic != 42 {
+		return nil, fmt.Errorf("unsupported TIFF version %d, BigTIFF is not supported", magic)
+	}
+	entries, err := readIFD(raw, bo, bo.Uint32(raw[4:8]))
+	if err != nil {
+		return nil, err
+	}
+
+	g := &GeoTiff{
+		Width:  int(entryInt(entries, bo, tagImageWidth, 0)),
+		Height: int(entryInt(entries, bo, tagImageLength, 0)),
+		Bands:  int(entryInt(entries, bo, tagSamplesPerPixel, 1)),
+		NoData: math.NaN(),
+	}
+	if g.Width <= 0 || g.Height <= 0 {
+		return nil, errors.New("invalid raster size")
+	}
+	compression := entryInt(entries, bo, tagCompression, 1)
+	if compression != 1 && compression != 8 && compression != 32946 {
+		return nil, fmt.Errorf("unsupported compression %d", compression)
+	}
+	if predictor := entryInt(entries, bo, tagPredictor, 1); predictor != 1 {
+		return nil, fmt.Errorf("unsupported predictor %d", predictor)
+	}
+	if planar := entryInt(entries, bo, tagPlanarConfiguration, 1); planar != 1 && g.Bands > 1 {
+		return nil, errors.New("only pixel interleaved multi band rasters are supported")
+	}
+	bits := int(entryInt(entries, bo, tagBitsPerSample, 8))
+	format := entryInt(entries, bo, tagSampleFormat, 1)
+	sample, err := sampleDecoder(bo, bits, format)
+	if err != nil {
+		return nil, err
+	}
+	if err := g.readGeoreferencing(entries, bo); err != nil {
+		return nil, err
+	}
+	if e, ok := entries[tagGdalNoData]; ok {
+		if v, err := strconv.ParseFloat(strings.Trim(string(e.data), "\x00 "), 64); err == nil {
+			g.NoData = v
+		}
+	}
+
+	// blocks are either strips (full width) or tiles
+	blockWidth, blockHeight := g.Width, int(entryInt(entries, bo, tagRowsPerStrip, uint64(g.Height)))
+	offsetsTag, countsTag := uint16(tagStripOffsets), uint16(tagStripByteCounts)
+	if _, tiled := entries[tagTileOffsets]; tiled {
+		blockWidth = int(entryInt(entries, bo, tagTileWidth, 0))
+		blockHeight = int(entryInt(entries, bo, tagTileLength, 0))
+		offsetsTag, countsTag = tagTileOffsets, tagTileByteCounts
+	}
+	if blockWidth <= 0 || blockHeight <= 0 {
+		return nil, errors.New("invalid block size")
+	}
+	offsets := entryInts(entries, bo, offsetsTag)
+	counts := entryInts(entries, bo, countsTag)
+	if len(offsets) == 0 || len(offsets) != len(counts) {
+		return nil, errors.New("invalid raster data offsets")
+	}
+	blocksAcross := (g.Width + blockWidth - 1) / blockWidth
+	sampleSize := bits / 8
+	g.data = make([]float32, g.Width*g.Height*g.Bands)
+	for i := range offsets {
+		if offsets[i]+counts[i] > uint64(len(raw)) {
+			return nil, errors.New("raster data out of bounds")
+		}
+		block := raw[offsets[i] : offsets[i]+counts[i]]
+		if compression != 1 {
+			zr, err := zlib.NewReader(bytes.NewReader(block))
+			if err != nil {
+				return nil, err
+			}
+			block, err = io.ReadAll(zr)
+			if err != nil {
+				return nil, err
+			}
+		}
+		bx, by := (i%blocksAcross)*blockWidth, (i/blocksAcross)*blockHeight
+		for r := 0; r < blockHeight && by+r < g.Height; r++ {
+			for c := 0; c < blockWidth && bx+c < g.Width; c++ {
+				for b := 0; b < g.Bands; b++ {
+					pos := ((r*blockWidth+c)*g.Bands + b) * sampleSize
+					if pos+sampleSize > len(block) {
+						return nil, errors.New("truncated raster block")
+					}
+					g.data[((by+r)*g.Width+bx+c)*g.Bands+b] = float32(sample(block[pos : pos+sampleSize]))
+				}
+			}
+		}
+	}
+	return g, nil
+}
+
+// readGeoreferencing computes the geotransform and the CRS of the raster from the GeoTIFF tags
+func (g *GeoTiff) readGeoreferencing(entries map[uint16]ifdEntry, bo binary.ByteOrder) error {
+	pixelIsArea := true
+	if keys := entryInts(entries, bo, tagGeoKeyDirectory); len(keys) >= 4 {
+		for i := 0; i < int(keys[3]) && 4+i*4+3 < len(keys); i++ {
+			key := keys[4+i*4 : 8+i*4]
+			// only keys with the value stored inline (location 0) are relevant here
+			if key[1] != 0 {
+				continue
+			}
+			switch key[0] {
+			case keyRasterType:
+				pixelIsArea = key[3] == rasterPixelIsArea
+			case keyGeographicType:
+				if g.Epsg == 0 {
+					g.Epsg = int(key[3])
+				}
+			case keyProjectedType:
+				g.Epsg = int(key[3])
+			}
+		}
+		// user defined CRS are not supported
+		if g.Epsg == 32767 {
+			g.Epsg = 0
+		}
+	}
+
+	if m := entryFloats(entries, bo, tagModelTransformation); len(m) == 16 {
+		g.geoTransform = [6]float64{m[3], m[0], m[1], m[7], m[4], m[5]}
+	} else {
+		scale := entryFloats(entries, bo, tagModelPixelScale)
+		tie := entryFloats(entries, bo, tagModelTiepoint)
+		if len(scale) < 2 || len(tie) < 6 {
+			return errors.New("the raster is not georeferenced")
+		}
+		g.geoTransform = [6]float64{tie[3] - tie[0]*scale[0], scale[0], 0, tie[4] + tie[1]*scale[1], 0, -scale[1]}
+	}
+	if !pixelIsArea {
+		// the georeferencing refers to the pixel centers, move it to the top left corner
+		gt := g.geoTransform
+		g.geoTransform[0] = gt[0] - 0.5*gt[1] - 0.5*gt[2]
+		g.geoTransform[3] = gt[3] - 0.5*gt[4] - 0.5*gt[5]
+	}
+	gt := g.geoTransform
+	det := gt[1]*gt[5] - gt[2]*gt[4]
+	if det == 0 {
+		return errors.New("invalid raster georeferencing")
+	}
+	g.inverse = [6]float64{
+		(gt[2]*gt[3] - gt[0]*gt[5]) / det, gt[5] / det, -gt[2] / det,
+		(gt[0]*gt[4] - gt[1]*gt[3]) / det, -gt[4] / det, gt[1] / det,
+	}
+	return nil
+}
+
+func readIFD(raw []byte, bo binary.ByteOrder, offset uint32) (map[uint16]ifdEntry, error) {
+	if int(offset)+2 > len(raw) {
+		return nil, errors.New("invalid IFD offset")
+	}
+	n := int(bo.Uint16(raw[offset : offset+2]))
+	entries := map[uint16]ifdEntry{}
+	for i := 0; i < n; i++ {
+		pos := int(offset) + 2 + i*12
+		if pos+12 > len(raw) {
+			return nil, errors.New("truncated IFD")
+		}
+		tag := bo.Uint16(raw[pos : pos+2])
+		typ := bo.Uint16(raw[pos+2 : pos+4])
+		count := bo.Uint32(raw[pos+4 : pos+8])
+		size, ok := typeSizes[typ]
+		if !ok {
+			continue
+		}
+		length := size * int(count)
+		var data []byte
+		if length <= 4 {
+			data = raw[pos+8 : pos+8+length]
+		} else {
+			start := int(bo.Uint32(raw[pos+8 : pos+12]))
+			if start+length > len(raw) {
+				return nil, fmt.Errorf("tag %d out of bounds", tag)
+			}
+			data = raw[start : start+length]
+		}
+		entries[tag] = ifdEntry{typ: typ, count: count, data: data}
+	}
+	return entries, nil
+}
+
+// entryInts returns the values of an integer tag
+func entryInts(entries map[uint16]ifdEntry, bo binary.ByteOrder, tag uint16) []uint64 {
+	e, ok := entries[tag]
+	if !ok {
+		return nil
+	}
+	out := make([]uint64, e.count)
+	for i := range out {
+		switch e.typ {
+		case 1, 7:
+			out[i] = uint64(e.data[i])
+		case 3:
+			out[i] = uint64(bo.Uint16(e.data[i*2:]))
+		case 4:
+			out[i] = uint64(bo.Uint32(e.data[i*4:]))
+		default:
+			return nil
+		}
+	}
+	return out
+}
+
+// entryInt returns the first value of an integer tag or the given default if missing
+func entryInt(entries map[uint16]ifdEntry, bo binary.ByteOrder, tag uint16, def uint64) uint64 {
+	if v := entryInts(entries, bo, tag); len(v) > 0 {
+		return v[0]
+	}
+	return def
+}
+
+// entryFloats returns the values of a floating point tag
+func entryFloats(entries map[uint16]ifdEntry, bo binary.ByteOrder, tag uint16) []float64 {
+	e, ok := entries[tag]
+	if !ok || e.typ != 12 {
+		return nil
+	}
+	out := make([]float64, e.count)
+	for i := range out {
+		out[i] = math.Float64frombits(bo.Uint64(e.data[i*8:]))
+	}
+	return out
+}
+
+// sampleDecoder returns a function decoding a raw sample with the given bit depth and format
+func sampleDecoder(bo binary.ByteOrder, bits int, format uint64) (func([]byte) float64, error) {
+	switch {
+	case format == 1 && bits == 8:
+		return func(b []byte) float64 { return float64(b[0]) }, nil
+	case format == 1 && bits == 16:
+		return func(b []byte) float64 { return float64(bo.Uint16(b)) }, nil
+	case format == 1 && bits == 32:
+		return func(b []byte) float64 { return float64(bo.Uint32(b)) }, nil
+	case format == 2 && bits == 8:
+		return func(b []byte) float64 { return float64(int8(b[0])) }, nil
+	case format == 2 && bits == 16:
+		return func(b []byte) float64 { return float64(int16(bo.Uint16(b))) }, nil
+	case format == 2 && bits == 32:
+		return func(b []byte) float64 { return float64(int32(bo.Uint32(b))) }, nil
+	case format == 3 && bits == 32:
+		return func(b []byte) float64 { return float64(math.Float32frombits(bo.Uint32(b))) }, nil
+	case format == 3 && bits == 64:
+		return func(b []byte) float64 { return math.Float64frombits(bo.Uint64(b)) }, nil
+	}
+	return nil, fmt.Errorf("unsupported sample format %d with %d bits per sample", format, bits)
+}
@@ -0,0 +1,209 @@
+package raster
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+type testTag struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	data  []byte
+}
+
+func shortsTag(tag uint16, values ...uint16) testTag {
+	b := make([]byte, 2*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint16(b[i*2:], v)
+	}
+	return testTag{tag: tag, typ: 3, count: uint32(len(values)), data: b}
+}
+
+func longsTag(tag uint16, values ...uint32) testTag {
+	b := make([]byte, 4*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint32(b[i*4:], v)
+	}
+	return testTag{tag: tag, typ: 4, count: uint32(len(values)), data: b}
+}
+
+func doublesTag(tag uint16, values ...float64) testTag {
+	b := make([]byte, 8*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint64(b[i*8:], math.Float64bits(v))
+	}
+	return testTag{tag: tag, typ: 12, count: uint32(len(values)), data: b}
+}
+
+// writeTestTiff writes a little endian, single strip, float32 GeoTIFF with the given pixel values
+// (band interleaved by pixel) and the given georeferencing tags
+func writeTestTiff(t *testing.T, width, height, bands int, values []float32, compress bool, geoTags ...testTag) string {
+	t.Helper()
+	var pixels bytes.Buffer
+	binary.Write(&pixels, binary.LittleEndian, values)
+	block := pixels.Bytes()
+	compression := uint16(1)
+	if compress {
+		var z bytes.Buffer
+		zw := zlib.NewWriter(&z)
+		zw.Write(block)
+		zw.Close()
+		block = z.Bytes()
+		compression = 8
+	}
+	bits := make([]uint16, bands)
+	formats := make([]uint16, bands)
+	for i := range bits {
+		bits[i], formats[i] = 32, 3
+	}
+	tags := []testTag{
+		longsTag(tagImageWidth, uint32(width)),
+		longsTag(tagImageLength, uint32(height)),
+		shortsTag(tagBitsPerSample, bits...),
+		shortsTag(tagCompression, compression),
+		shortsTag(tagSamplesPerPixel, uint16(bands)),
+		longsTag(tagRowsPerStrip, uint32(height)),
+		longsTag(tagStripByteCounts, uint32(len(block))),
+		shortsTag(tagSampleFormat, formats...),
+	}
+	tags = append(tags, geoTags...)
+	// the strip offset is patched once the layout is known
+	tags = append(tags, longsTag(tagStripOffsets, 0))
+	sort.Slice(tags, func(i, j int) bool { return tags[i].tag < tags[j].tag })
+
+	ifdOffset := 8
+	dataOffset := ifdOffset + 2 + len(tags)*12 + 4
+	var extra bytes.Buffer
+	var ifd bytes.Buffer
+	binary.Write(&ifd, binary.LittleEndian, uint16(len(tags)))
+	stripOffsetPos := 0
+	for _, tag := range tags {
+		binary.Write(&ifd, binary.LittleEndian, tag.tag)
+		binary.Write(&ifd, binary.LittleEndian, tag.typ)
+		binary.Write(&ifd, binary.LittleEndian, tag.count)
+		if tag.tag == tagStripOffsets {
+			stripOffsetPos = ifdOffset + ifd.Len()
+		}
+		if len(tag.data) <= 4 {
+			v := make([]byte, 4)
+			copy(v, tag.data)
+			ifd.Write(v)
+		} else {
+			binary.Write(&ifd, binary.LittleEndian, uint32(dataOffset+extra.Len()))
+			extra.Write(tag.data)
+		}
+	}
+	binary.Write(&ifd, binary.LittleEndian, uint32(0))
+
+	out := []byte{'I', 'I', 42, 0, 8, 0, 0, 0}
+	out = append(out, ifd.Bytes()...)
+	out = append(out, extra.Bytes()...)
+	binary.LittleEndian.PutUint32(out[stripOffsetPos:], uint32(len(out)))
+	out = append(out, block...)
+
+	p := filepath.Join(t.TempDir(), "test.tif")
+	if err := os.WriteFile(p, out, 0666); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	return p
+}
+
+func TestOpenAndSample(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		// 3x2 raster with 10m pixels, top left corner at (1000, 2000), EPSG:32633
+		p := writeTestTiff(t, 3, 2, 1, []float32{
+			1, 2, 3,
+			4, 5, -9999,
+		}, compress,
+			doublesTag(tagModelPixelScale, 10, 10, 0),
+			doublesTag(tagModelTiepoint, 0, 0, 0, 1000, 2000, 0),
+			shortsTag(tagGeoKeyDirectory, 1, 1, 0, 2, keyRasterType, 0, 1, rasterPixelIsArea, keyProjectedType, 0, 1, 32633),
+			testTag{tag: tagGdalNoData, typ: 2, count: 6, data: []byte("-9999\x00")},
+		)
+		g, err := Open(p)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if g.Width != 3 || g.Height != 2 || g.Bands != 1 {
+			t.Errorf("unexpected raster size %dx%dx%d", g.Width, g.Height, g.Bands)
+		}
+		if g.Epsg != 32633 {
+			t.Errorf("expected epsg %d got %d", 32633, g.Epsg)
+		}
+		if g.NoData != -9999 {
+			t.Errorf("expected nodata %v got %v", -9999, g.NoData)
+		}
+		cases := []struct {
+			x, y     float64
+			expected float64
+			ok       bool
+		}{
+			{1005, 1995, 1, true},   // center of the top left pixel
+			{1010, 1995, 1.5, true}, // halfway between the first two pixels
+			{1001, 1999, 1, true},   // edge, clamped to the first pixel center
+			{1010, 1990, 3, true},   // center of the four top left pixels
+			{1025, 1995, 3, true},   // center of the top right pixel
+			{1025, 1985, 0, false},  // nodata pixel
+			{999, 1995, 0, false},   // outside
+			{1005, 2001, 0, false},  // outside
+		}
+		for _, c := range cases {
+			actual, ok := g.Bilinear(0, c.x, c.y)
+			if ok != c.ok {
+				t.Errorf("at %v,%v expected ok %v got %v", c.x, c.y, c.ok, ok)
+				continue
+			}
+			if ok && math.Abs(actual-c.expected) > 1e-9 {
+				t.Errorf("at %v,%v expected %v got %v", c.x, c.y, c.expected, actual)
+			}
+		}
+		if v, ok := g.Nearest(0, 1019, 1981); !ok || v != 5 {
+			t.Errorf("expected nearest value %v got %v (%v)", 5, v, ok)
+		}
+	}
+}
+
+func TestOpenMultiBandPixelIsPoint(t *testing.T) {
+	// tiepoint refers to the center of the top left pixel
+	p := writeTestTiff(t, 2, 1, 3, []float32{
+		10, 20, 30, 40, 50, 60,
+	}, false,
+		doublesTag(tagModelPixelScale, 1, 1, 0),
+		doublesTag(tagModelTiepoint, 0, 0, 0, 0.5, 0.5, 0),
+		shortsTag(tagGeoKeyDirectory, 1, 1, 0, 2, keyRasterType, 0, 1, 2, keyGeographicType, 0, 1, 4326),
+	)
+	g, err := Open(p)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if g.Epsg != 4326 {
+		t.Errorf("expected epsg %d got %d", 4326, g.Epsg)
+	}
+	for band, expected := range []float64{25, 35, 45} {
+		if actual, ok := g.Bilinear(band, 1, 0.5); !ok || actual != expected {
+			t.Errorf("band %d: expected %v got %v (%v)", band, expected, actual, ok)
+		}
+	}
+}
+
+func TestOpenErrors(t *testing.T) {
+	if _, err := Open(filepath.Join(t.TempDir(), "missing.tif")); err == nil {
+		t.Errorf("expected error for missing file, got none")
+	}
+	notTiff := filepath.Join(t.TempDir(), "a.tif")
+	os.WriteFile(notTiff, []byte("not a tiff file"), 0666)
+	if _, err := Open(notTiff); err == nil {
+		t.Errorf("expected error for invalid file, got none")
+	}
+	notGeo := writeTestTiff(t, 1, 1, 1, []float32{1}, false)
+	if _, err := Open(notGeo); err == nil {
+		t.Errorf("expected error for non georeferenced file, got none")
+	}
+}
@@ -0,0 +1,155 @@
+package terrain
+
+import (
+	"math"
+	"math/big"
+	"sort"
+)
+
+// vertex is a point of the quantized tile grid, with coordinates in the 0-32767 range
+type vertex struct {
+	u, v int64
+}
+
+// triangle holds the indices of its vertices in counter-clockwise order and its circumcircle
+type triangle struct {
+	a, b, c int
+	cx, cy  float64
+	r       float64
+}
+
+// triangulate returns the Delaunay triangulation of the given vertices as triplets of indices in counter-clockwise order.
+// The first four vertices must be the corners of the rectangle containing all the others, no vertex can be repeated.
+// It implements the Bowyer-Watson algorithm starting from the two triangles of the rectangle, inserting the vertices
+// sorted by u so that the triangles whose circumcircle lies entirely before the current vertex can be set aside.
+// The predicates are exact, as the coordinates are integers.
+func triangulate(vs []vertex) [][3]int {
+	if len(vs) < 4 {
+		return nil
+	}
+	order := make([]int, 0, len(vs)-4)
+	for i := 4; i < len(vs); i++ {
+		order = append(order, i)
+	}
+	sort.Slice(order, func(i, j int) bool {
+		a, b := vs[order[i]], vs[order[j]]
+		if a.u != b.u {
+			return a.u < b.u
+		}
+		return a.v < b.v
+	})
+	open := []triangle{}
+	for _, t := range [][3]int{{0, 1, 2}, {0, 2, 3}} {
+		if tr, ok := newTriangle(vs, t[0], t[1], t[2]); ok {
+			open = append(open, tr)
+		}
+	}
+	closed := []triangle{}
+	for _, i := range order {
+		p := vs[i]
+		px := float64(p.u)
+		edges := map[[2]int]int{}
+		kept := open[:0]
+		for _, t := range open {
+			if px-t.cx > t.r*(1+1e-9)+1e-6 {
+				// no vertex still to insert can fall in the circumcircle
+				closed = append(closed, t)
+				continue
+			}
+			if inCircle(vs[t.a], vs[t.b], vs[t.c], p) {
+				for _, e := range [][2]int{{t.a, t.b}, {t.b, t.c}, {t.c, t.a}} {
+					edges[edgeKey(e)]++
+				}
+				continue
+			}
+			kept = append(kept, t)
+		}
+		open = kept
+		for e, count := range edges {
+			if count > 1 {
+				// edges shared by two removed triangles are inside the cavity
+				continue
+			}
+			// the edge the vertex lies on, if any, is replaced by the two adjacent new triangles
+			if tr, ok := newTriangle(vs, e[0], e[1], i); ok {
+				open = append(open, tr)
+			}
+		}
+	}
+	closed = append(closed, open...)
+	result := make([][3]int, len(closed))
+	for i, t := range closed {
+		result[i] = [3]int{t.a, t.b, t.c}
+	}
+	// map iteration order is random, sort the triangles to make the output deterministic
+	sort.Slice(result, func(i, j int) bool {
+		for k := 0; k < 3; k++ {
+			if result[i][k] != result[j][k] {
+				return result[i][k] < result[j][k]
+			}
+		}
+		return false
+	})
+	return result
+}
+
+func edgeKey(e [2]int) [2]int {
+	if e[0] > e[1] {
+		return [2]int{e[1], e[0]}
+	}
+	return e
+}
+
+// newTriangle returns the counter-clockwise triangle with the given vertices, or false if they are collinear
+func newTriangle(vs []vertex, a, b, c int) (triangle, bool) {
+	o := orient(vs[a], vs[b], vs[c])
+	if o == 0 {
+		return triangle{}, false
+	}
+	if o < 0 {
+		b, c = c, b
+	}
+	// circumcenter relative to the first vertex
+	bx, by := float64(vs[b].u-vs[a].u), float64(vs[b].v-vs[a].v)
+	cx, cy := float64(vs[c].u-vs[a].u), float64(vs[c].v-vs[a].v)
+	d := 2 * (bx*cy - by*cx)
+	b2, c2 := bx*bx+by*by, cx*cx+cy*cy
+	ux := (cy*b2 - by*c2) / d
+	uy := (bx*c2 - cx*b2) / d
+	return triangle{
+		a:  a,
+		b:  b,
+		c:  c,
+		cx: ux + float64(vs[a].u),
+		cy: uy + float64(vs[a].v),
+		r:  math.Sqrt(ux*ux + uy*uy),
+	}, true
+}
+
+// orient returns a positive value if a, b, c are in counter-clockwise order, negative if clockwise and zero if collinear
+func orient(a, b, c vertex) int64 {
+	return (b.u-a.u)*(c.v-a.v) - (b.v-a.v)*(c.u-a.u)
+}
+
+// inCircle returns true if p lies strictly inside the circumcircle of the counter-clockwise triangle a, b, c.
+// The determinant is evaluated in floating point and recomputed exactly only when the result is uncertain.
+func inCircle(a, b, c, p vertex) bool {
+	adx, ady := a.u-p.u, a.v-p.v
+	bdx, bdy := b.u-p.u, b.v-p.v
+	cdx, cdy := c.u-p.u, c.v-p.v
+	alift, blift, clift := adx*adx+ady*ady, bdx*bdx+bdy*bdy, cdx*cdx+cdy*cdy
+	bc, ca, ab := bdx*cdy-cdx*bdy, cdx*ady-adx*cdy, adx*bdy-bdx*ady
+	t1, t2, t3 := float64(alift)*float64(bc), float64(blift)*float64(ca), float64(clift)*float64(ab)
+	det := t1 + t2 + t3
+	bound := (math.Abs(t1) + math.Abs(t2) + math.Abs(t3)) * 1e-14
+	if det > bound {
+		return true
+	}
+	if det < -bound {
+		return false
+	}
+	exact := new(big.Int).Mul(big.NewInt(alift), big.NewInt(bc))
+	exact.Add(exact, new(big.Int).Mul(big.NewInt(blift), big.NewInt(ca)))
+	exact.Add(exact, new(big.Int).Mul(big.NewInt(clift), big.NewInt(ab)))
+	return exact.Sign() > 0
+}
@@ -0,0 +1,75 @@
+package terrain
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestTriangulate(t *testing.T) {
+	vs := []vertex{{0, 0}, {1000, 0}, {1000, 1000}, {0, 1000}}
+	seen := map[vertex]bool{}
+	for _, v := range vs {
+		seen[v] = true
+	}
+	// collinear vertices along the edges and a regular grid produce many degenerate configurations
+	for k := int64(100); k < 1000; k += 100 {
+		vs = append(vs, vertex{k, 0}, vertex{1000, k}, vertex{k, 1000}, vertex{0, k})
+	}
+	for x := int64(250); x < 1000; x += 250 {
+		for y := int64(250); y < 1000; y += 250 {
+			vs = append(vs, vertex{x, y})
+		}
+	}
+	r := rand.New(rand.NewSource(1))
+	for len(vs) < 200 {
+		v := vertex{r.Int63n(999) + 1, r.Int63n(999) + 1}
+		if !seen[v] {
+			vs = append(vs, v)
+		}
+		seen[v] = true
+	}
+
+	triangles := triangulate(vs)
+	var area int64
+	used := map[int]bool{}
+	for _, tr := range triangles {
+		o := orient(vs[tr[0]], vs[tr[1]], vs[tr[2]])
+		if o <= 0 {
+			t.Fatalf("expected counter-clockwise triangle, got %v", tr)
+		}
+		area += o
+		for _, i := range tr {
+			used[i] = true
+		}
+		for i, p := range vs {
+			if i != tr[0] && i != tr[1] && i != tr[2] && inCircle(vs[tr[0]], vs[tr[1]], vs[tr[2]], p) {
+				t.Fatalf("vertex %v is inside the circumcircle of triangle %v", p, tr)
+			}
+		}
+	}
+	// twice the area of the rectangle, as orient returns the doubled area
+	if area != 2*1000*1000 {
+		t.Errorf("expected triangles to cover the rectangle, got doubled area %d", area)
+	}
+	if len(used) != len(vs) {
+		t.Errorf("expected all %d vertices to be used got %d", len(vs), len(used))
+	}
+}
+
+func TestInCircle(t *testing.T) {
+	a, b, c := vertex{0, 0}, vertex{2, 0}, vertex{2, 2}
+	if !inCircle(a, b, c, vertex{1, 1}) {
+		t.Errorf("expected center to be inside the circle")
+	}
+	if inCircle(a, b, c, vertex{0, 2}) {
+		t.Errorf("expected cocircular point not to be inside the circle")
+	}
+	if inCircle(a, b, c, vertex{3, 3}) {
+		t.Errorf("expected far point to be outside the circle")
+	}
+	// large coordinates require the exact evaluation
+	a, b, c = vertex{0, 0}, vertex{32767, 0}, vertex{32767, 32767}
+	if inCircle(a, b, c, vertex{0, 32767}) {
+		t.Errorf("expected cocircular point not to be inside the circle")
+	}
+}
@@ -0,0 +1,223 @@
+package terrain
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"sort"
+)
+
+const (
+	// quantizedMax is the maximum value of the quantized vertex coordinates and heights
+	quantizedMax = 32767
+	// wgs84A and wgs84F are the semi-major axis and the flattening of the WGS84 ellipsoid
+	wgs84A = 6378137.0
+	wgs84F = 1 / 298.257223563
+)
+
+// rectangle is the extent of a tile in degrees
+type rectangle struct {
+	west, south, east, north float64
+}
+
+// tileMesh is the triangulated surface of a terrain tile, with vertices quantized over the tile rectangle
+type tileMesh struct {
+	rect      rectangle
+	vertices  []vertex
+	heights   []float64
+	triangles [][3]int
+}
+
+// header is the fixed size header of a quantized-mesh tile
+type header struct {
+	CenterX, CenterY, CenterZ                                              float64
+	MinimumHeight, MaximumHeight                                           float32
+	BoundingSphereCenterX, BoundingSphereCenterY                           float64
+	BoundingSphereCenterZ, BoundingSphereRadius                            float64
+	HorizonOcclusionPointX, HorizonOcclusionPointY, HorizonOcclusionPointZ float64
+}
+
+type vec3 struct {
+	x, y, z float64
+}
+
+func (a vec3) sub(b vec3) vec3      { return vec3{a.x - b.x, a.y - b.y, a.z - b.z} }
+func (a vec3) dot(b vec3) float64   { return a.x*b.x + a.y*b.y + a.z*b.z }
+func (a vec3) scale(s float64) vec3 { return vec3{a.x * s, a.y * s, a.z * s} }
+func (a vec3) norm() float64        { return math.Sqrt(a.dot(a)) }
+func (a vec3) cross(b vec3) vec3 {
+	return vec3{a.y*b.z - a.z*b.y, a.z*b.x - a.x*b.z, a.x*b.y - a.y*b.x}
+}
+func (a vec3) toScaledSpace() vec3 {
+	b := wgs84A * (1 - wgs84F)
+	return vec3{a.x / wgs84A, a.y / wgs84A, a.z / b}
+}
+
+// toEcef converts geographic coordinates in degrees and the ellipsoidal height in meters to EPSG:4978 coordinates
+func toEcef(lon, lat, h float64) vec3 {
+	e2 := wgs84F * (2 - wgs84F)
+	lonR, latR := lon*math.Pi/180, lat*math.Pi/180
+	n := wgs84A / math.Sqrt(1-e2*math.Sin(latR)*math.Sin(latR))
+	return vec3{
+		x: (n + h) * math.Cos(latR) * math.Cos(lonR),
+		y: (n + h) * math.Cos(latR) * math.Sin(lonR),
+		z: (n*(1-e2) + h) * math.Sin(latR),
+	}
+}
+
+// encode serializes the mesh in the quantized-mesh-1.0 format. Vertices are renumbered in order of first
+// use by the triangles, as required by the high water mark encoding of the indices.
+func (m *tileMesh) encode() []byte {
+	remap := make([]int, len(m.vertices))
+	for i := range remap {
+		remap[i] = -1
+	}
+	order := []int{}
+	indices := make([]int, 0, len(m.triangles)*3)
+	for _, t := range m.triangles {
+		for _, i := range t {
+			if remap[i] < 0 {
+				remap[i] = len(order)
+				order = append(order, i)
+			}
+			indices = append(indices, remap[i])
+		}
+	}
+
+	minH, maxH := math.MaxFloat64, -math.MaxFloat64
+	for _, i := range order {
+		minH = math.Min(minH, m.heights[i])
+		maxH = math.Max(maxH, m.heights[i])
+	}
+	positions := make([]vec3, len(order))
+	for k, i := range order {
+		positions[k] = toEcef(m.lon(m.vertices[i]), m.lat(m.vertices[i]), m.heights[i])
+	}
+	center := toEcef((m.rect.west+m.rect.east)/2, (m.rect.south+m.rect.north)/2, (minH+maxH)/2)
+	radius := 0.0
+	for _, p := range positions {
+		radius = math.Max(radius, p.sub(center).norm())
+	}
+	occlusion := horizonOcclusionPoint(center, positions)
+
+	buf := &bytes.Buffer{}
+	binary.Write(buf, binary.LittleEndian, header{
+		CenterX: center.x, CenterY: center.y, CenterZ: center.z,
+		MinimumHeight: float32(minH), MaximumHeight: float32(maxH),
+		BoundingSphereCenterX: center.x, BoundingSphereCenterY: center.y, BoundingSphereCenterZ: center.z,
+		BoundingSphereRadius:   radius,
+		HorizonOcclusionPointX: occlusion.x, HorizonOcclusionPointY: occlusion.y, HorizonOcclusionPointZ: occlusion.z,
+	})
+
+	binary.Write(buf, binary.LittleEndian, uint32(len(order)))
+	us, vs, hs := make([]int, len(order)), make([]int, len(order)), make([]int, len(order))
+	for k, i := range order {
+		us[k], vs[k] = int(m.vertices[i].u), int(m.vertices[i].v)
+		if maxH > minH {
+			hs[k] = int(math.Round((m.heights[i] - minH) / (maxH - minH) * quantizedMax))
+		}
+	}
+	for _, values := range [][]int{us, vs, hs} {
+		prev := 0
+		for _, value := range values {
+			binary.Write(buf, binary.LittleEndian, zigZag(value-prev))
+			prev = value
+		}
+	}
+
+	// 32 bit indices are used when the vertices cannot be addressed with 16 bits, aligned to 4 bytes
+	wide := len(order) > 65536
+	if wide && buf.Len()%4 != 0 {
+		buf.Write(make([]byte, 4-buf.Len()%4))
+	}
+	writeIndex := func(i int) {
+		if wide {
+			binary.Write(buf, binary.LittleEndian, uint32(i))
+		} else {
+			binary.Write(buf, binary.LittleEndian, uint16(i))
+		}
+	}
+	binary.Write(buf, binary.LittleEndian, uint32(len(m.triangles)))
+	highest := 0
+	for _, i := range indices {
+		writeIndex(highest - i)
+		if i == highest {
+			highest++
+		}
+	}
+
+	// edge vertices in west, south, east, north order
+	edges := [4][]int{}
+	for k, i := range order {
+		v := m.vertices[i]
+		if v.u == 0 {
+			edges[0] = append(edges[0], k)
+		}
+		if v.v == 0 {
+			edges[1] = append(edges[1], k)
+		}
+		if v.u == quantizedMax {
+			edges[2] = append(edges[2], k)
+		}
+		if v.v == quantizedMax {
+			edges[3] = append(edges[3], k)
+		}
+	}
+	for e, edge := range edges {
+		vertical := e%2 == 0
+		sort.Slice(edge, func(a, b int) bool {
+			if vertical {
+				return vs[edge[a]] < vs[edge[b]]
+			}
+			return us[edge[a]] < us[edge[b]]
+		})
+		binary.Write(buf, binary.LittleEndian, uint32(len(edge)))
+		for _, k := range edge {
+			writeIndex(k)
+		}
+	}
+	return buf.Bytes()
+}
+
+func (m *tileMesh) lon(v vertex) float64 {
+	return m.rect.west + float64(v.u)/quantizedMax*(m.rect.east-m.rect.west)
+}
+
+func (m *tileMesh) lat(v vertex) float64 {
+	return m.rect.south + float64(v.v)/quantizedMax*(m.rect.north-m.rect.south)
+}
+
+func zigZag(n int) uint16 {
+	return uint16((n << 1) ^ (n >> 31))
+}
+
+// horizonOcclusionPoint computes the point, in the ellipsoid-scaled frame, that when below the horizon guarantees that
+// all the given positions are below the horizon too. The direction is the one of the tile center, as done by Cesium.
+func horizonOcclusionPoint(center vec3, positions []vec3) vec3 {
+	direction := center.toScaledSpace()
+	direction = direction.scale(1 / direction.norm())
+	magnitude := 0.0
+	for _, p := range positions {
+		m := occlusionMagnitude(p.toScaledSpace(), direction)
+		if math.IsNaN(m) || math.IsInf(m, 0) || m <= 0 {
+			// the positions span too large a part of the ellipsoid, use a point far enough to never be occluded
+			magnitude = math.Max(magnitude, 1000)
+			continue
+		}
+		magnitude = math.Max(magnitude, m)
+	}
+	return direction.scale(magnitude)
+}
+
+func occlusionMagnitude(p, direction vec3) float64 {
+	magnitudeSquared := p.dot(p)
+	magnitude := math.Sqrt(magnitudeSquared)
+	p = p.scale(1 / magnitude)
+	magnitudeSquared = math.Max(1, magnitudeSquared)
+	magnitude = math.Max(1, magnitude)
+	cosAlpha := p.dot(direction)
+	sinAlpha := p.cross(direction).norm()
+	cosBeta := 1 / magnitude
+	sinBeta := math.Sqrt(magnitudeSquared-1) * cosBeta
+	return 1 / (cosAlpha*cosBeta - sinAlpha*sinBeta)
+}
@@ -0,0 +1,311 @@
+package terrain
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/conv/coor"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/tree"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/utils"
+)
+
+const (
+	// tileCells is the number of cells along each side of a tile. Each cell contributes at most one ground point to the mesh
+	tileCells = 64
+	// maxLevel is the deepest level of the generated tile pyramid
+	maxLevel = 20
+)
+
+// Point is a ground point in EPSG:4979 coordinates, with longitude and latitude in degrees and ellipsoidal height in meters
+type Point struct {
+	Lon, Lat, Height float64
+}
+
+// CollectPoints returns the points of the tree rooted at the given node that have the given classification,
+// converted to EPSG:4979 coordinates
+func CollectPoints(node tree.Node, conv coor.CoordinateConverter, classification uint8) ([]Point, error) {
+	pts := []Point{}
+	var visit func(n tree.Node) error
+	visit = func(n tree.Node) error {
+		cX, cY, cZ, err := n.GetCenter(conv)
+		if err != nil {
+			return err
+		}
+		list := n.GetPoints(conv)
+		list.Reset()
+		for i := 0; i < list.Len(); i++ {
+			pt, err := list.Next()
+			if err != nil {
+				return err
+			}
+			if pt.Classification != classification {
+				continue
+			}
+			c, err := conv.ToSrid(coor.EcefSrid, coor.GeographicSrid, geom.Coord{X: float64(pt.X) + cX, Y: float64(pt.Y) + cY, Z: float64(pt.Z) + cZ})
+			if err != nil {
+				return err
+			}
+			pts = append(pts, Point{Lon: c.X, Lat: c.Y, Height: c.Z})
+		}
+		for _, child := range n.GetChildren() {
+			if child != nil {
+				if err := visit(child); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := visit(node); err != nil {
+		return nil, err
+	}
+	return pts, nil
+}
+
+// Write triangulates the given ground points and stores them in the folder as a quantized-mesh terrain tileset
+// using the geographic TMS tiling scheme, with a layer.json descriptor and a {z}/{x}/{y}.terrain file per tile.
+// Each tile keeps a single point per cell of a tileCells x tileCells grid, triangulated together with vertices
+// placed along the tile edges, whose height is shared with the adjacent tiles to avoid cracks. Edges far from the
+// ground points are placed on the ellipsoid surface. The pyramid is generated down to the level whose cells are
+// as large as the average spacing of the points.
+func Write(folder string, pts []Point) error {
+	if len(pts) == 0 {
+		return fmt.Errorf("no ground points available to generate the terrain")
+	}
+	levels := levelCount(pts)
+	available := make([][]tileRange, levels+1)
+	for z := 0; z <= levels; z++ {
+		g := newLevelGrid(z, pts)
+		tiles := g.tiles()
+		for _, tile := range tiles {
+			dir := filepath.Join(folder, strconv.Itoa(z), strconv.Itoa(tile.x))
+			if err := utils.CreateDirectoryIfDoesNotExist(dir); err != nil {
+				return err
+			}
+			data := g.mesh(tile).encode()
+			if err := os.WriteFile(filepath.Join(dir, strconv.Itoa(tile.y)+".terrain"), data, 0644); err != nil {
+				return err
+			}
+		}
+		available[z] = tileRanges(tiles)
+	}
+	layerJson, err := json.MarshalIndent(layer{
+		TileJson:   "2.1.0",
+		Version:    "1.0.0",
+		Format:     "quantized-mesh-1.0",
+		Scheme:     "tms",
+		Tiles:      []string{"{z}/{x}/{y}.terrain"},
+		Projection: "EPSG:4326",
+		Bounds:     [4]float64{-180, -90, 180, 90},
+		MinZoom:    0,
+		MaxZoom:    levels,
+		Available:  available,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(folder, "layer.json"), layerJson, 0644)
+}
+
+// layer is the layer.json descriptor of a quantized-mesh tileset
+type layer struct {
+	TileJson   string        `json:"tilejson"`
+	Version    string        `json:"version"`
+	Format     string        `json:"format"`
+	Scheme     string        `json:"scheme"`
+	Tiles      []string      `json:"tiles"`
+	Projection string        `json:"projection"`
+	Bounds     [4]float64    `json:"bounds"`
+	MinZoom    int           `json:"minzoom"`
+	MaxZoom    int           `json:"maxzoom"`
+	Available  [][]tileRange `json:"available"`
+}
+
+// tileRange is a rectangle of available tiles, with inclusive bounds
+type tileRange struct {
+	StartX int `json:"startX"`
+	StartY int `json:"startY"`
+	EndX   int `json:"endX"`
+	EndY   int `json:"endY"`
+}
+
+// levelCount returns the deepest level whose cells are smaller than the average spacing of the points
+func levelCount(pts []Point) int {
+	minLon, maxLon, minLat, maxLat := math.MaxFloat64, -math.MaxFloat64, math.MaxFloat64, -math.MaxFloat64
+	for _, p := range pts {
+		minLon, maxLon = math.Min(minLon, p.Lon), math.Max(maxLon, p.Lon)
+		minLat, maxLat = math.Min(minLat, p.Lat), math.Max(maxLat, p.Lat)
+	}
+	spacing := math.Sqrt((maxLon - minLon) * (maxLat - minLat) / float64(len(pts)))
+	for z := 0; z < maxLevel; z++ {
+		if cellSize(z) <= spacing {
+			return z
+		}
+	}
+	return maxLevel
+}
+
+// cellSize returns the size in degrees of the cells at the given level. Level 0 has two tiles of 180 degrees.
+func cellSize(level int) float64 {
+	return 180 / math.Pow(2, float64(level)) / tileCells
+}
+
+type cellKey struct {
+	x, y int
+}
+
+// levelGrid holds, for each cell of a level, the ground point closest to the center of the cell
+type levelGrid struct {
+	level    int
+	cellSize float64
+	cells    map[cellKey]Point
+}
+
+func newLevelGrid(level int, pts []Point) *levelGrid {
+	g := &levelGrid{
+		level:    level,
+		cellSize: cellSize(level),
+		cells:    map[cellKey]Point{},
+	}
+	cols, rows := 2*tileCells<<level, tileCells<<level
+	for _, p := range pts {
+		k := cellKey{
+			x: clamp(int(math.Floor((p.Lon+180)/g.cellSize)), 0, cols-1),
+			y: clamp(int(math.Floor((p.Lat+90)/g.cellSize)), 0, rows-1),
+		}
+		if cur, ok := g.cells[k]; !ok || g.centerDistance(k, p) < g.centerDistance(k, cur) {
+			g.cells[k] = p
+		}
+	}
+	return g
+}
+
+func (g *levelGrid) centerDistance(k cellKey, p Point) float64 {
+	dx := p.Lon - (-180 + (float64(k.x)+0.5)*g.cellSize)
+	dy := p.Lat - (-90 + (float64(k.y)+0.5)*g.cellSize)
+	return dx*dx + dy*dy
+}
+
+// tiles returns the tiles containing at least a cell with a point, sorted by y and x. Both the tiles of level 0
+// are always returned, as clients need the whole globe to be covered at the root level.
+func (g *levelGrid) tiles() []cellKey {
+	set := map[cellKey]bool{}
+	if g.level == 0 {
+		set[cellKey{0, 0}] = true
+		set[cellKey{1, 0}] = true
+	}
+	for k := range g.cells {
+		set[cellKey{k.x / tileCells, k.y / tileCells}] = true
+	}
+	tiles := make([]cellKey, 0, len(set))
+	for k := range set {
+		tiles = append(tiles, k)
+	}
+	sort.Slice(tiles, func(i, j int) bool {
+		if tiles[i].y != tiles[j].y {
+			return tiles[i].y < tiles[j].y
+		}
+		return tiles[i].x < tiles[j].x
+	})
+	return tiles
+}
+
+// cornerHeight returns the height of the given cell corner as the average height of the points of the adjacent cells,
+// or zero if none of them has points
+func (g *levelGrid) cornerHeight(x, y int) float64 {
+	sum, count := 0.0, 0
+	for _, k := range []cellKey{{x - 1, y - 1}, {x, y - 1}, {x - 1, y}, {x, y}} {
+		if p, ok := g.cells[k]; ok {
+			sum += p.Height
+			count++
+		}
+	}
+	if count == 0 {
+		return 0
+	}
+	return sum / float64(count)
+}
+
+// mesh triangulates the given tile
+func (g *levelGrid) mesh(tile cellKey) *tileMesh {
+	tileSize := g.cellSize * tileCells
+	m := &tileMesh{
+		rect: rectangle{
+			west:  -180 + float64(tile.x)*tileSize,
+			south: -90 + float64(tile.y)*tileSize,
+			east:  -180 + float64(tile.x+1)*tileSize,
+			north: -90 + float64(tile.y+1)*tileSize,
+		},
+	}
+	seen := map[vertex]bool{}
+	add := func(v vertex, h float64) {
+		if !seen[v] {
+			seen[v] = true
+			m.vertices = append(m.vertices, v)
+			m.heights = append(m.heights, h)
+		}
+	}
+	x0, y0 := tile.x*tileCells, tile.y*tileCells
+	corner := func(i, j int) {
+		add(vertex{u: edgeCoordinate(i), v: edgeCoordinate(j)}, g.cornerHeight(x0+i, y0+j))
+	}
+	// the tile corners come first, in counter-clockwise order, as required by the triangulation
+	corner(0, 0)
+	corner(tileCells, 0)
+	corner(tileCells, tileCells)
+	corner(0, tileCells)
+	for k := 1; k < tileCells; k++ {
+		corner(k, 0)
+		corner(tileCells, k)
+		corner(k, tileCells)
+		corner(0, k)
+	}
+	for i := 0; i < tileCells; i++ {
+		for j := 0; j < tileCells; j++ {
+			p, ok := g.cells[cellKey{x0 + i, y0 + j}]
+			if !ok {
+				continue
+			}
+			// points on the tile edges are moved inside, the edges only hold the vertices shared with the adjacent tiles
+			u := clamp(int(math.Round((p.Lon-m.rect.west)/tileSize*quantizedMax)), 1, quantizedMax-1)
+			v := clamp(int(math.Round((p.Lat-m.rect.south)/tileSize*quantizedMax)), 1, quantizedMax-1)
+			add(vertex{u: int64(u), v: int64(v)}, p.Height)
+		}
+	}
+	m.triangles = triangulate(m.vertices)
+	return m
+}
+
+// edgeCoordinate returns the quantized coordinate of the k-th cell corner along a tile edge
+func edgeCoordinate(k int) int64 {
+	return int64(math.Round(float64(k) * quantizedMax / tileCells))
+}
+
+// tileRanges groups the tiles, sorted by y and x, in ranges of consecutive tiles of the same row
+func tileRanges(tiles []cellKey) []tileRange {
+	ranges := []tileRange{}
+	for _, t := range tiles {
+		if n := len(ranges); n > 0 && ranges[n-1].StartY == t.y && ranges[n-1].EndX == t.x-1 {
+			ranges[n-1].EndX = t.x
+			continue
+		}
+		ranges = append(ranges, tileRange{StartX: t.x, StartY: t.y, EndX: t.x, EndY: t.y})
+	}
+	return ranges
+}
+
+func clamp(v, min, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
@@ -0,0 +1,175 @@
+package terrain
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestMeshEncode(t *testing.T) {
+	m := &tileMesh{
+		rect:     rectangle{west: 10, south: 45, east: 11, north: 46},
+		vertices: []vertex{{0, 0}, {quantizedMax, 0}, {quantizedMax, quantizedMax}, {0, quantizedMax}, {16000, 16000}},
+		heights:  []float64{100, 100, 100, 100, 200},
+	}
+	m.triangles = triangulate(m.vertices)
+	if len(m.triangles) != 4 {
+		t.Fatalf("expected %d triangles got %d", 4, len(m.triangles))
+	}
+	data := m.encode()
+
+	if h := math.Float32frombits(binary.LittleEndian.Uint32(data[24:28])); h != 100 {
+		t.Errorf("expected min height %v got %v", 100, h)
+	}
+	if h := math.Float32frombits(binary.LittleEndian.Uint32(data[28:32])); h != 200 {
+		t.Errorf("expected max height %v got %v", 200, h)
+	}
+	center := toEcef(10.5, 45.5, 150)
+	if x := math.Float64frombits(binary.LittleEndian.Uint64(data[0:8])); math.Abs(x-center.x) > 1e-6 {
+		t.Errorf("expected center x %v got %v", center.x, x)
+	}
+	n := int(binary.LittleEndian.Uint32(data[88:92]))
+	if n != 5 {
+		t.Fatalf("expected %d vertices got %d", 5, n)
+	}
+	decode := func(offset int) []int {
+		values := make([]int, n)
+		prev := 0
+		for i := 0; i < n; i++ {
+			z := int(binary.LittleEndian.Uint16(data[offset+2*i:]))
+			prev += (z >> 1) ^ -(z & 1)
+			values[i] = prev
+		}
+		return values
+	}
+	us, vs, hs := decode(92), decode(92+2*n), decode(92+4*n)
+	offset := 92 + 6*n
+	triangleCount := int(binary.LittleEndian.Uint32(data[offset:]))
+	if triangleCount != 4 {
+		t.Fatalf("expected %d triangles got %d", 4, triangleCount)
+	}
+	highest := 0
+	for i := 0; i < triangleCount*3; i++ {
+		code := int(binary.LittleEndian.Uint16(data[offset+4+2*i:]))
+		idx := highest - code
+		if code == 0 {
+			highest++
+		}
+		if idx < 0 || idx >= n {
+			t.Fatalf("invalid index %d", idx)
+		}
+		if us[idx] == 16000 && (vs[idx] != 16000 || hs[idx] != quantizedMax) {
+			t.Errorf("expected the center vertex at the maximum height, got %v %v", vs[idx], hs[idx])
+		}
+	}
+	offset += 4 + 2*triangleCount*3
+	for e := 0; e < 4; e++ {
+		count := int(binary.LittleEndian.Uint32(data[offset:]))
+		if count != 2 {
+			t.Errorf("expected %d vertices on edge %d got %d", 2, e, count)
+		}
+		offset += 4 + 2*count
+	}
+	if offset != len(data) {
+		t.Errorf("expected %d bytes got %d", offset, len(data))
+	}
+}
+
+func TestHorizonOcclusionPoint(t *testing.T) {
+	center := toEcef(10, 45, 0)
+	positions := []vec3{toEcef(9.9, 44.9, 100), toEcef(10.1, 45.1, 100)}
+	p := horizonOcclusionPoint(center, positions)
+	// the point lies above the surface in the direction of the center
+	if m := p.norm(); m <= 1 || m > 1.01 {
+		t.Errorf("expected magnitude slightly above 1 got %v", m)
+	}
+	dir := center.toScaledSpace()
+	if cos := p.dot(dir) / p.norm() / dir.norm(); math.Abs(cos-1) > 1e-9 {
+		t.Errorf("expected point in the direction of the center, got cosine %v", cos)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	pts := []Point{}
+	for i := 0; i < 50; i++ {
+		for j := 0; j < 50; j++ {
+			pts = append(pts, Point{Lon: 11 + float64(i)*0.0001, Lat: 46 + float64(j)*0.0001, Height: 200 + float64(i)})
+		}
+	}
+	folder := t.TempDir()
+	if err := Write(folder, pts); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(folder, "layer.json"))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	l := layer{}
+	if err := json.Unmarshal(data, &l); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if l.Format != "quantized-mesh-1.0" || l.Scheme != "tms" {
+		t.Errorf("unexpected layer %v", l)
+	}
+	// the cells of level 15 are about 0.00009 degrees wide, smaller than the 0.0001 spacing
+	if l.MaxZoom != 15 {
+		t.Errorf("expected max zoom %d got %d", 15, l.MaxZoom)
+	}
+	if len(l.Available) != l.MaxZoom+1 {
+		t.Fatalf("expected %d levels got %d", l.MaxZoom+1, len(l.Available))
+	}
+	if len(l.Available[0]) != 1 || l.Available[0][0] != (tileRange{StartX: 0, StartY: 0, EndX: 1, EndY: 0}) {
+		t.Errorf("expected both root tiles to be available, got %v", l.Available[0])
+	}
+	for z, ranges := range l.Available {
+		for _, r := range ranges {
+			for x := r.StartX; x <= r.EndX; x++ {
+				for y := r.StartY; y <= r.EndY; y++ {
+					f := filepath.Join(folder, strconv.Itoa(z), strconv.Itoa(x), strconv.Itoa(y)+".terrain")
+					if _, err := os.Stat(f); err != nil {
+						t.Errorf("expected tile %s to exist: %v", f, err)
+					}
+				}
+			}
+		}
+	}
+
+	if err := Write(folder, nil); err == nil {
+		t.Errorf("expected error for missing points, got none")
+	}
+}
+
+func TestLevelGridSharedEdges(t *testing.T) {
+	// points straddling the boundary between two tiles of level 10
+	pts := []Point{}
+	boundary := -180 + 1024*cellSize(10)*tileCells
+	for i := -5; i < 5; i++ {
+		for j := 0; j < 5; j++ {
+			pts = append(pts, Point{Lon: boundary + float64(i)*0.001, Lat: 0.002 + float64(j)*0.001, Height: float64(i * j)})
+		}
+	}
+	g := newLevelGrid(10, pts)
+	west, east := g.mesh(cellKey{1023, 512}), g.mesh(cellKey{1024, 512})
+	edge := func(m *tileMesh, u int64) map[int64]float64 {
+		heights := map[int64]float64{}
+		for i, v := range m.vertices {
+			if v.u == u {
+				heights[v.v] = m.heights[i]
+			}
+		}
+		return heights
+	}
+	a, b := edge(west, quantizedMax), edge(east, 0)
+	if len(a) != tileCells+1 || len(a) != len(b) {
+		t.Fatalf("expected %d edge vertices got %d and %d", tileCells+1, len(a), len(b))
+	}
+	for v, h := range a {
+		if b[v] != h {
+			t.Errorf("expected the same height at %d, got %v and %v", v, h, b[v])
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package tree
+
+import (
+	"math"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+)
+
+// featureVariationThreshold is the surface variation above which the points of a grid cell are considered
+// to describe a feature (an edge, a corner or a rough surface) rather than a plane. The surface variation
+// ranges from 0, for points lying on a plane, to 1/3, for points isotropically scattered in space.
+const featureVariationThreshold = 0.05
+
+// minFeaturePoints is the minimum number of points a cell must contain for its surface variation to be evaluated
+const minFeaturePoints = 4
+
+// thinPreservingFeatures works as thinByGrid but for the cells whose points have a surface variation
+// above featureVariationThreshold it retains the point closest to the center of each of the 8 sub-cells
+// the cell can be divided into, instead of the single point closest to the cell center.
+// Returns the number of points of each octant.
+func (t *GridTreeNode) thinPreservingFeatures(nX, nY, nZ, gridSizeX, gridSizeY, gridSizeZ float64) [8]int {
+	type cell struct {
+		head       *geom.LinkedPoint
+		count      int
+		cX, cY, cZ float64
+	}
+
+	// first group the points by the grid cell they fall into
+	grid := map[[3]int32]*cell{}
+	cur := t.pts
+	for cur != nil {
+		t.totalNumPoints++
+		next := cur.Next
+
+		iX := int32(math.Min(math.Max(1, math.Ceil((float64(cur.Pt.X)-t.bounds.Xmin)/gridSizeX)), float64(nX)))
+		iY := int32(math.Min(math.Max(1, math.Ceil((float64(cur.Pt.Y)-t.bounds.Ymin)/gridSizeY)), float64(nY)))
+		iZ := int32(math.Min(math.Max(1, math.Ceil((float64(cur.Pt.Z)-t.bounds.Zmin)/gridSizeZ)), float64(nZ)))
+		cellIndex := [3]int32{iX, iY, iZ}
+
+		c, ok := grid[cellIndex]
+		if !ok {
+			c = &cell{
+				cX: t.bounds.Xmin + float64(iX-1)*gridSizeX + gridSizeX/2,
+				cY: t.bounds.Ymin + float64(iY-1)*gridSizeY + gridSizeY/2,
+				cZ: t.bounds.Zmin + float64(iZ-1)*gridSizeZ + gridSizeZ/2,
+			}
+			grid[cellIndex] = c
+		}
+		cur.Next = c.head
+		c.head = cur
+		c.count++
+		cur = next
+	}
+
+	childrenCount := [8]int{}
+	t.pts = nil
+	for _, c := range grid {
+		// the winners are the points closest to the cell center or, for feature cells, to the sub-cell centers
+		var winners [8]*geom.LinkedPoint
+		var dists [8]float64
+		feature := c.count >= minFeaturePoints && surfaceVariation(c.head) > featureVariationThreshold
+		for cur := c.head; cur != nil; cur = cur.Next {
+			tX, tY, tZ := c.cX, c.cY, c.cZ
+			sub := 0
+			if feature {
+				sub, tX, tY, tZ = subCellCenter(cur.Pt, c.cX, c.cY, c.cZ, gridSizeX, gridSizeY, gridSizeZ)
+			}
+			dist := (tX-float64(cur.Pt.X))*(tX-float64(cur.Pt.X)) + (tY-float64(cur.Pt.Y))*(tY-float64(cur.Pt.Y)) + (tZ-float64(cur.Pt.Z))*(tZ-float64(cur.Pt.Z))
+			if winners[sub] == nil || dist < dists[sub] {
+				winners[sub] = cur
+				dists[sub] = dist
+			}
+		}
+
+		// then move the winners to the current node and all other points to the children octants
+		cur := c.head
+		for cur != nil {
+			next := cur.Next
+			if isWinner(cur, winners) {
+				cur.Next = t.pts
+				t.pts = cur
+				t.numPoints++
+			} else {
+				idx := t.getChildrenIndex(cur.Pt)
+				childrenCount[idx]++
+				cur.Next = t.childrenPts[idx]
+				t.childrenPts[idx] = cur
+			}
+			cur = next
+		}
+	}
+	return childrenCount
+}
+
+// subCellCenter returns the index and the center of the sub-cell of the cell with the given center and size
+// the point falls into
+func subCellCenter(pt geom.Point32, cX, cY, cZ, sizeX, sizeY, sizeZ float64) (int, float64, float64, float64) {
+	idx := 0
+	x, y, z := cX-sizeX/4, cY-sizeY/4, cZ-sizeZ/4
+	if float64(pt.X) >= cX {
+		idx |= 1
+		x = cX + sizeX/4
+	}
+	if float64(pt.Y) >= cY {
+		idx |= 2
+		y = cY + sizeY/4
+	}
+	if float64(pt.Z) >= cZ {
+		idx |= 4
+		z = cZ + sizeZ/4
+	}
+	return idx, x, y, z
+}
+
+func isWinner(pt *geom.LinkedPoint, winners [8]*geom.LinkedPoint) bool {
+	for _, w := range winners {
+		if w == pt {
+			return true
+		}
+	}
+	return false
+}
+
+// surfaceVariation computes the ratio between the smallest eigenvalue of the covariance matrix of the
+// given points and the sum of all eigenvalues. The ratio is 0 for points lying on a plane.
+func surfaceVariation(pts *geom.LinkedPoint) float64 {
+	var mX, mY, mZ float64
+	n := 0
+	for cur := pts; cur != nil; cur = cur.Next {
+		mX += float64(cur.Pt.X)
+		mY += float64(cur.Pt.Y)
+		mZ += float64(cur.Pt.Z)
+		n++
+	}
+	if n == 0 {
+		return 0
+	}
+	mX, mY, mZ = mX/float64(n), mY/float64(n), mZ/float64(n)
+
+	var cov [3][3]float64
+	for cur := pts; cur != nil; cur = cur.Next {
+		d := [3]float64{float64(cur.Pt.X) - mX, float64(cur.Pt.Y) - mY, float64(cur.Pt.Z) - mZ}
+		for i := 0; i < 3; i++ {
+			for j := i; j < 3; j++ {
+				cov[i][j] += d[i] * d[j]
+			}
+		}
+	}
+	cov[1][0], cov[2][0], cov[2][1] = cov[0][1], cov[0][2], cov[1][2]
+
+	e1, e2, e3 := symmetricEigenvalues(cov)
+	sum := e1 + e2 + e3
+	if sum <= 0 {
+		return 0
+	}
+	return math.Max(0, math.Min(e1, math.Min(e2, e3))) / sum
+}
+
+// symmetricEigenvalues returns the eigenvalues of a 3x3 symmetric matrix using the closed form
+// trigonometric solution of its characteristic equation
+func symmetricEigenvalues(a [3][3]float64) (float64, float64, float64) {
+	p1 := a[0][1]*a[0][1] + a[0][2]*a[0][2] + a[1][2]*a[1][2]
+	if p1 == 0 {
+		// diagonal matrix
+		return a[0][0], a[1][1], a[2][2]
+	}
+	q := (a[0][0] + a[1][1] + a[2][2]) / 3
+	p2 := (a[0][0]-q)*(a[0][0]-q) + (a[1][1]-q)*(a[1][1]-q) + (a[2][2]-q)*(a[2][2]-q) + 2*p1
+	p := math.Sqrt(p2 / 6)
+
+	var b [3][3]float64
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			b[i][j] = a[i][j] / p
+			if i == j {
+				b[i][j] -= q / p
+			}
+		}
+	}
+	det := b[0][0]*(b[1][1]*b[2][2]-b[1][2]*b[2][1]) -
+		b[0][1]*(b[1][0]*b[2][2]-b[1][2]*b[2][0]) +
+		b[0][2]*(b[1][0]*b[2][1]-b[1][1]*b[2][0])
+	r := math.Max(-1, math.Min(1, det/2))
+	phi := math.Acos(r) / 3
+
+	e1 := q + 2*p*math.Cos(phi)
+	e3 := q + 2*p*math.Cos(phi+2*math.Pi/3)
+	e2 := 3*q - e1 - e3
+	return e1, e2, e3
+}
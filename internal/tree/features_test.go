@@ -0,0 +1,106 @@
+package tree
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+)
+
+func TestSymmetricEigenvalues(t *testing.T) {
+	// eigenvalues of [[2,1,0],[1,2,0],[0,0,5]] are 1, 3 and 5
+	e1, e2, e3 := symmetricEigenvalues([3][3]float64{{2, 1, 0}, {1, 2, 0}, {0, 0, 5}})
+	actual := []float64{e1, e2, e3}
+	for _, expected := range []float64{1, 3, 5} {
+		found := false
+		for _, e := range actual {
+			if math.Abs(e-expected) < 1e-9 {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected eigenvalue %v in %v", expected, actual)
+		}
+	}
+}
+
+func TestSurfaceVariation(t *testing.T) {
+	if v := surfaceVariation(planePoints(0)); v > 1e-6 {
+		t.Errorf("expected zero variation for a plane got %v", v)
+	}
+	if v := surfaceVariation(cornerPoints()); v <= featureVariationThreshold {
+		t.Errorf("expected variation above %v for a corner got %v", featureVariationThreshold, v)
+	}
+}
+
+func TestFeaturePreservingThinning(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		pts      *geom.LinkedPoint
+		expected int
+	}{
+		{name: "plane", pts: planePoints(0), expected: 1},
+		// the sub-cell opposite to the corner is empty
+		{name: "corner", pts: cornerPoints(), expected: 7},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			count := 0
+			for cur := tc.pts; cur != nil; cur = cur.Next {
+				count++
+			}
+			node := &GridTreeNode{
+				pts:                  tc.pts,
+				bounds:               geom.NewBoundingBox(-1, 11, -1, 11, -1, 11),
+				gridSize:             [3]float64{1000, 1000, 1000},
+				maxDepth:             5,
+				minPointsPerChildren: 1,
+				featurePreserving:    true,
+			}
+			if err := node.Build(); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if node.numPoints != tc.expected {
+				t.Errorf("expected %d points retained got %d", tc.expected, node.numPoints)
+			}
+			if node.totalNumPoints != count {
+				t.Errorf("expected %d total points got %d", count, node.totalNumPoints)
+			}
+			retained := 0
+			for cur := node.pts; cur != nil; cur = cur.Next {
+				retained++
+			}
+			for _, children := range node.childrenPts {
+				for cur := children; cur != nil; cur = cur.Next {
+					retained++
+				}
+			}
+			if retained != count {
+				t.Errorf("expected %d points in the node and children got %d", count, retained)
+			}
+		})
+	}
+}
+
+// planePoints returns a 10x10 grid of points on the horizontal plane at the given elevation
+func planePoints(z float32) *geom.LinkedPoint {
+	var pts *geom.LinkedPoint
+	for x := 0; x < 10; x++ {
+		for y := 0; y < 10; y++ {
+			pts = &geom.LinkedPoint{Pt: geom.Point32{X: float32(x), Y: float32(y), Z: z}, Next: pts}
+		}
+	}
+	return pts
+}
+
+// cornerPoints returns the points of three mutually orthogonal faces meeting in a corner
+func cornerPoints() *geom.LinkedPoint {
+	var pts *geom.LinkedPoint
+	for a := 0; a < 10; a++ {
+		for b := 0; b < 10; b++ {
+			pts = &geom.LinkedPoint{Pt: geom.Point32{X: float32(a), Y: float32(b), Z: 0}, Next: pts}
+			pts = &geom.LinkedPoint{Pt: geom.Point32{X: float32(a), Y: 0, Z: float32(b)}, Next: pts}
+			pts = &geom.LinkedPoint{Pt: geom.Point32{X: 0, Y: float32(a), Z: float32(b)}, Next: pts}
+		}
+	}
+	return pts
+}
@@ -2,6 +2,8 @@ package tree
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"math"
 	"sync"
 
@@ -9,6 +11,61 @@ import (
 	"github.com/mfbonfigli/gocesiumtiler/v2/internal/conv/elev"
 	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
 	"github.com/mfbonfigli/gocesiumtiler/v2/internal/las"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/mutator"
+)
+
+// ErrAllPointsDiscarded is returned when loading a point cloud whose points are all discarded by the mutators
+var ErrAllPointsDiscarded = errors.New("all points were discarded by the filters")
+
+// PointError wraps an error that occurred while transforming a specific point during the load
+type PointError struct {
+	Point geom.Point64
+	Err   error
+}
+
+func (e *PointError) Error() string {
+	return fmt.Sprintf("error transforming point (%v, %v, %v): %v", e.Point.X, e.Point.Y, e.Point.Z, e.Err)
+}
+
+func (e *PointError) Unwrap() error {
+	return e.Err
+}
+
+// SparseNodePolicy defines what happens to the children nodes with less points than the minimum number of
+// points per children
+type SparseNodePolicy int
+
+const (
+	// SparseConsolidate merges the points of the sparse children into their parent
+	SparseConsolidate SparseNodePolicy = iota
+	// SparseKeep keeps the sparse children as they are
+	SparseKeep
+	// SparseOmit discards the sparse children together with their points
+	SparseOmit
+)
+
+// Subdivision defines how the space of a node is partitioned among its children
+type Subdivision int
+
+const (
+	// SubdivisionOctree splits the nodes in the three directions, in up to eight children
+	SubdivisionOctree Subdivision = iota
+	// SubdivisionQuadtree splits the nodes only horizontally, in up to four children spanning the full
+	// vertical extent of their parent
+	SubdivisionQuadtree
+)
+
+// GeometricErrorMode defines how the geometric error of the nodes below the root is derived
+type GeometricErrorMode int
+
+const (
+	// GeometricErrorSpacing measures the error of each node as the mean spacing between its points
+	GeometricErrorSpacing GeometricErrorMode = iota
+	// GeometricErrorGeometric halves the error of the parent at each level
+	GeometricErrorGeometric
+	// GeometricErrorLinear decreases the error of the root linearly with the depth, reaching its fraction
+	// 1/(maxDepth+1) at the maximum depth
+	GeometricErrorLinear
 )
 
 // GridTreeNode implements both the Tree and Node interfaces. The points of the point cloud
@@ -30,26 +87,56 @@ type GridTreeNode struct {
 	children             [8]Node
 	childrenBuilt        bool
 	bounds               geom.BoundingBox
-	gridSize             float64
+	gridSize             [3]float64
 	built                bool
 	maxDepth             int
 	depth                int
 	numPoints            int
 	totalNumPoints       int
 	loadWorkersNumber    int
+	reprojectionWorkers  int
 	minPointsPerChildren int
+	mutator              mutator.Mutator
+	outlierNeighbors     int
+	outlierStdDevMul     float64
+	smoothingRadius      float64
+	smoothingIterations  int
+	featurePreserving    bool
+	sparsePolicy         SparseNodePolicy
+	subdivision          Subdivision
+	verticalAxis         int
+	roundingStep         float64
+	maxGeometricError    float64
+	rootGeometricError   float64
+	geometricErrorMode   GeometricErrorMode
+	geometricError       float64
+	geometricErrorOnce   sync.Once
+	indexCache           bool
+	equalArea            bool
+	rootPointTarget      int
+	cancelCheckInterval  int
+	index                *neighborIndex
+	indexBuilds          int
+	indexLock            sync.Mutex
 	sync.Mutex
 }
 
+// DefaultCancellationCheckInterval is the default number of points loaded between two checks of the context
+const DefaultCancellationCheckInterval = 1024
+
+// loadBatchSize is the number of points read from the reader and sent together to a load worker
+const loadBatchSize = 512
+
 func NewGridTree(opts ...func(*GridTreeNode)) *GridTreeNode {
 	t := &GridTreeNode{
 		built:                false,
 		maxDepth:             10,
 		depth:                0,
 		childrenBuilt:        false,
-		gridSize:             1,
+		gridSize:             [3]float64{1, 1, 1},
 		loadWorkersNumber:    1,
 		minPointsPerChildren: 10000,
+		cancelCheckInterval:  DefaultCancellationCheckInterval,
 	}
 	for _, optFn := range opts {
 		optFn(t)
@@ -59,7 +146,16 @@ func NewGridTree(opts ...func(*GridTreeNode)) *GridTreeNode {
 
 func WithGridSize(size float64) func(t *GridTreeNode) {
 	return func(t *GridTreeNode) {
-		t.gridSize = size
+		t.gridSize = [3]float64{size, size, size}
+	}
+}
+
+// WithGridSizeXYZ sets a different size of the grid cells along each axis, to thin the points anisotropically.
+// The cells are aligned to the EPSG:4978 axes: z applies to the axis closest to the local vertical, x and y to the
+// other two axes in order.
+func WithGridSizeXYZ(x, y, z float64) func(t *GridTreeNode) {
+	return func(t *GridTreeNode) {
+		t.gridSize = [3]float64{x, y, z}
 	}
 }
 
@@ -75,12 +171,123 @@ func WithLoadWorkersNumber(num int) func(t *GridTreeNode) {
 	}
 }
 
+// WithReprojectionWorkers sets the number of workers transforming the coordinates of the points while loading them,
+// overriding WithLoadWorkersNumber for the loading only. Each worker forks the coordinate converter, so that the
+// workers do not share projection objects not safe for concurrent use. A non positive value uses the load workers.
+func WithReprojectionWorkers(num int) func(t *GridTreeNode) {
+	return func(t *GridTreeNode) {
+		t.reprojectionWorkers = num
+	}
+}
+
 func WithMinPointsPerChildren(num int) func(t *GridTreeNode) {
 	return func(t *GridTreeNode) {
 		t.minPointsPerChildren = num
 	}
 }
 
+// WithMutator sets a mutator to apply to the points as they are loaded, before
+// converting them to the internal coordinate system
+func WithMutator(m mutator.Mutator) func(t *GridTreeNode) {
+	return func(t *GridTreeNode) {
+		t.mutator = m
+	}
+}
+
+// WithOutlierRemoval enables the statistical outlier removal of the loaded points, discarding the points
+// whose mean distance from their k nearest neighbors exceeds the global mean by more than stdDevMul
+// standard deviations. The filter runs after all points have been loaded, using the load workers.
+func WithOutlierRemoval(k int, stdDevMul float64) func(t *GridTreeNode) {
+	return func(t *GridTreeNode) {
+		t.outlierNeighbors = k
+		t.outlierStdDevMul = stdDevMul
+	}
+}
+
+// WithElevationSmoothing enables the smoothing of the elevation of the loaded points, replacing it, in each of the
+// given iterations, with the average of the elevations of the points within the given radius weighted by their
+// distance. The points are moved along the local vertical, the filter runs after the outlier removal.
+func WithElevationSmoothing(radius float64, iterations int) func(t *GridTreeNode) {
+	return func(t *GridTreeNode) {
+		t.smoothingRadius = radius
+		t.smoothingIterations = iterations
+	}
+}
+
+// WithFeaturePreservingThinning enables a thinning that retains more points in the grid cells whose points
+// do not lie on a plane, such as the cells containing edges and corners, so that sharp features are preserved
+// in the coarse levels of detail
+func WithFeaturePreservingThinning(enabled bool) func(t *GridTreeNode) {
+	return func(t *GridTreeNode) {
+		t.featurePreserving = enabled
+	}
+}
+
+// WithSpatialIndexCache enables the caching of the nearest neighbor index of the points of each node, so that the
+// index is built once and shared by all the neighborhood based operations, such as the outlier removal, the elevation
+// smoothing and the geometric error estimation, at the cost of keeping the index in memory until the last of them runs
+func WithSpatialIndexCache(enabled bool) func(t *GridTreeNode) {
+	return func(t *GridTreeNode) {
+		t.indexCache = enabled
+	}
+}
+
+// WithEqualAreaThinning makes the grid cells span the whole node along the axis closest to the vertical, so that
+// each node retains a uniform number of points per unit of horizontal area regardless of the slope of the terrain,
+// producing a uniform density when the tileset is viewed from above
+func WithEqualAreaThinning(enabled bool) func(t *GridTreeNode) {
+	return func(t *GridTreeNode) {
+		t.equalArea = enabled
+	}
+}
+
+// WithRootPointTarget scales the grid cells of the root node so that it retains about the given number of points,
+// regardless of the grid size, which still applies to the children. A non positive value disables the target.
+func WithRootPointTarget(n int) func(t *GridTreeNode) {
+	return func(t *GridTreeNode) {
+		t.rootPointTarget = n
+	}
+}
+
+// WithCancellationCheckInterval sets every how many points the loaders check whether the context has been cancelled.
+// The context is always checked before the first point. Values below 1 check it at every point.
+func WithCancellationCheckInterval(n int) func(t *GridTreeNode) {
+	return func(t *GridTreeNode) {
+		t.cancelCheckInterval = max(n, 1)
+	}
+}
+
+// WithSparseNodePolicy sets what happens to the children with less points than the minimum number of points per children
+func WithSparseNodePolicy(policy SparseNodePolicy) func(t *GridTreeNode) {
+	return func(t *GridTreeNode) {
+		t.sparsePolicy = policy
+	}
+}
+
+// WithSubdivision sets how the nodes are partitioned among their children. As the points are stored in
+// EPSG 4978, the quadtree subdivision does not split the axis closest to the vertical at the center of the cloud.
+func WithSubdivision(subdivision Subdivision) func(t *GridTreeNode) {
+	return func(t *GridTreeNode) {
+		t.subdivision = subdivision
+	}
+}
+
+// WithCoordinateRounding rounds the EPSG 4978 coordinates of the points to multiples of the given step,
+// in meters, after the reprojection. A non positive step disables the rounding.
+func WithCoordinateRounding(step float64) func(t *GridTreeNode) {
+	return func(t *GridTreeNode) {
+		t.roundingStep = step
+	}
+}
+
+// WithGeometricErrorMode sets how the geometric error of the nodes below the root is derived. The error of the root
+// is always the mean spacing between its points.
+func WithGeometricErrorMode(mode GeometricErrorMode) func(t *GridTreeNode) {
+	return func(t *GridTreeNode) {
+		t.geometricErrorMode = mode
+	}
+}
+
 func (t *GridTreeNode) Load(reader las.LasReader, coorConv coor.CoordinateConverter, elevConv elev.ElevationConverter, ctx context.Context) error {
 	return t.loadPoints(reader, coorConv, elevConv, ctx)
 }
@@ -112,15 +319,125 @@ func (t *GridTreeNode) Build() error {
 	}
 
 	// nX, nY, nZ represent the number of grid cells in each direction, should always be >= 1
-	nX := math.Ceil((t.bounds.Xmax - t.bounds.Xmin) / t.gridSize)
-	nY := math.Ceil((t.bounds.Ymax - t.bounds.Ymin) / t.gridSize)
-	nZ := math.Ceil((t.bounds.Zmax - t.bounds.Zmin) / t.gridSize)
+	cell := t.cellSize()
+	if t.IsRoot() && t.rootPointTarget > 0 {
+		scale := t.rootCellScale(cell)
+		cell = [3]float64{cell[0] * scale, cell[1] * scale, cell[2] * scale}
+	}
+	if t.equalArea {
+		// a single cell along the vertical axis
+		extent := [3]float64{t.bounds.Xmax - t.bounds.Xmin, t.bounds.Ymax - t.bounds.Ymin, t.bounds.Zmax - t.bounds.Zmin}
+		cell[t.verticalAxis] = math.Max(cell[t.verticalAxis], extent[t.verticalAxis])
+	}
+	nX := math.Ceil((t.bounds.Xmax - t.bounds.Xmin) / cell[0])
+	nY := math.Ceil((t.bounds.Ymax - t.bounds.Ymin) / cell[1])
+	nZ := math.Ceil((t.bounds.Zmax - t.bounds.Zmin) / cell[2])
 
 	// these are the actual gridSizes after the rounding
 	gridSizeX := (t.bounds.Xmax - t.bounds.Xmin) / nX
 	gridSizeY := (t.bounds.Ymax - t.bounds.Ymin) / nY
 	gridSizeZ := (t.bounds.Zmax - t.bounds.Zmin) / nZ
 
+	var childrenCount [8]int
+	if t.featurePreserving {
+		childrenCount = t.thinPreservingFeatures(nX, nY, nZ, gridSizeX, gridSizeY, gridSizeZ)
+	} else {
+		childrenCount = t.thinByGrid(nX, nY, nZ, gridSizeX, gridSizeY, gridSizeZ)
+	}
+
+	// are we done? Not really. If there are children with a number of points < minPointsPerChildren
+	// then handle them according to the sparse node policy, by default merging them with the current node
+	for i, count := range childrenCount {
+		if count >= t.minPointsPerChildren || t.childrenPts[i] == nil {
+			continue
+		}
+		switch t.sparsePolicy {
+		case SparseKeep:
+			// leave the sparse child as is
+		case SparseOmit:
+			t.childrenPts[i] = nil
+			t.totalNumPoints -= count
+		default:
+			current := t.childrenPts[i]
+			for current != nil {
+				next := current.Next
+				current.Next = t.pts
+				t.pts = current
+				current = next
+				t.numPoints++
+			}
+			t.childrenPts[i] = nil
+		}
+	}
+	// the points of the node are replaced by the sampled ones
+	t.restrictIndex()
+	t.built = true
+	return nil
+}
+
+// rootCellScale returns the factor scaling the given grid cells so that the points of the node occupy as many
+// cells as the root point target, or the closest lower number of cells, found with a bisection of the scale
+func (t *GridTreeNode) rootCellScale(cell [3]float64) float64 {
+	target := t.rootPointTarget
+	count := func(scale float64) int {
+		return t.countCells([3]float64{cell[0] * scale, cell[1] * scale, cell[2] * scale})
+	}
+	// lo is a scale occupying at least target cells, hi one occupying at most target cells
+	lo, hi := 1.0, 1.0
+	if c := count(1); c == target {
+		return 1
+	} else if c > target {
+		for hi < maxRootCellScale && count(hi) > target {
+			lo, hi = hi, hi*2
+		}
+	} else {
+		for lo > minRootCellScale && count(lo) < target {
+			lo, hi = lo/2, lo
+		}
+	}
+	for i := 0; i < rootCellScaleIterations; i++ {
+		mid := math.Sqrt(lo * hi)
+		c := count(mid)
+		if c == target {
+			return mid
+		}
+		if c > target {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return hi
+}
+
+// minRootCellScale, maxRootCellScale and rootCellScaleIterations bound the search of the scale of the root grid cells
+const (
+	minRootCellScale        = 1.0 / 1024
+	maxRootCellScale        = 1 << 20
+	rootCellScaleIterations = 20
+)
+
+// countCells returns the number of grid cells of the given size containing points of the node
+func (t *GridTreeNode) countCells(cell [3]float64) int {
+	nX := math.Ceil((t.bounds.Xmax - t.bounds.Xmin) / cell[0])
+	nY := math.Ceil((t.bounds.Ymax - t.bounds.Ymin) / cell[1])
+	nZ := math.Ceil((t.bounds.Zmax - t.bounds.Zmin) / cell[2])
+	gridSizeX := (t.bounds.Xmax - t.bounds.Xmin) / nX
+	gridSizeY := (t.bounds.Ymax - t.bounds.Ymin) / nY
+	gridSizeZ := (t.bounds.Zmax - t.bounds.Zmin) / nZ
+	cells := map[[3]int32]struct{}{}
+	for cur := t.pts; cur != nil; cur = cur.Next {
+		iX := int32(math.Min(math.Max(1, math.Ceil((float64(cur.Pt.X)-t.bounds.Xmin)/gridSizeX)), nX))
+		iY := int32(math.Min(math.Max(1, math.Ceil((float64(cur.Pt.Y)-t.bounds.Ymin)/gridSizeY)), nY))
+		iZ := int32(math.Min(math.Max(1, math.Ceil((float64(cur.Pt.Z)-t.bounds.Zmin)/gridSizeZ)), nZ))
+		cells[[3]int32{iX, iY, iZ}] = struct{}{}
+	}
+	return len(cells)
+}
+
+// thinByGrid retains in the node the point closest to the center of each grid cell, moving all
+// other points to the lists of the children octants. Returns the number of points of each octant.
+func (t *GridTreeNode) thinByGrid(nX, nY, nZ, gridSizeX, gridSizeY, gridSizeZ float64) [8]int {
 	// we need to keep track of the closest point to each grid cell center
 	// define an inner type so that it's not leaked outside the scope of the build method
 	type cell struct {
@@ -206,28 +523,11 @@ func (t *GridTreeNode) Build() error {
 		t.pts = point
 		t.numPoints++
 	}
-
-	// are we done? Not really. If there are children with a number of points < minPointsPerChildren
-	// then merge them with the current node
-	for i, count := range childrenCount {
-		if count < t.minPointsPerChildren {
-			current := t.childrenPts[i]
-			for current != nil {
-				next := current.Next
-				current.Next = t.pts
-				t.pts = current
-				current = next
-				t.numPoints++
-			}
-			t.childrenPts[i] = nil
-		}
-	}
-	t.built = true
-	return nil
+	return childrenCount
 }
 
 func (t *GridTreeNode) GetInternalSrid() int {
-	return 4978
+	return coor.EcefSrid
 }
 
 func (t *GridTreeNode) IsRoot() bool {
@@ -279,35 +579,35 @@ func (t *GridTreeNode) GetBoundingBoxRegion(converter coor.CoordinateConverter)
 		Y: bbox.Ymax + t.cY,
 		Z: bbox.Zmax + t.cZ,
 	}
-	p1c, err := converter.ToSrid(t.GetInternalSrid(), 4979, p1)
+	p1c, err := converter.ToSrid(t.GetInternalSrid(), coor.GeographicSrid, p1)
 	if err != nil {
 		return geom.BoundingBox{}, err
 	}
-	p2c, err := converter.ToSrid(t.GetInternalSrid(), 4979, p2)
+	p2c, err := converter.ToSrid(t.GetInternalSrid(), coor.GeographicSrid, p2)
 	if err != nil {
 		return geom.BoundingBox{}, err
 	}
-	p3c, err := converter.ToSrid(t.GetInternalSrid(), 4979, p3)
+	p3c, err := converter.ToSrid(t.GetInternalSrid(), coor.GeographicSrid, p3)
 	if err != nil {
 		return geom.BoundingBox{}, err
 	}
-	p4c, err := converter.ToSrid(t.GetInternalSrid(), 4979, p4)
+	p4c, err := converter.ToSrid(t.GetInternalSrid(), coor.GeographicSrid, p4)
 	if err != nil {
 		return geom.BoundingBox{}, err
 	}
-	p5c, err := converter.ToSrid(t.GetInternalSrid(), 4979, p5)
+	p5c, err := converter.ToSrid(t.GetInternalSrid(), coor.GeographicSrid, p5)
 	if err != nil {
 		return geom.BoundingBox{}, err
 	}
-	p6c, err := converter.ToSrid(t.GetInternalSrid(), 4979, p6)
+	p6c, err := converter.ToSrid(t.GetInternalSrid(), coor.GeographicSrid, p6)
 	if err != nil {
 		return geom.BoundingBox{}, err
 	}
-	p7c, err := converter.ToSrid(t.GetInternalSrid(), 4979, p7)
+	p7c, err := converter.ToSrid(t.GetInternalSrid(), coor.GeographicSrid, p7)
 	if err != nil {
 		return geom.BoundingBox{}, err
 	}
-	p8c, err := converter.ToSrid(t.GetInternalSrid(), 4979, p8)
+	p8c, err := converter.ToSrid(t.GetInternalSrid(), coor.GeographicSrid, p8)
 	if err != nil {
 		return geom.BoundingBox{}, err
 	}
@@ -351,6 +651,7 @@ func (t *GridTreeNode) GetChildren() [8]Node {
 		// not built? return nothing
 		return t.children
 	}
+	geometricError := t.ComputeGeometricError()
 	for i, c := range t.childrenPts {
 		if c == nil {
 			continue
@@ -358,12 +659,21 @@ func (t *GridTreeNode) GetChildren() [8]Node {
 		v := &GridTreeNode{
 			pts:                  c,
 			childrenPts:          [8]*geom.LinkedPoint{},
-			bounds:               geom.NewBoundingBoxFromParent(t.bounds, i),
+			bounds:               t.childBounds(i),
 			depth:                t.depth + 1,
 			maxDepth:             t.maxDepth,
-			gridSize:             t.gridSize / 2,
+			gridSize:             [3]float64{t.gridSize[0] / 2, t.gridSize[1] / 2, t.gridSize[2] / 2},
 			childrenBuilt:        false,
 			minPointsPerChildren: t.minPointsPerChildren,
+			featurePreserving:    t.featurePreserving,
+			sparsePolicy:         t.sparsePolicy,
+			subdivision:          t.subdivision,
+			verticalAxis:         t.verticalAxis,
+			indexCache:           t.indexCache,
+			equalArea:            t.equalArea,
+			maxGeometricError:    geometricError,
+			rootGeometricError:   t.rootGeometricError,
+			geometricErrorMode:   t.geometricErrorMode,
 			cX:                   t.cX,
 			cY:                   t.cY,
 			cZ:                   t.cZ,
@@ -397,11 +707,80 @@ func (t *GridTreeNode) IsLeaf() bool {
 	return true
 }
 
+// ComputeGeometricError returns the geometric error of the node. The error of the root, and of every node in the
+// spacing mode, is the mean spacing between the points of the node, measured as the mean distance of each point from
+// its nearest neighbor, so that the error reflects the density actually achieved by the sampling. The error of a node
+// never exceeds the one of its parent. Nodes with less than two points fall back to the diagonal of the grid cell.
+// In the geometric mode the error of the children is half the one of their parent, in the linear mode it decreases
+// linearly with the depth from the one of the root. The error is computed once and then cached.
 func (t *GridTreeNode) ComputeGeometricError() float64 {
-	return math.Sqrt(t.gridSize * t.gridSize * 3)
+	t.geometricErrorOnce.Do(func() {
+		if t.depth > 0 && t.geometricErrorMode == GeometricErrorGeometric {
+			t.geometricError = t.maxGeometricError / 2
+			return
+		}
+		if t.depth > 0 && t.geometricErrorMode == GeometricErrorLinear {
+			levels := float64(max(t.maxDepth, t.depth) + 1)
+			t.geometricError = t.rootGeometricError * (levels - float64(t.depth)) / levels
+			return
+		}
+		geometricError := math.Sqrt(t.gridSize[0]*t.gridSize[0] + t.gridSize[1]*t.gridSize[1] + t.gridSize[2]*t.gridSize[2])
+		if spacing, ok := meanSpacing(t.neighbors()); ok {
+			geometricError = spacing
+		}
+		// the estimation is the last operation using the index
+		t.dropIndex()
+		if t.maxGeometricError > 0 && geometricError > t.maxGeometricError {
+			geometricError = t.maxGeometricError
+		}
+		t.geometricError = geometricError
+		if t.depth == 0 {
+			t.rootGeometricError = geometricError
+		}
+	})
+	return t.geometricError
+}
+
+// cellSize returns the size of the grid cells along the X, Y and Z axes, assigning the vertical size to the
+// vertical axis and the horizontal sizes to the other two
+func (t *GridTreeNode) cellSize() [3]float64 {
+	switch t.verticalAxis {
+	case 0:
+		return [3]float64{t.gridSize[2], t.gridSize[0], t.gridSize[1]}
+	case 1:
+		return [3]float64{t.gridSize[0], t.gridSize[2], t.gridSize[1]}
+	default:
+		return t.gridSize
+	}
 }
 
+// getChildrenIndex returns the index of the child the point belongs to. In quadtree mode the bit
+// of the vertical axis is always cleared, so that only four children are used.
 func (t *GridTreeNode) getChildrenIndex(p geom.Point32) int {
+	idx := t.getOctantIndex(p)
+	if t.subdivision == SubdivisionQuadtree {
+		idx &^= 1 << t.verticalAxis
+	}
+	return idx
+}
+
+// childBounds returns the bounds of the child with the given index
+func (t *GridTreeNode) childBounds(idx int) geom.BoundingBox {
+	b := geom.NewBoundingBoxFromParent(t.bounds, idx)
+	if t.subdivision != SubdivisionQuadtree {
+		return b
+	}
+	// the child spans the full extent of the parent along the vertical axis
+	switch t.verticalAxis {
+	case 0:
+		return geom.NewBoundingBox(t.bounds.Xmin, t.bounds.Xmax, b.Ymin, b.Ymax, b.Zmin, b.Zmax)
+	case 1:
+		return geom.NewBoundingBox(b.Xmin, b.Xmax, t.bounds.Ymin, t.bounds.Ymax, b.Zmin, b.Zmax)
+	}
+	return geom.NewBoundingBox(b.Xmin, b.Xmax, b.Ymin, b.Ymax, t.bounds.Zmin, t.bounds.Zmax)
+}
+
+func (t *GridTreeNode) getOctantIndex(p geom.Point32) int {
 	if float64(p.X) < t.bounds.Xmid && float64(p.Y) < t.bounds.Ymid && float64(p.Z) < t.bounds.Zmid {
 		return 0
 	} else if float64(p.X) >= t.bounds.Xmid && float64(p.Y) < t.bounds.Ymid && float64(p.Z) < t.bounds.Zmid {
@@ -424,13 +803,36 @@ func (t *GridTreeNode) loadPoints(reader las.LasReader, cConv coor.CoordinateCon
 	numPts := reader.NumberOfPoints()
 
 	// all coordinates are referred as relative to the coordinates of the first point
-	baselinePt, err := reader.GetNext()
-	if err != nil {
-		return err
+	// that is not discarded by the mutators
+	var baselinePt geom.Point64
+	var err error
+	consumed := 0
+	for {
+		if consumed >= numPts {
+			return ErrAllPointsDiscarded
+		}
+		if consumed%t.cancelCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		baselinePt, err = reader.GetNext()
+		if errors.Is(err, las.ErrNoMorePoints) {
+			// the reader holds less points than declared, none of which was kept
+			return ErrAllPointsDiscarded
+		}
+		if err != nil {
+			return err
+		}
+		consumed++
+		var ok bool
+		if baselinePt, ok = t.mutate(baselinePt); ok {
+			break
+		}
 	}
 	baselinePt, err = t.transformPoint(baselinePt, cConv, eConv, reader.GetSrid())
 	if err != nil {
-		return err
+		return &PointError{Point: baselinePt, Err: err}
 	}
 	baselineGeomPt := &geom.LinkedPoint{Pt: baselinePt.ToPointFromBaseline(baselinePt)}
 
@@ -441,84 +843,117 @@ func (t *GridTreeNode) loadPoints(reader las.LasReader, cConv coor.CoordinateCon
 	maxY := baselinePt.Y
 	maxZ := baselinePt.Z
 
+	workers := t.loadWorkersNumber
+	if t.reprojectionWorkers > 0 {
+		workers = t.reprojectionWorkers
+	}
+
 	var wg sync.WaitGroup
 	var mutex sync.Mutex
 	var errchan chan error = make(chan error)
-	var ptchan chan geom.Point64 = make(chan geom.Point64, t.loadWorkersNumber*10)
+	var ptchan chan []geom.Point64 = make(chan []geom.Point64, workers*2)
 
 	// the consumers store their artifacts in these structures
-	startPts := make([]*geom.LinkedPoint, t.loadWorkersNumber)
-	endPts := make([]*geom.LinkedPoint, t.loadWorkersNumber)
-	averages := make([][3]float64, t.loadWorkersNumber)
-	ptCounts := make([]int, t.loadWorkersNumber)
+	startPts := make([]*geom.LinkedPoint, workers)
+	endPts := make([]*geom.LinkedPoint, workers)
+	averages := make([][3]float64, workers)
+	ptCounts := make([]int, workers)
 
 	wg.Add(1)
 
-	// PRODUCER: reads the points one after another and pushes them to a channel
+	// PRODUCER: reads the points one after another and pushes them to a channel in batches
 	produce := func() {
 		defer close(ptchan)
 		defer wg.Done()
-		for i := 1; i < numPts; i++ { // 1 point was already consumed (baseline pt)
-			if err := ctx.Err(); err != nil {
-				errchan <- err
-				return
+		batch := make([]geom.Point64, 0, loadBatchSize)
+		for i := consumed; i < numPts; i++ { // some points were already consumed to find the baseline pt
+			if (i-consumed)%t.cancelCheckInterval == 0 {
+				if err := ctx.Err(); err != nil {
+					errchan <- err
+					return
+				}
 			}
 			pt, err := reader.GetNext()
+			if errors.Is(err, las.ErrNoMorePoints) {
+				// the reader holds less points than declared
+				break
+			}
 			if err != nil {
 				errchan <- err
 				return
 			}
-			ptchan <- pt
+			batch = append(batch, pt)
+			if len(batch) == loadBatchSize {
+				ptchan <- batch
+				batch = make([]geom.Point64, 0, loadBatchSize)
+			}
+		}
+		if len(batch) > 0 {
+			ptchan <- batch
 		}
 	}
 
 	// CONSUMER: reads from the channel, transforms and stores the points
 	consume := func(i int) {
 		defer wg.Done()
+		// each consumer uses its own converter as the projection objects are not safe for concurrent use
+		conv, release := coor.Fork(cConv)
+		defer release()
 		var curNode *geom.LinkedPoint
+		n := 0
 		for {
-			if err := ctx.Err(); err != nil {
-				errchan <- err
-				return
-			}
 			// get work from channel
-			pt, ok := <-ptchan
+			batch, ok := <-ptchan
 			if !ok {
 				// channel was closed by producer, quit infinite loop
 				return
 			}
+			for _, pt := range batch {
+				if n%t.cancelCheckInterval == 0 {
+					if err := ctx.Err(); err != nil {
+						errchan <- err
+						return
+					}
+				}
+				n++
 
-			pt, err := t.transformPoint(pt, cConv, eConv, reader.GetSrid())
-			if err != nil {
-				errchan <- err
-				return
-			}
+				pt, keep := t.mutate(pt)
+				if !keep {
+					continue
+				}
 
-			averages[i][0] = (averages[i][0]*float64(ptCounts[i]) + pt.X)
-			ptCounts[i]++
-			// update bounds estimation
-			mutex.Lock()
-			minX = math.Min(float64(pt.X), minX)
-			minY = math.Min(float64(pt.Y), minY)
-			minZ = math.Min(float64(pt.Z), minZ)
-			maxX = math.Max(float64(pt.X), maxX)
-			maxY = math.Max(float64(pt.Y), maxY)
-			maxZ = math.Max(float64(pt.Z), maxZ)
-			newNode := &geom.LinkedPoint{Pt: pt.ToPointFromBaseline(baselinePt)}
-			if curNode == nil {
-				curNode = newNode
-				startPts[i] = curNode
-			} else {
-				curNode.Next = newNode
-				curNode = newNode
+				pt, err := t.transformPoint(pt, conv, eConv, reader.GetSrid())
+				if err != nil {
+					errchan <- &PointError{Point: pt, Err: err}
+					return
+				}
+
+				averages[i][0] = (averages[i][0]*float64(ptCounts[i]) + pt.X)
+				ptCounts[i]++
+				// update bounds estimation
+				mutex.Lock()
+				minX = math.Min(float64(pt.X), minX)
+				minY = math.Min(float64(pt.Y), minY)
+				minZ = math.Min(float64(pt.Z), minZ)
+				maxX = math.Max(float64(pt.X), maxX)
+				maxY = math.Max(float64(pt.Y), maxY)
+				maxZ = math.Max(float64(pt.Z), maxZ)
+				newNode := &geom.LinkedPoint{Pt: pt.ToPointFromBaseline(baselinePt)}
+				if curNode == nil {
+					curNode = newNode
+					startPts[i] = curNode
+				} else {
+					curNode.Next = newNode
+					curNode = newNode
+				}
 				endPts[i] = curNode
+				mutex.Unlock()
 			}
-			mutex.Unlock()
 		}
 	}
 
 	go produce()
-	for i := 0; i < t.loadWorkersNumber; i++ {
+	for i := 0; i < workers; i++ {
 		wg.Add(1)
 		go consume(i)
 	}
@@ -550,24 +985,63 @@ func (t *GridTreeNode) loadPoints(reader las.LasReader, cConv coor.CoordinateCon
 	}
 
 	for i, startPt := range startPts {
-		if t.pts != nil {
-			endPts[i].Next = t.pts
+		if startPt == nil {
+			// the worker did not store any point, e.g. because all were discarded
+			continue
 		}
+		endPts[i].Next = t.pts
 		t.pts = startPt
 	}
 	baselineGeomPt.Next = t.pts
 	t.pts = baselineGeomPt
 	t.bounds = geom.NewBoundingBox(minX-baselinePt.X, maxX-baselinePt.X, minY-baselinePt.Y, maxY-baselinePt.Y, minZ-baselinePt.Z, maxZ-baselinePt.Z)
+	if t.outlierNeighbors > 0 {
+		t.pts, _ = removeOutliers(t.neighbors(), t.outlierNeighbors, t.outlierStdDevMul, t.loadWorkersNumber)
+		// outliers usually lie at the edges of the cloud, so the bounds must be recomputed
+		t.bounds = computeBounds(t.pts)
+	}
+	if t.smoothingRadius > 0 && t.smoothingIterations > 0 {
+		// the local vertical is approximated by the direction from the center of the Earth
+		norm := math.Sqrt(baselinePt.X*baselinePt.X + baselinePt.Y*baselinePt.Y + baselinePt.Z*baselinePt.Z)
+		if norm > 0 {
+			up := [3]float64{baselinePt.X / norm, baselinePt.Y / norm, baselinePt.Z / norm}
+			if smoothElevations(t.neighbors(), up, t.smoothingRadius, t.smoothingIterations, t.loadWorkersNumber) {
+				t.dropIndex()
+			}
+			t.bounds = computeBounds(t.pts)
+		}
+	}
 	t.cX = baselinePt.X
 	t.cY = baselinePt.Y
 	t.cZ = baselinePt.Z
+	t.verticalAxis = verticalAxis(t.cX, t.cY, t.cZ)
 	return nil
 }
 
+// verticalAxis returns the index of the EPSG 4978 axis closest to the local vertical at the given location,
+// approximated by the direction from the center of the Earth
+func verticalAxis(x, y, z float64) int {
+	ax, ay, az := math.Abs(x), math.Abs(y), math.Abs(z)
+	if ax >= ay && ax >= az {
+		return 0
+	}
+	if ay >= az {
+		return 1
+	}
+	return 2
+}
+
 func (t *GridTreeNode) GetCenter(cConv coor.CoordinateConverter) (float64, float64, float64, error) {
 	return t.cX, t.cY, t.cZ, nil
 }
 
+func (t *GridTreeNode) mutate(pt geom.Point64) (geom.Point64, bool) {
+	if t.mutator == nil {
+		return pt, true
+	}
+	return t.mutator.Mutate(pt)
+}
+
 func (t *GridTreeNode) transformPoint(pt geom.Point64, cConv coor.CoordinateConverter, eConv elev.ElevationConverter, srid int) (geom.Point64, error) {
 	var err error
 	z := pt.Z
@@ -589,5 +1063,10 @@ func (t *GridTreeNode) transformPoint(pt geom.Point64, cConv coor.CoordinateConv
 		return pt, err
 	}
 	pt.X, pt.Y, pt.Z = coords.X, coords.Y, coords.Z
+	if t.roundingStep > 0 {
+		pt.X = math.Round(pt.X/t.roundingStep) * t.roundingStep
+		pt.Y = math.Round(pt.Y/t.roundingStep) * t.roundingStep
+		pt.Z = math.Round(pt.Z/t.roundingStep) * t.roundingStep
+	}
 	return pt, nil
 }
@@ -2,8 +2,12 @@ package tree
 
 import (
 	"context"
+	"errors"
+	"math"
+	"sync/atomic"
 	"testing"
 
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/conv/coor"
 	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
 	"github.com/mfbonfigli/gocesiumtiler/v2/internal/las"
 	"github.com/mfbonfigli/gocesiumtiler/v2/internal/utils/test"
@@ -29,8 +33,8 @@ func TestNewGridTreeWithMaxDepth(t *testing.T) {
 	if tree.depth != 0 {
 		t.Errorf("expected depth %d but got %d", 0, tree.depth)
 	}
-	if tree.gridSize != 11.5 {
-		t.Errorf("expected gridSize %f but got %f", 11.5, tree.gridSize)
+	if expected := [3]float64{11.5, 11.5, 11.5}; tree.gridSize != expected {
+		t.Errorf("expected gridSize %v but got %v", expected, tree.gridSize)
 	}
 }
 
@@ -54,8 +58,8 @@ func TestNewGridTreeWithDefaultDepth(t *testing.T) {
 	if tree.depth != 0 {
 		t.Errorf("expected depth %d but got %d", 0, tree.depth)
 	}
-	if tree.gridSize != 11.5 {
-		t.Errorf("expected gridSize %f but got %f", 11.5, tree.gridSize)
+	if expected := [3]float64{11.5, 11.5, 11.5}; tree.gridSize != expected {
+		t.Errorf("expected gridSize %v but got %v", expected, tree.gridSize)
 	}
 }
 
@@ -125,6 +129,190 @@ func TestGridTreeLoad(t *testing.T) {
 	}
 }
 
+// discardAll is a mutator discarding all points
+type discardAll struct{}
+
+func (d discardAll) Mutate(pt geom.Point64) (geom.Point64, bool) {
+	return pt, false
+}
+
+func TestGridTreeLoadAllPointsDiscarded(t *testing.T) {
+	tree := NewGridTree(WithGridSize(1000000), WithMutator(discardAll{}), WithLoadWorkersNumber(1))
+	reader := &las.MockLasReader{
+		Srid: 32633,
+		Pts: []geom.Point64{
+			{X: 432488.4714159001, Y: 4.705678720925195e+06, Z: 2.550538045727727},
+			{X: 432466.58372129063, Y: 4.705686414284739e+06, Z: 4.457767479175496},
+		},
+	}
+	err := tree.Load(reader, nil, nil, context.TODO())
+	if !errors.Is(err, ErrAllPointsDiscarded) {
+		t.Errorf("expected error %v got %v", ErrAllPointsDiscarded, err)
+	}
+	if reader.Cur != len(reader.Pts) {
+		t.Errorf("expected %d points to be read, got %d", len(reader.Pts), reader.Cur)
+	}
+}
+
+// cancellingReader cancels the context once the given number of points has been read
+type cancellingReader struct {
+	las.MockLasReader
+	cancelAt int
+	cancel   context.CancelFunc
+}
+
+func (r *cancellingReader) GetNext() (geom.Point64, error) {
+	pt, err := r.MockLasReader.GetNext()
+	if r.Cur == r.cancelAt {
+		r.cancel()
+	}
+	return pt, err
+}
+
+func TestGridTreeLoadCancellationCheckInterval(t *testing.T) {
+	for _, c := range []struct {
+		interval int
+		read     int
+	}{{1, 6}, {4, 8}, {100, 20}} {
+		ctx, cancel := context.WithCancel(context.TODO())
+		reader := &cancellingReader{MockLasReader: las.MockLasReader{Srid: 32633, Pts: make([]geom.Point64, 20)}, cancelAt: 6, cancel: cancel}
+		// all points are discarded, hence they are all read looking for the first point to keep
+		tree := NewGridTree(WithMutator(discardAll{}), WithCancellationCheckInterval(c.interval))
+		err := tree.Load(reader, nil, nil, ctx)
+		if c.read < len(reader.Pts) && !errors.Is(err, context.Canceled) {
+			t.Errorf("interval %d: expected error %v got %v", c.interval, context.Canceled, err)
+		}
+		if reader.Cur != c.read {
+			t.Errorf("interval %d: expected %d points to be read before the cancellation is detected, got %d", c.interval, c.read, reader.Cur)
+		}
+	}
+	if tree := NewGridTree(WithCancellationCheckInterval(0)); tree.cancelCheckInterval != 1 {
+		t.Errorf("expected the context to be checked at every point, got interval %d", tree.cancelCheckInterval)
+	}
+}
+
+// overcountingReader declares more points than it holds
+type overcountingReader struct {
+	las.MockLasReader
+	declared int
+}
+
+func (r *overcountingReader) NumberOfPoints() int {
+	return r.declared
+}
+
+func TestGridTreeLoadReaderExhausted(t *testing.T) {
+	conv, err := test.GetTestCoordinateConverter()
+	if err != nil {
+		t.Fatalf("error provisioning the coordinate converter for the test: %v", err)
+	}
+	pts := []geom.Point64{{X: 1, Y: 2, Z: 3}, {X: 2, Y: 3, Z: 4}, {X: 3, Y: 4, Z: 5}}
+	tree := NewGridTree()
+	if err := tree.Load(&overcountingReader{MockLasReader: las.MockLasReader{Srid: 4978, Pts: pts}, declared: 10}, conv, nil, context.TODO()); err != nil {
+		t.Fatalf("expected the reading to complete at the last point, got %v", err)
+	}
+	n := 0
+	for cur := tree.pts; cur != nil; cur = cur.Next {
+		n++
+	}
+	if n != len(pts) {
+		t.Errorf("expected %d points got %d", len(pts), n)
+	}
+	tree = NewGridTree()
+	if err := tree.Load(&overcountingReader{MockLasReader: las.MockLasReader{Srid: 4978}, declared: 10}, conv, nil, context.TODO()); !errors.Is(err, ErrAllPointsDiscarded) {
+		t.Errorf("expected error %v got %v", ErrAllPointsDiscarded, err)
+	}
+}
+
+// forkingConverter counts the converters forked and released by the load workers
+type forkingConverter struct {
+	coor.CoordinateConverter
+	forked   atomic.Int32
+	released atomic.Int32
+}
+
+func (c *forkingConverter) Fork() coor.CoordinateConverter {
+	c.forked.Add(1)
+	return &releasingConverter{CoordinateConverter: c.CoordinateConverter, released: &c.released}
+}
+
+type releasingConverter struct {
+	coor.CoordinateConverter
+	released *atomic.Int32
+}
+
+func (c *releasingConverter) Cleanup() {
+	c.released.Add(1)
+}
+
+func TestGridTreeLoadReprojectionWorkers(t *testing.T) {
+	base, err := test.GetTestCoordinateConverter()
+	if err != nil {
+		t.Fatalf("error provisioning the coordinate converter for the test: %v", err)
+	}
+	conv := &forkingConverter{CoordinateConverter: base}
+	pts := make([]geom.Point64, 3*loadBatchSize+10)
+	for i := range pts {
+		pts[i] = geom.Point64{X: float64(i), Y: 2, Z: 3}
+	}
+	tree := NewGridTree(WithLoadWorkersNumber(1), WithReprojectionWorkers(3))
+	if err := tree.Load(&las.MockLasReader{Srid: 4978, Pts: pts}, conv, nil, context.TODO()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	n := 0
+	for cur := tree.pts; cur != nil; cur = cur.Next {
+		n++
+	}
+	if n != len(pts) {
+		t.Errorf("expected %d points got %d", len(pts), n)
+	}
+	if forked, released := conv.forked.Load(), conv.released.Load(); forked != 3 || released != 3 {
+		t.Errorf("expected 3 converters forked and released got %d forked and %d released", forked, released)
+	}
+}
+
+func TestGridTreeLoadElevationSmoothing(t *testing.T) {
+	// a 10x10 grid at the north pole, where the vertical is the Z axis, with a checkerboard noise of +-0.2m
+	pts := []geom.Point64{}
+	for x := 0; x < 10; x++ {
+		for y := 0; y < 10; y++ {
+			z := 6356752.0 + 0.2
+			if (x+y)%2 == 1 {
+				z -= 0.4
+			}
+			pts = append(pts, geom.Point64{X: float64(x), Y: float64(y), Z: z})
+		}
+	}
+	conv, err := test.GetTestCoordinateConverter()
+	if err != nil {
+		t.Fatalf("error provisioning the coordinate converter for the test: %v", err)
+	}
+	for _, smoothing := range []bool{false, true} {
+		opts := []func(*GridTreeNode){}
+		if smoothing {
+			opts = append(opts, WithElevationSmoothing(1.5, 2))
+		}
+		tree := NewGridTree(opts...)
+		if err := tree.Load(&las.MockLasReader{Srid: 4978, Pts: pts}, conv, nil, context.TODO()); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		extent := tree.bounds.Zmax - tree.bounds.Zmin
+		if !smoothing && math.Abs(extent-0.4) > 1e-3 {
+			t.Errorf("expected an elevation extent of 0.4 without smoothing got %f", extent)
+		}
+		if smoothing && extent > 0.2 {
+			t.Errorf("expected the smoothing to reduce the elevation extent below 0.2 got %f", extent)
+		}
+		n := 0
+		for cur := tree.pts; cur != nil; cur = cur.Next {
+			n++
+		}
+		if n != len(pts) {
+			t.Errorf("expected %d points got %d", len(pts), n)
+		}
+	}
+}
+
 func TestGridTreeBuild(t *testing.T) {
 	// the grid size is kept big intentionally so that we have at most 1 point per octant during the tests
 	tree := NewGridTree(WithGridSize(1000000), WithMaxDepth(3), WithMinPointsPerChildren(1))
@@ -316,3 +504,490 @@ func TestGetBoundingBoxRegion(t *testing.T) {
 		t.Errorf("expected %v got %v", expected, bbox)
 	}
 }
+
+func TestGridTreeBuildSparseNodePolicy(t *testing.T) {
+	newPoints := func() *geom.LinkedPoint {
+		// the point at the center is retained by the node, octant 0 gets 10 points and octant 7 gets 2
+		pts := &geom.LinkedPoint{Pt: geom.Point32{X: 5, Y: 5, Z: 5}}
+		for i := 0; i < 10; i++ {
+			pts = &geom.LinkedPoint{Pt: geom.Point32{X: float32(i) / 10, Y: 1, Z: 1}, Next: pts}
+		}
+		pts = &geom.LinkedPoint{Pt: geom.Point32{X: 9, Y: 9, Z: 9}, Next: pts}
+		pts = &geom.LinkedPoint{Pt: geom.Point32{X: 9.5, Y: 9.5, Z: 9.5}, Next: pts}
+		return pts
+	}
+	cases := []struct {
+		policy        SparseNodePolicy
+		numPoints     int
+		totalPoints   int
+		sparseChildPt bool
+	}{
+		{SparseConsolidate, 3, 13, false},
+		{SparseKeep, 1, 13, true},
+		{SparseOmit, 1, 11, false},
+	}
+	for _, c := range cases {
+		node := &GridTreeNode{
+			pts:                  newPoints(),
+			bounds:               geom.NewBoundingBox(-1, 11, -1, 11, -1, 11),
+			gridSize:             [3]float64{1000, 1000, 1000},
+			maxDepth:             5,
+			minPointsPerChildren: 3,
+			sparsePolicy:         c.policy,
+		}
+		if err := node.Build(); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if node.NumberOfPoints() != c.numPoints {
+			t.Errorf("policy %d: expected %d points got %d", c.policy, c.numPoints, node.NumberOfPoints())
+		}
+		if node.TotalNumberOfPoints() != c.totalPoints {
+			t.Errorf("policy %d: expected %d total points got %d", c.policy, c.totalPoints, node.TotalNumberOfPoints())
+		}
+		if (node.childrenPts[7] != nil) != c.sparseChildPt {
+			t.Errorf("policy %d: expected sparse child to exist %v", c.policy, c.sparseChildPt)
+		}
+		if node.childrenPts[0] == nil {
+			t.Errorf("policy %d: expected dense child to exist", c.policy)
+		}
+		children := node.GetChildren()
+		if (children[7] != nil) != c.sparseChildPt {
+			t.Errorf("policy %d: expected sparse child node to exist %v", c.policy, c.sparseChildPt)
+		} else if c.sparseChildPt && children[7].(*GridTreeNode).sparsePolicy != c.policy {
+			t.Errorf("policy %d: expected policy to be propagated to the children", c.policy)
+		}
+	}
+}
+
+func TestGridTreeBuildAnisotropicGrid(t *testing.T) {
+	// a vertical column of points spaced 1 meter apart, along the X axis taken as vertical
+	newPoints := func() *geom.LinkedPoint {
+		var pts *geom.LinkedPoint
+		for i := 0; i < 10; i++ {
+			pts = &geom.LinkedPoint{Pt: geom.Point32{X: float32(i) + 0.5, Y: 5, Z: 5}, Next: pts}
+		}
+		return pts
+	}
+	cases := []struct {
+		x, y, z   float64
+		numPoints int
+	}{
+		// fine vertical cells retain all the points of the column
+		{100, 100, 1, 10},
+		// coarse vertical cells retain a single point
+		{1, 1, 100, 1},
+	}
+	for _, c := range cases {
+		node := NewGridTree(WithGridSizeXYZ(c.x, c.y, c.z), WithMinPointsPerChildren(1))
+		node.pts = newPoints()
+		node.bounds = geom.NewBoundingBox(0, 10, 0, 10, 0, 10)
+		node.verticalAxis = 0
+		if err := node.Build(); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if actual := node.NumberOfPoints(); actual != c.numPoints {
+			t.Errorf("grid %v %v %v: expected %d points got %d", c.x, c.y, c.z, c.numPoints, actual)
+		}
+	}
+	if expected := [3]float64{1, 2, 3}; (&GridTreeNode{gridSize: [3]float64{2, 3, 1}, verticalAxis: 0}).cellSize() != expected {
+		t.Errorf("expected the vertical size on the X axis")
+	}
+}
+
+func TestGridTreeBuildEqualAreaThinning(t *testing.T) {
+	// a steep face, 10 points stacked along the X axis taken as vertical, next to a flat strip
+	// of 10 points spaced 1 meter apart along Y
+	newPoints := func() *geom.LinkedPoint {
+		var pts *geom.LinkedPoint
+		for i := 0; i < 10; i++ {
+			pts = &geom.LinkedPoint{Pt: geom.Point32{X: float32(i) + 0.5, Y: 0.5, Z: 5}, Next: pts}
+			pts = &geom.LinkedPoint{Pt: geom.Point32{X: 0.5, Y: float32(i) + 0.5, Z: 5}, Next: pts}
+		}
+		return pts
+	}
+	cases := []struct {
+		equalArea bool
+		numPoints int
+	}{
+		// the face retains a point per meter of height
+		{false, 19},
+		// the face retains a single point, as its horizontal footprint is a single cell
+		{true, 10},
+	}
+	for _, c := range cases {
+		node := NewGridTree(WithGridSize(1), WithMinPointsPerChildren(1), WithEqualAreaThinning(c.equalArea))
+		node.pts = newPoints()
+		node.bounds = geom.NewBoundingBox(0, 10, 0, 10, 0, 10)
+		node.verticalAxis = 0
+		if err := node.Build(); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if actual := node.NumberOfPoints(); actual != c.numPoints {
+			t.Errorf("equal area %v: expected %d points got %d", c.equalArea, c.numPoints, actual)
+		}
+	}
+}
+
+func TestGridTreeComputeGeometricError(t *testing.T) {
+	// points along a line with a spacing of 2 meters
+	var pts *geom.LinkedPoint
+	for i := 0; i < 5; i++ {
+		pts = &geom.LinkedPoint{Pt: geom.Point32{X: float32(i * 2)}, Next: pts}
+	}
+	node := &GridTreeNode{pts: pts, gridSize: [3]float64{10, 10, 10}}
+	if actual := node.ComputeGeometricError(); math.Abs(actual-2) > 1e-9 {
+		t.Errorf("expected geometric error %v got %v", 2, actual)
+	}
+
+	capped := &GridTreeNode{pts: pts, gridSize: [3]float64{10, 10, 10}, maxGeometricError: 1.5}
+	if actual := capped.ComputeGeometricError(); actual != 1.5 {
+		t.Errorf("expected geometric error capped to %v got %v", 1.5, actual)
+	}
+
+	single := &GridTreeNode{pts: &geom.LinkedPoint{}, gridSize: [3]float64{10, 10, 10}}
+	if actual := single.ComputeGeometricError(); math.Abs(actual-math.Sqrt(300)) > 1e-9 {
+		t.Errorf("expected geometric error %v got %v", math.Sqrt(300), actual)
+	}
+}
+
+func TestGridTreeGeometricErrorMode(t *testing.T) {
+	// points along a line with a spacing of 2 meters
+	var pts *geom.LinkedPoint
+	for i := 0; i < 5; i++ {
+		pts = &geom.LinkedPoint{Pt: geom.Point32{X: float32(i * 2)}, Next: pts}
+	}
+	root := NewGridTree(WithGeometricErrorMode(GeometricErrorLinear), WithMaxDepth(3))
+	root.pts = pts
+	if actual := root.ComputeGeometricError(); math.Abs(actual-2) > 1e-9 {
+		t.Errorf("expected the root geometric error %v got %v", 2, actual)
+	}
+
+	cases := []struct {
+		mode     GeometricErrorMode
+		depth    int
+		expected float64
+	}{
+		{mode: GeometricErrorSpacing, depth: 2, expected: 1.5},
+		{mode: GeometricErrorGeometric, depth: 1, expected: 0.75},
+		{mode: GeometricErrorGeometric, depth: 2, expected: 0.75},
+		{mode: GeometricErrorLinear, depth: 1, expected: 3},
+		{mode: GeometricErrorLinear, depth: 2, expected: 2},
+		{mode: GeometricErrorLinear, depth: 3, expected: 1},
+	}
+	for _, c := range cases {
+		node := &GridTreeNode{
+			pts:                pts,
+			gridSize:           [3]float64{10, 10, 10},
+			depth:              c.depth,
+			maxDepth:           3,
+			maxGeometricError:  1.5,
+			rootGeometricError: 4,
+			geometricErrorMode: c.mode,
+		}
+		if actual := node.ComputeGeometricError(); math.Abs(actual-c.expected) > 1e-9 {
+			t.Errorf("mode %v depth %d: expected geometric error %v got %v", c.mode, c.depth, c.expected, actual)
+		}
+	}
+
+	// the mode and the error of the root are propagated to the children
+	var built *geom.LinkedPoint
+	for i := 0; i < 10; i++ {
+		built = &geom.LinkedPoint{Pt: geom.Point32{X: float32(i) / 10, Y: 1, Z: 1}, Next: built}
+	}
+	node := &GridTreeNode{
+		pts:                  built,
+		bounds:               geom.NewBoundingBox(-1, 11, -1, 11, -1, 11),
+		gridSize:             [3]float64{1000, 1000, 1000},
+		maxDepth:             5,
+		minPointsPerChildren: 1,
+		geometricErrorMode:   GeometricErrorGeometric,
+	}
+	if err := node.Build(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	child := node.GetChildren()[0]
+	if child == nil {
+		t.Fatalf("expected the child to exist")
+	}
+	if expected, actual := node.ComputeGeometricError()/2, child.ComputeGeometricError(); math.Abs(actual-expected) > 1e-9 {
+		t.Errorf("expected child geometric error %v got %v", expected, actual)
+	}
+}
+
+func TestGridTreeSpatialIndexCache(t *testing.T) {
+	var pts *geom.LinkedPoint
+	for i := 0; i < 5; i++ {
+		pts = &geom.LinkedPoint{Pt: geom.Point32{X: float32(i * 2)}, Next: pts}
+	}
+	uncached := &GridTreeNode{pts: pts, gridSize: [3]float64{10, 10, 10}}
+	if uncached.neighbors() == uncached.neighbors() {
+		t.Errorf("expected a new index on each invocation without the cache")
+	}
+
+	node := NewGridTree(WithSpatialIndexCache(true))
+	node.pts = pts
+	index := node.neighbors()
+	if len(index.coords) != 5 {
+		t.Errorf("expected %d indexed points got %d", 5, len(index.coords))
+	}
+	if node.neighbors() != index {
+		t.Errorf("expected the cached index to be reused")
+	}
+	if actual := node.ComputeGeometricError(); math.Abs(actual-2) > 1e-9 {
+		t.Errorf("expected geometric error %v got %v", 2, actual)
+	}
+	if node.index != nil {
+		t.Errorf("expected the index to be dropped after the geometric error estimation")
+	}
+	node.pts = pts.Next
+	index = node.neighbors()
+	node.restrictIndex()
+	if node.index != index || len(index.activeIndices()) != 4 {
+		t.Errorf("expected the index to be restricted to %d points got %v", 4, index.active)
+	}
+	node.pts = &geom.LinkedPoint{Pt: geom.Point32{X: 100}}
+	node.restrictIndex()
+	if node.index != nil {
+		t.Errorf("expected the index to be dropped when restricted to points it does not store")
+	}
+}
+
+func TestGridTreeSpatialIndexCachePipeline(t *testing.T) {
+	// a 10x10 grid at the north pole with a checkerboard noise and an outlier
+	pts := []geom.Point64{}
+	for x := 0; x < 10; x++ {
+		for y := 0; y < 10; y++ {
+			z := 6356752.0 + 0.2
+			if (x+y)%2 == 1 {
+				z -= 0.4
+			}
+			pts = append(pts, geom.Point64{X: float64(x), Y: float64(y), Z: z})
+		}
+	}
+	pts = append(pts, geom.Point64{X: 50, Y: 50, Z: 6356752})
+	conv, err := test.GetTestCoordinateConverter()
+	if err != nil {
+		t.Fatalf("error provisioning the coordinate converter for the test: %v", err)
+	}
+	for _, c := range []struct {
+		cache     bool
+		smoothing bool
+		builds    int
+	}{
+		{false, false, 2},
+		{true, false, 1},
+		{false, true, 3},
+		// the smoothing moves the points, so the index is rebuilt for the geometric error
+		{true, true, 2},
+	} {
+		opts := []func(*GridTreeNode){
+			WithSpatialIndexCache(c.cache),
+			WithOutlierRemoval(4, 1),
+			WithGridSizeXYZ(4, 4, 4),
+			WithGeometricErrorMode(GeometricErrorSpacing),
+		}
+		if c.smoothing {
+			opts = append(opts, WithElevationSmoothing(1.5, 2))
+		}
+		tree := NewGridTree(opts...)
+		if err := tree.Load(&las.MockLasReader{Srid: 4978, Pts: pts}, conv, nil, context.TODO()); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if err := tree.Build(); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		uncached := &GridTreeNode{pts: tree.pts}
+		expected, _ := meanSpacing(uncached.neighbors())
+		if actual := tree.ComputeGeometricError(); math.Abs(actual-expected) > 1e-6 {
+			t.Errorf("cache %v, smoothing %v: expected geometric error %v got %v", c.cache, c.smoothing, expected, actual)
+		}
+		if tree.indexBuilds != c.builds {
+			t.Errorf("cache %v, smoothing %v: expected %d indexes built got %d", c.cache, c.smoothing, c.builds, tree.indexBuilds)
+		}
+		if tree.index != nil {
+			t.Errorf("cache %v, smoothing %v: expected the index to be dropped", c.cache, c.smoothing)
+		}
+	}
+}
+
+func TestGridTreeBuildQuadtree(t *testing.T) {
+	pts := &geom.LinkedPoint{Pt: geom.Point32{X: 5, Y: 5, Z: 5}}
+	for i := 0; i < 10; i++ {
+		for j := 0; j < 10; j++ {
+			pts = &geom.LinkedPoint{Pt: geom.Point32{X: float32(i), Y: float32(j), Z: float32(i+j) / 2}, Next: pts}
+		}
+	}
+	node := &GridTreeNode{
+		pts:                  pts,
+		bounds:               geom.NewBoundingBox(0, 10, 0, 10, 0, 10),
+		gridSize:             [3]float64{1000, 1000, 1000},
+		maxDepth:             5,
+		minPointsPerChildren: 1,
+		subdivision:          SubdivisionQuadtree,
+		verticalAxis:         2,
+	}
+	if err := node.Build(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	children := node.GetChildren()
+	for i, c := range children {
+		if (c != nil) != (i < 4) {
+			t.Errorf("expected child %d to exist %v", i, i < 4)
+		}
+		if c == nil {
+			continue
+		}
+		b := c.(*GridTreeNode).bounds
+		if b.Zmin != 0 || b.Zmax != 10 {
+			t.Errorf("expected child %d to span the full vertical extent, got %v", i, b)
+		}
+		if b.Xmax-b.Xmin != 5 || b.Ymax-b.Ymin != 5 {
+			t.Errorf("expected child %d to be split horizontally, got %v", i, b)
+		}
+	}
+	if child := children[0].(*GridTreeNode); child.subdivision != SubdivisionQuadtree || child.verticalAxis != 2 {
+		t.Errorf("expected the subdivision to be propagated to the children")
+	}
+}
+
+func TestVerticalAxis(t *testing.T) {
+	for _, c := range []struct {
+		x, y, z  float64
+		expected int
+	}{
+		{6378137, 0, 0, 0},
+		{-100, -6378137, 200, 1},
+		{4000000, 700000, 4900000, 2},
+	} {
+		if actual := verticalAxis(c.x, c.y, c.z); actual != c.expected {
+			t.Errorf("expected axis %d got %d for %v, %v, %v", c.expected, actual, c.x, c.y, c.z)
+		}
+	}
+}
+
+// benchmarkReader returns a reader of n points on a regular grid of 0.5m in EPSG:32633
+func benchmarkReader(n int) *las.MockLasReader {
+	pts := make([]geom.Point64, n)
+	side := int(math.Sqrt(float64(n))) + 1
+	for i := range pts {
+		x, y := float64(i%side)*0.5, float64(i/side)*0.5
+		pts[i] = geom.Point64{X: 432000 + x, Y: 4705000 + y, Z: math.Sin(x) + math.Cos(y), Classification: 2}
+	}
+	return &las.MockLasReader{Srid: 32633, Pts: pts}
+}
+
+func BenchmarkGridTreeLoad(b *testing.B) {
+	c, err := test.GetTestCoordinateConverter()
+	if err != nil {
+		b.Fatalf("unexpected error %v", err)
+	}
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		reader := benchmarkReader(100000)
+		tree := NewGridTree(WithLoadWorkersNumber(4))
+		b.StartTimer()
+		if err := tree.Load(reader, c, nil, context.TODO()); err != nil {
+			b.Fatalf("unexpected error %v", err)
+		}
+	}
+}
+
+func BenchmarkGridTreeBuild(b *testing.B) {
+	c, err := test.GetTestCoordinateConverter()
+	if err != nil {
+		b.Fatalf("unexpected error %v", err)
+	}
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		tree := NewGridTree(WithGridSize(5), WithMinPointsPerChildren(1000), WithLoadWorkersNumber(4))
+		if err := tree.Load(benchmarkReader(100000), c, nil, context.TODO()); err != nil {
+			b.Fatalf("unexpected error %v", err)
+		}
+		b.StartTimer()
+		if err := tree.Build(); err != nil {
+			b.Fatalf("unexpected error %v", err)
+		}
+		// the children are built lazily, visit the whole tree
+		visitChildren(tree)
+	}
+}
+
+func visitChildren(n Node) {
+	for _, c := range n.GetChildren() {
+		if c != nil {
+			visitChildren(c)
+		}
+	}
+}
+
+// identityConverter returns the coordinates unchanged
+type identityConverter struct {
+	coor.CoordinateConverter
+}
+
+func (identityConverter) ToWGS84Cartesian(coord geom.Coord, sourceSrid int) (geom.Coord, error) {
+	return coord, nil
+}
+
+func TestGridTreeCoordinateRounding(t *testing.T) {
+	tree := NewGridTree(WithCoordinateRounding(0.01))
+	pt, err := tree.transformPoint(geom.Point64{X: 4566154.228810897, Y: 1153578.874392344, Z: -4286759.667199761}, identityConverter{}, nil, 4978)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	for i, c := range []struct{ actual, expected float64 }{{pt.X, 4566154.23}, {pt.Y, 1153578.87}, {pt.Z, -4286759.67}} {
+		if math.Abs(c.actual-c.expected) > 1e-6 {
+			t.Errorf("component %d: expected %v got %v", i, c.expected, c.actual)
+		}
+	}
+
+	// disabled by default
+	pt, _ = NewGridTree().transformPoint(geom.Point64{X: 1.23456}, identityConverter{}, nil, 4978)
+	if pt.X != 1.23456 {
+		t.Errorf("expected %v got %v", 1.23456, pt.X)
+	}
+}
+
+func TestGridTreeBuildRootPointTarget(t *testing.T) {
+	// a 40x40 grid of points spaced 0.25 meters apart
+	newPoints := func() *geom.LinkedPoint {
+		var pts *geom.LinkedPoint
+		for i := 0; i < 40; i++ {
+			for j := 0; j < 40; j++ {
+				pts = &geom.LinkedPoint{Pt: geom.Point32{X: float32(i)*0.25 + 0.1, Y: float32(j)*0.25 + 0.1, Z: 5}, Next: pts}
+			}
+		}
+		return pts
+	}
+	cases := []struct {
+		target   int
+		min, max int
+	}{
+		// the grid size alone retains 100 points
+		{0, 100, 100},
+		{25, 20, 25},
+		{400, 350, 400},
+	}
+	for _, c := range cases {
+		node := NewGridTree(WithGridSize(1), WithMinPointsPerChildren(1), WithRootPointTarget(c.target))
+		node.pts = newPoints()
+		node.bounds = geom.NewBoundingBox(0, 10, 0, 10, 0, 10)
+		if err := node.Build(); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if actual := node.NumberOfPoints(); actual < c.min || actual > c.max {
+			t.Errorf("target %d: expected between %d and %d points got %d", c.target, c.min, c.max, actual)
+		}
+		if actual := node.TotalNumberOfPoints(); actual != 1600 {
+			t.Errorf("target %d: expected %d points in total got %d", c.target, 1600, actual)
+		}
+		// the target only applies to the root
+		for _, child := range node.GetChildren() {
+			if child != nil {
+				if actual := child.(*GridTreeNode).gridSize; actual != [3]float64{0.5, 0.5, 0.5} {
+					t.Errorf("expected the children grid size to be unchanged got %v", actual)
+				}
+			}
+		}
+	}
+}
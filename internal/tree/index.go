@@ -0,0 +1,107 @@
+package tree
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+)
+
+// neighborIndex is a nearest neighbor index over a linked list of points, shared by the neighborhood based
+// operations performed on the same points
+type neighborIndex struct {
+	// nodes are the elements of the linked list, in list order
+	nodes []*geom.LinkedPoint
+	// coords are the coordinates of the nodes, in the same order, as indexed by the k-d tree
+	coords []geom.Point32
+	kd     *geom.KDTree
+	// active flags the nodes still belonging to the indexed points, nil if all of them do. The operations removing
+	// points deactivate them, so that the index keeps serving the remaining ones without being rebuilt.
+	active []bool
+}
+
+func newNeighborIndex(pts *geom.LinkedPoint) *neighborIndex {
+	idx := &neighborIndex{}
+	for cur := pts; cur != nil; cur = cur.Next {
+		idx.nodes = append(idx.nodes, cur)
+		idx.coords = append(idx.coords, cur.Pt)
+	}
+	idx.kd = geom.NewKDTree(idx.coords)
+	return idx
+}
+
+// activeIndices returns the positions of the active nodes, in list order
+func (idx *neighborIndex) activeIndices() []int {
+	ids := make([]int, 0, len(idx.nodes))
+	for i := range idx.nodes {
+		if idx.active == nil || idx.active[i] {
+			ids = append(ids, i)
+		}
+	}
+	return ids
+}
+
+// nearest returns up to k active nodes closest to the node at position i, itself excluded
+func (idx *neighborIndex) nearest(i int, k int) []geom.Neighbor {
+	p := idx.coords[i]
+	return idx.kd.NearestActive(p.X, p.Y, p.Z, k, i, idx.active)
+}
+
+// within returns the active nodes within the given radius from the node at position i, itself excluded
+func (idx *neighborIndex) within(i int, radius float64) []geom.Neighbor {
+	p := idx.coords[i]
+	return idx.kd.WithinActive(p.X, p.Y, p.Z, radius, i, idx.active)
+}
+
+// restrict deactivates the nodes not found in the given list, returning false if the list holds nodes that are
+// not indexed, in which case the index no longer describes the points
+func (idx *neighborIndex) restrict(pts *geom.LinkedPoint) bool {
+	positions := make(map[*geom.LinkedPoint]int, len(idx.nodes))
+	for i, n := range idx.nodes {
+		positions[n] = i
+	}
+	active := make([]bool, len(idx.nodes))
+	for cur := pts; cur != nil; cur = cur.Next {
+		i, ok := positions[cur]
+		if !ok {
+			return false
+		}
+		active[i] = true
+	}
+	idx.active = active
+	return true
+}
+
+// neighbors returns the nearest neighbor index over the current points of the node. With the cache enabled the
+// index is built once over the loaded points and shared by the outlier removal, the elevation smoothing and the
+// geometric error estimation: it is rebuilt only after the smoothing moves the points, while the points removed by
+// the outlier removal and by the sampling are just deactivated. Without the cache a new index is built on each
+// invocation.
+func (t *GridTreeNode) neighbors() *neighborIndex {
+	if !t.indexCache {
+		t.indexBuilds++
+		return newNeighborIndex(t.pts)
+	}
+	t.indexLock.Lock()
+	defer t.indexLock.Unlock()
+	if t.index == nil {
+		t.indexBuilds++
+		t.index = newNeighborIndex(t.pts)
+	}
+	return t.index
+}
+
+// restrictIndex deactivates in the cached index the nodes no longer stored by the node, dropping the index if it
+// does not cover all of them
+func (t *GridTreeNode) restrictIndex() {
+	t.indexLock.Lock()
+	defer t.indexLock.Unlock()
+	if t.index != nil && !t.index.restrict(t.pts) {
+		t.index = nil
+	}
+}
+
+// dropIndex discards the cached index, to be called whenever the points of the node move or once the last operation
+// using it has run
+func (t *GridTreeNode) dropIndex() {
+	t.indexLock.Lock()
+	defer t.indexLock.Unlock()
+	t.index = nil
+}
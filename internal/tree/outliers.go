@@ -0,0 +1,106 @@
+package tree
+
+import (
+	"math"
+	"sync"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+)
+
+// removeOutliers performs a statistical outlier removal on the linked list of points indexed by the given index.
+// For each point the mean distance to its k nearest neighbors is computed, then all points whose
+// mean distance exceeds the global mean by more than stdDevMul standard deviations are discarded.
+// The distances are computed in parallel using the given number of workers. Returns the head of the
+// filtered list, preserving the original ordering, and the number of points retained. The discarded points are
+// deactivated in the index, which can keep serving the retained ones.
+func removeOutliers(index *neighborIndex, k int, stdDevMul float64, workers int) (*geom.LinkedPoint, int) {
+	nodes := index.nodes
+	ids := index.activeIndices()
+	if len(ids) == 0 {
+		return nil, 0
+	}
+	if k <= 0 || len(ids) <= k {
+		// not enough points to compute meaningful statistics
+		return nodes[ids[0]], len(ids)
+	}
+
+	if workers < 1 {
+		workers = 1
+	}
+	meanDists := make([]float64, len(ids))
+	var wg sync.WaitGroup
+	chunk := (len(ids) + workers - 1) / workers
+	for w := 0; w < workers; w++ {
+		start := w * chunk
+		end := int(math.Min(float64(start+chunk), float64(len(ids))))
+		if start >= end {
+			break
+		}
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			for j := start; j < end; j++ {
+				sum := 0.0
+				neighbors := index.nearest(ids[j], k)
+				for _, n := range neighbors {
+					sum += math.Sqrt(n.DistSq)
+				}
+				meanDists[j] = sum / float64(len(neighbors))
+			}
+		}(start, end)
+	}
+	wg.Wait()
+
+	mean := 0.0
+	for _, d := range meanDists {
+		mean += d
+	}
+	mean /= float64(len(meanDists))
+	variance := 0.0
+	for _, d := range meanDists {
+		variance += (d - mean) * (d - mean)
+	}
+	stdDev := math.Sqrt(variance / float64(len(meanDists)))
+	threshold := mean + stdDevMul*stdDev
+
+	var head, tail *geom.LinkedPoint
+	count := 0
+	active := make([]bool, len(nodes))
+	for j, i := range ids {
+		n := nodes[i]
+		n.Next = nil
+		if meanDists[j] > threshold {
+			continue
+		}
+		active[i] = true
+		if head == nil {
+			head = n
+		} else {
+			tail.Next = n
+		}
+		tail = n
+		count++
+	}
+	if count < len(nodes) {
+		index.active = active
+	}
+	return head, count
+}
+
+// computeBounds returns the bounding box enclosing all points of the given linked list
+func computeBounds(pts *geom.LinkedPoint) geom.BoundingBox {
+	if pts == nil {
+		return geom.NewBoundingBox(0, 0, 0, 0, 0, 0)
+	}
+	minX, minY, minZ := float64(pts.Pt.X), float64(pts.Pt.Y), float64(pts.Pt.Z)
+	maxX, maxY, maxZ := minX, minY, minZ
+	for cur := pts.Next; cur != nil; cur = cur.Next {
+		minX = math.Min(float64(cur.Pt.X), minX)
+		minY = math.Min(float64(cur.Pt.Y), minY)
+		minZ = math.Min(float64(cur.Pt.Z), minZ)
+		maxX = math.Max(float64(cur.Pt.X), maxX)
+		maxY = math.Max(float64(cur.Pt.Y), maxY)
+		maxZ = math.Max(float64(cur.Pt.Z), maxZ)
+	}
+	return geom.NewBoundingBox(minX, maxX, minY, maxY, minZ, maxZ)
+}
@@ -0,0 +1,66 @@
+package tree
+
+import (
+	"testing"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+)
+
+func TestRemoveOutliers(t *testing.T) {
+	var pts *geom.LinkedPoint
+	// a regular 10x10 grid of points with 1m spacing plus two isolated points far away
+	for x := 0; x < 10; x++ {
+		for y := 0; y < 10; y++ {
+			pts = &geom.LinkedPoint{Pt: geom.Point32{X: float32(x), Y: float32(y)}, Next: pts}
+		}
+	}
+	pts = &geom.LinkedPoint{Pt: geom.Point32{X: 5, Y: 5, Z: 100}, Next: pts}
+	pts = &geom.LinkedPoint{Pt: geom.Point32{X: -50, Y: 5, Z: 0}, Next: pts}
+
+	for _, workers := range []int{1, 3} {
+		index := newNeighborIndex(copyList(pts))
+		head, count := removeOutliers(index, 4, 1, workers)
+		if count != 100 {
+			t.Errorf("expected %d points retained got %d", 100, count)
+		}
+		if active := len(index.activeIndices()); active != count {
+			t.Errorf("expected %d points active in the index got %d", count, active)
+		}
+		actual := 0
+		for cur := head; cur != nil; cur = cur.Next {
+			actual++
+			if cur.Pt.Z == 100 || cur.Pt.X == -50 {
+				t.Errorf("expected outlier %v to be removed", cur.Pt)
+			}
+		}
+		if actual != count {
+			t.Errorf("expected list of %d points got %d", count, actual)
+		}
+		bounds := computeBounds(head)
+		if bounds.Xmin != 0 || bounds.Xmax != 9 || bounds.Zmax != 0 {
+			t.Errorf("unexpected bounds %v", bounds)
+		}
+	}
+}
+
+func TestRemoveOutliersFewPoints(t *testing.T) {
+	pts := &geom.LinkedPoint{Pt: geom.Point32{X: 1}, Next: &geom.LinkedPoint{Pt: geom.Point32{X: 100}}}
+	head, count := removeOutliers(newNeighborIndex(pts), 4, 1, 1)
+	if head != pts || count != 2 {
+		t.Errorf("expected the list to be returned unchanged")
+	}
+}
+
+func copyList(pts *geom.LinkedPoint) *geom.LinkedPoint {
+	var head, tail *geom.LinkedPoint
+	for cur := pts; cur != nil; cur = cur.Next {
+		n := &geom.LinkedPoint{Pt: cur.Pt}
+		if head == nil {
+			head = n
+		} else {
+			tail.Next = n
+		}
+		tail = n
+	}
+	return head
+}
@@ -0,0 +1,65 @@
+package tree
+
+import (
+	"math"
+	"sync"
+)
+
+// smoothElevations smooths the elevation of the points indexed by the given index, replacing in each iteration
+// the elevation of each point with the average of the elevations of the points within the given radius, itself
+// included, weighted by 1 - distance / radius. The elevation is measured along the given unit vertical vector,
+// along which the points are moved. The neighborhoods are the ones of the points before the smoothing, found in
+// the index, and the averages are computed in parallel using the given number of workers. Returns true if any
+// point has been moved, making the index stale.
+func smoothElevations(index *neighborIndex, up [3]float64, radius float64, iterations int, workers int) bool {
+	nodes, coords := index.nodes, index.coords
+	ids := index.activeIndices()
+	if len(ids) == 0 || radius <= 0 || iterations <= 0 {
+		return false
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	heights := make([]float64, len(nodes))
+	for _, i := range ids {
+		p := coords[i]
+		heights[i] = float64(p.X)*up[0] + float64(p.Y)*up[1] + float64(p.Z)*up[2]
+	}
+	smoothed := make([]float64, len(nodes))
+	chunk := (len(ids) + workers - 1) / workers
+	for it := 0; it < iterations; it++ {
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			start := w * chunk
+			end := int(math.Min(float64(start+chunk), float64(len(ids))))
+			if start >= end {
+				break
+			}
+			wg.Add(1)
+			go func(start, end int) {
+				defer wg.Done()
+				for _, i := range ids[start:end] {
+					sum, weights := heights[i], 1.0
+					for _, n := range index.within(i, radius) {
+						w := 1 - math.Sqrt(n.DistSq)/radius
+						sum += w * heights[n.Index]
+						weights += w
+					}
+					smoothed[i] = sum / weights
+				}
+			}(start, end)
+		}
+		wg.Wait()
+		heights, smoothed = smoothed, heights
+	}
+	moved := false
+	for _, i := range ids {
+		n, p := nodes[i], coords[i]
+		delta := heights[i] - (float64(p.X)*up[0] + float64(p.Y)*up[1] + float64(p.Z)*up[2])
+		n.Pt.X = float32(float64(p.X) + delta*up[0])
+		n.Pt.Y = float32(float64(p.Y) + delta*up[1])
+		n.Pt.Z = float32(float64(p.Z) + delta*up[2])
+		moved = moved || n.Pt != p
+	}
+	return moved
+}
@@ -0,0 +1,81 @@
+package tree
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+)
+
+func TestSmoothElevations(t *testing.T) {
+	// a regular 10x10 grid of points with 1m spacing and a checkerboard noise of +-0.2m
+	var pts *geom.LinkedPoint
+	for x := 0; x < 10; x++ {
+		for y := 0; y < 10; y++ {
+			z := float32(0.2)
+			if (x+y)%2 == 1 {
+				z = -0.2
+			}
+			pts = &geom.LinkedPoint{Pt: geom.Point32{X: float32(x), Y: float32(y), Z: z}, Next: pts}
+		}
+	}
+	deviation := func(pts *geom.LinkedPoint) float64 {
+		sum := 0.0
+		for cur := pts; cur != nil; cur = cur.Next {
+			sum += float64(cur.Pt.Z) * float64(cur.Pt.Z)
+		}
+		return math.Sqrt(sum / 100)
+	}
+	up := [3]float64{0, 0, 1}
+	prev := deviation(pts)
+	for _, iterations := range []int{1, 3} {
+		for _, workers := range []int{1, 3} {
+			smoothed := copyList(pts)
+			smoothElevations(newNeighborIndex(smoothed), up, 1.5, iterations, workers)
+			actual := deviation(smoothed)
+			if actual >= prev*0.5 {
+				t.Errorf("%d iterations, %d workers: expected the noise to be reduced below %f got %f", iterations, workers, prev*0.5, actual)
+			}
+			for cur, orig := smoothed, pts; cur != nil; cur, orig = cur.Next, orig.Next {
+				if cur.Pt.X != orig.Pt.X || cur.Pt.Y != orig.Pt.Y {
+					t.Errorf("expected the point %v to move vertically only, got %v", orig.Pt, cur.Pt)
+				}
+			}
+		}
+	}
+	// more iterations smooth more
+	once, thrice := copyList(pts), copyList(pts)
+	smoothElevations(newNeighborIndex(once), up, 1.5, 1, 1)
+	smoothElevations(newNeighborIndex(thrice), up, 1.5, 3, 1)
+	if deviation(thrice) >= deviation(once) {
+		t.Errorf("expected 3 iterations to smooth more than one, got %f and %f", deviation(thrice), deviation(once))
+	}
+	// a zero radius disables the smoothing
+	unchanged := copyList(pts)
+	smoothElevations(newNeighborIndex(unchanged), up, 0, 3, 1)
+	if deviation(unchanged) != prev {
+		t.Errorf("expected the points to be unchanged")
+	}
+}
+
+func TestSmoothElevationsAlongVertical(t *testing.T) {
+	// two points on a plane orthogonal to a tilted vertical, plus one raised along it
+	up := [3]float64{math.Sqrt2 / 2, 0, math.Sqrt2 / 2}
+	pts := &geom.LinkedPoint{Pt: geom.Point32{X: 0, Y: 0, Z: 0}}
+	pts.Next = &geom.LinkedPoint{Pt: geom.Point32{X: 0, Y: 1, Z: 0}}
+	pts.Next.Next = &geom.LinkedPoint{Pt: geom.Point32{X: 0.3, Y: 0.5, Z: 0.3}}
+	smoothElevations(newNeighborIndex(pts), up, 10, 50, 1)
+	heights := []float64{}
+	for cur := pts; cur != nil; cur = cur.Next {
+		heights = append(heights, float64(cur.Pt.X)*up[0]+float64(cur.Pt.Y)*up[1]+float64(cur.Pt.Z)*up[2])
+		// the horizontal offset, orthogonal to the vertical, is preserved
+		if h := float64(cur.Pt.X)*up[2] - float64(cur.Pt.Z)*up[0]; math.Abs(h) > 1e-6 {
+			t.Errorf("expected the point %v to move along the vertical", cur.Pt)
+		}
+	}
+	for _, h := range heights[1:] {
+		if math.Abs(h-heights[0]) > 1e-3 {
+			t.Errorf("expected the heights to converge, got %v", heights)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package tree
+
+import (
+	"math"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/conv/coor"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+)
+
+// meanSpacing returns the mean distance of the points indexed by the given index from their nearest neighbor.
+// Returns false if there are less than two points.
+func meanSpacing(index *neighborIndex) (float64, bool) {
+	ids := index.activeIndices()
+	if len(ids) < 2 {
+		return 0, false
+	}
+	sum := 0.0
+	for _, i := range ids {
+		nearest := index.nearest(i, 1)
+		if len(nearest) > 0 {
+			sum += math.Sqrt(nearest[0].DistSq)
+		}
+	}
+	return sum / float64(len(ids)), true
+}
+
+const (
+	// spacingSubtreePoints is the maximum number of points of the subtrees sampled by EstimatePointSpacing
+	spacingSubtreePoints = 20000
+	// spacingSampledSubtrees is the maximum number of subtrees sampled by EstimatePointSpacing
+	spacingSampledSubtrees = 16
+	// spacingSubtreeQueries is the maximum number of points of each sampled subtree whose nearest neighbor is searched
+	spacingSubtreeQueries = 1000
+)
+
+// EstimatePointSpacing estimates the average distance, in meters, between the points of the tree rooted at the
+// given node and their nearest neighbor, at full resolution. Rather than indexing all the points, it samples up to
+// spacingSampledSubtrees subtrees, evenly spread among the largest ones storing at most spacingSubtreePoints points,
+// indexes all the points of each sampled subtree and measures the nearest neighbor distance of a subset of them.
+// The points stored in the ancestors of the subtrees are ignored, slightly overestimating the spacing.
+// Returns false if the sampled subtrees have less than two points.
+func EstimatePointSpacing(root Node, conv coor.CoordinateConverter) (float64, bool, error) {
+	subtrees := []Node{}
+	var find func(n Node)
+	find = func(n Node) {
+		if n.TotalNumberOfPoints() <= spacingSubtreePoints {
+			if n.TotalNumberOfPoints() > 1 {
+				subtrees = append(subtrees, n)
+			}
+			return
+		}
+		for _, child := range n.GetChildren() {
+			if child != nil {
+				find(child)
+			}
+		}
+	}
+	find(root)
+	sum, count := 0.0, 0
+	step := math.Max(1, float64(len(subtrees))/spacingSampledSubtrees)
+	for i := 0.0; int(i) < len(subtrees); i += step {
+		coords, err := subtreeCoordinates(subtrees[int(i)], conv)
+		if err != nil {
+			return 0, false, err
+		}
+		kd := geom.NewKDTree(coords)
+		queryStep := math.Max(1, float64(len(coords))/spacingSubtreeQueries)
+		for j := 0.0; int(j) < len(coords); j += queryStep {
+			p := coords[int(j)]
+			if nearest := kd.Nearest(p.X, p.Y, p.Z, 1, int(j)); len(nearest) > 0 {
+				sum += math.Sqrt(nearest[0].DistSq)
+				count++
+			}
+		}
+	}
+	if count == 0 {
+		return 0, false, nil
+	}
+	return sum / float64(count), true, nil
+}
+
+// subtreeCoordinates returns the coordinates of all the points of the subtree rooted at the given node, relative
+// to its center
+func subtreeCoordinates(node Node, conv coor.CoordinateConverter) ([]geom.Point32, error) {
+	cX, cY, cZ, err := node.GetCenter(conv)
+	if err != nil {
+		return nil, err
+	}
+	coords := []geom.Point32{}
+	var collect func(n Node) error
+	collect = func(n Node) error {
+		x, y, z, err := n.GetCenter(conv)
+		if err != nil {
+			return err
+		}
+		pts := n.GetPoints(conv)
+		pts.Reset()
+		for i := 0; i < pts.Len(); i++ {
+			pt, err := pts.Next()
+			if err != nil {
+				return err
+			}
+			pt.X = float32(float64(pt.X) + x - cX)
+			pt.Y = float32(float64(pt.Y) + y - cY)
+			pt.Z = float32(float64(pt.Z) + z - cZ)
+			coords = append(coords, pt)
+		}
+		pts.Reset()
+		for _, child := range n.GetChildren() {
+			if child != nil {
+				if err := collect(child); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := collect(node); err != nil {
+		return nil, err
+	}
+	return coords, nil
+}
@@ -0,0 +1,54 @@
+package tree
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+)
+
+// newGridNode returns a node storing the given rows of a grid of points with the given spacing, referred to the
+// given center
+func newGridNode(firstRow, rows, cols int, spacing float64, centerY float64) *MockNode {
+	var head *geom.LinkedPoint
+	for i := firstRow; i < firstRow+rows; i++ {
+		for j := 0; j < cols; j++ {
+			y := float64(i)*spacing - centerY
+			head = &geom.LinkedPoint{Pt: geom.NewPoint32(float32(float64(j)*spacing), float32(y), 0, 0, 0, 0, 0, 0), Next: head}
+		}
+	}
+	return &MockNode{
+		Pts:         geom.NewLinkedPointStream(head, rows*cols),
+		TotalNumPts: rows * cols,
+		CenterY:     centerY,
+		Leaf:        true,
+	}
+}
+
+func TestEstimatePointSpacing(t *testing.T) {
+	// the first row of the grid is stored in the root, the others in a child with a different center
+	root := newGridNode(0, 1, 10, 2, 0)
+	child := newGridNode(1, 9, 10, 2, 50)
+	root.Children = [8]Node{nil, child}
+	root.TotalNumPts = 100
+	root.Leaf = false
+
+	spacing, ok, err := EstimatePointSpacing(root, nil)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !ok || math.Abs(spacing-2) > 1e-6 {
+		t.Errorf("expected spacing %v got %v (%v)", 2, spacing, ok)
+	}
+	// the points can be read again afterwards
+	if actual := root.Pts.Len(); actual != 10 {
+		t.Errorf("expected %d points in the root got %d", 10, actual)
+	}
+	if _, err := root.Pts.Next(); err != nil {
+		t.Errorf("unexpected error reading the points again %v", err)
+	}
+
+	if _, ok, err := EstimatePointSpacing(newGridNode(0, 1, 1, 1, 0), nil); ok || err != nil {
+		t.Errorf("expected no spacing for a single point, got %v (%v)", ok, err)
+	}
+}
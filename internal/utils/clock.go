@@ -0,0 +1,25 @@
+package utils
+
+import "time"
+
+// Clock is the source of the time used to measure the elapsed times and to schedule the periodic reports, so that
+// they can be controlled in the tests
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+	// NewTicker returns a channel receiving the current time at every interval, dropping the ticks for slow
+	// receivers as time.Ticker does, and the function stopping the ticks
+	NewTicker(interval time.Duration) (<-chan time.Time, func())
+}
+
+// SystemClock is the Clock reading the system time
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (SystemClock) NewTicker(interval time.Duration) (<-chan time.Time, func()) {
+	t := time.NewTicker(interval)
+	return t.C, t.Stop
+}
@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMockClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewMockClock(start)
+	ticks, stop := c.NewTicker(time.Second)
+	c.Advance(500 * time.Millisecond)
+	if elapsed := c.Now().Sub(start); elapsed != 500*time.Millisecond {
+		t.Errorf("expected %v elapsed got %v", 500*time.Millisecond, elapsed)
+	}
+	select {
+	case <-ticks:
+		t.Errorf("expected no tick before the interval elapsed")
+	default:
+	}
+	// the ticks not received are dropped
+	c.Advance(3 * time.Second)
+	if tick := <-ticks; !tick.Equal(start.Add(3500 * time.Millisecond)) {
+		t.Errorf("expected tick at %v got %v", start.Add(3500*time.Millisecond), tick)
+	}
+	select {
+	case <-ticks:
+		t.Errorf("expected a single tick to be delivered")
+	default:
+	}
+	c.Advance(500 * time.Millisecond)
+	if tick := <-ticks; !tick.Equal(start.Add(4 * time.Second)) {
+		t.Errorf("expected tick at %v got %v", start.Add(4*time.Second), tick)
+	}
+	if n := c.Tickers(); n != 1 {
+		t.Errorf("expected %d tickers got %d", 1, n)
+	}
+	stop()
+	c.Advance(time.Second)
+	select {
+	case <-ticks:
+		t.Errorf("expected no tick after stopping the ticker")
+	default:
+	}
+	if n := c.Tickers(); n != 0 {
+		t.Errorf("expected %d tickers got %d", 0, n)
+	}
+}
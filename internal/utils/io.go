@@ -1,6 +1,9 @@
 package utils
 
 import (
+	"bufio"
+	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
@@ -24,6 +27,27 @@ func TouchFile(path string) error {
 	return f.Close()
 }
 
+// FilterFilesByPattern returns the files whose base name matches the given glob pattern,
+// using the syntax of filepath.Match. The match is case insensitive, consistently with
+// the detection of the LAS file extension. An empty pattern matches all files.
+func FilterFilesByPattern(files []string, pattern string) ([]string, error) {
+	if pattern == "" {
+		return files, nil
+	}
+	pattern = strings.ToLower(pattern)
+	filtered := []string{}
+	for _, f := range files {
+		match, err := filepath.Match(pattern, strings.ToLower(filepath.Base(f)))
+		if err != nil {
+			return nil, err
+		}
+		if match {
+			filtered = append(filtered, f)
+		}
+	}
+	return filtered, nil
+}
+
 func FindLasFilesInFolder(directory string) ([]string, error) {
 	if _, err := os.Stat(directory); err != nil {
 		return nil, err
@@ -34,19 +58,70 @@ func FindLasFilesInFolder(directory string) ([]string, error) {
 	}
 	files := []string{}
 	for _, e := range entries {
-		if e.IsDir() {
+		if e.IsDir() || !isLasFileName(e.Name()) {
 			continue
 		}
-		lastIndex := -1
-		name := e.Name()
-		if lastIndex = strings.LastIndex(name, "."); lastIndex != -1 {
-			ext := e.Name()[lastIndex+1:]
-			if strings.ToLower(ext) != "las" {
-				continue
-			}
-		}
 		f := filepath.Join(directory, e.Name())
 		files = append(files, f)
 	}
 	return files, nil
 }
+
+// FindLasFilesInFolderRecursive returns the LAS files in the directory and in all its subdirectories, in lexical
+// order of their paths
+func FindLasFilesInFolderRecursive(directory string) ([]string, error) {
+	if _, err := os.Stat(directory); err != nil {
+		return nil, err
+	}
+	files := []string{}
+	err := filepath.WalkDir(directory, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && isLasFileName(d.Name()) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// isLasFileName returns whether the file name has the las extension, ignoring the case, or no extension at all
+func isLasFileName(name string) bool {
+	if lastIndex := strings.LastIndex(name, "."); lastIndex != -1 {
+		return strings.ToLower(name[lastIndex+1:]) == "las"
+	}
+	return true
+}
+
+// ReadFileList reads a text file listing one file path per line. Blank lines and lines starting with #
+// are ignored. Relative paths are resolved against the folder containing the list file.
+func ReadFileList(listPath string) ([]string, error) {
+	f, err := os.Open(listPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	files := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !filepath.IsAbs(line) {
+			line = filepath.Join(filepath.Dir(listPath), line)
+		}
+		files = append(files, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files listed in %s", listPath)
+	}
+	return files, nil
+}
@@ -34,3 +34,101 @@ func TestFindLasFilesInFolder(t *testing.T) {
 		t.Errorf("expected %v got %v", expected, files)
 	}
 }
+
+func TestFindLasFilesInFolderRecursive(t *testing.T) {
+	tmp := t.TempDir()
+	for _, dir := range []string{"a", filepath.Join("a", "b"), "c"} {
+		if err := os.MkdirAll(filepath.Join(tmp, dir), 0777); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+	TouchFile(filepath.Join(tmp, "scan.las"))
+	TouchFile(filepath.Join(tmp, "a", "scan.las"))
+	TouchFile(filepath.Join(tmp, "a", "b", "scan.LAS"))
+	TouchFile(filepath.Join(tmp, "a", "b", "notes.txt"))
+	TouchFile(filepath.Join(tmp, "c", "other.las"))
+
+	files, err := FindLasFilesInFolderRecursive(tmp)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	expected := []string{
+		filepath.Join(tmp, "a", "b", "scan.LAS"),
+		filepath.Join(tmp, "a", "scan.las"),
+		filepath.Join(tmp, "c", "other.las"),
+		filepath.Join(tmp, "scan.las"),
+	}
+	if !reflect.DeepEqual(expected, files) {
+		t.Errorf("expected %v got %v", expected, files)
+	}
+
+	if _, err := FindLasFilesInFolderRecursive(filepath.Join(tmp, "missing")); err == nil {
+		t.Errorf("expected an error for a missing folder")
+	}
+}
+
+func TestFilterFilesByPattern(t *testing.T) {
+	files := []string{
+		filepath.Join("a", "tile_001.las"),
+		filepath.Join("tile_00", "other.las"),
+		filepath.Join("b", "tile_002.LAS"),
+		filepath.Join("b", "tile_010.las"),
+	}
+	actual, err := FilterFilesByPattern(files, "tile_00*")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	expected := []string{files[0], files[2]}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v got %v", expected, actual)
+	}
+
+	actual, err = FilterFilesByPattern(files, "TILE_00*.las")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v got %v", expected, actual)
+	}
+
+	actual, err = FilterFilesByPattern(files, "")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(files, actual) {
+		t.Errorf("expected %v got %v", files, actual)
+	}
+
+	if _, err = FilterFilesByPattern(files, "[a-"); err == nil {
+		t.Errorf("expected error on malformed pattern, got none")
+	}
+}
+
+func TestReadFileList(t *testing.T) {
+	tmp := t.TempDir()
+	abs := filepath.Join(tmp, "abs.las")
+	list := filepath.Join(tmp, "files.txt")
+	content := "# input files\n\nsub/a.las\n  " + abs + "  \r\n#b.las\n"
+	if err := os.WriteFile(list, []byte(content), 0644); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	actual, err := ReadFileList(list)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	expected := []string{filepath.Join(tmp, "sub", "a.las"), abs}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v got %v", expected, actual)
+	}
+
+	empty := filepath.Join(tmp, "empty.txt")
+	if err := os.WriteFile(empty, []byte("# nothing\n\n"), 0644); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, err := ReadFileList(empty); err == nil {
+		t.Errorf("expected error on empty list, got none")
+	}
+	if _, err := ReadFileList(filepath.Join(tmp, "missing.txt")); err == nil {
+		t.Errorf("expected error on missing list, got none")
+	}
+}
@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// MockClock is a Clock whose time only changes when advanced with Advance, firing the tickers whose interval
+// elapsed in the meantime. It is safe for concurrent use.
+type MockClock struct {
+	now     time.Time
+	tickers []*mockTicker
+	sync.Mutex
+}
+
+type mockTicker struct {
+	c        chan time.Time
+	interval time.Duration
+	next     time.Time
+	stopped  bool
+}
+
+func NewMockClock(now time.Time) *MockClock {
+	return &MockClock{now: now}
+}
+
+func (c *MockClock) Now() time.Time {
+	c.Lock()
+	defer c.Unlock()
+	return c.now
+}
+
+func (c *MockClock) NewTicker(interval time.Duration) (<-chan time.Time, func()) {
+	c.Lock()
+	defer c.Unlock()
+	t := &mockTicker{c: make(chan time.Time, 1), interval: interval, next: c.now.Add(interval)}
+	c.tickers = append(c.tickers, t)
+	return t.c, func() {
+		c.Lock()
+		defer c.Unlock()
+		t.stopped = true
+	}
+}
+
+// Tickers returns the number of tickers created and not stopped yet
+func (c *MockClock) Tickers() int {
+	c.Lock()
+	defer c.Unlock()
+	n := 0
+	for _, t := range c.tickers {
+		if !t.stopped {
+			n++
+		}
+	}
+	return n
+}
+
+// Advance moves the time forward by d, sending a tick to the tickers whose interval elapsed. As with time.Ticker,
+// the ticks are dropped if the previous ones have not been received yet.
+func (c *MockClock) Advance(d time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+	c.now = c.now.Add(d)
+	for _, t := range c.tickers {
+		if t.stopped || t.next.After(c.now) {
+			continue
+		}
+		select {
+		case t.c <- c.now:
+		default:
+		}
+		for !t.next.After(c.now) {
+			t.next = t.next.Add(t.interval)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package writer
+
+import (
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/conv/coor"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/tree"
+)
+
+// consolidatedNode exposes a tree node storing, besides its own points, the points of the descendants merged into it
+// because their content was too small
+type consolidatedNode struct {
+	tree.Node
+	children [8]tree.Node
+	// merged are the nodes whose points are stored in this node
+	merged    []tree.Node
+	numPoints int
+}
+
+// consolidate returns the given root node where, bottom up, the leaves whose content would be smaller than minBytes,
+// as estimated by contentSize from their number of points, are merged into their parent. A parent left without
+// children becomes a leaf, merged in turn into its own parent if its content is still too small.
+func consolidate(root tree.Node, minBytes int64, contentSize func(numPoints int) int64) tree.Node {
+	return newConsolidatedNode(root, minBytes, contentSize)
+}
+
+func newConsolidatedNode(node tree.Node, minBytes int64, contentSize func(numPoints int) int64) *consolidatedNode {
+	n := &consolidatedNode{Node: node, numPoints: node.NumberOfPoints()}
+	for i, child := range node.GetChildren() {
+		if child == nil || child.TotalNumberOfPoints() == 0 {
+			continue
+		}
+		c := newConsolidatedNode(child, minBytes, contentSize)
+		if !c.IsLeaf() || contentSize(c.numPoints) >= minBytes {
+			n.children[i] = c
+			continue
+		}
+		n.merged = append(n.merged, c.Node)
+		n.merged = append(n.merged, c.merged...)
+		n.numPoints += c.numPoints
+	}
+	return n
+}
+
+func (n *consolidatedNode) GetChildren() [8]tree.Node {
+	return n.children
+}
+
+func (n *consolidatedNode) IsLeaf() bool {
+	for _, child := range n.children {
+		if child != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func (n *consolidatedNode) NumberOfPoints() int {
+	return n.numPoints
+}
+
+func (n *consolidatedNode) TotalNumberOfPoints() int {
+	total := n.numPoints
+	for _, child := range n.children {
+		if child != nil {
+			total += child.TotalNumberOfPoints()
+		}
+	}
+	return total
+}
+
+func (n *consolidatedNode) GetPoints(converter coor.CoordinateConverter) geom.Point32List {
+	if len(n.merged) == 0 {
+		return n.Node.GetPoints(converter)
+	}
+	return mergePoints(n.Node, append([]tree.Node{n.Node}, n.merged...), converter)
+}
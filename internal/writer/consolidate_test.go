@@ -0,0 +1,144 @@
+package writer
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/conv/coor"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/tree"
+)
+
+// newConsolidateTestNode returns a node with the given number of points, all at its center
+func newConsolidateTestNode(numPoints int, centerX float64, children ...tree.Node) *tree.MockNode {
+	var head *geom.LinkedPoint
+	for i := 0; i < numPoints; i++ {
+		head = &geom.LinkedPoint{Pt: geom.NewPoint32(0, 0, 0, 1, 2, 3, 4, 5), Next: head}
+	}
+	n := &tree.MockNode{
+		Pts:         geom.NewLinkedPointStream(head, numPoints),
+		TotalNumPts: numPoints,
+		Leaf:        len(children) == 0,
+		CenterX:     centerX,
+	}
+	for i, child := range children {
+		n.Children[i] = child
+		n.TotalNumPts += child.TotalNumberOfPoints()
+	}
+	return n
+}
+
+func TestConsolidate(t *testing.T) {
+	// contents of 1 byte per point, the leaves with less than 10 points are merged
+	size := func(numPoints int) int64 { return int64(numPoints) }
+	smallLeaf := newConsolidateTestNode(3, 1)
+	bigLeaf := newConsolidateTestNode(20, 2)
+	tinyLeaf := newConsolidateTestNode(1, 3)
+	// a small subtree, entirely merged into the root
+	smallInner := newConsolidateTestNode(2, 4, newConsolidateTestNode(2, 5), newConsolidateTestNode(1, 6))
+	inner := newConsolidateTestNode(5, 7, smallLeaf, bigLeaf, tinyLeaf)
+	src := newConsolidateTestNode(4, 0, inner, smallInner)
+	src.Root = true
+
+	root := consolidate(src, 10, size)
+	children := root.GetChildren()
+	if children[1] != nil {
+		t.Errorf("expected the small subtree to be merged into the root")
+	}
+	if root.NumberOfPoints() != 4+5 || root.TotalNumberOfPoints() != src.TotalNumPts {
+		t.Errorf("expected the root to store %d of %d points, got %d of %d", 9, src.TotalNumPts, root.NumberOfPoints(), root.TotalNumberOfPoints())
+	}
+	if actual := root.GetPoints(nil).Len(); actual != 9 {
+		t.Errorf("expected the root to return %d points got %d", 9, actual)
+	}
+	c := children[0]
+	if c == nil || c.IsLeaf() || c.NumberOfPoints() != 5+3+1 {
+		t.Fatalf("expected the inner node to store %d points, got %v", 9, c)
+	}
+	grandchildren := c.GetChildren()
+	if grandchildren[0] != nil || grandchildren[2] != nil || grandchildren[1] == nil || !grandchildren[1].IsLeaf() {
+		t.Errorf("expected only the big leaf to be kept, got %v", grandchildren)
+	}
+	if countTiles(root) != 3 {
+		t.Errorf("expected 3 tiles got %d", countTiles(root))
+	}
+
+	// the merged points are referred to the center of the node storing them
+	pts := c.GetPoints(nil)
+	xs := map[float32]int{}
+	for i := 0; i < pts.Len(); i++ {
+		pt, err := pts.Next()
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		xs[pt.X]++
+	}
+	if xs[0] != 5 || xs[-6] != 3 || xs[-4] != 1 {
+		t.Errorf("unexpected merged points %v", xs)
+	}
+
+	// nothing is merged if all contents are big enough
+	if root := consolidate(src, 1, size); countTiles(root) != 8 || root.NumberOfPoints() != 4 {
+		t.Errorf("expected the tree to be unchanged, got %d tiles", countTiles(root))
+	}
+}
+
+func TestContentSize(t *testing.T) {
+	for _, opt := range []func(*StandardConsumer){
+		func(c *StandardConsumer) {},
+		func(c *StandardConsumer) { c.quantizedPositions = true },
+		func(c *StandardConsumer) { c.sourceFileAttribute = true; c.bufferAlignment = 8 },
+		func(c *StandardConsumer) { c.classAlpha = map[uint8]uint8{2: 0} },
+		func(c *StandardConsumer) { c.perPointBatchId = true },
+	} {
+		for _, n := range []int{1, 7, 100} {
+			s := &MockStorage{}
+			c := NewStandardConsumer(nil, s, opt).(*StandardConsumer)
+			node := newConsolidateTestNode(n, 6378137)
+			node.CenterY, node.CenterZ = 1000, -2000
+			if err := c.writeBinaryPntsFile(WorkUnit{Node: node, BasePath: "tile"}); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			actual := int64(len(s.Files["tile/content.pnts"]))
+			// only the lengths of the numbers in the json headers may differ
+			if estimate := c.contentSize(n); math.Abs(float64(estimate-actual)) > 64 {
+				t.Errorf("%d points: expected a size close to %d got %d", n, actual, estimate)
+			}
+		}
+	}
+}
+
+func TestWriterWithMinContentBytes(t *testing.T) {
+	leaf := newConsolidateTestNode(1, 1)
+	src := newConsolidateTestNode(1, 0, leaf)
+	src.Root = true
+	for _, c := range []struct {
+		minBytes int64
+		tiles    int
+	}{{0, 2}, {10000, 1}} {
+		w, err := NewWriter("base", nil, WithMinContentBytes(c.minBytes))
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		w.producerFunc = func(basepath, folder string) Producer {
+			return &MockProducer{}
+		}
+		w.consumerFunc = func(cc coor.CoordinateConverter, s Storage) Consumer {
+			return &MockConsumer{}
+		}
+		w.storageProvider = func(root string) (Storage, error) {
+			return &MockStorage{}, nil
+		}
+		total := 0
+		w.progress = func(done, tiles int) {
+			total = tiles
+		}
+		if err := w.Write(src, "base", context.TODO()); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if total != c.tiles {
+			t.Errorf("min bytes %d: expected %d tiles got %d", c.minBytes, c.tiles, total)
+		}
+	}
+}
@@ -1,12 +1,12 @@
 package writer
 
 import (
-	"bufio"
+	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"os"
+	"math"
 	"path"
 	"strconv"
 	"strings"
@@ -23,13 +23,51 @@ type Consumer interface {
 }
 
 type StandardConsumer struct {
-	conv coor.CoordinateConverter
+	conv          coor.CoordinateConverter
+	storage       Storage
+	assetExtras   map[string]interface{}
+	prettyTileset bool
+	// properties are the ranges of the point properties stored in the root tileset.json
+	properties map[string]Property
+	// quantizedPositions stores the positions as 16 bit integers over the bounding box of the tile points
+	quantizedPositions bool
+	// sourceFileAttribute stores the index of the source file of each point in the _SOURCE_FILE batch table property
+	sourceFileAttribute bool
+	// pointSourceIdAttribute stores the point source ID of each point in the POINT_SOURCE_ID batch table property
+	pointSourceIdAttribute bool
+	// bufferAlignment is the alignment in bytes of the tables of the content.pnts files, 0 for the default layout
+	bufferAlignment int
+	// contentBoundingVolumes stores the region enclosing the points of each content.pnts in its content object
+	contentBoundingVolumes bool
+	// geometricErrorScale multiplies the geometric errors of the nodes
+	geometricErrorScale float64
+	// classAlpha maps the classifications to the alpha of the colors, if set the colors are stored as RGBA
+	classAlpha map[uint8]uint8
+	// contentFile is the name of the content files of the tiles, content.pnts by default
+	contentFile string
+	// perPointBatchId stores the index of each point in the BATCH_ID of the feature table, making each point a feature
+	perPointBatchId bool
 }
 
-func NewStandardConsumer(coordinateConverter coor.CoordinateConverter) Consumer {
-	return &StandardConsumer{
-		conv: coordinateConverter,
+// quantizationVolume is the box over which the positions of the points of a tile are quantized,
+// relative to the RTC_CENTER of the tile
+type quantizationVolume struct {
+	offset [3]float64
+	scale  [3]float64
+}
+
+func NewStandardConsumer(coordinateConverter coor.CoordinateConverter, storage Storage, options ...func(*StandardConsumer)) Consumer {
+	c := &StandardConsumer{
+		conv:                coordinateConverter,
+		storage:             storage,
+		prettyTileset:       true,
+		geometricErrorScale: 1,
+		contentFile:         defaultContentFile,
+	}
+	for _, optFn := range options {
+		optFn(c)
 	}
+	return c
 }
 
 // Continually consumes WorkUnits submitted to a work channel producing corresponding content.pnts files and tileset.json files
@@ -81,12 +119,6 @@ func (c *StandardConsumer) writeBinaryPntsFile(workUnit WorkUnit) error {
 	parentFolder := workUnit.BasePath
 	node := workUnit.Node
 
-	// Create base folder if it does not exist
-	err := utils.CreateDirectoryIfDoesNotExist(parentFolder)
-	if err != nil {
-		return err
-	}
-
 	pts := node.GetPoints(c.conv)
 	cX, cY, cZ, err := node.GetCenter(c.conv)
 	if err != nil {
@@ -99,22 +131,46 @@ func (c *StandardConsumer) writeBinaryPntsFile(workUnit WorkUnit) error {
 	}
 
 	// Feature table
+	var volume *quantizationVolume
 	featureTableBytes, featureTableLen := c.generateFeatureTable(averageXYZ[0], averageXYZ[1], averageXYZ[2], pts.Len())
+	if c.quantizedPositions {
+		volume, err = c.computeQuantizationVolume(pts, averageXYZ, cX, cY, cZ)
+		if err != nil {
+			return err
+		}
+		featureTableBytes, featureTableLen = c.generateQuantizedFeatureTable(averageXYZ[0], averageXYZ[1], averageXYZ[2], volume, pts.Len())
+	}
+
+	featureTableBytes = c.alignTable(featureTableBytes, pntsHeaderLength)
+	featureTableLen = len(featureTableBytes)
 
 	// Batch table
 	batchTableBytes, batchTableLen := c.generateBatchTable(pts.Len())
+	batchTableBytes = c.alignTable(batchTableBytes, 0)
+	batchTableLen = len(batchTableBytes)
 
-	// Write binary content to file
-	pntsFilePath := path.Join(parentFolder, "content.pnts")
-	f, err := os.Create(pntsFilePath)
+	// Stream binary content to the storage
+	pntsFilePath := path.Join(parentFolder, c.contentFile)
+	w, err := c.storage.Create(pntsFilePath)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-
-	w := bufio.NewWriter(f)
+	err = c.writePnts(pts, averageXYZ, cX, cY, cZ, volume, featureTableBytes, featureTableLen, batchTableBytes, batchTableLen, w)
+	if err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
 
-	err = c.writePntsHeader(pts.Len(), featureTableLen, batchTableLen, w)
+// Writes the content of a content.pnts file to the given writer
+// If a quantization volume is given, the positions are quantized over it.
+func (c *StandardConsumer) writePnts(pts geom.Point32List, averageXYZ []float64, cX, cY, cZ float64, volume *quantizationVolume, featureTableBytes []byte, featureTableLen int, batchTableBytes []byte, batchTableLen int, w io.Writer) error {
+	positionSize := 12
+	if volume != nil {
+		positionSize = 6
+	}
+	err := c.writePntsHeader(pts.Len(), positionSize, featureTableLen, batchTableLen, w)
 	if err != nil {
 		return err
 	}
@@ -123,7 +179,11 @@ func (c *StandardConsumer) writeBinaryPntsFile(workUnit WorkUnit) error {
 		return err
 	}
 
-	err = c.writePointCoords(pts, averageXYZ, cX, cY, cZ, w)
+	if volume != nil {
+		err = c.writeQuantizedPointCoords(pts, averageXYZ, cX, cY, cZ, volume, w)
+	} else {
+		err = c.writePointCoords(pts, averageXYZ, cX, cY, cZ, w)
+	}
 	if err != nil {
 		return err
 	}
@@ -133,6 +193,18 @@ func (c *StandardConsumer) writeBinaryPntsFile(workUnit WorkUnit) error {
 		return err
 	}
 
+	if c.perPointBatchId {
+		err = c.writePointBatchIds(pts.Len(), positionSize, w)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = c.writePadding(c.featureTableBinaryLength(pts.Len(), positionSize), w)
+	if err != nil {
+		return err
+	}
+
 	err = c.writeTable(batchTableBytes, w)
 	if err != nil {
 		return err
@@ -148,12 +220,110 @@ func (c *StandardConsumer) writeBinaryPntsFile(workUnit WorkUnit) error {
 		return err
 	}
 
-	err = w.Flush()
-	if err != nil {
-		return err
+	if c.sourceFileAttribute {
+		err = c.writePointSourceFiles(pts, w)
+		if err != nil {
+			return err
+		}
 	}
 
-	return nil
+	if c.pointSourceIdAttribute {
+		err = c.writePointSourceIds(pts, w)
+		if err != nil {
+			return err
+		}
+	}
+	return c.writePadding(c.batchTableBinaryLength(pts.Len()), w)
+}
+
+// contentSize estimates the size in bytes of the content.pnts file storing the given number of points. The lengths
+// of the json headers depend on the coordinates of the tile, which are approximated with the radius of the Earth.
+func (c *StandardConsumer) contentSize(numPoints int) int64 {
+	const r = 6378137.0
+	positionSize := 12
+	featureTable := c.alignTable([]byte(c.generateFeatureTableJsonContent(r, r, r, numPoints, 0)), pntsHeaderLength)
+	if c.quantizedPositions {
+		positionSize = 6
+		volume := &quantizationVolume{offset: [3]float64{r, r, r}, scale: [3]float64{r, r, r}}
+		featureTable = c.alignTable([]byte(c.generateQuantizedFeatureTableJsonContent(r, r, r, volume, numPoints, 0)), pntsHeaderLength)
+	}
+	featureTableBinary := c.featureTableBinaryLength(numPoints, positionSize)
+	featureTableBinary += c.padding(featureTableBinary)
+	batchTable := c.alignTable([]byte(c.generateBatchTableJsonContent(numPoints, 0)), 0)
+	batchTableBinary := c.batchTableBinaryLength(numPoints)
+	batchTableBinary += c.padding(batchTableBinary)
+	return int64(pntsHeaderLength + len(featureTable) + featureTableBinary + len(batchTable) + batchTableBinary)
+}
+
+// colorSize returns the number of bytes of the color of each point, 4 if the colors are stored as RGBA, 3 otherwise
+func (c *StandardConsumer) colorSize() int {
+	if c.classAlpha != nil {
+		return 4
+	}
+	return 3
+}
+
+// colorSemantic returns the semantic of the feature table storing the colors of the points
+func (c *StandardConsumer) colorSemantic() string {
+	if c.classAlpha != nil {
+		return "RGBA"
+	}
+	return "RGB"
+}
+
+// Returns the number of padding bytes to append to a section of the given length to align the following one
+func (c *StandardConsumer) padding(length int) int {
+	if c.bufferAlignment <= 0 || length%c.bufferAlignment == 0 {
+		return 0
+	}
+	return c.bufferAlignment - length%c.bufferAlignment
+}
+
+// Pads the json of a table, starting at the given offset of a section already aligned, with trailing spaces
+// so that the following binary body is aligned
+func (c *StandardConsumer) alignTable(tableBytes []byte, offset int) []byte {
+	n := c.padding(offset + len(tableBytes))
+	if n == 0 {
+		return tableBytes
+	}
+	return append(tableBytes, []byte(strings.Repeat(" ", n))...)
+}
+
+// Pads a binary body of the given length with zeros so that the following section is aligned
+func (c *StandardConsumer) writePadding(length int, w io.Writer) error {
+	n := c.padding(length)
+	if n == 0 {
+		return nil
+	}
+	_, err := w.Write(make([]byte, n))
+	return err
+}
+
+// Returns the length of the binary body of the feature table, without padding
+func (c *StandardConsumer) featureTableBinaryLength(numPoints int, positionSize int) int {
+	if c.perPointBatchId {
+		return c.batchIdOffset(numPoints, positionSize) + 4*numPoints // batch id as unsigned int
+	}
+	return numPoints * (positionSize + c.colorSize())
+}
+
+// Returns the offset of the BATCH_ID in the binary body of the feature table, following the positions and the colors
+// and aligned to 4 bytes as required by its component type
+func (c *StandardConsumer) batchIdOffset(numPoints int, positionSize int) int {
+	length := numPoints * (positionSize + c.colorSize())
+	return (length + 3) / 4 * 4
+}
+
+// Returns the length of the binary body of the batch table, without padding
+func (c *StandardConsumer) batchTableBinaryLength(numPoints int) int {
+	length := 2 * numPoints // intensity + classification
+	if c.sourceFileAttribute {
+		length += 2 * numPoints // source file index as unsigned short
+	}
+	if c.pointSourceIdAttribute {
+		length += 2 * numPoints // point source ID as unsigned short
+	}
+	return length
 }
 
 func (c *StandardConsumer) generateFeatureTable(avgX float64, avgY float64, avgZ float64, numPoints int) ([]byte, int) {
@@ -168,7 +338,7 @@ func (c *StandardConsumer) generateBatchTable(numPoints int) ([]byte, int) {
 	return []byte(batchTableStr), batchTableLen
 }
 
-func (c *StandardConsumer) writePntsHeader(numPoints int, featureTableLen int, batchTableLen int, w io.Writer) error {
+func (c *StandardConsumer) writePntsHeader(numPoints int, positionSize int, featureTableLen int, batchTableLen int, w io.Writer) error {
 	_, err := w.Write([]byte("pnts")) // magic
 	if err != nil {
 		return err
@@ -177,8 +347,10 @@ func (c *StandardConsumer) writePntsHeader(numPoints int, featureTableLen int, b
 	if err != nil {
 		return err
 	}
-	positionBytesLen := 4 * 3 * numPoints                                                 // 4 bytes per coordinate component (x,y,z) -> 12 bytes per point
-	err = utils.WriteIntAs4ByteNumber(28+featureTableLen+positionBytesLen+numPoints*3, w) // numpoints*3 is colorbytes (1 byte per color component)
+	// 12 bytes per point as float32, 6 bytes when quantized, 1 byte per color component and the optional batch ids
+	featureTableBinaryLen := c.featureTableBinaryLength(numPoints, positionSize)
+	featureTableBinaryLen += c.padding(featureTableBinaryLen)
+	err = utils.WriteIntAs4ByteNumber(28+featureTableLen+featureTableBinaryLen, w)
 	if err != nil {
 		return err
 	}
@@ -186,7 +358,7 @@ func (c *StandardConsumer) writePntsHeader(numPoints int, featureTableLen int, b
 	if err != nil {
 		return err
 	}
-	err = utils.WriteIntAs4ByteNumber(positionBytesLen+numPoints*3, w) // feature table binary length (position len + colors len)
+	err = utils.WriteIntAs4ByteNumber(featureTableBinaryLen, w) // feature table binary length (position len + colors len + batch ids len)
 	if err != nil {
 		return err
 	}
@@ -194,7 +366,9 @@ func (c *StandardConsumer) writePntsHeader(numPoints int, featureTableLen int, b
 	if err != nil {
 		return err
 	}
-	err = utils.WriteIntAs4ByteNumber(2*numPoints, w) // intensity + classification
+	batchTableBinaryLen := c.batchTableBinaryLength(numPoints)
+	batchTableBinaryLen += c.padding(batchTableBinaryLen)
+	err = utils.WriteIntAs4ByteNumber(batchTableBinaryLen, w)
 	if err != nil {
 		return err
 	}
@@ -234,6 +408,31 @@ func (c *StandardConsumer) writePointCoords(pts geom.Point32List, avgCoords []fl
 	return nil
 }
 
+// Writes the positions of the points as 16 bit integers quantized over the given volume
+func (c *StandardConsumer) writeQuantizedPointCoords(pts geom.Point32List, avgCoords []float64, cX, cY, cZ float64, volume *quantizationVolume, w io.Writer) error {
+	n := pts.Len()
+	b := make([]byte, 6)
+	for i := 0; i < n; i++ {
+		pt, err := pts.Next()
+		if err != nil {
+			return err
+		}
+		local := [3]float64{float64(pt.X) - avgCoords[0] + cX, float64(pt.Y) - avgCoords[1] + cY, float64(pt.Z) - avgCoords[2] + cZ}
+		for k := 0; k < 3; k++ {
+			var q float64
+			if volume.scale[k] > 0 {
+				q = math.Round((local[k] - volume.offset[k]) / volume.scale[k] * math.MaxUint16)
+			}
+			binary.LittleEndian.PutUint16(b[2*k:], uint16(math.Max(0, math.Min(math.MaxUint16, q))))
+		}
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+	pts.Reset()
+	return nil
+}
+
 func (c *StandardConsumer) writePointColors(pts geom.Point32List, w io.Writer) error {
 	n := pts.Len()
 	// write colors
@@ -242,7 +441,15 @@ func (c *StandardConsumer) writePointColors(pts geom.Point32List, w io.Writer) e
 		if err != nil {
 			return err
 		}
-		_, err = w.Write([]byte{pt.R, pt.G, pt.B})
+		if c.classAlpha != nil {
+			alpha, ok := c.classAlpha[pt.Classification]
+			if !ok {
+				alpha = 255
+			}
+			_, err = w.Write([]byte{pt.R, pt.G, pt.B, alpha})
+		} else {
+			_, err = w.Write([]byte{pt.R, pt.G, pt.B})
+		}
 		if err != nil {
 			return err
 		}
@@ -251,6 +458,22 @@ func (c *StandardConsumer) writePointColors(pts geom.Point32List, w io.Writer) e
 	return nil
 }
 
+// Writes the batch id of each point, its index in the tile, after padding the colors to the offset of the batch ids
+func (c *StandardConsumer) writePointBatchIds(numPoints int, positionSize int, w io.Writer) error {
+	pad := c.batchIdOffset(numPoints, positionSize) - numPoints*(positionSize+c.colorSize())
+	if _, err := w.Write(make([]byte, pad)); err != nil {
+		return err
+	}
+	b := make([]byte, 4)
+	for i := 0; i < numPoints; i++ {
+		binary.LittleEndian.PutUint32(b, uint32(i))
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (c *StandardConsumer) writePointIntensities(pts geom.Point32List, w io.Writer) error {
 	n := pts.Len()
 	// write colors
@@ -285,6 +508,42 @@ func (c *StandardConsumer) writePointClassifications(pts geom.Point32List, w io.
 	return nil
 }
 
+func (c *StandardConsumer) writePointSourceFiles(pts geom.Point32List, w io.Writer) error {
+	n := pts.Len()
+	b := make([]byte, 2)
+	for i := 0; i < n; i++ {
+		pt, err := pts.Next()
+		if err != nil {
+			return err
+		}
+		binary.LittleEndian.PutUint16(b, pt.FileIndex)
+		_, err = w.Write(b)
+		if err != nil {
+			return err
+		}
+	}
+	pts.Reset()
+	return nil
+}
+
+func (c *StandardConsumer) writePointSourceIds(pts geom.Point32List, w io.Writer) error {
+	n := pts.Len()
+	b := make([]byte, 2)
+	for i := 0; i < n; i++ {
+		pt, err := pts.Next()
+		if err != nil {
+			return err
+		}
+		binary.LittleEndian.PutUint16(b, pt.PointSourceId)
+		_, err = w.Write(b)
+		if err != nil {
+			return err
+		}
+	}
+	pts.Reset()
+	return nil
+}
+
 func (c *StandardConsumer) computeAverageXYZFromPointStream(pts geom.Point32List, cX, cY, cZ float64) ([]float64, error) {
 	var avgX, avgY, avgZ float64
 	n := pts.Len()
@@ -301,12 +560,68 @@ func (c *StandardConsumer) computeAverageXYZFromPointStream(pts geom.Point32List
 	return []float64{avgX, avgY, avgZ}, nil
 }
 
+// Computes the bounding box of the points relative to the average coordinates, used as RTC_CENTER
+func (c *StandardConsumer) computeQuantizationVolume(pts geom.Point32List, avgCoords []float64, cX, cY, cZ float64) (*quantizationVolume, error) {
+	min := [3]float64{math.MaxFloat64, math.MaxFloat64, math.MaxFloat64}
+	max := [3]float64{-math.MaxFloat64, -math.MaxFloat64, -math.MaxFloat64}
+	n := pts.Len()
+	for i := 0; i < n; i++ {
+		pt, err := pts.Next()
+		if err != nil {
+			return nil, err
+		}
+		local := [3]float64{float64(pt.X) - avgCoords[0] + cX, float64(pt.Y) - avgCoords[1] + cY, float64(pt.Z) - avgCoords[2] + cZ}
+		for k := 0; k < 3; k++ {
+			min[k] = math.Min(min[k], local[k])
+			max[k] = math.Max(max[k], local[k])
+		}
+	}
+	pts.Reset()
+	volume := &quantizationVolume{}
+	for k := 0; k < 3; k++ {
+		if n > 0 {
+			volume.offset[k] = min[k]
+			volume.scale[k] = max[k] - min[k]
+		}
+	}
+	return volume, nil
+}
+
+func (c *StandardConsumer) generateQuantizedFeatureTable(avgX float64, avgY float64, avgZ float64, volume *quantizationVolume, numPoints int) ([]byte, int) {
+	featureTableStr := c.generateQuantizedFeatureTableJsonContent(avgX, avgY, avgZ, volume, numPoints, 0)
+	return []byte(featureTableStr), len(featureTableStr)
+}
+
+// Generates the json representation of the feature table with quantized positions. The volume is written
+// with full precision, as the positions are quantized against it.
+func (c *StandardConsumer) generateQuantizedFeatureTableJsonContent(x, y, z float64, volume *quantizationVolume, pointNo int, spaceNo int) string {
+	f := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	s := fmt.Sprintf(`{"POINTS_LENGTH":%d,"RTC_CENTER":[%f,%f,%f],"QUANTIZED_VOLUME_OFFSET":[%s,%s,%s],"QUANTIZED_VOLUME_SCALE":[%s,%s,%s],"POSITION_QUANTIZED":{"byteOffset":0},"%s":{"byteOffset":%d}%s}%s`,
+		pointNo,
+		x, y, z,
+		f(volume.offset[0]), f(volume.offset[1]), f(volume.offset[2]),
+		f(volume.scale[0]), f(volume.scale[1]), f(volume.scale[2]),
+		c.colorSemantic(), pointNo*6,
+		c.batchIdJsonContent(pointNo, 6),
+		strings.Repeat(" ", spaceNo),
+	)
+	headerByteLength := len([]byte(s))
+	paddingSize := headerByteLength % 4
+	if paddingSize != 0 {
+		return c.generateQuantizedFeatureTableJsonContent(x, y, z, volume, pointNo, 4-paddingSize)
+	}
+	return s
+}
+
 // Generates the json representation of the feature table
 func (c *StandardConsumer) generateFeatureTableJsonContent(x, y, z float64, pointNo int, spaceNo int) string {
-	s := fmt.Sprintf(`{"POINTS_LENGTH":%d,"RTC_CENTER":[%f%s,%f%s,%f%s],"POSITION":{"byteOffset":0},"RGB":{"byteOffset":%d}}`,
+	s := fmt.Sprintf(`{"POINTS_LENGTH":%d,"RTC_CENTER":[%f%s,%f%s,%f%s],"POSITION":{"byteOffset":0},"%s":{"byteOffset":%d}%s}`,
 		pointNo,
 		x, strings.Repeat("0", spaceNo), y, strings.Repeat("0", spaceNo), z, strings.Repeat("0", spaceNo),
-		pointNo*12,
+		c.colorSemantic(), pointNo*12,
+		c.batchIdJsonContent(pointNo, 12),
 	)
 	headerByteLength := len([]byte(s))
 	paddingSize := headerByteLength % 4
@@ -316,10 +631,31 @@ func (c *StandardConsumer) generateFeatureTableJsonContent(x, y, z float64, poin
 	return s
 }
 
+// Generates the json properties of the feature table declaring the batch ids, if enabled. Each point is its own
+// feature, so the batch length equals the number of points.
+func (c *StandardConsumer) batchIdJsonContent(pointNo int, positionSize int) string {
+	if !c.perPointBatchId {
+		return ""
+	}
+	return fmt.Sprintf(`,"BATCH_LENGTH":%d,"BATCH_ID":{"byteOffset":%d,"componentType":"UNSIGNED_INT"}`, pointNo, c.batchIdOffset(pointNo, positionSize))
+}
+
 // Generates the json representation of the batch table
 func (c *StandardConsumer) generateBatchTableJsonContent(pointNumber, spaceNumber int) string {
+	// the optional properties follow intensity and classification, in this order
+	optional := ""
+	offset := 2 * pointNumber
+	if c.sourceFileAttribute {
+		optional += fmt.Sprintf(`,
+	"_SOURCE_FILE":{"byteOffset":%d,"componentType":"UNSIGNED_SHORT","type":"SCALAR"}`, offset)
+		offset += 2 * pointNumber
+	}
+	if c.pointSourceIdAttribute {
+		optional += fmt.Sprintf(`,
+	"POINT_SOURCE_ID":{"byteOffset":%d,"componentType":"UNSIGNED_SHORT","type":"SCALAR"}`, offset)
+	}
 	s := fmt.Sprintf(`{"INTENSITY":{"byteOffset":0,"componentType":"UNSIGNED_BYTE","type":"SCALAR"},
-	"CLASSIFICATION":{"byteOffset":%d,"componentType":"UNSIGNED_BYTE","type":"SCALAR"}}%s`, pointNumber, strings.Repeat(" ", spaceNumber))
+	"CLASSIFICATION":{"byteOffset":%d,"componentType":"UNSIGNED_BYTE","type":"SCALAR"}%s}%s`, pointNumber, optional, strings.Repeat(" ", spaceNumber))
 	headerByteLength := len([]byte(s))
 	paddingSize := headerByteLength % 4
 	if paddingSize != 0 {
@@ -333,12 +669,6 @@ func (c *StandardConsumer) writeTilesetJsonFile(workUnit WorkUnit) error {
 	parentFolder := workUnit.BasePath
 	node := workUnit.Node
 
-	// Create base folder if it does not exist
-	err := utils.CreateDirectoryIfDoesNotExist(parentFolder)
-	if err != nil {
-		return err
-	}
-
 	// tileset.json file
 	file := path.Join(parentFolder, "tileset.json")
 	jsonData, err := c.generateTilesetJson(node)
@@ -347,12 +677,7 @@ func (c *StandardConsumer) writeTilesetJsonFile(workUnit WorkUnit) error {
 	}
 
 	// Writes the tileset.json binary content to the given file
-	err = os.WriteFile(file, jsonData, 0666)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return c.storage.WriteFile(file, jsonData)
 }
 
 // Generates the tileset.json content for the given tree node
@@ -365,6 +690,9 @@ func (c *StandardConsumer) generateTilesetJson(node tree.Node) ([]byte, error) {
 
 		tileset := c.generateTileset(node, root)
 
+		if !c.prettyTileset {
+			return json.Marshal(tileset)
+		}
 		// Outputting a formatted json file
 		e, err := json.MarshalIndent(tileset, "", "\t")
 		if err != nil {
@@ -389,10 +717,15 @@ func (c *StandardConsumer) generateTilesetRoot(node tree.Node) (Root, error) {
 		return Root{}, err
 	}
 
+	content, err := c.generateContent(node, c.contentFile)
+	if err != nil {
+		return Root{}, err
+	}
+
 	return Root{
-		Content:        Content{"content.pnts"},
+		Content:        content,
 		BoundingVolume: BoundingVolume{reg.GetAsArray()},
-		GeometricError: node.ComputeGeometricError(),
+		GeometricError: c.geometricError(node),
 		Refine:         "ADD",
 		Children:       children,
 	}, nil
@@ -401,12 +734,19 @@ func (c *StandardConsumer) generateTilesetRoot(node tree.Node) (Root, error) {
 func (c *StandardConsumer) generateTileset(node tree.Node, root Root) Tileset {
 	tileset := Tileset{}
 	tileset.Asset = Asset{Version: "1.0"}
-	tileset.GeometricError = node.ComputeGeometricError()
+	if node.IsRoot() {
+		tileset.Asset.Extras = c.assetExtras
+		tileset.Properties = c.properties
+	}
+	tileset.GeometricError = c.geometricError(node)
 	tileset.Root = root
 
 	return tileset
 }
 
+// Generates the children of the tileset of the given node. The children are listed in ascending order of their
+// index among the node children, i.e. the octant index, which is also the name of their folder, so that the
+// tileset.json files are identical across runs on the same input.
 func (c *StandardConsumer) generateTilesetChildren(node tree.Node) ([]Child, error) {
 	var children []Child
 	for i, child := range node.GetChildren() {
@@ -429,11 +769,19 @@ func (c *StandardConsumer) generateTilesetChild(child tree.Node, childIndex int)
 	childJson := Child{}
 	filename := "tileset.json"
 	if child.IsLeaf() {
-		filename = "content.pnts"
+		filename = c.contentFile
 	}
-	childJson.Content = Content{
+	content := Content{
 		Url: strconv.Itoa(childIndex) + "/" + filename,
 	}
+	if child.IsLeaf() {
+		// the content of the inner nodes is an external tileset, whose root declares its own content volume
+		var err error
+		if content, err = c.generateContent(child, content.Url); err != nil {
+			return Child{}, err
+		}
+	}
+	childJson.Content = content
 	reg, err := child.GetBoundingBoxRegion(c.conv)
 	if err != nil {
 		return Child{}, err
@@ -441,7 +789,28 @@ func (c *StandardConsumer) generateTilesetChild(child tree.Node, childIndex int)
 	childJson.BoundingVolume = BoundingVolume{
 		Region: reg.GetAsArray(),
 	}
-	childJson.GeometricError = child.ComputeGeometricError()
+	childJson.GeometricError = c.geometricError(child)
 	childJson.Refine = "ADD"
 	return childJson, nil
 }
+
+// geometricError returns the geometric error of the given node written in the tilesets
+func (c *StandardConsumer) geometricError(node tree.Node) float64 {
+	return node.ComputeGeometricError() * c.geometricErrorScale
+}
+
+// Generates the content object referring to the given uri, storing the points of the given node
+func (c *StandardConsumer) generateContent(node tree.Node, uri string) (Content, error) {
+	content := Content{Url: uri}
+	if !c.contentBoundingVolumes {
+		return content, nil
+	}
+	region, err := computeContentRegion(node, c.conv)
+	if err != nil {
+		return Content{}, err
+	}
+	if region != nil {
+		content.BoundingVolume = &BoundingVolume{Region: region}
+	}
+	return content, nil
+}
@@ -1,10 +1,14 @@
 package writer
 
 import (
+	"encoding/binary"
 	"encoding/json"
+	"fmt"
+	"math"
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"sync"
 	"testing"
 
@@ -18,7 +22,7 @@ func TestConsume(t *testing.T) {
 	if err != nil {
 		t.Fatalf("unexpected error %v", err)
 	}
-	c := NewStandardConsumer(cv)
+	c := NewStandardConsumer(cv, NewFsStorage())
 	wc := make(chan *WorkUnit)
 	ec := make(chan error)
 	wg := &sync.WaitGroup{}
@@ -135,3 +139,258 @@ func TestConsume(t *testing.T) {
 		t.Errorf("expected pnts:\n%v\n\ngot:\n\n%v\n", expectedPnts, actualPnts)
 	}
 }
+
+func TestGenerateTilesetAssetExtras(t *testing.T) {
+	extras := map[string]interface{}{"key": "value"}
+	c := NewStandardConsumer(nil, NewFsStorage(), func(c *StandardConsumer) {
+		c.assetExtras = extras
+	}).(*StandardConsumer)
+	tileset := c.generateTileset(&tree.MockNode{Root: true}, Root{})
+	if !reflect.DeepEqual(tileset.Asset.Extras, extras) {
+		t.Errorf("expected extras %v got %v", extras, tileset.Asset.Extras)
+	}
+	// nested tilesets do not carry the extras
+	tileset = c.generateTileset(&tree.MockNode{}, Root{})
+	if tileset.Asset.Extras != nil {
+		t.Errorf("expected no extras for nested tileset got %v", tileset.Asset.Extras)
+	}
+}
+
+func TestGenerateTilesetChildrenOrder(t *testing.T) {
+	newLeaf := func() *tree.MockNode {
+		return &tree.MockNode{Pts: geom.NewLinkedPointStream(nil, 0), TotalNumPts: 1, Leaf: true}
+	}
+	node := &tree.MockNode{
+		Root:     true,
+		Children: [8]tree.Node{nil, newLeaf(), nil, nil, newLeaf(), nil, newLeaf(), nil},
+	}
+	c := NewStandardConsumer(nil, NewFsStorage()).(*StandardConsumer)
+	var previous []byte
+	for i := 0; i < 3; i++ {
+		data, err := c.generateTilesetJson(node)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		var tileset Tileset
+		if err := json.Unmarshal(data, &tileset); err != nil {
+			t.Fatalf("unexpected error decoding the tileset %v", err)
+		}
+		uris := []string{}
+		for _, child := range tileset.Root.Children {
+			uris = append(uris, child.Content.Url)
+		}
+		if expected := []string{"1/content.pnts", "4/content.pnts", "6/content.pnts"}; !reflect.DeepEqual(uris, expected) {
+			t.Errorf("expected children %v got %v", expected, uris)
+		}
+		if previous != nil && string(previous) != string(data) {
+			t.Errorf("expected identical tilesets across runs, got %s and %s", previous, data)
+		}
+		previous = data
+	}
+}
+
+func TestGenerateTilesetJsonMinified(t *testing.T) {
+	node := &tree.MockNode{Root: true, Leaf: true, GeomError: 20}
+	for _, pretty := range []bool{true, false} {
+		c := NewStandardConsumer(nil, NewFsStorage(), func(c *StandardConsumer) {
+			c.prettyTileset = pretty
+		}).(*StandardConsumer)
+		data, err := c.generateTilesetJson(node)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if indented := strings.Contains(string(data), "\n"); indented != pretty {
+			t.Errorf("expected indented output %v got %s", pretty, data)
+		}
+		var tileset Tileset
+		if err := json.Unmarshal(data, &tileset); err != nil {
+			t.Errorf("unexpected error decoding the tileset %v", err)
+		}
+	}
+}
+
+func TestWriteSourceFileAttribute(t *testing.T) {
+	pts := []geom.Point32{
+		geom.NewPoint32(1, 2, 3, 1, 2, 3, 4, 5),
+		geom.NewPoint32(4, 5, 6, 6, 7, 8, 9, 10),
+	}
+	pts[1].FileIndex = 300
+	n := &tree.MockNode{
+		TotalNumPts: 2,
+		Pts:         geom.NewLinkedPointStream(&geom.LinkedPoint{Pt: pts[0], Next: &geom.LinkedPoint{Pt: pts[1]}}, 2),
+		Leaf:        true,
+	}
+	s := &MockStorage{}
+	c := NewStandardConsumer(nil, s, func(c *StandardConsumer) { c.sourceFileAttribute = true }).(*StandardConsumer)
+	if err := c.writeBinaryPntsFile(WorkUnit{Node: n, BasePath: "tile"}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	data := s.Files["tile/content.pnts"]
+	featureTableLen := int(binary.LittleEndian.Uint32(data[12:16]))
+	featureTableBinLen := int(binary.LittleEndian.Uint32(data[16:20]))
+	batchTableLen := int(binary.LittleEndian.Uint32(data[20:24]))
+	if batchTableBinLen := int(binary.LittleEndian.Uint32(data[24:28])); batchTableBinLen != 2*2+2*2 {
+		t.Errorf("expected batch table binary length %d got %d", 2*2+2*2, batchTableBinLen)
+	}
+	batchTableStart := 28 + featureTableLen + featureTableBinLen
+	bt := map[string]struct {
+		ByteOffset    int    `json:"byteOffset"`
+		ComponentType string `json:"componentType"`
+	}{}
+	if err := json.Unmarshal(data[batchTableStart:batchTableStart+batchTableLen], &bt); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	sf, ok := bt["_SOURCE_FILE"]
+	if !ok || sf.ComponentType != "UNSIGNED_SHORT" {
+		t.Fatalf("expected an unsigned short _SOURCE_FILE property, got %v", bt)
+	}
+	body := data[batchTableStart+batchTableLen:]
+	if len(body) != 8 {
+		t.Fatalf("expected batch table binary body of %d bytes got %d", 8, len(body))
+	}
+	for i, expected := range []uint16{0, 300} {
+		if actual := binary.LittleEndian.Uint16(body[sf.ByteOffset+2*i:]); actual != expected {
+			t.Errorf("point %d: expected source file %d got %d", i, expected, actual)
+		}
+	}
+}
+
+func TestWriteQuantizedPositions(t *testing.T) {
+	pts := []geom.Point32{
+		geom.NewPoint32(-10, 0, 5, 1, 2, 3, 4, 5),
+		geom.NewPoint32(10, 2, 5, 6, 7, 8, 9, 10),
+		geom.NewPoint32(0.5, 1, 5, 11, 12, 13, 14, 15),
+	}
+	var root *geom.LinkedPoint
+	for i := len(pts) - 1; i >= 0; i-- {
+		root = &geom.LinkedPoint{Pt: pts[i], Next: root}
+	}
+	n := &tree.MockNode{
+		TotalNumPts: 3,
+		Pts:         geom.NewLinkedPointStream(root, 3),
+		Leaf:        true,
+		CenterX:     1000,
+		CenterY:     2000,
+		CenterZ:     3000,
+	}
+	s := &MockStorage{}
+	c := NewStandardConsumer(nil, s, func(c *StandardConsumer) { c.quantizedPositions = true }).(*StandardConsumer)
+	if err := c.writeBinaryPntsFile(WorkUnit{Node: n, BasePath: "tile"}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	data := s.Files["tile/content.pnts"]
+	featureTableLen := int(binary.LittleEndian.Uint32(data[12:16]))
+	featureTableBinLen := int(binary.LittleEndian.Uint32(data[16:20]))
+	if featureTableBinLen != 3*6+3*3 {
+		t.Errorf("expected feature table binary length %d got %d", 3*6+3*3, featureTableBinLen)
+	}
+	ft := struct {
+		RtcCenter []float64 `json:"RTC_CENTER"`
+		Offset    []float64 `json:"QUANTIZED_VOLUME_OFFSET"`
+		Scale     []float64 `json:"QUANTIZED_VOLUME_SCALE"`
+		Rgb       struct {
+			ByteOffset int `json:"byteOffset"`
+		} `json:"RGB"`
+	}{}
+	if err := json.Unmarshal(data[28:28+featureTableLen], &ft); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if ft.Rgb.ByteOffset != 18 {
+		t.Errorf("expected RGB offset %d got %d", 18, ft.Rgb.ByteOffset)
+	}
+	if ft.Scale[2] != 0 {
+		t.Errorf("expected zero scale for a flat tile got %v", ft.Scale[2])
+	}
+	body := data[28+featureTableLen:]
+	for i, pt := range pts {
+		for k, expected := range []float64{float64(pt.X) + 1000, float64(pt.Y) + 2000, float64(pt.Z) + 3000} {
+			q := float64(binary.LittleEndian.Uint16(body[6*i+2*k:]))
+			actual := ft.RtcCenter[k] + ft.Offset[k] + q*ft.Scale[k]/65535
+			if math.Abs(actual-expected) > ft.Scale[k]/65535 {
+				t.Errorf("point %d component %d: expected %v got %v", i, k, expected, actual)
+			}
+		}
+		if rgb := body[18+3*i : 21+3*i]; rgb[0] != pt.R || rgb[1] != pt.G || rgb[2] != pt.B {
+			t.Errorf("point %d: unexpected color %v", i, rgb)
+		}
+	}
+}
+
+func BenchmarkWriteBinaryPntsFile(b *testing.B) {
+	var root *geom.LinkedPoint
+	for i := 0; i < 50000; i++ {
+		root = &geom.LinkedPoint{Pt: geom.NewPoint32(float32(i%250), float32(i/250), float32(i%7), 1, 2, 3, 4, 5), Next: root}
+	}
+	n := &tree.MockNode{
+		TotalNumPts: 50000,
+		Pts:         geom.NewLinkedPointStream(root, 50000),
+		Leaf:        true,
+		CenterX:     4000000,
+		CenterY:     900000,
+		CenterZ:     4800000,
+	}
+	for _, quantized := range []bool{false, true} {
+		b.Run(fmt.Sprintf("quantized=%v", quantized), func(b *testing.B) {
+			c := NewStandardConsumer(nil, &MockStorage{}, func(c *StandardConsumer) { c.quantizedPositions = quantized }).(*StandardConsumer)
+			for i := 0; i < b.N; i++ {
+				if err := c.writeBinaryPntsFile(WorkUnit{Node: n, BasePath: "tile"}); err != nil {
+					b.Fatalf("unexpected error %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteClassAlpha(t *testing.T) {
+	pts := []geom.Point32{
+		geom.NewPoint32(1, 2, 3, 10, 20, 30, 4, 7),
+		geom.NewPoint32(4, 5, 6, 40, 50, 60, 9, 2),
+	}
+	n := &tree.MockNode{
+		TotalNumPts: 2,
+		Pts:         geom.NewLinkedPointStream(&geom.LinkedPoint{Pt: pts[0], Next: &geom.LinkedPoint{Pt: pts[1]}}, 2),
+		Leaf:        true,
+	}
+	s := &MockStorage{}
+	w, err := NewWriter("base", nil, WithClassAlpha(map[uint8]uint8{7: 64}))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	c := NewStandardConsumer(nil, s, w.consumerOptions...).(*StandardConsumer)
+	if err := c.writeBinaryPntsFile(WorkUnit{Node: n, BasePath: "tile"}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	data := s.Files["tile/content.pnts"]
+	featureTableLen := int(binary.LittleEndian.Uint32(data[12:16]))
+	if featureTableBinLen := int(binary.LittleEndian.Uint32(data[16:20])); featureTableBinLen != 2*12+2*4 {
+		t.Errorf("expected feature table binary length %d got %d", 2*12+2*4, featureTableBinLen)
+	}
+	ft := struct {
+		Rgb  *struct{} `json:"RGB"`
+		Rgba struct {
+			ByteOffset int `json:"byteOffset"`
+		} `json:"RGBA"`
+	}{}
+	if err := json.Unmarshal(data[28:28+featureTableLen], &ft); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if ft.Rgb != nil || ft.Rgba.ByteOffset != 24 {
+		t.Errorf("expected RGBA colors at offset %d got %v", 24, ft)
+	}
+	colors := data[28+featureTableLen+ft.Rgba.ByteOffset:]
+	// the classification 2 is not mapped, hence fully opaque
+	for i, expected := range [][4]byte{{10, 20, 30, 64}, {40, 50, 60, 255}} {
+		if actual := [4]byte(colors[4*i : 4*i+4]); actual != expected {
+			t.Errorf("point %d: expected color %v got %v", i, expected, actual)
+		}
+	}
+
+	// an empty map keeps the RGB colors
+	w, err = NewWriter("base", nil, WithClassAlpha(map[uint8]uint8{}))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if c := NewStandardConsumer(nil, s, w.consumerOptions...).(*StandardConsumer); c.colorSemantic() != "RGB" {
+		t.Errorf("expected RGB colors with an empty map got %s", c.colorSemantic())
+	}
+}
@@ -0,0 +1,42 @@
+package writer
+
+import (
+	"math"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/conv/coor"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/tree"
+)
+
+// computeContentRegion returns the region tightly enclosing the points stored in the given node, i.e. the content of
+// the tile, as [west, south, east, north, minimum height, maximum height] with angles in radians. Unlike the tile
+// bounding volume it does not include the points of the descendants nor the empty space of the node cell.
+func computeContentRegion(node tree.Node, conv coor.CoordinateConverter) ([]float64, error) {
+	cX, cY, cZ, err := node.GetCenter(conv)
+	if err != nil {
+		return nil, err
+	}
+	min := [3]float64{math.Inf(1), math.Inf(1), math.Inf(1)}
+	max := [3]float64{math.Inf(-1), math.Inf(-1), math.Inf(-1)}
+	list := node.GetPoints(conv)
+	list.Reset()
+	for i := 0; i < list.Len(); i++ {
+		pt, err := list.Next()
+		if err != nil {
+			return nil, err
+		}
+		c, err := conv.ToSrid(coor.EcefSrid, coor.GeographicSrid, geom.Coord{X: float64(pt.X) + cX, Y: float64(pt.Y) + cY, Z: float64(pt.Z) + cZ})
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range [3]float64{c.X * math.Pi / 180, c.Y * math.Pi / 180, c.Z} {
+			min[k] = math.Min(min[k], v)
+			max[k] = math.Max(max[k], v)
+		}
+	}
+	list.Reset()
+	if list.Len() == 0 {
+		return nil, nil
+	}
+	return []float64{min[0], min[1], max[0], max[1], min[2], max[2]}, nil
+}
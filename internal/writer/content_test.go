@@ -0,0 +1,93 @@
+package writer
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/tree"
+)
+
+func TestWriterWithContentBoundingVolumes(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		w, err := NewWriter("base", nil, WithContentBoundingVolumes(enabled))
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		s := &MockStorage{}
+		w.storageProvider = func(root string) (Storage, error) {
+			return s, nil
+		}
+		// the root stores a single point 100 meters above the ellipsoid, the child two points 1 degree apart
+		low, err := w.conv.ToWGS84Cartesian(geom.Coord{X: 10, Y: 45, Z: 100}, 4326)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		west, err := w.conv.ToWGS84Cartesian(geom.Coord{X: 10, Y: 45, Z: 200}, 4326)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		east, err := w.conv.ToWGS84Cartesian(geom.Coord{X: 11, Y: 46, Z: 300}, 4326)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		child := &tree.MockNode{
+			Pts: geom.NewLinkedPointStream(&geom.LinkedPoint{
+				Pt:   geom.NewPoint32(0, 0, 0, 0, 0, 0, 0, 0),
+				Next: &geom.LinkedPoint{Pt: geom.NewPoint32(float32(east.X-west.X), float32(east.Y-west.Y), float32(east.Z-west.Z), 0, 0, 0, 0, 0)},
+			}, 2),
+			TotalNumPts: 2,
+			Leaf:        true,
+			CenterX:     west.X,
+			CenterY:     west.Y,
+			CenterZ:     west.Z,
+		}
+		root := &tree.MockNode{
+			Pts:         geom.NewLinkedPointStream(&geom.LinkedPoint{Pt: geom.NewPoint32(0, 0, 0, 0, 0, 0, 0, 0)}, 1),
+			TotalNumPts: 3,
+			Root:        true,
+			Children:    [8]tree.Node{child},
+			CenterX:     low.X,
+			CenterY:     low.Y,
+			CenterZ:     low.Z,
+		}
+		if err := w.Write(root, "", context.TODO()); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		tileset := Tileset{}
+		if err := json.Unmarshal(s.Files["base/tileset.json"], &tileset); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if !enabled {
+			if tileset.Root.Content.BoundingVolume != nil || tileset.Root.Children[0].Content.BoundingVolume != nil {
+				t.Errorf("expected no content bounding volumes")
+			}
+			continue
+		}
+		rad := math.Pi / 180
+		for _, c := range []struct {
+			volume   *BoundingVolume
+			expected []float64
+		}{
+			{tileset.Root.Content.BoundingVolume, []float64{10 * rad, 45 * rad, 10 * rad, 45 * rad, 100, 100}},
+			{tileset.Root.Children[0].Content.BoundingVolume, []float64{10 * rad, 45 * rad, 11 * rad, 46 * rad, 200, 300}},
+		} {
+			if c.volume == nil {
+				t.Fatalf("expected a content bounding volume")
+			}
+			for i, v := range c.expected {
+				// the points are stored as 32 bit floats relative to the tile center
+				tolerance := 1e-6
+				if i >= 4 {
+					tolerance = 0.1
+				}
+				if math.Abs(c.volume.Region[i]-v) > tolerance {
+					t.Errorf("expected region %v got %v", c.expected, c.volume.Region)
+					break
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package writer
+
+import (
+	"math"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/tree"
+)
+
+const (
+	// fullDetailScreenError is the maximum screen space error, in pixels, of the Cesium clients by default
+	fullDetailScreenError = 16.0
+	// fullDetailScreenWidth is the width in pixels of the reference viewport, a full HD screen
+	fullDetailScreenWidth = 1920.0
+	// fullDetailFov is the horizontal field of view, in radians, of the Cesium cameras by default
+	fullDetailFov = math.Pi / 3
+)
+
+// fullDetailScale returns the factor scaling the geometric errors of the tree so that the clients refine every tile
+// with children, hence display the points at full resolution, when the camera is within the given distance from them.
+// Cesium refines a tile when its screen space error, geometricError * width / (distance * 2 * tan(fov / 2)), exceeds
+// the maximum screen space error: the factor makes the smallest geometric error among the inner tiles reach it exactly
+// at the given distance, assuming the Cesium defaults on a full HD screen. Returns 1 if the tree has no inner tiles.
+func fullDetailScale(root tree.Node, distance float64) float64 {
+	minError := minInnerGeometricError(root)
+	if math.IsInf(minError, 1) || minError <= 0 {
+		return 1
+	}
+	target := fullDetailScreenError * distance * 2 * math.Tan(fullDetailFov/2) / fullDetailScreenWidth
+	return target / minError
+}
+
+// minInnerGeometricError returns the smallest positive geometric error among the nodes with children in the subtree
+// rooted at the given node, +Inf if there are none
+func minInnerGeometricError(node tree.Node) float64 {
+	minError := math.Inf(1)
+	if node.IsLeaf() {
+		return minError
+	}
+	if e := node.ComputeGeometricError(); e > 0 {
+		minError = e
+	}
+	for _, child := range node.GetChildren() {
+		if child != nil && child.TotalNumberOfPoints() > 0 {
+			minError = math.Min(minError, minInnerGeometricError(child))
+		}
+	}
+	return minError
+}
@@ -0,0 +1,67 @@
+package writer
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/conv/coor"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/tree"
+)
+
+func TestFullDetailScale(t *testing.T) {
+	leaf := &tree.MockNode{Leaf: true, GeomError: 0.1, TotalNumPts: 10}
+	inner := &tree.MockNode{GeomError: 2, TotalNumPts: 20, Children: [8]tree.Node{leaf}}
+	empty := &tree.MockNode{GeomError: 0.5}
+	root := &tree.MockNode{Root: true, GeomError: 8, TotalNumPts: 50, Children: [8]tree.Node{inner, empty}}
+
+	distance := 100.0
+	scale := fullDetailScale(root, distance)
+	// the screen space error of the deepest inner node at the given distance must match the Cesium default
+	sse := inner.GeomError * scale * fullDetailScreenWidth / (distance * 2 * math.Tan(fullDetailFov/2))
+	if math.Abs(sse-fullDetailScreenError) > 1e-9 {
+		t.Errorf("expected a screen space error of %f at %f meters, got %f", fullDetailScreenError, distance, sse)
+	}
+	if s := fullDetailScale(root, 2*distance); math.Abs(s-2*scale) > 1e-9 {
+		t.Errorf("expected the scale to double with the distance, got %f and %f", scale, s)
+	}
+	if s := fullDetailScale(leaf, distance); s != 1 {
+		t.Errorf("expected a scale of 1 without inner nodes, got %f", s)
+	}
+}
+
+func TestWriterWithFullDetailDistance(t *testing.T) {
+	leaf := &tree.MockNode{Leaf: true, GeomError: 0.1, TotalNumPts: 10}
+	root := &tree.MockNode{Root: true, GeomError: 4, TotalNumPts: 20, Children: [8]tree.Node{leaf}}
+	for _, distance := range []float64{0, 50} {
+		w, err := NewWriter("base", nil, WithFullDetailDistance(distance))
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		consumerFunc := w.consumerFunc
+		var consumer *StandardConsumer
+		w.producerFunc = func(basepath, folder string) Producer {
+			return &MockProducer{}
+		}
+		w.consumerFunc = func(cc coor.CoordinateConverter, s Storage) Consumer {
+			consumer = consumerFunc(cc, s).(*StandardConsumer)
+			return &MockConsumer{}
+		}
+		w.storageProvider = func(root string) (Storage, error) {
+			return &MockStorage{}, nil
+		}
+		if err := w.Write(root, "base", context.TODO()); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		expected := 1.0
+		if distance > 0 {
+			expected = fullDetailScale(root, distance)
+		}
+		if consumer.geometricErrorScale != expected {
+			t.Errorf("distance %f: expected scale %f got %f", distance, expected, consumer.geometricErrorScale)
+		}
+		if actual := consumer.geometricError(root); actual != root.GeomError*expected {
+			t.Errorf("distance %f: expected geometric error %f got %f", distance, root.GeomError*expected, actual)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package writer
+
+import (
+	"math"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/conv/coor"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/tree"
+)
+
+// flattenedNode exposes a tree node capping the depth of the subtree rooted at it: the nodes at the last allowed
+// level become leaves storing the points of all their descendants
+type flattenedNode struct {
+	tree.Node
+	// levels is the number of levels allowed below the node
+	levels int
+}
+
+// flatten returns the given root node capped to the given number of levels, root included
+func flatten(root tree.Node, maxLevels int) tree.Node {
+	return &flattenedNode{Node: root, levels: maxLevels - 1}
+}
+
+// merged returns true if the node stores the points of its descendants
+func (n *flattenedNode) merged() bool {
+	return n.levels <= 0 && !n.Node.IsLeaf()
+}
+
+func (n *flattenedNode) GetChildren() [8]tree.Node {
+	children := [8]tree.Node{}
+	if n.levels <= 0 {
+		return children
+	}
+	for i, child := range n.Node.GetChildren() {
+		if child != nil {
+			children[i] = &flattenedNode{Node: child, levels: n.levels - 1}
+		}
+	}
+	return children
+}
+
+func (n *flattenedNode) IsLeaf() bool {
+	return n.levels <= 0 || n.Node.IsLeaf()
+}
+
+func (n *flattenedNode) NumberOfPoints() int {
+	if n.merged() {
+		return n.Node.TotalNumberOfPoints()
+	}
+	return n.Node.NumberOfPoints()
+}
+
+func (n *flattenedNode) GetPoints(converter coor.CoordinateConverter) geom.Point32List {
+	if !n.merged() {
+		return n.Node.GetPoints(converter)
+	}
+	nodes := []tree.Node{}
+	var collect func(node tree.Node)
+	collect = func(node tree.Node) {
+		nodes = append(nodes, node)
+		for _, child := range node.GetChildren() {
+			if child != nil {
+				collect(child)
+			}
+		}
+	}
+	collect(n.Node)
+	return mergePoints(n.Node, nodes, converter)
+}
+
+// mergePoints returns the points of all the given nodes referred to the center of the target node
+func mergePoints(target tree.Node, nodes []tree.Node, converter coor.CoordinateConverter) geom.Point32List {
+	cX, cY, cZ, err := target.GetCenter(converter)
+	if err != nil {
+		return geom.NewLinkedPointStream(nil, 0)
+	}
+	var head *geom.LinkedPoint
+	count := 0
+	for _, node := range nodes {
+		x, y, z, err := node.GetCenter(converter)
+		if err != nil {
+			continue
+		}
+		pts := node.GetPoints(converter)
+		for i := 0; i < pts.Len(); i++ {
+			pt, err := pts.Next()
+			if err != nil {
+				break
+			}
+			// the points are referred to the center of the node they are stored in
+			pt.X = float32(float64(pt.X) + x - cX)
+			pt.Y = float32(float64(pt.Y) + y - cY)
+			pt.Z = float32(float64(pt.Z) + z - cZ)
+			head = &geom.LinkedPoint{Pt: pt, Next: head}
+			count++
+		}
+		pts.Reset()
+	}
+	return geom.NewLinkedPointStream(head, count)
+}
+
+// ComputeGeometricError returns, for the nodes storing the points of their descendants, the smallest geometric
+// error of the subtree, as the node contains the full detail of the points
+func (n *flattenedNode) ComputeGeometricError() float64 {
+	if !n.merged() {
+		return n.Node.ComputeGeometricError()
+	}
+	var finest func(node tree.Node) float64
+	finest = func(node tree.Node) float64 {
+		e := node.ComputeGeometricError()
+		for _, child := range node.GetChildren() {
+			if child != nil {
+				e = math.Min(e, finest(child))
+			}
+		}
+		return e
+	}
+	return finest(n.Node)
+}
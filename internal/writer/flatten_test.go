@@ -0,0 +1,104 @@
+package writer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/tree"
+)
+
+func newFlattenTestTree() *tree.MockNode {
+	grandchild := &tree.MockNode{
+		Pts: geom.NewLinkedPointStream(&geom.LinkedPoint{
+			Pt:   geom.NewPoint32(1, 1, 1, 1, 2, 3, 4, 5),
+			Next: &geom.LinkedPoint{Pt: geom.NewPoint32(2, 2, 2, 1, 2, 3, 4, 5)},
+		}, 2),
+		TotalNumPts: 2,
+		Leaf:        true,
+		GeomError:   1,
+		CenterX:     100,
+	}
+	child := &tree.MockNode{
+		Pts:         geom.NewLinkedPointStream(&geom.LinkedPoint{Pt: geom.NewPoint32(3, 3, 3, 1, 2, 3, 4, 5)}, 1),
+		Children:    [8]tree.Node{nil, grandchild},
+		TotalNumPts: 3,
+		GeomError:   5,
+		CenterX:     10,
+	}
+	return &tree.MockNode{
+		Pts:         geom.NewLinkedPointStream(&geom.LinkedPoint{Pt: geom.NewPoint32(4, 4, 4, 1, 2, 3, 4, 5)}, 1),
+		Children:    [8]tree.Node{child},
+		TotalNumPts: 4,
+		Root:        true,
+		GeomError:   20,
+	}
+}
+
+func TestFlatten(t *testing.T) {
+	root := flatten(newFlattenTestTree(), 2)
+	if root.IsLeaf() || root.NumberOfPoints() != 1 || root.ComputeGeometricError() != 20 {
+		t.Errorf("expected the root to be unchanged")
+	}
+	child := root.GetChildren()[0]
+	if child == nil || !child.IsLeaf() {
+		t.Fatalf("expected the child to become a leaf")
+	}
+	if actual := child.GetChildren(); actual != [8]tree.Node{} {
+		t.Errorf("expected no children got %v", actual)
+	}
+	if actual := child.NumberOfPoints(); actual != 3 {
+		t.Errorf("expected %d points got %d", 3, actual)
+	}
+	if actual := child.ComputeGeometricError(); actual != 1 {
+		t.Errorf("expected the geometric error of the finest level %v got %v", 1, actual)
+	}
+	pts := child.GetPoints(nil)
+	if pts.Len() != 3 {
+		t.Fatalf("expected %d points got %d", 3, pts.Len())
+	}
+	xs := map[float32]bool{}
+	for i := 0; i < pts.Len(); i++ {
+		pt, err := pts.Next()
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		xs[pt.X] = true
+	}
+	// the points of the grandchild are referred to the center of the child
+	for _, x := range []float32{3, 91, 92} {
+		if !xs[x] {
+			t.Errorf("expected a point with X %v, got %v", x, xs)
+		}
+	}
+
+	full := flatten(newFlattenTestTree(), 3)
+	if actual := countTiles(full); actual != 3 {
+		t.Errorf("expected %d tiles got %d", 3, actual)
+	}
+	if actual := countTiles(flatten(newFlattenTestTree(), 1)); actual != 1 {
+		t.Errorf("expected %d tiles got %d", 1, actual)
+	}
+}
+
+func TestWriterWithFlatten(t *testing.T) {
+	w, err := NewWriter("base", nil, WithFlatten(2), WithMaxTiles(2))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	s := &MockStorage{}
+	w.storageProvider = func(root string) (Storage, error) {
+		return s, nil
+	}
+	if err := w.Write(newFlattenTestTree(), "", context.TODO()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	for _, f := range []string{"base/tileset.json", "base/content.pnts", "base/0/content.pnts"} {
+		if _, ok := s.Files[f]; !ok {
+			t.Errorf("expected file %s to be written", f)
+		}
+	}
+	if _, ok := s.Files["base/0/1/content.pnts"]; ok {
+		t.Errorf("expected the third level not to be written")
+	}
+}
@@ -0,0 +1,78 @@
+package writer
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path"
+	"strconv"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/conv/coor"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/tree"
+)
+
+type featureCollection struct {
+	Type     string    `json:"type"`
+	Features []feature `json:"features"`
+}
+
+type feature struct {
+	Type       string            `json:"type"`
+	Geometry   polygon           `json:"geometry"`
+	Properties footprintProperty `json:"properties"`
+}
+
+type polygon struct {
+	Type        string         `json:"type"`
+	Coordinates [][][2]float64 `json:"coordinates"`
+}
+
+type footprintProperty struct {
+	Tile   string `json:"tile"`
+	Level  int    `json:"level"`
+	Points int    `json:"points"`
+}
+
+// WriteFootprints writes to the given file a GeoJSON FeatureCollection with the footprint of each tile of the
+// tree rooted at the given node, as a polygon in geographic coordinates. Each feature reports the folder of the
+// tile relative to the tileset root, its level in the tree and the number of points it stores.
+func WriteFootprints(file string, root tree.Node, conv coor.CoordinateConverter) error {
+	fc := featureCollection{Type: "FeatureCollection", Features: []feature{}}
+	if err := appendFootprints(&fc, "", 0, root, conv); err != nil {
+		return err
+	}
+	data, err := json.Marshal(fc)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0644)
+}
+
+func appendFootprints(fc *featureCollection, tile string, level int, node tree.Node, conv coor.CoordinateConverter) error {
+	reg, err := node.GetBoundingBoxRegion(conv)
+	if err != nil {
+		return err
+	}
+	// the region is expressed in radians
+	w, s, e, n := reg.Xmin*180/math.Pi, reg.Ymin*180/math.Pi, reg.Xmax*180/math.Pi, reg.Ymax*180/math.Pi
+	fc.Features = append(fc.Features, feature{
+		Type: "Feature",
+		Geometry: polygon{
+			Type:        "Polygon",
+			Coordinates: [][][2]float64{{{w, s}, {e, s}, {e, n}, {w, n}, {w, s}}},
+		},
+		Properties: footprintProperty{
+			Tile:   tile,
+			Level:  level,
+			Points: node.NumberOfPoints(),
+		},
+	})
+	for i, child := range node.GetChildren() {
+		if child != nil {
+			if err := appendFootprints(fc, path.Join(tile, strconv.Itoa(i)), level+1, child, conv); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
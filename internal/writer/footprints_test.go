@@ -0,0 +1,55 @@
+package writer
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/tree"
+)
+
+func TestWriteFootprints(t *testing.T) {
+	deg := math.Pi / 180
+	child := &tree.MockNode{
+		Region: geom.NewBoundingBox(10*deg, 10.5*deg, 45*deg, 45.5*deg, 0, 10),
+		Pts:    geom.NewLinkedPointStream(nil, 2),
+		Leaf:   true,
+	}
+	root := &tree.MockNode{
+		Region: geom.NewBoundingBox(10*deg, 11*deg, 45*deg, 46*deg, 0, 10),
+		Pts:    geom.NewLinkedPointStream(nil, 5),
+		Root:   true,
+	}
+	root.Children[3] = child
+	file := filepath.Join(t.TempDir(), "footprints.geojson")
+	if err := WriteFootprints(file, root, nil); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	fc := featureCollection{}
+	if err := json.Unmarshal(data, &fc); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if fc.Type != "FeatureCollection" || len(fc.Features) != 2 {
+		t.Fatalf("expected a collection of %d features got %v", 2, fc)
+	}
+	expected := []footprintProperty{{Tile: "", Level: 0, Points: 5}, {Tile: "3", Level: 1, Points: 2}}
+	for i, f := range fc.Features {
+		if f.Properties != expected[i] {
+			t.Errorf("expected properties %v got %v", expected[i], f.Properties)
+		}
+	}
+	ring := fc.Features[1].Geometry.Coordinates[0]
+	if len(ring) != 5 || ring[0] != ring[4] {
+		t.Fatalf("expected a closed ring got %v", ring)
+	}
+	if math.Abs(ring[0][0]-10) > 1e-9 || math.Abs(ring[0][1]-45) > 1e-9 || math.Abs(ring[2][0]-10.5) > 1e-9 || math.Abs(ring[2][1]-45.5) > 1e-9 {
+		t.Errorf("unexpected footprint %v", ring)
+	}
+}
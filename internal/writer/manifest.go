@@ -0,0 +1,155 @@
+package writer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/utils"
+)
+
+// Manifest lists all the files of a tileset together with their size and SHA-256 hash
+type Manifest struct {
+	Files []ManifestEntry `json:"files"`
+}
+
+// ManifestEntry describes a single file of the tileset. The path is relative to the tileset root folder.
+type ManifestEntry struct {
+	Path   string `json:"path"`
+	Sha256 string `json:"sha256"`
+	Size   int    `json:"size"`
+}
+
+// ManifestStorage decorates a Storage recording the hash and size of each file written through it.
+// When closed it closes the decorated storage and writes the manifest as a JSON file.
+type ManifestStorage struct {
+	Storage
+	root         string
+	manifestPath string
+	entries      []ManifestEntry
+	sync.Mutex
+}
+
+// NewManifestStorage wraps the given storage, writing the manifest at manifestPath on Close.
+// The paths in the manifest are computed relative to root.
+func NewManifestStorage(s Storage, root string, manifestPath string) *ManifestStorage {
+	return &ManifestStorage{
+		Storage:      s,
+		root:         root,
+		manifestPath: manifestPath,
+	}
+}
+
+func (s *ManifestStorage) WriteFile(filePath string, data []byte) error {
+	if err := s.Storage.WriteFile(filePath, data); err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(filepath.Clean(s.root), filepath.Clean(filePath))
+	if err != nil {
+		return err
+	}
+	hash := sha256.Sum256(data)
+	s.addEntry(rel, hash[:], len(data))
+	return nil
+}
+
+// Create streams the file to the decorated storage, hashing its content on the fly
+func (s *ManifestStorage) Create(filePath string) (io.WriteCloser, error) {
+	rel, err := filepath.Rel(filepath.Clean(s.root), filepath.Clean(filePath))
+	if err != nil {
+		return nil, err
+	}
+	w, err := s.Storage.Create(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return &hashingFile{WriteCloser: w, hash: sha256.New(), onClose: func(hash []byte, size int) {
+		s.addEntry(rel, hash, size)
+	}}, nil
+}
+
+func (s *ManifestStorage) addEntry(rel string, hash []byte, size int) {
+	s.Lock()
+	defer s.Unlock()
+	s.entries = append(s.entries, ManifestEntry{
+		Path:   filepath.ToSlash(rel),
+		Sha256: hex.EncodeToString(hash),
+		Size:   size,
+	})
+}
+
+func (s *ManifestStorage) Close() error {
+	if err := s.Storage.Close(); err != nil {
+		return err
+	}
+	s.Lock()
+	defer s.Unlock()
+	// files are written concurrently, sort them to get a stable output
+	sort.Slice(s.entries, func(i, j int) bool {
+		return s.entries[i].Path < s.entries[j].Path
+	})
+	data, err := json.MarshalIndent(Manifest{Files: s.entries}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := utils.CreateDirectoryIfDoesNotExist(filepath.Dir(s.manifestPath)); err != nil {
+		return err
+	}
+	return os.WriteFile(s.manifestPath, data, 0666)
+}
+
+// Abort aborts the decorated storage without writing the manifest. Any manifest left at the same path
+// by a previous export is removed, as it would not describe the files currently in the output folder.
+func (s *ManifestStorage) Abort() error {
+	err := s.Storage.Abort()
+	if rmErr := os.Remove(s.manifestPath); rmErr != nil && !os.IsNotExist(rmErr) && err == nil {
+		err = rmErr
+	}
+	return err
+}
+
+// ManifestStorageProvider returns a provider that decorates the storages returned by the given provider
+// with a ManifestStorage. A relative manifestPath is resolved against the root folder of each tileset.
+func ManifestStorageProvider(provider StorageProvider, manifestPath string) StorageProvider {
+	return func(root string) (Storage, error) {
+		s, err := provider(root)
+		if err != nil {
+			return nil, err
+		}
+		p := manifestPath
+		if !filepath.IsAbs(p) {
+			p = path.Join(root, filepath.ToSlash(p))
+		}
+		return NewManifestStorage(s, root, p), nil
+	}
+}
+
+// hashingFile computes the hash and size of the data written through it, reporting them on Close
+type hashingFile struct {
+	io.WriteCloser
+	hash    hash.Hash
+	size    int
+	onClose func(hash []byte, size int)
+}
+
+func (h *hashingFile) Write(p []byte) (int, error) {
+	n, err := h.WriteCloser.Write(p)
+	h.hash.Write(p[:n])
+	h.size += n
+	return n, err
+}
+
+func (h *hashingFile) Close() error {
+	if err := h.WriteCloser.Close(); err != nil {
+		return err
+	}
+	h.onClose(h.hash.Sum(nil), h.size)
+	return nil
+}
@@ -2,6 +2,7 @@ package writer
 
 import (
 	"context"
+	"io"
 	"sync"
 
 	"github.com/mfbonfigli/gocesiumtiler/v2/internal/tree"
@@ -56,3 +57,36 @@ func (m *MockWriter) Write(t tree.Tree, folderName string, ctx context.Context)
 	m.Ctx = ctx
 	return m.Err
 }
+
+type MockStorage struct {
+	Files   map[string][]byte
+	Closed  bool
+	Aborted bool
+	sync.Mutex
+}
+
+func (m *MockStorage) WriteFile(filePath string, data []byte) error {
+	m.Lock()
+	defer m.Unlock()
+	if m.Files == nil {
+		m.Files = map[string][]byte{}
+	}
+	m.Files[filePath] = data
+	return nil
+}
+
+func (m *MockStorage) Create(filePath string) (io.WriteCloser, error) {
+	return &memoryFile{onClose: func(data []byte) error {
+		return m.WriteFile(filePath, data)
+	}}, nil
+}
+
+func (m *MockStorage) Close() error {
+	m.Closed = true
+	return nil
+}
+
+func (m *MockStorage) Abort() error {
+	m.Aborted = true
+	return nil
+}
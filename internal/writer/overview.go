@@ -0,0 +1,113 @@
+package writer
+
+import (
+	"math"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/conv/coor"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/tree"
+)
+
+// overviewNode exposes the root of a tree as a single leaf storing at most maxPoints points, taken from the coarsest
+// levels of the tree. The levels are included whole from the root down while they fit, the first level that does not
+// fit is sampled uniformly to fill the remaining points.
+type overviewNode struct {
+	tree.Node
+	// full are the nodes whose points are all included
+	full []tree.Node
+	// partial are the nodes of the level whose points are sampled
+	partial []tree.Node
+	// sampled is the number of points sampled from the partial level
+	sampled int
+	count   int
+}
+
+// overview returns a leaf node storing at most maxPoints points of the tree rooted at the given node
+func overview(root tree.Node, maxPoints int) tree.Node {
+	n := &overviewNode{Node: root}
+	level := []tree.Node{root}
+	for len(level) > 0 {
+		levelPoints := 0
+		for _, node := range level {
+			levelPoints += node.NumberOfPoints()
+		}
+		if n.count+levelPoints > maxPoints {
+			n.partial = level
+			n.sampled = maxPoints - n.count
+			n.count = maxPoints
+			break
+		}
+		n.full = append(n.full, level...)
+		n.count += levelPoints
+		next := []tree.Node{}
+		for _, node := range level {
+			for _, child := range node.GetChildren() {
+				if child != nil {
+					next = append(next, child)
+				}
+			}
+		}
+		level = next
+	}
+	return n
+}
+
+func (n *overviewNode) GetChildren() [8]tree.Node {
+	return [8]tree.Node{}
+}
+
+func (n *overviewNode) IsLeaf() bool {
+	return true
+}
+
+func (n *overviewNode) NumberOfPoints() int {
+	return n.count
+}
+
+func (n *overviewNode) TotalNumberOfPoints() int {
+	return n.count
+}
+
+func (n *overviewNode) GetPoints(converter coor.CoordinateConverter) geom.Point32List {
+	pts := mergePoints(n.Node, n.full, converter)
+	if n.sampled <= 0 {
+		return pts
+	}
+	var head *geom.LinkedPoint
+	count := 0
+	for i := 0; i < pts.Len(); i++ {
+		pt, err := pts.Next()
+		if err != nil {
+			break
+		}
+		head = &geom.LinkedPoint{Pt: pt, Next: head}
+		count++
+	}
+	partial := mergePoints(n.Node, n.partial, converter)
+	total := partial.Len()
+	for i := 0; i < total; i++ {
+		pt, err := partial.Next()
+		if err != nil {
+			break
+		}
+		// keeps the points where the number of sampled points increases, evenly spread over the level
+		if i*n.sampled/total != (i+1)*n.sampled/total {
+			head = &geom.LinkedPoint{Pt: pt, Next: head}
+			count++
+		}
+	}
+	return geom.NewLinkedPointStream(head, count)
+}
+
+// ComputeGeometricError returns the smallest geometric error of the levels included whole, as the overview contains
+// their full detail, or the error of the root if even the root is sampled
+func (n *overviewNode) ComputeGeometricError() float64 {
+	if len(n.full) == 0 {
+		return n.Node.ComputeGeometricError()
+	}
+	e := math.Inf(1)
+	for _, node := range n.full {
+		e = math.Min(e, node.ComputeGeometricError())
+	}
+	return e
+}
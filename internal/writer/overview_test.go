@@ -0,0 +1,85 @@
+package writer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/tree"
+)
+
+func TestOverview(t *testing.T) {
+	root := overview(newFlattenTestTree(), 3)
+	if !root.IsLeaf() || root.GetChildren() != [8]tree.Node{} {
+		t.Errorf("expected the overview to be a leaf")
+	}
+	if actual := root.NumberOfPoints(); actual != 3 {
+		t.Errorf("expected %d points got %d", 3, actual)
+	}
+	if actual := root.ComputeGeometricError(); actual != 5 {
+		t.Errorf("expected the geometric error of the child %v got %v", 5, actual)
+	}
+	pts := root.GetPoints(nil)
+	if pts.Len() != 3 {
+		t.Fatalf("expected %d points got %d", 3, pts.Len())
+	}
+	xs := map[float32]bool{}
+	for i := 0; i < pts.Len(); i++ {
+		pt, err := pts.Next()
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		xs[pt.X] = true
+	}
+	// the root and the child are included whole, a single point is sampled from the grandchild
+	if !xs[4] || !xs[13] || xs[101] == xs[102] {
+		t.Errorf("unexpected points %v", xs)
+	}
+
+	all := overview(newFlattenTestTree(), 10)
+	if actual := all.NumberOfPoints(); actual != 4 {
+		t.Errorf("expected %d points got %d", 4, actual)
+	}
+	if actual := all.GetPoints(nil).Len(); actual != 4 {
+		t.Errorf("expected %d points got %d", 4, actual)
+	}
+	if actual := all.ComputeGeometricError(); actual != 1 {
+		t.Errorf("expected the geometric error of the finest level %v got %v", 1, actual)
+	}
+
+	sampledRoot := overview(newFlattenTestTree(), 0)
+	if actual := sampledRoot.GetPoints(nil).Len(); actual != 0 {
+		t.Errorf("expected no points got %d", actual)
+	}
+	if actual := sampledRoot.ComputeGeometricError(); actual != 20 {
+		t.Errorf("expected the geometric error of the root %v got %v", 20, actual)
+	}
+}
+
+func TestWriterWithOverview(t *testing.T) {
+	w, err := NewWriter("base", nil, WithOverview(3), WithFlatten(2))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	s := &MockStorage{}
+	w.storageProvider = func(root string) (Storage, error) {
+		return s, nil
+	}
+	if err := w.Write(newFlattenTestTree(), "", context.TODO()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	for _, f := range []string{"base/tileset.json", "base/content.pnts"} {
+		if _, ok := s.Files[f]; !ok {
+			t.Errorf("expected file %s to be written", f)
+		}
+	}
+	if _, ok := s.Files["base/0/content.pnts"]; ok {
+		t.Errorf("expected a single tile to be written")
+	}
+	pts, err := ReadPnts(s.Files["base/content.pnts"])
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(pts) != 3 {
+		t.Errorf("expected %d points got %d", 3, len(pts))
+	}
+}
@@ -0,0 +1,133 @@
+package writer
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+)
+
+// pntsHeaderLength is the length of the header of a pnts file
+const pntsHeaderLength = 28
+
+const (
+	// contentFilePrefix is the name, without the extension, of the content files of the tiles
+	contentFilePrefix = "content."
+	// defaultContentFile is the name of the content files of the tiles unless set with WithContentExtension
+	defaultContentFile = contentFilePrefix + "pnts"
+)
+
+type binaryRef struct {
+	ByteOffset int `json:"byteOffset"`
+}
+
+type pntsFeatureTable struct {
+	PointsLength          int        `json:"POINTS_LENGTH"`
+	RtcCenter             []float64  `json:"RTC_CENTER"`
+	QuantizedVolumeOffset []float64  `json:"QUANTIZED_VOLUME_OFFSET"`
+	QuantizedVolumeScale  []float64  `json:"QUANTIZED_VOLUME_SCALE"`
+	Position              *binaryRef `json:"POSITION"`
+	PositionQuantized     *binaryRef `json:"POSITION_QUANTIZED"`
+	Rgb                   *binaryRef `json:"RGB"`
+	Rgba                  *binaryRef `json:"RGBA"`
+}
+
+// ReadPnts decodes the points stored in a content.pnts file written by the consumer, returning them with absolute
+// EPSG:4978 coordinates. The alpha of RGBA colors is discarded. The intensity, the classification and, when present,
+// the source file index and the point source ID are read from the batch table.
+func ReadPnts(data []byte) ([]geom.Point64, error) {
+	if len(data) < pntsHeaderLength || string(data[0:4]) != "pnts" {
+		return nil, fmt.Errorf("not a pnts file")
+	}
+	lengths := [4]int{}
+	for i := range lengths {
+		lengths[i] = int(binary.LittleEndian.Uint32(data[12+4*i:]))
+	}
+	ftJsonStart := pntsHeaderLength
+	ftBinStart := ftJsonStart + lengths[0]
+	btJsonStart := ftBinStart + lengths[1]
+	btBinStart := btJsonStart + lengths[2]
+	if btBinStart+lengths[3] > len(data) {
+		return nil, fmt.Errorf("truncated pnts file")
+	}
+
+	ft := pntsFeatureTable{}
+	if err := json.Unmarshal(data[ftJsonStart:ftBinStart], &ft); err != nil {
+		return nil, fmt.Errorf("invalid feature table: %w", err)
+	}
+	n := ft.PointsLength
+	colors, colorSize := ft.Rgb, 3
+	if ft.Rgba != nil {
+		colors, colorSize = ft.Rgba, 4
+	}
+	if len(ft.RtcCenter) != 3 || colors == nil || (ft.Position == nil && ft.PositionQuantized == nil) {
+		return nil, fmt.Errorf("unsupported feature table layout")
+	}
+	ftBin := data[ftBinStart:btJsonStart]
+	pts := make([]geom.Point64, n)
+
+	if ft.Position != nil {
+		if ft.Position.ByteOffset+12*n > len(ftBin) {
+			return nil, fmt.Errorf("truncated positions")
+		}
+		for i := range pts {
+			off := ft.Position.ByteOffset + 12*i
+			pts[i].X = ft.RtcCenter[0] + float64(math.Float32frombits(binary.LittleEndian.Uint32(ftBin[off:])))
+			pts[i].Y = ft.RtcCenter[1] + float64(math.Float32frombits(binary.LittleEndian.Uint32(ftBin[off+4:])))
+			pts[i].Z = ft.RtcCenter[2] + float64(math.Float32frombits(binary.LittleEndian.Uint32(ftBin[off+8:])))
+		}
+	} else {
+		if len(ft.QuantizedVolumeOffset) != 3 || len(ft.QuantizedVolumeScale) != 3 || ft.PositionQuantized.ByteOffset+6*n > len(ftBin) {
+			return nil, fmt.Errorf("invalid quantized positions")
+		}
+		for i := range pts {
+			off := ft.PositionQuantized.ByteOffset + 6*i
+			var c [3]float64
+			for k := 0; k < 3; k++ {
+				q := float64(binary.LittleEndian.Uint16(ftBin[off+2*k:]))
+				c[k] = ft.RtcCenter[k] + ft.QuantizedVolumeOffset[k] + q/math.MaxUint16*ft.QuantizedVolumeScale[k]
+			}
+			pts[i].X, pts[i].Y, pts[i].Z = c[0], c[1], c[2]
+		}
+	}
+	if colors.ByteOffset+colorSize*n > len(ftBin) {
+		return nil, fmt.Errorf("truncated colors")
+	}
+	for i := range pts {
+		off := colors.ByteOffset + colorSize*i
+		pts[i].R, pts[i].G, pts[i].B = ftBin[off], ftBin[off+1], ftBin[off+2]
+	}
+
+	if lengths[2] == 0 {
+		return pts, nil
+	}
+	bt := map[string]binaryRef{}
+	if err := json.Unmarshal(data[btJsonStart:btBinStart], &bt); err != nil {
+		return nil, fmt.Errorf("invalid batch table: %w", err)
+	}
+	btBin := data[btBinStart : btBinStart+lengths[3]]
+	for name, size := range map[string]int{"INTENSITY": 1, "CLASSIFICATION": 1, "_SOURCE_FILE": 2, "POINT_SOURCE_ID": 2} {
+		ref, ok := bt[name]
+		if !ok {
+			continue
+		}
+		if ref.ByteOffset+size*n > len(btBin) {
+			return nil, fmt.Errorf("truncated batch table property %s", name)
+		}
+		for i := range pts {
+			switch name {
+			case "INTENSITY":
+				pts[i].Intensity = btBin[ref.ByteOffset+i]
+			case "CLASSIFICATION":
+				pts[i].Classification = btBin[ref.ByteOffset+i]
+			case "_SOURCE_FILE":
+				pts[i].FileIndex = int(binary.LittleEndian.Uint16(btBin[ref.ByteOffset+2*i:]))
+			default:
+				pts[i].PointSourceId = binary.LittleEndian.Uint16(btBin[ref.ByteOffset+2*i:])
+			}
+		}
+	}
+	return pts, nil
+}
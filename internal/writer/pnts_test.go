@@ -0,0 +1,184 @@
+package writer
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/tree"
+)
+
+func TestReadPnts(t *testing.T) {
+	pts := []geom.Point32{
+		geom.NewPoint32(-10, 0, 5, 1, 2, 3, 4, 5),
+		geom.NewPoint32(10, 2, 7, 6, 7, 8, 9, 10),
+		geom.NewPoint32(0.5, 1, 6, 11, 12, 13, 14, 15),
+	}
+	pts[2].FileIndex = 3
+	pts[1].PointSourceId = 1001
+	for _, opt := range []func(*StandardConsumer){
+		func(c *StandardConsumer) {},
+		func(c *StandardConsumer) { c.quantizedPositions = true },
+		func(c *StandardConsumer) { c.sourceFileAttribute = true },
+		func(c *StandardConsumer) { c.sourceFileAttribute, c.pointSourceIdAttribute = true, true },
+		func(c *StandardConsumer) { c.classAlpha = map[uint8]uint8{10: 128} },
+		func(c *StandardConsumer) { c.classAlpha, c.quantizedPositions = map[uint8]uint8{10: 128}, true },
+		func(c *StandardConsumer) { c.perPointBatchId = true },
+		func(c *StandardConsumer) { c.perPointBatchId, c.quantizedPositions = true, true },
+	} {
+		var root *geom.LinkedPoint
+		for i := len(pts) - 1; i >= 0; i-- {
+			root = &geom.LinkedPoint{Pt: pts[i], Next: root}
+		}
+		n := &tree.MockNode{
+			TotalNumPts: 3,
+			Pts:         geom.NewLinkedPointStream(root, 3),
+			Leaf:        true,
+			CenterX:     4000000,
+			CenterY:     900000,
+			CenterZ:     4800000,
+		}
+		s := &MockStorage{}
+		c := NewStandardConsumer(nil, s, opt).(*StandardConsumer)
+		if err := c.writeBinaryPntsFile(WorkUnit{Node: n, BasePath: "tile"}); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		actual, err := ReadPnts(s.Files["tile/content.pnts"])
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if len(actual) != len(pts) {
+			t.Fatalf("expected %d points got %d", len(pts), len(actual))
+		}
+		for i, pt := range pts {
+			a := actual[i]
+			if math.Abs(a.X-float64(pt.X)-4000000) > 1e-3 || math.Abs(a.Y-float64(pt.Y)-900000) > 1e-3 || math.Abs(a.Z-float64(pt.Z)-4800000) > 1e-3 {
+				t.Errorf("point %d: expected coordinates of %v got %v", i, pt, a)
+			}
+			if a.R != pt.R || a.G != pt.G || a.B != pt.B || a.Intensity != pt.Intensity || a.Classification != pt.Classification {
+				t.Errorf("point %d: expected attributes of %v got %v", i, pt, a)
+			}
+			if c.sourceFileAttribute && a.FileIndex != int(pt.FileIndex) {
+				t.Errorf("point %d: expected file index %d got %d", i, pt.FileIndex, a.FileIndex)
+			}
+			if c.pointSourceIdAttribute && a.PointSourceId != pt.PointSourceId {
+				t.Errorf("point %d: expected point source ID %d got %d", i, pt.PointSourceId, a.PointSourceId)
+			}
+		}
+	}
+
+	if _, err := ReadPnts([]byte("b3dm")); err == nil {
+		t.Errorf("expected error for invalid content, got none")
+	}
+}
+
+func TestPerPointBatchId(t *testing.T) {
+	for _, quantized := range []bool{false, true} {
+		n := &tree.MockNode{
+			TotalNumPts: 3,
+			Pts: geom.NewLinkedPointStream(&geom.LinkedPoint{
+				Pt: geom.NewPoint32(1, 2, 3, 4, 5, 6, 7, 8),
+				Next: &geom.LinkedPoint{
+					Pt:   geom.NewPoint32(2, 3, 4, 5, 6, 7, 8, 9),
+					Next: &geom.LinkedPoint{Pt: geom.NewPoint32(3, 4, 5, 6, 7, 8, 9, 10)},
+				},
+			}, 3),
+			Leaf: true,
+		}
+		s := &MockStorage{}
+		c := NewStandardConsumer(nil, s, func(c *StandardConsumer) {
+			c.perPointBatchId = true
+			c.quantizedPositions = quantized
+		}).(*StandardConsumer)
+		if err := c.writeBinaryPntsFile(WorkUnit{Node: n, BasePath: "tile"}); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		data := s.Files["tile/content.pnts"]
+		ftLen := int(binary.LittleEndian.Uint32(data[12:]))
+		ft := struct {
+			BatchLength int `json:"BATCH_LENGTH"`
+			BatchId     struct {
+				ByteOffset    int    `json:"byteOffset"`
+				ComponentType string `json:"componentType"`
+			} `json:"BATCH_ID"`
+		}{}
+		if err := json.Unmarshal(data[pntsHeaderLength:pntsHeaderLength+ftLen], &ft); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if ft.BatchLength != 3 || ft.BatchId.ComponentType != "UNSIGNED_INT" {
+			t.Errorf("quantized %v: unexpected batch length %d or component type %s", quantized, ft.BatchLength, ft.BatchId.ComponentType)
+		}
+		if ft.BatchId.ByteOffset%4 != 0 {
+			t.Errorf("quantized %v: expected the batch ids to be aligned to 4 bytes, got offset %d", quantized, ft.BatchId.ByteOffset)
+		}
+		ftBin := data[pntsHeaderLength+ftLen:]
+		for i := 0; i < 3; i++ {
+			if id := binary.LittleEndian.Uint32(ftBin[ft.BatchId.ByteOffset+4*i:]); id != uint32(i) {
+				t.Errorf("quantized %v: expected batch id %d got %d", quantized, i, id)
+			}
+		}
+		offset := pntsHeaderLength
+		for i := 0; i < 4; i++ {
+			offset += int(binary.LittleEndian.Uint32(data[12+4*i:]))
+		}
+		if offset != len(data) {
+			t.Errorf("quantized %v: expected %d bytes got %d", quantized, offset, len(data))
+		}
+	}
+}
+
+func TestBufferAlignment(t *testing.T) {
+	for _, alignment := range []int{8, 16} {
+		for _, quantized := range []bool{false, true} {
+			n := &tree.MockNode{
+				TotalNumPts: 3,
+				Pts: geom.NewLinkedPointStream(&geom.LinkedPoint{
+					Pt: geom.NewPoint32(1, 2, 3, 4, 5, 6, 7, 8),
+					Next: &geom.LinkedPoint{
+						Pt:   geom.NewPoint32(2, 3, 4, 5, 6, 7, 8, 9),
+						Next: &geom.LinkedPoint{Pt: geom.NewPoint32(3, 4, 5, 6, 7, 8, 9, 10)},
+					},
+				}, 3),
+				Leaf: true,
+			}
+			s := &MockStorage{}
+			c := NewStandardConsumer(nil, s, func(c *StandardConsumer) {
+				c.bufferAlignment = alignment
+				c.quantizedPositions = quantized
+				c.sourceFileAttribute = true
+			}).(*StandardConsumer)
+			if err := c.writeBinaryPntsFile(WorkUnit{Node: n, BasePath: "tile"}); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			data := s.Files["tile/content.pnts"]
+			offset := pntsHeaderLength
+			for i := 0; i < 4; i++ {
+				offset += int(binary.LittleEndian.Uint32(data[12+4*i:]))
+				if offset%alignment != 0 {
+					t.Errorf("alignment %d: expected section %d to end at an aligned offset, got %d", alignment, i, offset)
+				}
+			}
+			if offset != len(data) {
+				t.Errorf("expected %d bytes got %d", offset, len(data))
+			}
+			pts, err := ReadPnts(data)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if len(pts) != 3 || pts[2].Intensity != 9 || pts[2].Classification != 10 {
+				t.Errorf("unexpected points %v", pts)
+			}
+		}
+	}
+
+	w, err := NewWriter("base", nil, WithBufferAlignment(6))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	c := NewStandardConsumer(nil, nil, w.consumerOptions...).(*StandardConsumer)
+	if c.bufferAlignment != 8 {
+		t.Errorf("expected the alignment to be rounded up to %d got %d", 8, c.bufferAlignment)
+	}
+}
@@ -0,0 +1,54 @@
+package writer
+
+import (
+	"io"
+)
+
+// ContentPostProcessor transforms the bytes of a tile content before they are stored. The path is the slash separated
+// path of the content relative to the tileset root, e.g. "0/3/content.pnts".
+type ContentPostProcessor func(path string, data []byte) ([]byte, error)
+
+// postProcessingStorage decorates a Storage passing the tile contents through a ContentPostProcessor before storing
+// them. The other files, such as the tileset.json files, are stored unchanged.
+type postProcessingStorage struct {
+	Storage
+	root    string
+	process ContentPostProcessor
+}
+
+func (s *postProcessingStorage) WriteFile(filePath string, data []byte) error {
+	if !isTileContent(filePath) {
+		return s.Storage.WriteFile(filePath, data)
+	}
+	rel, err := relativeArchivePath(s.root, filePath)
+	if err != nil {
+		return err
+	}
+	data, err = s.process(rel, data)
+	if err != nil {
+		return err
+	}
+	return s.Storage.WriteFile(filePath, data)
+}
+
+// Create buffers the tile contents in memory, as the post processor needs the complete content, storing them on Close
+func (s *postProcessingStorage) Create(filePath string) (io.WriteCloser, error) {
+	if !isTileContent(filePath) {
+		return s.Storage.Create(filePath)
+	}
+	return &memoryFile{onClose: func(data []byte) error {
+		return s.WriteFile(filePath, data)
+	}}, nil
+}
+
+// PostProcessingStorageProvider returns a provider that decorates the storages returned by the given provider
+// passing the tile contents through the given post processor
+func PostProcessingStorageProvider(provider StorageProvider, process ContentPostProcessor) StorageProvider {
+	return func(root string) (Storage, error) {
+		s, err := provider(root)
+		if err != nil {
+			return nil, err
+		}
+		return &postProcessingStorage{Storage: s, root: root, process: process}, nil
+	}
+}
@@ -0,0 +1,55 @@
+package writer
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestPostProcessingStorage(t *testing.T) {
+	m := &MockStorage{}
+	var paths []string
+	provider := PostProcessingStorageProvider(func(root string) (Storage, error) {
+		return m, nil
+	}, func(path string, data []byte) ([]byte, error) {
+		paths = append(paths, path)
+		if path == "1/content.pnts" {
+			return nil, fmt.Errorf("mock error")
+		}
+		return append([]byte{0}, data...), nil
+	})
+	s, err := provider("/out")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := s.WriteFile("/out/content.pnts", []byte{1, 2}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	w, err := s.Create("/out/0/content.pnts")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	w.Write([]byte{3})
+	w.Write([]byte{4})
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := s.WriteFile("/out/tileset.json", []byte{5}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := s.WriteFile("/out/1/content.pnts", []byte{6}); err == nil {
+		t.Errorf("expected the post processor error, got none")
+	}
+
+	if expected := []string{"content.pnts", "0/content.pnts", "1/content.pnts"}; !reflect.DeepEqual(paths, expected) {
+		t.Errorf("expected the post processor to be called for %v got %v", expected, paths)
+	}
+	expected := map[string][]byte{
+		"/out/content.pnts":   {0, 1, 2},
+		"/out/0/content.pnts": {0, 3, 4},
+		"/out/tileset.json":   {5},
+	}
+	if !reflect.DeepEqual(m.Files, expected) {
+		t.Errorf("expected files %v got %v", expected, m.Files)
+	}
+}
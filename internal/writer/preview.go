@@ -0,0 +1,145 @@
+package writer
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"sort"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/conv/coor"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/tree"
+)
+
+// previewPointsPerPixel is the number of points, on average, drawn in each pixel of the preview image
+const previewPointsPerPixel = 4
+
+// previewPoint is a point projected on the plane tangent to the ellipsoid at the center of the cloud
+type previewPoint struct {
+	east, north, up float64
+	color           color.RGBA
+}
+
+// WritePreview writes to the given file a PNG image of the given size with the top-down orthographic view of the
+// points of the tree rooted at the given node. The points are projected on the plane tangent to the Earth at the
+// center of the root, scaled to fit the image preserving the aspect ratio, and drawn from the lowest to the highest.
+// The points are thinned taking them from the coarsest levels of the tree, about four for each pixel. The points are
+// colored with their RGB colors or, if no point has a color, with a ramp of their elevation. The pixels without
+// points are transparent.
+func WritePreview(file string, root tree.Node, conv coor.CoordinateConverter, width, height int) error {
+	if width <= 0 || height <= 0 {
+		return fmt.Errorf("invalid preview size %dx%d: must be greater than zero", width, height)
+	}
+	pts, err := previewPoints(root, conv, width*height*previewPointsPerPixel)
+	if err != nil {
+		return err
+	}
+	img := renderPreview(pts, width, height)
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// previewPoints returns at most maxPoints points of the tree, projected on the east, north and up axes of the
+// plane tangent to the Earth at the center of the root
+func previewPoints(root tree.Node, conv coor.CoordinateConverter, maxPoints int) ([]previewPoint, error) {
+	cX, cY, cZ, err := root.GetCenter(conv)
+	if err != nil {
+		return nil, err
+	}
+	lon := math.Atan2(cY, cX)
+	lat := math.Atan2(cZ, math.Hypot(cX, cY))
+	east := [3]float64{-math.Sin(lon), math.Cos(lon), 0}
+	north := [3]float64{-math.Sin(lat) * math.Cos(lon), -math.Sin(lat) * math.Sin(lon), math.Cos(lat)}
+	up := [3]float64{math.Cos(lat) * math.Cos(lon), math.Cos(lat) * math.Sin(lon), math.Sin(lat)}
+	dot := func(a [3]float64, x, y, z float64) float64 {
+		return a[0]*x + a[1]*y + a[2]*z
+	}
+	// the points of the overview are referred to the center of the root
+	stream := overview(root, maxPoints).GetPoints(conv)
+	pts := make([]previewPoint, 0, stream.Len())
+	for i := 0; i < stream.Len(); i++ {
+		pt, err := stream.Next()
+		if err != nil {
+			break
+		}
+		x, y, z := float64(pt.X), float64(pt.Y), float64(pt.Z)
+		pts = append(pts, previewPoint{
+			east:  dot(east, x, y, z),
+			north: dot(north, x, y, z),
+			up:    dot(up, x, y, z),
+			color: color.RGBA{R: pt.R, G: pt.G, B: pt.B, A: 255},
+		})
+	}
+	return pts, nil
+}
+
+// renderPreview draws the points on an image of the given size
+func renderPreview(pts []previewPoint, width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	if len(pts) == 0 {
+		return img
+	}
+	minE, minN, minU := math.Inf(1), math.Inf(1), math.Inf(1)
+	maxE, maxN, maxU := math.Inf(-1), math.Inf(-1), math.Inf(-1)
+	colored := false
+	for _, p := range pts {
+		minE, maxE = math.Min(minE, p.east), math.Max(maxE, p.east)
+		minN, maxN = math.Min(minN, p.north), math.Max(maxN, p.north)
+		minU, maxU = math.Min(minU, p.up), math.Max(maxU, p.up)
+		colored = colored || p.color.R != 0 || p.color.G != 0 || p.color.B != 0
+	}
+	// the same scale on both axes, the cloud is centered along the axis with spare pixels
+	scale := math.Inf(1)
+	if maxE > minE {
+		scale = float64(width-1) / (maxE - minE)
+	}
+	if maxN > minN {
+		scale = math.Min(scale, float64(height-1)/(maxN-minN))
+	}
+	if math.IsInf(scale, 1) {
+		scale = 0
+	}
+	offsetX := (float64(width-1) - (maxE-minE)*scale) / 2
+	offsetY := (float64(height-1) - (maxN-minN)*scale) / 2
+	sort.Slice(pts, func(i, j int) bool {
+		return pts[i].up < pts[j].up
+	})
+	for _, p := range pts {
+		c := p.color
+		if !colored {
+			c = elevationColor(p.up, minU, maxU)
+		}
+		x := int(math.Round(offsetX + (p.east-minE)*scale))
+		// the image rows grow southwards
+		y := height - 1 - int(math.Round(offsetY+(p.north-minN)*scale))
+		img.SetRGBA(x, y, c)
+	}
+	return img
+}
+
+// elevationColor returns the color of the elevation z in the [min, max] range, on a ramp going from blue at the
+// bottom through green and yellow to red at the top
+func elevationColor(z, min, max float64) color.RGBA {
+	t := 0.0
+	if max > min {
+		t = (z - min) / (max - min)
+	}
+	ramp := [][3]float64{{0, 0, 255}, {0, 255, 0}, {255, 255, 0}, {255, 0, 0}}
+	pos := t * float64(len(ramp)-1)
+	i := int(math.Min(math.Floor(pos), float64(len(ramp)-2)))
+	f := pos - float64(i)
+	c := [3]uint8{}
+	for k := range c {
+		c[k] = uint8(math.Round(ramp[i][k] + (ramp[i+1][k]-ramp[i][k])*f))
+	}
+	return color.RGBA{R: c[0], G: c[1], B: c[2], A: 255}
+}
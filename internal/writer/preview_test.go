@@ -0,0 +1,94 @@
+package writer
+
+import (
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/tree"
+)
+
+func TestWritePreview(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "preview.png")
+	// with the center at the origin the east axis is Y, the north axis is Z and the up axis is X
+	if err := WritePreview(file, newFlattenTestTree(), nil, 4, 4); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != 4 || b.Dy() != 4 {
+		t.Fatalf("expected a 4x4 image got %v", b)
+	}
+	pointColor := color.RGBA{R: 1, G: 2, B: 3, A: 255}
+	for _, c := range []struct {
+		x, y     int
+		expected color.RGBA
+	}{
+		// the south west and north east corners
+		{0, 3, pointColor},
+		{3, 0, pointColor},
+		{1, 2, pointColor},
+		{0, 0, color.RGBA{}},
+		{3, 3, color.RGBA{}},
+	} {
+		if actual := color.RGBAModel.Convert(img.At(c.x, c.y)).(color.RGBA); actual != c.expected {
+			t.Errorf("at %d,%d expected color %v got %v", c.x, c.y, c.expected, actual)
+		}
+	}
+
+	if err := WritePreview(file, newFlattenTestTree(), nil, 0, 4); err == nil {
+		t.Errorf("expected error for an invalid size, got none")
+	}
+}
+
+func TestRenderPreviewElevationColors(t *testing.T) {
+	root := &tree.MockNode{
+		Pts: geom.NewLinkedPointStream(&geom.LinkedPoint{
+			Pt:   geom.NewPoint32(0, 0, 0, 0, 0, 0, 0, 0),
+			Next: &geom.LinkedPoint{Pt: geom.NewPoint32(10, 2, 2, 0, 0, 0, 0, 0)},
+		}, 2),
+		TotalNumPts: 2,
+		Root:        true,
+		Leaf:        true,
+	}
+	pts, err := previewPoints(root, nil, 10)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	img := renderPreview(pts, 2, 2)
+	// the lowest point is blue, the highest red
+	if actual := img.RGBAAt(0, 1); actual != (color.RGBA{B: 255, A: 255}) {
+		t.Errorf("expected the lowest point to be blue got %v", actual)
+	}
+	if actual := img.RGBAAt(1, 0); actual != (color.RGBA{R: 255, A: 255}) {
+		t.Errorf("expected the highest point to be red got %v", actual)
+	}
+}
+
+func TestElevationColor(t *testing.T) {
+	for _, c := range []struct {
+		z        float64
+		expected color.RGBA
+	}{
+		{0, color.RGBA{B: 255, A: 255}},
+		{1.5, color.RGBA{R: 128, G: 255, A: 255}},
+		{3, color.RGBA{R: 255, A: 255}},
+	} {
+		if actual := elevationColor(c.z, 0, 3); actual != c.expected {
+			t.Errorf("for %v expected %v got %v", c.z, c.expected, actual)
+		}
+	}
+	if actual := elevationColor(5, 5, 5); actual != (color.RGBA{B: 255, A: 255}) {
+		t.Errorf("expected blue for an empty range got %v", actual)
+	}
+}
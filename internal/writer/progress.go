@@ -0,0 +1,71 @@
+package writer
+
+import (
+	"io"
+	"path"
+	"strings"
+	"sync/atomic"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/tree"
+)
+
+// ProgressFunc receives the number of tiles written so far and the total number of tiles of the tileset
+type ProgressFunc func(written, total int)
+
+// tileCountingStorage decorates a Storage counting the tiles whose content has been fully written
+type tileCountingStorage struct {
+	Storage
+	written int64
+}
+
+func (s *tileCountingStorage) WriteFile(filePath string, data []byte) error {
+	if err := s.Storage.WriteFile(filePath, data); err != nil {
+		return err
+	}
+	if isTileContent(filePath) {
+		atomic.AddInt64(&s.written, 1)
+	}
+	return nil
+}
+
+func (s *tileCountingStorage) Create(filePath string) (io.WriteCloser, error) {
+	w, err := s.Storage.Create(filePath)
+	if err != nil || !isTileContent(filePath) {
+		return w, err
+	}
+	return &countingFile{WriteCloser: w, counter: &s.written}, nil
+}
+
+func (s *tileCountingStorage) tilesWritten() int {
+	return int(atomic.LoadInt64(&s.written))
+}
+
+// isTileContent returns whether the file is the content of a tile, whatever its extension
+func isTileContent(filePath string) bool {
+	return strings.HasPrefix(path.Base(filePath), contentFilePrefix)
+}
+
+// countingFile increments the counter when the file is successfully closed
+type countingFile struct {
+	io.WriteCloser
+	counter *int64
+}
+
+func (c *countingFile) Close() error {
+	if err := c.WriteCloser.Close(); err != nil {
+		return err
+	}
+	atomic.AddInt64(c.counter, 1)
+	return nil
+}
+
+// countTiles returns the number of tiles that will be written for the tree rooted at the given node
+func countTiles(node tree.Node) int {
+	count := 1
+	for _, child := range node.GetChildren() {
+		if child != nil {
+			count += countTiles(child)
+		}
+	}
+	return count
+}
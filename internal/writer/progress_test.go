@@ -0,0 +1,129 @@
+package writer
+
+import (
+	"context"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/conv/coor"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/tree"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/utils"
+)
+
+// contentConsumer writes an empty content.pnts file for each work unit
+type contentConsumer struct {
+	storage Storage
+}
+
+func (c *contentConsumer) Consume(workchan chan *WorkUnit, errchan chan error, waitGroup *sync.WaitGroup) {
+	defer waitGroup.Done()
+	for work := range workchan {
+		w, err := c.storage.Create(path.Join(work.BasePath, "content.pnts"))
+		if err != nil {
+			errchan <- err
+			return
+		}
+		w.Close()
+		c.storage.WriteFile(path.Join(work.BasePath, "tileset.json"), []byte("{}"))
+	}
+}
+
+func TestWriterWithProgress(t *testing.T) {
+	pt := &geom.LinkedPoint{Pt: geom.NewPoint32(1, 2, 3, 4, 5, 6, 7, 8)}
+	leaf := func() *tree.MockNode {
+		return &tree.MockNode{TotalNumPts: 1, Pts: geom.NewLinkedPointStream(pt, 1), Leaf: true}
+	}
+	root := &tree.MockNode{
+		TotalNumPts: 3,
+		Pts:         geom.NewLinkedPointStream(pt, 1),
+		Root:        true,
+		Children:    [8]tree.Node{leaf(), nil, leaf()},
+	}
+	if n := countTiles(root); n != 3 {
+		t.Errorf("expected %d tiles got %d", 3, n)
+	}
+
+	type call struct{ written, total int }
+	calls := []call{}
+	w, err := NewWriter("base", nil, WithProgress(func(written, total int) {
+		calls = append(calls, call{written, total})
+	}, time.Millisecond))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	w.consumerFunc = func(cc coor.CoordinateConverter, s Storage) Consumer {
+		return &contentConsumer{storage: s}
+	}
+	s := &MockStorage{}
+	w.storageProvider = func(root string) (Storage, error) {
+		return s, nil
+	}
+	if err := w.Write(root, "", context.TODO()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(calls) == 0 {
+		t.Fatalf("expected progress to be reported")
+	}
+	if last := calls[len(calls)-1]; last != (call{3, 3}) {
+		t.Errorf("expected last progress %v got %v", call{3, 3}, last)
+	}
+	for i := 1; i < len(calls); i++ {
+		if calls[i].written < calls[i-1].written {
+			t.Errorf("expected non decreasing progress got %v", calls)
+		}
+	}
+	if len(s.Files) != 6 {
+		t.Errorf("expected %d files written got %d", 6, len(s.Files))
+	}
+}
+
+func TestWriterProgressInterval(t *testing.T) {
+	clock := utils.NewMockClock(time.Unix(0, 0))
+	calls := make(chan int, 10)
+	w, err := NewWriter("base", nil, WithClock(clock), WithProgress(func(written, total int) {
+		calls <- written
+	}, time.Second))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	counter := &tileCountingStorage{Storage: &MockStorage{}}
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	done := make(chan struct{})
+	go func() {
+		w.waitReportingProgress(wg, counter, 3)
+		close(done)
+	}()
+	for clock.Tickers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	clock.Advance(900 * time.Millisecond)
+	select {
+	case n := <-calls:
+		t.Errorf("expected no progress before the interval elapsed got %d", n)
+	default:
+	}
+	counter.WriteFile(path.Join("0", "content.pnts"), nil)
+	clock.Advance(100 * time.Millisecond)
+	select {
+	case n := <-calls:
+		if n != 1 {
+			t.Errorf("expected progress %d got %d", 1, n)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected progress to be reported when the interval elapsed")
+	}
+
+	wg.Done()
+	<-done
+	if n := <-calls; n != 1 {
+		t.Errorf("expected final progress %d got %d", 1, n)
+	}
+	if n := clock.Tickers(); n != 0 {
+		t.Errorf("expected the ticker to be stopped, %d running", n)
+	}
+}
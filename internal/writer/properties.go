@@ -0,0 +1,64 @@
+package writer
+
+import (
+	"math"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/conv/coor"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/tree"
+)
+
+// computeProperties returns the range of the intensity, the classification and the ellipsoidal height of all
+// the points of the tree rooted at the given node, as expected by the properties object of the root tileset.json
+func computeProperties(root tree.Node, conv coor.CoordinateConverter) (map[string]Property, error) {
+	ranges := map[string]*Property{}
+	for _, name := range []string{"INTENSITY", "CLASSIFICATION", "Height"} {
+		ranges[name] = &Property{Minimum: math.Inf(1), Maximum: math.Inf(-1)}
+	}
+	update := func(name string, v float64) {
+		r := ranges[name]
+		r.Minimum = math.Min(r.Minimum, v)
+		r.Maximum = math.Max(r.Maximum, v)
+	}
+	var visit func(n tree.Node) error
+	visit = func(n tree.Node) error {
+		cX, cY, cZ, err := n.GetCenter(conv)
+		if err != nil {
+			return err
+		}
+		list := n.GetPoints(conv)
+		list.Reset()
+		for i := 0; i < list.Len(); i++ {
+			pt, err := list.Next()
+			if err != nil {
+				return err
+			}
+			c, err := conv.ToSrid(coor.EcefSrid, coor.GeographicSrid, geom.Coord{X: float64(pt.X) + cX, Y: float64(pt.Y) + cY, Z: float64(pt.Z) + cZ})
+			if err != nil {
+				return err
+			}
+			update("INTENSITY", float64(pt.Intensity))
+			update("CLASSIFICATION", float64(pt.Classification))
+			update("Height", c.Z)
+		}
+		list.Reset()
+		for _, child := range n.GetChildren() {
+			if child != nil {
+				if err := visit(child); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := visit(root); err != nil {
+		return nil, err
+	}
+	properties := map[string]Property{}
+	for name, r := range ranges {
+		if r.Minimum <= r.Maximum {
+			properties[name] = *r
+		}
+	}
+	return properties, nil
+}
@@ -0,0 +1,77 @@
+package writer
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/tree"
+)
+
+func TestWriterWithProperties(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		w, err := NewWriter("base", nil, WithProperties(enabled))
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		s := &MockStorage{}
+		w.storageProvider = func(root string) (Storage, error) {
+			return s, nil
+		}
+		// two points 100 and 250 meters above the ellipsoid, one in the root and one in the child
+		low, err := w.conv.ToWGS84Cartesian(geom.Coord{X: 10, Y: 45, Z: 100}, 4326)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		high, err := w.conv.ToWGS84Cartesian(geom.Coord{X: 10, Y: 45, Z: 250}, 4326)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		child := &tree.MockNode{
+			Pts:         geom.NewLinkedPointStream(&geom.LinkedPoint{Pt: geom.NewPoint32(0, 0, 0, 0, 0, 0, 200, 6)}, 1),
+			TotalNumPts: 1,
+			Leaf:        true,
+			CenterX:     high.X,
+			CenterY:     high.Y,
+			CenterZ:     high.Z,
+		}
+		root := &tree.MockNode{
+			Pts:         geom.NewLinkedPointStream(&geom.LinkedPoint{Pt: geom.NewPoint32(0, 0, 0, 0, 0, 0, 10, 2)}, 1),
+			TotalNumPts: 2,
+			Root:        true,
+			Children:    [8]tree.Node{child},
+			CenterX:     low.X,
+			CenterY:     low.Y,
+			CenterZ:     low.Z,
+		}
+		if err := w.Write(root, "", context.TODO()); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		tileset := Tileset{}
+		if err := json.Unmarshal(s.Files["base/tileset.json"], &tileset); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if !enabled {
+			if tileset.Properties != nil {
+				t.Errorf("expected no properties got %v", tileset.Properties)
+			}
+			continue
+		}
+		expected := map[string]Property{
+			"INTENSITY":      {Minimum: 10, Maximum: 200},
+			"CLASSIFICATION": {Minimum: 2, Maximum: 6},
+			"Height":         {Minimum: 100, Maximum: 250},
+		}
+		if len(tileset.Properties) != len(expected) {
+			t.Fatalf("expected properties %v got %v", expected, tileset.Properties)
+		}
+		for name, e := range expected {
+			a := tileset.Properties[name]
+			if math.Abs(a.Minimum-e.Minimum) > 1e-3 || math.Abs(a.Maximum-e.Maximum) > 1e-3 {
+				t.Errorf("property %s: expected %v got %v", name, e, a)
+			}
+		}
+	}
+}
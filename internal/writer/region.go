@@ -0,0 +1,65 @@
+package writer
+
+import (
+	"sync"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/conv/coor"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/tree"
+)
+
+// prunedNode exposes a tree node dropping the children whose bounding region does not intersect an area, together
+// with their subtree. The nodes intersecting the area are kept with their points, so that the area is displayed
+// with all the levels of detail of the whole tree.
+type prunedNode struct {
+	tree.Node
+	// area is [west, south, east, north] in radians
+	area         [4]float64
+	conv         coor.CoordinateConverter
+	children     [8]tree.Node
+	childrenOnce sync.Once
+}
+
+// prune returns the given root node pruned to the area [west, south, east, north], in radians
+func prune(root tree.Node, area [4]float64, conv coor.CoordinateConverter) tree.Node {
+	return &prunedNode{Node: root, area: area, conv: conv}
+}
+
+// intersects returns true if the bounding region of the given node intersects the area. The nodes whose region
+// cannot be computed are assumed to intersect it.
+func intersects(node tree.Node, area [4]float64, conv coor.CoordinateConverter) bool {
+	reg, err := node.GetBoundingBoxRegion(conv)
+	if err != nil {
+		return true
+	}
+	return reg.Xmin <= area[2] && area[0] <= reg.Xmax && reg.Ymin <= area[3] && area[1] <= reg.Ymax
+}
+
+func (n *prunedNode) GetChildren() [8]tree.Node {
+	n.childrenOnce.Do(func() {
+		for i, child := range n.Node.GetChildren() {
+			if child != nil && intersects(child, n.area, n.conv) {
+				n.children[i] = &prunedNode{Node: child, area: n.area, conv: n.conv}
+			}
+		}
+	})
+	return n.children
+}
+
+func (n *prunedNode) IsLeaf() bool {
+	for _, child := range n.GetChildren() {
+		if child != nil && child.TotalNumberOfPoints() > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (n *prunedNode) TotalNumberOfPoints() int {
+	total := n.Node.NumberOfPoints()
+	for _, child := range n.GetChildren() {
+		if child != nil {
+			total += child.TotalNumberOfPoints()
+		}
+	}
+	return total
+}
@@ -0,0 +1,108 @@
+package writer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/conv/coor"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/tree"
+)
+
+func newRegionTestTree() *tree.MockNode {
+	pts := func(n int) geom.Point32List {
+		var head *geom.LinkedPoint
+		for i := 0; i < n; i++ {
+			head = &geom.LinkedPoint{Pt: geom.NewPoint32(float32(i), 0, 0, 1, 2, 3, 4, 5), Next: head}
+		}
+		return geom.NewLinkedPointStream(head, n)
+	}
+	west := &tree.MockNode{
+		Region:      geom.NewBoundingBox(0, 0.5, 0, 1, 0, 10),
+		Pts:         pts(2),
+		TotalNumPts: 2,
+		Leaf:        true,
+	}
+	eastLeaf := &tree.MockNode{
+		Region:      geom.NewBoundingBox(0.9, 1, 0, 0.2, 0, 10),
+		Pts:         pts(1),
+		TotalNumPts: 1,
+		Leaf:        true,
+	}
+	east := &tree.MockNode{
+		Region:      geom.NewBoundingBox(0.5, 1, 0, 1, 0, 10),
+		Pts:         pts(3),
+		Children:    [8]tree.Node{nil, eastLeaf},
+		TotalNumPts: 4,
+	}
+	return &tree.MockNode{
+		Region:      geom.NewBoundingBox(0, 1, 0, 1, 0, 10),
+		Pts:         pts(4),
+		Children:    [8]tree.Node{west, east},
+		TotalNumPts: 10,
+		Root:        true,
+	}
+}
+
+func TestPrune(t *testing.T) {
+	// the area intersects the east node but not its leaf child
+	root := prune(newRegionTestTree(), [4]float64{0.6, 0.5, 0.8, 0.9}, nil)
+	children := root.GetChildren()
+	if children[0] != nil {
+		t.Errorf("expected the west node to be pruned")
+	}
+	east := children[1]
+	if east == nil {
+		t.Fatalf("expected the east node to be kept")
+	}
+	if !east.IsLeaf() || east.NumberOfPoints() != 3 || east.TotalNumberOfPoints() != 3 {
+		t.Errorf("expected the east node to become a leaf with 3 points, got leaf %v with %d points", east.IsLeaf(), east.TotalNumberOfPoints())
+	}
+	if root.IsLeaf() || root.TotalNumberOfPoints() != 7 {
+		t.Errorf("expected the root to keep 7 points got %d", root.TotalNumberOfPoints())
+	}
+	if countTiles(root) != 2 {
+		t.Errorf("expected 2 tiles got %d", countTiles(root))
+	}
+
+	// an area covering the whole tree keeps all the nodes
+	root = prune(newRegionTestTree(), [4]float64{-1, -1, 2, 2}, nil)
+	if root.TotalNumberOfPoints() != 10 || countTiles(root) != 4 {
+		t.Errorf("expected all the nodes to be kept, got %d points in %d tiles", root.TotalNumberOfPoints(), countTiles(root))
+	}
+}
+
+func TestWriterWithOutputRegion(t *testing.T) {
+	for _, c := range []struct {
+		area      [4]float64
+		expectErr bool
+	}{
+		{[4]float64{0.6, 0.5, 0.8, 0.9}, false},
+		{[4]float64{2, 2, 3, 3}, true},
+	} {
+		w, err := NewWriter("base", nil, WithOutputRegion(c.area))
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		w.producerFunc = func(basepath, folder string) Producer {
+			return &MockProducer{}
+		}
+		w.consumerFunc = func(cc coor.CoordinateConverter, s Storage) Consumer {
+			return &MockConsumer{}
+		}
+		w.storageProvider = func(root string) (Storage, error) {
+			return &MockStorage{}, nil
+		}
+		totals := []int{}
+		w.progress = func(done, total int) {
+			totals = append(totals, total)
+		}
+		err = w.Write(newRegionTestTree(), "base", context.TODO())
+		if (err != nil) != c.expectErr {
+			t.Errorf("area %v: expected error %v got %v", c.area, c.expectErr, err)
+		}
+		if !c.expectErr && (len(totals) == 0 || totals[len(totals)-1] != 2) {
+			t.Errorf("area %v: expected 2 tiles to be written got %v", c.area, totals)
+		}
+	}
+}
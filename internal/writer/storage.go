@@ -0,0 +1,244 @@
+package writer
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/utils"
+)
+
+// Storage abstracts the destination where the tileset files are persisted.
+// Implementations must be safe for concurrent use by multiple consumers.
+type Storage interface {
+	// WriteFile stores the given data at the given path. The path is the full path
+	// computed by the producer, i.e. it includes the base path of the tileset.
+	WriteFile(filePath string, data []byte) error
+	// Create returns a writer to stream the content of the file at the given path. The file is
+	// stored when the writer is closed, the writer must always be closed even if writing fails.
+	Create(filePath string) (io.WriteCloser, error)
+	// Close finalizes the storage. No writes are allowed after Close is called.
+	Close() error
+	// Abort releases the resources held by the storage discarding, where possible, the files of an export
+	// that failed or was cancelled. No writes are allowed after Abort is called.
+	Abort() error
+}
+
+// FsStorage writes the tileset files as loose files on the filesystem
+type FsStorage struct{}
+
+func NewFsStorage() *FsStorage {
+	return &FsStorage{}
+}
+
+func (s *FsStorage) WriteFile(filePath string, data []byte) error {
+	// Create base folder if it does not exist
+	err := utils.CreateDirectoryIfDoesNotExist(path.Dir(filePath))
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filePath, data, 0666)
+}
+
+// Create streams the file directly to the filesystem through a buffered writer
+func (s *FsStorage) Create(filePath string) (io.WriteCloser, error) {
+	err := utils.CreateDirectoryIfDoesNotExist(path.Dir(filePath))
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Create(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return &bufferedFile{Writer: bufio.NewWriter(f), f: f}, nil
+}
+
+func (s *FsStorage) Close() error {
+	return nil
+}
+
+// Abort is a no-op, the files already written are left in place as the output folder could contain other data
+func (s *FsStorage) Abort() error {
+	return nil
+}
+
+// bufferedFile is a buffered writer that flushes and closes the underlying file on Close
+type bufferedFile struct {
+	*bufio.Writer
+	f *os.File
+}
+
+func (b *bufferedFile) Close() error {
+	if err := b.Flush(); err != nil {
+		b.f.Close()
+		return err
+	}
+	return b.f.Close()
+}
+
+// name of the index file that must be the last entry of a 3tz archive
+const threeTzIndexName = "@3dtilesIndex1@"
+
+// ThreeTzStorage writes all tileset files into a single 3D Tiles archive (.3tz).
+// A 3tz is a zip file whose entries are stored uncompressed and that terminates with
+// an index file mapping the MD5 hash of each entry path to the offset of its local file header.
+type ThreeTzStorage struct {
+	root    string
+	path    string
+	f       *os.File
+	cw      *countingWriter
+	zw      *zip.Writer
+	entries []threeTzIndexEntry
+	sync.Mutex
+}
+
+type threeTzIndexEntry struct {
+	hash   [16]byte
+	offset uint64
+}
+
+// NewThreeTzStorage creates a new 3tz archive at archivePath. Files written to the storage
+// must be located under root, their path in the archive is computed relative to root.
+func NewThreeTzStorage(root string, archivePath string) (*ThreeTzStorage, error) {
+	err := utils.CreateDirectoryIfDoesNotExist(filepath.Dir(archivePath))
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Create(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	cw := &countingWriter{w: f}
+	return &ThreeTzStorage{
+		root: root,
+		path: archivePath,
+		f:    f,
+		cw:   cw,
+		zw:   zip.NewWriter(cw),
+	}, nil
+}
+
+func (s *ThreeTzStorage) WriteFile(filePath string, data []byte) error {
+	name, err := s.archivePath(filePath)
+	if err != nil {
+		return err
+	}
+	s.Lock()
+	defer s.Unlock()
+	offset, err := s.writeEntry(name, data)
+	if err != nil {
+		return err
+	}
+	s.entries = append(s.entries, threeTzIndexEntry{
+		hash:   md5.Sum([]byte(name)),
+		offset: offset,
+	})
+	return nil
+}
+
+// Create returns a writer buffering the file in memory, as archive entries
+// must be written sequentially the entry is appended to the archive on Close
+func (s *ThreeTzStorage) Create(filePath string) (io.WriteCloser, error) {
+	if _, err := s.archivePath(filePath); err != nil {
+		return nil, err
+	}
+	return &memoryFile{onClose: func(data []byte) error {
+		return s.WriteFile(filePath, data)
+	}}, nil
+}
+
+// Abort closes and removes the archive, so that no incomplete but well formed 3tz is left behind
+func (s *ThreeTzStorage) Abort() error {
+	s.Lock()
+	defer s.Unlock()
+	s.f.Close()
+	return os.Remove(s.path)
+}
+
+func (s *ThreeTzStorage) Close() error {
+	s.Lock()
+	defer s.Unlock()
+	// the index must be sorted by hash, comparing the hashes as two
+	// little endian unsigned 64 bit integers, the first 8 bytes being the most significant
+	sort.Slice(s.entries, func(i, j int) bool {
+		iLo := binary.LittleEndian.Uint64(s.entries[i].hash[0:8])
+		jLo := binary.LittleEndian.Uint64(s.entries[j].hash[0:8])
+		if iLo == jLo {
+			return binary.LittleEndian.Uint64(s.entries[i].hash[8:16]) < binary.LittleEndian.Uint64(s.entries[j].hash[8:16])
+		}
+		return iLo < jLo
+	})
+	var index bytes.Buffer
+	for _, e := range s.entries {
+		index.Write(e.hash[:])
+		binary.Write(&index, binary.LittleEndian, e.offset)
+	}
+	if _, err := s.writeEntry(threeTzIndexName, index.Bytes()); err != nil {
+		s.f.Close()
+		return err
+	}
+	if err := s.zw.Close(); err != nil {
+		s.f.Close()
+		return err
+	}
+	return s.f.Close()
+}
+
+// writeEntry appends a stored (uncompressed) entry to the archive returning the offset of its local file header
+func (s *ThreeTzStorage) writeEntry(name string, data []byte) (uint64, error) {
+	// flush any buffered data so that the counter reflects the actual offset in the file
+	if err := s.zw.Flush(); err != nil {
+		return 0, err
+	}
+	offset := s.cw.n
+	w, err := s.zw.CreateRaw(&zip.FileHeader{
+		Name:               name,
+		Method:             zip.Store,
+		CRC32:              crc32.ChecksumIEEE(data),
+		CompressedSize64:   uint64(len(data)),
+		UncompressedSize64: uint64(len(data)),
+	})
+	if err != nil {
+		return 0, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return 0, err
+	}
+	return offset, nil
+}
+
+// archivePath returns the slash separated path of the file relative to the archive root
+func (s *ThreeTzStorage) archivePath(filePath string) (string, error) {
+	return relativeArchivePath(s.root, filePath)
+}
+
+// countingWriter keeps track of the number of bytes written to the underlying writer
+type countingWriter struct {
+	w io.Writer
+	n uint64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += uint64(n)
+	return n, err
+}
+
+// memoryFile buffers the file content in memory, passing it to the onClose function when closed
+type memoryFile struct {
+	bytes.Buffer
+	onClose func(data []byte) error
+}
+
+func (m *memoryFile) Close() error {
+	return m.onClose(m.Bytes())
+}
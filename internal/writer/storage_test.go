@@ -0,0 +1,278 @@
+package writer
+
+import (
+	"archive/zip"
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFsStorage(t *testing.T) {
+	tmp, err := os.MkdirTemp(os.TempDir(), "tst")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(tmp)
+	})
+
+	s := NewFsStorage()
+	filePath := path.Join(filepath.ToSlash(tmp), "a", "b", "content.pnts")
+	if err := s.WriteFile(filePath, []byte{1, 2, 3}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	actual, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !reflect.DeepEqual(actual, []byte{1, 2, 3}) {
+		t.Errorf("expected %v got %v", []byte{1, 2, 3}, actual)
+	}
+
+	streamed := path.Join(filepath.ToSlash(tmp), "c", "content.pnts")
+	w, err := s.Create(streamed)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	w.Write([]byte{4, 5})
+	w.Write([]byte{6})
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	actual, err = os.ReadFile(streamed)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !reflect.DeepEqual(actual, []byte{4, 5, 6}) {
+		t.Errorf("expected %v got %v", []byte{4, 5, 6}, actual)
+	}
+}
+
+func TestThreeTzStorage(t *testing.T) {
+	tmp, err := os.MkdirTemp(os.TempDir(), "tst")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(tmp)
+	})
+
+	root := path.Join(filepath.ToSlash(tmp), "out")
+	s, err := ThreeTzStorageProvider(root)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	files := map[string][]byte{
+		"tileset.json":        []byte(`{"asset":{"version":"1.0"}}`),
+		"content.pnts":        {1, 2, 3, 4},
+		"0/content.pnts":      {5, 6, 7},
+		"0/1/tileset.json":    []byte(`{}`),
+		"0/1/2/content.pnts":  {8},
+		"0/1/2/3/content.pnt": {},
+	}
+	for name, data := range files {
+		if strings.HasSuffix(name, ".pnts") {
+			// binary files are streamed
+			w, err := s.Create(path.Join(root, name))
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			w.Write(data)
+			if err := w.Close(); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			continue
+		}
+		if err := s.WriteFile(path.Join(root, name), data); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+	if err := s.WriteFile(path.Join(tmp, "outside.json"), []byte{}); err == nil {
+		t.Errorf("expected error writing outside of the archive root but got none")
+	}
+	if _, err := s.Create(path.Join(tmp, "outside.pnts")); err == nil {
+		t.Errorf("expected error creating a file outside of the archive root but got none")
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	archive := path.Join(root, "tileset.3tz")
+	z, err := zip.OpenReader(archive)
+	if err != nil {
+		t.Fatalf("unable to open the archive: %v", err)
+	}
+	defer z.Close()
+
+	if actual := len(z.File); actual != len(files)+1 {
+		t.Fatalf("expected %d entries, got %d", len(files)+1, actual)
+	}
+	last := z.File[len(z.File)-1]
+	if last.Name != threeTzIndexName {
+		t.Fatalf("expected index to be the last entry, got %s", last.Name)
+	}
+
+	offsets := map[string]int64{}
+	for _, f := range z.File[:len(z.File)-1] {
+		expected, ok := files[f.Name]
+		if !ok {
+			t.Errorf("unexpected entry %s", f.Name)
+			continue
+		}
+		if f.Method != zip.Store {
+			t.Errorf("expected entry %s to be stored uncompressed", f.Name)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		actual, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if !reflect.DeepEqual(actual, expected) && len(expected) != 0 {
+			t.Errorf("unexpected content for %s, expected %v got %v", f.Name, expected, actual)
+		}
+		offset, err := f.DataOffset()
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		// the local file header is 30 bytes plus the file name length, no extra field is written
+		offsets[f.Name] = offset - 30 - int64(len(f.Name))
+	}
+
+	rc, err := last.Open()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	index, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(index) != 24*len(files) {
+		t.Fatalf("expected index of %d bytes, got %d", 24*len(files), len(index))
+	}
+	hashes := map[[16]byte]string{}
+	for name := range files {
+		hashes[md5.Sum([]byte(name))] = name
+	}
+	var prev [16]byte
+	for i := 0; i < len(files); i++ {
+		entry := index[i*24 : (i+1)*24]
+		var hash [16]byte
+		copy(hash[:], entry[0:16])
+		name, ok := hashes[hash]
+		if !ok {
+			t.Fatalf("unexpected hash in index at position %d", i)
+		}
+		if actual := int64(binary.LittleEndian.Uint64(entry[16:24])); actual != offsets[name] {
+			t.Errorf("expected offset %d for %s, got %d", offsets[name], name, actual)
+		}
+		if i > 0 {
+			pLo, cLo := binary.LittleEndian.Uint64(prev[0:8]), binary.LittleEndian.Uint64(hash[0:8])
+			if pLo > cLo || (pLo == cLo && binary.LittleEndian.Uint64(prev[8:16]) > binary.LittleEndian.Uint64(hash[8:16])) {
+				t.Errorf("index is not sorted at position %d", i)
+			}
+		}
+		prev = hash
+	}
+}
+
+func TestThreeTzStorageAbort(t *testing.T) {
+	root := path.Join(filepath.ToSlash(t.TempDir()), "out")
+	s, err := ThreeTzStorageProvider(root)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := s.WriteFile(path.Join(root, "content.pnts"), []byte{1, 2, 3}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := s.Abort(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, err := os.Stat(path.Join(root, "tileset.3tz")); !os.IsNotExist(err) {
+		t.Errorf("expected the archive to be removed, got %v", err)
+	}
+}
+
+func TestManifestStorage(t *testing.T) {
+	tmp, err := os.MkdirTemp(os.TempDir(), "tst")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(tmp)
+	})
+
+	root := path.Join(filepath.ToSlash(tmp), "out")
+	s, err := ManifestStorageProvider(FsStorageProvider, "manifest.json")(root)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := s.WriteFile(path.Join(root, "tileset.json"), []byte("{}")); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	w, err := s.Create(path.Join(root, "0", "content.pnts"))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	w.Write([]byte{1, 2})
+	w.Write([]byte{3})
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	data, err := os.ReadFile(path.Join(root, "manifest.json"))
+	if err != nil {
+		t.Fatalf("unable to read the manifest: %v", err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	expected := Manifest{Files: []ManifestEntry{
+		{Path: "0/content.pnts", Sha256: "039058c6f2c0cb492c533b0a4d14ef77cc0f78abccced5287d84a1a2011cfb81", Size: 3},
+		{Path: "tileset.json", Sha256: "44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a", Size: 2},
+	}}
+	if !reflect.DeepEqual(m, expected) {
+		t.Errorf("expected manifest %v got %v", expected, m)
+	}
+}
+
+func TestManifestStorageAbort(t *testing.T) {
+	root := path.Join(filepath.ToSlash(t.TempDir()), "out")
+	manifest := path.Join(root, "manifest.json")
+	// a manifest of a previous export
+	os.MkdirAll(root, 0777)
+	os.WriteFile(manifest, []byte("{}"), 0666)
+
+	inner := &MockStorage{}
+	s := NewManifestStorage(inner, root, manifest)
+	if err := s.WriteFile(path.Join(root, "tileset.json"), []byte("{}")); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := s.Abort(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !inner.Aborted || inner.Closed {
+		t.Errorf("expected inner storage to be aborted and not closed, got aborted %v closed %v", inner.Aborted, inner.Closed)
+	}
+	if _, err := os.Stat(manifest); !os.IsNotExist(err) {
+		t.Errorf("expected no manifest after abort, got %v", err)
+	}
+}
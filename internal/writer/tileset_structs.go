@@ -1,11 +1,14 @@
 package writer
 
 type Asset struct {
-	Version string `json:"version"`
+	Version string                 `json:"version"`
+	Extras  map[string]interface{} `json:"extras,omitempty"`
 }
 
 type Content struct {
 	Url string `json:"uri"`
+	// BoundingVolume tightly encloses the content of the tile, if computed
+	BoundingVolume *BoundingVolume `json:"boundingVolume,omitempty"`
 }
 
 type BoundingVolume struct {
@@ -27,8 +30,15 @@ type Root struct {
 	Refine         string         `json:"refine"`
 }
 
+// Property is the range of the values of a property of the points, used by the clients to style them
+type Property struct {
+	Minimum float64 `json:"minimum"`
+	Maximum float64 `json:"maximum"`
+}
+
 type Tileset struct {
-	Asset          Asset   `json:"asset"`
-	GeometricError float64 `json:"geometricError"`
-	Root           Root    `json:"root"`
+	Asset          Asset               `json:"asset"`
+	Properties     map[string]Property `json:"properties,omitempty"`
+	GeometricError float64             `json:"geometricError"`
+	Root           Root                `json:"root"`
 }
@@ -0,0 +1,76 @@
+package writer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ValidateTileset checks the loose files tileset rooted at the tileset.json in the given folder. The tileset.json
+// files, including the ones referenced as children, must be valid with a region bounding volume and a non negative
+// geometric error, and all the referenced contents must be readable pnts files. Returns the total number of points
+// stored in the tileset.
+func ValidateTileset(folder string) (int, error) {
+	return validateTilesetFile(filepath.Join(folder, "tileset.json"))
+}
+
+func validateTilesetFile(file string) (int, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return 0, err
+	}
+	tileset := Tileset{}
+	if err := json.Unmarshal(data, &tileset); err != nil {
+		return 0, fmt.Errorf("invalid tileset %s: %w", file, err)
+	}
+	if tileset.Asset.Version == "" {
+		return 0, fmt.Errorf("missing asset version in tileset %s", file)
+	}
+	if tileset.GeometricError < 0 || tileset.Root.GeometricError < 0 {
+		return 0, fmt.Errorf("negative geometric error in tileset %s", file)
+	}
+	if len(tileset.Root.BoundingVolume.Region) != 6 {
+		return 0, fmt.Errorf("invalid root bounding volume in tileset %s", file)
+	}
+	folder := filepath.Dir(file)
+	total, err := validateContent(folder, tileset.Root.Content)
+	if err != nil {
+		return 0, err
+	}
+	for _, child := range tileset.Root.Children {
+		if len(child.BoundingVolume.Region) != 6 {
+			return 0, fmt.Errorf("invalid bounding volume of child %s in tileset %s", child.Content.Url, file)
+		}
+		if child.GeometricError > tileset.Root.GeometricError {
+			return 0, fmt.Errorf("child %s has a geometric error larger than its parent in tileset %s", child.Content.Url, file)
+		}
+		n, err := validateContent(folder, child.Content)
+		if err != nil {
+			return 0, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// validateContent validates the content at the given uri, relative to the folder, returning its number of points
+func validateContent(folder string, content Content) (int, error) {
+	if content.Url == "" {
+		return 0, nil
+	}
+	file := filepath.Join(folder, filepath.FromSlash(content.Url))
+	if strings.HasSuffix(content.Url, ".json") {
+		return validateTilesetFile(file)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return 0, err
+	}
+	pts, err := ReadPnts(data)
+	if err != nil {
+		return 0, fmt.Errorf("invalid content %s: %w", file, err)
+	}
+	return len(pts), nil
+}
@@ -0,0 +1,42 @@
+package writer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateTileset(t *testing.T) {
+	tmp := t.TempDir()
+	w, err := NewWriter(tmp, nil)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := w.Write(newFlattenTestTree(), "", context.TODO()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	n, err := ValidateTileset(tmp)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if n != 4 {
+		t.Errorf("expected %d points got %d", 4, n)
+	}
+
+	if err := os.WriteFile(filepath.Join(tmp, "0", "1", "content.pnts"), []byte("b3dm"), 0644); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, err := ValidateTileset(tmp); err == nil {
+		t.Errorf("expected error for an invalid content, got none")
+	}
+	if err := os.WriteFile(filepath.Join(tmp, "tileset.json"), []byte("{}"), 0644); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, err := ValidateTileset(tmp); err == nil {
+		t.Errorf("expected error for an invalid tileset, got none")
+	}
+	if _, err := ValidateTileset(filepath.Join(tmp, "missing")); err == nil {
+		t.Errorf("expected error for a missing tileset, got none")
+	}
+}
@@ -2,12 +2,17 @@ package writer
 
 import (
 	"context"
+	"fmt"
 	"math"
+	"path"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/mfbonfigli/gocesiumtiler/v2/internal/conv/coor"
 	"github.com/mfbonfigli/gocesiumtiler/v2/internal/conv/coor/proj4"
 	"github.com/mfbonfigli/gocesiumtiler/v2/internal/tree"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/utils"
 )
 
 // Writer writes a tree as a 3D Cesium Point cloud to the given output folder
@@ -15,22 +20,57 @@ type Writer interface {
 	Write(t tree.Tree, folderName string, ctx context.Context) error
 }
 
+// StorageProvider returns the Storage where to persist the files of a tileset rooted at the given folder
+type StorageProvider func(root string) (Storage, error)
+
 type StandardWriter struct {
-	numWorkers   int
-	bufferRatio  int
-	basePath     string
-	conv         coor.CoordinateConverter
-	producerFunc func(basepath, folder string) Producer
-	consumerFunc func(coor.CoordinateConverter) Consumer
+	numWorkers      int
+	bufferRatio     int
+	basePath        string
+	conv            coor.CoordinateConverter
+	storageProvider StorageProvider
+	producerFunc    func(basepath, folder string) Producer
+	consumerFunc    func(coor.CoordinateConverter, Storage) Consumer
+	consumerOptions []func(*StandardConsumer)
+	progress        ProgressFunc
+	progressEvery   time.Duration
+	clock           utils.Clock
+	maxTiles        int
+	flattenLevels   int
+	// properties enables the computation of the ranges of the point properties, stored in computedProperties
+	properties         bool
+	computedProperties map[string]Property
+	// fullDetailDistance is the camera distance in meters within which the full resolution is displayed, 0 to keep
+	// the geometric errors of the tree, in which case geometricErrorScale is 1
+	fullDetailDistance  float64
+	geometricErrorScale float64
+	// outputRegion is the area [west, south, east, north], in radians, the written tree is pruned to, nil to
+	// write the whole tree
+	outputRegion *[4]float64
+	// minContentBytes is the size below which the contents of the leaves are merged into their parent, 0 to
+	// write the tree as is
+	minContentBytes int64
+	// overviewPoints is the number of points of the single tile the tree is reduced to, 0 to write the whole tree
+	overviewPoints int
 }
 
 func NewWriter(basePath string, conv coor.CoordinateConverter, options ...func(*StandardWriter)) (*StandardWriter, error) {
 	w := &StandardWriter{
-		basePath:     basePath,
-		numWorkers:   1,
-		bufferRatio:  5,
-		producerFunc: NewStandardProducer,
-		consumerFunc: NewStandardConsumer,
+		basePath:            basePath,
+		numWorkers:          1,
+		bufferRatio:         5,
+		storageProvider:     FsStorageProvider,
+		producerFunc:        NewStandardProducer,
+		clock:               utils.SystemClock{},
+		geometricErrorScale: 1,
+	}
+	w.consumerFunc = func(conv coor.CoordinateConverter, s Storage) Consumer {
+		options := append([]func(*StandardConsumer){}, w.consumerOptions...)
+		options = append(options, func(c *StandardConsumer) {
+			c.properties = w.computedProperties
+			c.geometricErrorScale = w.geometricErrorScale
+		})
+		return NewStandardConsumer(conv, s, options...)
 	}
 	for _, optFn := range options {
 		optFn(w)
@@ -57,7 +97,279 @@ func WithBufferRatio(n int) func(*StandardWriter) {
 	}
 }
 
+// WithStorageProvider sets the function used to initialize the storage where the tileset files are written
+func WithStorageProvider(p StorageProvider) func(*StandardWriter) {
+	return func(w *StandardWriter) {
+		w.storageProvider = p
+	}
+}
+
+// WithAssetExtras sets the application specific data to store in the asset.extras property of the root tileset.json
+func WithAssetExtras(extras map[string]interface{}) func(*StandardWriter) {
+	return func(w *StandardWriter) {
+		w.consumerOptions = append(w.consumerOptions, func(c *StandardConsumer) {
+			c.assetExtras = extras
+		})
+	}
+}
+
+// WithPrettyTileset sets whether the tileset.json files are indented (the default) or minified
+func WithPrettyTileset(pretty bool) func(*StandardWriter) {
+	return func(w *StandardWriter) {
+		w.consumerOptions = append(w.consumerOptions, func(c *StandardConsumer) {
+			c.prettyTileset = pretty
+		})
+	}
+}
+
+// WithQuantizedPositions sets whether the positions of the points are stored as 16 bit integers quantized over the
+// bounding box of each tile, halving their size, instead of 32 bit floats (the default)
+func WithQuantizedPositions(quantized bool) func(*StandardWriter) {
+	return func(w *StandardWriter) {
+		w.consumerOptions = append(w.consumerOptions, func(c *StandardConsumer) {
+			c.quantizedPositions = quantized
+		})
+	}
+}
+
+// WithSourceFileAttribute sets whether the index of the file each point was read from is stored in the
+// _SOURCE_FILE property of the batch table, as an unsigned short
+func WithSourceFileAttribute(sourceFile bool) func(*StandardWriter) {
+	return func(w *StandardWriter) {
+		w.consumerOptions = append(w.consumerOptions, func(c *StandardConsumer) {
+			c.sourceFileAttribute = sourceFile
+		})
+	}
+}
+
+// WithPointSourceIdAttribute sets whether the point source ID of each point is stored in the POINT_SOURCE_ID
+// property of the batch table, as an unsigned short
+func WithPointSourceIdAttribute(pointSourceId bool) func(*StandardWriter) {
+	return func(w *StandardWriter) {
+		w.consumerOptions = append(w.consumerOptions, func(c *StandardConsumer) {
+			c.pointSourceIdAttribute = pointSourceId
+		})
+	}
+}
+
+// WithPerPointBatchId sets whether the index of each point in its tile is stored in the BATCH_ID of the feature
+// table, as an unsigned int, with a BATCH_LENGTH equal to the number of points, so that each point is a feature
+// addressable by the clients, e.g. when picking it, and associated to its properties of the batch table
+func WithPerPointBatchId(enabled bool) func(*StandardWriter) {
+	return func(w *StandardWriter) {
+		w.consumerOptions = append(w.consumerOptions, func(c *StandardConsumer) {
+			c.perPointBatchId = enabled
+		})
+	}
+}
+
+// WithBufferAlignment pads the json and the binary bodies of the feature and batch tables of the content.pnts files
+// so that each section starts at an offset, from the beginning of the file, multiple of the given number of bytes.
+// Values that are not multiple of 4 are rounded up to the next multiple of 4, as required by the json headers.
+// A non positive value keeps the default layout, aligning the json headers to 4 bytes only.
+func WithBufferAlignment(n int) func(*StandardWriter) {
+	return func(w *StandardWriter) {
+		w.consumerOptions = append(w.consumerOptions, func(c *StandardConsumer) {
+			c.bufferAlignment = 0
+			if n > 0 {
+				c.bufferAlignment = (n + 3) / 4 * 4
+			}
+		})
+	}
+}
+
+// WithContentBoundingVolumes sets whether the content object of each tile declares the region tightly enclosing the
+// points of its content.pnts, improving the culling of the clients. Computing the regions requires reprojecting all
+// the points once more.
+func WithContentBoundingVolumes(enabled bool) func(*StandardWriter) {
+	return func(w *StandardWriter) {
+		w.consumerOptions = append(w.consumerOptions, func(c *StandardConsumer) {
+			c.contentBoundingVolumes = enabled
+		})
+	}
+}
+
+// WithProgress sets a function periodically invoked, at the given interval, with the number of tiles written
+// and the total number of tiles while the tileset is written. The function is always invoked from the goroutine
+// calling Write, a last time when all tiles have been processed. A non positive interval defaults to one second.
+func WithProgress(fn ProgressFunc, interval time.Duration) func(*StandardWriter) {
+	return func(w *StandardWriter) {
+		w.progress = fn
+		w.progressEvery = interval
+	}
+}
+
+// WithClock sets the clock scheduling the progress reports, defaults to the system clock
+func WithClock(clock utils.Clock) func(*StandardWriter) {
+	return func(w *StandardWriter) {
+		w.clock = clock
+	}
+}
+
+// WithMaxTiles makes Write fail before writing any file if the tileset would have more than the given number of tiles.
+// A non positive value disables the limit.
+func WithMaxTiles(n int) func(*StandardWriter) {
+	return func(w *StandardWriter) {
+		w.maxTiles = n
+	}
+}
+
+// WithFlatten caps the number of levels of the written tileset, root included, merging the points of the nodes
+// below the last level into the nodes of the last level. A non positive value writes the tree as is.
+func WithFlatten(maxLevels int) func(*StandardWriter) {
+	return func(w *StandardWriter) {
+		w.flattenLevels = maxLevels
+	}
+}
+
+// WithOverview writes, instead of the whole tree, a tileset made of a single tile storing at most the given number
+// of points, taken from the coarsest levels of the tree and sampled uniformly from the first level exceeding the
+// limit. A non positive value writes the whole tree.
+func WithOverview(maxPoints int) func(*StandardWriter) {
+	return func(w *StandardWriter) {
+		w.overviewPoints = maxPoints
+	}
+}
+
+// WithProperties sets whether the range of the intensity, the classification and the ellipsoidal height of the points
+// is stored in the properties object of the root tileset.json, as used by the clients to style the points. Computing
+// the ranges requires an additional pass over all the points before writing the tileset.
+func WithProperties(properties bool) func(*StandardWriter) {
+	return func(w *StandardWriter) {
+		w.properties = properties
+	}
+}
+
+// WithFullDetailDistance scales the geometric errors of all the tiles so that the clients display the points at full
+// resolution when the camera is within the given distance, in meters, from them, assuming the default maximum screen
+// space error of Cesium, 16 pixels, on a full HD screen. Computing the scale requires building all the nodes of the
+// tree before writing the tileset. A non positive value keeps the geometric errors of the tree.
+func WithFullDetailDistance(meters float64) func(*StandardWriter) {
+	return func(w *StandardWriter) {
+		w.fullDetailDistance = meters
+	}
+}
+
+// WithOutputRegion prunes the written tree to the nodes whose bounding region intersects the given area
+// [west, south, east, north], in radians, together with their ancestors. The other nodes are not written, with
+// their subtree. Write fails if the root node does not intersect the area.
+func WithOutputRegion(area [4]float64) func(*StandardWriter) {
+	return func(w *StandardWriter) {
+		w.outputRegion = &area
+	}
+}
+
+// WithMinContentBytes merges, bottom up, the leaves whose content.pnts would be smaller than the given number of
+// bytes into their parent, which becomes a leaf if all its children are merged and is then merged in turn if still
+// too small. The sizes are estimated from the number of points and the layout of the contents. A non positive value
+// writes the tree as is.
+func WithMinContentBytes(n int64) func(*StandardWriter) {
+	return func(w *StandardWriter) {
+		w.minContentBytes = n
+	}
+}
+
+// WithClassAlpha stores the colors of the points as RGBA, with the alpha of each point looked up by its
+// classification in the given map. The classifications missing from the map are fully opaque. A nil or empty map
+// stores the colors as RGB (the default).
+func WithClassAlpha(alpha map[uint8]uint8) func(*StandardWriter) {
+	return func(w *StandardWriter) {
+		w.consumerOptions = append(w.consumerOptions, func(c *StandardConsumer) {
+			c.classAlpha = nil
+			if len(alpha) > 0 {
+				c.classAlpha = alpha
+			}
+		})
+	}
+}
+
+// WithContentExtension sets the extension of the content files of the tiles, e.g. ".bin", used both for the files
+// and for the content URIs of the tileset.json files. The leading dot is optional. The content is still a pnts
+// regardless of the extension. An empty extension keeps the default .pnts.
+func WithContentExtension(ext string) func(*StandardWriter) {
+	return func(w *StandardWriter) {
+		w.consumerOptions = append(w.consumerOptions, func(c *StandardConsumer) {
+			c.contentFile = contentFileName(ext)
+		})
+	}
+}
+
+// contentFileName returns the name of the content files with the given extension
+func contentFileName(ext string) string {
+	if ext == "" {
+		return defaultContentFile
+	}
+	return contentFilePrefix + strings.TrimPrefix(ext, ".")
+}
+
+// FsStorageProvider returns a storage writing the tileset as loose files on the filesystem
+func FsStorageProvider(root string) (Storage, error) {
+	return NewFsStorage(), nil
+}
+
+// ThreeTzStorageProvider returns a storage writing the tileset into a single tileset.3tz archive
+// located in the root folder
+func ThreeTzStorageProvider(root string) (Storage, error) {
+	return NewThreeTzStorage(root, path.Join(root, "tileset.3tz"))
+}
+
+// ZipStorageProvider returns a storage writing the tileset into a single tileset.zip archive located in the root
+// folder, ready to be uploaded to Cesium ion
+func ZipStorageProvider(root string) (Storage, error) {
+	return NewZipStorage(root, path.Join(root, "tileset.zip"))
+}
+
 func (w *StandardWriter) Write(t tree.Tree, folderName string, ctx context.Context) error {
+	// the tiles are counted upfront to report the progress and enforce the limit, this builds all the nodes
+	// of the tree which would anyway be built by the producer while traversing it
+	root := t.GetRootNode()
+	if w.outputRegion != nil {
+		if !intersects(root, *w.outputRegion, w.conv) {
+			return fmt.Errorf("the output region does not intersect the point cloud")
+		}
+		root = prune(root, *w.outputRegion, w.conv)
+	}
+	if w.overviewPoints > 0 {
+		root = overview(root, w.overviewPoints)
+	}
+	if w.flattenLevels > 0 && w.overviewPoints <= 0 {
+		root = flatten(root, w.flattenLevels)
+	}
+	if w.minContentBytes > 0 && w.overviewPoints <= 0 {
+		probe := NewStandardConsumer(w.conv, nil, w.consumerOptions...).(*StandardConsumer)
+		root = consolidate(root, w.minContentBytes, probe.contentSize)
+	}
+	total := 0
+	if w.progress != nil || w.maxTiles > 0 {
+		total = countTiles(root)
+	}
+	if w.maxTiles > 0 && total > w.maxTiles {
+		return fmt.Errorf("the tileset would have %d tiles, exceeding the limit of %d tiles", total, w.maxTiles)
+	}
+	if w.properties {
+		properties, err := computeProperties(root, w.conv)
+		if err != nil {
+			return err
+		}
+		w.computedProperties = properties
+	}
+
+	w.geometricErrorScale = 1
+	if w.fullDetailDistance > 0 {
+		w.geometricErrorScale = fullDetailScale(root, w.fullDetailDistance)
+	}
+
+	storage, err := w.storageProvider(path.Join(w.basePath, folderName))
+	if err != nil {
+		return err
+	}
+
+	var counter *tileCountingStorage
+	if w.progress != nil {
+		counter = &tileCountingStorage{Storage: storage}
+		storage = counter
+	}
+
 	// init channel where consumers can eventually submit errors that prevented them to finish the job
 	errorChannel := make(chan error)
 
@@ -70,12 +382,12 @@ func (w *StandardWriter) Write(t tree.Tree, folderName string, ctx context.Conte
 	// producing is easy, only 1 producer
 	producer := w.producerFunc(w.basePath, folderName)
 	waitGroup.Add(1)
-	go producer.Produce(workChannel, errorChannel, &waitGroup, t.GetRootNode(), ctx)
+	go producer.Produce(workChannel, errorChannel, &waitGroup, root, ctx)
 
 	// add consumers to waitgroup and launch them
 	for i := 0; i < w.numWorkers; i++ {
 		waitGroup.Add(1)
-		consumer := w.consumerFunc(w.conv)
+		consumer := w.consumerFunc(w.conv, storage)
 		go consumer.Consume(workChannel, errorChannel, &waitGroup)
 	}
 
@@ -94,14 +406,50 @@ func (w *StandardWriter) Write(t tree.Tree, folderName string, ctx context.Conte
 	}()
 
 	// wait for producers and consumers to finish
-	waitGroup.Wait()
+	if w.progress != nil {
+		w.waitReportingProgress(&waitGroup, counter, total)
+	} else {
+		waitGroup.Wait()
+	}
 
 	// close error chan
 	close(errorChannel)
 	errorWaitGroup.Wait()
 
+	// finalize the storage only if the export succeeded, otherwise abort it so that
+	// no resources are leaked and no incomplete output is finalized
+	if len(errs) != 0 {
+		storage.Abort()
+	} else if err := storage.Close(); err != nil {
+		errs = append(errs, err)
+	}
+
 	if len(errs) != 0 {
 		return errs[0]
 	}
 	return nil
 }
+
+// waitReportingProgress waits for the given wait group, periodically reporting the number of tiles written
+func (w *StandardWriter) waitReportingProgress(wg *sync.WaitGroup, counter *tileCountingStorage, total int) {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	interval := w.progressEvery
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticks, stop := w.clock.NewTicker(interval)
+	defer stop()
+	for {
+		select {
+		case <-done:
+			w.progress(counter.tilesWritten(), total)
+			return
+		case <-ticks:
+			w.progress(counter.tilesWritten(), total)
+		}
+	}
+}
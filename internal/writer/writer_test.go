@@ -2,7 +2,9 @@ package writer
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"reflect"
 	"testing"
 
 	"github.com/mfbonfigli/gocesiumtiler/v2/internal/conv/coor"
@@ -48,16 +50,23 @@ func TestWriter(t *testing.T) {
 	}
 	p := &MockProducer{}
 	c := &MockConsumer{}
+	s := &MockStorage{}
 	w.producerFunc = func(basepath, folder string) Producer {
 		return p
 	}
-	w.consumerFunc = func(cc coor.CoordinateConverter) Consumer {
+	w.consumerFunc = func(cc coor.CoordinateConverter, s Storage) Consumer {
 		return c
 	}
+	w.storageProvider = func(root string) (Storage, error) {
+		return s, nil
+	}
 	err = w.Write(root, "base", context.TODO())
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
+	if !s.Closed || s.Aborted {
+		t.Errorf("expected storage to be closed and not aborted, got closed %v aborted %v", s.Closed, s.Aborted)
+	}
 	if p.Wc == nil {
 		t.Errorf("empty work channel passed")
 	} else {
@@ -114,16 +123,23 @@ func TestWriterWithProducerError(t *testing.T) {
 		Err: fmt.Errorf("mock error"),
 	}
 	c := &MockConsumer{}
+	s := &MockStorage{}
 	w.producerFunc = func(basepath, folder string) Producer {
 		return p
 	}
-	w.consumerFunc = func(cc coor.CoordinateConverter) Consumer {
+	w.consumerFunc = func(cc coor.CoordinateConverter, s Storage) Consumer {
 		return c
 	}
+	w.storageProvider = func(root string) (Storage, error) {
+		return s, nil
+	}
 	err = w.Write(root, "base", context.TODO())
 	if err == nil {
 		t.Errorf("expected error but got none")
 	}
+	if !s.Aborted || s.Closed {
+		t.Errorf("expected storage to be aborted and not closed, got aborted %v closed %v", s.Aborted, s.Closed)
+	}
 	if p.Wc == nil {
 		t.Errorf("empty work channel passed")
 	} else {
@@ -183,7 +199,7 @@ func TestWriterWithConsumerError(t *testing.T) {
 	w.producerFunc = func(basepath, folder string) Producer {
 		return p
 	}
-	w.consumerFunc = func(cc coor.CoordinateConverter) Consumer {
+	w.consumerFunc = func(cc coor.CoordinateConverter, s Storage) Consumer {
 		return c
 	}
 	err = w.Write(root, "base", context.TODO())
@@ -205,3 +221,153 @@ func TestWriterWithConsumerError(t *testing.T) {
 		}
 	}
 }
+
+func TestWriterWithAssetExtras(t *testing.T) {
+	extras := map[string]interface{}{"key": "value"}
+	w, err := NewWriter("base", nil, WithAssetExtras(extras))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	c, ok := w.consumerFunc(w.conv, NewFsStorage()).(*StandardConsumer)
+	if !ok {
+		t.Fatalf("unexpected consumer type")
+	}
+	if !reflect.DeepEqual(c.assetExtras, extras) {
+		t.Errorf("expected extras %v got %v", extras, c.assetExtras)
+	}
+	if !c.prettyTileset {
+		t.Errorf("expected pretty tileset by default")
+	}
+}
+
+func TestWriterWithPrettyTileset(t *testing.T) {
+	w, err := NewWriter("base", nil, WithPrettyTileset(false))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if c := w.consumerFunc(w.conv, NewFsStorage()).(*StandardConsumer); c.prettyTileset {
+		t.Errorf("expected minified tileset")
+	}
+}
+
+func TestWriterWithQuantizedPositions(t *testing.T) {
+	w, err := NewWriter("base", nil, WithQuantizedPositions(true))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if c := w.consumerFunc(w.conv, NewFsStorage()).(*StandardConsumer); !c.quantizedPositions {
+		t.Errorf("expected quantized positions")
+	}
+}
+
+func TestWriterWithSourceFileAttribute(t *testing.T) {
+	w, err := NewWriter("base", nil, WithSourceFileAttribute(true))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if c := w.consumerFunc(w.conv, NewFsStorage()).(*StandardConsumer); !c.sourceFileAttribute {
+		t.Errorf("expected source file attribute")
+	}
+}
+
+func TestWriterWithPointSourceIdAttribute(t *testing.T) {
+	w, err := NewWriter("base", nil, WithPointSourceIdAttribute(true))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if c := w.consumerFunc(w.conv, NewFsStorage()).(*StandardConsumer); !c.pointSourceIdAttribute {
+		t.Errorf("expected point source ID attribute")
+	}
+}
+
+func TestWriterWithPerPointBatchId(t *testing.T) {
+	w, err := NewWriter("base", nil, WithPerPointBatchId(true))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if c := w.consumerFunc(w.conv, NewFsStorage()).(*StandardConsumer); !c.perPointBatchId {
+		t.Errorf("expected per point batch ids")
+	}
+}
+
+func TestWriterWithContentExtension(t *testing.T) {
+	for _, c := range []struct {
+		ext      string
+		expected string
+	}{
+		{"", "content.pnts"},
+		{".bin", "content.bin"},
+		{"bin", "content.bin"},
+	} {
+		w, err := NewWriter("base", nil, WithContentExtension(c.ext), WithFlatten(2))
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		s := &MockStorage{}
+		w.storageProvider = func(root string) (Storage, error) {
+			return s, nil
+		}
+		var counted int
+		w.progress = func(written, total int) {
+			counted = written
+		}
+		if err := w.Write(newFlattenTestTree(), "", context.TODO()); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		for _, f := range []string{"base/" + c.expected, "base/0/" + c.expected} {
+			if _, ok := s.Files[f]; !ok {
+				t.Errorf("extension %q: expected file %s to be written", c.ext, f)
+			}
+		}
+		var tileset Tileset
+		if err := json.Unmarshal(s.Files["base/tileset.json"], &tileset); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if tileset.Root.Content.Url != c.expected || tileset.Root.Children[0].Content.Url != "0/"+c.expected {
+			t.Errorf("extension %q: unexpected content uris %s, %s", c.ext, tileset.Root.Content.Url, tileset.Root.Children[0].Content.Url)
+		}
+		// the contents are counted as tiles whatever their extension
+		if counted != 2 {
+			t.Errorf("extension %q: expected %d tiles written got %d", c.ext, 2, counted)
+		}
+	}
+}
+
+func TestWriterWithMaxTiles(t *testing.T) {
+	child := &tree.MockNode{Pts: geom.NewLinkedPointStream(nil, 1)}
+	root := &tree.MockNode{
+		Pts:      geom.NewLinkedPointStream(nil, 1),
+		Children: [8]tree.Node{child},
+	}
+	for _, c := range []struct {
+		maxTiles  int
+		expectErr bool
+	}{
+		{1, true},
+		{2, false},
+		{0, false},
+	} {
+		w, err := NewWriter("base", nil, WithMaxTiles(c.maxTiles))
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		storageCreated := false
+		w.producerFunc = func(basepath, folder string) Producer {
+			return &MockProducer{}
+		}
+		w.consumerFunc = func(cc coor.CoordinateConverter, s Storage) Consumer {
+			return &MockConsumer{}
+		}
+		w.storageProvider = func(root string) (Storage, error) {
+			storageCreated = true
+			return &MockStorage{}, nil
+		}
+		err = w.Write(root, "base", context.TODO())
+		if (err != nil) != c.expectErr {
+			t.Errorf("max tiles %d: expected error %v got %v", c.maxTiles, c.expectErr, err)
+		}
+		if storageCreated == c.expectErr {
+			t.Errorf("max tiles %d: expected storage to be created %v", c.maxTiles, !c.expectErr)
+		}
+	}
+}
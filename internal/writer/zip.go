@@ -0,0 +1,157 @@
+package writer
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/utils"
+)
+
+// ZipStorage writes all tileset files into a single compressed zip archive laid out as expected by the Cesium ion
+// asset upload: the root tileset.json at the root of the archive and all the other files in subfolders, referenced
+// by relative paths. When closed, the archive is validated checking that the root tileset.json is present and that
+// every content referenced by the tileset.json files is stored in the archive.
+type ZipStorage struct {
+	root string
+	path string
+	f    *os.File
+	zw   *zip.Writer
+	// names are the paths of the entries in the archive
+	names map[string]bool
+	// tilesets are the tileset.json files in the archive, by path, kept to validate their references
+	tilesets map[string][]byte
+	sync.Mutex
+}
+
+// NewZipStorage creates a new zip archive at archivePath. Files written to the storage
+// must be located under root, their path in the archive is computed relative to root.
+func NewZipStorage(root string, archivePath string) (*ZipStorage, error) {
+	err := utils.CreateDirectoryIfDoesNotExist(filepath.Dir(archivePath))
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Create(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	return &ZipStorage{
+		root:     root,
+		path:     archivePath,
+		f:        f,
+		zw:       zip.NewWriter(f),
+		names:    map[string]bool{},
+		tilesets: map[string][]byte{},
+	}, nil
+}
+
+func (s *ZipStorage) WriteFile(filePath string, data []byte) error {
+	name, err := relativeArchivePath(s.root, filePath)
+	if err != nil {
+		return err
+	}
+	s.Lock()
+	defer s.Unlock()
+	if s.names[name] {
+		return fmt.Errorf("file %s already stored in the archive", name)
+	}
+	w, err := s.zw.CreateHeader(&zip.FileHeader{
+		Name:   name,
+		Method: zip.Deflate,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	s.names[name] = true
+	if path.Base(name) == "tileset.json" {
+		s.tilesets[name] = data
+	}
+	return nil
+}
+
+// Create returns a writer buffering the file in memory, as archive entries
+// must be written sequentially the entry is appended to the archive on Close
+func (s *ZipStorage) Create(filePath string) (io.WriteCloser, error) {
+	if _, err := relativeArchivePath(s.root, filePath); err != nil {
+		return nil, err
+	}
+	return &memoryFile{onClose: func(data []byte) error {
+		return s.WriteFile(filePath, data)
+	}}, nil
+}
+
+// Abort closes and removes the archive, so that no incomplete archive is left behind
+func (s *ZipStorage) Abort() error {
+	s.Lock()
+	defer s.Unlock()
+	s.f.Close()
+	return os.Remove(s.path)
+}
+
+// Close validates the content of the archive and completes it. If the validation fails the archive is removed.
+func (s *ZipStorage) Close() error {
+	s.Lock()
+	defer s.Unlock()
+	if err := s.validate(); err != nil {
+		s.f.Close()
+		os.Remove(s.path)
+		return err
+	}
+	if err := s.zw.Close(); err != nil {
+		s.f.Close()
+		return err
+	}
+	return s.f.Close()
+}
+
+// validate checks that the root tileset.json is stored in the archive and that all the contents referenced by the
+// tileset.json files are stored in the archive too
+func (s *ZipStorage) validate() error {
+	if _, ok := s.tilesets["tileset.json"]; !ok {
+		return fmt.Errorf("invalid archive: no tileset.json at the root of the archive")
+	}
+	for name, data := range s.tilesets {
+		tileset := Tileset{}
+		if err := json.Unmarshal(data, &tileset); err != nil {
+			return fmt.Errorf("invalid archive: invalid tileset %s: %w", name, err)
+		}
+		uris := []string{tileset.Root.Content.Url}
+		for _, child := range tileset.Root.Children {
+			uris = append(uris, child.Content.Url)
+		}
+		for _, uri := range uris {
+			if uri == "" {
+				continue
+			}
+			if path.IsAbs(uri) || strings.Contains(uri, "\\") || strings.Contains(uri, "://") {
+				return fmt.Errorf("invalid archive: content %s of tileset %s is not a relative path", uri, name)
+			}
+			if target := path.Join(path.Dir(name), uri); !s.names[target] {
+				return fmt.Errorf("invalid archive: content %s of tileset %s is not stored in the archive", uri, name)
+			}
+		}
+	}
+	return nil
+}
+
+// relativeArchivePath returns the slash separated path of the file relative to the archive root
+func relativeArchivePath(root string, filePath string) (string, error) {
+	rel, err := filepath.Rel(filepath.Clean(root), filepath.Clean(filePath))
+	if err != nil {
+		return "", err
+	}
+	rel = filepath.ToSlash(rel)
+	if rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", fmt.Errorf("file %s is outside of the archive root %s", filePath, root)
+	}
+	return rel, nil
+}
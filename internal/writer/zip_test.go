@@ -0,0 +1,99 @@
+package writer
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+)
+
+func TestZipStorage(t *testing.T) {
+	root := path.Join(filepath.ToSlash(t.TempDir()), "out")
+	s, err := ZipStorageProvider(root)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	files := map[string][]byte{
+		"tileset.json":     []byte(`{"asset":{"version":"1.0"},"root":{"content":{"uri":"content.pnts"},"children":[{"content":{"uri":"0/tileset.json"}}]}}`),
+		"content.pnts":     {1, 2, 3, 4},
+		"0/tileset.json":   []byte(`{"asset":{"version":"1.0"},"root":{"content":{"uri":"content.pnts"},"children":[{"content":{"uri":"1/content.pnts"}}]}}`),
+		"0/content.pnts":   {5, 6, 7},
+		"0/1/content.pnts": {8},
+	}
+	for name, data := range files {
+		w, err := s.Create(path.Join(root, name))
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		w.Write(data)
+		if err := w.Close(); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+	if err := s.WriteFile(path.Join(root, "content.pnts"), []byte{1}); err == nil {
+		t.Errorf("expected error writing a file twice but got none")
+	}
+	if err := s.WriteFile(path.Join(path.Dir(root), "outside.json"), []byte{}); err == nil {
+		t.Errorf("expected error writing outside of the archive root but got none")
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	z, err := zip.OpenReader(path.Join(root, "tileset.zip"))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	defer z.Close()
+	if len(z.File) != len(files) {
+		t.Errorf("expected %d entries got %d", len(files), len(z.File))
+	}
+	for _, f := range z.File {
+		expected, ok := files[f.Name]
+		if !ok {
+			t.Errorf("unexpected entry %s", f.Name)
+			continue
+		}
+		if f.Method != zip.Deflate {
+			t.Errorf("expected entry %s to be compressed", f.Name)
+		}
+		r, err := f.Open()
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		data, err := io.ReadAll(r)
+		r.Close()
+		if err != nil || string(data) != string(expected) {
+			t.Errorf("expected entry %s to contain %v got %v (%v)", f.Name, expected, data, err)
+		}
+	}
+}
+
+func TestZipStorageValidation(t *testing.T) {
+	cases := map[string]map[string]string{
+		"missing root tileset": {"0/tileset.json": `{"root":{}}`},
+		"missing content":      {"tileset.json": `{"root":{"content":{"uri":"content.pnts"}}}`},
+		"absolute content":     {"tileset.json": `{"root":{"content":{"uri":"/content.pnts"}}}`, "content.pnts": ""},
+		"invalid tileset":      {"tileset.json": `{`},
+	}
+	for desc, files := range cases {
+		root := filepath.Join(t.TempDir(), "out")
+		s, err := NewZipStorage(root, filepath.Join(root, "tileset.zip"))
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		for name, data := range files {
+			if err := s.WriteFile(filepath.Join(root, name), []byte(data)); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+		}
+		if err := s.Close(); err == nil {
+			t.Errorf("%s: expected validation error, got none", desc)
+		}
+		if _, err := os.Stat(filepath.Join(root, "tileset.zip")); !os.IsNotExist(err) {
+			t.Errorf("%s: expected the invalid archive to be removed", desc)
+		}
+	}
+}
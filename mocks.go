@@ -12,13 +12,17 @@ type MockTiler struct {
 	ProcessFilesCalled  bool
 	ProcessFolderCalled bool
 	// opts settings
-	EightBit   bool
-	GeoidElev  bool
-	GridSize   float64
-	PtsPerTile int
-	Depth      int
-	ElevOffset float64
-	err        error
+	EightBit        bool
+	GeoidElev       bool
+	GridSize        [3]float64
+	PtsPerTile      int
+	Depth           int
+	ElevOffset      float64
+	Packaging       Packaging
+	Pattern         string
+	ContinueOnError bool
+	CrsGrouping     bool
+	err             error
 }
 
 func (m *MockTiler) ProcessFiles(inputLasFiles []string, outputFolder string, epsgCode int, opts *TilerOptions, ctx context.Context) error {
@@ -34,6 +38,10 @@ func (m *MockTiler) ProcessFiles(inputLasFiles []string, outputFolder string, ep
 	m.PtsPerTile = opts.minPointsPerTile
 	m.Depth = opts.maxDepth
 	m.ElevOffset = opts.elevationOffset
+	m.Packaging = opts.packaging
+	m.Pattern = opts.filePattern
+	m.ContinueOnError = opts.continueOnError
+	m.CrsGrouping = opts.crsGrouping
 	return m.err
 }
 
@@ -50,5 +58,9 @@ func (m *MockTiler) ProcessFolder(inputFolder, outputFolder string, epsgCode int
 	m.PtsPerTile = opts.minPointsPerTile
 	m.Depth = opts.maxDepth
 	m.ElevOffset = opts.elevationOffset
+	m.Packaging = opts.packaging
+	m.Pattern = opts.filePattern
+	m.ContinueOnError = opts.continueOnError
+	m.CrsGrouping = opts.crsGrouping
 	return m.err
 }
@@ -1,6 +1,14 @@
 package tiler
 
-import "runtime"
+import (
+	"io"
+	"runtime"
+	"time"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/las"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/tree"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/utils"
+)
 
 type TilerEvent int
 
@@ -17,34 +25,418 @@ const (
 	EventExportStarted
 	EventExportCompleted
 	EventExportError
+	EventPointLoadingFileStarted
+	// EventExportProgress is periodically emitted during the export reporting the number of tiles written
+	EventExportProgress
+	// EventCrsWarning is emitted when the extent of the points does not match the units of the input CRS
+	EventCrsWarning
+	// EventTerrainExportStarted is emitted when the generation of the terrain set with WithTerrainOutput starts
+	EventTerrainExportStarted
+	// EventOverviewExportStarted is emitted when the generation of the tileset set with WithOverviewTileset starts
+	EventOverviewExportStarted
+)
+
+// ElevationClampMode defines what happens to the points whose elevation falls outside of the clamp range
+type ElevationClampMode int
+
+const (
+	// ClampDrop discards the points outside of the elevation range
+	ClampDrop ElevationClampMode = iota
+	// ClampToRange keeps the points outside of the elevation range moving them to the nearest bound
+	ClampToRange
+)
+
+// RasterOutsidePolicy defines what happens to the points for which a raster provides no value,
+// because they fall outside of the raster or on NoData pixels
+type RasterOutsidePolicy int
+
+const (
+	// RasterOutsideDrop discards the points
+	RasterOutsideDrop RasterOutsidePolicy = iota
+	// RasterOutsideKeepZ keeps the points with their original elevation
+	RasterOutsideKeepZ
+)
+
+// Packaging defines how the files of the output tileset are packaged
+type Packaging int
+
+const (
+	// PackageNone writes the tileset as loose files in the output folder
+	PackageNone Packaging = iota
+	// Package3tz writes the tileset in a single 3D Tiles archive (tileset.3tz) in the output folder
+	Package3tz
+	// PackageIonZip writes the tileset in a single zip archive (tileset.zip) in the output folder, laid out as expected
+	// by the Cesium ion asset upload, with the root tileset.json at the root of the archive. The archive is validated
+	// once written, checking that all the contents referenced by the tileset.json files are stored in it.
+	PackageIonZip
+)
+
+// ColorSource identifies a channel of the LAS point records that can be used as a color channel
+type ColorSource int
+
+const (
+	ColorSourceRed       = ColorSource(las.ColorRed)
+	ColorSourceGreen     = ColorSource(las.ColorGreen)
+	ColorSourceBlue      = ColorSource(las.ColorBlue)
+	ColorSourceNIR       = ColorSource(las.ColorNIR)
+	ColorSourceIntensity = ColorSource(las.ColorIntensity)
+)
+
+// AttrFlags is a set of the attributes of the points loaded from the LAS files, besides the coordinates
+type AttrFlags int
+
+const (
+	// AttrColor are the RGB colors of the points
+	AttrColor = AttrFlags(las.AttributeColor)
+	// AttrIntensity is the intensity of the points
+	AttrIntensity = AttrFlags(las.AttributeIntensity)
+	// AttrClassification is the classification of the points, together with the classification flags such as
+	// withheld, overlap and synthetic
+	AttrClassification = AttrFlags(las.AttributeClassification)
+	// AttrGpsTime is the GPS time of the points
+	AttrGpsTime = AttrFlags(las.AttributeGpsTime)
+	// AttrPointSourceId is the point source ID of the points
+	AttrPointSourceId = AttrFlags(las.AttributePointSourceId)
+	// AttrAll are all the attributes of the points
+	AttrAll = AttrFlags(las.AllAttributes)
+)
+
+// SparseNodePolicy defines what happens to the tiles that would store less points than the minimum number of points per tile
+type SparseNodePolicy int
+
+const (
+	// SparseNodeConsolidate merges the points of the sparse tiles into their parent tile
+	SparseNodeConsolidate = SparseNodePolicy(tree.SparseConsolidate)
+	// SparseNodeKeep keeps the sparse tiles as they are
+	SparseNodeKeep = SparseNodePolicy(tree.SparseKeep)
+	// SparseNodeOmit discards the sparse tiles together with their points
+	SparseNodeOmit = SparseNodePolicy(tree.SparseOmit)
+)
+
+// Subdivision defines how the tiles are split into their children
+type Subdivision int
+
+const (
+	// SubdivisionOctree splits the tiles in up to eight children
+	SubdivisionOctree = Subdivision(tree.SubdivisionOctree)
+	// SubdivisionQuadtree splits the tiles only horizontally, in up to four children spanning the full height of the parent
+	SubdivisionQuadtree = Subdivision(tree.SubdivisionQuadtree)
+)
+
+// GeometricErrorMode defines how the geometric error decreases from the root tile to the deepest tiles
+type GeometricErrorMode int
+
+const (
+	// GeoErrSpacing measures the geometric error of each tile as the mean spacing between its points
+	GeoErrSpacing = GeometricErrorMode(tree.GeometricErrorSpacing)
+	// GeoErrGeometric halves the geometric error of the parent at each level
+	GeoErrGeometric = GeometricErrorMode(tree.GeometricErrorGeometric)
+	// GeoErrLinear decreases the geometric error of the root linearly with the level
+	GeoErrLinear = GeometricErrorMode(tree.GeometricErrorLinear)
+)
+
+// SyntheticPointPolicy defines how the points marked with the LAS synthetic flag are tiled
+type SyntheticPointPolicy int
+
+const (
+	// SyntheticInclude tiles the synthetic points together with the other points
+	SyntheticInclude SyntheticPointPolicy = iota
+	// SyntheticExclude discards the synthetic points
+	SyntheticExclude
+	// SyntheticSeparate tiles the synthetic points in a distinct tileset, stored in the synthetic subfolder of the
+	// output folder, and the other points in the main tileset
+	SyntheticSeparate
+)
+
+// LengthUnit is a unit of length, expressed as its length in meters
+type LengthUnit float64
+
+const (
+	UnitMeter        LengthUnit = 1
+	UnitFoot         LengthUnit = 0.3048
+	UnitUSSurveyFoot LengthUnit = 1200.0 / 3937
 )
 
 type TilerOptions struct {
-	gridSize         float64
-	maxDepth         int
-	elevationOffset  float64
-	eightBitColors   bool
-	geoidElevation   bool
-	numWorkers       int
-	minPointsPerTile int
-	callback         TilerCallback
+	gridSize               [3]float64
+	maxDepth               int
+	elevationOffset        float64
+	eightBitColors         bool
+	geoidElevation         bool
+	readWorkers            int
+	exportWorkers          int
+	minPointsPerTile       int
+	packaging              Packaging
+	dropWithheld           bool
+	dropOverlap            bool
+	elevationClamp         *elevationClamp
+	sorNeighbors           int
+	sorStdDevMul           float64
+	filePattern            string
+	colorGamma             float64
+	readBufferSize         int
+	loadAttributes         AttrFlags
+	crsGrouping            bool
+	cancelCheckInterval    int
+	fullDetailDistance     float64
+	smoothingRadius        float64
+	smoothingIterations    int
+	outputRegion           *Region
+	minContentBytes        int64
+	classAlpha             map[uint8]uint8
+	recursive              bool
+	reprojectionWorkers    int
+	clock                  utils.Clock
+	overviewPoints         int
+	overview               bool
+	epsgRegistry           string
+	heightRules            []HeightRule
+	contentExtension       string
+	errorCollector         *ErrorCollector
+	previewImage           *previewImage
+	geometricErrorMode     GeometricErrorMode
+	perPointBatchId        bool
+	progressWriter         *progressPrinter
+	datumGridPath          string
+	openFileLimiter        *las.OpenFileLimiter
+	manifestPath           string
+	elevationRaster        *elevationRaster
+	classRemap             map[uint8]uint8
+	prettyTileset          bool
+	featurePreserving      bool
+	colorMapping           [3]ColorSource
+	heightExaggeration     float64
+	sparseNodePolicy       SparseNodePolicy
+	extraFilter            *extraDimensionFilter
+	terrainOutput          bool
+	resultCallback         ResultCallback
+	heightAboveGround      *heightBand
+	quantizedPositions     bool
+	continueOnError        bool
+	verticalEpsg           int
+	sourceFileAttribute    bool
+	subdivision            Subdivision
+	debugFootprints        string
+	colorByClass           bool
+	classPalette           map[uint8][3]uint8
+	phaseCallback          PhaseCallback
+	externalClassification string
+	coordinateRounding     float64
+	maxTiles               int
+	inputUnits             [2]LengthUnit
+	tileSink               *tileSink
+	geoidGrid              string
+	sourceQuantization     bool
+	spatialIndexCache      bool
+	classColorFile         string
+	pointSourceFilter      []uint16
+	pointSourceIdAttribute bool
+	equalAreaThinning      bool
+	flattenLevels          int
+	tilesetProperties      bool
+	bufferAlignment        int
+	pointRange             [2]int
+	colorRaster            string
+	flipAxis               [3]bool
+	temporalWindow         time.Duration
+	// timeWindow is the GPS time interval of the points of the tileset being exported by WithTemporalTiling
+	timeWindow             *[2]float64
+	contentBoundingVolumes bool
+	colorMatrix            [9]float64
+	rootPointTarget        int
+	defaultColor           *[3]uint8
+	colorBits              int
+	contentPostProcessor   ContentPostProcessor
+	strictCrs              bool
+	syntheticPolicy        SyntheticPointPolicy
+	// syntheticOnly keeps only the synthetic points, for the tileset exported by SyntheticSeparate
+	syntheticOnly bool
+	pointSpacing  bool
+	// averagePointSpacing is the spacing estimated by WithPointSpacing for the tileset being exported, 0 if unknown
+	averagePointSpacing float64
+	callback            TilerCallback
+}
+
+type elevationClamp struct {
+	min  float64
+	max  float64
+	mode ElevationClampMode
+}
+
+type extraDimensionFilter struct {
+	name string
+	min  float64
+	max  float64
+}
+
+// heightBand is a range of heights above the ground
+type heightBand struct {
+	min float64
+	max float64
+}
+
+type elevationRaster struct {
+	path   string
+	policy RasterOutsidePolicy
+}
+
+// previewImage is the top-down image of the point cloud written with WithPreviewImage
+type previewImage struct {
+	path   string
+	width  int
+	height int
 }
 
 type tilerOptionsFn func(*TilerOptions)
 
+// Region is a geographic area, with the longitudes and latitudes in degrees (WGS84)
+type Region struct {
+	West, South, East, North float64
+}
+
+// HeightRule assigns the Classification to the points whose height above the local ground is in the [Min, Max] range
+type HeightRule struct {
+	Min, Max       float64
+	Classification uint8
+}
+
+// TilerCallback is invoked to report the progress of the tiler. All events are emitted
+// from the goroutine calling ProcessFiles or ProcessFolder.
 type TilerCallback func(event TilerEvent, inputDesc string, elapsed int64, msg string)
 
+// TilesetResult describes a tileset generated by the tiler
+type TilesetResult struct {
+	// OutputFolder is the folder storing the tileset
+	OutputFolder string
+	// RootTransform is the 4x4 matrix, in column-major order, converting the coordinates of the points of the root
+	// tile, which are relative to its RTC_CENTER, to EPSG:4978 coordinates
+	RootTransform [16]float64
+	// InverseRootTransform converts EPSG:4978 coordinates to coordinates relative to the center of the root tile
+	InverseRootTransform [16]float64
+	// Timings reports the time spent in each phase of the processing
+	Timings PhaseTimings
+	// AveragePointSpacing is the average distance, in meters, between the points and their nearest neighbor estimated
+	// with WithPointSpacing, 0 if not estimated
+	AveragePointSpacing float64
+}
+
+// Phase identifies a phase of the processing of a tileset
+type Phase string
+
+const (
+	PhaseReadHeader   Phase = "read-header"
+	PhaseLoad         Phase = "load"
+	PhaseReprojection Phase = "reprojection"
+	PhaseBuild        Phase = "build"
+	PhaseExport       Phase = "export"
+)
+
+// PhaseTimings reports the wall clock time spent in each phase of the processing of a tileset
+type PhaseTimings struct {
+	// ReadHeader is the time spent opening the input files and reading their headers
+	ReadHeader time.Duration
+	// Load is the time spent reading, transforming and storing the points, including the Reprojection
+	Load time.Duration
+	// Reprojection is the time spent converting the coordinates of the points while loading them. It is summed
+	// over the load workers, hence it can exceed the Load time when using multiple workers.
+	Reprojection time.Duration
+	// Build is the time spent building the tree
+	Build time.Duration
+	// Export is the time spent writing the tileset and the additional outputs
+	Export time.Duration
+}
+
+// PhaseCallback receives the duration of a phase of the processing, as soon as it completes.
+// It is always invoked from the goroutine calling ProcessFiles or ProcessFolder.
+type PhaseCallback func(phase Phase, elapsed time.Duration)
+
+// ResultCallback receives the description of a generated tileset
+type ResultCallback func(result TilesetResult)
+
+// ContentPostProcessor transforms the bytes of a tile content right before they are stored. The path is the slash
+// separated path of the content relative to the tileset root, e.g. "0/3/content.pnts".
+type ContentPostProcessor func(path string, data []byte) ([]byte, error)
+
 // NewDefaultTilerOptions returns sensible defaults for tiling options
 func NewDefaultTilerOptions() *TilerOptions {
 	return &TilerOptions{
-		gridSize:         20,
-		maxDepth:         10,
-		elevationOffset:  0,
-		numWorkers:       runtime.NumCPU(),
-		minPointsPerTile: 5000,
-		eightBitColors:   false,
-		geoidElevation:   false,
-		callback:         nil,
+		gridSize:               [3]float64{20, 20, 20},
+		maxDepth:               10,
+		elevationOffset:        0,
+		readWorkers:            runtime.NumCPU(),
+		exportWorkers:          runtime.NumCPU(),
+		minPointsPerTile:       5000,
+		eightBitColors:         false,
+		geoidElevation:         false,
+		packaging:              PackageNone,
+		dropWithheld:           false,
+		dropOverlap:            false,
+		elevationClamp:         nil,
+		sorNeighbors:           0,
+		sorStdDevMul:           0,
+		filePattern:            "",
+		colorGamma:             1,
+		readBufferSize:         las.DefaultReadBufferSize,
+		loadAttributes:         AttrAll,
+		crsGrouping:            false,
+		cancelCheckInterval:    tree.DefaultCancellationCheckInterval,
+		manifestPath:           "",
+		elevationRaster:        nil,
+		classRemap:             nil,
+		prettyTileset:          true,
+		featurePreserving:      false,
+		colorMapping:           [3]ColorSource{ColorSourceRed, ColorSourceGreen, ColorSourceBlue},
+		heightExaggeration:     1,
+		sparseNodePolicy:       SparseNodeConsolidate,
+		extraFilter:            nil,
+		terrainOutput:          false,
+		resultCallback:         nil,
+		heightAboveGround:      nil,
+		quantizedPositions:     false,
+		continueOnError:        false,
+		verticalEpsg:           0,
+		sourceFileAttribute:    false,
+		subdivision:            SubdivisionOctree,
+		debugFootprints:        "",
+		colorByClass:           false,
+		classPalette:           nil,
+		phaseCallback:          nil,
+		externalClassification: "",
+		coordinateRounding:     0,
+		maxTiles:               1000000,
+		inputUnits:             [2]LengthUnit{UnitMeter, UnitMeter},
+		tileSink:               nil,
+		geoidGrid:              "",
+		sourceQuantization:     false,
+		spatialIndexCache:      false,
+		classColorFile:         "",
+		pointSourceFilter:      nil,
+		pointSourceIdAttribute: false,
+		equalAreaThinning:      false,
+		flattenLevels:          0,
+		tilesetProperties:      false,
+		pointRange:             [2]int{0, -1},
+		colorMatrix:            identityColorMatrix,
+		defaultColor:           nil,
+		colorBits:              24,
+		contentPostProcessor:   nil,
+		strictCrs:              false,
+		syntheticPolicy:        SyntheticInclude,
+		pointSpacing:           false,
+		callback:               nil,
+		clock:                  utils.SystemClock{},
+		overviewPoints:         0,
+		overview:               false,
+		epsgRegistry:           "",
+		heightRules:            nil,
+		contentExtension:       "",
+		errorCollector:         nil,
+		previewImage:           nil,
+		geometricErrorMode:     GeoErrSpacing,
+		perPointBatchId:        false,
+		progressWriter:         nil,
+		datumGridPath:          "",
+		openFileLimiter:        nil,
 	}
 }
 
@@ -62,7 +454,17 @@ func NewTilerOptions(optFn ...tilerOptionsFn) *TilerOptions {
 // any two points at the coarser level of detail. Expressed in meters.
 func WithGridSize(size float64) tilerOptionsFn {
 	return func(opt *TilerOptions) {
-		opt.gridSize = size
+		opt.gridSize = [3]float64{size, size, size}
+	}
+}
+
+// WithGridSizeXYZ sets a different grid size along each axis, so that the points are thinned with non cubic cells,
+// e.g. to preserve the vertical detail of cliffs while thinning flat ground. x and y are the horizontal sizes and z
+// the vertical one, all expressed in meters. The cells are aligned to the axes of EPSG:4978, the vertical size
+// applying to the axis closest to the local vertical. WithGridSize sets the same size along all axes.
+func WithGridSizeXYZ(x, y, z float64) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.gridSize = [3]float64{x, y, z}
 	}
 }
 
@@ -81,11 +483,56 @@ func WithElevationOffset(offset float64) tilerOptionsFn {
 	}
 }
 
-// WithWorkerNumber sets the number of workers to use to read the las files or to
-// run the export jobs
+// WithWorkerNumber sets the number of workers to use to read the las files and to
+// run the export jobs. It is a shortcut for setting both WithReadWorkers and WithExportWorkers.
 func WithWorkerNumber(numWorkers int) tilerOptionsFn {
 	return func(opt *TilerOptions) {
-		opt.numWorkers = numWorkers
+		opt.readWorkers = numWorkers
+		opt.exportWorkers = numWorkers
+	}
+}
+
+// WithReadWorkers sets the number of workers to use to load the points, transforming their coordinates,
+// and to run the statistical outlier removal
+func WithReadWorkers(numWorkers int) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.readWorkers = numWorkers
+	}
+}
+
+// withClock sets the clock measuring the elapsed times reported to the callbacks and scheduling the progress
+// reports, so that the tests can control them. Defaults to the system clock.
+func withClock(clock utils.Clock) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.clock = clock
+	}
+}
+
+// WithParallelReprojection sets the number of workers reprojecting the points while loading them, overriding
+// WithReadWorkers for the loading only, e.g. to scale the reprojection of large clouds in projected CRSs, which is
+// the most expensive step of the loading. The points are read in batches distributed to the workers, each with its
+// own proj4 projection objects as these are not safe for concurrent use. A non positive value (default) uses the
+// read workers.
+func WithParallelReprojection(numWorkers int) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.reprojectionWorkers = numWorkers
+	}
+}
+
+// WithExportWorkers sets the number of workers to use to write the tiles
+func WithExportWorkers(numWorkers int) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.exportWorkers = numWorkers
+	}
+}
+
+// WithMaxOpenFiles sets the maximum number of LAS files open at the same time, independently of the number of
+// workers, to stay below the limit of open files of the operating system when joining or processing many files.
+// Each file is then kept open only while reading its header and its points. The limit is shared by all the tilesets
+// generated with the returned options. A value not greater than zero means no limit (the default).
+func WithMaxOpenFiles(n int) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.openFileLimiter = las.NewOpenFileLimiter(n)
 	}
 }
 
@@ -104,6 +551,19 @@ func WithCallback(callback TilerCallback) tilerOptionsFn {
 	}
 }
 
+// WithProgressWriter writes the progress of the tiler to the given writer as human readable lines, one for each event,
+// as an alternative to implementing a TilerCallback. If the writer is a terminal the progress of the export is drawn
+// as a bar redrawn in place, otherwise it is written as lines too. It can be combined with WithCallback.
+// A nil writer disables the output.
+func WithProgressWriter(w io.Writer) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.progressWriter = nil
+		if w != nil {
+			opt.progressWriter = newProgressPrinter(w)
+		}
+	}
+}
+
 // WithEightBitColors true forces the tiler to interpret the color info on the file as eight bit colors
 func WithEightBitColors(eightBit bool) tilerOptionsFn {
 	return func(opt *TilerOptions) {
@@ -117,3 +577,806 @@ func WithGeoidElevation(geoid bool) tilerOptionsFn {
 		opt.geoidElevation = geoid
 	}
 }
+
+// WithPackaging sets how the output tileset files should be packaged, either as loose files,
+// as a single 3D Tiles archive (.3tz) or as a single zip archive ready to be uploaded to Cesium ion
+func WithPackaging(packaging Packaging) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.packaging = packaging
+	}
+}
+
+// WithDropWithheld true discards the points flagged as withheld while loading them
+func WithDropWithheld(drop bool) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.dropWithheld = drop
+	}
+}
+
+// WithDropOverlap true discards the points flagged as overlap while loading them.
+// For legacy LAS point formats (0-5) the points with classification 12 are considered overlap points.
+func WithDropOverlap(drop bool) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.dropOverlap = drop
+	}
+}
+
+// WithElevationClamp discards the points whose Z coordinate is outside of the [min, max] range.
+// The range is expressed in the input CRS and it is applied before any elevation correction or reprojection.
+func WithElevationClamp(min, max float64) tilerOptionsFn {
+	return WithElevationClampMode(min, max, ClampDrop)
+}
+
+// WithElevationClampMode limits the Z coordinate of the points to the [min, max] range, either discarding
+// the points outside of it or moving them to the nearest bound depending on the given mode.
+// The range is expressed in the input CRS and it is applied before any elevation correction or reprojection.
+// The tiling fails with an error if min is greater than max.
+func WithElevationClampMode(min, max float64, mode ElevationClampMode) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.elevationClamp = &elevationClamp{
+			min:  min,
+			max:  max,
+			mode: mode,
+		}
+	}
+}
+
+// WithStatisticalOutlierRemoval enables the removal of noisy points. For each point the mean distance to its
+// k nearest neighbors is computed and the points whose mean distance exceeds the global mean by more than
+// stdDevMul standard deviations are discarded. The filter is computed after loading all points, using the
+// configured number of read workers. A k of 0 disables the filter.
+func WithStatisticalOutlierRemoval(k int, stdDevMul float64) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.sorNeighbors = k
+		opt.sorStdDevMul = stdDevMul
+	}
+}
+
+// WithElevationSmoothing smooths the elevation of the points to reduce the vertical noise of bare-earth clouds,
+// improving the look of the terrain levels of detail. In each iteration the elevation of every point is replaced with
+// the average of the elevations of the points within the given radius, in meters, weighted by 1 - distance / radius.
+// Unlike WithStatisticalOutlierRemoval no point is discarded, but the geometry is modified: the points are moved
+// along the local vertical, so the smoothing is not suited to clouds with buildings or vegetation whose edges would
+// be blurred. The filter runs after loading all points and after the outlier removal, using the spatial index shared
+// with the other neighborhood based operations. A non positive radius or number of iterations (default) disables it.
+func WithElevationSmoothing(radius float64, iterations int) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.smoothingRadius = radius
+		opt.smoothingIterations = iterations
+	}
+}
+
+// WithFilePattern restricts the files processed in folder mode to the ones whose base name matches
+// the given glob pattern (e.g. "tile_00*.las"), ignoring the case. An empty pattern processes all LAS files.
+// The tiling fails with an error if no file matches the pattern.
+func WithFilePattern(pattern string) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.filePattern = pattern
+	}
+}
+
+// WithRecursive makes the folder mode process the LAS files in all the subfolders of the input folder too. The
+// tileset of each file is stored in the output folder under the path of the file relative to the input folder, e.g.
+// the tileset of a/scan.las is stored in a/scan, so that files with the same name in different subfolders do not
+// collide. Defaults to false, processing only the files directly in the input folder.
+func WithRecursive(recursive bool) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.recursive = recursive
+	}
+}
+
+// WithColorGamma sets the gamma correction to apply to the RGB colors of the points while loading them.
+// Each channel is computed as 255 * (c/255)^(1/gamma), hence values above 1 brighten the colors and values
+// below 1 darken them. The default value of 1 leaves the colors unchanged. The gamma must be greater than zero,
+// otherwise the tiling fails with an error.
+func WithColorGamma(gamma float64) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.colorGamma = gamma
+	}
+}
+
+// identityColorMatrix is the color matrix leaving the colors unchanged
+var identityColorMatrix = [9]float64{1, 0, 0, 0, 1, 0, 0, 0, 1}
+
+// WithColorMatrix transforms the RGB colors of the points while loading them with the given 3x3 matrix, stored in
+// row major order, e.g. to convert the colors from the space of a sensor to sRGB: each output channel is the sum of
+// the input R, G and B channels multiplied by the corresponding row of the matrix, rounded and clamped to 0-255.
+// The matrix applies to the colors sampled by WithColorFromRaster too and before WithColorGamma.
+// Defaults to the identity matrix, leaving the colors unchanged.
+func WithColorMatrix(matrix [9]float64) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.colorMatrix = matrix
+	}
+}
+
+// WithDefaultColor assigns the given RGB color to the points without a color, i.e. the ones whose R, G and B
+// channels are all zero, so that they do not render black, e.g. when joining files with and without colors.
+// A mid gray such as 128, 128, 128 works well in most scenes. The color is assigned after WithColorMatrix and
+// WithColorGamma, so it is rendered unchanged, while WithColorByClassification takes precedence over it.
+// By default the points without a color are left black.
+func WithDefaultColor(r, g, b uint8) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.defaultColor = &[3]uint8{r, g, b}
+	}
+}
+
+// WithColorBits reduces the precision of the RGB colors of the points to the given total number of bits, between
+// 3 and 24, before they are exported: e.g. 16 bits stores them as RGB565, 5 bits for red and blue and 6 for green.
+// The colors are still stored as 8 bit channels in the pnts, but the fewer distinct values compress considerably
+// better with gzip or Draco, pairing well with WithQuantizedPositions. The visual impact is a banding of smooth
+// gradients, hardly noticeable in most scenes at 16 bits and increasingly evident below 12 bits, while pure black
+// and white are always preserved. The reduction applies last, after all the other color options. Defaults to 24,
+// preserving the full color fidelity. Values out of range make the tiling fail with an error.
+func WithColorBits(bits int) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.colorBits = bits
+	}
+}
+
+// WithCancellationCheckInterval sets every how many points the loaders check whether the context has been
+// cancelled. Checking at every point costs time in the tight loading loop of large files, while checking too
+// rarely delays the reaction to a cancellation, e.g. on Ctrl-C. The context is always checked before the first
+// point and values below 1 check it at every point. Defaults to 1024 points.
+func WithCancellationCheckInterval(n int) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.cancelCheckInterval = n
+	}
+}
+
+// WithReadBufferSize sets the size, in bytes, of the buffer used to read the LAS files. Larger buffers
+// reduce the number of read syscalls, which can considerably speed up reading from network storage.
+// Defaults to 1MB. The size must be greater than zero, otherwise the tiling fails with an error.
+func WithReadBufferSize(size int) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.readBufferSize = size
+	}
+}
+
+// WithLoadAttributes sets the attributes of the points decoded from the LAS files, e.g. AttrColor to load only the
+// coordinates and the colors, skipping the time spent decoding the others on large files. The attributes not loaded
+// are zero, hence they are exported as zeros and the options relying on them, such as WithDropWithheld,
+// WithColorByClassification, WithTemporalTiling or WithPointSourceFilter, behave as if all the points had zero
+// values. The coordinates are always loaded. Defaults to AttrAll.
+func WithLoadAttributes(attributes AttrFlags) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.loadAttributes = attributes
+	}
+}
+
+// WithManifest writes a JSON manifest listing the path, size and SHA-256 hash of every file of the
+// output tileset. A relative path is resolved against the output folder of each tileset, hence in
+// folder mode every tileset gets its own manifest. Since tilesets would overwrite each other's manifest,
+// in folder mode the path must be relative and must not point outside of the tileset folder.
+// When packaging as 3tz or zip the manifest lists the files stored in the archive. The manifest is written
+// only if the export succeeds. An empty path disables the manifest.
+func WithManifest(path string) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.manifestPath = path
+	}
+}
+
+// WithElevationFromRaster replaces the elevation of the points with the one sampled with a bilinear interpolation
+// from the GeoTIFF DEM at the given path. If the raster declares a CRS different from the input one, the points
+// are reprojected to the raster CRS to sample it. Points falling outside of the raster or on NoData pixels are discarded.
+// The replaced elevation is the one checked by WithElevationClamp, if also set. It cannot be combined with WithHeightExaggeration.
+// The raster is read only once per ProcessFiles or ProcessFolder call but it is fully decoded in memory
+// as 32 bit floats, requiring about width*height*bands*4 bytes: large DEMs should be cropped to the area of interest.
+func WithElevationFromRaster(path string) tilerOptionsFn {
+	return WithElevationFromRasterPolicy(path, RasterOutsideDrop)
+}
+
+// WithElevationFromRasterPolicy works as WithElevationFromRaster, with the given policy determining what happens
+// to the points falling outside of the raster or on NoData pixels.
+func WithElevationFromRasterPolicy(path string, policy RasterOutsidePolicy) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.elevationRaster = &elevationRaster{
+			path:   path,
+			policy: policy,
+		}
+	}
+}
+
+// WithColorFromRaster replaces the color of the points with the one sampled with a bilinear interpolation from the
+// first three bands, as red, green and blue, of the GeoTIFF orthophoto at the given path. If the raster declares a
+// CRS different from the input one, the points are reprojected to the raster CRS to sample it. The bands must store
+// 8 bit values. Points falling outside of the raster or on NoData pixels keep their color. The colors sampled are
+// further processed by WithColorGamma and replaced by WithColorByClassification, if also set. As for
+// WithElevationFromRaster the raster is read once per ProcessFiles or ProcessFolder call and fully decoded in memory.
+func WithColorFromRaster(path string) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.colorRaster = path
+	}
+}
+
+// WithClassificationRemap converts the classification of the points while they are loaded, according to the given
+// mapping from the original class to the new one, e.g. {40: 2} converts the vendor specific class 40 to the ASPRS
+// class 2 (ground). Classes not present in the mapping are left unchanged.
+func WithClassificationRemap(mapping map[uint8]uint8) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.classRemap = make(map[uint8]uint8, len(mapping))
+		for from, to := range mapping {
+			opt.classRemap[from] = to
+		}
+	}
+}
+
+// WithPrettyTileset sets whether the tileset.json files are indented for readability (the default)
+// or minified to reduce their size.
+func WithPrettyTileset(pretty bool) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.prettyTileset = pretty
+	}
+}
+
+// WithFeaturePreservingThinning enables an alternate sampling that preserves the sharp features of the cloud,
+// such as building edges and corners, in the coarse levels of detail. When promoting points to a parent tile, the
+// grid cells whose points do not lie on a plane retain up to 8 points, one per sub-cell, instead of a single one.
+// As a result the tiles can store more points than with the default sampling.
+func WithFeaturePreservingThinning(enabled bool) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.featurePreserving = enabled
+	}
+}
+
+// WithColorChannelMapping sets the channels of the LAS point records used as the red, green and blue colors of the
+// points, enabling false color visualizations of multispectral data, e.g. (ColorSourceNIR, ColorSourceRed, ColorSourceGreen)
+// shows the vegetation in red. The NIR channel is only stored by the LAS point formats 8 and 10. The intensities of a
+// file are scaled down as 16 bit values unless none of them exceeds 255. Sources not stored by the point format of a
+// file produce zero values. Defaults to (ColorSourceRed, ColorSourceGreen, ColorSourceBlue).
+func WithColorChannelMapping(r, g, b ColorSource) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.colorMapping = [3]ColorSource{r, g, b}
+	}
+}
+
+// WithHeightExaggeration multiplies the height of the points above the minimum elevation of the dataset, as declared
+// in the LAS headers, by the given factor, to enhance subtle terrain features when visualizing the tileset. The
+// exaggeration is applied in the input CRS before any elevation correction or reprojection, after WithElevationClamp.
+// Note that the output elevations are no longer metric and do not match the real elevations of the points.
+// It cannot be combined with WithElevationFromRaster, as the header elevations do not relate to the replaced ones, and
+// the tiling fails with an error if both are set.
+// Defaults to 1, which leaves the elevations unchanged. The factor must be greater than zero, otherwise the tiling fails with an error.
+func WithHeightExaggeration(factor float64) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.heightExaggeration = factor
+	}
+}
+
+// WithSparseNodePolicy sets what happens to the tiles that would store less points than the minimum number of points
+// per tile, e.g. at the sparse edges of the cloud: SparseNodeConsolidate (default) moves their points into the parent
+// tile, so that no point is lost but the parent grows denser, SparseNodeKeep writes them as tiles below the threshold
+// and SparseNodeOmit discards them together with their points. Viewers can behave differently with the resulting
+// trees, e.g. omitting the sparse tiles avoids overcrowded parents but leaves gaps in the finest levels of detail.
+func WithSparseNodePolicy(policy SparseNodePolicy) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.sparseNodePolicy = policy
+	}
+}
+
+// WithExtraDimensionFilter discards the points whose value of the given extra bytes dimension is outside of the
+// [min, max] range, e.g. to drop the points with a low confidence assigned by a ML classifier. The dimension is looked
+// up by name in the Extra Bytes VLR of the LAS files and its scale and offset are applied before the comparison.
+// Points storing the no data value of the dimension are discarded. The tiling fails with an error if a file does
+// not define the dimension or if min is greater than max.
+func WithExtraDimensionFilter(name string, min, max float64) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.extraFilter = &extraDimensionFilter{
+			name: name,
+			min:  min,
+			max:  max,
+		}
+	}
+}
+
+// WithTerrainOutput enables the generation of a Cesium quantized-mesh terrain from the ground points, i.e. the points
+// with classification 2, stored in the terrain subfolder of the tileset next to the point tiles. The ground points are
+// triangulated with a Delaunay triangulation in each terrain tile, from the root level down to the level matching their
+// average spacing, and the terrain can be loaded in Cesium with a CesiumTerrainProvider pointing to that folder.
+// The terrain is always stored as loose files, also when the tileset is packaged. The tiling fails with an error
+// if the tileset contains no ground points.
+func WithTerrainOutput(enabled bool) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.terrainOutput = enabled
+	}
+}
+
+// WithOverviewTileset generates, next to the tileset, a coarse overview tileset stored in the overview subfolder,
+// made of a single tile with at most the given number of points. The points are taken from the coarsest levels of
+// the tree, sampling uniformly the first level exceeding the limit, so that a viewer can load the overview quickly
+// and then switch to the full tileset. A non positive value generates no overview (the default).
+func WithOverviewTileset(maxPoints int) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.overviewPoints = maxPoints
+	}
+}
+
+// WithEpsgRegistry loads the proj4 definitions of the CRSs from the EPSG registry file at the given path, overriding
+// the definitions bundled with the tiler for the codes it contains and adding the codes they lack. The file can be a
+// proj init file, with entries such as <4326> +proj=longlat +datum=WGS84 +no_defs <>, or a tab separated file with
+// the EPSG:code, the description and the definition on each line. The registry is read once per ProcessFiles or
+// ProcessFolder call. An empty path uses the bundled definitions only (the default).
+func WithEpsgRegistry(path string) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.epsgRegistry = path
+	}
+}
+
+// WithDatumGridPath sets a folder where the datum grid shift files, such as the NTv2 (.gsb) and NADCON grids, are
+// searched in before the grids bundled with the tiler, to apply accurate grid based datum shifts to the CRSs whose
+// definitions reference them with the +nadgrids parameter, e.g. NAD27. The folder is searched by all the conversions
+// of the process while the tiling runs, together with the folders of the other runs in progress, hence the grid files
+// with the same name must be the same in all the folders used concurrently. The tiling fails with an error if the
+// folder does not exist.
+func WithDatumGridPath(dir string) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.datumGridPath = dir
+	}
+}
+
+// WithErrorCollector sets the collector accumulating the non fatal issues found while tiling, such as the points
+// discarded by each filter, the colors truncated reading 16 bit colors with WithEightBitColors and the CRS warnings,
+// to be inspected with its Warnings method once the processing returns. The same collector can be shared by
+// multiple runs. A nil collector (the default) collects nothing.
+func WithErrorCollector(collector *ErrorCollector) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.errorCollector = collector
+	}
+}
+
+// WithResultCallback sets a function invoked with the description of each tileset generated, once the export completes.
+// ProcessFolder invokes it once per input file.
+func WithResultCallback(callback ResultCallback) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.resultCallback = callback
+	}
+}
+
+// WithHeightAboveGround retains only the points whose height above the local ground is in the [min, max] range, e.g.
+// 0.5-3 to isolate the objects standing on the ground. The ground surface is computed, before loading the points,
+// from the mean elevation of the ground points (classification 2) in cells of 5x5 units of the input CRS, hence
+// it requires a projected input CRS with metric units. Ground points are always retained, while points with no
+// ground points nearby are discarded. The filter runs after all the other transformations and filters, so that it
+// sees the final classification and elevation of the points. The tiling fails with an error if min is greater than max.
+func WithHeightAboveGround(min, max float64) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.heightAboveGround = &heightBand{
+			min: min,
+			max: max,
+		}
+	}
+}
+
+// WithHeightRules reclassifies the points by their height above the local ground, assigning to each point the
+// classification of the first rule whose range contains its height, e.g. a rule from -1000 to 0.2 with
+// classification 2 turns into ground all the points less than 20 cm above the ground. Points matching no rule keep
+// their classification, as well as points with no ground points nearby. The ground surface is computed from the
+// ground points as done by WithHeightAboveGround, with the classifications preceding the rules, hence it requires a
+// projected input CRS with metric units. The rules are applied after the other transformations, except the
+// classification colors of WithColorByClassification which reflect the new classifications, and before the filter of
+// WithHeightAboveGround. The tiling fails with an error if the min of a rule is greater than its max.
+func WithHeightRules(rules []HeightRule) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.heightRules = rules
+	}
+}
+
+// WithQuantizedPositions stores the positions of the points as 16 bit integers quantized over the bounding box of each
+// tile, using the POSITION_QUANTIZED property of the pnts format, instead of 32 bit floats (the default). This roughly
+// halves the size of the positions, at the cost of a precision of 1/65535 of the tile extent along each axis.
+func WithQuantizedPositions(quantized bool) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.quantizedPositions = quantized
+	}
+}
+
+// WithCrsGrouping sets whether ProcessFolder groups the files by the CRS declared in the GeoTIFF keys or the WKT of
+// their (extended) variable length records, merging the files of each group in a single tileset, instead of
+// converting each file separately (the default). This makes folders mixing files in several CRSs usable in a single
+// run. The tileset of each group is stored in the epsg_<code> subfolder of the output folder, while the crs.json file
+// in the output folder lists the groups and their files. The files not declaring an EPSG code, e.g. because their CRS
+// is user defined, are assigned to the EPSG code passed to ProcessFolder, which can be omitted if all the files
+// declare one.
+// WithContinueOnError is ignored when grouping.
+func WithCrsGrouping(enabled bool) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.crsGrouping = enabled
+	}
+}
+
+// WithContinueOnError sets whether ProcessFolder keeps processing the remaining files when the conversion of a file fails,
+// instead of stopping at the first failure (the default). When enabled, ProcessFolder returns an error joining the
+// errors of all the failed files, each identified by a las.FileError, once all the files have been processed.
+// Processing stops anyway if the context is cancelled.
+func WithContinueOnError(continueOnError bool) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.continueOnError = continueOnError
+	}
+}
+
+// WithVerticalEpsg sets the EPSG code of the vertical CRS the input elevations are referred to, for data in a
+// compound CRS. Heights referred to the EGM96 geoid (EPSG:5773) are converted to ellipsoidal heights with the built-in
+// EGM96 model, as done by WithGeoidElevation. Any other vertical datum, including EPSG:3855 EGM2008 and the ones
+// that require a national grid, e.g. EPSG:5783 DHHN92, make the processing fail unless the grid is set with
+// WithGeoidGrid.
+// A value of 0 (default) means the elevations are ellipsoidal or handled by WithGeoidElevation.
+func WithVerticalEpsg(code int) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.verticalEpsg = code
+	}
+}
+
+// WithSourceFileAttribute true stores the index of the input file each point was read from in the _SOURCE_FILE
+// per point property, useful to trace the provenance of the points when joining multiple files. The list of
+// the input files, in index order, is stored in the sourceFiles property of the asset.extras of the root tileset.json.
+func WithSourceFileAttribute(sourceFile bool) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.sourceFileAttribute = sourceFile
+	}
+}
+
+// WithSubdivision sets how the tiles are split into their children, by default as an octree. The quadtree
+// subdivision only splits the tiles horizontally, producing fewer tiles for largely flat datasets.
+func WithSubdivision(subdivision Subdivision) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.subdivision = subdivision
+	}
+}
+
+// WithDebugFootprints writes a GeoJSON FeatureCollection with the geographic footprint of each tile, its level and
+// number of points to the given file, to diagnose the coverage of the tileset. Relative paths are resolved against
+// the output folder of the tileset. An empty path (default) disables the output.
+func WithDebugFootprints(path string) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.debugFootprints = path
+	}
+}
+
+// WithPreviewImage writes a PNG image of the given size, in pixels, with the top-down view of the point cloud, e.g. as
+// a thumbnail for a catalog. The points are taken from the coarsest levels of the tree, about four for each pixel,
+// and drawn from the lowest to the highest, colored with their RGB colors or, if the cloud has no colors, with a ramp
+// of their elevation. The image fits the cloud preserving its aspect ratio, the pixels without points are transparent.
+// Relative paths are resolved against the output folder of the tileset. The tiling fails with an error if the width
+// or the height is not greater than zero.
+func WithPreviewImage(path string, width, height int) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.previewImage = &previewImage{
+			path:   path,
+			width:  width,
+			height: height,
+		}
+	}
+}
+
+// WithGeometricErrorMode sets how the geometric error of the tiles decreases with their level. The error of the root
+// tile is always the mean spacing between its points. GeoErrSpacing (default) measures the error of every tile as the
+// mean spacing between its points, capped by the error of its parent, so that the dense and the sparse areas of clouds
+// with a mixed density are refined each at their own pace. GeoErrGeometric halves the error at each level, as the grid
+// spacing does, GeoErrLinear decreases it linearly down to a fraction 1/(max depth + 1) of the root error at the
+// maximum depth. The higher errors of the deeper tiles in the linear mode make viewers refine them, loading their
+// children, from farther away. In both modes the root is the only tile whose error is measured.
+func WithGeometricErrorMode(mode GeometricErrorMode) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.geometricErrorMode = mode
+	}
+}
+
+// WithColorByClassification true replaces the color of the points with the one of their classification, as defined by
+// the built-in palette of the ASPRS classes and the palette set with WithClassificationPalette.
+// The classes present in neither of them are colored in gray.
+func WithColorByClassification(colorByClass bool) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.colorByClass = colorByClass
+	}
+}
+
+// WithClassificationPalette sets the RGB colors of the classes used by WithColorByClassification, overriding the
+// colors of the built-in palette for the classes present in the given mapping.
+func WithClassificationPalette(palette map[uint8][3]uint8) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.classPalette = make(map[uint8][3]uint8, len(palette))
+		for class, color := range palette {
+			opt.classPalette[class] = color
+		}
+	}
+}
+
+// WithClassificationColorFile colors the points by classification as WithColorByClassification does, reading the
+// colors of the classes from a CSV file mapping each classification code to a hex RGB color, e.g. "6,#e63946".
+// The colors of the file override the built-in palette and are in turn overridden by WithClassificationPalette.
+func WithClassificationColorFile(path string) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.classColorFile = path
+		if path != "" {
+			opt.colorByClass = true
+		}
+	}
+}
+
+// WithPhaseCallback sets a function invoked with the duration of each phase of the processing as soon as the phase
+// completes. The durations are also reported in the Timings of the TilesetResult.
+func WithPhaseCallback(callback PhaseCallback) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.phaseCallback = callback
+	}
+}
+
+// WithExternalClassification overrides the classification of the points with the labels stored in the given file,
+// one byte per point in the same order as the points of the input files. The tiling fails if the number of labels
+// differs from the number of points. The labels are applied before the mutators, e.g. before WithClassificationRemap.
+func WithExternalClassification(path string) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.externalClassification = path
+	}
+}
+
+// WithCoordinateRounding rounds the coordinates of the points to multiples of the given step after the reprojection,
+// in the meters of the EPSG:4978 output coordinates, e.g. 0.01 to remove the spurious precision below one centimeter.
+// Unlike WithQuantizedPositions it does not change the tile format. A step of 0 (default) disables the rounding.
+func WithCoordinateRounding(step float64) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.coordinateRounding = step
+	}
+}
+
+// WithMaxTiles makes the export fail, before writing any tile, if the tileset would have more than the given
+// number of tiles. It is a safety limit against misconfigured resolutions and depths filling the disk with tiny tiles.
+// Defaults to 1 million tiles, a value of 0 disables the limit.
+func WithMaxTiles(n int) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.maxTiles = n
+	}
+}
+
+// WithInputUnits sets the units of the horizontal (X, Y) and vertical (Z) coordinates of the input points, which
+// are converted to meters before any other transformation, e.g. WithInputUnits(UnitUSSurveyFoot, UnitMeter) for
+// data in US survey feet horizontally and meters vertically. The EPSG code must then refer to a CRS in meters.
+// Both units default to meters.
+func WithInputUnits(horizontal, vertical LengthUnit) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.inputUnits = [2]LengthUnit{horizontal, vertical}
+	}
+}
+
+// WithGeoidGrid sets the path of a geoid undulation grid, either a GTX file or a single band GeoTIFF in EPSG:4326,
+// used to convert the input elevations from geoid to ellipsoidal heights in place of the built-in EGM model.
+// Setting a grid implies the conversion, and allows any vertical datum to be set with WithVerticalEpsg, as the
+// grid defines the geoid the heights are referred to. An empty path (default) keeps the built-in model.
+func WithGeoidGrid(path string) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.geoidGrid = path
+	}
+}
+
+// WithSourceQuantization true records in the asset.extras.source property of the tileset the scale and offset of the
+// coordinates of each input LAS file, as declared in the file headers, to preserve the provenance of the data.
+// The geometry of the tiles is not affected.
+func WithSourceQuantization(enabled bool) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.sourceQuantization = enabled
+	}
+}
+
+// WithSpatialIndexCache true builds the nearest neighbor index of the loaded points only once, sharing it among the
+// outlier removal, the elevation smoothing and the estimation of the geometric error of the root tile, instead of
+// building one for each of them. The points removed by the outlier removal and by the sampling of the root are just
+// excluded from the index, which is rebuilt only after the smoothing moves the points. This speeds up the processing
+// when several of them are enabled, at the cost of keeping the index in memory until the geometric error is estimated.
+func WithSpatialIndexCache(enabled bool) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.spatialIndexCache = enabled
+	}
+}
+
+// WithPointSourceFilter keeps only the points whose point source ID, which typically identifies the flight line or
+// scan they were acquired in, is among the given ones. An empty list (default) keeps all points.
+func WithPointSourceFilter(ids []uint16) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.pointSourceFilter = append([]uint16(nil), ids...)
+	}
+}
+
+// WithPointSourceIdAttribute true stores the point source ID of each point, which typically identifies the flight line
+// or scan it was acquired in, in the POINT_SOURCE_ID property of the batch table of the tiles.
+func WithPointSourceIdAttribute(pointSourceId bool) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.pointSourceIdAttribute = pointSourceId
+	}
+}
+
+// WithPerPointBatchId true stores a BATCH_ID for each point in the feature table of the tiles, with a batch length
+// equal to the number of points, so that each point is a feature whose properties of the batch table are returned
+// when picking it in Cesium. The ids take four bytes per point, so it is disabled by default.
+func WithPerPointBatchId(enabled bool) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.perPointBatchId = enabled
+	}
+}
+
+// WithEqualAreaThinning true thins the points on a grid of columns spanning each tile vertically instead of cubic
+// cells, so that the tiles retain a uniform number of points per unit of horizontal area regardless of the slope of
+// the terrain. This produces a uniform screen density in nadir views, at the cost of sparser steep faces.
+func WithEqualAreaThinning(enabled bool) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.equalAreaThinning = enabled
+	}
+}
+
+// WithFlatten caps the number of levels of the exported tileset, root included, for clients unable to handle deep
+// tilesets: the tiles of the last level store the thinned points of all their descendants. Unlike WithMaxDepth, the
+// tree is built as usual and only reshaped when exported. A non positive value (default) exports all the levels.
+func WithFlatten(maxLevels int) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.flattenLevels = maxLevels
+	}
+}
+
+// WithMinContentBytes reduces the number of tiny files of the tileset, which perform poorly on object stores, merging
+// the points of the leaf tiles whose content.pnts would be smaller than n bytes into their parent tile. The merge
+// proceeds bottom up: a tile whose children are all merged becomes a leaf, merged in turn into its own parent if its
+// content is still smaller than n bytes. The merged points are displayed together with the ones of the parent tile,
+// trading a slightly coarser level of detail for far fewer files. The sizes are estimated from the number of points
+// before writing the tiles. A non positive value (default) writes all the tiles.
+func WithMinContentBytes(n int64) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.minContentBytes = n
+	}
+}
+
+// WithClassAlpha stores the colors of the points as RGBA, with the alpha of each point looked up by its
+// classification in the given map, e.g. to make the vegetation semi-transparent so that the structures below it
+// remain visible. The classifications missing from the map are fully opaque. The points are de-emphasized rather
+// than dropped. A nil or empty map (default) stores fully opaque RGB colors.
+func WithClassAlpha(alpha map[uint8]uint8) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.classAlpha = alpha
+	}
+}
+
+// WithOutputRegion exports only the part of the tree covering the given region, e.g. to serve a region of interest
+// to a client: the tiles whose bounding volume does not intersect the region are not written, together with their
+// descendants, while the tiles intersecting it are written with all their points, so that the region is displayed
+// with the same levels of detail and geometric errors as in the tileset of the whole cloud. Unlike the filters applied
+// while loading the points, the tree is built from all the points. The tiling fails with an error if the region
+// is invalid or it does not intersect the point cloud. Defaults to nil, exporting the whole tree.
+func WithOutputRegion(region Region) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.outputRegion = &region
+	}
+}
+
+// WithFullDetailDistance scales the geometric errors of the exported tileset so that the clients display the points
+// at full resolution when the camera is within the given distance, in meters, from them. The scale is back-solved
+// from the screen space error formula of Cesium, geometricError * screenWidth / (distance * 2 * tan(fov / 2)), making
+// the deepest tiles with children refine at the given distance with the Cesium defaults: a maximum screen space error
+// of 16 pixels and a 60 degrees field of view, on a full HD screen. A non positive value (default) exports the
+// geometric errors computed by the tiler.
+func WithFullDetailDistance(meters float64) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.fullDetailDistance = meters
+	}
+}
+
+// WithTilesetProperties true stores the range of the intensity, the classification and the ellipsoidal height of the
+// points in the properties object of the root tileset.json, so that the styling expressions of the clients can refer
+// to them. Computing the ranges requires an additional pass over all the points before exporting the tileset.
+func WithTilesetProperties(enabled bool) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.tilesetProperties = enabled
+	}
+}
+
+// WithContentExtension sets the extension of the content files of the tiles, e.g. ".bin" for a server or a CDN
+// serving the contents under a custom extension, replacing the .pnts extension both in the names of the files and in
+// the content URIs of the tileset.json files. The leading dot is optional and the contents are still pnts. An empty
+// extension keeps the default .pnts. The tiling fails with an error if the extension contains path separators or
+// is .json, which is reserved for the tileset files.
+func WithContentExtension(ext string) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.contentExtension = ext
+	}
+}
+
+// WithBufferAlignment pads the tables of the content.pnts files so that each json header and binary body starts at
+// an offset multiple of the given number of bytes, e.g. 8 as required by some 3D Tiles loaders. Values that are not
+// multiple of 4 are rounded up to the next multiple of 4. The tiles are written as pnts, there is no glb content to
+// align. A non positive value (default) aligns the json headers to 4 bytes only.
+func WithBufferAlignment(n int) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.bufferAlignment = n
+	}
+}
+
+// WithPointRange reads only the count points starting at the given index of each input file, e.g. to split the
+// processing of a single large file among several machines, each exporting its own range of points. The range is
+// clipped to the points stored in the file. A negative count (default) reads all the points from start onwards.
+func WithPointRange(start, count int) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.pointRange = [2]int{start, count}
+	}
+}
+
+// WithFlipAxis negates the X, Y and Z coordinates of the input points, as flagged, while they are loaded and before
+// any other transformation, mirroring them around the origin of the input CRS. It fixes the handedness of data
+// exported with flipped axes, e.g. by CAD systems, without editing the source files. All flags default to false.
+func WithFlipAxis(flipX, flipY, flipZ bool) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.flipAxis = [3]bool{flipX, flipY, flipZ}
+	}
+}
+
+// WithTemporalTiling splits the points by their GPS time in consecutive windows of the given length, exporting the
+// points of each window containing any as a separate tileset, for the temporal playback of mobile mapping data. The
+// tilesets are stored in subfolders of the output folder named after the start of their window, in GPS seconds, and
+// listed in the temporal.json file (see TemporalIndex). The asset.extras.timeWindow property of each tileset stores
+// the start and the end of its window. The GPS time is read as stored in the files, which must all use the same time
+// format. The input files are read once more per window. Ignored by StreamTiles and AppendFiles. A non positive
+// window (default) exports a single tileset.
+func WithTemporalTiling(window time.Duration) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.temporalWindow = window
+	}
+}
+
+// WithContentBoundingVolumes true declares in the content object of each tile the region tightly enclosing the points
+// of the tile, in addition to the bounding volume of the tile, which also encloses the points of its descendants.
+// The clients use it to cull the tile content more effectively. Computing the regions requires reprojecting all the
+// points once more while exporting the tileset. Defaults to false.
+func WithContentBoundingVolumes(enabled bool) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.contentBoundingVolumes = enabled
+	}
+}
+
+// WithRootPointTarget sets the number of points, approximately, retained by the root tile, which is the first one
+// loaded by the clients, to make the initial view denser or lighter independently of the grid size. The grid cells of
+// the root are scaled until its points occupy about n cells, at the cost of a few additional passes over the points,
+// while the other tiles keep the grid size halved at each level. The geometric error of the root reflects the spacing
+// of its points and caps the one of its descendants: a larger target lowers it, delaying the refinement to the
+// children, a smaller one raises it. A non positive value (default) derives the root points from the grid size.
+func WithRootPointTarget(n int) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.rootPointTarget = n
+	}
+}
+
+// WithStrictCrs makes the tiler fail with an error, instead of emitting an EventCrsWarning event, when the X, Y extent
+// of the points declared in the LAS headers does not match the units of the input EPSG code, e.g. coordinates in the
+// millions for a geographic CRS in degrees, which would place the tileset nowhere near the actual location.
+// Defaults to false.
+func WithStrictCrs(strict bool) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.strictCrs = strict
+	}
+}
+
+// WithSyntheticPointPolicy sets how the points marked with the LAS synthetic flag, e.g. modeled points, are tiled:
+// together with the other points, discarded or in a distinct tileset stored in the synthetic subfolder of the output
+// folder. The flag is independent from the classification of the points. With SyntheticSeparate the input files are
+// read once per tileset, and no synthetic tileset is written if no point is synthetic.
+// Defaults to SyntheticInclude.
+func WithSyntheticPointPolicy(policy SyntheticPointPolicy) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.syntheticPolicy = policy
+	}
+}
+
+// WithPointSpacing estimates the average distance between the points and their nearest neighbor at full resolution,
+// storing it in meters as averagePointSpacing in the asset extras of the root tileset.json and reporting it in the
+// TilesetResult. The estimate is computed after building the tree, on a sample of its subtrees.
+// Defaults to false.
+func WithPointSpacing(enabled bool) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.pointSpacing = enabled
+	}
+}
+
+// WithContentPostProcessor sets a function transforming the bytes of each tile content right before it is written,
+// or streamed by StreamTiles, e.g. to encrypt, sign, watermark or compress it with a custom algorithm. The content
+// is stored as returned by the function, while an error aborts the export. The tileset.json files and the other
+// outputs are not post processed, and the manifest set with WithManifest describes the post processed contents.
+// The function is invoked concurrently by the export workers, hence it must be safe for concurrent use.
+func WithContentPostProcessor(process ContentPostProcessor) tilerOptionsFn {
+	return func(opt *TilerOptions) {
+		opt.contentPostProcessor = process
+	}
+}
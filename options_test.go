@@ -1,7 +1,10 @@
 package tiler
 
 import (
+	"bytes"
+	"reflect"
 	"testing"
+	"time"
 )
 
 func TestOptions(t *testing.T) {
@@ -14,6 +17,42 @@ func TestOptions(t *testing.T) {
 		WithMaxDepth(12),
 		WithMinPointsPerTile(10),
 		WithWorkerNumber(3),
+		WithPackaging(Package3tz),
+		WithDropWithheld(true),
+		WithDropOverlap(true),
+		WithElevationClampMode(-10, 100, ClampToRange),
+		WithStatisticalOutlierRemoval(8, 2.5),
+		WithFilePattern("tile_*.las"),
+		WithColorGamma(2.2),
+		WithReadBufferSize(8*1024*1024),
+		WithManifest("manifest.json"),
+		WithElevationFromRasterPolicy("dem.tif", RasterOutsideKeepZ),
+		WithClassificationRemap(map[uint8]uint8{40: 2}),
+		WithPrettyTileset(false),
+		WithFeaturePreservingThinning(true),
+		WithColorChannelMapping(ColorSourceNIR, ColorSourceRed, ColorSourceIntensity),
+		WithHeightExaggeration(2.5),
+		WithSparseNodePolicy(SparseNodeOmit),
+		WithExtraDimensionFilter("confidence", 0.5, 1),
+		WithTerrainOutput(true),
+		WithResultCallback(func(result TilesetResult) {}),
+		WithHeightAboveGround(0.5, 3),
+		WithQuantizedPositions(true),
+		WithContinueOnError(true),
+		WithVerticalEpsg(5773),
+		WithSourceFileAttribute(true),
+		WithSubdivision(SubdivisionQuadtree),
+		WithDebugFootprints("footprints.geojson"),
+		WithColorByClassification(true),
+		WithClassificationPalette(map[uint8][3]uint8{6: {255, 0, 0}}),
+		WithPhaseCallback(func(phase Phase, elapsed time.Duration) {}),
+		WithExternalClassification("labels.bin"),
+		WithCoordinateRounding(0.01),
+		WithMaxTiles(100),
+		WithInputUnits(UnitUSSurveyFoot, UnitMeter),
+		WithGeoidGrid("geoid.gtx"),
+		WithSourceQuantization(true),
+		WithSpatialIndexCache(true),
 	)
 
 	if opts.callback == nil {
@@ -28,8 +67,8 @@ func TestOptions(t *testing.T) {
 	if opts.geoidElevation != true {
 		t.Errorf("expected geoidElevation to be %v got %v", true, opts.geoidElevation)
 	}
-	if opts.gridSize != 11.1 {
-		t.Errorf("expected gridSize to be %v got %v", 11.1, opts.gridSize)
+	if expected := [3]float64{11.1, 11.1, 11.1}; opts.gridSize != expected {
+		t.Errorf("expected gridSize to be %v got %v", expected, opts.gridSize)
 	}
 	if opts.maxDepth != 12 {
 		t.Errorf("expected maxDepth to be %v got %v", 12, opts.maxDepth)
@@ -37,7 +76,333 @@ func TestOptions(t *testing.T) {
 	if opts.minPointsPerTile != 10 {
 		t.Errorf("expected minPointsPerTile to be %v got %v", 10, opts.minPointsPerTile)
 	}
-	if opts.numWorkers != 3 {
-		t.Errorf("expected numWorkers to be %v got %v", 3, opts.numWorkers)
+	if opts.readWorkers != 3 || opts.exportWorkers != 3 {
+		t.Errorf("expected readWorkers and exportWorkers to be %v got %v and %v", 3, opts.readWorkers, opts.exportWorkers)
+	}
+	if opts.packaging != Package3tz {
+		t.Errorf("expected packaging to be %v got %v", Package3tz, opts.packaging)
+	}
+	if opts.dropWithheld != true {
+		t.Errorf("expected dropWithheld to be %v got %v", true, opts.dropWithheld)
+	}
+	if opts.dropOverlap != true {
+		t.Errorf("expected dropOverlap to be %v got %v", true, opts.dropOverlap)
+	}
+	if expected := (elevationClamp{min: -10, max: 100, mode: ClampToRange}); opts.elevationClamp == nil || *opts.elevationClamp != expected {
+		t.Errorf("expected elevationClamp to be %v got %v", expected, opts.elevationClamp)
+	}
+	if opts.sorNeighbors != 8 {
+		t.Errorf("expected sorNeighbors to be %v got %v", 8, opts.sorNeighbors)
+	}
+	if opts.sorStdDevMul != 2.5 {
+		t.Errorf("expected sorStdDevMul to be %v got %v", 2.5, opts.sorStdDevMul)
+	}
+	if opts.filePattern != "tile_*.las" {
+		t.Errorf("expected filePattern to be %v got %v", "tile_*.las", opts.filePattern)
+	}
+	if opts.colorGamma != 2.2 {
+		t.Errorf("expected colorGamma to be %v got %v", 2.2, opts.colorGamma)
+	}
+	if opts.readBufferSize != 8*1024*1024 {
+		t.Errorf("expected readBufferSize to be %v got %v", 8*1024*1024, opts.readBufferSize)
+	}
+	if opts.manifestPath != "manifest.json" {
+		t.Errorf("expected manifestPath to be %v got %v", "manifest.json", opts.manifestPath)
+	}
+	if expected := (elevationRaster{path: "dem.tif", policy: RasterOutsideKeepZ}); opts.elevationRaster == nil || *opts.elevationRaster != expected {
+		t.Errorf("expected elevationRaster to be %v got %v", expected, opts.elevationRaster)
+	}
+	if expected := map[uint8]uint8{40: 2}; !reflect.DeepEqual(opts.classRemap, expected) {
+		t.Errorf("expected classRemap to be %v got %v", expected, opts.classRemap)
+	}
+	if opts.prettyTileset != false {
+		t.Errorf("expected prettyTileset to be %v got %v", false, opts.prettyTileset)
+	}
+	if opts.featurePreserving != true {
+		t.Errorf("expected featurePreserving to be %v got %v", true, opts.featurePreserving)
+	}
+	if expected := [3]ColorSource{ColorSourceNIR, ColorSourceRed, ColorSourceIntensity}; opts.colorMapping != expected {
+		t.Errorf("expected colorMapping to be %v got %v", expected, opts.colorMapping)
+	}
+	if opts.heightExaggeration != 2.5 {
+		t.Errorf("expected heightExaggeration to be %v got %v", 2.5, opts.heightExaggeration)
+	}
+	if opts.sparseNodePolicy != SparseNodeOmit {
+		t.Errorf("expected sparseNodePolicy to be %v got %v", SparseNodeOmit, opts.sparseNodePolicy)
+	}
+	if opts := NewTilerOptions(WithWorkerNumber(3), WithReadWorkers(2), WithExportWorkers(8)); opts.readWorkers != 2 || opts.exportWorkers != 8 {
+		t.Errorf("expected readWorkers %v and exportWorkers %v got %v and %v", 2, 8, opts.readWorkers, opts.exportWorkers)
+	}
+	if expected := (extraDimensionFilter{name: "confidence", min: 0.5, max: 1}); opts.extraFilter == nil || *opts.extraFilter != expected {
+		t.Errorf("expected extraFilter to be %v got %v", expected, opts.extraFilter)
+	}
+	if opts.terrainOutput != true {
+		t.Errorf("expected terrainOutput to be %v got %v", true, opts.terrainOutput)
+	}
+	if opts.resultCallback == nil {
+		t.Errorf("unexpected nil result callback")
+	}
+	if opts.heightAboveGround == nil || *opts.heightAboveGround != (heightBand{min: 0.5, max: 3}) {
+		t.Errorf("expected heightAboveGround to be %v got %v", heightBand{min: 0.5, max: 3}, opts.heightAboveGround)
+	}
+	if opts.quantizedPositions != true {
+		t.Errorf("expected quantizedPositions to be %v got %v", true, opts.quantizedPositions)
+	}
+	if opts.continueOnError != true {
+		t.Errorf("expected continueOnError to be %v got %v", true, opts.continueOnError)
+	}
+	if opts.verticalEpsg != 5773 {
+		t.Errorf("expected verticalEpsg to be %v got %v", 5773, opts.verticalEpsg)
+	}
+	if opts.sourceFileAttribute != true {
+		t.Errorf("expected sourceFileAttribute to be %v got %v", true, opts.sourceFileAttribute)
+	}
+	if opts.subdivision != SubdivisionQuadtree {
+		t.Errorf("expected subdivision to be %v got %v", SubdivisionQuadtree, opts.subdivision)
+	}
+	if opts.debugFootprints != "footprints.geojson" {
+		t.Errorf("expected debugFootprints to be %v got %v", "footprints.geojson", opts.debugFootprints)
+	}
+	if opts.colorByClass != true {
+		t.Errorf("expected colorByClass to be %v got %v", true, opts.colorByClass)
+	}
+	if expected := map[uint8][3]uint8{6: {255, 0, 0}}; !reflect.DeepEqual(opts.classPalette, expected) {
+		t.Errorf("expected classPalette to be %v got %v", expected, opts.classPalette)
+	}
+	if opts.phaseCallback == nil {
+		t.Errorf("expected phaseCallback to be set")
+	}
+	if opts.externalClassification != "labels.bin" {
+		t.Errorf("expected externalClassification to be %v got %v", "labels.bin", opts.externalClassification)
+	}
+	if opts.coordinateRounding != 0.01 {
+		t.Errorf("expected coordinateRounding to be %v got %v", 0.01, opts.coordinateRounding)
+	}
+	if opts.maxTiles != 100 {
+		t.Errorf("expected maxTiles to be %v got %v", 100, opts.maxTiles)
+	}
+	if expected := [2]LengthUnit{UnitUSSurveyFoot, UnitMeter}; opts.inputUnits != expected {
+		t.Errorf("expected inputUnits to be %v got %v", expected, opts.inputUnits)
+	}
+	if opts.geoidGrid != "geoid.gtx" {
+		t.Errorf("expected geoidGrid to be %v got %v", "geoid.gtx", opts.geoidGrid)
+	}
+	if opts.sourceQuantization != true {
+		t.Errorf("expected sourceQuantization to be %v got %v", true, opts.sourceQuantization)
+	}
+	if opts.spatialIndexCache != true {
+		t.Errorf("expected spatialIndexCache to be %v got %v", true, opts.spatialIndexCache)
+	}
+	if opts := NewTilerOptions(WithGridSizeXYZ(1, 2, 3)); opts.gridSize != [3]float64{1, 2, 3} {
+		t.Errorf("expected gridSize to be %v got %v", [3]float64{1, 2, 3}, opts.gridSize)
+	}
+	if opts := NewTilerOptions(WithClassificationColorFile("palette.csv")); opts.classColorFile != "palette.csv" || !opts.colorByClass {
+		t.Errorf("expected classColorFile palette.csv and colorByClass true got %q %v", opts.classColorFile, opts.colorByClass)
+	}
+	if opts := NewTilerOptions(WithPointSourceFilter([]uint16{3, 7})); !reflect.DeepEqual(opts.pointSourceFilter, []uint16{3, 7}) {
+		t.Errorf("expected pointSourceFilter [3 7] got %v", opts.pointSourceFilter)
+	}
+	if opts := NewTilerOptions(WithPointSourceIdAttribute(true)); !opts.pointSourceIdAttribute {
+		t.Errorf("expected pointSourceIdAttribute to be true")
+	}
+	if opts := NewTilerOptions(WithEqualAreaThinning(true)); !opts.equalAreaThinning {
+		t.Errorf("expected equalAreaThinning to be true")
+	}
+	if opts := NewTilerOptions(WithFlatten(2)); opts.flattenLevels != 2 {
+		t.Errorf("expected flattenLevels 2 got %d", opts.flattenLevels)
+	}
+	if opts := NewTilerOptions(WithTilesetProperties(true)); !opts.tilesetProperties {
+		t.Errorf("expected tilesetProperties to be true")
+	}
+	if opts := NewTilerOptions(WithBufferAlignment(8)); opts.bufferAlignment != 8 {
+		t.Errorf("expected bufferAlignment 8 got %d", opts.bufferAlignment)
+	}
+	if opts := NewTilerOptions(); opts.pointRange != [2]int{0, -1} {
+		t.Errorf("expected the full point range by default got %v", opts.pointRange)
+	}
+	if opts := NewTilerOptions(WithPointRange(10, 20)); opts.pointRange != [2]int{10, 20} {
+		t.Errorf("expected pointRange [10 20] got %v", opts.pointRange)
+	}
+	if opts := NewTilerOptions(WithColorFromRaster("ortho.tif")); opts.colorRaster != "ortho.tif" {
+		t.Errorf("expected colorRaster ortho.tif got %s", opts.colorRaster)
+	}
+	if opts := NewTilerOptions(WithFlipAxis(false, true, false)); opts.flipAxis != [3]bool{false, true, false} {
+		t.Errorf("expected flipAxis [false true false] got %v", opts.flipAxis)
+	}
+	if opts := NewTilerOptions(WithTemporalTiling(time.Minute)); opts.temporalWindow != time.Minute {
+		t.Errorf("expected temporalWindow 1m got %v", opts.temporalWindow)
+	}
+	if opts := NewTilerOptions(WithContentBoundingVolumes(true)); !opts.contentBoundingVolumes {
+		t.Errorf("expected contentBoundingVolumes to be true")
+	}
+	if opts := NewTilerOptions(); opts.colorMatrix != identityColorMatrix {
+		t.Errorf("expected the identity color matrix by default got %v", opts.colorMatrix)
+	}
+	if opts := NewTilerOptions(WithColorMatrix([9]float64{0, 0, 1, 0, 1, 0, 1, 0, 0})); opts.colorMatrix != [9]float64{0, 0, 1, 0, 1, 0, 1, 0, 0} {
+		t.Errorf("expected the given color matrix got %v", opts.colorMatrix)
+	}
+	if opts := NewTilerOptions(WithRootPointTarget(50000)); opts.rootPointTarget != 50000 {
+		t.Errorf("expected rootPointTarget 50000 got %d", opts.rootPointTarget)
+	}
+	if opts := NewTilerOptions(); opts.defaultColor != nil {
+		t.Errorf("expected no default color by default got %v", opts.defaultColor)
+	}
+	if opts := NewTilerOptions(WithDefaultColor(1, 2, 3)); opts.defaultColor == nil || *opts.defaultColor != [3]uint8{1, 2, 3} {
+		t.Errorf("expected the given default color got %v", opts.defaultColor)
+	}
+	if opts := NewTilerOptions(); opts.colorBits != 24 {
+		t.Errorf("expected 24 color bits by default got %d", opts.colorBits)
+	}
+	if opts := NewTilerOptions(WithColorBits(16)); opts.colorBits != 16 {
+		t.Errorf("expected 16 color bits got %d", opts.colorBits)
+	}
+	if opts := NewTilerOptions(); opts.contentPostProcessor != nil {
+		t.Errorf("expected no content post processor by default")
+	}
+	if opts := NewTilerOptions(WithContentPostProcessor(func(path string, data []byte) ([]byte, error) { return data, nil })); opts.contentPostProcessor == nil {
+		t.Errorf("expected the content post processor to be set")
+	}
+	if opts := NewTilerOptions(); opts.loadAttributes != AttrAll {
+		t.Errorf("expected all the attributes to be loaded by default got %b", opts.loadAttributes)
+	}
+	if opts := NewTilerOptions(WithLoadAttributes(AttrColor | AttrIntensity)); opts.loadAttributes != AttrColor|AttrIntensity {
+		t.Errorf("expected the given attributes got %b", opts.loadAttributes)
+	}
+	if opts := NewTilerOptions(); opts.crsGrouping {
+		t.Errorf("expected the files not to be grouped by CRS by default")
+	}
+	if opts := NewTilerOptions(WithCrsGrouping(true)); !opts.crsGrouping {
+		t.Errorf("expected the files to be grouped by CRS")
+	}
+	if opts := NewTilerOptions(); opts.cancelCheckInterval != 1024 {
+		t.Errorf("expected the context to be checked every 1024 points by default got %d", opts.cancelCheckInterval)
+	}
+	if opts := NewTilerOptions(WithCancellationCheckInterval(10)); opts.cancelCheckInterval != 10 {
+		t.Errorf("expected the context to be checked every 10 points got %d", opts.cancelCheckInterval)
+	}
+	if opts := NewTilerOptions(); opts.fullDetailDistance != 0 {
+		t.Errorf("expected no full detail distance by default got %f", opts.fullDetailDistance)
+	}
+	if opts := NewTilerOptions(WithFullDetailDistance(250)); opts.fullDetailDistance != 250 {
+		t.Errorf("expected a full detail distance of 250 got %f", opts.fullDetailDistance)
+	}
+	if opts := NewTilerOptions(); opts.smoothingRadius != 0 || opts.smoothingIterations != 0 {
+		t.Errorf("expected no elevation smoothing by default got radius %f and %d iterations", opts.smoothingRadius, opts.smoothingIterations)
+	}
+	if opts := NewTilerOptions(WithElevationSmoothing(2.5, 3)); opts.smoothingRadius != 2.5 || opts.smoothingIterations != 3 {
+		t.Errorf("expected radius 2.5 and 3 iterations got %f and %d", opts.smoothingRadius, opts.smoothingIterations)
+	}
+	if opts := NewTilerOptions(); opts.outputRegion != nil {
+		t.Errorf("expected no output region by default got %v", opts.outputRegion)
+	}
+	if opts := NewTilerOptions(WithOutputRegion(Region{West: 10, South: 45, East: 11, North: 46})); opts.outputRegion == nil || *opts.outputRegion != (Region{West: 10, South: 45, East: 11, North: 46}) {
+		t.Errorf("expected the output region to be set got %v", opts.outputRegion)
+	}
+	if opts := NewTilerOptions(); opts.minContentBytes != 0 {
+		t.Errorf("expected no minimum content size by default got %d", opts.minContentBytes)
+	}
+	if opts := NewTilerOptions(WithMinContentBytes(4096)); opts.minContentBytes != 4096 {
+		t.Errorf("expected a minimum content size of 4096 bytes got %d", opts.minContentBytes)
+	}
+	if opts := NewTilerOptions(); opts.classAlpha != nil {
+		t.Errorf("expected no alpha by classification by default got %v", opts.classAlpha)
+	}
+	if opts := NewTilerOptions(WithClassAlpha(map[uint8]uint8{5: 64})); len(opts.classAlpha) != 1 || opts.classAlpha[5] != 64 {
+		t.Errorf("expected alpha 64 for class 5 got %v", opts.classAlpha)
+	}
+	if opts := NewTilerOptions(); opts.recursive {
+		t.Errorf("expected the folder mode not to be recursive by default")
+	}
+	if opts := NewTilerOptions(WithRecursive(true)); !opts.recursive {
+		t.Errorf("expected the folder mode to be recursive")
+	}
+	if opts := NewTilerOptions(); opts.reprojectionWorkers != 0 {
+		t.Errorf("expected the reprojection to use the read workers by default got %d", opts.reprojectionWorkers)
+	}
+	if opts := NewTilerOptions(WithParallelReprojection(6)); opts.reprojectionWorkers != 6 {
+		t.Errorf("expected 6 reprojection workers got %d", opts.reprojectionWorkers)
+	}
+	if opts := NewTilerOptions(WithOverviewTileset(1000)); opts.overviewPoints != 1000 || opts.overview {
+		t.Errorf("expected overviewPoints to be %d got %d", 1000, opts.overviewPoints)
+	}
+	if opts := NewTilerOptions(WithEpsgRegistry("epsg")); opts.epsgRegistry != "epsg" {
+		t.Errorf("expected epsgRegistry to be %s got %s", "epsg", opts.epsgRegistry)
+	}
+	rules := []HeightRule{{Min: 0, Max: 1, Classification: 3}}
+	if opts := NewTilerOptions(WithHeightRules(rules)); !reflect.DeepEqual(opts.heightRules, rules) {
+		t.Errorf("expected heightRules to be %v got %v", rules, opts.heightRules)
+	}
+	if opts := NewTilerOptions(WithContentExtension(".bin")); opts.contentExtension != ".bin" {
+		t.Errorf("expected contentExtension to be %s got %s", ".bin", opts.contentExtension)
+	}
+	collector := NewErrorCollector()
+	if opts := NewTilerOptions(WithErrorCollector(collector)); opts.errorCollector != collector {
+		t.Errorf("expected errorCollector to be %v got %v", collector, opts.errorCollector)
+	}
+	if opts := NewTilerOptions(WithPreviewImage("preview.png", 256, 128)); *opts.previewImage != (previewImage{"preview.png", 256, 128}) {
+		t.Errorf("expected previewImage to be %v got %v", previewImage{"preview.png", 256, 128}, *opts.previewImage)
+	}
+	if opts := NewTilerOptions(); opts.geometricErrorMode != GeoErrSpacing {
+		t.Errorf("expected geometricErrorMode to be %v got %v", GeoErrSpacing, opts.geometricErrorMode)
+	}
+	if opts := NewTilerOptions(WithGeometricErrorMode(GeoErrLinear)); opts.geometricErrorMode != GeoErrLinear {
+		t.Errorf("expected geometricErrorMode to be %v got %v", GeoErrLinear, opts.geometricErrorMode)
+	}
+	if opts := NewTilerOptions(WithGeometricErrorMode(GeoErrGeometric)); opts.geometricErrorMode != GeoErrGeometric {
+		t.Errorf("expected geometricErrorMode to be %v got %v", GeoErrGeometric, opts.geometricErrorMode)
+	}
+	if opts := NewTilerOptions(); opts.sparseNodePolicy != SparseNodeConsolidate {
+		t.Errorf("expected sparseNodePolicy to be %v got %v", SparseNodeConsolidate, opts.sparseNodePolicy)
+	}
+	if opts := NewTilerOptions(); opts.perPointBatchId {
+		t.Errorf("expected perPointBatchId to be false by default")
+	}
+	if opts := NewTilerOptions(WithPerPointBatchId(true)); !opts.perPointBatchId {
+		t.Errorf("expected perPointBatchId to be true")
+	}
+	if opts := NewTilerOptions(); opts.progressWriter != nil {
+		t.Errorf("expected progressWriter to be nil by default")
+	}
+	if opts := NewTilerOptions(WithProgressWriter(&bytes.Buffer{})); opts.progressWriter == nil || opts.progressWriter.tty {
+		t.Errorf("expected a progressWriter writing lines")
+	}
+	if opts := NewTilerOptions(WithProgressWriter(&bytes.Buffer{}), WithProgressWriter(nil)); opts.progressWriter != nil {
+		t.Errorf("expected a nil writer to disable the progressWriter")
+	}
+	if opts := NewTilerOptions(WithDatumGridPath("grids")); opts.datumGridPath != "grids" {
+		t.Errorf("expected datumGridPath to be %s got %s", "grids", opts.datumGridPath)
+	}
+	if opts := NewTilerOptions(); opts.openFileLimiter != nil {
+		t.Errorf("expected no limit of open files by default")
+	}
+	if opts := NewTilerOptions(WithMaxOpenFiles(4)); opts.openFileLimiter == nil {
+		t.Errorf("expected a limit of open files")
+	}
+	if opts := NewTilerOptions(WithMaxOpenFiles(4), WithMaxOpenFiles(0)); opts.openFileLimiter != nil {
+		t.Errorf("expected zero to disable the limit of open files")
+	}
+	if opts := NewTilerOptions(); opts.strictCrs {
+		t.Errorf("expected the CRS check not to be strict by default")
+	}
+	if opts := NewTilerOptions(WithStrictCrs(true)); !opts.strictCrs {
+		t.Errorf("expected the CRS check to be strict")
+	}
+	if opts := NewTilerOptions(); opts.syntheticPolicy != SyntheticInclude {
+		t.Errorf("expected the synthetic points to be included by default got %v", opts.syntheticPolicy)
+	}
+	if opts := NewTilerOptions(WithSyntheticPointPolicy(SyntheticSeparate)); opts.syntheticPolicy != SyntheticSeparate {
+		t.Errorf("expected policy %v got %v", SyntheticSeparate, opts.syntheticPolicy)
+	}
+	if opts := NewTilerOptions(); opts.pointSpacing {
+		t.Errorf("expected the point spacing not to be estimated by default")
+	}
+	if opts := NewTilerOptions(WithPointSpacing(true)); !opts.pointSpacing {
+		t.Errorf("expected the point spacing to be estimated")
+	}
+	if opts := NewTilerOptions(WithElevationFromRaster("dem.tif")); opts.elevationRaster.policy != RasterOutsideDrop {
+		t.Errorf("expected elevationRaster policy to be %v got %v", RasterOutsideDrop, opts.elevationRaster.policy)
+	}
+	if opts := NewTilerOptions(WithElevationClamp(1, 2)); opts.elevationClamp.mode != ClampDrop {
+		t.Errorf("expected elevationClamp mode to be %v got %v", ClampDrop, opts.elevationClamp.mode)
 	}
 }
@@ -0,0 +1,27 @@
+package tiler
+
+import (
+	"context"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/las"
+)
+
+// Point is a point to tile with ProcessPoints. The colors, intensity and classification are 8 bit values.
+type Point = geom.Point64
+
+// pointsInputName is the name of the input reported in the events and results of ProcessPoints
+const pointsInputName = "points"
+
+// ProcessPoints converts the given in-memory points, expressed in the CRS with the given EPSG code, into a tileset
+// stored in the output folder. The points go through the same pipeline as the ones read by ProcessFiles, as if they
+// were read from a single LAS file with 8 bit colors, which makes it convenient for tests and small programmatic jobs.
+// The options decoding the LAS records, such as WithEightBitColors, WithColorChannelMapping and WithPointRange, do
+// not apply. The events report the input as "points".
+func (t *GoCesiumTiler) ProcessPoints(pts []Point, outputFolder string, epsgCode int, opts *TilerOptions, ctx context.Context) error {
+	pointsTiler := *t
+	pointsTiler.lasReaderProvider = func(inputLasFiles []string, epsgCode int, opts *TilerOptions) (las.LasReader, error) {
+		return las.NewSliceLasReader(pts, epsgCode), nil
+	}
+	return pointsTiler.processFiles([]string{pointsInputName}, outputFolder, epsgCode, opts, &runResources{}, ctx)
+}
@@ -0,0 +1,40 @@
+package tiler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/writer"
+)
+
+func TestTilerProcessPoints(t *testing.T) {
+	tiler, err := NewGoCesiumTiler()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pts := []Point{}
+	for i := 0; i < 30; i++ {
+		for j := 0; j < 30; j++ {
+			pts = append(pts, Point{X: 500000 + float64(i), Y: 5000000 + float64(j), Z: 100 + float64(i%3), R: 200, G: 100, B: 50})
+		}
+	}
+	inputs := map[string]bool{}
+	callback := func(event TilerEvent, inputDesc string, elapsed int64, msg string) {
+		inputs[inputDesc] = true
+	}
+	out := t.TempDir()
+	opts := NewTilerOptions(WithGridSize(2), WithMinPointsPerTile(50), WithCallback(callback))
+	if err := tiler.ProcessPoints(pts, out, 32633, opts, context.TODO()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	n, err := writer.ValidateTileset(out)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != len(pts) {
+		t.Errorf("expected %d points in the tileset got %d", len(pts), n)
+	}
+	if len(inputs) != 1 || !inputs[pointsInputName] {
+		t.Errorf("expected the events to report the input as %s got %v", pointsInputName, inputs)
+	}
+}
@@ -0,0 +1,90 @@
+package tiler
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// progressBarWidth is the number of characters of the bar drawn on terminals
+const progressBarWidth = 30
+
+// progressPrinter writes the events of the tiler as human readable lines, see WithProgressWriter
+type progressPrinter struct {
+	w io.Writer
+	// tty is true if the writer is a terminal, where the export progress is drawn as a bar redrawn in place
+	tty bool
+	// barLen is the length of the bar currently drawn on the last line, 0 if none
+	barLen int
+	sync.Mutex
+}
+
+func newProgressPrinter(w io.Writer) *progressPrinter {
+	return &progressPrinter{w: w, tty: isTerminal(w)}
+}
+
+// isTerminal returns whether the writer is a file attached to a terminal
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// event writes a line describing the event, replacing the progress bar if one is drawn. The export progress events
+// are written by progress instead. Does nothing on a nil printer.
+func (p *progressPrinter) event(e TilerEvent, inputDesc string, elapsed int64, msg string) {
+	if p == nil || e == EventExportProgress {
+		return
+	}
+	p.Lock()
+	defer p.Unlock()
+	p.clearBar()
+	fmt.Fprintf(p.w, "[%s] [%s] %s\n", time.Duration(elapsed)*time.Millisecond, inputDesc, msg)
+}
+
+// progress reports the number of tiles exported so far, redrawing the progress bar on terminals or writing a line
+// otherwise. Does nothing on a nil printer.
+func (p *progressPrinter) progress(inputDesc string, elapsed int64, written, total int) {
+	if p == nil {
+		return
+	}
+	p.Lock()
+	defer p.Unlock()
+	if !p.tty {
+		fmt.Fprintf(p.w, "[%s] [%s] exported %d/%d tiles\n", time.Duration(elapsed)*time.Millisecond, inputDesc, written, total)
+		return
+	}
+	ratio := 1.0
+	if total > 0 {
+		ratio = min(float64(written)/float64(total), 1)
+	}
+	filled := int(ratio * progressBarWidth)
+	bar := fmt.Sprintf("[%s] [%s%s] %3.0f%% %d/%d tiles", inputDesc, strings.Repeat("#", filled),
+		strings.Repeat(" ", progressBarWidth-filled), ratio*100, written, total)
+	// pads the bar to overwrite a longer one
+	fmt.Fprintf(p.w, "\r%s%s", bar, strings.Repeat(" ", max(p.barLen-len(bar), 0)))
+	p.barLen = len(bar)
+	if written >= total {
+		// keeps the completed bar on its own line
+		fmt.Fprint(p.w, "\n")
+		p.barLen = 0
+	}
+}
+
+// clearBar blanks the progress bar drawn on the last line, if any, moving the cursor to the start of the line
+func (p *progressPrinter) clearBar() {
+	if p.barLen == 0 {
+		return
+	}
+	fmt.Fprintf(p.w, "\r%s\r", strings.Repeat(" ", p.barLen))
+	p.barLen = 0
+}
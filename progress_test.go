@@ -0,0 +1,119 @@
+package tiler
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/conv/coor"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/las"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/mutator"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/tree"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/writer"
+)
+
+func TestProgressPrinterLines(t *testing.T) {
+	buf := &bytes.Buffer{}
+	p := newProgressPrinter(buf)
+	if p.tty {
+		t.Fatalf("expected a buffer not to be a terminal")
+	}
+	p.event(EventExportStarted, "a.las", 1500, "export started")
+	p.event(EventExportProgress, "a.las", 1600, "exported 1/2 tiles")
+	p.progress("a.las", 1600, 1, 2)
+	expected := "[1.5s] [a.las] export started\n[1.6s] [a.las] exported 1/2 tiles\n"
+	if actual := buf.String(); actual != expected {
+		t.Errorf("expected output %q got %q", expected, actual)
+	}
+
+	var nilPrinter *progressPrinter
+	nilPrinter.event(EventExportStarted, "a.las", 0, "export started")
+	nilPrinter.progress("a.las", 0, 1, 2)
+}
+
+func TestProgressPrinterBar(t *testing.T) {
+	buf := &bytes.Buffer{}
+	p := &progressPrinter{w: buf, tty: true}
+	p.progress("a.las", 0, 1, 4)
+	bar := "[a.las] [#######                       ]  25% 1/4 tiles"
+	if actual := buf.String(); actual != "\r"+bar {
+		t.Errorf("expected bar %q got %q", "\r"+bar, actual)
+	}
+	buf.Reset()
+	p.event(EventCrsWarning, "a.las", 0, "crs check warning")
+	expected := "\r" + strings.Repeat(" ", len(bar)) + "\r[0s] [a.las] crs check warning\n"
+	if actual := buf.String(); actual != expected {
+		t.Errorf("expected the bar to be cleared before the line, got %q", actual)
+	}
+	buf.Reset()
+	p.progress("a.las", 0, 4, 4)
+	if actual := buf.String(); !strings.HasSuffix(actual, "100% 4/4 tiles\n") {
+		t.Errorf("expected the completed bar on its own line, got %q", actual)
+	}
+	buf.Reset()
+	p.event(EventExportCompleted, "a.las", 0, "export completed")
+	if actual := buf.String(); actual != "[0s] [a.las] export completed\n" {
+		t.Errorf("expected no bar to clear, got %q", actual)
+	}
+}
+
+func TestTilerProcessFilesProgressWriter(t *testing.T) {
+	tiler, err := NewGoCesiumTiler()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var progress writer.ProgressFunc
+	tiler.writerProvider = func(folder string, inputFiles []string, reader las.LasReader, c coor.CoordinateConverter, opts *TilerOptions, p writer.ProgressFunc) (writer.Writer, error) {
+		progress = p
+		return &writer.MockWriter{}, nil
+	}
+	tiler.treeProvider = func(opts *TilerOptions, m mutator.Mutator) tree.Tree {
+		return &tree.MockNode{}
+	}
+	tiler.lasReaderProvider = func(inputLasFiles []string, epsgCode int, opts *TilerOptions) (las.LasReader, error) {
+		return &las.MockLasReader{}, nil
+	}
+	buf := &bytes.Buffer{}
+	if err := tiler.ProcessFiles([]string{"abc.las"}, "out", 32633, NewTilerOptions(WithProgressWriter(buf)), context.TODO()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	for _, msg := range []string{"[abc.las] start reading las\n", "[abc.las] export started\n", "[abc.las] export completed"} {
+		if !strings.Contains(buf.String(), msg) {
+			t.Errorf("expected the output to contain %q, got %q", msg, buf.String())
+		}
+	}
+	if progress == nil {
+		t.Fatalf("expected the export progress to be reported")
+	}
+	progress(3, 5)
+	if !strings.Contains(buf.String(), "[abc.las] exported 3/5 tiles\n") {
+		t.Errorf("expected the export progress in the output, got %q", buf.String())
+	}
+}
+
+func TestTilerProcessFilesProgressWriterOverview(t *testing.T) {
+	tiler, err := NewGoCesiumTiler()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tr := &tree.MockNode{Pts: geom.NewLinkedPointStream(nil, 1), Root: true, Leaf: true}
+	tiler.writerProvider = func(folder string, inputFiles []string, reader las.LasReader, c coor.CoordinateConverter, opts *TilerOptions, p writer.ProgressFunc) (writer.Writer, error) {
+		return &writer.MockWriter{}, nil
+	}
+	tiler.treeProvider = func(opts *TilerOptions, m mutator.Mutator) tree.Tree {
+		return tr
+	}
+	tiler.lasReaderProvider = func(inputLasFiles []string, epsgCode int, opts *TilerOptions) (las.LasReader, error) {
+		return &las.MockLasReader{}, nil
+	}
+	buf := &bytes.Buffer{}
+	opts := NewTilerOptions(WithProgressWriter(buf), WithOverviewTileset(100))
+	if err := tiler.ProcessFiles([]string{"abc.las"}, t.TempDir(), 4326, opts, context.TODO()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !strings.Contains(buf.String(), "[abc.las] generating overview tileset\n") {
+		t.Errorf("expected the overview generation in the output, got %q", buf.String())
+	}
+}
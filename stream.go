@@ -0,0 +1,138 @@
+package tiler
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/conv/coor"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/tree"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/writer"
+)
+
+// Tile is a file of a tileset produced by StreamTiles
+type Tile struct {
+	// URI is the path of the file relative to the root of the tileset, e.g. "0/3/content.pnts"
+	URI string
+	// Content is the content of the file
+	Content []byte
+	// BoundingVolume is the region of the tile the file belongs to, expressed as
+	// [west, south, east, north, minimum height, maximum height] with angles in radians and heights in meters
+	BoundingVolume [6]float64
+}
+
+// StreamTiles converts the given LAS files as ProcessFiles does, but instead of storing the tileset sends each of
+// its files to the returned tile channel as soon as it is produced, so that it can be uploaded or served without
+// touching the disk. The tile channel is closed when the conversion ends, after which the error channel yields the
+// error that stopped it, if any, and is closed too. The caller must drain the tile channel or cancel the context.
+// The outputs stored next to the tileset, such as the terrain, the overview, the footprints, the preview image, the
+// manifest and the packaging, are not produced when streaming, nor the tilesets split by WithTemporalTiling. With SyntheticSeparate
+// the synthetic points are excluded, as their tileset is not streamed.
+func (t *GoCesiumTiler) StreamTiles(inputLasFiles []string, epsgCode int, opts *TilerOptions, ctx context.Context) (<-chan Tile, <-chan error) {
+	tiles := make(chan Tile)
+	errs := make(chan error, 1)
+	streamOpts := *opts
+	streamOpts.terrainOutput = false
+	streamOpts.overviewPoints = 0
+	streamOpts.debugFootprints = ""
+	streamOpts.previewImage = nil
+	streamOpts.manifestPath = ""
+	streamOpts.packaging = PackageNone
+	streamOpts.temporalWindow = 0
+	if streamOpts.syntheticPolicy == SyntheticSeparate {
+		streamOpts.syntheticPolicy = SyntheticExclude
+	}
+	streamOpts.tileSink = &tileSink{tiles: tiles, ctx: ctx}
+	go func() {
+		defer close(errs)
+		err := t.processFiles(inputLasFiles, "", epsgCode, &streamOpts, &runResources{}, ctx)
+		close(tiles)
+		if err != nil {
+			errs <- err
+		}
+	}()
+	return tiles, errs
+}
+
+// tileSink sends the files of the tileset being exported to a channel, together with the bounding volume of their tile
+type tileSink struct {
+	tiles   chan<- Tile
+	ctx     context.Context
+	volumes map[string][6]float64
+}
+
+// indexVolumes computes the bounding volume of each tile of the tree rooted at the given node, indexed by the folder
+// of the tile relative to the tileset root
+func (s *tileSink) indexVolumes(root tree.Node, conv coor.CoordinateConverter) error {
+	s.volumes = map[string][6]float64{}
+	return s.indexNode(".", root, conv)
+}
+
+func (s *tileSink) indexNode(folder string, node tree.Node, conv coor.CoordinateConverter) error {
+	reg, err := node.GetBoundingBoxRegion(conv)
+	if err != nil {
+		return err
+	}
+	s.volumes[folder] = [6]float64{reg.Xmin, reg.Ymin, reg.Xmax, reg.Ymax, reg.Zmin, reg.Zmax}
+	for i, child := range node.GetChildren() {
+		if child != nil {
+			if err := s.indexNode(path.Join(folder, strconv.Itoa(i)), child, conv); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// storage is the writer.StorageProvider returning the storage that sends the files to the channel
+func (s *tileSink) storage(root string) (writer.Storage, error) {
+	return &sinkStorage{sink: s, root: root}, nil
+}
+
+// sinkStorage is a writer.Storage sending the files to the channel of the tileSink instead of storing them
+type sinkStorage struct {
+	sink *tileSink
+	root string
+}
+
+func (s *sinkStorage) WriteFile(filePath string, data []byte) error {
+	uri := strings.TrimPrefix(strings.TrimPrefix(filePath, s.root), "/")
+	tile := Tile{
+		URI:            uri,
+		Content:        data,
+		BoundingVolume: s.sink.volumes[path.Dir(uri)],
+	}
+	select {
+	case s.sink.tiles <- tile:
+		return nil
+	case <-s.sink.ctx.Done():
+		return s.sink.ctx.Err()
+	}
+}
+
+func (s *sinkStorage) Create(filePath string) (io.WriteCloser, error) {
+	return &sinkFile{onClose: func(data []byte) error {
+		return s.WriteFile(filePath, data)
+	}}, nil
+}
+
+func (s *sinkStorage) Close() error {
+	return nil
+}
+
+func (s *sinkStorage) Abort() error {
+	return nil
+}
+
+// sinkFile buffers the file content in memory, sending it to the channel when closed
+type sinkFile struct {
+	bytes.Buffer
+	onClose func(data []byte) error
+}
+
+func (f *sinkFile) Close() error {
+	return f.onClose(f.Bytes())
+}
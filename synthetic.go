@@ -0,0 +1,32 @@
+package tiler
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/tree"
+)
+
+// syntheticFolder is the subfolder of the output folder storing the tileset of the synthetic points exported by
+// SyntheticSeparate
+const syntheticFolder = "synthetic"
+
+// processSynthetic exports the points without the synthetic flag in the main tileset in the output folder and the
+// synthetic points in a distinct tileset in the synthetic subfolder. Either tileset is skipped if it has no points.
+func (t *GoCesiumTiler) processSynthetic(inputLasFiles []string, outputFolder string, epsgCode int, opts *TilerOptions, res *runResources, ctx context.Context) error {
+	mainOpts := *opts
+	mainOpts.syntheticPolicy = SyntheticExclude
+	mainErr := t.processFiles(inputLasFiles, outputFolder, epsgCode, &mainOpts, res, ctx)
+	if mainErr != nil && !errors.Is(mainErr, tree.ErrAllPointsDiscarded) {
+		return mainErr
+	}
+	syntheticOpts := *opts
+	syntheticOpts.syntheticPolicy = SyntheticInclude
+	syntheticOpts.syntheticOnly = true
+	err := t.processFiles(inputLasFiles, filepath.Join(outputFolder, syntheticFolder), epsgCode, &syntheticOpts, res, ctx)
+	if errors.Is(err, tree.ErrAllPointsDiscarded) && mainErr == nil {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,72 @@
+package tiler
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/writer"
+)
+
+func TestMutatorPipelineSyntheticPolicy(t *testing.T) {
+	cases := []struct {
+		opts      *TilerOptions
+		synthetic bool
+		regular   bool
+	}{
+		{NewTilerOptions(), true, true},
+		{NewTilerOptions(WithSyntheticPointPolicy(SyntheticExclude)), false, true},
+		{NewTilerOptions(func(opt *TilerOptions) { opt.syntheticOnly = true }), true, false},
+	}
+	for i, c := range cases {
+		p, err := newMutatorPipeline(c.opts, 0, nil, &runResources{}, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, keep := p.Mutate(geom.Point64{Flags: geom.FlagSynthetic}); keep != c.synthetic {
+			t.Errorf("case %d: expected keep %v for the synthetic point got %v", i, c.synthetic, keep)
+		}
+		if _, keep := p.Mutate(geom.Point64{}); keep != c.regular {
+			t.Errorf("case %d: expected keep %v for the regular point got %v", i, c.regular, keep)
+		}
+	}
+}
+
+func TestTilerSyntheticSeparate(t *testing.T) {
+	tiler, err := NewGoCesiumTiler()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pts := []Point{}
+	for i := 0; i < 20; i++ {
+		for j := 0; j < 20; j++ {
+			pt := Point{X: 500000 + float64(i), Y: 5000000 + float64(j), Z: 100}
+			if i < 5 {
+				pt.Flags = geom.FlagSynthetic
+			}
+			pts = append(pts, pt)
+		}
+	}
+	out := t.TempDir()
+	opts := NewTilerOptions(WithGridSize(2), WithSyntheticPointPolicy(SyntheticSeparate))
+	if err := tiler.ProcessPoints(pts, out, 32633, opts, context.TODO()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n, err := writer.ValidateTileset(out); err != nil || n != 300 {
+		t.Errorf("expected %d points in the main tileset got %d (%v)", 300, n, err)
+	}
+	if n, err := writer.ValidateTileset(filepath.Join(out, syntheticFolder)); err != nil || n != 100 {
+		t.Errorf("expected %d points in the synthetic tileset got %d (%v)", 100, n, err)
+	}
+
+	// no synthetic tileset is written without synthetic points
+	out = t.TempDir()
+	if err := tiler.ProcessPoints(pts[100:], out, 32633, opts, context.TODO()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(out, syntheticFolder)); !os.IsNotExist(err) {
+		t.Errorf("expected no synthetic tileset, got %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package tiler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/las"
+)
+
+// temporalIndexFile is the file, stored in the output folder, listing the tilesets exported by WithTemporalTiling
+const temporalIndexFile = "temporal.json"
+
+// TemporalIndex lists the tilesets exported by WithTemporalTiling, one per time window containing points
+type TemporalIndex struct {
+	// Window is the length of the time windows in seconds
+	Window float64 `json:"window"`
+	// Tilesets are sorted by ascending start time
+	Tilesets []TemporalTileset `json:"tilesets"`
+}
+
+// TemporalTileset is a tileset storing the points whose GPS time falls in the [Start, End) interval
+type TemporalTileset struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	// Folder is the folder of the tileset relative to the output folder
+	Folder string `json:"folder"`
+}
+
+// processTemporal exports a tileset for each time window containing points in a subfolder of the output folder,
+// and writes the index of the exported tilesets. The input files are read once to find the time windows and once
+// per time window to export it.
+func (t *GoCesiumTiler) processTemporal(inputLasFiles []string, outputFolder string, epsgCode int, opts *TilerOptions, res *runResources, ctx context.Context) error {
+	window := opts.temporalWindow.Seconds()
+	windows, err := t.readTimeWindows(inputLasFiles, epsgCode, opts, res, window)
+	if err != nil {
+		return err
+	}
+	index := TemporalIndex{Window: window, Tilesets: []TemporalTileset{}}
+	for _, w := range windows {
+		start := float64(w) * window
+		tileset := TemporalTileset{
+			Start:  start,
+			End:    start + window,
+			Folder: "gps_" + strconv.FormatFloat(start, 'f', -1, 64),
+		}
+		windowOpts := *opts
+		windowOpts.temporalWindow = 0
+		windowOpts.timeWindow = &[2]float64{tileset.Start, tileset.End}
+		if err := t.processFiles(inputLasFiles, filepath.Join(outputFolder, tileset.Folder), epsgCode, &windowOpts, res, ctx); err != nil {
+			return err
+		}
+		index.Tilesets = append(index.Tilesets, tileset)
+	}
+	data, err := json.MarshalIndent(index, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(outputFolder, 0777); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(outputFolder, temporalIndexFile), data, 0666)
+}
+
+// readTimeWindows reads the input files returning, sorted, the indexes of the time windows of the given length
+// in seconds containing at least a point. The points are transformed by the mutators set by the options first,
+// so that the windows match the points that are exported.
+func (t *GoCesiumTiler) readTimeWindows(inputLasFiles []string, epsgCode int, opts *TilerOptions, res *runResources, window float64) ([]int64, error) {
+	var reader las.LasReader
+	reader, err := t.lasReaderProvider(inputLasFiles, epsgCode, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer closeReader(reader)
+	if opts.externalClassification != "" {
+		labels, err := las.NewLabelReader(reader, opts.externalClassification)
+		if err != nil {
+			return nil, err
+		}
+		defer labels.Close()
+		reader = labels
+	}
+	m, err := newMutatorPipeline(opts, epsgCode, t.cconv, res, reader)
+	if err != nil {
+		return nil, err
+	}
+	found := map[int64]bool{}
+	for i := 0; i < reader.NumberOfPoints(); i++ {
+		pt, err := reader.GetNext()
+		if errors.Is(err, las.ErrNoMorePoints) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if pt, ok := m.Mutate(pt); ok {
+			found[int64(math.Floor(pt.GpsTime/window))] = true
+		}
+	}
+	if len(found) == 0 {
+		return nil, fmt.Errorf("no points to split in time windows")
+	}
+	windows := make([]int64, 0, len(found))
+	for w := range found {
+		windows = append(windows, w)
+	}
+	sort.Slice(windows, func(i, j int) bool { return windows[i] < windows[j] })
+	return windows, nil
+}
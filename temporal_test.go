@@ -0,0 +1,70 @@
+package tiler
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/conv/coor"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/las"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/mutator"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/tree"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/writer"
+)
+
+func TestTilerProcessFilesTemporalTiling(t *testing.T) {
+	tiler, err := NewGoCesiumTiler()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	folders := []string{}
+	tiler.writerProvider = func(folder string, inputFiles []string, reader las.LasReader, c coor.CoordinateConverter, opts *TilerOptions, progress writer.ProgressFunc) (writer.Writer, error) {
+		folders = append(folders, folder)
+		return &writer.MockWriter{}, nil
+	}
+	mutators := []mutator.Mutator{}
+	tiler.treeProvider = func(opts *TilerOptions, m mutator.Mutator) tree.Tree {
+		mutators = append(mutators, m)
+		return &tree.MockNode{}
+	}
+	tiler.lasReaderProvider = func(inputLasFiles []string, epsgCode int, opts *TilerOptions) (las.LasReader, error) {
+		return &las.MockLasReader{Pts: []geom.Point64{{GpsTime: 125}, {GpsTime: 61}, {GpsTime: 130}, {GpsTime: 179.5}}}, nil
+	}
+	out := t.TempDir()
+	if err := tiler.ProcessFiles([]string{"abc.las"}, out, 32633, NewTilerOptions(WithTemporalTiling(time.Minute)), context.TODO()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	expected := []string{filepath.Join(out, "gps_60"), filepath.Join(out, "gps_120")}
+	if len(folders) != len(expected) || folders[0] != expected[0] || folders[1] != expected[1] {
+		t.Fatalf("expected tilesets %v got %v", expected, folders)
+	}
+	// each tileset keeps only the points of its window
+	if _, keep := mutators[0].Mutate(geom.Point64{GpsTime: 61}); !keep {
+		t.Errorf("expected the point to be kept in the first window")
+	}
+	if _, keep := mutators[0].Mutate(geom.Point64{GpsTime: 125}); keep {
+		t.Errorf("expected the point to be discarded from the first window")
+	}
+	if _, keep := mutators[1].Mutate(geom.Point64{GpsTime: 179.5}); !keep {
+		t.Errorf("expected the point to be kept in the second window")
+	}
+
+	data, err := os.ReadFile(filepath.Join(out, temporalIndexFile))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	index := TemporalIndex{}
+	if err := json.Unmarshal(data, &index); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if index.Window != 60 || len(index.Tilesets) != 2 {
+		t.Fatalf("unexpected index %v", index)
+	}
+	if actual := index.Tilesets[1]; actual != (TemporalTileset{Start: 120, End: 180, Folder: "gps_120"}) {
+		t.Errorf("unexpected tileset %v", actual)
+	}
+}
@@ -2,16 +2,24 @@ package tiler
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"math"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/mfbonfigli/gocesiumtiler/v2/internal/conv/coor"
 	"github.com/mfbonfigli/gocesiumtiler/v2/internal/conv/coor/proj4"
 	"github.com/mfbonfigli/gocesiumtiler/v2/internal/conv/elev"
 	"github.com/mfbonfigli/gocesiumtiler/v2/internal/conv/elev/geoid2ellipsoid"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
 	"github.com/mfbonfigli/gocesiumtiler/v2/internal/las"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/mutator"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/raster"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/terrain"
 	"github.com/mfbonfigli/gocesiumtiler/v2/internal/tree"
 	"github.com/mfbonfigli/gocesiumtiler/v2/internal/utils"
 	"github.com/mfbonfigli/gocesiumtiler/v2/internal/writer"
@@ -22,6 +30,19 @@ type Tiler interface {
 	ProcessFolder(inputFolder, outputFolder string, epsgCode int, opts *TilerOptions, ctx context.Context) error
 }
 
+// terrainFolder is the subfolder of the tileset storing the terrain generated with WithTerrainOutput
+const terrainFolder = "terrain"
+
+// overviewFolder is the subfolder of the tileset storing the overview tileset generated with WithOverviewTileset
+const overviewFolder = "overview"
+
+// groundCellSize is the size, in units of the input CRS, of the cells of the ground surface used by WithHeightAboveGround
+// and WithHeightRules
+const groundCellSize = 5
+
+// exportProgressInterval is how often the progress of the export is reported to the callback
+const exportProgressInterval = time.Second
+
 // GoCesiumTiler wraps the logic required to convert
 // LAS point clouds into Cesium 3D tiles
 type GoCesiumTiler struct {
@@ -31,9 +52,9 @@ type GoCesiumTiler struct {
 	lasReaderProvider
 }
 
-type treeProvider func(opts *TilerOptions) tree.Tree
-type writerProvider func(folder string, c coor.CoordinateConverter, opts *TilerOptions) (writer.Writer, error)
-type lasReaderProvider func(inputLasFiles []string, epsgCode int, eightbit bool) (las.LasReader, error)
+type treeProvider func(opts *TilerOptions, m mutator.Mutator) tree.Tree
+type writerProvider func(folder string, inputFiles []string, reader las.LasReader, c coor.CoordinateConverter, opts *TilerOptions, progress writer.ProgressFunc) (writer.Writer, error)
+type lasReaderProvider func(inputLasFiles []string, epsgCode int, opts *TilerOptions) (las.LasReader, error)
 
 // NewGoCesiumTiler returns a new tiler to be used to convert LAS files into Cesium 3D Tiles
 func NewGoCesiumTiler() (*GoCesiumTiler, error) {
@@ -43,79 +64,404 @@ func NewGoCesiumTiler() (*GoCesiumTiler, error) {
 	}
 	return &GoCesiumTiler{
 		cconv: cconv,
-		treeProvider: func(opts *TilerOptions) tree.Tree {
+		treeProvider: func(opts *TilerOptions, m mutator.Mutator) tree.Tree {
 			return tree.NewGridTree(
-				tree.WithGridSize(opts.gridSize),
+				tree.WithGridSizeXYZ(opts.gridSize[0], opts.gridSize[1], opts.gridSize[2]),
 				tree.WithMaxDepth(opts.maxDepth),
-				tree.WithLoadWorkersNumber(opts.numWorkers),
+				tree.WithLoadWorkersNumber(opts.readWorkers),
+				tree.WithReprojectionWorkers(opts.reprojectionWorkers),
 				tree.WithMinPointsPerChildren(opts.minPointsPerTile),
+				tree.WithMutator(m),
+				tree.WithOutlierRemoval(opts.sorNeighbors, opts.sorStdDevMul),
+				tree.WithElevationSmoothing(opts.smoothingRadius, opts.smoothingIterations),
+				tree.WithFeaturePreservingThinning(opts.featurePreserving),
+				tree.WithSparseNodePolicy(tree.SparseNodePolicy(opts.sparseNodePolicy)),
+				tree.WithSubdivision(tree.Subdivision(opts.subdivision)),
+				tree.WithCoordinateRounding(opts.coordinateRounding),
+				tree.WithSpatialIndexCache(opts.spatialIndexCache),
+				tree.WithEqualAreaThinning(opts.equalAreaThinning),
+				tree.WithRootPointTarget(opts.rootPointTarget),
+				tree.WithCancellationCheckInterval(opts.cancelCheckInterval),
+				tree.WithGeometricErrorMode(tree.GeometricErrorMode(opts.geometricErrorMode)),
 			)
 		},
-		writerProvider: func(folder string, c coor.CoordinateConverter, opts *TilerOptions) (writer.Writer, error) {
-			return writer.NewWriter(folder, c, writer.WithNumWorkers(opts.numWorkers))
+		writerProvider: func(folder string, inputFiles []string, reader las.LasReader, c coor.CoordinateConverter, opts *TilerOptions, progress writer.ProgressFunc) (writer.Writer, error) {
+			storageProvider := writer.FsStorageProvider
+			switch opts.packaging {
+			case Package3tz:
+				storageProvider = writer.ThreeTzStorageProvider
+			case PackageIonZip:
+				storageProvider = writer.ZipStorageProvider
+			}
+			if opts.tileSink != nil {
+				storageProvider = opts.tileSink.storage
+			}
+			if opts.manifestPath != "" {
+				storageProvider = writer.ManifestStorageProvider(storageProvider, opts.manifestPath)
+			}
+			if opts.contentPostProcessor != nil {
+				// decorates the manifest storage, so that the manifest describes the post processed contents
+				storageProvider = writer.PostProcessingStorageProvider(storageProvider, writer.ContentPostProcessor(opts.contentPostProcessor))
+			}
+			extras := assetExtras()
+			if opts.sourceFileAttribute {
+				// maps the values of the _SOURCE_FILE property back to the input files
+				extras["sourceFiles"] = inputFiles
+			}
+			if w := opts.timeWindow; w != nil {
+				// the GPS time interval of the points of the tileset exported by WithTemporalTiling
+				extras["timeWindow"] = map[string]float64{"start": w[0], "end": w[1]}
+			}
+			if s := opts.averagePointSpacing; s > 0 {
+				extras["averagePointSpacing"] = s
+			}
+			if q, ok := reader.(las.QuantizationSource); ok && opts.sourceQuantization {
+				extras["source"] = q.Quantization()
+			}
+			writerOpts := []func(*writer.StandardWriter){
+				writer.WithNumWorkers(opts.exportWorkers),
+				writer.WithStorageProvider(storageProvider),
+				writer.WithAssetExtras(extras),
+				writer.WithPrettyTileset(opts.prettyTileset),
+				writer.WithQuantizedPositions(opts.quantizedPositions),
+				writer.WithSourceFileAttribute(opts.sourceFileAttribute),
+				writer.WithPointSourceIdAttribute(opts.pointSourceIdAttribute),
+				writer.WithPerPointBatchId(opts.perPointBatchId),
+				writer.WithMaxTiles(opts.maxTiles),
+				writer.WithFlatten(opts.flattenLevels),
+				writer.WithFullDetailDistance(opts.fullDetailDistance),
+				writer.WithMinContentBytes(opts.minContentBytes),
+				writer.WithClassAlpha(opts.classAlpha),
+				writer.WithContentExtension(opts.contentExtension),
+				writer.WithProperties(opts.tilesetProperties),
+				writer.WithBufferAlignment(opts.bufferAlignment),
+				writer.WithContentBoundingVolumes(opts.contentBoundingVolumes),
+				writer.WithProgress(progress, exportProgressInterval),
+				writer.WithClock(opts.clock),
+			}
+			if opts.overview {
+				writerOpts = append(writerOpts, writer.WithOverview(opts.overviewPoints))
+			}
+			if r := opts.outputRegion; r != nil {
+				writerOpts = append(writerOpts, writer.WithOutputRegion([4]float64{
+					r.West * math.Pi / 180, r.South * math.Pi / 180, r.East * math.Pi / 180, r.North * math.Pi / 180,
+				}))
+			}
+			return writer.NewWriter(folder, c, writerOpts...)
 		},
-		lasReaderProvider: func(inputLasFiles []string, epsgCode int, eightbit bool) (las.LasReader, error) {
-			return las.NewCombinedFileLasReader(inputLasFiles, epsgCode, eightbit)
+		lasReaderProvider: func(inputLasFiles []string, epsgCode int, opts *TilerOptions) (las.LasReader, error) {
+			readerOpts := []func(*las.FileLasReader){
+				las.WithReadBufferSize(opts.readBufferSize),
+				las.WithAttributes(las.Attributes(opts.loadAttributes)),
+				las.WithPointRange(opts.pointRange[0], opts.pointRange[1]),
+				las.WithColorChannelMapping(las.ColorSource(opts.colorMapping[0]), las.ColorSource(opts.colorMapping[1]), las.ColorSource(opts.colorMapping[2])),
+			}
+			if opts.extraFilter != nil {
+				readerOpts = append(readerOpts, las.WithExtraDimension(opts.extraFilter.name))
+			}
+			if opts.openFileLimiter != nil {
+				readerOpts = append(readerOpts, las.WithOpenFileLimiter(opts.openFileLimiter))
+			}
+			files, err := las.ExpandVirtualClouds(inputLasFiles)
+			if err != nil {
+				return nil, err
+			}
+			return las.NewVirtualLasReader(files, epsgCode, opts.eightBitColors, readerOpts...)
 		},
 	}, nil
 }
 
 // ProcessFolder converts all LAS files found in the provided input folder converting them into separate tilesets
-// each tileset is stored in a subdirectory in the outputFolder named after the filename
+// each tileset is stored in a subdirectory in the outputFolder named after the filename. With WithRecursive the
+// files in the subfolders are converted too, each tileset is stored under the path of the file relative to the
+// input folder.
 func (t *GoCesiumTiler) ProcessFolder(inputFolder, outputFolder string, epsgCode int, opts *TilerOptions, ctx context.Context) error {
-	files, err := utils.FindLasFilesInFolder(inputFolder)
+	files, err := FindMatchingLasFiles(inputFolder, opts.recursive, opts.filePattern)
 	if err != nil {
 		return err
 	}
+	if opts.manifestPath != "" && !filepath.IsLocal(opts.manifestPath) {
+		return fmt.Errorf("manifest path %s must be relative to the tileset folder when processing a folder", opts.manifestPath)
+	}
+	res := &runResources{}
+	if opts.crsGrouping {
+		return t.processCrsGroups(files, outputFolder, epsgCode, opts, res, ctx)
+	}
+	failures := []error{}
 	for _, f := range files {
-		subfolderName := strings.TrimSuffix(filepath.Base(f), filepath.Ext(f))
-		err := t.ProcessFiles([]string{f}, filepath.Join(outputFolder, subfolderName), epsgCode, opts, ctx)
-		if err != nil {
+		err := t.processFiles([]string{f}, filepath.Join(outputFolder, tilesetFolderName(inputFolder, f)), epsgCode, opts, res, ctx)
+		if err == nil {
+			continue
+		}
+		if !opts.continueOnError || ctx.Err() != nil {
 			return err
 		}
+		var fileErr *las.FileError
+		if !errors.As(err, &fileErr) {
+			err = &las.FileError{File: f, Err: err}
+		}
+		failures = append(failures, err)
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%d of %d files failed:\n%w", len(failures), len(files), errors.Join(failures...))
 	}
 	return nil
 }
 
 // ProcessFiles converts the specified LAS files as a single cesium tileset and stores them in the
+// outputFolder. Inputs with the .json extension are virtual cloud descriptors, listing the LAS files to read in
+// their place, in order, each with an optional transform of its coordinates:
+//
+//	{"files": [{"path": "a.las"}, {"path": "b.las", "offset": [0, 0, -32.5], "scale": [1, 1, 0.3048]}]}
+//
+// The coordinates are multiplied by the scale, defaulting to 1, and then the offset, defaulting to 0, is added.
+// Relative paths are resolved against the folder of the descriptor.
 func (t *GoCesiumTiler) ProcessFiles(inputLasFiles []string, outputFolder string, epsgCode int, opts *TilerOptions, ctx context.Context) error {
-	start := time.Now()
-	tr := t.treeProvider(opts)
+	return t.processFiles(inputLasFiles, outputFolder, epsgCode, opts, &runResources{}, ctx)
+}
+
+// FindLasFiles returns the LAS files in the given folder and, if recursive, in all its subfolders
+func FindLasFiles(folder string, recursive bool) ([]string, error) {
+	if recursive {
+		return utils.FindLasFilesInFolderRecursive(folder)
+	}
+	return utils.FindLasFilesInFolder(folder)
+}
+
+// FindMatchingLasFiles returns the LAS files found as FindLasFiles does whose base name matches the given glob
+// pattern, failing if no file matches it. An empty pattern matches all the files.
+func FindMatchingLasFiles(folder string, recursive bool, pattern string) ([]string, error) {
+	files, err := FindLasFiles(folder, recursive)
+	if err != nil {
+		return nil, err
+	}
+	files, err = utils.FilterFilesByPattern(files, pattern)
+	if err != nil {
+		return nil, err
+	}
+	if pattern != "" && len(files) == 0 {
+		return nil, fmt.Errorf("no LAS files matching the pattern %s found in %s", pattern, folder)
+	}
+	return files, nil
+}
+
+// tilesetFolderName returns the path, relative to the output folder, of the tileset of the given file found in the
+// input folder: the path of the file relative to the input folder without the extension
+func tilesetFolderName(inputFolder, file string) string {
+	rel, err := filepath.Rel(inputFolder, file)
+	if err != nil {
+		rel = filepath.Base(file)
+	}
+	return strings.TrimSuffix(rel, filepath.Ext(rel))
+}
+
+// ReadFileList reads the paths of the LAS files to pass to ProcessFiles from a text file listing one path per line.
+// Blank lines and lines starting with # are ignored, relative paths are resolved against the folder of the list file.
+func ReadFileList(listPath string) ([]string, error) {
+	return utils.ReadFileList(listPath)
+}
+
+// listFiles returns the LAS files read by the reader, which differ from the inputs if these include virtual cloud
+// descriptors, or the inputs if the reader cannot list them
+func listFiles(reader las.LasReader, inputLasFiles []string) []string {
+	if lister, ok := reader.(las.FileLister); ok {
+		return lister.FileNames()
+	}
+	return inputLasFiles
+}
+
+// closeReader closes the files kept open by the given reader, if it has any
+func closeReader(r las.LasReader) {
+	if c, ok := r.(io.Closer); ok {
+		c.Close()
+	}
+}
+
+// runResources holds the resources shared by all the tilesets generated in a single ProcessFiles or ProcessFolder call.
+// Resources are loaded the first time they are needed and then reused.
+type runResources struct {
+	elevationRaster *raster.GeoTiff
+	colorRaster     *raster.GeoTiff
+	epsgRegistry    map[int]string
+}
+
+// getEpsgRegistry returns the proj4 definitions stored in the EPSG registry at the given path, reading them only
+// on the first invocation
+func (r *runResources) getEpsgRegistry(path string) (map[int]string, error) {
+	if r.epsgRegistry == nil {
+		definitions, err := proj4.LoadEpsgRegistry(path)
+		if err != nil {
+			return nil, err
+		}
+		r.epsgRegistry = definitions
+	}
+	return r.epsgRegistry, nil
+}
+
+// getElevationRaster returns the DEM at the given path, reading it only on the first invocation
+func (r *runResources) getElevationRaster(path string) (*raster.GeoTiff, error) {
+	if r.elevationRaster == nil {
+		g, err := raster.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		r.elevationRaster = g
+	}
+	return r.elevationRaster, nil
+}
+
+// getColorRaster returns the orthophoto at the given path, reading it only on the first invocation
+func (r *runResources) getColorRaster(path string) (*raster.GeoTiff, error) {
+	if r.colorRaster == nil {
+		g, err := raster.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		if g.Bands < 3 {
+			return nil, fmt.Errorf("the color raster %s has %d bands, at least 3 are required", path, g.Bands)
+		}
+		r.colorRaster = g
+	}
+	return r.colorRaster, nil
+}
+
+func (t *GoCesiumTiler) processFiles(inputLasFiles []string, outputFolder string, epsgCode int, opts *TilerOptions, res *runResources, ctx context.Context) error {
+	if opts.readBufferSize <= 0 {
+		return fmt.Errorf("invalid read buffer size %d: must be greater than zero", opts.readBufferSize)
+	}
+	if opts.epsgRegistry != "" {
+		return t.processWithEpsgRegistry(inputLasFiles, outputFolder, epsgCode, opts, res, ctx)
+	}
+	if opts.datumGridPath != "" {
+		return t.processWithDatumGridPath(inputLasFiles, outputFolder, epsgCode, opts, res, ctx)
+	}
+	if opts.temporalWindow > 0 {
+		return t.processTemporal(inputLasFiles, outputFolder, epsgCode, opts, res, ctx)
+	}
+	if opts.syntheticPolicy == SyntheticSeparate {
+		return t.processSynthetic(inputLasFiles, outputFolder, epsgCode, opts, res, ctx)
+	}
+	start := opts.clock.Now()
+	if err := checkOutputRegion(opts.outputRegion); err != nil {
+		return err
+	}
+	if err := checkContentExtension(opts.contentExtension); err != nil {
+		return err
+	}
+	if p := opts.previewImage; p != nil && (p.width <= 0 || p.height <= 0) {
+		return fmt.Errorf("invalid preview image size %dx%d: must be greater than zero", p.width, p.height)
+	}
 
 	inputDesc := fmt.Sprintf("%d files", len(inputLasFiles))
 	if len(inputLasFiles) == 1 {
 		inputDesc = inputLasFiles[0]
 	}
 
+	timer := &phaseTimer{start: start, clock: opts.clock, callback: opts.phaseCallback}
+	timings := PhaseTimings{}
+
 	// PARSE LAS HEADER
 	emitEvent(EventReadLasHeaderStarted, opts, start, inputDesc, "start reading las")
-	lasFile, err := t.lasReaderProvider(inputLasFiles, epsgCode, opts.eightBitColors)
+	lasFile, err := t.lasReaderProvider(inputLasFiles, epsgCode, opts)
 	if err != nil {
-		emitEvent(EventReadLasHeaderError, opts, start, inputDesc, fmt.Sprintf("las read error: %v", err))
+		emitEvent(EventReadLasHeaderError, opts, start, fileErrorDesc(err, inputDesc), fmt.Sprintf("las read error: %v", err))
 		return err
 	}
+	defer closeReader(lasFile)
+	var source las.LasReader = lasFile
+	if opts.externalClassification != "" {
+		labels, err := las.NewLabelReader(lasFile, opts.externalClassification)
+		if err != nil {
+			emitEvent(EventReadLasHeaderError, opts, start, inputDesc, fmt.Sprintf("labels read error: %v", err))
+			return err
+		}
+		defer labels.Close()
+		source = labels
+	}
+	if extent, ok := lasFile.(las.HorizontalExtent); ok {
+		minX, minY, maxX, maxY := extent.Extent()
+		if err := checkCrsExtent(epsgCode, minX, minY, maxX, maxY); err != nil {
+			if opts.strictCrs {
+				emitEvent(EventReadLasHeaderError, opts, start, inputDesc, fmt.Sprintf("crs check error: %v", err))
+				return err
+			}
+			emitEvent(EventCrsWarning, opts, start, inputDesc, fmt.Sprintf("crs check warning: %v", err))
+			opts.errorCollector.add(WarningCrs, inputDesc, 1, err.Error())
+		}
+	}
 	emitEvent(EventReadLasHeaderCompleted, opts, start, inputDesc, fmt.Sprintf("las header read completed: found %d points", lasFile.NumberOfPoints()))
+	timings.ReadHeader = timer.end(PhaseReadHeader)
 
 	// LOAD POINTS
 	emitEvent(EventPointLoadingStarted, opts, start, inputDesc, "point loading started")
-	elevationConverters := []elev.ElevationConverter{
-		elev.NewOffsetElevationConverter(opts.elevationOffset),
+	mutators, err := newMutatorPipeline(opts, epsgCode, t.cconv, res, lasFile)
+	if err != nil {
+		emitEvent(EventPointLoadingError, opts, start, inputDesc, fmt.Sprintf("mutators init error: %v", err))
+		return err
 	}
-	if opts.geoidElevation {
-		egmCalc, err := geoid2ellipsoid.NewEGMCalculator(t.cconv)
-		if err != nil {
-			emitEvent(EventPointLoadingError, opts, start, inputDesc, fmt.Sprintf("converter init error: %v", err))
-			return err
+	if err := t.addGroundMutators(mutators, inputLasFiles, epsgCode, opts); err != nil {
+		emitEvent(EventPointLoadingError, opts, start, fileErrorDesc(err, inputDesc), fmt.Sprintf("ground surface error: %v", err))
+		return err
+	}
+	var drops []*dropCountingMutator
+	if opts.errorCollector != nil {
+		drops = countDrops(mutators)
+	}
+	tr := t.treeProvider(opts, mutators)
+	// when joining multiple files, track the file currently being read to report it in the events
+	reader := source
+	tracker, tracking := lasFile.(las.FileTracker)
+	fileChanges := make(chan string)
+	sourceFiles := listFiles(lasFile, inputLasFiles)
+	if tracking && len(sourceFiles) > 1 {
+		reader = &fileTrackingReader{
+			LasReader: source,
+			tracker:   tracker,
+			changes:   fileChanges,
 		}
-		elevationConverters = append(elevationConverters, elev.NewGeoidElevationConverter(epsgCode, egmCalc))
 	}
-	eConv := elev.NewPipelineElevationCorrector(elevationConverters...)
-	err = tr.Load(lasFile, t.cconv, eConv, ctx)
+	eConv, err := t.newElevationConverter(epsgCode, opts)
 	if err != nil {
-		emitEvent(EventPointLoadingError, opts, start, inputDesc, fmt.Sprintf("load error: %v", err))
+		emitEvent(EventPointLoadingError, opts, start, inputDesc, fmt.Sprintf("converter init error: %v", err))
+		return err
+	}
+	// the reprojection is timed only if the timings are reported, as it adds some overhead to each point
+	var loadConv coor.CoordinateConverter = t.cconv
+	var reprojection *timedConverter
+	if opts.phaseCallback != nil || opts.resultCallback != nil {
+		reprojection = &timedConverter{CoordinateConverter: t.cconv, clock: opts.clock}
+		loadConv = reprojection
+	}
+	// the points are read in a separate goroutine, so that the file change events are emitted from this one
+	loadErr := make(chan error)
+	go func() {
+		loadErr <- tr.Load(reader, loadConv, eConv, ctx)
+	}()
+	for loading := true; loading; {
+		select {
+		case fileName := <-fileChanges:
+			emitEvent(EventPointLoadingFileStarted, opts, start, fileName, "reading points from file")
+		case err = <-loadErr:
+			loading = false
+		}
+	}
+	opts.errorCollector.addDrops(drops, inputDesc)
+	opts.errorCollector.addColorOverflows(lasFile, inputDesc)
+	if err != nil {
+		// errors transforming a point are attributed to the file the point was read from
+		var ptErr *tree.PointError
+		if errors.As(err, &ptErr) && tracking {
+			err = &las.FileError{File: tracker.FileName(ptErr.Point.FileIndex), Err: err}
+		}
+		emitEvent(EventPointLoadingError, opts, start, fileErrorDesc(err, inputDesc), fmt.Sprintf("load error: %v", err))
 		return err
 	}
 	emitEvent(EventPointLoadingCompleted, opts, start, inputDesc, "point loading completed")
+	timings.Load = timer.end(PhaseLoad)
+	if reprojection != nil {
+		timings.Reprojection = reprojection.elapsed()
+		timer.report(PhaseReprojection, timings.Reprojection)
+	}
 
 	// BUILD TREE
 	emitEvent(EventBuildStarted, opts, start, inputDesc, "build started")
@@ -125,10 +471,37 @@ func (t *GoCesiumTiler) ProcessFiles(inputLasFiles []string, outputFolder string
 		return err
 	}
 	emitEvent(EventBuildCompleted, opts, start, inputDesc, "build completed")
+	timings.Build = timer.end(PhaseBuild)
+
+	if opts.pointSpacing {
+		spacing, ok, err := tree.EstimatePointSpacing(tr.GetRootNode(), t.cconv)
+		if err != nil {
+			emitEvent(EventBuildError, opts, start, inputDesc, fmt.Sprintf("point spacing error: %v", err))
+			return err
+		}
+		if ok {
+			spacingOpts := *opts
+			spacingOpts.averagePointSpacing = spacing
+			opts = &spacingOpts
+		}
+	}
 
 	// EXPORT
 	emitEvent(EventExportStarted, opts, start, inputDesc, "export started")
-	w, err := t.writerProvider(outputFolder, t.cconv, opts)
+	var progress writer.ProgressFunc
+	if opts.callback != nil || opts.progressWriter != nil {
+		progress = func(written, total int) {
+			emitEvent(EventExportProgress, opts, start, inputDesc, fmt.Sprintf("exported %d/%d tiles", written, total))
+			opts.progressWriter.progress(inputDesc, opts.clock.Now().Sub(start).Milliseconds(), written, total)
+		}
+	}
+	if opts.tileSink != nil {
+		if err := opts.tileSink.indexVolumes(tr.GetRootNode(), t.cconv); err != nil {
+			emitEvent(EventBuildError, opts, start, inputDesc, fmt.Sprintf("export init error: %v", err))
+			return err
+		}
+	}
+	w, err := t.writerProvider(outputFolder, sourceFiles, lasFile, t.cconv, opts, progress)
 	if err != nil {
 		emitEvent(EventBuildError, opts, start, inputDesc, fmt.Sprintf("export init error: %v", err))
 		return err
@@ -138,12 +511,527 @@ func (t *GoCesiumTiler) ProcessFiles(inputLasFiles []string, outputFolder string
 		emitEvent(EventBuildError, opts, start, inputDesc, fmt.Sprintf("export error: %v", err))
 		return err
 	}
-	emitEvent(EventExportStarted, opts, start, inputDesc, fmt.Sprintf("export completed in %v seconds", time.Since(start).String()))
+	if opts.terrainOutput {
+		emitEvent(EventTerrainExportStarted, opts, start, inputDesc, "generating terrain")
+		if err := writeTerrain(filepath.Join(outputFolder, terrainFolder), tr.GetRootNode(), t.cconv); err != nil {
+			emitEvent(EventExportError, opts, start, inputDesc, fmt.Sprintf("terrain export error: %v", err))
+			return err
+		}
+	}
+	if opts.overviewPoints > 0 {
+		emitEvent(EventOverviewExportStarted, opts, start, inputDesc, "generating overview tileset")
+		if err := t.writeOverview(filepath.Join(outputFolder, overviewFolder), sourceFiles, lasFile, tr, opts, ctx); err != nil {
+			emitEvent(EventExportError, opts, start, inputDesc, fmt.Sprintf("overview export error: %v", err))
+			return err
+		}
+	}
+	if opts.debugFootprints != "" {
+		file := opts.debugFootprints
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(outputFolder, file)
+		}
+		if err := writer.WriteFootprints(file, tr.GetRootNode(), t.cconv); err != nil {
+			emitEvent(EventExportError, opts, start, inputDesc, fmt.Sprintf("footprints export error: %v", err))
+			return err
+		}
+	}
+	if p := opts.previewImage; p != nil {
+		file := p.path
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(outputFolder, file)
+		}
+		if err := writer.WritePreview(file, tr.GetRootNode(), t.cconv, p.width, p.height); err != nil {
+			emitEvent(EventExportError, opts, start, inputDesc, fmt.Sprintf("preview image export error: %v", err))
+			return err
+		}
+	}
+	timings.Export = timer.end(PhaseExport)
+	if opts.resultCallback != nil {
+		result, err := newTilesetResult(outputFolder, tr.GetRootNode(), t.cconv)
+		if err != nil {
+			emitEvent(EventExportError, opts, start, inputDesc, fmt.Sprintf("result error: %v", err))
+			return err
+		}
+		result.Timings = timings
+		result.AveragePointSpacing = opts.averagePointSpacing
+		opts.resultCallback(result)
+	}
+	emitEvent(EventExportStarted, opts, start, inputDesc, fmt.Sprintf("export completed in %v seconds", opts.clock.Now().Sub(start).String()))
 	return nil
 }
 
+// newTilesetResult describes the tileset stored in the given folder, whose root tile is the given node
+func newTilesetResult(outputFolder string, root tree.Node, conv coor.CoordinateConverter) (TilesetResult, error) {
+	cX, cY, cZ, err := root.GetCenter(conv)
+	if err != nil {
+		return TilesetResult{}, err
+	}
+	transform := geom.NewTranslation(cX, cY, cZ)
+	inverse, err := transform.Inverse()
+	if err != nil {
+		return TilesetResult{}, err
+	}
+	return TilesetResult{
+		OutputFolder:         outputFolder,
+		RootTransform:        transform,
+		InverseRootTransform: inverse,
+	}, nil
+}
+
+// writeOverview writes the overview tileset of the given tree in the given folder. The manifest describes the main
+// tileset only, hence it is not written for the overview.
+func (t *GoCesiumTiler) writeOverview(folder string, sourceFiles []string, reader las.LasReader, tr tree.Tree, opts *TilerOptions, ctx context.Context) error {
+	overviewOpts := *opts
+	overviewOpts.overview = true
+	overviewOpts.manifestPath = ""
+	w, err := t.writerProvider(folder, sourceFiles, reader, t.cconv, &overviewOpts, nil)
+	if err != nil {
+		return err
+	}
+	return w.Write(tr, "", ctx)
+}
+
+// writeTerrain generates the quantized-mesh terrain from the ground points of the tree rooted at the given node
+func writeTerrain(folder string, root tree.Node, conv coor.CoordinateConverter) error {
+	pts, err := terrain.CollectPoints(root, conv, mutator.GroundClassification)
+	if err != nil {
+		return err
+	}
+	return terrain.Write(folder, pts)
+}
+
+// newMutatorPipeline returns the mutators to apply to the points while they are loaded, as per the given options.
+// The epsg code of the input points and the coordinate converter are used by the mutators that need to
+// convert the points to other coordinate systems, while external data such as rasters are taken from the given resources.
+// The reader provides the metadata of the input files, such as their elevation range.
+// Mutators that replace the coordinates run before the ones filtering on them, so that filters see the final values.
+func newMutatorPipeline(opts *TilerOptions, epsgCode int, conv coor.CoordinateConverter, res *runResources, reader las.LasReader) (*mutator.Pipeline, error) {
+	mutators := []mutator.Mutator{}
+	horizontal, vertical := float64(opts.inputUnits[0]), float64(opts.inputUnits[1])
+	if horizontal <= 0 || vertical <= 0 {
+		return nil, fmt.Errorf("invalid input units %v, %v: must be greater than zero", horizontal, vertical)
+	}
+	if flip := opts.flipAxis; flip != [3]bool{} {
+		mutators = append(mutators, mutator.NewAxisFlip(flip[0], flip[1], flip[2]))
+	}
+	if horizontal != 1 || vertical != 1 {
+		mutators = append(mutators, mutator.NewUnitScale(horizontal, vertical))
+	}
+	var flagMask uint8
+	if opts.dropWithheld {
+		flagMask |= geom.FlagWithheld
+	}
+	if opts.dropOverlap {
+		flagMask |= geom.FlagOverlap
+	}
+	if opts.syntheticPolicy == SyntheticExclude {
+		flagMask |= geom.FlagSynthetic
+	}
+	if flagMask != 0 {
+		mutators = append(mutators, mutator.NewFlagFilter(flagMask))
+	}
+	if opts.syntheticOnly {
+		mutators = append(mutators, mutator.NewFlagSelect(geom.FlagSynthetic))
+	}
+	if f := opts.extraFilter; f != nil {
+		if f.min > f.max {
+			return nil, fmt.Errorf("invalid range for the extra dimension %s: min %v is greater than max %v", f.name, f.min, f.max)
+		}
+		mutators = append(mutators, mutator.NewExtraRangeFilter(f.min, f.max))
+	}
+	if len(opts.pointSourceFilter) > 0 {
+		mutators = append(mutators, mutator.NewPointSourceFilter(opts.pointSourceFilter))
+	}
+	if w := opts.timeWindow; w != nil {
+		mutators = append(mutators, mutator.NewTimeWindowFilter(w[0], w[1]))
+	}
+	if len(opts.classRemap) > 0 {
+		mutators = append(mutators, mutator.NewClassificationRemap(opts.classRemap))
+	}
+	if r := opts.elevationRaster; r != nil {
+		if opts.heightExaggeration != 1 {
+			// the exaggeration reference comes from the headers and does not relate to the elevations of the raster
+			return nil, fmt.Errorf("height exaggeration cannot be combined with the elevation from a raster")
+		}
+		g, err := res.getElevationRaster(r.path)
+		if err != nil {
+			return nil, err
+		}
+		mutators = append(mutators, mutator.NewRasterElevation(newRasterSampler(g, 0, epsgCode, conv), r.policy == RasterOutsideKeepZ))
+	}
+	if opts.colorRaster != "" {
+		g, err := res.getColorRaster(opts.colorRaster)
+		if err != nil {
+			return nil, err
+		}
+		mutators = append(mutators, mutator.NewRasterColor(newRasterColorSampler(g, epsgCode, conv)))
+	}
+	if c := opts.elevationClamp; c != nil {
+		if c.min > c.max {
+			return nil, fmt.Errorf("invalid elevation clamp range: min %v is greater than max %v", c.min, c.max)
+		}
+		mutators = append(mutators, mutator.NewElevationClamp(c.min, c.max, c.mode == ClampToRange))
+	}
+	if opts.heightExaggeration <= 0 {
+		return nil, fmt.Errorf("invalid height exaggeration %v: must be greater than zero", opts.heightExaggeration)
+	}
+	if opts.heightExaggeration != 1 {
+		zRange, ok := reader.(las.ElevationRange)
+		if !ok {
+			return nil, fmt.Errorf("height exaggeration requires the elevation range of the input files")
+		}
+		// the elevation range of the headers is expressed in the input units, before flipping the axes
+		base := zRange.MinZ()
+		if opts.flipAxis[2] {
+			base = -zRange.MaxZ()
+		}
+		mutators = append(mutators, mutator.NewHeightExaggeration(base*vertical, opts.heightExaggeration))
+	}
+	if h := opts.heightAboveGround; h != nil && h.min > h.max {
+		return nil, fmt.Errorf("invalid height above ground range: min %v is greater than max %v", h.min, h.max)
+	}
+	for _, r := range opts.heightRules {
+		if r.Min > r.Max {
+			return nil, fmt.Errorf("invalid height rule for classification %d: min %v is greater than max %v", r.Classification, r.Min, r.Max)
+		}
+	}
+	if opts.colorGamma <= 0 {
+		return nil, fmt.Errorf("invalid color gamma %v: must be greater than zero", opts.colorGamma)
+	}
+	if opts.colorMatrix != identityColorMatrix {
+		mutators = append(mutators, mutator.NewColorMatrix(opts.colorMatrix))
+	}
+	if opts.colorGamma != 1 {
+		mutators = append(mutators, mutator.NewColorGamma(opts.colorGamma))
+	}
+	if c := opts.defaultColor; c != nil {
+		mutators = append(mutators, mutator.NewDefaultColor(c[0], c[1], c[2]))
+	}
+	if opts.colorByClass {
+		// applied last so that the palette colors are not altered by the color corrections
+		palette := opts.classPalette
+		if opts.classColorFile != "" {
+			filePalette, err := mutator.ReadClassificationPalette(opts.classColorFile)
+			if err != nil {
+				return nil, err
+			}
+			for class, color := range opts.classPalette {
+				filePalette[class] = color
+			}
+			palette = filePalette
+		}
+		mutators = append(mutators, mutator.NewClassificationColor(palette))
+	}
+	if opts.colorBits < 3 || opts.colorBits > 24 {
+		return nil, fmt.Errorf("invalid color bits %d: must be between 3 and 24", opts.colorBits)
+	}
+	if opts.colorBits != 24 {
+		// applied after all the other color options, right before the export
+		mutators = append(mutators, mutator.NewColorBits(opts.colorBits))
+	}
+	return mutator.NewPipeline(mutators...), nil
+}
+
+// insertBeforeClassColor inserts the mutator in the pipeline before the classification colors, if any, so that the
+// colors match the classifications it assigns, or appends it otherwise
+func insertBeforeClassColor(p *mutator.Pipeline, m mutator.Mutator) {
+	for i, existing := range p.Mutators {
+		if _, ok := existing.(*mutator.ClassificationColor); ok {
+			p.Mutators = append(p.Mutators[:i], append([]mutator.Mutator{m}, p.Mutators[i:]...)...)
+			return
+		}
+	}
+	p.Mutators = append(p.Mutators, m)
+}
+
+// addGroundMutators adds to the pipeline the mutators that depend on the height of the points above the ground, as set
+// by WithHeightRules and WithHeightAboveGround, reading the ground surface from the input files if any is needed
+func (t *GoCesiumTiler) addGroundMutators(mutators *mutator.Pipeline, inputLasFiles []string, epsgCode int, opts *TilerOptions) error {
+	if opts.heightAboveGround == nil && len(opts.heightRules) == 0 {
+		return nil
+	}
+	ground, err := t.readGroundSurface(inputLasFiles, epsgCode, opts, mutators)
+	if err != nil {
+		return err
+	}
+	if len(opts.heightRules) > 0 {
+		rules := make([]mutator.HeightClass, len(opts.heightRules))
+		for i, r := range opts.heightRules {
+			rules[i] = mutator.HeightClass{Min: r.Min, Max: r.Max, Classification: r.Classification}
+		}
+		insertBeforeClassColor(mutators, mutator.NewHeightReclassification(ground, rules))
+	}
+	if h := opts.heightAboveGround; h != nil {
+		mutators.Mutators = append(mutators.Mutators, mutator.NewHeightAboveGround(ground, h.min, h.max))
+	}
+	return nil
+}
+
+// readGroundSurface reads the input files computing the ground surface used by WithHeightAboveGround and
+// WithHeightRules from the ground points. The points are transformed by the given mutators first, so that the
+// surface matches the points being filtered.
+func (t *GoCesiumTiler) readGroundSurface(inputLasFiles []string, epsgCode int, opts *TilerOptions, m mutator.Mutator) (*mutator.GroundSurface, error) {
+	var reader las.LasReader
+	reader, err := t.lasReaderProvider(inputLasFiles, epsgCode, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer closeReader(reader)
+	if opts.externalClassification != "" {
+		labels, err := las.NewLabelReader(reader, opts.externalClassification)
+		if err != nil {
+			return nil, err
+		}
+		defer labels.Close()
+		reader = labels
+	}
+	ground := mutator.NewGroundSurface(groundCellSize)
+	for i := 0; i < reader.NumberOfPoints(); i++ {
+		pt, err := reader.GetNext()
+		if errors.Is(err, las.ErrNoMorePoints) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		pt, ok := m.Mutate(pt)
+		if ok && pt.Classification == mutator.GroundClassification {
+			ground.Add(pt.X, pt.Y, pt.Z)
+		}
+	}
+	return ground, nil
+}
+
+// newRasterSampler returns a function sampling the given band of the raster with a bilinear interpolation.
+// If the raster declares a CRS different from the one of the points, the coordinates are reprojected first.
+func newRasterSampler(g *raster.GeoTiff, band int, epsgCode int, conv coor.CoordinateConverter) mutator.Sampler {
+	return func(x, y float64) (float64, bool) {
+		x, y, ok := toRasterCrs(g, epsgCode, conv, x, y)
+		if !ok {
+			return 0, false
+		}
+		return g.Bilinear(band, x, y)
+	}
+}
+
+// newRasterColorSampler returns a function sampling the first three bands of the raster, as red, green and blue,
+// with a bilinear interpolation. The coordinates are reprojected as done by newRasterSampler, once for all bands.
+func newRasterColorSampler(g *raster.GeoTiff, epsgCode int, conv coor.CoordinateConverter) mutator.ColorSampler {
+	return func(x, y float64) ([3]float64, bool) {
+		var c [3]float64
+		x, y, ok := toRasterCrs(g, epsgCode, conv, x, y)
+		if !ok {
+			return c, false
+		}
+		for band := range c {
+			if c[band], ok = g.Bilinear(band, x, y); !ok {
+				return c, false
+			}
+		}
+		return c, true
+	}
+}
+
+// toRasterCrs reprojects the given coordinates to the CRS of the raster, if it declares one different from the
+// one of the points
+func toRasterCrs(g *raster.GeoTiff, epsgCode int, conv coor.CoordinateConverter, x, y float64) (float64, float64, bool) {
+	if g.Epsg == 0 || g.Epsg == epsgCode {
+		return x, y, true
+	}
+	c, err := conv.ToSrid(epsgCode, g.Epsg, geom.Coord{X: x, Y: y})
+	if err != nil {
+		return 0, 0, false
+	}
+	return c.X, c.Y, true
+}
+
+// checkOutputRegion verifies that the region set with WithOutputRegion, if any, is a valid geographic area
+func checkOutputRegion(r *Region) error {
+	if r == nil {
+		return nil
+	}
+	if r.West > r.East || r.South > r.North {
+		return fmt.Errorf("invalid output region: west %v must not exceed east %v and south %v must not exceed north %v", r.West, r.East, r.South, r.North)
+	}
+	if r.West < -180 || r.East > 180 || r.South < -90 || r.North > 90 {
+		return fmt.Errorf("invalid output region: the longitudes must be within [-180, 180] and the latitudes within [-90, 90] degrees")
+	}
+	return nil
+}
+
+// checkContentExtension verifies that the extension set with WithContentExtension, if any, can name the content files:
+// it must not contain path separators and must differ from the .json extension of the tileset files
+func checkContentExtension(ext string) error {
+	if strings.ContainsAny(ext, "/\\") {
+		return fmt.Errorf("invalid content extension %s: must not contain path separators", ext)
+	}
+	if strings.EqualFold(strings.TrimPrefix(ext, "."), "json") {
+		return fmt.Errorf("invalid content extension %s: reserved for the tileset files", ext)
+	}
+	return nil
+}
+
+// assetExtras returns the data stamped in the asset.extras property of the root tileset.json
+func assetExtras() map[string]interface{} {
+	return map[string]interface{}{
+		"gocesiumtiler": GetBuildInfo(),
+	}
+}
+
+// fileErrorDesc returns the name of the file that caused the error, if known, otherwise the given default description
+func fileErrorDesc(err error, desc string) string {
+	var fileErr *las.FileError
+	if errors.As(err, &fileErr) {
+		return fileErr.File
+	}
+	return desc
+}
+
+// fileTrackingReader wraps a reader of multiple files, sending the file name to the changes channel
+// every time the reader moves to a new file
+type fileTrackingReader struct {
+	las.LasReader
+	tracker las.FileTracker
+	current string
+	changes chan<- string
+}
+
+func (r *fileTrackingReader) GetNext() (geom.Point64, error) {
+	pt, err := r.LasReader.GetNext()
+	if f := r.tracker.CurrentFile(); f != r.current {
+		r.current = f
+		r.changes <- f
+	}
+	return pt, err
+}
+
 func emitEvent(e TilerEvent, opts *TilerOptions, start time.Time, inputDesc string, msg string) {
+	if opts.callback == nil && opts.progressWriter == nil {
+		return
+	}
+	elapsed := opts.clock.Now().Sub(start).Milliseconds()
 	if opts.callback != nil {
-		opts.callback(e, inputDesc, time.Since(start).Milliseconds(), msg)
+		opts.callback(e, inputDesc, elapsed, msg)
+	}
+	opts.progressWriter.event(e, inputDesc, elapsed, msg)
+}
+
+// newElevationConverter returns the converter applying the elevation offset and, if required, the conversion from
+// geoid to ellipsoidal heights to the elevations of the input points
+func (t *GoCesiumTiler) newElevationConverter(epsgCode int, opts *TilerOptions) (elev.ElevationConverter, error) {
+	elevationConverters := []elev.ElevationConverter{
+		elev.NewOffsetElevationConverter(opts.elevationOffset),
+	}
+	geoid, err := useGeoidModel(opts)
+	if err != nil {
+		return nil, err
 	}
+	if geoid {
+		var geoidCalc geoid2ellipsoid.Calculator
+		if opts.geoidGrid != "" {
+			geoidCalc, err = geoid2ellipsoid.NewGridCalculator(opts.geoidGrid, t.cconv)
+		} else {
+			geoidCalc, err = geoid2ellipsoid.NewEGMCalculator(t.cconv)
+		}
+		if err != nil {
+			return nil, err
+		}
+		elevationConverters = append(elevationConverters, elev.NewGeoidElevationConverter(epsgCode, geoidCalc))
+	}
+	return elev.NewPipelineElevationCorrector(elevationConverters...), nil
+}
+
+// geoidVerticalEpsgCodes are the vertical CRSs whose heights are referred to the geoid of the built-in EGM96 model.
+// EGM2008 heights (EPSG:3855) differ from EGM96 ones by up to a metre, hence they require an EGM2008 geoid grid.
+var geoidVerticalEpsgCodes = map[int]bool{
+	5773: true, // EGM96 height
+}
+
+// useGeoidModel returns true if the input elevations have to be converted from geoid to ellipsoidal heights,
+// either because requested explicitly or because implied by the vertical CRS or the geoid grid
+func useGeoidModel(opts *TilerOptions) (bool, error) {
+	if opts.geoidGrid != "" {
+		return true, nil
+	}
+	if opts.verticalEpsg == 0 {
+		return opts.geoidElevation, nil
+	}
+	if !geoidVerticalEpsgCodes[opts.verticalEpsg] {
+		return false, fmt.Errorf("unsupported vertical datum EPSG:%d, only EPSG:5773 is supported without a geoid grid", opts.verticalEpsg)
+	}
+	return true, nil
+}
+
+// phaseTimer measures the duration of the consecutive phases of the processing, reporting them to the callback
+type phaseTimer struct {
+	start    time.Time
+	clock    utils.Clock
+	callback PhaseCallback
+}
+
+// end returns the time elapsed since the end of the previous phase, or the start of the processing
+func (p *phaseTimer) end(phase Phase) time.Duration {
+	now := p.clock.Now()
+	elapsed := now.Sub(p.start)
+	p.start = now
+	p.report(phase, elapsed)
+	return elapsed
+}
+
+func (p *phaseTimer) report(phase Phase, elapsed time.Duration) {
+	if p.callback != nil {
+		p.callback(phase, elapsed)
+	}
+}
+
+// timedConverter wraps a coordinate converter measuring the total time spent in the conversions.
+// It is safe for concurrent use if the wrapped converter is.
+type timedConverter struct {
+	coor.CoordinateConverter
+	clock utils.Clock
+	nanos atomic.Int64
+	// parent is the converter this one was forked from, measuring the time of the fork too
+	parent *timedConverter
+	// release releases the fork of the wrapped converter, nil if not forked
+	release func()
+}
+
+// Fork returns a converter wrapping a fork of the wrapped converter, if it implements coor.Forker, whose
+// conversion time is added to the one of c
+func (c *timedConverter) Fork() coor.CoordinateConverter {
+	forked, release := coor.Fork(c.CoordinateConverter)
+	return &timedConverter{CoordinateConverter: forked, clock: c.clock, parent: c, release: release}
+}
+
+// Cleanup releases the fork of the wrapped converter if c was forked, or cleans up the wrapped converter otherwise
+func (c *timedConverter) Cleanup() {
+	if c.release != nil {
+		c.release()
+		return
+	}
+	c.CoordinateConverter.Cleanup()
+}
+
+func (c *timedConverter) ToSrid(sourceSrid int, targetSrid int, coord geom.Coord) (geom.Coord, error) {
+	start := c.clock.Now()
+	defer c.add(start)
+	return c.CoordinateConverter.ToSrid(sourceSrid, targetSrid, coord)
+}
+
+func (c *timedConverter) ToWGS84Cartesian(coord geom.Coord, sourceSrid int) (geom.Coord, error) {
+	start := c.clock.Now()
+	defer c.add(start)
+	return c.CoordinateConverter.ToWGS84Cartesian(coord, sourceSrid)
+}
+
+func (c *timedConverter) add(start time.Time) {
+	if c.parent != nil {
+		c.parent.add(start)
+		return
+	}
+	c.nanos.Add(int64(c.clock.Now().Sub(start)))
+}
+
+func (c *timedConverter) elapsed() time.Duration {
+	return time.Duration(c.nanos.Load())
 }
@@ -2,13 +2,23 @@ package tiler
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/mfbonfigli/gocesiumtiler/v2/internal/conv/coor"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/conv/elev"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
 	"github.com/mfbonfigli/gocesiumtiler/v2/internal/las"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/mutator"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/raster"
 	"github.com/mfbonfigli/gocesiumtiler/v2/internal/tree"
 	"github.com/mfbonfigli/gocesiumtiler/v2/internal/utils"
 	"github.com/mfbonfigli/gocesiumtiler/v2/internal/writer"
@@ -19,7 +29,7 @@ func TestTilerDefaults(t *testing.T) {
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	tr := tiler.treeProvider(NewDefaultTilerOptions())
+	tr := tiler.treeProvider(NewDefaultTilerOptions(), nil)
 	switch tr.(type) {
 	case *tree.GridTreeNode:
 	default:
@@ -27,7 +37,7 @@ func TestTilerDefaults(t *testing.T) {
 	}
 	// this returns an error due to a non-esitant path
 	// but we ignore it on purpose for the sake of this test
-	l, _ := tiler.lasReaderProvider([]string{""}, 123, true)
+	l, _ := tiler.lasReaderProvider([]string{""}, 123, NewDefaultTilerOptions())
 	switch l.(type) {
 	case *las.CombinedFileLasReader:
 	default:
@@ -35,7 +45,7 @@ func TestTilerDefaults(t *testing.T) {
 	}
 	// this returns an error due to a non-esitant path
 	// but we ignore it on purpose for the sake of this test
-	w, err := tiler.writerProvider("", nil, NewDefaultTilerOptions())
+	w, err := tiler.writerProvider("", nil, nil, nil, NewDefaultTilerOptions(), nil)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -56,13 +66,13 @@ func TestTilerProcessFile(t *testing.T) {
 	l := &las.MockLasReader{}
 	opts := NewDefaultTilerOptions()
 	c := context.TODO()
-	tiler.writerProvider = func(folder string, c coor.CoordinateConverter, opts *TilerOptions) (writer.Writer, error) {
+	tiler.writerProvider = func(folder string, inputFiles []string, reader las.LasReader, c coor.CoordinateConverter, opts *TilerOptions, progress writer.ProgressFunc) (writer.Writer, error) {
 		return w, nil
 	}
-	tiler.treeProvider = func(opts *TilerOptions) tree.Tree {
+	tiler.treeProvider = func(opts *TilerOptions, m mutator.Mutator) tree.Tree {
 		return tr
 	}
-	tiler.lasReaderProvider = func(inputLasFiles []string, epsgCode int, eightbit bool) (las.LasReader, error) {
+	tiler.lasReaderProvider = func(inputLasFiles []string, epsgCode int, opts *TilerOptions) (las.LasReader, error) {
 		return l, nil
 	}
 
@@ -109,14 +119,14 @@ func TestTilerProcessFolder(t *testing.T) {
 	l := &las.MockLasReader{}
 	opts := NewDefaultTilerOptions()
 	c := context.TODO()
-	tiler.writerProvider = func(folder string, c coor.CoordinateConverter, opts *TilerOptions) (writer.Writer, error) {
+	tiler.writerProvider = func(folder string, inputFiles []string, reader las.LasReader, c coor.CoordinateConverter, opts *TilerOptions, progress writer.ProgressFunc) (writer.Writer, error) {
 		return w, nil
 	}
-	tiler.treeProvider = func(opts *TilerOptions) tree.Tree {
+	tiler.treeProvider = func(opts *TilerOptions, m mutator.Mutator) tree.Tree {
 		return tr
 	}
 	files := []string{}
-	tiler.lasReaderProvider = func(inputLasFiles []string, epsgCode int, eightbit bool) (las.LasReader, error) {
+	tiler.lasReaderProvider = func(inputLasFiles []string, epsgCode int, opts *TilerOptions) (las.LasReader, error) {
 		files = append(files, inputLasFiles...)
 		return l, nil
 	}
@@ -170,3 +180,2259 @@ func TestTilerProcessFolder(t *testing.T) {
 		t.Errorf("expected files processed %v, got %v", files, expected)
 	}
 }
+
+func TestTilerProcessFolderWithPattern(t *testing.T) {
+	tiler, err := NewGoCesiumTiler()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tiler.writerProvider = func(folder string, inputFiles []string, reader las.LasReader, c coor.CoordinateConverter, opts *TilerOptions, progress writer.ProgressFunc) (writer.Writer, error) {
+		return &writer.MockWriter{}, nil
+	}
+	tiler.treeProvider = func(opts *TilerOptions, m mutator.Mutator) tree.Tree {
+		return &tree.MockNode{}
+	}
+	files := []string{}
+	tiler.lasReaderProvider = func(inputLasFiles []string, epsgCode int, opts *TilerOptions) (las.LasReader, error) {
+		files = append(files, inputLasFiles...)
+		return &las.MockLasReader{}, nil
+	}
+
+	tmp, err := os.MkdirTemp(os.TempDir(), "tst")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(tmp)
+	})
+	utils.TouchFile(filepath.Join(tmp, "tile_001.las"))
+	utils.TouchFile(filepath.Join(tmp, "tile_002.LAS"))
+	utils.TouchFile(filepath.Join(tmp, "tile_010.las"))
+	utils.TouchFile(filepath.Join(tmp, "tile_003.xyz"))
+	err = tiler.ProcessFolder(tmp, "out", 123, NewTilerOptions(WithFilePattern("tile_00*.las")), context.TODO())
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	expected := []string{
+		filepath.Join(tmp, "tile_001.las"),
+		filepath.Join(tmp, "tile_002.LAS"),
+	}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("expected files processed %v, got %v", expected, files)
+	}
+
+	if err := tiler.ProcessFolder(tmp, "out", 123, NewTilerOptions(WithFilePattern("[")), context.TODO()); err == nil {
+		t.Errorf("expected error on malformed pattern, got none")
+	}
+	if err := tiler.ProcessFolder(tmp, "out", 123, NewTilerOptions(WithFilePattern("other*")), context.TODO()); err == nil {
+		t.Errorf("expected error when no file matches the pattern, got none")
+	}
+}
+
+func TestTilerProcessFolderRecursive(t *testing.T) {
+	tiler, err := NewGoCesiumTiler()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	folders := []string{}
+	tiler.writerProvider = func(folder string, inputFiles []string, reader las.LasReader, c coor.CoordinateConverter, opts *TilerOptions, progress writer.ProgressFunc) (writer.Writer, error) {
+		folders = append(folders, folder)
+		return &writer.MockWriter{}, nil
+	}
+	tiler.treeProvider = func(opts *TilerOptions, m mutator.Mutator) tree.Tree {
+		return &tree.MockNode{}
+	}
+	tiler.lasReaderProvider = func(inputLasFiles []string, epsgCode int, opts *TilerOptions) (las.LasReader, error) {
+		return &las.MockLasReader{}, nil
+	}
+
+	tmp := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tmp, "a", "b"), 0777); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	utils.TouchFile(filepath.Join(tmp, "scan.las"))
+	utils.TouchFile(filepath.Join(tmp, "a", "scan.las"))
+	utils.TouchFile(filepath.Join(tmp, "a", "b", "scan.las"))
+	if err := tiler.ProcessFolder(tmp, "out", 123, NewTilerOptions(WithRecursive(true)), context.TODO()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	expected := []string{
+		filepath.Join("out", "a", "b", "scan"),
+		filepath.Join("out", "a", "scan"),
+		filepath.Join("out", "scan"),
+	}
+	if !reflect.DeepEqual(folders, expected) {
+		t.Errorf("expected tileset folders %v, got %v", expected, folders)
+	}
+
+	folders = []string{}
+	if err := tiler.ProcessFolder(tmp, "out", 123, NewDefaultTilerOptions(), context.TODO()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if expected := []string{filepath.Join("out", "scan")}; !reflect.DeepEqual(folders, expected) {
+		t.Errorf("expected tileset folders %v, got %v", expected, folders)
+	}
+}
+
+// drainingTree is a mock tree that reads all points from the reader on load
+type drainingTree struct {
+	tree.MockNode
+}
+
+func (d *drainingTree) Load(l las.LasReader, c coor.CoordinateConverter, e elev.ElevationConverter, ctx context.Context) error {
+	for i := 0; i < l.NumberOfPoints(); i++ {
+		if _, err := l.GetNext(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestTilerProcessFilesJoinTracksCurrentFile(t *testing.T) {
+	tiler, err := NewGoCesiumTiler()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tiler.writerProvider = func(folder string, inputFiles []string, reader las.LasReader, c coor.CoordinateConverter, opts *TilerOptions, progress writer.ProgressFunc) (writer.Writer, error) {
+		return &writer.MockWriter{}, nil
+	}
+	tiler.treeProvider = func(opts *TilerOptions, m mutator.Mutator) tree.Tree {
+		return &drainingTree{}
+	}
+	files := []string{"./internal/las/testdata/las-12-pf1.las", "./internal/las/testdata/las-12-pf2.las"}
+	loaded := []string{}
+	opts := NewTilerOptions(WithCallback(func(event TilerEvent, inputDesc string, elapsed int64, msg string) {
+		if event == EventPointLoadingFileStarted {
+			loaded = append(loaded, inputDesc)
+		}
+	}))
+	if err := tiler.ProcessFiles(files, "out", 32633, opts, context.TODO()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !reflect.DeepEqual(loaded, files) {
+		t.Errorf("expected file events for %v got %v", files, loaded)
+	}
+}
+
+func TestTilerProcessFilesVirtualCloud(t *testing.T) {
+	tiler, err := NewGoCesiumTiler()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var sourceFiles []string
+	var numPts int
+	tiler.writerProvider = func(folder string, inputFiles []string, reader las.LasReader, c coor.CoordinateConverter, opts *TilerOptions, progress writer.ProgressFunc) (writer.Writer, error) {
+		sourceFiles = inputFiles
+		numPts = reader.NumberOfPoints()
+		return &writer.MockWriter{}, nil
+	}
+	tiler.treeProvider = func(opts *TilerOptions, m mutator.Mutator) tree.Tree {
+		return &drainingTree{}
+	}
+	dir := t.TempDir()
+	files := []string{}
+	for _, f := range []string{"./internal/las/testdata/las-12-pf1.las", "./internal/las/testdata/las-12-pf2.las"} {
+		abs, err := filepath.Abs(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, abs)
+	}
+	descriptor := filepath.Join(dir, "cloud.json")
+	content := fmt.Sprintf(`{"files": [{"path": %q}, {"path": %q, "offset": [10, 0, 0]}]}`, filepath.ToSlash(files[0]), filepath.ToSlash(files[1]))
+	if err := os.WriteFile(descriptor, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	loaded := []string{}
+	opts := NewTilerOptions(WithCallback(func(event TilerEvent, inputDesc string, elapsed int64, msg string) {
+		if event == EventPointLoadingFileStarted {
+			loaded = append(loaded, inputDesc)
+		}
+	}))
+	if err := tiler.ProcessFiles([]string{descriptor}, "out", 32633, opts, context.TODO()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !reflect.DeepEqual(sourceFiles, files) {
+		t.Errorf("expected source files %v got %v", files, sourceFiles)
+	}
+	if !reflect.DeepEqual(loaded, files) {
+		t.Errorf("expected file events for %v got %v", files, loaded)
+	}
+	if numPts != 20 {
+		t.Errorf("expected 20 points got %d", numPts)
+	}
+}
+
+// closingReader is a LAS reader recording whether it has been closed
+type closingReader struct {
+	las.MockLasReader
+	closed bool
+}
+
+func (r *closingReader) Close() error {
+	r.closed = true
+	return nil
+}
+
+func TestTilerProcessFilesMaxOpenFiles(t *testing.T) {
+	tiler, err := NewGoCesiumTiler()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var numPts int
+	tiler.writerProvider = func(folder string, inputFiles []string, reader las.LasReader, c coor.CoordinateConverter, opts *TilerOptions, progress writer.ProgressFunc) (writer.Writer, error) {
+		numPts = reader.NumberOfPoints()
+		return &writer.MockWriter{}, nil
+	}
+	// the tree fails if a point cannot be read
+	tiler.treeProvider = func(opts *TilerOptions, m mutator.Mutator) tree.Tree {
+		return &drainingTree{}
+	}
+	files := []string{"./internal/las/testdata/las-12-pf1.las", "./internal/las/testdata/las-12-pf2.las", "./internal/las/testdata/las-13-pf1.las"}
+	if err := tiler.ProcessFiles(files, "out", 32633, NewTilerOptions(WithMaxOpenFiles(1)), context.TODO()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if numPts != 30 {
+		t.Errorf("expected 30 points got %d", numPts)
+	}
+
+	reader := &closingReader{}
+	tiler.lasReaderProvider = func(inputLasFiles []string, epsgCode int, opts *TilerOptions) (las.LasReader, error) {
+		return reader, nil
+	}
+	if err := tiler.ProcessFiles([]string{"abc.las"}, "out", 32633, NewTilerOptions(), context.TODO()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !reader.closed {
+		t.Errorf("expected the reader to be closed")
+	}
+}
+
+// closingExtentReader is a LAS reader declaring its extent and recording whether it has been closed
+type closingExtentReader struct {
+	extentReader
+	closed bool
+}
+
+func (r *closingExtentReader) Close() error {
+	r.closed = true
+	return nil
+}
+
+func TestTilerComputeBoundsClosesReader(t *testing.T) {
+	tiler, err := NewGoCesiumTiler()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tiler.cconv = &bulgingConverter{}
+	reader := &closingExtentReader{extentReader: extentReader{extent: [4]float64{0, 10, 1, 11}}}
+	tiler.lasReaderProvider = func(inputLasFiles []string, epsgCode int, opts *TilerOptions) (las.LasReader, error) {
+		return reader, nil
+	}
+	if _, err := tiler.ComputeBounds([]string{"a.las"}, 32633); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !reader.closed {
+		t.Errorf("expected the reader to be closed")
+	}
+
+	noExtent := &closingReader{}
+	tiler.lasReaderProvider = func(inputLasFiles []string, epsgCode int, opts *TilerOptions) (las.LasReader, error) {
+		return noExtent, nil
+	}
+	if _, err := tiler.ComputeBounds([]string{"a.las"}, 32633); err == nil {
+		t.Fatalf("expected error for a reader without extent, got none")
+	}
+	if !noExtent.closed {
+		t.Errorf("expected the reader to be closed on error")
+	}
+}
+
+func TestTilerLoadAttributes(t *testing.T) {
+	tiler, err := NewGoCesiumTiler()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	files := []string{"./internal/las/testdata/las-12-pf2.las"}
+	all, err := tiler.lasReaderProvider(files, 32633, NewDefaultTilerOptions())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	colors, err := tiler.lasReaderProvider(files, 32633, NewTilerOptions(WithLoadAttributes(AttrColor)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 0; i < all.NumberOfPoints(); i++ {
+		expected, err := all.GetNext()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		actual, err := colors.GetNext()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected.Intensity, expected.Classification, expected.Flags, expected.GpsTime, expected.PointSourceId = 0, 0, 0, 0, 0
+		if actual != expected {
+			t.Fatalf("expected point %v got %v", expected, actual)
+		}
+	}
+}
+
+// failingTree is a mock tree that reads all points from the reader on load, then fails
+// with an error referring to the first point
+type failingTree struct {
+	tree.MockNode
+}
+
+func (f *failingTree) Load(l las.LasReader, c coor.CoordinateConverter, e elev.ElevationConverter, ctx context.Context) error {
+	first, err := l.GetNext()
+	if err != nil {
+		return err
+	}
+	for i := 1; i < l.NumberOfPoints(); i++ {
+		if _, err := l.GetNext(); err != nil {
+			return err
+		}
+	}
+	return &tree.PointError{Point: first, Err: fmt.Errorf("mock error")}
+}
+
+func TestTilerProcessFilesJoinReportsPointFile(t *testing.T) {
+	tiler, err := NewGoCesiumTiler()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tiler.treeProvider = func(opts *TilerOptions, m mutator.Mutator) tree.Tree {
+		return &failingTree{}
+	}
+	files := []string{"./internal/las/testdata/las-12-pf1.las", "./internal/las/testdata/las-12-pf2.las"}
+	failed := ""
+	opts := NewTilerOptions(WithCallback(func(event TilerEvent, inputDesc string, elapsed int64, msg string) {
+		if event == EventPointLoadingError {
+			failed = inputDesc
+		}
+	}))
+	err = tiler.ProcessFiles(files, "out", 32633, opts, context.TODO())
+	if err == nil {
+		t.Fatalf("expected error, got none")
+	}
+	// the reader moved on to the second file but the failing point belongs to the first one
+	if failed != files[0] {
+		t.Errorf("expected error event for %s got %s", files[0], failed)
+	}
+}
+
+func TestTilerProcessFilesJoinReportsFailingFile(t *testing.T) {
+	tiler, err := NewGoCesiumTiler()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	files := []string{"./internal/las/testdata/las-12-pf1.las", "./internal/las/testdata/missing.las"}
+	failed := ""
+	opts := NewTilerOptions(WithCallback(func(event TilerEvent, inputDesc string, elapsed int64, msg string) {
+		if event == EventReadLasHeaderError {
+			failed = inputDesc
+		}
+	}))
+	err = tiler.ProcessFiles(files, "out", 32633, opts, context.TODO())
+	if err == nil {
+		t.Fatalf("expected error, got none")
+	}
+	if !strings.Contains(err.Error(), files[1]) {
+		t.Errorf("expected error to reference %s, got %v", files[1], err)
+	}
+	if failed != files[1] {
+		t.Errorf("expected error event for %s got %s", files[1], failed)
+	}
+}
+
+func TestMutatorPipeline(t *testing.T) {
+	p, _ := newMutatorPipeline(NewDefaultTilerOptions(), 0, nil, &runResources{}, nil)
+	if actual := len(p.Mutators); actual != 0 {
+		t.Errorf("expected no mutators by default, got %d", actual)
+	}
+
+	p, _ = newMutatorPipeline(NewTilerOptions(WithDropWithheld(true), WithDropOverlap(true)), 0, nil, &runResources{}, nil)
+	cases := []struct {
+		flags    uint8
+		expected bool
+	}{
+		{0, true},
+		{geom.FlagSynthetic, true},
+		{geom.FlagWithheld, false},
+		{geom.FlagOverlap, false},
+	}
+	for _, c := range cases {
+		if _, actual := p.Mutate(geom.Point64{Flags: c.flags}); actual != c.expected {
+			t.Errorf("for flags %b expected %v got %v", c.flags, c.expected, actual)
+		}
+	}
+}
+
+func TestMutatorPipelineElevationClamp(t *testing.T) {
+	p, _ := newMutatorPipeline(NewTilerOptions(WithElevationClamp(0, 10)), 0, nil, &runResources{}, nil)
+	if _, keep := p.Mutate(geom.Point64{Z: 11}); keep {
+		t.Errorf("expected point to be discarded")
+	}
+
+	p, _ = newMutatorPipeline(NewTilerOptions(WithElevationClampMode(0, 10, ClampToRange)), 0, nil, &runResources{}, nil)
+	pt, keep := p.Mutate(geom.Point64{Z: 11})
+	if !keep {
+		t.Errorf("expected point to be kept")
+	}
+	if pt.Z != 10 {
+		t.Errorf("expected Z %v got %v", 10, pt.Z)
+	}
+
+	if _, err := newMutatorPipeline(NewTilerOptions(WithElevationClamp(10, 0)), 0, nil, &runResources{}, nil); err == nil {
+		t.Errorf("expected error for inverted range, got none")
+	}
+}
+
+func TestMutatorPipelineClassificationRemap(t *testing.T) {
+	p, _ := newMutatorPipeline(NewTilerOptions(WithClassificationRemap(map[uint8]uint8{40: 2})), 0, nil, &runResources{}, nil)
+	if pt, _ := p.Mutate(geom.Point64{Classification: 40}); pt.Classification != 2 {
+		t.Errorf("expected class %v got %v", 2, pt.Classification)
+	}
+	if pt, _ := p.Mutate(geom.Point64{Classification: 6}); pt.Classification != 6 {
+		t.Errorf("expected class %v got %v", 6, pt.Classification)
+	}
+}
+
+func TestMutatorPipelineColorByClassification(t *testing.T) {
+	opts := NewTilerOptions(
+		WithClassificationRemap(map[uint8]uint8{40: 6}),
+		WithColorGamma(2),
+		WithColorByClassification(true),
+		WithClassificationPalette(map[uint8][3]uint8{6: {10, 20, 30}}),
+	)
+	p, _ := newMutatorPipeline(opts, 0, nil, &runResources{}, nil)
+	// the palette applies to the remapped class and is not altered by the gamma correction
+	if pt, _ := p.Mutate(geom.Point64{Classification: 40}); pt.R != 10 || pt.G != 20 || pt.B != 30 {
+		t.Errorf("expected color {10 20 30} got {%d %d %d}", pt.R, pt.G, pt.B)
+	}
+	p, _ = newMutatorPipeline(NewTilerOptions(WithClassificationPalette(map[uint8][3]uint8{6: {10, 20, 30}})), 0, nil, &runResources{}, nil)
+	if pt, _ := p.Mutate(geom.Point64{R: 1, G: 2, B: 3, Classification: 6}); pt.R != 1 || pt.G != 2 || pt.B != 3 {
+		t.Errorf("expected the palette to be ignored unless coloring by classification, got {%d %d %d}", pt.R, pt.G, pt.B)
+	}
+}
+
+func TestMutatorPipelineClassificationColorFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "palette.csv")
+	if err := os.WriteFile(file, []byte("class,color\n6,#0a141e\n2,#010203\n"), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	opts := NewTilerOptions(
+		WithClassificationColorFile(file),
+		WithClassificationPalette(map[uint8][3]uint8{2: {40, 50, 60}}),
+	)
+	p, err := newMutatorPipeline(opts, 0, nil, &runResources{}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pt, _ := p.Mutate(geom.Point64{Classification: 6}); pt.R != 10 || pt.G != 20 || pt.B != 30 {
+		t.Errorf("expected color {10 20 30} got {%d %d %d}", pt.R, pt.G, pt.B)
+	}
+	// the palette set in code takes precedence over the file
+	if pt, _ := p.Mutate(geom.Point64{Classification: 2}); pt.R != 40 || pt.G != 50 || pt.B != 60 {
+		t.Errorf("expected color {40 50 60} got {%d %d %d}", pt.R, pt.G, pt.B)
+	}
+	if err := os.WriteFile(file, []byte("6,#0a141e\n2,blue\n"), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := newMutatorPipeline(opts, 0, nil, &runResources{}, nil); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected error reporting line 2, got %v", err)
+	}
+}
+
+func TestMutatorPipelineExtraDimensionFilter(t *testing.T) {
+	p, err := newMutatorPipeline(NewTilerOptions(WithExtraDimensionFilter("confidence", 0.5, 1)), 0, nil, &runResources{}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, keep := p.Mutate(geom.Point64{Extra: 0.7}); !keep {
+		t.Errorf("expected point to be kept")
+	}
+	if _, keep := p.Mutate(geom.Point64{Extra: 0.2}); keep {
+		t.Errorf("expected point to be discarded")
+	}
+	if _, err := newMutatorPipeline(NewTilerOptions(WithExtraDimensionFilter("confidence", 1, 0)), 0, nil, &runResources{}, nil); err == nil {
+		t.Errorf("expected error for min greater than max, got none")
+	}
+}
+
+func TestMutatorPipelinePointSourceFilter(t *testing.T) {
+	p, err := newMutatorPipeline(NewTilerOptions(WithPointSourceFilter([]uint16{3, 7})), 0, nil, &runResources{}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, keep := p.Mutate(geom.Point64{PointSourceId: 7}); !keep {
+		t.Errorf("expected point to be kept")
+	}
+	if _, keep := p.Mutate(geom.Point64{PointSourceId: 4}); keep {
+		t.Errorf("expected point to be discarded")
+	}
+	p, _ = newMutatorPipeline(NewDefaultTilerOptions(), 0, nil, &runResources{}, nil)
+	if actual := len(p.Mutators); actual != 0 {
+		t.Errorf("expected no mutators by default, got %d", actual)
+	}
+}
+
+func TestMutatorPipelineColorGamma(t *testing.T) {
+	p, _ := newMutatorPipeline(NewTilerOptions(WithColorGamma(1)), 0, nil, &runResources{}, nil)
+	if actual := len(p.Mutators); actual != 0 {
+		t.Errorf("expected no mutators for unit gamma, got %d", actual)
+	}
+	p, _ = newMutatorPipeline(NewTilerOptions(WithColorGamma(2.2)), 0, nil, &runResources{}, nil)
+	if pt, _ := p.Mutate(geom.Point64{G: 100}); pt.G != 167 {
+		t.Errorf("expected G %v got %v", 167, pt.G)
+	}
+	for _, gamma := range []float64{0, -1} {
+		if _, err := newMutatorPipeline(NewTilerOptions(WithColorGamma(gamma)), 0, nil, &runResources{}, nil); err == nil {
+			t.Errorf("expected error for gamma %v, got none", gamma)
+		}
+	}
+}
+
+func TestMutatorPipelineColorMatrix(t *testing.T) {
+	p, _ := newMutatorPipeline(NewDefaultTilerOptions(), 0, nil, &runResources{}, nil)
+	if actual := len(p.Mutators); actual != 0 {
+		t.Errorf("expected no mutators for the identity matrix, got %d", actual)
+	}
+	// the matrix applies before the gamma correction
+	p, _ = newMutatorPipeline(NewTilerOptions(WithColorMatrix([9]float64{0, 1, 0, 0, 0, 0, 0, 0, 0}), WithColorGamma(2.2)), 0, nil, &runResources{}, nil)
+	if pt, _ := p.Mutate(geom.Point64{R: 50, G: 100}); pt.R != 167 || pt.G != 0 {
+		t.Errorf("expected R %v and G %v got %v and %v", 167, 0, pt.R, pt.G)
+	}
+}
+
+func TestMutatorPipelineDefaultColor(t *testing.T) {
+	// the default color applies after the gamma correction and is overridden by the classification colors
+	p, _ := newMutatorPipeline(NewTilerOptions(WithDefaultColor(128, 128, 128), WithColorGamma(2.2)), 0, nil, &runResources{}, nil)
+	if pt, _ := p.Mutate(geom.Point64{}); pt.R != 128 || pt.G != 128 || pt.B != 128 {
+		t.Errorf("expected the default color got {%d %d %d}", pt.R, pt.G, pt.B)
+	}
+	p, _ = newMutatorPipeline(NewTilerOptions(WithDefaultColor(128, 128, 128), WithColorByClassification(true)), 0, nil, &runResources{}, nil)
+	if pt, _ := p.Mutate(geom.Point64{Classification: 2}); pt.R == 128 && pt.G == 128 && pt.B == 128 {
+		t.Errorf("expected the classification color got {%d %d %d}", pt.R, pt.G, pt.B)
+	}
+}
+
+func TestMutatorPipelineColorBits(t *testing.T) {
+	// the reduction applies to the classification colors too
+	p, _ := newMutatorPipeline(NewTilerOptions(WithColorBits(16), WithColorByClassification(true), WithClassificationPalette(map[uint8][3]uint8{2: {100, 100, 100}})), 0, nil, &runResources{}, nil)
+	if pt, _ := p.Mutate(geom.Point64{Classification: 2}); pt.R != 99 || pt.G != 101 || pt.B != 99 {
+		t.Errorf("expected {99 101 99} got {%d %d %d}", pt.R, pt.G, pt.B)
+	}
+	for _, bits := range []int{0, 2, 25} {
+		if _, err := newMutatorPipeline(NewTilerOptions(WithColorBits(bits)), 0, nil, &runResources{}, nil); err == nil {
+			t.Errorf("expected error for %d color bits, got none", bits)
+		}
+	}
+}
+
+// elevationRangeReader is a mock reader reporting an elevation range
+type elevationRangeReader struct {
+	las.MockLasReader
+	minZ, maxZ float64
+}
+
+func (r *elevationRangeReader) MinZ() float64 { return r.minZ }
+func (r *elevationRangeReader) MaxZ() float64 { return r.maxZ }
+
+func TestMutatorPipelineHeightExaggeration(t *testing.T) {
+	reader := &elevationRangeReader{minZ: 100, maxZ: 200}
+	p, err := newMutatorPipeline(NewTilerOptions(WithHeightExaggeration(3)), 0, nil, &runResources{}, reader)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pt, _ := p.Mutate(geom.Point64{Z: 110}); pt.Z != 130 {
+		t.Errorf("expected Z %v got %v", 130, pt.Z)
+	}
+	for _, factor := range []float64{0, -1} {
+		if _, err := newMutatorPipeline(NewTilerOptions(WithHeightExaggeration(factor)), 0, nil, &runResources{}, reader); err == nil {
+			t.Errorf("expected error for factor %v, got none", factor)
+		}
+	}
+	if _, err := newMutatorPipeline(NewTilerOptions(WithHeightExaggeration(2)), 0, nil, &runResources{}, &las.MockLasReader{}); err == nil {
+		t.Errorf("expected error for a reader without elevation range, got none")
+	}
+	// the header elevations are not the reference of the raster elevations
+	opts := NewTilerOptions(WithHeightExaggeration(2), WithElevationFromRaster("dem.tif"))
+	if _, err := newMutatorPipeline(opts, 0, nil, &runResources{}, reader); err == nil || !strings.Contains(err.Error(), "height exaggeration") {
+		t.Errorf("expected height exaggeration error with an elevation raster, got %v", err)
+	}
+}
+
+func TestMutatorPipelineInputUnits(t *testing.T) {
+	// the exaggeration reference is converted from feet too
+	reader := &elevationRangeReader{minZ: 100, maxZ: 200}
+	p, err := newMutatorPipeline(NewTilerOptions(WithInputUnits(UnitUSSurveyFoot, UnitFoot), WithHeightExaggeration(2)), 0, nil, &runResources{}, reader)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pt, _ := p.Mutate(geom.Point64{X: 3937, Y: 0, Z: 110})
+	if math.Abs(pt.X-1200) > 1e-9 || math.Abs(pt.Z-(30.48+2*3.048)) > 1e-9 {
+		t.Errorf("expected X %v and Z %v got %v and %v", 1200, 30.48+2*3.048, pt.X, pt.Z)
+	}
+	if p, _ := newMutatorPipeline(NewDefaultTilerOptions(), 0, nil, &runResources{}, nil); len(p.Mutators) != 0 {
+		t.Errorf("expected no unit conversion for meters")
+	}
+	if _, err := newMutatorPipeline(NewTilerOptions(WithInputUnits(0, UnitMeter)), 0, nil, &runResources{}, nil); err == nil {
+		t.Errorf("expected error for invalid unit, got none")
+	}
+}
+
+func TestMutatorPipelineFlipAxis(t *testing.T) {
+	// the exaggeration reference is the lowest point after flipping
+	reader := &elevationRangeReader{minZ: 100, maxZ: 200}
+	p, err := newMutatorPipeline(NewTilerOptions(WithFlipAxis(false, true, true), WithHeightExaggeration(2)), 0, nil, &runResources{}, reader)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pt, _ := p.Mutate(geom.Point64{X: 10, Y: 20, Z: 190})
+	if pt.X != 10 || pt.Y != -20 || pt.Z != -180 {
+		t.Errorf("expected X %v, Y %v and Z %v got %v, %v and %v", 10, -20, -180, pt.X, pt.Y, pt.Z)
+	}
+}
+
+func TestTilerProcessFilesElevationFromRaster(t *testing.T) {
+	tiler, err := NewGoCesiumTiler()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tiler.writerProvider = func(folder string, inputFiles []string, reader las.LasReader, c coor.CoordinateConverter, opts *TilerOptions, progress writer.ProgressFunc) (writer.Writer, error) {
+		return &writer.MockWriter{}, nil
+	}
+	var m mutator.Mutator
+	tiler.treeProvider = func(opts *TilerOptions, mut mutator.Mutator) tree.Tree {
+		m = mut
+		return &tree.MockNode{}
+	}
+	tiler.lasReaderProvider = func(inputLasFiles []string, epsgCode int, opts *TilerOptions) (las.LasReader, error) {
+		return &las.MockLasReader{}, nil
+	}
+	// the clamp must apply to the elevation sampled from the raster
+	opts := NewTilerOptions(
+		WithElevationFromRaster("./internal/raster/testdata/dem-32633.tif"),
+		WithElevationClamp(150, 500),
+	)
+	if err := tiler.ProcessFiles([]string{"abc.las"}, "out", 32633, opts, context.TODO()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	cases := []struct {
+		pt       geom.Point64
+		expected float64
+		keep     bool
+	}{
+		{geom.Point64{X: 1015, Y: 2005, Z: 0}, 400, true},
+		{geom.Point64{X: 1005, Y: 2015, Z: 300}, 0, false},
+		{geom.Point64{X: 900, Y: 2015, Z: 300}, 0, false},
+	}
+	for _, c := range cases {
+		pt, keep := m.Mutate(c.pt)
+		if keep != c.keep {
+			t.Errorf("at %v,%v expected keep %v got %v", c.pt.X, c.pt.Y, c.keep, keep)
+			continue
+		}
+		if keep && pt.Z != c.expected {
+			t.Errorf("at %v,%v expected Z %v got %v", c.pt.X, c.pt.Y, c.expected, pt.Z)
+		}
+	}
+
+	opts = NewTilerOptions(WithElevationFromRaster("./internal/raster/testdata/missing.tif"))
+	if err := tiler.ProcessFiles([]string{"abc.las"}, "out", 32633, opts, context.TODO()); err == nil {
+		t.Errorf("expected error for missing raster, got none")
+	}
+}
+
+func TestMutatorPipelineColorFromRaster(t *testing.T) {
+	p, err := newMutatorPipeline(NewTilerOptions(WithColorFromRaster("./internal/raster/testdata/ortho-32633.tif")), 32633, nil, &runResources{}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	pt, keep := p.Mutate(geom.Point64{X: 1015, Y: 2015, R: 1, G: 2, B: 3})
+	if !keep || pt.R != 10 || pt.G != 20 || pt.B != 30 {
+		t.Errorf("expected color 10, 20, 30 got %v, %v, %v (%v)", pt.R, pt.G, pt.B, keep)
+	}
+	pt, keep = p.Mutate(geom.Point64{X: 900, Y: 2015, R: 1, G: 2, B: 3})
+	if !keep || pt.R != 1 || pt.G != 2 || pt.B != 3 {
+		t.Errorf("expected the color of a point outside of the raster to be retained, got %v, %v, %v (%v)", pt.R, pt.G, pt.B, keep)
+	}
+
+	// the DEM has a single band
+	if _, err := newMutatorPipeline(NewTilerOptions(WithColorFromRaster("./internal/raster/testdata/dem-32633.tif")), 32633, nil, &runResources{}, nil); err == nil {
+		t.Errorf("expected error for a raster with less than three bands, got none")
+	}
+}
+
+func TestTilerProcessFolderReadsRasterOnce(t *testing.T) {
+	tiler, err := NewGoCesiumTiler()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tmp := t.TempDir()
+	dem := filepath.Join(tmp, "dem.tif")
+	data, err := os.ReadFile("./internal/raster/testdata/dem-32633.tif")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	os.WriteFile(dem, data, 0666)
+	utils.TouchFile(filepath.Join(tmp, "abc.las"))
+	utils.TouchFile(filepath.Join(tmp, "def.las"))
+
+	tiler.writerProvider = func(folder string, inputFiles []string, reader las.LasReader, c coor.CoordinateConverter, opts *TilerOptions, progress writer.ProgressFunc) (writer.Writer, error) {
+		return &writer.MockWriter{}, nil
+	}
+	trees := 0
+	tiler.treeProvider = func(opts *TilerOptions, m mutator.Mutator) tree.Tree {
+		// removing the raster makes any further attempt to read it fail
+		os.Remove(dem)
+		trees++
+		return &tree.MockNode{}
+	}
+	tiler.lasReaderProvider = func(inputLasFiles []string, epsgCode int, opts *TilerOptions) (las.LasReader, error) {
+		return &las.MockLasReader{}, nil
+	}
+	err = tiler.ProcessFolder(tmp, filepath.Join(tmp, "out"), 32633, NewTilerOptions(WithElevationFromRaster(dem)), context.TODO())
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if trees != 2 {
+		t.Errorf("expected %d tilesets got %d", 2, trees)
+	}
+}
+
+// offsetConverter is a coordinate converter translating the coordinates by a fixed offset
+type offsetConverter struct {
+	coor.CoordinateConverter
+	dx, dy float64
+	calls  int
+}
+
+func (o *offsetConverter) ToSrid(sourceSrid int, targetSrid int, coord geom.Coord) (geom.Coord, error) {
+	o.calls++
+	return geom.Coord{X: coord.X + o.dx, Y: coord.Y + o.dy, Z: coord.Z}, nil
+}
+
+func TestRasterSamplerReprojection(t *testing.T) {
+	g, err := raster.Open("./internal/raster/testdata/dem-32633.tif")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	conv := &offsetConverter{dx: 1000, dy: 2000}
+	sample := newRasterSampler(g, 0, 4326, conv)
+	if v, ok := sample(15, 5); !ok || v != 400 {
+		t.Errorf("expected value %v got %v (%v)", 400, v, ok)
+	}
+	if conv.calls != 1 {
+		t.Errorf("expected %d reprojections got %d", 1, conv.calls)
+	}
+
+	// no reprojection if the raster has the same CRS of the points
+	sample = newRasterSampler(g, 0, 32633, conv)
+	if v, ok := sample(1015, 2005); !ok || v != 400 {
+		t.Errorf("expected value %v got %v (%v)", 400, v, ok)
+	}
+	if conv.calls != 1 {
+		t.Errorf("expected %d reprojections got %d", 1, conv.calls)
+	}
+}
+
+// writeTestTileset exports a single tile tileset using the writer returned by the default writer provider
+// with the given options, returning the output folder
+// quantizedReader is a mock reader reporting the scale and offset of the input files
+type quantizedReader struct {
+	las.MockLasReader
+	q []las.Quantization
+}
+
+func (r *quantizedReader) Quantization() []las.Quantization {
+	return r.q
+}
+
+func writeTestTileset(t *testing.T, opts *TilerOptions) string {
+	t.Helper()
+	return writeTestTilesetWithProgress(t, opts, nil)
+}
+
+func writeTestTilesetWithProgress(t *testing.T, opts *TilerOptions, progress writer.ProgressFunc) string {
+	t.Helper()
+	tiler, err := NewGoCesiumTiler()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tmp := t.TempDir()
+	reader := &quantizedReader{q: []las.Quantization{
+		{File: "a.las", Scale: [3]float64{0.01, 0.01, 0.001}, Offset: [3]float64{500000, 4000000, 0}},
+		{File: "b.las", Scale: [3]float64{0.1, 0.1, 0.1}, Offset: [3]float64{0, 0, -100}},
+	}}
+	w, err := tiler.writerProvider(tmp, []string{"a.las", "b.las"}, reader, tiler.cconv, opts, progress)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pt := &geom.LinkedPoint{Pt: geom.NewPoint32(1, 2, 3, 4, 5, 6, 7, 8)}
+	root := &tree.MockNode{
+		TotalNumPts: 1,
+		Pts:         geom.NewLinkedPointStream(pt, 1),
+		Root:        true,
+		Leaf:        true,
+	}
+	if err := w.Write(root, "", context.TODO()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return tmp
+}
+
+func TestTilerWriterFlatten(t *testing.T) {
+	tiler, err := NewGoCesiumTiler()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tmp := t.TempDir()
+	w, err := tiler.writerProvider(tmp, []string{"a.las"}, &las.MockLasReader{}, tiler.cconv, NewTilerOptions(WithFlatten(1)), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	child := &tree.MockNode{
+		TotalNumPts: 1,
+		Pts:         geom.NewLinkedPointStream(&geom.LinkedPoint{Pt: geom.NewPoint32(1, 2, 3, 4, 5, 6, 7, 8)}, 1),
+		Leaf:        true,
+	}
+	root := &tree.MockNode{
+		TotalNumPts: 2,
+		Pts:         geom.NewLinkedPointStream(&geom.LinkedPoint{Pt: geom.NewPoint32(1, 2, 3, 4, 5, 6, 7, 8)}, 1),
+		Root:        true,
+		Children:    [8]tree.Node{child},
+	}
+	if err := w.Write(root, "", context.TODO()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "0")); !os.IsNotExist(err) {
+		t.Errorf("expected the children not to be exported, got %v", err)
+	}
+}
+
+func TestTilerWriterMinContentBytes(t *testing.T) {
+	tiler, err := NewGoCesiumTiler()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tmp := t.TempDir()
+	w, err := tiler.writerProvider(tmp, []string{"a.las"}, &las.MockLasReader{}, tiler.cconv, NewTilerOptions(WithMinContentBytes(1024)), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	child := &tree.MockNode{
+		TotalNumPts: 1,
+		Pts:         geom.NewLinkedPointStream(&geom.LinkedPoint{Pt: geom.NewPoint32(1, 2, 3, 4, 5, 6, 7, 8)}, 1),
+		Leaf:        true,
+	}
+	root := &tree.MockNode{
+		TotalNumPts: 2,
+		Pts:         geom.NewLinkedPointStream(&geom.LinkedPoint{Pt: geom.NewPoint32(1, 2, 3, 4, 5, 6, 7, 8)}, 1),
+		Root:        true,
+		Children:    [8]tree.Node{child},
+	}
+	if err := w.Write(root, "", context.TODO()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "0")); !os.IsNotExist(err) {
+		t.Errorf("expected the small child to be merged into the root, got %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(tmp, "content.pnts"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pts, err := writer.ReadPnts(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pts) != 2 {
+		t.Errorf("expected the root content to store %d points got %d", 2, len(pts))
+	}
+}
+
+func TestTilerWriterProgress(t *testing.T) {
+	written, total := 0, 0
+	writeTestTilesetWithProgress(t, NewDefaultTilerOptions(), func(w, tot int) {
+		written, total = w, tot
+	})
+	if written != 1 || total != 1 {
+		t.Errorf("expected progress 1/1 got %d/%d", written, total)
+	}
+}
+
+func TestTilerWriterManifest(t *testing.T) {
+	tmp := writeTestTileset(t, NewTilerOptions(WithManifest("manifest.json")))
+	if _, err := os.Stat(filepath.Join(tmp, "manifest.json")); err != nil {
+		t.Errorf("expected manifest to be written, got %v", err)
+	}
+}
+
+func TestTilerWriterContentPostProcessor(t *testing.T) {
+	var paths []string
+	process := func(path string, data []byte) ([]byte, error) {
+		paths = append(paths, path)
+		return append([]byte("SIGNED"), data...), nil
+	}
+	tmp := writeTestTileset(t, NewTilerOptions(WithContentPostProcessor(process), WithManifest("manifest.json")))
+	if !reflect.DeepEqual(paths, []string{"content.pnts"}) {
+		t.Errorf("expected the post processor to be called for the root content, got %v", paths)
+	}
+	data, err := os.ReadFile(filepath.Join(tmp, "content.pnts"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "SIGNED") {
+		t.Errorf("expected the post processed content to be stored")
+	}
+	// the manifest describes the stored content
+	manifestData, err := os.ReadFile(filepath.Join(tmp, "manifest.json"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	manifest := writer.Manifest{}
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, e := range manifest.Files {
+		if e.Path == "content.pnts" && e.Size != len(data) {
+			t.Errorf("expected the manifest size %d got %d", len(data), e.Size)
+		}
+	}
+	data, err = os.ReadFile(filepath.Join(tmp, "tileset.json"))
+	if err != nil || strings.HasPrefix(string(data), "SIGNED") {
+		t.Errorf("expected the tileset.json not to be post processed (%v)", err)
+	}
+
+	tiler, err := NewGoCesiumTiler()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	opts := NewTilerOptions(WithContentPostProcessor(func(path string, data []byte) ([]byte, error) {
+		return nil, fmt.Errorf("mock error")
+	}))
+	w, err := tiler.writerProvider(t.TempDir(), []string{"a.las"}, &las.MockLasReader{}, tiler.cconv, opts, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	root := &tree.MockNode{
+		TotalNumPts: 1,
+		Pts:         geom.NewLinkedPointStream(&geom.LinkedPoint{Pt: geom.NewPoint32(1, 2, 3, 4, 5, 6, 7, 8)}, 1),
+		Root:        true,
+		Leaf:        true,
+	}
+	if err := w.Write(root, "", context.TODO()); err == nil {
+		t.Errorf("expected the post processor error to abort the export, got none")
+	}
+}
+
+func TestTilerWriterAssetExtras(t *testing.T) {
+	tmp := writeTestTileset(t, NewDefaultTilerOptions())
+	data, err := os.ReadFile(filepath.Join(tmp, "tileset.json"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var tileset struct {
+		Asset struct {
+			Extras struct {
+				Gocesiumtiler BuildInfo `json:"gocesiumtiler"`
+			} `json:"extras"`
+		} `json:"asset"`
+	}
+	if err := json.Unmarshal(data, &tileset); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if actual := tileset.Asset.Extras.Gocesiumtiler; actual != GetBuildInfo() {
+		t.Errorf("expected build info %v got %v", GetBuildInfo(), actual)
+	}
+}
+
+func TestTilerProcessFolderRejectsSharedManifest(t *testing.T) {
+	tiler, err := NewGoCesiumTiler()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tiler.writerProvider = func(folder string, inputFiles []string, reader las.LasReader, c coor.CoordinateConverter, opts *TilerOptions, progress writer.ProgressFunc) (writer.Writer, error) {
+		t.Errorf("unexpected export")
+		return &writer.MockWriter{}, nil
+	}
+	tmp := t.TempDir()
+	utils.TouchFile(filepath.Join(tmp, "abc.las"))
+	for _, p := range []string{filepath.Join(tmp, "manifest.json"), "../manifest.json"} {
+		err := tiler.ProcessFolder(tmp, filepath.Join(tmp, "out"), 32633, NewTilerOptions(WithManifest(p)), context.TODO())
+		if err == nil {
+			t.Errorf("expected error for manifest path %s, got none", p)
+		}
+	}
+}
+
+func TestTilerWriterMinifiedTileset(t *testing.T) {
+	tmp := writeTestTileset(t, NewTilerOptions(WithPrettyTileset(false)))
+	data, err := os.ReadFile(filepath.Join(tmp, "tileset.json"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(string(data), "\n") {
+		t.Errorf("expected minified tileset.json got %s", data)
+	}
+}
+
+func TestTilerWriterQuantizedPositions(t *testing.T) {
+	tmp := writeTestTileset(t, NewTilerOptions(WithQuantizedPositions(true)))
+	data, err := os.ReadFile(filepath.Join(tmp, "content.pnts"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(string(data), "POSITION_QUANTIZED") {
+		t.Errorf("expected quantized positions in the feature table")
+	}
+}
+
+func TestTilerWriterMaxTiles(t *testing.T) {
+	tiler, err := NewGoCesiumTiler()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tmp := t.TempDir()
+	w, err := tiler.writerProvider(tmp, nil, nil, tiler.cconv, NewTilerOptions(WithMaxTiles(1)), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pt := &geom.LinkedPoint{Pt: geom.NewPoint32(1, 2, 3, 4, 5, 6, 7, 8)}
+	root := &tree.MockNode{
+		Pts:  geom.NewLinkedPointStream(pt, 1),
+		Root: true,
+	}
+	root.Children[0] = &tree.MockNode{Pts: geom.NewLinkedPointStream(pt, 1), Leaf: true}
+	if err := w.Write(root, "", context.TODO()); err == nil {
+		t.Errorf("expected error exceeding the maximum number of tiles, got none")
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "content.pnts")); !os.IsNotExist(err) {
+		t.Errorf("expected no tile to be written, got %v", err)
+	}
+}
+
+func TestTilerWriterOutputRegion(t *testing.T) {
+	tiler, err := NewGoCesiumTiler()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tmp := t.TempDir()
+	// keeps the eastern half of a cloud spanning from 10 to 11 degrees of longitude
+	region := Region{West: 10.6, South: 45.2, East: 10.8, North: 45.4}
+	w, err := tiler.writerProvider(tmp, nil, nil, tiler.cconv, NewTilerOptions(WithOutputRegion(region)), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	deg := math.Pi / 180
+	pt := &geom.LinkedPoint{Pt: geom.NewPoint32(1, 2, 3, 4, 5, 6, 7, 8)}
+	root := &tree.MockNode{
+		Region:      geom.NewBoundingBox(10*deg, 11*deg, 45*deg, 46*deg, 0, 10),
+		Pts:         geom.NewLinkedPointStream(pt, 1),
+		TotalNumPts: 3,
+		Root:        true,
+	}
+	for i, west := range []float64{10, 10.5} {
+		root.Children[i] = &tree.MockNode{
+			Region:      geom.NewBoundingBox(west*deg, (west+0.5)*deg, 45*deg, 46*deg, 0, 10),
+			Pts:         geom.NewLinkedPointStream(&geom.LinkedPoint{Pt: pt.Pt}, 1),
+			TotalNumPts: 1,
+			Leaf:        true,
+		}
+	}
+	if err := w.Write(root, "", context.TODO()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "0", "content.pnts")); !os.IsNotExist(err) {
+		t.Errorf("expected the western tile not to be written, got %v", err)
+	}
+	for _, f := range []string{"tileset.json", "content.pnts", filepath.Join("1", "content.pnts")} {
+		if _, err := os.Stat(filepath.Join(tmp, f)); err != nil {
+			t.Errorf("expected %s to be written, got %v", f, err)
+		}
+	}
+
+	for _, invalid := range []Region{
+		{West: 11, South: 45, East: 10, North: 46},
+		{West: 10, South: 46, East: 11, North: 45},
+		{West: -190, South: 45, East: 11, North: 46},
+	} {
+		err := tiler.ProcessFiles([]string{"a.las"}, tmp, 32633, NewTilerOptions(WithOutputRegion(invalid)), context.TODO())
+		if err == nil || !strings.Contains(err.Error(), "invalid output region") {
+			t.Errorf("region %v: expected invalid output region error got %v", invalid, err)
+		}
+	}
+}
+
+func TestTilerStreamTiles(t *testing.T) {
+	tiler, err := NewGoCesiumTiler()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pt := &geom.LinkedPoint{Pt: geom.NewPoint32(1, 2, 3, 4, 5, 6, 7, 8)}
+	root := &tree.MockNode{
+		Region: geom.NewBoundingBox(0.1, 0.2, 0.3, 0.4, 10, 20),
+		Pts:    geom.NewLinkedPointStream(pt, 1),
+		Root:   true,
+	}
+	root.Children[3] = &tree.MockNode{
+		Region: geom.NewBoundingBox(0.1, 0.15, 0.3, 0.35, 10, 15),
+		Pts:    geom.NewLinkedPointStream(pt, 1),
+		Leaf:   true,
+	}
+	tiler.treeProvider = func(opts *TilerOptions, m mutator.Mutator) tree.Tree {
+		return root
+	}
+	tiler.lasReaderProvider = func(inputLasFiles []string, epsgCode int, opts *TilerOptions) (las.LasReader, error) {
+		return &las.MockLasReader{}, nil
+	}
+
+	tiles, errs := tiler.StreamTiles([]string{"abc.las"}, 4978, NewTilerOptions(WithTerrainOutput(true)), context.TODO())
+	received := map[string]Tile{}
+	for tile := range tiles {
+		received[tile.URI] = tile
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string][6]float64{
+		"tileset.json":   {0.1, 0.3, 0.2, 0.4, 10, 20},
+		"content.pnts":   {0.1, 0.3, 0.2, 0.4, 10, 20},
+		"3/content.pnts": {0.1, 0.3, 0.15, 0.35, 10, 15},
+	}
+	if len(received) != len(expected) {
+		t.Errorf("expected %d tiles got %d", len(expected), len(received))
+	}
+	for uri, volume := range expected {
+		tile, ok := received[uri]
+		if !ok {
+			t.Errorf("expected tile %s to be streamed", uri)
+			continue
+		}
+		if len(tile.Content) == 0 {
+			t.Errorf("expected content for tile %s", uri)
+		}
+		if tile.BoundingVolume != volume {
+			t.Errorf("expected bounding volume %v for tile %s got %v", volume, uri, tile.BoundingVolume)
+		}
+	}
+}
+
+func TestTilerStreamTilesCancelled(t *testing.T) {
+	tiler, err := NewGoCesiumTiler()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pt := &geom.LinkedPoint{Pt: geom.NewPoint32(1, 2, 3, 4, 5, 6, 7, 8)}
+	tiler.treeProvider = func(opts *TilerOptions, m mutator.Mutator) tree.Tree {
+		return &tree.MockNode{Pts: geom.NewLinkedPointStream(pt, 1), Root: true, Leaf: true}
+	}
+	tiler.lasReaderProvider = func(inputLasFiles []string, epsgCode int, opts *TilerOptions) (las.LasReader, error) {
+		return &las.MockLasReader{}, nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	tiles, errs := tiler.StreamTiles([]string{"abc.las"}, 4978, NewDefaultTilerOptions(), ctx)
+	for range tiles {
+	}
+	if err := <-errs; err == nil {
+		t.Errorf("expected error on cancelled context, got none")
+	}
+}
+
+func TestTilerWriterSourceQuantization(t *testing.T) {
+	readSource := func(tmp string) []las.Quantization {
+		data, err := os.ReadFile(filepath.Join(tmp, "tileset.json"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		tileset := struct {
+			Asset struct {
+				Extras struct {
+					Source []las.Quantization `json:"source"`
+				} `json:"extras"`
+			} `json:"asset"`
+		}{}
+		if err := json.Unmarshal(data, &tileset); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return tileset.Asset.Extras.Source
+	}
+	expected := []las.Quantization{
+		{File: "a.las", Scale: [3]float64{0.01, 0.01, 0.001}, Offset: [3]float64{500000, 4000000, 0}},
+		{File: "b.las", Scale: [3]float64{0.1, 0.1, 0.1}, Offset: [3]float64{0, 0, -100}},
+	}
+	if actual := readSource(writeTestTileset(t, NewTilerOptions(WithSourceQuantization(true)))); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected source %v got %v", expected, actual)
+	}
+	if actual := readSource(writeTestTileset(t, NewDefaultTilerOptions())); actual != nil {
+		t.Errorf("expected no source by default, got %v", actual)
+	}
+}
+
+func TestTilerWriterPointSourceIdAttribute(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		tmp := writeTestTileset(t, NewTilerOptions(WithPointSourceIdAttribute(enabled)))
+		data, err := os.ReadFile(filepath.Join(tmp, "content.pnts"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if actual := strings.Contains(string(data), "POINT_SOURCE_ID"); actual != enabled {
+			t.Errorf("expected the point source ID property in the batch table to be %v got %v", enabled, actual)
+		}
+	}
+}
+
+func TestTilerWriterSourceFileAttribute(t *testing.T) {
+	tmp := writeTestTileset(t, NewTilerOptions(WithSourceFileAttribute(true)))
+	data, err := os.ReadFile(filepath.Join(tmp, "content.pnts"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(string(data), "_SOURCE_FILE") {
+		t.Errorf("expected the source file property in the batch table")
+	}
+	data, err = os.ReadFile(filepath.Join(tmp, "tileset.json"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tileset := struct {
+		Asset struct {
+			Extras struct {
+				SourceFiles []string `json:"sourceFiles"`
+			} `json:"extras"`
+		} `json:"asset"`
+	}{}
+	if err := json.Unmarshal(data, &tileset); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if files := tileset.Asset.Extras.SourceFiles; !reflect.DeepEqual(files, []string{"a.las", "b.las"}) {
+		t.Errorf("expected source files %v got %v", []string{"a.las", "b.las"}, files)
+	}
+}
+
+func TestTilerProcessFilesTerrainOutput(t *testing.T) {
+	tiler, err := NewGoCesiumTiler()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the converter returns the offsets from the node center as longitude and latitude
+	tiler.cconv = &offsetConverter{}
+	var pts *geom.LinkedPoint
+	for i := 0; i < 10; i++ {
+		for j := 0; j < 10; j++ {
+			pts = &geom.LinkedPoint{Pt: geom.NewPoint32(11+float32(i)*0.001, 46+float32(j)*0.001, 100, 0, 0, 0, 0, 2), Next: pts}
+		}
+	}
+	// non ground points are ignored
+	pts = &geom.LinkedPoint{Pt: geom.NewPoint32(12, 47, 100, 0, 0, 0, 0, 6), Next: pts}
+	tr := &tree.MockNode{Pts: geom.NewLinkedPointStream(pts, 101), Root: true, Leaf: true}
+	tiler.writerProvider = func(folder string, inputFiles []string, reader las.LasReader, c coor.CoordinateConverter, opts *TilerOptions, progress writer.ProgressFunc) (writer.Writer, error) {
+		return &writer.MockWriter{}, nil
+	}
+	tiler.treeProvider = func(opts *TilerOptions, m mutator.Mutator) tree.Tree {
+		return tr
+	}
+	tiler.lasReaderProvider = func(inputLasFiles []string, epsgCode int, opts *TilerOptions) (las.LasReader, error) {
+		return &las.MockLasReader{}, nil
+	}
+	out := t.TempDir()
+	if err := tiler.ProcessFiles([]string{"abc.las"}, out, 4326, NewTilerOptions(WithTerrainOutput(true)), context.TODO()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(out, "terrain", "layer.json")); err != nil {
+		t.Errorf("expected terrain layer.json to exist: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(out, "terrain", "0", "1", "0.terrain")); err != nil {
+		t.Errorf("expected root terrain tile to exist: %v", err)
+	}
+
+	// without ground points the terrain cannot be generated
+	tr.Pts = geom.NewLinkedPointStream(&geom.LinkedPoint{Pt: geom.NewPoint32(12, 47, 100, 0, 0, 0, 0, 6)}, 1)
+	if err := tiler.ProcessFiles([]string{"abc.las"}, t.TempDir(), 4326, NewTilerOptions(WithTerrainOutput(true)), context.TODO()); err == nil {
+		t.Errorf("expected error for missing ground points, got none")
+	}
+}
+
+func TestTilerProcessFilesOverview(t *testing.T) {
+	tiler, err := NewGoCesiumTiler()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tr := &tree.MockNode{Pts: geom.NewLinkedPointStream(nil, 1), Root: true, Leaf: true}
+	folders := []string{}
+	overviews := []bool{}
+	manifests := []string{}
+	tiler.writerProvider = func(folder string, inputFiles []string, reader las.LasReader, c coor.CoordinateConverter, opts *TilerOptions, progress writer.ProgressFunc) (writer.Writer, error) {
+		folders = append(folders, folder)
+		overviews = append(overviews, opts.overview)
+		manifests = append(manifests, opts.manifestPath)
+		return &writer.MockWriter{}, nil
+	}
+	tiler.treeProvider = func(opts *TilerOptions, m mutator.Mutator) tree.Tree {
+		return tr
+	}
+	tiler.lasReaderProvider = func(inputLasFiles []string, epsgCode int, opts *TilerOptions) (las.LasReader, error) {
+		return &las.MockLasReader{}, nil
+	}
+	out := t.TempDir()
+	opts := NewTilerOptions(WithOverviewTileset(100), WithManifest("manifest.json"))
+	if err := tiler.ProcessFiles([]string{"abc.las"}, out, 4326, opts, context.TODO()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	expected := []string{out, filepath.Join(out, "overview")}
+	if !reflect.DeepEqual(folders, expected) {
+		t.Fatalf("expected tilesets written to %v got %v", expected, folders)
+	}
+	if !reflect.DeepEqual(overviews, []bool{false, true}) {
+		t.Errorf("expected only the second tileset to be the overview, got %v", overviews)
+	}
+	if manifests[1] != "" {
+		t.Errorf("expected no manifest for the overview, got %s", manifests[1])
+	}
+
+	folders = []string{}
+	if err := tiler.ProcessFiles([]string{"abc.las"}, out, 4326, NewTilerOptions(), context.TODO()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(folders) != 1 {
+		t.Errorf("expected no overview by default, got %v", folders)
+	}
+}
+
+func TestTilerWriterOverview(t *testing.T) {
+	opts := NewTilerOptions(WithOverviewTileset(1))
+	opts.overview = true
+	tmp := writeTestTileset(t, opts)
+	n, err := writer.ValidateTileset(tmp)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("expected %d points got %d", 1, n)
+	}
+}
+
+func TestTilerProcessFilesDebugFootprints(t *testing.T) {
+	tiler, err := NewGoCesiumTiler()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tr := &tree.MockNode{Pts: geom.NewLinkedPointStream(nil, 1), Root: true, Leaf: true}
+	tiler.writerProvider = func(folder string, inputFiles []string, reader las.LasReader, c coor.CoordinateConverter, opts *TilerOptions, progress writer.ProgressFunc) (writer.Writer, error) {
+		return &writer.MockWriter{}, nil
+	}
+	tiler.treeProvider = func(opts *TilerOptions, m mutator.Mutator) tree.Tree {
+		return tr
+	}
+	tiler.lasReaderProvider = func(inputLasFiles []string, epsgCode int, opts *TilerOptions) (las.LasReader, error) {
+		return &las.MockLasReader{}, nil
+	}
+	out := t.TempDir()
+	abs := filepath.Join(t.TempDir(), "abs.geojson")
+	for _, c := range []struct {
+		path     string
+		expected string
+	}{
+		{"footprints.geojson", filepath.Join(out, "footprints.geojson")},
+		{abs, abs},
+	} {
+		if err := tiler.ProcessFiles([]string{"abc.las"}, out, 4326, NewTilerOptions(WithDebugFootprints(c.path)), context.TODO()); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if _, err := os.Stat(c.expected); err != nil {
+			t.Errorf("expected footprints file to exist: %v", err)
+		}
+	}
+}
+
+func TestTilerProcessFilesPreviewImage(t *testing.T) {
+	tiler, err := NewGoCesiumTiler()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tr := &tree.MockNode{
+		Pts:         geom.NewLinkedPointStream(&geom.LinkedPoint{Pt: geom.NewPoint32(1, 2, 3, 4, 5, 6, 7, 8)}, 1),
+		TotalNumPts: 1,
+		Root:        true,
+		Leaf:        true,
+	}
+	tiler.writerProvider = func(folder string, inputFiles []string, reader las.LasReader, c coor.CoordinateConverter, opts *TilerOptions, progress writer.ProgressFunc) (writer.Writer, error) {
+		return &writer.MockWriter{}, nil
+	}
+	tiler.treeProvider = func(opts *TilerOptions, m mutator.Mutator) tree.Tree {
+		return tr
+	}
+	tiler.lasReaderProvider = func(inputLasFiles []string, epsgCode int, opts *TilerOptions) (las.LasReader, error) {
+		return &las.MockLasReader{}, nil
+	}
+	out := t.TempDir()
+	if err := tiler.ProcessFiles([]string{"abc.las"}, out, 4326, NewTilerOptions(WithPreviewImage("preview.png", 8, 8)), context.TODO()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(out, "preview.png"))
+	if err != nil {
+		t.Fatalf("expected the preview image to exist: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "\x89PNG") {
+		t.Errorf("expected a PNG image")
+	}
+
+	if err := tiler.ProcessFiles([]string{"abc.las"}, out, 4326, NewTilerOptions(WithPreviewImage("preview.png", 8, 0)), context.TODO()); err == nil {
+		t.Errorf("expected error for an invalid size, got none")
+	}
+}
+
+func TestTilerProcessFilesResult(t *testing.T) {
+	tiler, err := NewGoCesiumTiler()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tr := &tree.MockNode{CenterX: 4000000, CenterY: 900000, CenterZ: 4800000}
+	tiler.writerProvider = func(folder string, inputFiles []string, reader las.LasReader, c coor.CoordinateConverter, opts *TilerOptions, progress writer.ProgressFunc) (writer.Writer, error) {
+		return &writer.MockWriter{}, nil
+	}
+	tiler.treeProvider = func(opts *TilerOptions, m mutator.Mutator) tree.Tree {
+		return tr
+	}
+	tiler.lasReaderProvider = func(inputLasFiles []string, epsgCode int, opts *TilerOptions) (las.LasReader, error) {
+		return &las.MockLasReader{}, nil
+	}
+	results := []TilesetResult{}
+	opts := NewTilerOptions(WithResultCallback(func(result TilesetResult) {
+		results = append(results, result)
+	}))
+	if err := tiler.ProcessFiles([]string{"abc.las"}, "out", 32633, opts, context.TODO()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected %d results got %d", 1, len(results))
+	}
+	r := results[0]
+	if r.OutputFolder != "out" {
+		t.Errorf("expected output folder %s got %s", "out", r.OutputFolder)
+	}
+	// the points of the root tile are relative to its center
+	ecef := geom.Transform(r.RootTransform).Apply(geom.Coord{X: 1, Y: 2, Z: 3})
+	if expected := (geom.Coord{X: 4000001, Y: 900002, Z: 4800003}); ecef != expected {
+		t.Errorf("expected %v got %v", expected, ecef)
+	}
+	if local := geom.Transform(r.InverseRootTransform).Apply(ecef); local != (geom.Coord{X: 1, Y: 2, Z: 3}) {
+		t.Errorf("expected the inverse transform to return the local coordinates, got %v", local)
+	}
+}
+
+func TestTilerPointSpacing(t *testing.T) {
+	tiler, err := NewGoCesiumTiler()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pts := []Point{}
+	for i := 0; i < 40; i++ {
+		for j := 0; j < 40; j++ {
+			pts = append(pts, Point{X: 500000 + float64(i)*0.5, Y: 5000000 + float64(j)*0.5, Z: 100})
+		}
+	}
+	out := t.TempDir()
+	var result TilesetResult
+	opts := NewTilerOptions(WithGridSize(2), WithPointSpacing(true), WithResultCallback(func(r TilesetResult) {
+		result = r
+	}))
+	if err := tiler.ProcessPoints(pts, out, 32633, opts, context.TODO()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if math.Abs(result.AveragePointSpacing-0.5) > 0.01 {
+		t.Errorf("expected spacing %v got %v", 0.5, result.AveragePointSpacing)
+	}
+	data, err := os.ReadFile(filepath.Join(out, "tileset.json"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var tileset writer.Tileset
+	if err := json.Unmarshal(data, &tileset); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if actual := tileset.Asset.Extras["averagePointSpacing"]; actual != result.AveragePointSpacing {
+		t.Errorf("expected spacing %v in the asset extras got %v", result.AveragePointSpacing, actual)
+	}
+}
+
+func TestTilerGeometricErrorSpacing(t *testing.T) {
+	tiler, err := NewGoCesiumTiler()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// a sparse cloud with a dense patch in one corner
+	pts := []Point{}
+	for i := 0; i < 50; i++ {
+		for j := 0; j < 50; j++ {
+			pts = append(pts, Point{X: 500000 + float64(i)*2, Y: 5000000 + float64(j)*2, Z: 100})
+		}
+	}
+	for i := 0; i < 100; i++ {
+		for j := 0; j < 100; j++ {
+			pts = append(pts, Point{X: 500000.01 + float64(i)*0.1, Y: 5000000.01 + float64(j)*0.1, Z: 100})
+		}
+	}
+	childErrors := func(mode ...tilerOptionsFn) (float64, []float64) {
+		out := t.TempDir()
+		opts := NewTilerOptions(append([]tilerOptionsFn{WithGridSize(5), WithMaxDepth(3)}, mode...)...)
+		if err := tiler.ProcessPoints(pts, out, 32633, opts, context.TODO()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		data, err := os.ReadFile(filepath.Join(out, "tileset.json"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var tileset writer.Tileset
+		if err := json.Unmarshal(data, &tileset); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		errs := []float64{}
+		for _, child := range tileset.Root.Children {
+			errs = append(errs, child.GeometricError)
+		}
+		return tileset.Root.GeometricError, errs
+	}
+	rootError, errs := childErrors(WithGeometricErrorMode(GeoErrGeometric))
+	for _, e := range errs {
+		if math.Abs(e-rootError/2) > 1e-9 {
+			t.Errorf("expected child error %v in the geometric mode got %v", rootError/2, e)
+		}
+	}
+	rootError, errs = childErrors(WithGeometricErrorMode(GeoErrSpacing))
+	if len(errs) == 0 {
+		t.Fatalf("expected the root to have children")
+	}
+	// the spacing mode is the default
+	defaultRootError, defaultErrs := childErrors()
+	if math.Abs(defaultRootError-rootError) > 1e-9 || len(defaultErrs) != len(errs) {
+		t.Fatalf("expected the default errors to be the measured ones %v, %v got %v, %v", rootError, errs, defaultRootError, defaultErrs)
+	}
+	for i := range errs {
+		if math.Abs(defaultErrs[i]-errs[i]) > 1e-9 {
+			t.Errorf("expected the default child error to be the measured one %v got %v", errs[i], defaultErrs[i])
+		}
+	}
+	for _, e := range errs {
+		if e > rootError {
+			t.Errorf("expected child error not to exceed the root error %v got %v", rootError, e)
+		}
+		if math.Abs(e-rootError/2) < 1e-9 {
+			t.Errorf("expected child error to be measured in the spacing mode got half the root error %v", e)
+		}
+	}
+}
+
+func TestTilerProcessFilesHeightAboveGround(t *testing.T) {
+	tiler, err := NewGoCesiumTiler()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tiler.writerProvider = func(folder string, inputFiles []string, reader las.LasReader, c coor.CoordinateConverter, opts *TilerOptions, progress writer.ProgressFunc) (writer.Writer, error) {
+		return &writer.MockWriter{}, nil
+	}
+	var m mutator.Mutator
+	tiler.treeProvider = func(opts *TilerOptions, mut mutator.Mutator) tree.Tree {
+		m = mut
+		return &drainingTree{}
+	}
+	readers := 0
+	tiler.lasReaderProvider = func(inputLasFiles []string, epsgCode int, opts *TilerOptions) (las.LasReader, error) {
+		readers++
+		// the ground points are remapped to class 2 before computing the ground surface
+		return &las.MockLasReader{Pts: []geom.Point64{
+			{X: 2, Y: 2, Z: 100, Classification: 40},
+			{X: 8, Y: 8, Z: 100, Classification: 40},
+			{X: 5, Y: 5, Z: 102, Classification: 5},
+		}}, nil
+	}
+	opts := NewTilerOptions(WithClassificationRemap(map[uint8]uint8{40: 2}), WithHeightAboveGround(0.5, 3))
+	if err := tiler.ProcessFiles([]string{"abc.las"}, "out", 32633, opts, context.TODO()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if readers != 2 {
+		t.Errorf("expected the files to be read %d times got %d", 2, readers)
+	}
+	for _, c := range []struct {
+		pt       geom.Point64
+		expected bool
+	}{
+		{geom.Point64{X: 3, Y: 3, Z: 102}, true},
+		{geom.Point64{X: 3, Y: 3, Z: 100.1}, false},
+		{geom.Point64{X: 3, Y: 3, Z: 100.1, Classification: 40}, true},
+		{geom.Point64{X: 300, Y: 3, Z: 102}, false},
+	} {
+		if _, keep := m.Mutate(c.pt); keep != c.expected {
+			t.Errorf("for point %v expected keep %v got %v", c.pt, c.expected, keep)
+		}
+	}
+
+	opts = NewTilerOptions(WithHeightAboveGround(3, 0.5))
+	if err := tiler.ProcessFiles([]string{"abc.las"}, "out", 32633, opts, context.TODO()); err == nil {
+		t.Errorf("expected error for an invalid range, got none")
+	}
+}
+
+func TestTilerProcessFilesHeightRules(t *testing.T) {
+	tiler, err := NewGoCesiumTiler()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tiler.writerProvider = func(folder string, inputFiles []string, reader las.LasReader, c coor.CoordinateConverter, opts *TilerOptions, progress writer.ProgressFunc) (writer.Writer, error) {
+		return &writer.MockWriter{}, nil
+	}
+	var m mutator.Mutator
+	tiler.treeProvider = func(opts *TilerOptions, mut mutator.Mutator) tree.Tree {
+		m = mut
+		return &drainingTree{}
+	}
+	tiler.lasReaderProvider = func(inputLasFiles []string, epsgCode int, opts *TilerOptions) (las.LasReader, error) {
+		return &las.MockLasReader{Pts: []geom.Point64{
+			{X: 2, Y: 2, Z: 100, Classification: 2},
+			{X: 8, Y: 8, Z: 100, Classification: 2},
+			{X: 5, Y: 5, Z: 102, Classification: 1},
+		}}, nil
+	}
+	opts := NewTilerOptions(
+		WithHeightRules([]HeightRule{{Min: -1000, Max: 0.2, Classification: 2}, {Min: 0.2, Max: 5, Classification: 3}}),
+		WithColorByClassification(true),
+	)
+	if err := tiler.ProcessFiles([]string{"abc.las"}, "out", 32633, opts, context.TODO()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	for _, c := range []struct {
+		pt       geom.Point64
+		expected uint8
+	}{
+		{geom.Point64{X: 3, Y: 3, Z: 100.1, Classification: 1}, 2},
+		{geom.Point64{X: 3, Y: 3, Z: 102, Classification: 1}, 3},
+		{geom.Point64{X: 3, Y: 3, Z: 110, Classification: 1}, 1},
+		{geom.Point64{X: 300, Y: 3, Z: 100, Classification: 1}, 1},
+	} {
+		pt, keep := m.Mutate(c.pt)
+		if !keep || pt.Classification != c.expected {
+			t.Errorf("for point %v expected classification %d got %d (%v)", c.pt, c.expected, pt.Classification, keep)
+		}
+		// the colors are assigned after the reclassification
+		if color := mutator.DefaultClassificationPalette[c.expected]; [3]uint8{pt.R, pt.G, pt.B} != color {
+			t.Errorf("for point %v expected color %v got %v", c.pt, color, [3]uint8{pt.R, pt.G, pt.B})
+		}
+	}
+
+	opts = NewTilerOptions(WithHeightRules([]HeightRule{{Min: 3, Max: 0.5, Classification: 3}}))
+	if err := tiler.ProcessFiles([]string{"abc.las"}, "out", 32633, opts, context.TODO()); err == nil {
+		t.Errorf("expected error for an invalid range, got none")
+	}
+}
+
+func TestTilerProcessFolderContinueOnError(t *testing.T) {
+	tiler, err := NewGoCesiumTiler()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tiler.writerProvider = func(folder string, inputFiles []string, reader las.LasReader, c coor.CoordinateConverter, opts *TilerOptions, progress writer.ProgressFunc) (writer.Writer, error) {
+		return &writer.MockWriter{}, nil
+	}
+	tiler.treeProvider = func(opts *TilerOptions, m mutator.Mutator) tree.Tree {
+		return &tree.MockNode{}
+	}
+	files := []string{}
+	tiler.lasReaderProvider = func(inputLasFiles []string, epsgCode int, opts *TilerOptions) (las.LasReader, error) {
+		files = append(files, inputLasFiles...)
+		if strings.Contains(inputLasFiles[0], "bad") {
+			return nil, fmt.Errorf("corrupt file")
+		}
+		return &las.MockLasReader{}, nil
+	}
+	tmp := t.TempDir()
+	for _, f := range []string{"a.las", "bad1.las", "c.las", "bad2.las"} {
+		utils.TouchFile(filepath.Join(tmp, f))
+	}
+
+	// by default the processing stops at the first failure
+	err = tiler.ProcessFolder(tmp, "out", 32633, NewDefaultTilerOptions(), context.TODO())
+	if err == nil {
+		t.Fatalf("expected error, got none")
+	}
+	if len(files) != 2 {
+		t.Errorf("expected %d files to be processed got %v", 2, files)
+	}
+
+	files = []string{}
+	err = tiler.ProcessFolder(tmp, "out", 32633, NewTilerOptions(WithContinueOnError(true)), context.TODO())
+	if err == nil {
+		t.Fatalf("expected error, got none")
+	}
+	if len(files) != 4 {
+		t.Errorf("expected %d files to be processed got %v", 4, files)
+	}
+	for _, f := range []string{"bad1.las", "bad2.las"} {
+		if !strings.Contains(err.Error(), filepath.Join(tmp, f)) {
+			t.Errorf("expected error to report %s, got %v", f, err)
+		}
+	}
+	var fileErr *las.FileError
+	if !errors.As(err, &fileErr) {
+		t.Errorf("expected the failures to be file errors, got %v", err)
+	}
+
+	// cancelling the context stops the processing
+	files = []string{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	tiler.ProcessFolder(tmp, "out", 32633, NewTilerOptions(WithContinueOnError(true)), ctx)
+	if len(files) != 2 {
+		t.Errorf("expected %d files to be processed got %v", 2, files)
+	}
+}
+
+func TestUseGeoidModel(t *testing.T) {
+	for _, c := range []struct {
+		opts     *TilerOptions
+		expected bool
+	}{
+		{NewDefaultTilerOptions(), false},
+		{NewTilerOptions(WithGeoidElevation(true)), true},
+		{NewTilerOptions(WithVerticalEpsg(5773)), true},
+		{NewTilerOptions(WithGeoidGrid("egm2008.gtx"), WithVerticalEpsg(3855)), true},
+		{NewTilerOptions(WithGeoidGrid("geoid.gtx")), true},
+		{NewTilerOptions(WithGeoidGrid("geoid.gtx"), WithVerticalEpsg(5783)), true},
+	} {
+		actual, err := useGeoidModel(c.opts)
+		if err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+		if actual != c.expected {
+			t.Errorf("expected %v got %v for vertical EPSG %d", c.expected, actual, c.opts.verticalEpsg)
+		}
+	}
+	for _, code := range []int{5783, 3855} {
+		if _, err := useGeoidModel(NewTilerOptions(WithVerticalEpsg(code))); err == nil || !strings.Contains(err.Error(), fmt.Sprintf("EPSG:%d", code)) {
+			t.Errorf("expected unsupported vertical datum error for EPSG:%d, got %v", code, err)
+		}
+	}
+}
+
+func TestTilerProcessFilesUnsupportedVerticalEpsg(t *testing.T) {
+	tiler, err := NewGoCesiumTiler()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tiler.writerProvider = func(folder string, inputFiles []string, reader las.LasReader, c coor.CoordinateConverter, opts *TilerOptions, progress writer.ProgressFunc) (writer.Writer, error) {
+		return &writer.MockWriter{}, nil
+	}
+	tr := &tree.MockNode{}
+	tiler.treeProvider = func(opts *TilerOptions, m mutator.Mutator) tree.Tree {
+		return tr
+	}
+	tiler.lasReaderProvider = func(inputLasFiles []string, epsgCode int, opts *TilerOptions) (las.LasReader, error) {
+		return &las.MockLasReader{}, nil
+	}
+	err = tiler.ProcessFiles([]string{"abc.las"}, "out", 25832, NewTilerOptions(WithVerticalEpsg(5783)), context.TODO())
+	if err == nil {
+		t.Fatalf("expected error, got none")
+	}
+	if tr.LoadCalled {
+		t.Errorf("expected the points not to be loaded")
+	}
+}
+
+func TestTilerProcessFilesMissingGeoidGrid(t *testing.T) {
+	tiler, err := NewGoCesiumTiler()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tiler.writerProvider = func(folder string, inputFiles []string, reader las.LasReader, c coor.CoordinateConverter, opts *TilerOptions, progress writer.ProgressFunc) (writer.Writer, error) {
+		return &writer.MockWriter{}, nil
+	}
+	tr := &tree.MockNode{}
+	tiler.treeProvider = func(opts *TilerOptions, m mutator.Mutator) tree.Tree {
+		return tr
+	}
+	tiler.lasReaderProvider = func(inputLasFiles []string, epsgCode int, opts *TilerOptions) (las.LasReader, error) {
+		return &las.MockLasReader{}, nil
+	}
+	opts := NewTilerOptions(WithGeoidGrid(filepath.Join(t.TempDir(), "missing.gtx")))
+	if err := tiler.ProcessFiles([]string{"abc.las"}, "out", 25832, opts, context.TODO()); err == nil {
+		t.Fatalf("expected error, got none")
+	}
+	if tr.LoadCalled {
+		t.Errorf("expected the points not to be loaded")
+	}
+}
+
+func TestTilerProcessFilesPhaseTimings(t *testing.T) {
+	tiler, err := NewGoCesiumTiler()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tiler.writerProvider = func(folder string, inputFiles []string, reader las.LasReader, c coor.CoordinateConverter, opts *TilerOptions, progress writer.ProgressFunc) (writer.Writer, error) {
+		return &writer.MockWriter{}, nil
+	}
+	tr := &loadConverterTree{MockNode: &tree.MockNode{}}
+	tiler.treeProvider = func(opts *TilerOptions, m mutator.Mutator) tree.Tree {
+		return tr
+	}
+	tiler.lasReaderProvider = func(inputLasFiles []string, epsgCode int, opts *TilerOptions) (las.LasReader, error) {
+		return &las.MockLasReader{}, nil
+	}
+	phases := []Phase{}
+	var result TilesetResult
+	opts := NewTilerOptions(
+		WithPhaseCallback(func(phase Phase, elapsed time.Duration) {
+			phases = append(phases, phase)
+		}),
+		WithResultCallback(func(r TilesetResult) {
+			result = r
+		}),
+	)
+	if err := tiler.ProcessFiles([]string{"abc.las"}, "out", 32633, opts, context.TODO()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	expected := []Phase{PhaseReadHeader, PhaseLoad, PhaseReprojection, PhaseBuild, PhaseExport}
+	if !reflect.DeepEqual(phases, expected) {
+		t.Errorf("expected phases %v got %v", expected, phases)
+	}
+	if _, ok := tr.loadConv.(*timedConverter); !ok {
+		t.Errorf("expected the points to be loaded with a timed converter, got %T", tr.loadConv)
+	}
+	if result.Timings.ReadHeader < 0 || result.Timings.Load < 0 || result.Timings.Export < 0 {
+		t.Errorf("unexpected timings %v", result.Timings)
+	}
+
+	// without callbacks the converter is not wrapped
+	if err := tiler.ProcessFiles([]string{"abc.las"}, "out", 32633, NewDefaultTilerOptions(), context.TODO()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, ok := tr.loadConv.(*timedConverter); ok {
+		t.Errorf("expected the converter not to be wrapped")
+	}
+}
+
+// loadConverterTree records the coordinate converter used to load the points
+type loadConverterTree struct {
+	*tree.MockNode
+	loadConv coor.CoordinateConverter
+}
+
+func (l *loadConverterTree) Load(r las.LasReader, c coor.CoordinateConverter, e elev.ElevationConverter, ctx context.Context) error {
+	l.loadConv = c
+	return l.MockNode.Load(r, c, e, ctx)
+}
+
+// slowConverter is a coordinate converter taking a fixed time for each conversion
+type slowConverter struct {
+	coor.CoordinateConverter
+	clock *utils.MockClock
+	delay time.Duration
+}
+
+func (s *slowConverter) ToWGS84Cartesian(coord geom.Coord, sourceSrid int) (geom.Coord, error) {
+	s.clock.Advance(s.delay)
+	return coord, nil
+}
+
+func TestTimedConverter(t *testing.T) {
+	clock := utils.NewMockClock(time.Unix(0, 0))
+	c := &timedConverter{CoordinateConverter: &slowConverter{clock: clock, delay: time.Millisecond}, clock: clock}
+	for i := 0; i < 3; i++ {
+		if actual, err := c.ToWGS84Cartesian(geom.Coord{X: 1, Y: 2, Z: 3}, 4326); err != nil || actual != (geom.Coord{X: 1, Y: 2, Z: 3}) {
+			t.Errorf("unexpected conversion %v %v", actual, err)
+		}
+	}
+	if c.elapsed() != 3*time.Millisecond {
+		t.Errorf("expected %v got %v", 3*time.Millisecond, c.elapsed())
+	}
+
+	// a fork wrapping a non forkable converter shares it, without cleaning it up
+	forked, release := coor.Fork(c)
+	if _, err := forked.ToWGS84Cartesian(geom.Coord{X: 1, Y: 2, Z: 3}, 4326); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	release()
+	if c.elapsed() != 4*time.Millisecond {
+		t.Errorf("expected the fork time to be added, expected %v got %v", 4*time.Millisecond, c.elapsed())
+	}
+}
+
+// cleanupCountingConverter counts the cleanups of its forks
+type cleanupCountingConverter struct {
+	coor.CoordinateConverter
+	cleanups *int
+}
+
+func (c *cleanupCountingConverter) Fork() coor.CoordinateConverter {
+	return &cleanupCountingConverter{CoordinateConverter: c.CoordinateConverter, cleanups: c.cleanups}
+}
+
+func (c *cleanupCountingConverter) Cleanup() {
+	*c.cleanups++
+}
+
+func TestTimedConverterFork(t *testing.T) {
+	clock := utils.NewMockClock(time.Unix(0, 0))
+	cleanups := 0
+	base := &cleanupCountingConverter{CoordinateConverter: &slowConverter{clock: clock, delay: time.Millisecond}, cleanups: &cleanups}
+	c := &timedConverter{CoordinateConverter: base, clock: clock}
+	forked, release := coor.Fork(c)
+	if tc, ok := forked.(*timedConverter); !ok || tc.CoordinateConverter == coor.CoordinateConverter(base) {
+		t.Errorf("expected the wrapped converter to be forked")
+	}
+	if _, err := forked.ToWGS84Cartesian(geom.Coord{X: 1, Y: 2, Z: 3}, 4326); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	release()
+	if cleanups != 1 {
+		t.Errorf("expected the fork of the wrapped converter to be cleaned up once got %d", cleanups)
+	}
+	if c.elapsed() != time.Millisecond {
+		t.Errorf("expected %v got %v", time.Millisecond, c.elapsed())
+	}
+}
+
+// clockTree advances the clock while loading and building
+type clockTree struct {
+	*tree.MockNode
+	clock *utils.MockClock
+}
+
+func (c *clockTree) Load(r las.LasReader, conv coor.CoordinateConverter, e elev.ElevationConverter, ctx context.Context) error {
+	c.clock.Advance(2 * time.Second)
+	return c.MockNode.Load(r, conv, e, ctx)
+}
+
+func (c *clockTree) Build() error {
+	c.clock.Advance(3 * time.Second)
+	return c.MockNode.Build()
+}
+
+func TestTilerProcessFilesClock(t *testing.T) {
+	tiler, err := NewGoCesiumTiler()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	clock := utils.NewMockClock(time.Unix(0, 0))
+	tiler.writerProvider = func(folder string, inputFiles []string, reader las.LasReader, c coor.CoordinateConverter, opts *TilerOptions, progress writer.ProgressFunc) (writer.Writer, error) {
+		return &writer.MockWriter{}, nil
+	}
+	tiler.treeProvider = func(opts *TilerOptions, m mutator.Mutator) tree.Tree {
+		return &clockTree{MockNode: &tree.MockNode{}, clock: clock}
+	}
+	tiler.lasReaderProvider = func(inputLasFiles []string, epsgCode int, opts *TilerOptions) (las.LasReader, error) {
+		return &las.MockLasReader{}, nil
+	}
+	elapsed := map[TilerEvent]int64{}
+	phases := map[Phase]time.Duration{}
+	opts := NewTilerOptions(
+		withClock(clock),
+		WithCallback(func(e TilerEvent, filename string, ms int64, msg string) {
+			elapsed[e] = ms
+		}),
+		WithPhaseCallback(func(phase Phase, d time.Duration) {
+			phases[phase] = d
+		}),
+	)
+	if err := tiler.ProcessFiles([]string{"abc.las"}, "out", 32633, opts, context.TODO()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	expected := map[TilerEvent]int64{
+		EventReadLasHeaderCompleted: 0,
+		EventPointLoadingCompleted:  2000,
+		EventBuildStarted:           2000,
+		EventBuildCompleted:         5000,
+	}
+	for e, ms := range expected {
+		if elapsed[e] != ms {
+			t.Errorf("expected event %v after %dms got %dms", e, ms, elapsed[e])
+		}
+	}
+	if phases[PhaseLoad] != 2*time.Second || phases[PhaseBuild] != 3*time.Second || phases[PhaseExport] != 0 {
+		t.Errorf("unexpected phase durations %v", phases)
+	}
+}
+
+// recordingTree stores the points read while loading
+type recordingTree struct {
+	tree.MockNode
+	pts []geom.Point64
+}
+
+func (r *recordingTree) Load(l las.LasReader, c coor.CoordinateConverter, e elev.ElevationConverter, ctx context.Context) error {
+	for i := 0; i < l.NumberOfPoints(); i++ {
+		pt, err := l.GetNext()
+		if err != nil {
+			return err
+		}
+		r.pts = append(r.pts, pt)
+	}
+	return nil
+}
+
+func TestTilerProcessFilesExternalClassification(t *testing.T) {
+	tiler, err := NewGoCesiumTiler()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tiler.writerProvider = func(folder string, inputFiles []string, reader las.LasReader, c coor.CoordinateConverter, opts *TilerOptions, progress writer.ProgressFunc) (writer.Writer, error) {
+		return &writer.MockWriter{}, nil
+	}
+	tr := &recordingTree{}
+	tiler.treeProvider = func(opts *TilerOptions, m mutator.Mutator) tree.Tree {
+		return tr
+	}
+	tiler.lasReaderProvider = func(inputLasFiles []string, epsgCode int, opts *TilerOptions) (las.LasReader, error) {
+		return &las.MockLasReader{Pts: []geom.Point64{{Classification: 1}, {Classification: 1}}}, nil
+	}
+	labels := filepath.Join(t.TempDir(), "labels.bin")
+	if err := os.WriteFile(labels, []byte{2, 6}, 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := tiler.ProcessFiles([]string{"abc.las"}, "out", 32633, NewTilerOptions(WithExternalClassification(labels)), context.TODO()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(tr.pts) != 2 || tr.pts[0].Classification != 2 || tr.pts[1].Classification != 6 {
+		t.Errorf("expected the classes from the labels file, got %v", tr.pts)
+	}
+
+	// the number of labels must match the number of points
+	if err := os.WriteFile(labels, []byte{2}, 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := tiler.ProcessFiles([]string{"abc.las"}, "out", 32633, NewTilerOptions(WithExternalClassification(labels)), context.TODO()); err == nil {
+		t.Errorf("expected error for mismatching labels, got none")
+	}
+}
+
+// writeAppendBaseTileset writes a tileset with a root and two tiles, the first one around 10E 45N, to append points to
+func writeAppendBaseTileset(t *testing.T, tiler *GoCesiumTiler, opts *TilerOptions) string {
+	rad := math.Pi / 180
+	tile := func(lon, lat float64) *tree.MockNode {
+		c, err := tiler.cconv.ToWGS84Cartesian(geom.Coord{X: lon, Y: lat, Z: 0}, 4326)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		pt := &geom.LinkedPoint{Pt: geom.NewPoint32(1, 2, 3, 4, 5, 6, 7, 8)}
+		pt.Next = &geom.LinkedPoint{Pt: geom.NewPoint32(-1, -2, -3, 4, 5, 6, 7, 8)}
+		return &tree.MockNode{
+			Region:      geom.NewBoundingBox(lon*rad, (lon+0.001)*rad, lat*rad, (lat+0.001)*rad, 0, 10),
+			TotalNumPts: 2,
+			Pts:         geom.NewLinkedPointStream(pt, 2),
+			Leaf:        true,
+			GeomError:   1,
+			CenterX:     c.X,
+			CenterY:     c.Y,
+			CenterZ:     c.Z,
+		}
+	}
+	root := tile(10, 45)
+	root.Root, root.Leaf, root.TotalNumPts, root.GeomError = true, false, 6, 10
+	root.Region = geom.NewBoundingBox(10*rad, 11.001*rad, 45*rad, 45.001*rad, 0, 10)
+	root.Children[0] = tile(10, 45)
+	root.Children[1] = tile(11, 45)
+	tmp := t.TempDir()
+	w, err := tiler.writerProvider(tmp, []string{"a.las"}, &las.MockLasReader{}, tiler.cconv, opts, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := w.Write(root, "", context.TODO()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return tmp
+}
+
+func TestTilerAppendFiles(t *testing.T) {
+	tiler, err := NewGoCesiumTiler()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	opts := NewTilerOptions(WithSourceFileAttribute(true), WithTilesetProperties(true))
+	tmp := writeAppendBaseTileset(t, tiler, opts)
+
+	tiler.lasReaderProvider = func(inputLasFiles []string, epsgCode int, opts *TilerOptions) (las.LasReader, error) {
+		return &las.MockLasReader{Srid: 4326, Pts: []geom.Point64{
+			{X: 10.0005, Y: 45.0005, Z: 1},
+			{X: 10.0006, Y: 45.0006, Z: 2},
+		}}, nil
+	}
+	if err := tiler.AppendFiles(tmp, []string{"b.las"}, 4326, opts, context.TODO()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(tmp, "tileset.json"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tileset := writer.Tileset{}
+	if err := json.Unmarshal(data, &tileset); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	uris := []string{}
+	for _, c := range tileset.Root.Children {
+		uris = append(uris, c.Content.Url)
+	}
+	if expected := []string{"1/content.pnts", "append1/tileset.json"}; !reflect.DeepEqual(uris, expected) {
+		t.Errorf("expected children %v got %v", expected, uris)
+	}
+	if expected := []interface{}{"a.las", "b.las"}; !reflect.DeepEqual(tileset.Asset.Extras["sourceFiles"], expected) {
+		t.Errorf("expected source files %v got %v", expected, tileset.Asset.Extras["sourceFiles"])
+	}
+	// the new points have intensity 0, below the existing ones
+	if p := tileset.Properties["INTENSITY"]; p.Minimum != 0 || p.Maximum != 7 {
+		t.Errorf("expected the intensity range to be widened to [0, 7] got %v", p)
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "0")); !os.IsNotExist(err) {
+		t.Errorf("expected the replaced subtree to be removed, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "1", "content.pnts")); err != nil {
+		t.Errorf("expected the untouched subtree to be kept, got %v", err)
+	}
+	pts, err := readTilePoints(tmp, "append1/tileset.json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pts) != 4 {
+		t.Errorf("expected 4 points in the rebuilt subtree, got %d", len(pts))
+	}
+	files := map[int]int{}
+	for _, pt := range pts {
+		files[pt.FileIndex]++
+	}
+	if files[0] != 2 || files[1] != 2 {
+		t.Errorf("expected 2 points from each file, got %v", files)
+	}
+
+	opts = NewTilerOptions(WithPackaging(Package3tz))
+	if err := tiler.AppendFiles(tmp, []string{"b.las"}, 4326, opts, context.TODO()); err == nil {
+		t.Errorf("expected error appending to a 3tz package, got none")
+	}
+	opts = NewTilerOptions(WithPackaging(PackageIonZip))
+	if err := tiler.AppendFiles(tmp, []string{"b.las"}, 4326, opts, context.TODO()); err == nil {
+		t.Errorf("expected error appending to a zip package, got none")
+	}
+}
+
+func TestTilerAppendFilesHeightRules(t *testing.T) {
+	tiler, err := NewGoCesiumTiler()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	opts := NewTilerOptions(WithSourceFileAttribute(true))
+	tmp := writeAppendBaseTileset(t, tiler, opts)
+
+	tiler.lasReaderProvider = func(inputLasFiles []string, epsgCode int, opts *TilerOptions) (las.LasReader, error) {
+		return &las.MockLasReader{Srid: 4326, Pts: []geom.Point64{
+			{X: 10.0005, Y: 45.0005, Z: 1, Classification: 2},
+			{X: 10.0006, Y: 45.0006, Z: 1, Classification: 2},
+			{X: 10.0007, Y: 45.0007, Z: 3, Classification: 1},
+		}}, nil
+	}
+	opts = NewTilerOptions(WithSourceFileAttribute(true), WithHeightRules([]HeightRule{{Min: 0.2, Max: 5, Classification: 3}}))
+	if err := tiler.AppendFiles(tmp, []string{"b.las"}, 4326, opts, context.TODO()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pts, err := readTilePoints(tmp, "append1/tileset.json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	classes := map[uint8]int{}
+	for _, pt := range pts {
+		if pt.FileIndex == 1 {
+			classes[pt.Classification]++
+		}
+	}
+	if expected := map[uint8]int{2: 2, 3: 1}; !reflect.DeepEqual(classes, expected) {
+		t.Errorf("expected the appended points to be reclassified as %v got %v", expected, classes)
+	}
+}
+
+func TestTilerWriterTimeWindow(t *testing.T) {
+	opts := NewDefaultTilerOptions()
+	opts.timeWindow = &[2]float64{120, 180}
+	data, err := os.ReadFile(filepath.Join(writeTestTileset(t, opts), "tileset.json"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var tileset struct {
+		Asset struct {
+			Extras struct {
+				TimeWindow map[string]float64 `json:"timeWindow"`
+			} `json:"extras"`
+		} `json:"asset"`
+	}
+	if err := json.Unmarshal(data, &tileset); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if actual := tileset.Asset.Extras.TimeWindow; actual["start"] != 120 || actual["end"] != 180 {
+		t.Errorf("expected the time window in the asset extras, got %v", actual)
+	}
+}
+
+func TestTilerWriterContentExtension(t *testing.T) {
+	tmp := writeTestTileset(t, NewTilerOptions(WithContentExtension(".bin")))
+	if _, err := os.Stat(filepath.Join(tmp, "content.bin")); err != nil {
+		t.Errorf("expected the content to be stored with the custom extension: %v", err)
+	}
+	if n, err := writer.ValidateTileset(tmp); err != nil || n != 1 {
+		t.Errorf("expected a valid tileset with %d point, got %d (%v)", 1, n, err)
+	}
+}
+
+func TestTilerProcessFilesInvalidContentExtension(t *testing.T) {
+	tiler, err := NewGoCesiumTiler()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tiler.lasReaderProvider = func(inputLasFiles []string, epsgCode int, opts *TilerOptions) (las.LasReader, error) {
+		t.Fatalf("expected the files not to be read")
+		return nil, nil
+	}
+	for _, ext := range []string{".json", "JSON", "a/b", `a\b`} {
+		if err := tiler.ProcessFiles([]string{"abc.las"}, "out", 4326, NewTilerOptions(WithContentExtension(ext)), context.TODO()); err == nil {
+			t.Errorf("expected error for extension %s, got none", ext)
+		}
+	}
+}
+
+func TestTilerProcessFilesInvalidReadBufferSize(t *testing.T) {
+	tiler, err := NewGoCesiumTiler()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tiler.lasReaderProvider = func(inputLasFiles []string, epsgCode int, opts *TilerOptions) (las.LasReader, error) {
+		t.Fatalf("expected the files not to be read")
+		return nil, nil
+	}
+	for _, size := range []int{0, -1} {
+		if err := tiler.ProcessFiles([]string{"abc.las"}, "out", 4326, NewTilerOptions(WithReadBufferSize(size)), context.TODO()); err == nil {
+			t.Errorf("expected error for read buffer size %d, got none", size)
+		}
+	}
+}
+
+func TestTilerWriterPerPointBatchId(t *testing.T) {
+	tmp := writeTestTileset(t, NewTilerOptions(WithPerPointBatchId(true)))
+	data, err := os.ReadFile(filepath.Join(tmp, "content.pnts"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(string(data), `"BATCH_ID"`) {
+		t.Errorf("expected batch ids in the content")
+	}
+	if n, err := writer.ValidateTileset(tmp); err != nil || n != 1 {
+		t.Errorf("expected a valid tileset with %d point, got %d (%v)", 1, n, err)
+	}
+}
+
+func TestTilerWriterClassAlpha(t *testing.T) {
+	tmp := writeTestTileset(t, NewTilerOptions(WithClassAlpha(map[uint8]uint8{8: 100})))
+	data, err := os.ReadFile(filepath.Join(tmp, "content.pnts"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(string(data), `"RGBA"`) {
+		t.Errorf("expected RGBA colors in the content")
+	}
+}
@@ -0,0 +1,47 @@
+package tiler
+
+import (
+	"runtime/debug"
+)
+
+// version is the version of the tiler. It can be overridden at build time with
+// -ldflags "-X github.com/mfbonfigli/gocesiumtiler/v2.version=<version>"
+var version = "2.0.0-alpha"
+
+// BuildInfo describes the build of the tiler
+type BuildInfo struct {
+	// Version is the version of the tiler
+	Version string `json:"version"`
+	// Commit is the VCS revision the tiler was built from, empty if unknown
+	Commit string `json:"commit,omitempty"`
+	// BuildDate is the time of the commit the tiler was built from, in RFC3339 format, empty if unknown
+	BuildDate string `json:"buildDate,omitempty"`
+	// Modified is true if the tiler was built from a working tree with uncommitted changes
+	Modified bool `json:"modified,omitempty"`
+}
+
+// Version returns the version of the tiler
+func Version() string {
+	return version
+}
+
+// GetBuildInfo returns the version of the tiler together with the VCS information embedded
+// by the Go toolchain in the binary, if available
+func GetBuildInfo() BuildInfo {
+	b := BuildInfo{Version: version}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return b
+	}
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			b.Commit = s.Value
+		case "vcs.time":
+			b.BuildDate = s.Value
+		case "vcs.modified":
+			b.Modified = s.Value == "true"
+		}
+	}
+	return b
+}
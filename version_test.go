@@ -0,0 +1,14 @@
+package tiler
+
+import (
+	"testing"
+)
+
+func TestVersion(t *testing.T) {
+	if actual := Version(); actual != version {
+		t.Errorf("expected version %s got %s", version, actual)
+	}
+	if actual := GetBuildInfo().Version; actual != version {
+		t.Errorf("expected build info version %s got %s", version, actual)
+	}
+}
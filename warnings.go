@@ -0,0 +1,124 @@
+package tiler
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/las"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/mutator"
+)
+
+// The categories of the warnings collected by an ErrorCollector
+const (
+	// WarningCrs reports an extent of the points not matching the units of the input CRS, see WithStrictCrs
+	WarningCrs = "crs"
+	// WarningColorOverflow reports the points whose colors, read as 8 bit values with WithEightBitColors, exceed 255
+	// and are truncated
+	WarningColorOverflow = "color overflow"
+	// WarningDroppedPoints prefixes the categories reporting the points discarded by a filter, followed by the
+	// name of the filter, e.g. "dropped points: ElevationClamp"
+	WarningDroppedPoints = "dropped points"
+)
+
+// Warning describes a non fatal issue found while tiling an input
+type Warning struct {
+	// Category is the kind of issue, one of the Warning constants
+	Category string
+	// Input is the description of the input the issue was found in, as reported to the TilerCallback
+	Input string
+	// Count is the number of occurrences of the issue, e.g. the number of points affected
+	Count int
+	// Sample describes the first occurrence of the issue
+	Sample string
+}
+
+// ErrorCollector accumulates the non fatal issues found while tiling, aggregated by category and input, so that
+// they can be inspected once ProcessFiles or ProcessFolder returns. The errors stopping the tiling are returned by
+// the process calls instead. An ErrorCollector is safe for concurrent use.
+type ErrorCollector struct {
+	warnings []Warning
+	sync.Mutex
+}
+
+func NewErrorCollector() *ErrorCollector {
+	return &ErrorCollector{}
+}
+
+// Warnings returns the warnings collected so far, in the order they were first found
+func (c *ErrorCollector) Warnings() []Warning {
+	c.Lock()
+	defer c.Unlock()
+	return append([]Warning{}, c.warnings...)
+}
+
+// add records count occurrences of the issue, merging them with the warning of the same category and input if any.
+// Does nothing on a nil collector.
+func (c *ErrorCollector) add(category string, input string, count int, sample string) {
+	if c == nil || count <= 0 {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	for i, w := range c.warnings {
+		if w.Category == category && w.Input == input {
+			c.warnings[i].Count += count
+			return
+		}
+	}
+	c.warnings = append(c.warnings, Warning{Category: category, Input: input, Count: count, Sample: sample})
+}
+
+// addColorOverflows records the points read with truncated colors, if the reader counts them
+func (c *ErrorCollector) addColorOverflows(reader las.LasReader, input string) {
+	if counter, ok := reader.(las.ColorOverflowCounter); ok {
+		n, sample := counter.ColorOverflows()
+		c.add(WarningColorOverflow, input, n, sample)
+	}
+}
+
+// dropCountingMutator counts the points discarded by the wrapped mutator
+type dropCountingMutator struct {
+	mutator.Mutator
+	dropped int64
+	// sample describes the first point discarded, it is written once before dropped is incremented
+	sample string
+	once   sync.Once
+}
+
+func (m *dropCountingMutator) Mutate(pt geom.Point64) (geom.Point64, bool) {
+	out, ok := m.Mutator.Mutate(pt)
+	if !ok {
+		m.once.Do(func() {
+			m.sample = fmt.Sprintf("point at %v, %v, %v with classification %d", pt.X, pt.Y, pt.Z, pt.Classification)
+		})
+		atomic.AddInt64(&m.dropped, 1)
+	}
+	return out, ok
+}
+
+// name returns the name of the wrapped mutator, i.e. its type without the package
+func (m *dropCountingMutator) name() string {
+	return strings.TrimPrefix(fmt.Sprintf("%T", m.Mutator), "*mutator.")
+}
+
+// countDrops wraps each mutator of the pipeline counting the points it discards, returning the wrappers
+func countDrops(p *mutator.Pipeline) []*dropCountingMutator {
+	counters := make([]*dropCountingMutator, len(p.Mutators))
+	for i, m := range p.Mutators {
+		counters[i] = &dropCountingMutator{Mutator: m}
+		p.Mutators[i] = counters[i]
+	}
+	return counters
+}
+
+// addDrops records the points discarded by the mutators wrapped by countDrops
+func (c *ErrorCollector) addDrops(counters []*dropCountingMutator, input string) {
+	for _, m := range counters {
+		if n := atomic.LoadInt64(&m.dropped); n > 0 {
+			c.add(WarningDroppedPoints+": "+m.name(), input, int(n), m.sample)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package tiler
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/conv/coor"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/conv/elev"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/geom"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/las"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/mutator"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/tree"
+	"github.com/mfbonfigli/gocesiumtiler/v2/internal/writer"
+)
+
+func TestErrorCollector(t *testing.T) {
+	c := NewErrorCollector()
+	c.add(WarningCrs, "a.las", 1, "first")
+	c.add(WarningCrs, "b.las", 1, "other input")
+	c.add(WarningCrs, "a.las", 2, "second")
+	c.add(WarningColorOverflow, "a.las", 0, "nothing")
+	expected := []Warning{
+		{Category: WarningCrs, Input: "a.las", Count: 3, Sample: "first"},
+		{Category: WarningCrs, Input: "b.las", Count: 1, Sample: "other input"},
+	}
+	if actual := c.Warnings(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected warnings %v got %v", expected, actual)
+	}
+
+	var none *ErrorCollector
+	none.add(WarningCrs, "a.las", 1, "ignored")
+}
+
+func TestCountDrops(t *testing.T) {
+	p := mutator.NewPipeline(mutator.NewElevationClamp(0, 10, false), mutator.NewFlagFilter(geom.FlagWithheld))
+	counters := countDrops(p)
+	for _, pt := range []geom.Point64{{Z: 5}, {Z: 20}, {Z: 30}, {Z: 5, Flags: geom.FlagWithheld}} {
+		p.Mutate(pt)
+	}
+	c := NewErrorCollector()
+	c.addDrops(counters, "a.las")
+	expected := []Warning{
+		{Category: "dropped points: ElevationClamp", Input: "a.las", Count: 2, Sample: "point at 0, 0, 20 with classification 0"},
+		{Category: "dropped points: FlagFilter", Input: "a.las", Count: 1, Sample: "point at 0, 0, 5 with classification 0"},
+	}
+	if actual := c.Warnings(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected warnings %v got %v", expected, actual)
+	}
+}
+
+// mutatingTree loads the points passing them through the mutator
+type mutatingTree struct {
+	tree.MockNode
+	m mutator.Mutator
+}
+
+func (d *mutatingTree) Load(l las.LasReader, c coor.CoordinateConverter, e elev.ElevationConverter, ctx context.Context) error {
+	for i := 0; i < l.NumberOfPoints(); i++ {
+		pt, err := l.GetNext()
+		if err != nil {
+			return err
+		}
+		d.m.Mutate(pt)
+	}
+	return nil
+}
+
+// overflowingReader is a reader whose extent looks like degrees, reporting truncated colors
+type overflowingReader struct {
+	las.MockLasReader
+}
+
+func (r *overflowingReader) ColorOverflows() (int, string) {
+	return 3, "overflow"
+}
+
+func (r *overflowingReader) Extent() (minX, minY, maxX, maxY float64) {
+	return 11, 46, 11.1, 46.1
+}
+
+func TestTilerProcessFilesErrorCollector(t *testing.T) {
+	tiler, err := NewGoCesiumTiler()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tiler.writerProvider = func(folder string, inputFiles []string, reader las.LasReader, c coor.CoordinateConverter, opts *TilerOptions, progress writer.ProgressFunc) (writer.Writer, error) {
+		return &writer.MockWriter{}, nil
+	}
+	tiler.treeProvider = func(opts *TilerOptions, m mutator.Mutator) tree.Tree {
+		return &mutatingTree{m: m}
+	}
+	tiler.lasReaderProvider = func(inputLasFiles []string, epsgCode int, opts *TilerOptions) (las.LasReader, error) {
+		return &overflowingReader{las.MockLasReader{Pts: []geom.Point64{{Z: 5}, {Z: 50, Classification: 6}}}}, nil
+	}
+	c := NewErrorCollector()
+	opts := NewTilerOptions(WithElevationClamp(0, 10), WithErrorCollector(c))
+	if err := tiler.ProcessFiles([]string{"abc.las"}, "out", 32633, opts, context.TODO()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	warnings := c.Warnings()
+	categories := []string{}
+	for _, w := range warnings {
+		categories = append(categories, w.Category)
+	}
+	expected := []string{WarningCrs, "dropped points: ElevationClamp", WarningColorOverflow}
+	if !reflect.DeepEqual(categories, expected) {
+		t.Fatalf("expected warnings %v got %v", expected, warnings)
+	}
+	if w := warnings[1]; w.Count != 1 || w.Sample != "point at 0, 0, 50 with classification 6" {
+		t.Errorf("unexpected dropped points warning %v", w)
+	}
+	if w := warnings[2]; w.Count != 3 || w.Sample != "overflow" {
+		t.Errorf("unexpected color overflow warning %v", w)
+	}
+}